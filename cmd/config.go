@@ -3,19 +3,26 @@ package cmd
 import (
 	"cloud.google.com/go/profiler"
 	"fmt"
+	"github.com/go-redis/redis/v7"
 	"github.com/jinzhu/gorm"
 	"github.com/spf13/viper"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/router/auth"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/variable"
+	"github.com/traPtitech/traQ/service/webpush"
 	"github.com/traPtitech/traQ/utils/storage"
+	"github.com/traPtitech/traQ/utils/webauthn"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
 	"image"
+	"net/url"
 	"time"
 )
 
@@ -108,6 +115,27 @@ type Config struct {
 		} `mapstructure:"swift" yaml:"swift"`
 	} `mapstructure:"storage" yaml:"storage"`
 
+	// Session HTTPセッション設定
+	Session struct {
+		// Type セッションストアタイプ (default: database)
+		// 	database: データベース
+		// 	redis: Redis
+		// 	memory: メモリ (再起動すると全てのセッションが失われます)
+		Type string `mapstructure:"type" yaml:"type"`
+
+		// Redis Redis接続設定
+		Redis struct {
+			// Addr アドレス (default: 127.0.0.1:6379)
+			Addr string `mapstructure:"addr" yaml:"addr"`
+			// Password パスワード
+			Password string `mapstructure:"password" yaml:"password"`
+			// DB データベース番号 (default: 0)
+			DB int `mapstructure:"db" yaml:"db"`
+			// Prefix キーのプレフィックス (default: traq:session:)
+			Prefix string `mapstructure:"prefix" yaml:"prefix"`
+		} `mapstructure:"redis" yaml:"redis"`
+	} `mapstructure:"session" yaml:"session"`
+
 	// GCP Google Cloud Platform設定
 	GCP struct {
 		// ServiceAccount サービスアカウント設定
@@ -137,12 +165,50 @@ type Config struct {
 		} `mapstructure:"serviceAccount" yaml:"serviceAccount"`
 	} `mapstructure:"firebase" yaml:"firebase"`
 
+	// WebPush Web Push設定
+	WebPush struct {
+		// VAPID VAPID鍵設定 (空の場合はWeb Pushが無効になります)
+		VAPID struct {
+			// PublicKey 公開鍵 (base64url)
+			PublicKey string `mapstructure:"publicKey" yaml:"publicKey"`
+			// PrivateKey 秘密鍵 (base64url)
+			PrivateKey string `mapstructure:"privateKey" yaml:"privateKey"`
+		} `mapstructure:"vapid" yaml:"vapid"`
+		// Subscriber プッシュサービスに通知する連絡先 (mailto:またはhttps:のURL)
+		Subscriber string `mapstructure:"subscriber" yaml:"subscriber"`
+	} `mapstructure:"webpush" yaml:"webpush"`
+
+	// Email メール通知設定
+	Email struct {
+		// SMTP SMTPサーバー設定
+		SMTP struct {
+			// Host ホスト名 (空の場合はメール通知が無効になります)
+			Host string `mapstructure:"host" yaml:"host"`
+			// Port ポート番号 (default: 587)
+			Port int `mapstructure:"port" yaml:"port"`
+			// Username 認証ユーザー名 (空の場合は認証を行いません)
+			Username string `mapstructure:"username" yaml:"username"`
+			// Password 認証パスワード
+			Password string `mapstructure:"password" yaml:"password"`
+		} `mapstructure:"smtp" yaml:"smtp"`
+		// From 送信元アドレス
+		From string `mapstructure:"from" yaml:"from"`
+		// DailyDigestHour 1日毎のダイジェストを送信する時刻(時) (default: 8)
+		DailyDigestHour int `mapstructure:"dailyDigestHour" yaml:"dailyDigestHour"`
+	} `mapstructure:"email" yaml:"email"`
+
 	// OAuth2 OAuth2認可サーバー設定
 	OAuth2 struct {
 		// IsRefreshEnabled リフレッシュトークンを有効にするかどうか (default: false)
 		IsRefreshEnabled bool `mapstructure:"isRefreshEnabled" yaml:"isRefreshEnabled"`
 		// AccessTokenExpire アクセストークン有効期間(秒) (default: 31536000)
 		AccessTokenExpire int `mapstructure:"accessTokenExp" yaml:"accessTokenExp"`
+		// IsJWTAccessTokenEnabled アクセストークンを署名付きJWTで発行するかどうか (default: false)
+		IsJWTAccessTokenEnabled bool `mapstructure:"jwtAccessToken" yaml:"jwtAccessToken"`
+		// IsOpenClientRegistrationEnabled 初期アクセストークン無しの動的クライアント登録を受け付けるかどうか (default: false)
+		//
+		// 受け付けたクライアントは管理者の承認待ちになります。
+		IsOpenClientRegistrationEnabled bool `mapstructure:"openClientRegistration" yaml:"openClientRegistration"`
 	} `mapstructure:"oauth2" yaml:"oauth2"`
 
 	// ExternalAuthentication 外部認証設定
@@ -169,6 +235,8 @@ type Config struct {
 		Keys struct {
 			// Private ECDSA秘密鍵ファイル
 			Private string `mapstructure:"private" yaml:"private"`
+			// Previous ローテーション前のECDSA秘密鍵ファイル (発行済みのJWTの検証にのみ使用)
+			Previous []string `mapstructure:"previous" yaml:"previous"`
 		} `mapstructure:"keys" yaml:"keys"`
 	} `mapstructure:"jwt" yaml:"jwt"`
 
@@ -196,7 +264,55 @@ type Config struct {
 			ClientSecret string   `mapstructure:"clientSecret" yaml:"clientSecret"`
 			AllowSignUp  bool     `mapstructure:"allowSignUp" yaml:"allowSignUp"`
 			Scopes       []string `mapstructure:"scopes" yaml:"scopes"`
+			GroupSync    struct {
+				// Claim グループ一覧を含むクレーム名 (空の場合はグループを同期しない)
+				Claim string `mapstructure:"claim" yaml:"claim"`
+				// Mapping クレームの値からユーザーグループへの対応 (空の場合はクレームの値をそのままグループ名とする)
+				Mapping map[string]struct {
+					Group string `mapstructure:"group" yaml:"group"`
+					Role  string `mapstructure:"role" yaml:"role"`
+				} `mapstructure:"mapping" yaml:"mapping"`
+				// Type 同期の対象とするユーザーグループのタイプ (default: oidc)
+				Type string `mapstructure:"type" yaml:"type"`
+				// CreateMissing 存在しないユーザーグループを作成するかどうか
+				CreateMissing bool `mapstructure:"createMissing" yaml:"createMissing"`
+				// Admin 作成したユーザーグループの管理者とするユーザーの名前 (default: traq)
+				Admin string `mapstructure:"admin" yaml:"admin"`
+				// Roles クレームの値からユーザーロールへの対応 (先頭から順に評価し、空の場合はロールを同期しない)
+				Roles []struct {
+					Value string `mapstructure:"value" yaml:"value"`
+					Role  string `mapstructure:"role" yaml:"role"`
+				} `mapstructure:"roles" yaml:"roles"`
+			} `mapstructure:"groupSync" yaml:"groupSync"`
 		} `mapstructure:"oidc" yaml:"oidc"`
+		LDAP struct {
+			URL                  string            `mapstructure:"url" yaml:"url"`
+			StartTLS             bool              `mapstructure:"startTLS" yaml:"startTLS"`
+			InsecureSkipVerify   bool              `mapstructure:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+			BindDN               string            `mapstructure:"bindDN" yaml:"bindDN"`
+			BindPassword         string            `mapstructure:"bindPassword" yaml:"bindPassword"`
+			BaseDN               string            `mapstructure:"baseDN" yaml:"baseDN"`
+			UserFilter           string            `mapstructure:"userFilter" yaml:"userFilter"`
+			IDAttribute          string            `mapstructure:"idAttribute" yaml:"idAttribute"`
+			NameAttribute        string            `mapstructure:"nameAttribute" yaml:"nameAttribute"`
+			DisplayNameAttribute string            `mapstructure:"displayNameAttribute" yaml:"displayNameAttribute"`
+			IconAttribute        string            `mapstructure:"iconAttribute" yaml:"iconAttribute"`
+			GroupBaseDN          string            `mapstructure:"groupBaseDN" yaml:"groupBaseDN"`
+			GroupFilter          string            `mapstructure:"groupFilter" yaml:"groupFilter"`
+			GroupNameAttribute   string            `mapstructure:"groupNameAttribute" yaml:"groupNameAttribute"`
+			GroupMapping         map[string]string `mapstructure:"groupMapping" yaml:"groupMapping"`
+			AllowSignUp          bool              `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+		} `mapstructure:"ldap" yaml:"ldap"`
+		SAML struct {
+			EntityID             string `mapstructure:"entityId" yaml:"entityId"`
+			Certificate          string `mapstructure:"certificate" yaml:"certificate"`
+			PrivateKey           string `mapstructure:"privateKey" yaml:"privateKey"`
+			IdPMetadata          string `mapstructure:"idpMetadata" yaml:"idpMetadata"`
+			IDAttribute          string `mapstructure:"idAttribute" yaml:"idAttribute"`
+			NameAttribute        string `mapstructure:"nameAttribute" yaml:"nameAttribute"`
+			DisplayNameAttribute string `mapstructure:"displayNameAttribute" yaml:"displayNameAttribute"`
+			AllowSignUp          bool   `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+		} `mapstructure:"saml" yaml:"saml"`
 	} `mapstructure:"externalAuth" yaml:"externalAuth"`
 }
 
@@ -229,12 +345,28 @@ func init() {
 	viper.SetDefault("storage.swift.authUrl", "")
 	viper.SetDefault("storage.swift.tempUrlKey", "")
 	viper.SetDefault("storage.swift.cacheDir", "")
+	viper.SetDefault("session.type", "database")
+	viper.SetDefault("session.redis.addr", "127.0.0.1:6379")
+	viper.SetDefault("session.redis.password", "")
+	viper.SetDefault("session.redis.db", 0)
+	viper.SetDefault("session.redis.prefix", "traq:session:")
 	viper.SetDefault("gcp.serviceAccount.projectId", "")
 	viper.SetDefault("gcp.serviceAccount.file", "")
 	viper.SetDefault("gcp.stackdriver.profiler.enabled", false)
 	viper.SetDefault("firebase.serviceAccount.file", "")
+	viper.SetDefault("webpush.vapid.publicKey", "")
+	viper.SetDefault("webpush.vapid.privateKey", "")
+	viper.SetDefault("webpush.subscriber", "")
+	viper.SetDefault("email.smtp.host", "")
+	viper.SetDefault("email.smtp.port", 587)
+	viper.SetDefault("email.smtp.username", "")
+	viper.SetDefault("email.smtp.password", "")
+	viper.SetDefault("email.from", "")
+	viper.SetDefault("email.dailyDigestHour", 8)
 	viper.SetDefault("oauth2.isRefreshEnabled", false)
 	viper.SetDefault("oauth2.accessTokenExp", 60*60*24*365)
+	viper.SetDefault("oauth2.jwtAccessToken", false)
+	viper.SetDefault("oauth2.openClientRegistration", false)
 	viper.SetDefault("externalAuthentication.enabled", false)
 	viper.SetDefault("externalAuthentication.authPost.url", "")
 	viper.SetDefault("externalAuthentication.authPost.successfulCode", 0)
@@ -291,6 +423,26 @@ func (c Config) getFileStorage() (storage.FileStorage, error) {
 	}
 }
 
+func (c Config) getSessionStore(db *gorm.DB) (session.Store, error) {
+	switch c.Session.Type {
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     c.Session.Redis.Addr,
+			Password: c.Session.Redis.Password,
+			DB:       c.Session.Redis.DB,
+		})
+		if err := client.Ping().Err(); err != nil {
+			_ = client.Close()
+			return nil, err
+		}
+		return session.NewRedisStore(client, c.Session.Redis.Prefix), nil
+	case "memory":
+		return session.NewMemorySessionStore(), nil
+	default:
+		return session.NewGormStore(db), nil
+	}
+}
+
 func (c Config) getDatabase() (*gorm.DB, error) {
 	engine, err := gorm.Open("mysql", fmt.Sprintf(
 		"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&collation=utf8mb4_general_ci&parseTime=true",
@@ -319,11 +471,30 @@ func initStackdriverProfiler(c *Config) error {
 	}, option.WithCredentialsFile(c.GCP.ServiceAccount.File))
 }
 
-func newFCMClientIfAvailable(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, file variable.FirebaseCredentialsFilePathString) (fcm.Client, error) {
+func newFCMClientIfAvailable(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, file variable.FirebaseCredentialsFilePathString, wpConfig webpush.Config) (fcm.Client, error) {
+	var senders []fcm.Sender
 	if len(file) > 0 {
-		return fcm.NewClientWithCredentialsFile(repo, logger, unreadCounter, file)
+		s, err := fcm.NewSenderWithCredentialsFile(repo, logger, file)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, s)
+	}
+	if len(wpConfig.VAPIDPublicKey) > 0 && len(wpConfig.VAPIDPrivateKey) > 0 {
+		s, err := webpush.NewSender(repo, logger, wpConfig)
+		if err != nil {
+			return nil, err
+		}
+		senders = append(senders, s)
+	}
+	return fcm.NewDispatcher(repo, logger, unreadCounter, senders...), nil
+}
+
+func newEmailClientIfAvailable(repo repository.Repository, cm channel.Manager, logger *zap.Logger, config email.Config, origin variable.ServerOriginString) (email.Client, error) {
+	if len(config.Host) > 0 {
+		return email.NewClient(repo, cm, logger, config, origin)
 	}
-	return fcm.NewNullClient(), nil
+	return email.NewNullClient(), nil
 }
 
 func provideServerOriginString(c *Config) variable.ServerOriginString {
@@ -334,6 +505,25 @@ func provideFirebaseCredentialsFilePathString(c *Config) variable.FirebaseCreden
 	return variable.FirebaseCredentialsFilePathString(c.Firebase.ServiceAccount.File)
 }
 
+func provideWebPushConfig(c *Config) webpush.Config {
+	return webpush.Config{
+		VAPIDPublicKey:  c.WebPush.VAPID.PublicKey,
+		VAPIDPrivateKey: c.WebPush.VAPID.PrivateKey,
+		Subscriber:      c.WebPush.Subscriber,
+	}
+}
+
+func provideEmailConfig(c *Config) email.Config {
+	return email.Config{
+		Host:            c.Email.SMTP.Host,
+		Port:            c.Email.SMTP.Port,
+		Username:        c.Email.SMTP.Username,
+		Password:        c.Email.SMTP.Password,
+		From:            c.Email.From,
+		DailyDigestHour: c.Email.DailyDigestHour,
+	}
+}
+
 func provideImageProcessorConfig(c *Config) imaging.Config {
 	return imaging.Config{
 		MaxPixels:        c.Imaging.MaxPixels,
@@ -361,6 +551,14 @@ func provideAuthGoogleProviderConfig(c *Config) auth.GoogleProviderConfig {
 }
 
 func provideAuthOIDCProviderConfig(c *Config) auth.OIDCProviderConfig {
+	mapping := make(map[string]auth.OIDCGroupMapping, len(c.ExternalAuth.OIDC.GroupSync.Mapping))
+	for k, v := range c.ExternalAuth.OIDC.GroupSync.Mapping {
+		mapping[k] = auth.OIDCGroupMapping{Group: v.Group, Role: v.Role}
+	}
+	roles := make([]auth.OIDCRoleMapping, len(c.ExternalAuth.OIDC.GroupSync.Roles))
+	for i, v := range c.ExternalAuth.OIDC.GroupSync.Roles {
+		roles[i] = auth.OIDCRoleMapping{Value: v.Value, Role: v.Role}
+	}
 	return auth.OIDCProviderConfig{
 		Issuer:                 c.ExternalAuth.OIDC.Issuer,
 		ClientID:               c.ExternalAuth.OIDC.ClientID,
@@ -368,6 +566,51 @@ func provideAuthOIDCProviderConfig(c *Config) auth.OIDCProviderConfig {
 		Scopes:                 c.ExternalAuth.OIDC.Scopes,
 		CallbackURL:            c.Origin + "/api/auth/oidc/callback",
 		RegisterUserIfNotFound: c.ExternalAuth.OIDC.AllowSignUp,
+		GroupsClaim:            c.ExternalAuth.OIDC.GroupSync.Claim,
+		GroupMapping:           mapping,
+		GroupType:              c.ExternalAuth.OIDC.GroupSync.Type,
+		CreateMissingGroups:    c.ExternalAuth.OIDC.GroupSync.CreateMissing,
+		GroupAdmin:             c.ExternalAuth.OIDC.GroupSync.Admin,
+		RoleMapping:            roles,
+	}
+}
+
+func provideAuthLDAPProviderConfig(c *Config) auth.LDAPProviderConfig {
+	return auth.LDAPProviderConfig{
+		URL:                    c.ExternalAuth.LDAP.URL,
+		StartTLS:               c.ExternalAuth.LDAP.StartTLS,
+		InsecureSkipVerify:     c.ExternalAuth.LDAP.InsecureSkipVerify,
+		BindDN:                 c.ExternalAuth.LDAP.BindDN,
+		BindPassword:           c.ExternalAuth.LDAP.BindPassword,
+		BaseDN:                 c.ExternalAuth.LDAP.BaseDN,
+		UserFilter:             c.ExternalAuth.LDAP.UserFilter,
+		IDAttribute:            c.ExternalAuth.LDAP.IDAttribute,
+		NameAttribute:          c.ExternalAuth.LDAP.NameAttribute,
+		DisplayNameAttribute:   c.ExternalAuth.LDAP.DisplayNameAttribute,
+		IconAttribute:          c.ExternalAuth.LDAP.IconAttribute,
+		GroupBaseDN:            c.ExternalAuth.LDAP.GroupBaseDN,
+		GroupFilter:            c.ExternalAuth.LDAP.GroupFilter,
+		GroupNameAttribute:     c.ExternalAuth.LDAP.GroupNameAttribute,
+		GroupMapping:           c.ExternalAuth.LDAP.GroupMapping,
+		RegisterUserIfNotFound: c.ExternalAuth.LDAP.AllowSignUp,
+	}
+}
+
+func provideAuthSAMLProviderConfig(c *Config) auth.SAMLProviderConfig {
+	entityID := c.ExternalAuth.SAML.EntityID
+	if len(entityID) == 0 {
+		entityID = c.Origin + "/api/auth/saml/metadata"
+	}
+	return auth.SAMLProviderConfig{
+		EntityID:               entityID,
+		ACSURL:                 c.Origin + "/api/auth/saml/acs",
+		CertificateFile:        c.ExternalAuth.SAML.Certificate,
+		PrivateKeyFile:         c.ExternalAuth.SAML.PrivateKey,
+		IdPMetadata:            c.ExternalAuth.SAML.IdPMetadata,
+		IDAttribute:            c.ExternalAuth.SAML.IDAttribute,
+		NameAttribute:          c.ExternalAuth.SAML.NameAttribute,
+		DisplayNameAttribute:   c.ExternalAuth.SAML.DisplayNameAttribute,
+		RegisterUserIfNotFound: c.ExternalAuth.SAML.AllowSignUp,
 	}
 }
 
@@ -387,19 +630,37 @@ func provideRouterExternalAuthConfig(c *Config) router.ExternalAuthConfig {
 		Google: provideAuthGoogleProviderConfig(c),
 		TraQ:   provideAuthTraQProviderConfig(c),
 		OIDC:   provideAuthOIDCProviderConfig(c),
+		LDAP:   provideAuthLDAPProviderConfig(c),
+		SAML:   provideAuthSAMLProviderConfig(c),
+	}
+}
+
+func provideWebAuthnRelyingParty(c *Config) webauthn.RelyingParty {
+	rp := webauthn.RelyingParty{
+		Name:   "traQ",
+		Origin: c.Origin,
+	}
+	if u, err := url.Parse(c.Origin); err == nil {
+		rp.ID = u.Hostname()
 	}
+	return rp
 }
 
 func provideRouterConfig(c *Config) *router.Config {
 	return &router.Config{
-		Development:      c.DevMode,
-		Version:          Version,
-		Revision:         Revision,
-		AccessLogging:    c.AccessLog.Enabled,
-		Gzipped:          c.Gzip,
-		AccessTokenExp:   c.OAuth2.AccessTokenExpire,
-		IsRefreshEnabled: c.OAuth2.IsRefreshEnabled,
-		SkyWaySecretKey:  c.SkyWay.SecretKey,
-		ExternalAuth:     provideRouterExternalAuthConfig(c),
+		Development:                     c.DevMode,
+		Version:                         Version,
+		Revision:                        Revision,
+		AccessLogging:                   c.AccessLog.Enabled,
+		Gzipped:                         c.Gzip,
+		Origin:                          c.Origin,
+		AccessTokenExp:                  c.OAuth2.AccessTokenExpire,
+		IsRefreshEnabled:                c.OAuth2.IsRefreshEnabled,
+		IsJWTAccessTokenEnabled:         c.OAuth2.IsJWTAccessTokenEnabled,
+		IsOpenClientRegistrationEnabled: c.OAuth2.IsOpenClientRegistrationEnabled,
+		SkyWaySecretKey:                 c.SkyWay.SecretKey,
+		ExternalAuth:                    provideRouterExternalAuthConfig(c),
+		WebPushVAPIDPublicKey:           c.WebPush.VAPID.PublicKey,
+		WebAuthn:                        provideWebAuthnRelyingParty(c),
 	}
 }
@@ -3,17 +3,14 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/spf13/cobra"
-	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/utils/gormzap"
 	"github.com/traPtitech/traQ/utils/jwt"
-	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/random"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -64,6 +61,14 @@ func serveCommand() *cobra.Command {
 			}
 			logger.Info("file storage is ok")
 
+			// SessionStore
+			logger.Info("checking session store...")
+			sessStore, err := c.getSessionStore(engine)
+			if err != nil {
+				logger.Fatal("failed to setup session store", zap.Error(err))
+			}
+			logger.Info("session store is ok")
+
 			// Repository
 			logger.Info("setting up repository...")
 			repo, err := repository.NewGormRepository(engine, fs, hub, logger)
@@ -105,24 +110,32 @@ func serveCommand() *cobra.Command {
 				logger.Info("data initialization finished")
 			}
 
-			// JWT for QRCode
+			// JWT for QRCode, OpenID Connect
 			if priv := c.JWT.Keys.Private; priv != "" {
 				privRaw, err := ioutil.ReadFile(priv)
 				if err != nil {
 					logger.Fatal("failed to read jwt private key", zap.Error(err))
 				}
-				if err := jwt.SetupSigner(privRaw); err != nil {
+				previousRaws := make([][]byte, 0, len(c.JWT.Keys.Previous))
+				for _, f := range c.JWT.Keys.Previous {
+					raw, err := ioutil.ReadFile(f)
+					if err != nil {
+						logger.Fatal("failed to read previous jwt private key", zap.Error(err))
+					}
+					previousRaws = append(previousRaws, raw)
+				}
+				if err := jwt.SetupSigner(privRaw, previousRaws...); err != nil {
 					logger.Fatal("failed to setup signer", zap.Error(err))
 				}
 			} else {
 				// 一時鍵を発行
 				privRaw, pubRaw := random.GenerateECDSAKey()
 				_ = jwt.SetupSigner(privRaw)
-				logger.Warn("a temporary key for QRCode JWT was generated. This key is valid only during this running.", zap.String("public_key", string(pubRaw)))
+				logger.Warn("a temporary key for JWT was generated. This key is valid only during this running.", zap.String("public_key", string(pubRaw)))
 			}
 
 			// サーバー作成
-			server, err := newServer(hub, engine, repo, logger, c)
+			server, err := newServer(hub, engine, repo, sessStore, logger, c)
 			if err != nil {
 				logger.Fatal("failed to create server", zap.Error(err))
 			}
@@ -161,15 +174,6 @@ type Server struct {
 }
 
 func (s *Server) Start(address string) error {
-	go func() {
-		// TODO 適切なパッケージに移動させる
-		sub := s.Hub.Subscribe(10, event.UserOffline)
-		for ev := range sub.Receiver {
-			userID := ev.Fields["user_id"].(uuid.UUID)
-			datetime := ev.Fields["datetime"].(time.Time)
-			_ = s.Repo.UpdateUser(userID, repository.UpdateUserArgs{LastOnline: optional.TimeFrom(datetime)})
-		}
-	}()
 	s.SS.BOT.Start()
 	return s.Router.Start(address)
 }
@@ -187,6 +191,14 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		s.SS.FCM.Close()
 		return nil
 	})
+	eg.Go(func() error {
+		s.SS.Email.Close()
+		return nil
+	})
+	eg.Go(func() error {
+		s.SS.Presence.Close()
+		return nil
+	})
 	eg.Go(func() error {
 		s.SS.ChannelManager.Wait()
 		return nil
@@ -8,6 +8,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router"
+	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
 	"github.com/traPtitech/traQ/service/channel"
@@ -15,6 +16,7 @@ import (
 	"github.com/traPtitech/traQ/service/heartbeat"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/presence"
 	rbac2 "github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -23,7 +25,7 @@ import (
 	"go.uber.org/zap"
 )
 
-func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, logger *zap.Logger, c *Config) (*Server, error) {
+func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, sessStore session.Store, logger *zap.Logger, c *Config) (*Server, error) {
 	wire.Build(
 		bot.NewService,
 		channel.InitChannelManager,
@@ -34,6 +36,7 @@ func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, logger *za
 		heartbeat.NewManager,
 		imaging.NewProcessor,
 		notification.NewService,
+		presence.NewManager,
 		rbac2.New,
 		sse.NewStreamer,
 		viewer.NewManager,
@@ -41,13 +44,17 @@ func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, logger *za
 		ws.NewStreamer,
 		router.Setup,
 		newFCMClientIfAvailable,
+		newEmailClientIfAvailable,
 		provideServerOriginString,
 		provideFirebaseCredentialsFilePathString,
+		provideEmailConfig,
+		provideWebPushConfig,
 		provideImageProcessorConfig,
 		provideRouterConfig,
 		wire.Struct(new(service.Services), "*"),
 		wire.Struct(new(Server), "*"),
 		wire.Bind(new(repository.ChannelRepository), new(repository.Repository)),
+		wire.Bind(new(repository.UserBlockRepository), new(repository.Repository)),
 	)
 	return nil, nil
 }
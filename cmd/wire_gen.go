@@ -10,6 +10,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router"
+	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
 	"github.com/traPtitech/traQ/service/channel"
@@ -17,6 +18,7 @@ import (
 	"github.com/traPtitech/traQ/service/heartbeat"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/presence"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -32,8 +34,8 @@ import (
 
 // Injectors from serve_wire.go:
 
-func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, logger *zap.Logger, c2 *Config) (*Server, error) {
-	manager, err := channel.InitChannelManager(repo, logger)
+func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, sessStore session.Store, logger *zap.Logger, c2 *Config) (*Server, error) {
+	manager, err := channel.InitChannelManager(repo, repo, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -51,24 +53,34 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, logger *z
 	if err != nil {
 		return nil, err
 	}
+	config := provideEmailConfig(c2)
+	serverOriginString := provideServerOriginString(c2)
+	client, err := newEmailClientIfAvailable(repo, manager, logger, config, serverOriginString)
+	if err != nil {
+		return nil, err
+	}
 	firebaseCredentialsFilePathString := provideFirebaseCredentialsFilePathString(c2)
-	client, err := newFCMClientIfAvailable(repo, logger, unreadMessageCounter, firebaseCredentialsFilePathString)
+	webpushConfig := provideWebPushConfig(c2)
+	fcmClient, err := newFCMClientIfAvailable(repo, logger, unreadMessageCounter, firebaseCredentialsFilePathString, webpushConfig)
 	if err != nil {
 		return nil, err
 	}
 	viewerManager := viewer.NewManager(hub2)
 	heartbeatManager := heartbeat.NewManager(viewerManager)
-	config := provideImageProcessorConfig(c2)
-	processor := imaging.NewProcessor(config)
+	imagingConfig := provideImageProcessorConfig(c2)
+	processor := imaging.NewProcessor(imagingConfig)
 	streamer := sse.NewStreamer(hub2)
 	webrtcv3Manager := webrtcv3.NewManager(hub2)
 	wsStreamer := ws.NewStreamer(hub2, viewerManager, webrtcv3Manager, logger)
-	serverOriginString := provideServerOriginString(c2)
-	notificationService := notification.NewService(repo, manager, hub2, logger, client, streamer, wsStreamer, viewerManager, serverOriginString)
-	rbacRBAC, err := rbac.New(db)
+	presenceManager, err := presence.NewManager(repo, hub2, onlineCounter, logger)
+	if err != nil {
+		return nil, err
+	}
+	rbacRBAC, err := rbac.New(db, hub2, logger)
 	if err != nil {
 		return nil, err
 	}
+	notificationService := notification.NewService(repo, manager, hub2, logger, fcmClient, client, streamer, wsStreamer, viewerManager, onlineCounter, presenceManager, rbacRBAC, serverOriginString)
 	services := &service.Services{
 		BOT:                  botService,
 		ChannelManager:       manager,
@@ -76,10 +88,12 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, logger *z
 		UnreadMessageCounter: unreadMessageCounter,
 		MessageCounter:       messageCounter,
 		ChannelCounter:       channelCounter,
-		FCM:                  client,
+		Email:                client,
+		FCM:                  fcmClient,
 		HeartBeats:           heartbeatManager,
 		Imaging:              processor,
 		Notification:         notificationService,
+		Presence:             presenceManager,
 		RBAC:                 rbacRBAC,
 		SSE:                  streamer,
 		ViewerManager:        viewerManager,
@@ -87,7 +101,7 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, logger *z
 		WS:                   wsStreamer,
 	}
 	routerConfig := provideRouterConfig(c2)
-	echo := router.Setup(hub2, db, repo, services, logger, routerConfig)
+	echo := router.Setup(hub2, db, repo, services, sessStore, logger, routerConfig)
 	server := &Server{
 		L:      logger,
 		SS:     services,
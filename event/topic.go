@@ -24,6 +24,12 @@ const (
 	//		user_id: uuid.UUID
 	//		datetime: time.Time
 	UserOffline = "user.offline"
+	// UserStatusUpdated ユーザーのステータス・プレゼンスが更新された
+	// 	Fields:
+	//		user_id: uuid.UUID
+	//		status: *model.UserStatus
+	//		presence: model.Presence
+	UserStatusUpdated = "user.status_updated"
 
 	// UserTagAdded ユーザーにタグが追加された
 	// 	Fields:
@@ -61,6 +67,11 @@ const (
 	//		user_id: uuid.UUID
 	UserGroupMemberRemoved = "user_group.member.removed"
 
+	// RoleUpdated ユーザーロールが作成・更新・削除された
+	// 	Fields:
+	//		role: string
+	RoleUpdated = "role.updated"
+
 	// MessageCreated メッセージが作成された
 	// 	Fields:
 	//		message_id: uuid.UUID
@@ -195,6 +206,18 @@ const (
 	// 		stamp_palette_id: uuid.UUID
 	StampPaletteDeleted = "stamp_palette.deleted"
 
+	// KeywordAlertCreated キーワード通知が作成された
+	// 	Fields:
+	//		user_id: uuid.UUID
+	// 		keyword_alert_id: uuid.UUID
+	// 		keyword_alert: *model.KeywordAlert
+	KeywordAlertCreated = "keyword_alert.created"
+	// KeywordAlertDeleted キーワード通知が削除された
+	// 	Fields:
+	//		user_id: uuid.UUID
+	// 		keyword_alert_id: uuid.UUID
+	KeywordAlertDeleted = "keyword_alert.deleted"
+
 	// WebhookCreated Webhookが作成された
 	// 	Fields:
 	// 		webhook_id: uuid.UUID
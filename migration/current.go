@@ -29,6 +29,26 @@ func Migrations() []*gormigrate.Migration {
 		v17(), // ユーザーホームチャンネル
 		v18(), // インデックス追加
 		v19(), // httpセッション管理テーブル変更
+		v20(), // おやすみモード
+		v21(), // キーワード通知
+		v22(), // 特殊メンション
+		v23(), // インボックス
+		v24(), // メール通知
+		v25(), // Web Push
+		v26(), // ユーザーステータス・プレゼンス
+		v27(), // ユーザーブロック・ミュート
+		v28(), // TOTP二要素認証
+		v29(), // WebAuthn認証情報
+		v30(), // ログイン試行の制限と監査ログ
+		v31(), // 招待リンク
+		v32(), // OAuth2デバイス認可
+		v33(), // パーソナルアクセストークン
+		v34(), // OAuth2動的クライアント登録
+		v35(), // httpセッションのメタデータ
+		v36(), // カスタムロール
+		v37(), // チャンネルスコープのロール
+		v38(), // 使用済みSAMLアサーション
+		v39(), // メール通知先アドレスの確認
 	}
 }
 
@@ -37,6 +57,7 @@ func Migrations() []*gormigrate.Migration {
 // 最新のスキーマの全テーブルのモデル構造体を記述すること
 func AllTables() []interface{} {
 	return []interface{}{
+		&model.ChannelRoleBinding{},
 		&model.ChannelEvent{},
 		&model.RolePermission{},
 		&model.RoleInheritance{},
@@ -48,6 +69,8 @@ func AllTables() []interface{} {
 		&model.Bot{},
 		&model.OAuth2Client{},
 		&model.OAuth2Authorize{},
+		&model.OAuth2DeviceAuthorization{},
+		&model.OAuth2InitialAccessToken{},
 		&model.OAuth2Token{},
 		&model.MessageReport{},
 		&model.WebhookBot{},
@@ -57,6 +80,23 @@ func AllTables() []interface{} {
 		&model.Unread{},
 		&model.Star{},
 		&model.Device{},
+		&model.WebPushSubscription{},
+		&model.UserStatus{},
+		&model.UserBlock{},
+		&model.UserTOTP{},
+		&model.UserRecoveryCode{},
+		&model.WebAuthnCredential{},
+		&model.LoginLock{},
+		&model.LoginAuditLog{},
+		&model.SAMLUsedAssertion{},
+		&model.InvitationChannel{},
+		&model.InvitationGroup{},
+		&model.Invitation{},
+		&model.DoNotDisturbSetting{},
+		&model.KeywordAlert{},
+		&model.InboxEntry{},
+		&model.EmailAddressVerification{},
+		&model.EmailNotificationSetting{},
 		&model.Pin{},
 		&model.FileACLEntry{},
 		&model.File{},
@@ -126,6 +166,31 @@ func AllForeignKeys() [][5]string {
 		{"stamp_palettes", "creator_id", "users(id)", "CASCADE", "CASCADE"},
 		{"external_provider_users", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"user_profiles", "home_channel", "channels(id)", "CASCADE", "CASCADE"},
+		{"do_not_disturb_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"keyword_alerts", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"inbox_entries", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"inbox_entries", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+		{"inbox_entries", "actor_id", "users(id)", "CASCADE", "CASCADE"},
+		{"email_notification_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"email_address_verifications", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"webpush_subscriptions", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_statuses", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_blocks", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_blocks", "target_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_totps", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_recovery_codes", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_webauthn_credentials", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"login_audit_logs", "user_id", "users(id)", "SET NULL", "CASCADE"},
+		{"invitations", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+		{"invitations", "role", "user_roles(name)", "CASCADE", "CASCADE"},
+		{"invitation_groups", "invitation_id", "invitations(id)", "CASCADE", "CASCADE"},
+		{"invitation_groups", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+		{"invitation_channels", "invitation_id", "invitations(id)", "CASCADE", "CASCADE"},
+		{"invitation_channels", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"oauth2_initial_access_tokens", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+		{"channel_role_bindings", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"channel_role_bindings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"channel_role_bindings", "role", "user_roles(name)", "CASCADE", "CASCADE"},
 	}
 }
 
@@ -143,6 +208,7 @@ func AllCompositeIndexes() [][]string {
 		{"idx_messages_stamps_user_id_stamp_id_updated_at", "messages_stamps", "user_id", "stamp_id", "updated_at"},
 		{"idx_channel_channels_id_is_public_is_forced", "channels", "id", "is_public", "is_forced"},
 		{"idx_messages_deleted_at_created_at", "messages", "deleted_at", "created_at"},
+		{"idx_inbox_entries_user_id_created_at", "inbox_entries", "user_id", "created_at"},
 	}
 }
 
@@ -153,5 +219,7 @@ func AllCompositeUniqueIndexes() [][]string {
 	return [][]string{
 		// Name,  Table, Columns...
 		{"idx_external_provider_users_provider_name_external_id", "external_provider_users", "provider_name", "external_id"},
+		{"idx_keyword_alerts_user_id_keyword", "keyword_alerts", "user_id", "keyword"},
+		{"idx_inbox_entries_user_id_message_id_type_actor_id", "inbox_entries", "user_id", "message_id", "type", "actor_id"},
 	}
 }
@@ -59,6 +59,22 @@ func Migrate(db *gorm.DB) error {
 				}
 			}
 		}
+		for _, v := range role.DefaultRoleModels() {
+			if err := db.Create(v).Error; err != nil {
+				return err
+			}
+
+			for _, v := range v.Inheritances {
+				if err := db.Create(&v).Error; err != nil {
+					return err
+				}
+			}
+			for _, v := range v.Permissions {
+				if err := db.Create(&v).Error; err != nil {
+					return err
+				}
+			}
+		}
 
 		return nil
 	})
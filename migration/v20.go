@@ -0,0 +1,74 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v20 おやすみモード
+func v20() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "20",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v20DoNotDisturbSetting{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"do_not_disturb_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"get_dnd_setting",
+					"edit_dnd_setting",
+				},
+				"read": {
+					"get_dnd_setting",
+				},
+				"write": {
+					"edit_dnd_setting",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v20RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v20DoNotDisturbSetting struct {
+	UserID         uuid.UUID                  `gorm:"type:char(36);not null;primary_key"`
+	Enabled        bool                       `gorm:"type:boolean;not null;default:false"`
+	Timezone       string                     `gorm:"type:varchar(64);not null;default:'UTC'"`
+	Schedule       model.DoNotDisturbSchedule `gorm:"type:text;not null"`
+	AllowedUsers   model.UUIDs                `gorm:"type:text;not null"`
+	UrgentChannels model.UUIDs                `gorm:"type:text;not null"`
+	UpdatedAt      time.Time                  `gorm:"precision:6"`
+}
+
+func (*v20DoNotDisturbSetting) TableName() string {
+	return "do_not_disturb_settings"
+}
+
+type v20RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v20RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v21 キーワード通知
+func v21() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "21",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v21KeywordAlert{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"keyword_alerts", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			uniqueIndexes := [][]string{
+				{"idx_keyword_alerts_user_id_keyword", "keyword_alerts", "user_id", "keyword"},
+			}
+			for _, v := range uniqueIndexes {
+				if err := db.Table(v[1]).AddUniqueIndex(v[0], v[2:]...).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"get_keyword_alert",
+					"edit_keyword_alert",
+				},
+				"read": {
+					"get_keyword_alert",
+				},
+				"write": {
+					"edit_keyword_alert",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v21RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v21KeywordAlert struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null"`
+	Keyword   string    `gorm:"type:varchar(100);not null"`
+	IsRegexp  bool      `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v21KeywordAlert) TableName() string {
+	return "keyword_alerts"
+}
+
+type v21RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v21RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v22 特殊メンション
+func v22() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "22",
+		Migrate: func(db *gorm.DB) error {
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"use_special_mention",
+				},
+				"write": {
+					"use_special_mention",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v22RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v22RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v22RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v23 インボックス
+func v23() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "23",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v23InboxEntry{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"inbox_entries", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"inbox_entries", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+				{"inbox_entries", "actor_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			indexes := [][]string{
+				{"idx_inbox_entries_user_id_created_at", "inbox_entries", "user_id", "created_at"},
+			}
+			for _, c := range indexes {
+				if err := db.Table(c[1]).AddIndex(c[0], c[2:]...).Error; err != nil {
+					return err
+				}
+			}
+
+			uniqueIndexes := [][]string{
+				{"idx_inbox_entries_user_id_message_id_type_actor_id", "inbox_entries", "user_id", "message_id", "type", "actor_id"},
+			}
+			for _, v := range uniqueIndexes {
+				if err := db.Table(v[1]).AddUniqueIndex(v[0], v[2:]...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v23InboxEntry struct {
+	ID        uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID     `gorm:"type:char(36);not null"`
+	Type      string        `gorm:"type:varchar(30);not null"`
+	MessageID uuid.UUID     `gorm:"type:char(36);not null"`
+	ActorID   uuid.UUID     `gorm:"type:char(36);not null"`
+	StampID   optional.UUID `gorm:"type:char(36)"`
+	IsRead    bool          `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+}
+
+func (*v23InboxEntry) TableName() string {
+	return "inbox_entries"
+}
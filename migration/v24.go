@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v24 メール通知
+func v24() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "24",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v24EmailNotificationSetting{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"email_notification_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"get_email_setting",
+					"edit_email_setting",
+				},
+				"read": {
+					"get_email_setting",
+				},
+				"write": {
+					"edit_email_setting",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v24RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v24EmailNotificationSetting struct {
+	UserID       uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	Address      string        `gorm:"type:varchar(254);not null;default:''"`
+	Enabled      bool          `gorm:"type:boolean;not null;default:false"`
+	Digest       string        `gorm:"type:varchar(10);not null;default:'none'"`
+	LastDigestAt optional.Time `gorm:"precision:6"`
+	UpdatedAt    time.Time     `gorm:"precision:6"`
+}
+
+func (*v24EmailNotificationSetting) TableName() string {
+	return "email_notification_settings"
+}
+
+type v24RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v24RolePermission) TableName() string {
+	return "user_role_permissions"
+}
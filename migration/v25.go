@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v25 Web Push
+func v25() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "25",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v25WebPushSubscription{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"webpush_subscriptions", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"register_webpush_subscription",
+				},
+				"write": {
+					"register_webpush_subscription",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v25RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v25WebPushSubscription struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;index"`
+	Endpoint  string    `gorm:"type:text;not null"`
+	P256dh    string    `gorm:"type:varchar(100);not null"`
+	Auth      string    `gorm:"type:varchar(50);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v25WebPushSubscription) TableName() string {
+	return "webpush_subscriptions"
+}
+
+type v25RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v25RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v26 ユーザーステータス・プレゼンス
+func v26() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "26",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v26UserStatus{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_statuses", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"edit_my_status",
+				},
+				"write": {
+					"edit_my_status",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v26RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v26UserStatus struct {
+	UserID    uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	Text      string        `gorm:"type:varchar(100);not null;default:''"`
+	StampID   optional.UUID `gorm:"type:char(36)"`
+	ExpiresAt optional.Time `gorm:"precision:6"`
+	Mode      string        `gorm:"type:varchar(10);not null;default:'auto'"`
+	UpdatedAt time.Time     `gorm:"precision:6"`
+}
+
+func (*v26UserStatus) TableName() string {
+	return "user_statuses"
+}
+
+type v26RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v26RolePermission) TableName() string {
+	return "user_role_permissions"
+}
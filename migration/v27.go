@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v27 ユーザーブロック・ミュート
+func v27() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "27",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v27UserBlock{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_blocks", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"user_blocks", "target_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"get_user_block",
+					"edit_user_block",
+				},
+				"read": {
+					"get_user_block",
+				},
+				"write": {
+					"edit_user_block",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v27RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v27UserBlock struct {
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	TargetID  uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Type      string    `gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v27UserBlock) TableName() string {
+	return "user_blocks"
+}
+
+type v27RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v27RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,87 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v28 TOTP二要素認証
+func v28() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "28",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v28UserTOTP{}, &v28UserRecoveryCode{}, &v28UserRole{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_totps", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"user_recovery_codes", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"manage_my_two_factor",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v28RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v28UserTOTP struct {
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Secret    string    `gorm:"type:varchar(64);not null"`
+	Enabled   bool      `gorm:"type:boolean;not null;default:false"`
+	LastStep  int64     `gorm:"type:bigint;not null;default:0"`
+	CreatedAt time.Time `gorm:"precision:6"`
+	UpdatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v28UserTOTP) TableName() string {
+	return "user_totps"
+}
+
+type v28UserRecoveryCode struct {
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	CodeHash  string    `gorm:"type:char(64);not null;primary_key"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v28UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+type v28UserRole struct {
+	Name              string `gorm:"type:varchar(30);not null;primary_key"`
+	Oauth2Scope       bool   `gorm:"type:boolean;not null;default:false"`
+	System            bool   `gorm:"type:boolean;not null;default:false"`
+	TwoFactorRequired bool   `gorm:"type:boolean;not null;default:false"`
+}
+
+func (*v28UserRole) TableName() string {
+	return "user_roles"
+}
+
+type v28RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v28RolePermission) TableName() string {
+	return "user_role_permissions"
+}
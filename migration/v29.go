@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v29 WebAuthn認証情報
+func v29() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "29",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v29WebAuthnCredential{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_webauthn_credentials", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"manage_my_webauthn",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v29RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v29WebAuthnCredential struct {
+	ID           uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	UserID       uuid.UUID     `gorm:"type:char(36);not null;index"`
+	Name         string        `gorm:"type:varchar(32);not null"`
+	CredentialID string        `gorm:"type:varchar(255);not null;unique"`
+	PublicKey    []byte        `gorm:"type:blob;not null"`
+	SignCount    uint32        `gorm:"type:int unsigned;not null;default:0"`
+	AAGUID       []byte        `gorm:"type:binary(16)"`
+	LastUsedAt   optional.Time `gorm:"precision:6"`
+	CreatedAt    time.Time     `gorm:"precision:6"`
+}
+
+func (*v29WebAuthnCredential) TableName() string {
+	return "user_webauthn_credentials"
+}
+
+type v29RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v29RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v30 ログイン試行の制限と監査ログ
+func v30() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "30",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v30LoginLock{}, &v30LoginAuditLog{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"login_audit_logs", "user_id", "users(id)", "SET NULL", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v30LoginLock struct {
+	Kind         string        `gorm:"type:varchar(10);not null;primary_key"`
+	Key          string        `gorm:"type:varchar(64);not null;primary_key"`
+	Failures     int           `gorm:"type:int;not null;default:0"`
+	LastFailedAt time.Time     `gorm:"precision:6"`
+	LockedUntil  optional.Time `gorm:"precision:6"`
+}
+
+func (*v30LoginLock) TableName() string {
+	return "login_locks"
+}
+
+type v30LoginAuditLog struct {
+	ID        uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	UserID    optional.UUID `gorm:"type:char(36);index"`
+	Name      string        `gorm:"type:varchar(32);not null"`
+	IP        string        `gorm:"type:varchar(64);not null;index"`
+	Method    string        `gorm:"type:varchar(20);not null"`
+	Reason    string        `gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time     `gorm:"precision:6;index"`
+}
+
+func (*v30LoginAuditLog) TableName() string {
+	return "login_audit_logs"
+}
@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v31 招待リンク
+func v31() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "31",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v31Invitation{}, &v31InvitationGroup{}, &v31InvitationChannel{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"invitations", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+				{"invitations", "role", "user_roles(name)", "CASCADE", "CASCADE"},
+				{"invitation_groups", "invitation_id", "invitations(id)", "CASCADE", "CASCADE"},
+				{"invitation_groups", "group_id", "user_groups(id)", "CASCADE", "CASCADE"},
+				{"invitation_channels", "invitation_id", "invitations(id)", "CASCADE", "CASCADE"},
+				{"invitation_channels", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"create_invitation",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v31RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v31Invitation struct {
+	ID        uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	Token     string        `gorm:"type:varchar(64);not null;unique"`
+	CreatorID uuid.UUID     `gorm:"type:char(36);not null;index"`
+	Role      string        `gorm:"type:varchar(30);not null"`
+	MaxUses   int           `gorm:"type:int;not null;default:0"`
+	Uses      int           `gorm:"type:int;not null;default:0"`
+	ExpiresAt optional.Time `gorm:"precision:6"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+}
+
+func (*v31Invitation) TableName() string {
+	return "invitations"
+}
+
+type v31InvitationGroup struct {
+	InvitationID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	GroupID      uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+}
+
+func (*v31InvitationGroup) TableName() string {
+	return "invitation_groups"
+}
+
+type v31InvitationChannel struct {
+	InvitationID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	ChannelID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Level        int       `gorm:"type:int;not null"`
+}
+
+func (*v31InvitationChannel) TableName() string {
+	return "invitation_channels"
+}
+
+type v31RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v31RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v32 OAuth2デバイス認可
+func v32() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "32",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v32OAuth2DeviceAuthorization{}).Error
+		},
+	}
+}
+
+type v32OAuth2DeviceAuthorization struct {
+	DeviceCode      string    `gorm:"type:varchar(36);primary_key"`
+	UserCode        string    `gorm:"type:varchar(16);unique"`
+	ClientID        string    `gorm:"type:char(36)"`
+	UserID          uuid.UUID `gorm:"type:char(36)"`
+	Status          string    `gorm:"type:varchar(16)"`
+	Scopes          string    `gorm:"type:text"`
+	PollingInterval int
+	ExpiresIn       int
+	LastPolledAt    optional.Time `gorm:"precision:6"`
+	CreatedAt       time.Time     `gorm:"precision:6"`
+}
+
+func (*v32OAuth2DeviceAuthorization) TableName() string {
+	return "oauth2_device_authorizations"
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v33 パーソナルアクセストークン
+func v33() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "33",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v33OAuth2Token{}).Error; err != nil {
+				return err
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"create_my_token",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v33RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v33OAuth2Token struct {
+	ID             uuid.UUID `gorm:"type:char(36);primary_key"`
+	ClientID       string    `gorm:"type:char(36)"`
+	UserID         uuid.UUID `gorm:"type:char(36)"`
+	RedirectURI    string    `gorm:"type:text"`
+	AccessToken    string    `gorm:"type:varchar(36);unique"`
+	RefreshToken   string    `gorm:"type:varchar(36);unique"`
+	RefreshEnabled bool      `gorm:"type:boolean;default:false"`
+	Scopes         string    `gorm:"type:text"`
+	ExpiresIn      int
+	Name           string        `gorm:"type:varchar(32);not null;default:''"`
+	LastUsedAt     optional.Time `gorm:"precision:6"`
+	CreatedAt      time.Time     `gorm:"precision:6"`
+	DeletedAt      *time.Time    `gorm:"precision:6"`
+}
+
+func (*v33OAuth2Token) TableName() string {
+	return "oauth2_tokens"
+}
+
+type v33RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v33RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,62 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v34 OAuth2動的クライアント登録
+func v34() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "34",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v34OAuth2Client{}, &v34OAuth2InitialAccessToken{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"oauth2_initial_access_tokens", "creator_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v34OAuth2Client struct {
+	ID                      string `gorm:"type:char(36);primary_key"`
+	Name                    string `gorm:"type:varchar(32)"`
+	Description             string `gorm:"type:text"`
+	Confidential            bool
+	CreatorID               uuid.UUID  `gorm:"type:char(36)"`
+	Secret                  string     `gorm:"type:varchar(36)"`
+	RedirectURI             string     `gorm:"type:text"`
+	Scopes                  string     `gorm:"type:text"`
+	RequirePKCE             bool       `gorm:"type:boolean;not null;default:false"`
+	Pending                 bool       `gorm:"type:boolean;not null;default:false"`
+	RegistrationAccessToken string     `gorm:"type:varchar(36);not null;default:''"`
+	CreatedAt               time.Time  `gorm:"precision:6"`
+	UpdatedAt               time.Time  `gorm:"precision:6"`
+	DeletedAt               *time.Time `gorm:"precision:6"`
+}
+
+func (*v34OAuth2Client) TableName() string {
+	return "oauth2_clients"
+}
+
+type v34OAuth2InitialAccessToken struct {
+	Token     string    `gorm:"type:varchar(36);primary_key"`
+	CreatorID uuid.UUID `gorm:"type:char(36);not null"`
+	ExpiresAt time.Time `gorm:"precision:6"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v34OAuth2InitialAccessToken) TableName() string {
+	return "oauth2_initial_access_tokens"
+}
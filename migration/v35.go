@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v35 httpセッションのメタデータ
+func v35() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "35",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v35SessionRecord{}).Error; err != nil {
+				return err
+			}
+			// 既存のセッションの最終アクティビティ日時は発行日時とする
+			return db.Table(v35SessionRecord{}.TableName()).UpdateColumn("last_activity", gorm.Expr("created")).Error
+		},
+	}
+}
+
+type v35SessionRecord struct {
+	Token        string    `gorm:"type:varchar(50);primary_key"`
+	ReferenceID  uuid.UUID `gorm:"type:char(36);unique"`
+	UserID       uuid.UUID `gorm:"type:varchar(36);index"`
+	Data         []byte    `gorm:"type:longblob"`
+	UserAgent    string    `gorm:"type:varchar(255);not null;default:''"`
+	IP           string    `gorm:"type:varchar(45);not null;default:''"`
+	LoginMethod  string    `gorm:"type:varchar(30);not null;default:''"`
+	LastActivity time.Time `gorm:"precision:6"`
+	Created      time.Time `gorm:"precision:6"`
+}
+
+func (v35SessionRecord) TableName() string {
+	return "r_sessions"
+}
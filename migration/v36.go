@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v36 カスタムロール・moderatorロール
+func v36() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "36",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.Create(&v36UserRole{Name: "moderator"}).Error; err != nil {
+				return err
+			}
+			if err := db.Create(&v36RoleInheritance{Role: "moderator", SubRole: "user"}).Error; err != nil {
+				return err
+			}
+
+			addedRolePermissions := map[string][]string{
+				"moderator": {
+					"get_message_reports",
+					"manage_login_lock",
+					"get_login_audit_log",
+					"manage_others_sessions",
+					"edit_stamp_created_by_others",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v36RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v36UserRole struct {
+	Name              string `gorm:"type:varchar(30);not null;primary_key"`
+	Oauth2Scope       bool   `gorm:"type:boolean;not null;default:false"`
+	System            bool   `gorm:"type:boolean;not null;default:false"`
+	TwoFactorRequired bool   `gorm:"type:boolean;not null;default:false"`
+}
+
+func (*v36UserRole) TableName() string {
+	return "user_roles"
+}
+
+type v36RoleInheritance struct {
+	Role    string `gorm:"type:varchar(30);not null;primary_key"`
+	SubRole string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v36RoleInheritance) TableName() string {
+	return "user_role_inheritances"
+}
+
+type v36RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v36RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v37 チャンネルスコープのロール・channel_leadロール
+func v37() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "37",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v37ChannelRoleBinding{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"channel_role_bindings", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"channel_role_bindings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"channel_role_bindings", "role", "user_roles(name)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			lead := v37UserRole{Name: "channel_lead"}
+			if err := db.Where(&lead).FirstOrCreate(&lead).Error; err != nil {
+				return err
+			}
+
+			addedRolePermissions := map[string][]string{
+				"channel_lead": {
+					"edit_channel",
+					"edit_channel_topic",
+					"manage_channel_role",
+					"create_message_pin",
+					"delete_message_pin",
+					"delete_others_message",
+				},
+				"moderator": {
+					"delete_others_message",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				// ロールは実行時に削除・編集されている可能性がある
+				var count int
+				if err := db.Model(&v37UserRole{}).Where(&v37UserRole{Name: role}).Count(&count).Error; err != nil {
+					return err
+				}
+				if count == 0 {
+					continue
+				}
+				for _, perm := range perms {
+					rp := v37RolePermission{Role: role, Permission: perm}
+					if err := db.Where(&rp).FirstOrCreate(&rp).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v37ChannelRoleBinding struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Role      string    `gorm:"type:varchar(30);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v37ChannelRoleBinding) TableName() string {
+	return "channel_role_bindings"
+}
+
+type v37UserRole struct {
+	Name              string `gorm:"type:varchar(30);not null;primary_key"`
+	Oauth2Scope       bool   `gorm:"type:boolean;not null;default:false"`
+	System            bool   `gorm:"type:boolean;not null;default:false"`
+	TwoFactorRequired bool   `gorm:"type:boolean;not null;default:false"`
+}
+
+func (*v37UserRole) TableName() string {
+	return "user_roles"
+}
+
+type v37RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v37RolePermission) TableName() string {
+	return "user_role_permissions"
+}
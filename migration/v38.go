@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v38 使用済みSAMLアサーション
+func v38() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "38",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v38SAMLUsedAssertion{}).Error
+		},
+	}
+}
+
+type v38SAMLUsedAssertion struct {
+	ID        string    `gorm:"type:varchar(255);not null;primary_key"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+}
+
+func (*v38SAMLUsedAssertion) TableName() string {
+	return "saml_used_assertions"
+}
@@ -0,0 +1,42 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v39 メール通知先アドレスの確認
+func v39() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "39",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v39EmailAddressVerification{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"email_address_verifications", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v39EmailAddressVerification struct {
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	Address   string    `gorm:"type:varchar(254);not null"`
+	Token     string    `gorm:"type:varchar(64);not null;unique"`
+	ExpiresAt time.Time `gorm:"precision:6"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v39EmailAddressVerification) TableName() string {
+	return "email_address_verifications"
+}
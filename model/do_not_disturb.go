@@ -0,0 +1,208 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// DoNotDisturbSetting ユーザーのおやすみモード設定
+type DoNotDisturbSetting struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Enabled 手動でおやすみモードを有効にしているかどうか
+	Enabled bool `gorm:"type:boolean;not null;default:false"`
+	// Timezone スケジュールを評価するタイムゾーン(IANA Time Zone名)
+	Timezone string `gorm:"type:varchar(64);not null;default:'UTC'"`
+	// Schedule おやすみモードの週間スケジュール
+	Schedule DoNotDisturbSchedule `gorm:"type:text;not null"`
+	// AllowedUsers おやすみモード中でもDMの通知を受け取るユーザーのID
+	AllowedUsers UUIDs `gorm:"type:text;not null"`
+	// UrgentChannels おやすみモード中でも通知を受け取る強制通知チャンネルのID
+	UrgentChannels UUIDs     `gorm:"type:text;not null"`
+	UpdatedAt      time.Time `gorm:"precision:6"`
+}
+
+// TableName DoNotDisturbSetting構造体のテーブル名
+func (*DoNotDisturbSetting) TableName() string {
+	return "do_not_disturb_settings"
+}
+
+// Location スケジュールを評価するタイムゾーンを返します
+//
+// タイムゾーンが読み込めない場合はUTCを返します。
+func (s *DoNotDisturbSetting) Location() *time.Location {
+	if len(s.Timezone) == 0 {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsActive 指定した時刻におやすみモードが有効かどうか
+func (s *DoNotDisturbSetting) IsActive(now time.Time) bool {
+	if s.Enabled {
+		return true
+	}
+	return s.Schedule.Contains(now.In(s.Location()))
+}
+
+// Allows おやすみモード中でも指定した通知を許可するかどうか
+//
+// 許可ユーザーからのDM、緊急指定された強制通知チャンネルのメッセージは許可されます。
+func (s *DoNotDisturbSetting) Allows(senderID, channelID uuid.UUID, isDM, isForced bool) bool {
+	if isDM {
+		for _, id := range s.AllowedUsers {
+			if id == senderID {
+				return true
+			}
+		}
+	}
+	if isForced {
+		for _, id := range s.UrgentChannels {
+			if id == channelID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DoNotDisturbSchedule おやすみモードの週間スケジュール
+type DoNotDisturbSchedule []*DoNotDisturbPeriod
+
+// DoNotDisturbPeriod おやすみモードの期間
+//
+// EndがStart以前の場合は翌日のEndまでを表します。StartとEndが等しい場合は24時間を表します。
+type DoNotDisturbPeriod struct {
+	// Weekdays 期間が開始する曜日 (0:日曜日 ~ 6:土曜日) 空の場合は毎日
+	Weekdays []time.Weekday `json:"weekdays"`
+	// Start 開始時刻 (HH:MM)
+	Start string `json:"start"`
+	// End 終了時刻 (HH:MM)
+	End string `json:"end"`
+}
+
+// Validate 期間が正しいかどうかを検証します
+func (p *DoNotDisturbPeriod) Validate() error {
+	for _, w := range p.Weekdays {
+		if w < time.Sunday || w > time.Saturday {
+			return fmt.Errorf("invalid weekday: %d", w)
+		}
+	}
+	if _, err := parseClock(p.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(p.End); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Contains 指定した時刻が期間に含まれるかどうか
+//
+// tはスケジュールのタイムゾーンで与えてください。
+func (p *DoNotDisturbPeriod) Contains(t time.Time) bool {
+	start, err := parseClock(p.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(p.End)
+	if err != nil {
+		return false
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+
+	// 当日開始の期間と前日開始の期間を調べる
+	for _, offset := range []int{0, -1} {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, t.Location())
+		if !p.startsOn(day.Weekday()) {
+			continue
+		}
+		from := day.Add(start)
+		to := day.Add(end)
+		if !t.Before(from) && t.Before(to) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *DoNotDisturbPeriod) startsOn(w time.Weekday) bool {
+	if len(p.Weekdays) == 0 {
+		return true
+	}
+	for _, v := range p.Weekdays {
+		if v == w {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 指定した時刻がスケジュールのいずれかの期間に含まれるかどうか
+func (s DoNotDisturbSchedule) Contains(t time.Time) bool {
+	for _, p := range s {
+		if p.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate スケジュールが正しいかどうかを検証します
+func (s DoNotDisturbSchedule) Validate() error {
+	for _, p := range s {
+		if p == nil {
+			return errors.New("period must not be null")
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value database/sql/driver.Valuer 実装
+func (s DoNotDisturbSchedule) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return json.MarshalToString(s)
+}
+
+// Scan database/sql.Scanner 実装
+func (s *DoNotDisturbSchedule) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = DoNotDisturbSchedule{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	default:
+		return errors.New("failed to scan DoNotDisturbSchedule")
+	}
+}
+
+// parseClock HH:MM形式の時刻を0時からの経過時間に変換します (24:00を許容します)
+func parseClock(s string) (time.Duration, error) {
+	var h, m int
+	if len(s) != 5 || s[2] != ':' {
+		return 0, fmt.Errorf("invalid time format: %s", s)
+	}
+	if _, err := fmt.Sscanf(s, "%02d:%02d", &h, &m); err != nil {
+		return 0, fmt.Errorf("invalid time format: %s", s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time: %s", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
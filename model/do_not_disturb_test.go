@@ -0,0 +1,142 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDoNotDisturbSetting_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "do_not_disturb_settings", (&DoNotDisturbSetting{}).TableName())
+}
+
+func TestDoNotDisturbSetting_IsActive(t *testing.T) {
+	t.Parallel()
+
+	// 2020/06/01 は月曜日
+	jst := time.FixedZone("JST", 9*60*60)
+
+	t.Run("manual", func(t *testing.T) {
+		t.Parallel()
+		s := &DoNotDisturbSetting{Enabled: true}
+		assert.True(t, s.IsActive(time.Now()))
+	})
+
+	t.Run("no schedule", func(t *testing.T) {
+		t.Parallel()
+		s := &DoNotDisturbSetting{}
+		assert.False(t, s.IsActive(time.Now()))
+	})
+
+	t.Run("overnight", func(t *testing.T) {
+		t.Parallel()
+		s := &DoNotDisturbSetting{
+			Timezone: "Asia/Tokyo",
+			Schedule: DoNotDisturbSchedule{{Start: "22:00", End: "07:00"}},
+		}
+		assert.True(t, s.IsActive(time.Date(2020, 6, 1, 23, 0, 0, 0, jst)))
+		assert.True(t, s.IsActive(time.Date(2020, 6, 1, 6, 59, 0, 0, jst)))
+		assert.True(t, s.IsActive(time.Date(2020, 6, 1, 14, 0, 0, 0, time.UTC))) // 23:00 JST
+		assert.False(t, s.IsActive(time.Date(2020, 6, 1, 7, 0, 0, 0, jst)))
+		assert.False(t, s.IsActive(time.Date(2020, 6, 1, 21, 59, 0, 0, jst)))
+	})
+
+	t.Run("weekdays", func(t *testing.T) {
+		t.Parallel()
+		s := &DoNotDisturbSetting{
+			Timezone: "Asia/Tokyo",
+			Schedule: DoNotDisturbSchedule{{Weekdays: []time.Weekday{time.Friday}, Start: "20:00", End: "09:00"}},
+		}
+		assert.True(t, s.IsActive(time.Date(2020, 6, 5, 21, 0, 0, 0, jst)))  // 金曜日
+		assert.True(t, s.IsActive(time.Date(2020, 6, 6, 8, 0, 0, 0, jst)))   // 土曜日の朝
+		assert.False(t, s.IsActive(time.Date(2020, 6, 6, 21, 0, 0, 0, jst))) // 土曜日
+		assert.False(t, s.IsActive(time.Date(2020, 6, 5, 8, 0, 0, 0, jst)))  // 金曜日の朝
+	})
+
+	t.Run("whole day", func(t *testing.T) {
+		t.Parallel()
+		s := &DoNotDisturbSetting{
+			Schedule: DoNotDisturbSchedule{{Weekdays: []time.Weekday{time.Sunday}, Start: "00:00", End: "00:00"}},
+		}
+		assert.True(t, s.IsActive(time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC)))
+		assert.True(t, s.IsActive(time.Date(2020, 6, 7, 23, 59, 0, 0, time.UTC)))
+		assert.False(t, s.IsActive(time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC)))
+	})
+}
+
+func TestDoNotDisturbSetting_Allows(t *testing.T) {
+	t.Parallel()
+
+	user := uuid.Must(uuid.NewV4())
+	channel := uuid.Must(uuid.NewV4())
+	s := &DoNotDisturbSetting{
+		AllowedUsers:   UUIDs{user},
+		UrgentChannels: UUIDs{channel},
+	}
+
+	assert.True(t, s.Allows(user, uuid.Nil, true, false))
+	assert.False(t, s.Allows(user, uuid.Nil, false, false))
+	assert.False(t, s.Allows(uuid.Must(uuid.NewV4()), uuid.Nil, true, false))
+	assert.True(t, s.Allows(uuid.Nil, channel, false, true))
+	assert.False(t, s.Allows(uuid.Nil, channel, false, false))
+	assert.False(t, s.Allows(uuid.Nil, uuid.Must(uuid.NewV4()), false, true))
+}
+
+func TestDoNotDisturbSchedule_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, DoNotDisturbSchedule{}.Validate())
+	assert.NoError(t, DoNotDisturbSchedule{{Start: "00:00", End: "24:00"}}.Validate())
+	assert.Error(t, DoNotDisturbSchedule{nil}.Validate())
+	assert.Error(t, DoNotDisturbSchedule{{Start: "0:00", End: "01:00"}}.Validate())
+	assert.Error(t, DoNotDisturbSchedule{{Start: "00:60", End: "01:00"}}.Validate())
+	assert.Error(t, DoNotDisturbSchedule{{Start: "24:30", End: "01:00"}}.Validate())
+	assert.Error(t, DoNotDisturbSchedule{{Weekdays: []time.Weekday{7}, Start: "00:00", End: "01:00"}}.Validate())
+}
+
+func TestDoNotDisturbSchedule_Value(t *testing.T) {
+	t.Parallel()
+
+	s := DoNotDisturbSchedule{{Weekdays: []time.Weekday{time.Monday}, Start: "22:00", End: "07:00"}}
+	v, err := s.Value()
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `[{"weekdays":[1],"start":"22:00","end":"07:00"}]`, v.(string))
+	}
+}
+
+func TestDoNotDisturbSchedule_Scan(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nil", func(t *testing.T) {
+		t.Parallel()
+
+		s := DoNotDisturbSchedule{}
+		assert.NoError(t, s.Scan(nil))
+		assert.Len(t, s, 0)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+
+		s := DoNotDisturbSchedule{}
+		assert.NoError(t, s.Scan(`[{"weekdays":[1],"start":"22:00","end":"07:00"}]`))
+		assert.EqualValues(t, DoNotDisturbSchedule{{Weekdays: []time.Weekday{time.Monday}, Start: "22:00", End: "07:00"}}, s)
+	})
+
+	t.Run("[]byte", func(t *testing.T) {
+		t.Parallel()
+
+		s := DoNotDisturbSchedule{}
+		assert.NoError(t, s.Scan([]byte(`[{"weekdays":[],"start":"22:00","end":"07:00"}]`)))
+		assert.EqualValues(t, DoNotDisturbSchedule{{Weekdays: []time.Weekday{}, Start: "22:00", End: "07:00"}}, s)
+	})
+
+	t.Run("other", func(t *testing.T) {
+		t.Parallel()
+
+		s := DoNotDisturbSchedule{}
+		assert.Error(t, s.Scan(123))
+	})
+}
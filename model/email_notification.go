@@ -0,0 +1,101 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// EmailDigestInterval メール通知ダイジェストの送信間隔
+type EmailDigestInterval string
+
+const (
+	// EmailDigestNone ダイジェストを送信しない
+	EmailDigestNone EmailDigestInterval = "none"
+	// EmailDigestHourly 1時間毎にダイジェストを送信する
+	EmailDigestHourly EmailDigestInterval = "hourly"
+	// EmailDigestDaily 1日毎にダイジェストを送信する
+	EmailDigestDaily EmailDigestInterval = "daily"
+)
+
+// Valid 有効な送信間隔かどうか
+func (i EmailDigestInterval) Valid() bool {
+	switch i {
+	case EmailDigestNone, EmailDigestHourly, EmailDigestDaily:
+		return true
+	default:
+		return false
+	}
+}
+
+// Duration 送信間隔の長さを返します
+func (i EmailDigestInterval) Duration() time.Duration {
+	switch i {
+	case EmailDigestHourly:
+		return time.Hour
+	case EmailDigestDaily:
+		return 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// EmailNotificationSetting ユーザーのメール通知設定
+type EmailNotificationSetting struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Address 通知先メールアドレス
+	Address string `gorm:"type:varchar(254);not null;default:''"`
+	// Enabled DM・メンションを受け取った際にメールを送信するかどうか
+	Enabled bool `gorm:"type:boolean;not null;default:false"`
+	// Digest 未読メンションのダイジェストの送信間隔
+	Digest EmailDigestInterval `gorm:"type:varchar(10);not null;default:'none'"`
+	// LastDigestAt 最後にダイジェストを送信した日時
+	LastDigestAt optional.Time `gorm:"precision:6"`
+	UpdatedAt    time.Time     `gorm:"precision:6"`
+}
+
+// TableName EmailNotificationSetting構造体のテーブル名
+func (*EmailNotificationSetting) TableName() string {
+	return "email_notification_settings"
+}
+
+// IsDigestDue 指定した時刻にダイジェストを送信すべきかどうか
+//
+// 1日毎のダイジェストは、前回の送信から23時間以上経過しており、現在時刻がdailyHour時台の場合に送信します。
+func (s *EmailNotificationSetting) IsDigestDue(now time.Time, dailyHour int) bool {
+	if len(s.Address) == 0 {
+		return false
+	}
+	switch s.Digest {
+	case EmailDigestHourly:
+		return !s.LastDigestAt.Valid || now.Sub(s.LastDigestAt.Time) >= time.Hour
+	case EmailDigestDaily:
+		if now.Hour() != dailyHour {
+			return false
+		}
+		return !s.LastDigestAt.Valid || now.Sub(s.LastDigestAt.Time) >= 23*time.Hour
+	default:
+		return false
+	}
+}
+
+// EmailAddressVerification メール通知先アドレスの確認待ち情報
+type EmailAddressVerification struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Address 確認待ちのメールアドレス
+	Address string `gorm:"type:varchar(254);not null"`
+	// Token 確認リンクに含まれるトークン
+	Token     string    `gorm:"type:varchar(64);not null;unique"`
+	ExpiresAt time.Time `gorm:"precision:6"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName EmailAddressVerification構造体のテーブル名
+func (*EmailAddressVerification) TableName() string {
+	return "email_address_verifications"
+}
+
+// IsExpired 確認期限が切れているかどうか
+func (v *EmailAddressVerification) IsExpired() bool {
+	return time.Now().After(v.ExpiresAt)
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestEmailNotificationSetting_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "email_notification_settings", (&EmailNotificationSetting{}).TableName())
+}
+
+func TestEmailDigestInterval_Valid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, EmailDigestNone.Valid())
+	assert.True(t, EmailDigestHourly.Valid())
+	assert.True(t, EmailDigestDaily.Valid())
+	assert.False(t, EmailDigestInterval("weekly").Valid())
+}
+
+func TestEmailNotificationSetting_IsDigestDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 6, 1, 8, 30, 0, 0, time.UTC)
+
+	t.Run("no address", func(t *testing.T) {
+		t.Parallel()
+		s := &EmailNotificationSetting{Digest: EmailDigestHourly}
+		assert.False(t, s.IsDigestDue(now, 8))
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+		s := &EmailNotificationSetting{Address: "a@example.com", Digest: EmailDigestNone}
+		assert.False(t, s.IsDigestDue(now, 8))
+	})
+
+	t.Run("hourly", func(t *testing.T) {
+		t.Parallel()
+		s := &EmailNotificationSetting{Address: "a@example.com", Digest: EmailDigestHourly}
+		assert.True(t, s.IsDigestDue(now, 8))
+		s.LastDigestAt = optional.TimeFrom(now.Add(-30 * time.Minute))
+		assert.False(t, s.IsDigestDue(now, 8))
+		s.LastDigestAt = optional.TimeFrom(now.Add(-time.Hour))
+		assert.True(t, s.IsDigestDue(now, 8))
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		t.Parallel()
+		s := &EmailNotificationSetting{Address: "a@example.com", Digest: EmailDigestDaily}
+		assert.True(t, s.IsDigestDue(now, 8))
+		assert.False(t, s.IsDigestDue(now, 9))
+		s.LastDigestAt = optional.TimeFrom(now.Add(-time.Hour))
+		assert.False(t, s.IsDigestDue(now, 8))
+		s.LastDigestAt = optional.TimeFrom(now.Add(-24 * time.Hour))
+		assert.True(t, s.IsDigestDue(now, 8))
+	})
+}
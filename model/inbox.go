@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// InboxEntryType インボックス項目の種類
+type InboxEntryType string
+
+const (
+	// InboxEntryTypeMention 自分へのメンション
+	InboxEntryTypeMention InboxEntryType = "mention"
+	// InboxEntryTypeGroupMention 自分が所属するグループへのメンション
+	InboxEntryTypeGroupMention InboxEntryType = "group_mention"
+	// InboxEntryTypeReply 自分のメッセージへの返信 (同じチャンネルでの引用)
+	InboxEntryTypeReply InboxEntryType = "reply"
+	// InboxEntryTypeCitation 自分のメッセージの他のチャンネルでの引用
+	InboxEntryTypeCitation InboxEntryType = "citation"
+	// InboxEntryTypeStamp 自分のメッセージへのスタンプ
+	InboxEntryTypeStamp InboxEntryType = "stamp"
+)
+
+// Valid 有効な種類かどうか
+func (t InboxEntryType) Valid() bool {
+	switch t {
+	case InboxEntryTypeMention, InboxEntryTypeGroupMention, InboxEntryTypeReply, InboxEntryTypeCitation, InboxEntryTypeStamp:
+		return true
+	default:
+		return false
+	}
+}
+
+// InboxEntry インボックス項目
+type InboxEntry struct {
+	ID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// UserID 受信者のユーザーID
+	UserID uuid.UUID      `gorm:"type:char(36);not null"`
+	Type   InboxEntryType `gorm:"type:varchar(30);not null"`
+	// MessageID 対象のメッセージID (メンション・返信・引用の場合は投稿されたメッセージ、スタンプの場合はスタンプが押されたメッセージ)
+	MessageID uuid.UUID `gorm:"type:char(36);not null"`
+	// ActorID 操作を行ったユーザーのID
+	ActorID uuid.UUID `gorm:"type:char(36);not null"`
+	// StampID 押されたスタンプのID (スタンプの場合のみ)
+	StampID   optional.UUID `gorm:"type:char(36)"`
+	IsRead    bool          `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+}
+
+// TableName InboxEntry構造体のテーブル名
+func (*InboxEntry) TableName() string {
+	return "inbox_entries"
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInboxEntry_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "inbox_entries", (&InboxEntry{}).TableName())
+}
+
+func TestInboxEntryType_Valid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, InboxEntryTypeMention.Valid())
+	assert.True(t, InboxEntryTypeGroupMention.Valid())
+	assert.True(t, InboxEntryTypeReply.Valid())
+	assert.True(t, InboxEntryTypeCitation.Valid())
+	assert.True(t, InboxEntryTypeStamp.Valid())
+	assert.False(t, InboxEntryType("").Valid())
+	assert.False(t, InboxEntryType("thread").Valid())
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// Invitation アカウント登録用の招待リンク構造体
+type Invitation struct {
+	ID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Token 招待リンクに含まれるトークン
+	Token     string    `gorm:"type:varchar(64);not null;unique"`
+	CreatorID uuid.UUID `gorm:"type:char(36);not null;index"`
+	// Role 登録されるユーザーのロール
+	Role string `gorm:"type:varchar(30);not null"`
+	// MaxUses 最大使用回数 (0の場合は無制限)
+	MaxUses int `gorm:"type:int;not null;default:0"`
+	// Uses 使用回数
+	Uses      int           `gorm:"type:int;not null;default:0"`
+	ExpiresAt optional.Time `gorm:"precision:6"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+
+	Groups   []*InvitationGroup   `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:InvitationID"`
+	Channels []*InvitationChannel `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:InvitationID"`
+}
+
+// TableName Invitation構造体のテーブル名
+func (*Invitation) TableName() string {
+	return "invitations"
+}
+
+// IsAvailable 招待リンクが指定した時刻に使用可能かどうか
+func (i *Invitation) IsAvailable(now time.Time) bool {
+	if i.ExpiresAt.Valid && !now.Before(i.ExpiresAt.Time) {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
+
+// GroupIDs 登録時に追加されるユーザーグループのIDの配列を返します
+func (i *Invitation) GroupIDs() []uuid.UUID {
+	result := make([]uuid.UUID, len(i.Groups))
+	for k, g := range i.Groups {
+		result[k] = g.GroupID
+	}
+	return result
+}
+
+// InvitationGroup 招待リンクから登録したユーザーが追加されるユーザーグループ構造体
+type InvitationGroup struct {
+	InvitationID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	GroupID      uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+}
+
+// TableName InvitationGroup構造体のテーブル名
+func (*InvitationGroup) TableName() string {
+	return "invitation_groups"
+}
+
+// InvitationChannel 招待リンクから登録したユーザーが購読するチャンネル構造体
+type InvitationChannel struct {
+	InvitationID uuid.UUID             `gorm:"type:char(36);not null;primary_key"`
+	ChannelID    uuid.UUID             `gorm:"type:char(36);not null;primary_key"`
+	Level        ChannelSubscribeLevel `gorm:"type:int;not null"`
+}
+
+// TableName InvitationChannel構造体のテーブル名
+func (*InvitationChannel) TableName() string {
+	return "invitation_channels"
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestInvitation_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "invitations", (&Invitation{}).TableName())
+}
+
+func TestInvitation_IsAvailable(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+
+	assert.True(t, (&Invitation{}).IsAvailable(now))
+	assert.True(t, (&Invitation{MaxUses: 2, Uses: 1}).IsAvailable(now))
+	assert.False(t, (&Invitation{MaxUses: 2, Uses: 2}).IsAvailable(now))
+	assert.True(t, (&Invitation{ExpiresAt: optional.TimeFrom(now.Add(time.Minute))}).IsAvailable(now))
+	assert.False(t, (&Invitation{ExpiresAt: optional.TimeFrom(now)}).IsAvailable(now))
+}
+
+func TestInvitation_GroupIDs(t *testing.T) {
+	t.Parallel()
+	id1 := uuid.Must(uuid.NewV4())
+	id2 := uuid.Must(uuid.NewV4())
+	i := &Invitation{Groups: []*InvitationGroup{{GroupID: id1}, {GroupID: id2}}}
+	assert.Equal(t, []uuid.UUID{id1, id2}, i.GroupIDs())
+}
+
+func TestInvitationGroup_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "invitation_groups", (&InvitationGroup{}).TableName())
+}
+
+func TestInvitationChannel_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "invitation_channels", (&InvitationChannel{}).TableName())
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// KeywordAlert ユーザーのキーワード通知設定
+type KeywordAlert struct {
+	ID     uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID uuid.UUID `gorm:"type:char(36);not null"`
+	// Keyword キーワードまたは正規表現
+	Keyword string `gorm:"type:varchar(100);not null"`
+	// IsRegexp Keywordが正規表現かどうか
+	IsRegexp  bool      `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName KeywordAlert構造体のテーブル名
+func (*KeywordAlert) TableName() string {
+	return "keyword_alerts"
+}
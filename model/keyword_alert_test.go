@@ -0,0 +1,11 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeywordAlert_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "keyword_alerts", (&KeywordAlert{}).TableName())
+}
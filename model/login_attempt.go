@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// LoginLockKind ログイン試行の失敗を集計する単位
+type LoginLockKind string
+
+const (
+	// LoginLockKindUser アカウント単位
+	LoginLockKindUser LoginLockKind = "user"
+	// LoginLockKindIP IPアドレス単位
+	LoginLockKindIP LoginLockKind = "ip"
+)
+
+// Valid 有効な値かどうか
+func (k LoginLockKind) Valid() bool {
+	return k == LoginLockKindUser || k == LoginLockKindIP
+}
+
+// LoginLock ログイン試行の失敗回数とロック状態
+type LoginLock struct {
+	Kind LoginLockKind `gorm:"type:varchar(10);not null;primary_key"`
+	// Key Kindがuserの場合はユーザーUUID、ipの場合はIPアドレス
+	Key string `gorm:"type:varchar(64);not null;primary_key"`
+	// Failures 連続失敗回数
+	Failures     int           `gorm:"type:int;not null;default:0"`
+	LastFailedAt time.Time     `gorm:"precision:6"`
+	LockedUntil  optional.Time `gorm:"precision:6"`
+}
+
+// TableName LoginLock構造体のテーブル名
+func (*LoginLock) TableName() string {
+	return "login_locks"
+}
+
+// IsLocked 指定した時刻にロックされているかどうか
+func (l *LoginLock) IsLocked(now time.Time) bool {
+	return l.LockedUntil.Valid && now.Before(l.LockedUntil.Time)
+}
+
+// 失敗したログイン試行の理由
+const (
+	LoginFailureReasonUnknownUser    = "unknown_user"
+	LoginFailureReasonWrongPassword  = "wrong_password"
+	LoginFailureReasonWrongTwoFactor = "wrong_two_factor"
+	LoginFailureReasonLocked         = "locked"
+)
+
+// LoginAuditLog 失敗したログイン試行の監査ログ
+type LoginAuditLog struct {
+	ID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// UserID 対象ユーザーUUID (存在しないユーザー名の場合はNULL)
+	UserID optional.UUID `gorm:"type:char(36);index"`
+	// Name 試行されたユーザー名
+	Name string `gorm:"type:varchar(32);not null"`
+	IP   string `gorm:"type:varchar(64);not null;index"`
+	// Method ログイン方法 (v1, v3, oauth2_password)
+	Method string `gorm:"type:varchar(20);not null"`
+	// Reason 失敗理由
+	Reason    string    `gorm:"type:varchar(20);not null"`
+	CreatedAt time.Time `gorm:"precision:6;index"`
+}
+
+// TableName LoginAuditLog構造体のテーブル名
+func (*LoginAuditLog) TableName() string {
+	return "login_audit_logs"
+}
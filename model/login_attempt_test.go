@@ -0,0 +1,34 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestLoginLock_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "login_locks", (&LoginLock{}).TableName())
+}
+
+func TestLoginAuditLog_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "login_audit_logs", (&LoginAuditLog{}).TableName())
+}
+
+func TestLoginLockKind_Valid(t *testing.T) {
+	t.Parallel()
+	assert.True(t, LoginLockKindUser.Valid())
+	assert.True(t, LoginLockKindIP.Valid())
+	assert.False(t, LoginLockKind("").Valid())
+	assert.False(t, LoginLockKind("name").Valid())
+}
+
+func TestLoginLock_IsLocked(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	assert.False(t, (&LoginLock{}).IsLocked(now))
+	assert.True(t, (&LoginLock{LockedUntil: optional.TimeFrom(now.Add(time.Minute))}).IsLocked(now))
+	assert.False(t, (&LoginLock{LockedUntil: optional.TimeFrom(now.Add(-time.Minute))}).IsLocked(now))
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/validator"
 	"strings"
 	"time"
@@ -22,6 +23,16 @@ import (
 // /と"は使えません。
 type AccessScope string
 
+// OpenID Connectのスコープ
+//
+// これらのスコープはAPIへのアクセス権限を持ちません。
+const (
+	// ScopeOpenID IDトークンを要求するスコープ
+	ScopeOpenID AccessScope = "openid"
+	// ScopeProfile IDトークン・UserInfoでユーザーのプロフィールを要求するスコープ
+	ScopeProfile AccessScope = "profile"
+)
+
 // AccessScopes AccessScopeのセット
 type AccessScopes map[AccessScope]struct{}
 
@@ -109,7 +120,7 @@ func (arr AccessScopes) StringArray() (r []string) {
 // Validate github.com/go-ozzo/ozzo-validation.Validatable 実装
 func (arr AccessScopes) Validate() error {
 	// TODO カスタムスコープに対応
-	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In("read", "write", "manage_bot")))
+	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In("read", "write", "manage_bot", string(ScopeOpenID), string(ScopeProfile))))
 }
 
 // OAuth2Authorize OAuth2 認可データの構造体
@@ -171,9 +182,19 @@ type OAuth2Client struct {
 	Secret       string       `gorm:"type:varchar(36)"`
 	RedirectURI  string       `gorm:"type:text"`
 	Scopes       AccessScopes `gorm:"type:text"`
-	CreatedAt    time.Time    `gorm:"precision:6"`
-	UpdatedAt    time.Time    `gorm:"precision:6"`
-	DeletedAt    *time.Time   `gorm:"precision:6"`
+	// RequirePKCE 認可リクエストでPKCE(S256)を必須にするかどうか
+	RequirePKCE bool `gorm:"type:boolean;not null;default:false"`
+	// Pending 管理者の承認待ちかどうか
+	//
+	// 承認待ちのクライアントは認可・トークン発行に使用できません。
+	Pending bool `gorm:"type:boolean;not null;default:false"`
+	// RegistrationAccessToken クライアント設定エンドポイント(RFC 7592)のアクセストークン
+	//
+	// 動的クライアント登録(RFC 7591)で登録されたクライアントのみ持ちます。
+	RegistrationAccessToken string     `gorm:"type:varchar(36);not null;default:''"`
+	CreatedAt               time.Time  `gorm:"precision:6"`
+	UpdatedAt               time.Time  `gorm:"precision:6"`
+	DeletedAt               *time.Time `gorm:"precision:6"`
 }
 
 // TableName OAuth2Clientのテーブル名
@@ -192,6 +213,32 @@ func (c *OAuth2Client) GetAvailableScopes(request AccessScopes) (result AccessSc
 	return
 }
 
+// IsDynamicallyRegistered 動的クライアント登録で登録されたクライアントかどうか
+func (c *OAuth2Client) IsDynamicallyRegistered() bool {
+	return len(c.RegistrationAccessToken) > 0
+}
+
+// OAuth2InitialAccessToken 動的クライアント登録のための初期アクセストークン (RFC 7591)
+//
+// 1回のみ使用できます。
+type OAuth2InitialAccessToken struct {
+	Token string `gorm:"type:varchar(36);primary_key"`
+	// CreatorID 発行者のユーザーID (このトークンで登録されたクライアントの開発者になります)
+	CreatorID uuid.UUID `gorm:"type:char(36);not null"`
+	ExpiresAt time.Time `gorm:"precision:6"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName OAuth2InitialAccessTokenのテーブル名
+func (*OAuth2InitialAccessToken) TableName() string {
+	return "oauth2_initial_access_tokens"
+}
+
+// IsExpired 有効期限が切れているかどうか
+func (t *OAuth2InitialAccessToken) IsExpired() bool {
+	return t.ExpiresAt.Before(time.Now())
+}
+
 // OAuth2Token OAuth2 トークンの構造体
 type OAuth2Token struct {
 	ID             uuid.UUID    `gorm:"type:char(36);primary_key"`
@@ -203,8 +250,14 @@ type OAuth2Token struct {
 	RefreshEnabled bool         `gorm:"type:boolean;default:false"`
 	Scopes         AccessScopes `gorm:"type:text"`
 	ExpiresIn      int
-	CreatedAt      time.Time  `gorm:"precision:6"`
-	DeletedAt      *time.Time `gorm:"precision:6"`
+	// Name パーソナルアクセストークンの名前
+	//
+	// OAuth2クライアントによって発行されたトークンの場合は空です。
+	Name string `gorm:"type:varchar(32);not null;default:''"`
+	// LastUsedAt パーソナルアクセストークンの最終使用日時
+	LastUsedAt optional.Time `gorm:"precision:6"`
+	CreatedAt  time.Time     `gorm:"precision:6"`
+	DeletedAt  *time.Time    `gorm:"precision:6"`
 }
 
 // TableName OAuth2Tokenのテーブル名
@@ -225,7 +278,17 @@ func (t *OAuth2Token) GetAvailableScopes(request AccessScopes) (result AccessSco
 
 // IsExpired 有効期限が切れているかどうか
 func (t *OAuth2Token) IsExpired() bool {
-	return t.CreatedAt.Add(time.Duration(t.ExpiresIn) * time.Second).Before(time.Now())
+	return t.ExpiresAt().Before(time.Now())
+}
+
+// IsPersonal パーソナルアクセストークンかどうか
+func (t *OAuth2Token) IsPersonal() bool {
+	return len(t.ClientID) == 0 && len(t.Name) > 0
+}
+
+// ExpiresAt 有効期限
+func (t *OAuth2Token) ExpiresAt() time.Time {
+	return t.CreatedAt.Add(time.Duration(t.ExpiresIn) * time.Second)
 }
 
 // IsRefreshEnabled リフレッシュトークンが有効かどうか
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// OAuth2DeviceAuthorizationStatus デバイス認可の状態
+type OAuth2DeviceAuthorizationStatus string
+
+const (
+	// OAuth2DeviceAuthorizationPending ユーザーの承認待ち
+	OAuth2DeviceAuthorizationPending OAuth2DeviceAuthorizationStatus = "pending"
+	// OAuth2DeviceAuthorizationApproved ユーザーが承認した
+	OAuth2DeviceAuthorizationApproved OAuth2DeviceAuthorizationStatus = "approved"
+	// OAuth2DeviceAuthorizationDenied ユーザーが拒否した
+	OAuth2DeviceAuthorizationDenied OAuth2DeviceAuthorizationStatus = "denied"
+)
+
+// OAuth2DeviceAuthorization OAuth2 デバイス認可データの構造体 (RFC 8628)
+type OAuth2DeviceAuthorization struct {
+	DeviceCode string                          `gorm:"type:varchar(36);primary_key"`
+	UserCode   string                          `gorm:"type:varchar(16);unique"`
+	ClientID   string                          `gorm:"type:char(36)"`
+	UserID     uuid.UUID                       `gorm:"type:char(36)"`
+	Status     OAuth2DeviceAuthorizationStatus `gorm:"type:varchar(16)"`
+	Scopes     AccessScopes                    `gorm:"type:text"`
+	// PollingInterval ポーリングの最小間隔(秒)
+	PollingInterval int
+	ExpiresIn       int
+	LastPolledAt    optional.Time `gorm:"precision:6"`
+	CreatedAt       time.Time     `gorm:"precision:6"`
+}
+
+// TableName OAuth2DeviceAuthorizationのテーブル名
+func (*OAuth2DeviceAuthorization) TableName() string {
+	return "oauth2_device_authorizations"
+}
+
+// IsExpired 有効期限が切れているかどうか
+func (d *OAuth2DeviceAuthorization) IsExpired() bool {
+	return d.CreatedAt.Add(time.Duration(d.ExpiresIn) * time.Second).Before(time.Now())
+}
+
+// IsPolledTooFrequently 前回のポーリングからPollingInterval秒経過していないかどうか
+func (d *OAuth2DeviceAuthorization) IsPolledTooFrequently(now time.Time) bool {
+	return d.LastPolledAt.Valid && now.Before(d.LastPolledAt.Time.Add(time.Duration(d.PollingInterval)*time.Second))
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestOAuth2DeviceAuthorization_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "oauth2_device_authorizations", (&OAuth2DeviceAuthorization{}).TableName())
+}
+
+func TestOAuth2DeviceAuthorization_IsExpired(t *testing.T) {
+	t.Parallel()
+	d := &OAuth2DeviceAuthorization{ExpiresIn: 600, CreatedAt: time.Now()}
+	assert.False(t, d.IsExpired())
+	d.CreatedAt = time.Now().Add(-11 * time.Minute)
+	assert.True(t, d.IsExpired())
+}
+
+func TestOAuth2DeviceAuthorization_IsPolledTooFrequently(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2020, 6, 1, 8, 30, 0, 0, time.UTC)
+	d := &OAuth2DeviceAuthorization{PollingInterval: 5}
+	assert.False(t, d.IsPolledTooFrequently(now))
+	d.LastPolledAt = optional.TimeFrom(now.Add(-3 * time.Second))
+	assert.True(t, d.IsPolledTooFrequently(now))
+	d.LastPolledAt = optional.TimeFrom(now.Add(-5 * time.Second))
+	assert.False(t, d.IsPolledTooFrequently(now))
+}
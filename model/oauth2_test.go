@@ -253,3 +253,11 @@ func TestOAuth2Token_IsRefreshEnabled(t *testing.T) {
 	assert.False(t, (&OAuth2Token{RefreshToken: "test"}).IsRefreshEnabled())
 	assert.True(t, (&OAuth2Token{RefreshToken: "test", RefreshEnabled: true}).IsRefreshEnabled())
 }
+
+func TestOAuth2Token_IsPersonal(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, (&OAuth2Token{Name: "script"}).IsPersonal())
+	assert.False(t, (&OAuth2Token{ClientID: "client", Name: "script"}).IsPersonal())
+	assert.False(t, (&OAuth2Token{}).IsPersonal())
+}
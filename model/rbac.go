@@ -1,12 +1,18 @@
 package model
 
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
 // UserRole ユーザーロール構造体
 type UserRole struct {
-	Name         string            `gorm:"type:varchar(30);not null;primary_key"`
-	Oauth2Scope  bool              `gorm:"type:boolean;not null;default:false"`
-	Inheritances []RoleInheritance `gorm:"association_autoupdate:false;association_autocreate:false;foreignkey:Role"`
-	Permissions  []RolePermission  `gorm:"association_autoupdate:false;association_autocreate:false;foreignkey:Role"`
-	System       bool              `gorm:"type:boolean;not null;default:false"`
+	Name              string            `gorm:"type:varchar(30);not null;primary_key"`
+	Oauth2Scope       bool              `gorm:"type:boolean;not null;default:false"`
+	Inheritances      []RoleInheritance `gorm:"association_autoupdate:false;association_autocreate:false;foreignkey:Role"`
+	Permissions       []RolePermission  `gorm:"association_autoupdate:false;association_autocreate:false;foreignkey:Role"`
+	System            bool              `gorm:"type:boolean;not null;default:false"`
+	TwoFactorRequired bool              `gorm:"type:boolean;not null;default:false"`
 }
 
 // TableName UserDefinedRole構造体のテーブル名
@@ -35,3 +41,18 @@ type RolePermission struct {
 func (*RolePermission) TableName() string {
 	return "user_role_permissions"
 }
+
+// ChannelRoleBinding チャンネルスコープのロール割り当て構造体
+//
+// 割り当てられたロールは、チャンネルとその子孫チャンネルでのみ有効です。
+type ChannelRoleBinding struct {
+	ChannelID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	Role      string    `gorm:"type:varchar(30);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName ChannelRoleBinding構造体のテーブル名
+func (*ChannelRoleBinding) TableName() string {
+	return "channel_role_bindings"
+}
@@ -19,3 +19,8 @@ func TestRolePermission_TableName(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "user_role_permissions", (&RolePermission{}).TableName())
 }
+
+func TestChannelRoleBinding_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "channel_role_bindings", (&ChannelRoleBinding{}).TableName())
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// SAMLUsedAssertion 使用済みのSAMLアサーション
+//
+// 同じアサーションによるログインの再送 (リプレイ攻撃) を防ぐため、有効期限まで保持します。
+type SAMLUsedAssertion struct {
+	// ID アサーションのID属性
+	ID        string    `gorm:"type:varchar(255);not null;primary_key"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+}
+
+// TableName SAMLUsedAssertion構造体のテーブル名
+func (*SAMLUsedAssertion) TableName() string {
+	return "saml_used_assertions"
+}
@@ -9,11 +9,15 @@ import (
 
 // SessionRecord GORM用Session構造体
 type SessionRecord struct {
-	Token       string    `gorm:"type:varchar(50);primary_key"`
-	ReferenceID uuid.UUID `gorm:"type:char(36);unique"`
-	UserID      uuid.UUID `gorm:"type:varchar(36);index"`
-	Data        []byte    `gorm:"type:longblob"`
-	Created     time.Time `gorm:"precision:6"`
+	Token        string    `gorm:"type:varchar(50);primary_key"`
+	ReferenceID  uuid.UUID `gorm:"type:char(36);unique"`
+	UserID       uuid.UUID `gorm:"type:varchar(36);index"`
+	Data         []byte    `gorm:"type:longblob"`
+	UserAgent    string    `gorm:"type:varchar(255);not null;default:''"`
+	IP           string    `gorm:"type:varchar(45);not null;default:''"`
+	LoginMethod  string    `gorm:"type:varchar(30);not null;default:''"`
+	LastActivity time.Time `gorm:"precision:6"`
+	Created      time.Time `gorm:"precision:6"`
 }
 
 // TableName SessionRecordのテーブル名
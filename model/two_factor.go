@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// UserTOTP ユーザーのTOTP(二要素認証)設定
+type UserTOTP struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Secret Base32エンコードされたシークレット
+	Secret string `gorm:"type:varchar(64);not null"`
+	// Enabled 有効かどうか (falseの場合は登録手続き中)
+	Enabled bool `gorm:"type:boolean;not null;default:false"`
+	// LastStep 最後に使用されたコードのタイムステップ
+	LastStep  int64     `gorm:"type:bigint;not null;default:0"`
+	CreatedAt time.Time `gorm:"precision:6"`
+	UpdatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName UserTOTP構造体のテーブル名
+func (*UserTOTP) TableName() string {
+	return "user_totps"
+}
+
+// UserRecoveryCode 二要素認証のリカバリーコード
+type UserRecoveryCode struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// CodeHash リカバリーコードのSHA-256ハッシュ値
+	CodeHash  string    `gorm:"type:char(64);not null;primary_key"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName UserRecoveryCode構造体のテーブル名
+func (*UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUserTOTP_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_totps", (&UserTOTP{}).TableName())
+}
+
+func TestUserRecoveryCode_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_recovery_codes", (&UserRecoveryCode{}).TableName())
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// UserBlockType ユーザーブロックの種類
+type UserBlockType string
+
+const (
+	// UserBlockTypeBlock ブロック
+	//
+	// DMの受信を拒否し、相手からのメンション・スタンプ等の通知を受け取りません。
+	UserBlockTypeBlock UserBlockType = "block"
+	// UserBlockTypeMute ミュート
+	//
+	// 相手からのメンション・スタンプ等の通知のみを受け取りません。
+	UserBlockTypeMute UserBlockType = "mute"
+)
+
+// Valid 有効なブロックの種類かどうか
+func (t UserBlockType) Valid() bool {
+	switch t {
+	case UserBlockTypeBlock, UserBlockTypeMute:
+		return true
+	default:
+		return false
+	}
+}
+
+// UserBlock ユーザーのブロック・ミュート
+type UserBlock struct {
+	UserID   uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	TargetID uuid.UUID `gorm:"type:char(36);not null;primary_key;index"`
+	// Type ブロックの種類
+	Type      UserBlockType `gorm:"type:varchar(10);not null"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+}
+
+// TableName UserBlock構造体のテーブル名
+func (*UserBlock) TableName() string {
+	return "user_blocks"
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUserBlock_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_blocks", (&UserBlock{}).TableName())
+}
+
+func TestUserBlockType_Valid(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, UserBlockTypeBlock.Valid())
+	assert.True(t, UserBlockTypeMute.Valid())
+	assert.False(t, UserBlockType("").Valid())
+	assert.False(t, UserBlockType("ban").Valid())
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// PresenceMode ユーザーが明示的に設定するプレゼンスモード
+type PresenceMode string
+
+const (
+	// PresenceModeAuto 接続状態に応じて自動で決定
+	PresenceModeAuto PresenceMode = "auto"
+	// PresenceModeAway 離席中
+	PresenceModeAway PresenceMode = "away"
+	// PresenceModeBusy 取り込み中
+	PresenceModeBusy PresenceMode = "busy"
+	// PresenceModeInvisible オフライン表示
+	PresenceModeInvisible PresenceMode = "invisible"
+)
+
+// Valid 有効なプレゼンスモードかどうか
+func (m PresenceMode) Valid() bool {
+	switch m {
+	case PresenceModeAuto, PresenceModeAway, PresenceModeBusy, PresenceModeInvisible:
+		return true
+	default:
+		return false
+	}
+}
+
+// Presence 他のユーザーから見えるプレゼンス
+type Presence string
+
+const (
+	// PresenceOnline オンライン
+	PresenceOnline Presence = "online"
+	// PresenceAway 離席中
+	PresenceAway Presence = "away"
+	// PresenceBusy 取り込み中
+	PresenceBusy Presence = "busy"
+	// PresenceOffline オフライン
+	PresenceOffline Presence = "offline"
+)
+
+// UserStatus ユーザーのカスタムステータス
+type UserStatus struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Text ステータスメッセージ
+	Text string `gorm:"type:varchar(100);not null;default:''"`
+	// StampID ステータスに表示するスタンプのID
+	StampID optional.UUID `gorm:"type:char(36)"`
+	// ExpiresAt ステータスメッセージとスタンプの有効期限
+	ExpiresAt optional.Time `gorm:"precision:6"`
+	// Mode プレゼンスモード
+	Mode      PresenceMode `gorm:"type:varchar(10);not null;default:'auto'"`
+	UpdatedAt time.Time    `gorm:"precision:6"`
+}
+
+// TableName UserStatus構造体のテーブル名
+func (*UserStatus) TableName() string {
+	return "user_statuses"
+}
+
+// IsExpired 指定した時刻にステータスメッセージが期限切れかどうか
+func (s *UserStatus) IsExpired(now time.Time) bool {
+	return s.ExpiresAt.Valid && !now.Before(s.ExpiresAt.Time)
+}
+
+// HasMessage ステータスメッセージかスタンプが設定されているかどうか
+func (s *UserStatus) HasMessage() bool {
+	return len(s.Text) > 0 || s.StampID.Valid
+}
+
+// Presence 接続状態から他のユーザーに見えるプレゼンスを返します
+func (s *UserStatus) Presence(online bool) Presence {
+	if !online {
+		return PresenceOffline
+	}
+	switch s.Mode {
+	case PresenceModeAway:
+		return PresenceAway
+	case PresenceModeBusy:
+		return PresenceBusy
+	case PresenceModeInvisible:
+		return PresenceOffline
+	default:
+		return PresenceOnline
+	}
+}
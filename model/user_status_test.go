@@ -0,0 +1,60 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestUserStatus_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_statuses", (&UserStatus{}).TableName())
+}
+
+func TestPresenceMode_Valid(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, PresenceModeAuto.Valid())
+	assert.True(t, PresenceModeInvisible.Valid())
+	assert.False(t, PresenceMode("").Valid())
+	assert.False(t, PresenceMode("online").Valid())
+}
+
+func TestUserStatus_IsExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	assert.False(t, (&UserStatus{}).IsExpired(now))
+	assert.False(t, (&UserStatus{ExpiresAt: optional.TimeFrom(now.Add(time.Minute))}).IsExpired(now))
+	assert.True(t, (&UserStatus{ExpiresAt: optional.TimeFrom(now)}).IsExpired(now))
+}
+
+func TestUserStatus_HasMessage(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, (&UserStatus{}).HasMessage())
+	assert.True(t, (&UserStatus{Text: "lunch"}).HasMessage())
+	assert.True(t, (&UserStatus{StampID: optional.UUIDFrom(uuid.Must(uuid.NewV4()))}).HasMessage())
+}
+
+func TestUserStatus_Presence(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		mode   PresenceMode
+		online bool
+		want   Presence
+	}{
+		{PresenceModeAuto, true, PresenceOnline},
+		{PresenceModeAuto, false, PresenceOffline},
+		{PresenceModeAway, true, PresenceAway},
+		{PresenceModeBusy, true, PresenceBusy},
+		{PresenceModeBusy, false, PresenceOffline},
+		{PresenceModeInvisible, true, PresenceOffline},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, (&UserStatus{Mode: c.mode}).Presence(c.online), "%s %v", c.mode, c.online)
+	}
+}
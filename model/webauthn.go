@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// WebAuthnCredential ユーザーのWebAuthn認証情報(パスキー)
+type WebAuthnCredential struct {
+	ID     uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID uuid.UUID `gorm:"type:char(36);not null;index"`
+	// Name 表示名
+	Name string `gorm:"type:varchar(32);not null"`
+	// CredentialID base64urlエンコードされたCredential ID
+	CredentialID string `gorm:"type:varchar(255);not null;unique"`
+	// PublicKey COSE形式の公開鍵
+	PublicKey []byte `gorm:"type:blob;not null"`
+	// SignCount 署名カウンタ
+	SignCount uint32 `gorm:"type:int unsigned;not null;default:0"`
+	// AAGUID 認証器のAAGUID
+	AAGUID     []byte        `gorm:"type:binary(16)"`
+	LastUsedAt optional.Time `gorm:"precision:6"`
+	CreatedAt  time.Time     `gorm:"precision:6"`
+}
+
+// TableName WebAuthnCredential構造体のテーブル名
+func (*WebAuthnCredential) TableName() string {
+	return "user_webauthn_credentials"
+}
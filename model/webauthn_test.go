@@ -0,0 +1,11 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWebAuthnCredential_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_webauthn_credentials", (&WebAuthnCredential{}).TableName())
+}
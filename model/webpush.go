@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// WebPushSubscription Web Pushの購読情報
+type WebPushSubscription struct {
+	ID     uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID uuid.UUID `gorm:"type:char(36);not null;index"`
+	// Endpoint プッシュサービスのエンドポイントURL
+	Endpoint string `gorm:"type:text;not null"`
+	// P256dh クライアントのP-256公開鍵 (base64url)
+	P256dh string `gorm:"type:varchar(100);not null"`
+	// Auth 認証シークレット (base64url)
+	Auth      string    `gorm:"type:varchar(50);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName WebPushSubscription構造体のテーブル名
+func (*WebPushSubscription) TableName() string {
+	return "webpush_subscriptions"
+}
@@ -0,0 +1,11 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWebPushSubscription_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "webpush_subscriptions", (&WebPushSubscription{}).TableName())
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// UpdateDoNotDisturbSettingArgs おやすみモード設定更新引数
+type UpdateDoNotDisturbSettingArgs struct {
+	Enabled        optional.Bool
+	Timezone       optional.String
+	Schedule       model.DoNotDisturbSchedule
+	AllowedUsers   model.UUIDs
+	UrgentChannels model.UUIDs
+}
+
+// DoNotDisturbRepository おやすみモード設定リポジトリ
+type DoNotDisturbRepository interface {
+	// GetDoNotDisturbSetting 指定したユーザーのおやすみモード設定を取得します
+	//
+	// 成功した場合、設定とnilを返します。設定が存在しない場合は初期設定を返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	GetDoNotDisturbSetting(userID uuid.UUID) (*model.DoNotDisturbSetting, error)
+	// GetDoNotDisturbSettings 指定したユーザーのおやすみモード設定を取得します
+	//
+	// 成功した場合、ユーザーIDをキーとした設定のマップとnilを返します。設定が存在しないユーザーは含まれません。
+	// DBによるエラーを返すことがあります。
+	GetDoNotDisturbSettings(userIDs set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error)
+	// UpdateDoNotDisturbSetting 指定したユーザーのおやすみモード設定を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateDoNotDisturbSetting(userID uuid.UUID, args UpdateDoNotDisturbSettingArgs) error
+}
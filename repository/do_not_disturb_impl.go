@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// GetDoNotDisturbSetting implements DoNotDisturbRepository interface.
+func (repo *GormRepository) GetDoNotDisturbSetting(userID uuid.UUID) (*model.DoNotDisturbSetting, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	var s model.DoNotDisturbSetting
+	if err := repo.db.First(&s, &model.DoNotDisturbSetting{UserID: userID}).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return defaultDoNotDisturbSetting(userID), nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetDoNotDisturbSettings implements DoNotDisturbRepository interface.
+func (repo *GormRepository) GetDoNotDisturbSettings(userIDs set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error) {
+	result := make(map[uuid.UUID]*model.DoNotDisturbSetting)
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var tmp []*model.DoNotDisturbSetting
+	if err := repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&tmp).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range tmp {
+		result[s.UserID] = s
+	}
+	return result, nil
+}
+
+// UpdateDoNotDisturbSetting implements DoNotDisturbRepository interface.
+func (repo *GormRepository) UpdateDoNotDisturbSetting(userID uuid.UUID, args UpdateDoNotDisturbSettingArgs) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	if args.Timezone.Valid {
+		if _, err := time.LoadLocation(args.Timezone.String); err != nil || len(args.Timezone.String) == 0 {
+			return ArgError("args.Timezone", "invalid timezone")
+		}
+	}
+	if args.Schedule != nil {
+		if err := args.Schedule.Validate(); err != nil {
+			return ArgError("args.Schedule", err.Error())
+		}
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var s model.DoNotDisturbSetting
+		if err := tx.First(&s, &model.DoNotDisturbSetting{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = *defaultDoNotDisturbSetting(userID)
+		}
+
+		if args.Enabled.Valid {
+			s.Enabled = args.Enabled.Bool
+		}
+		if args.Timezone.Valid {
+			s.Timezone = args.Timezone.String
+		}
+		if args.Schedule != nil {
+			s.Schedule = args.Schedule
+		}
+		if args.AllowedUsers != nil {
+			s.AllowedUsers = args.AllowedUsers
+		}
+		if args.UrgentChannels != nil {
+			s.UrgentChannels = args.UrgentChannels
+		}
+		return tx.Save(&s).Error
+	})
+}
+
+func defaultDoNotDisturbSetting(userID uuid.UUID) *model.DoNotDisturbSetting {
+	return &model.DoNotDisturbSetting{
+		UserID:         userID,
+		Enabled:        false,
+		Timezone:       "UTC",
+		Schedule:       model.DoNotDisturbSchedule{},
+		AllowedUsers:   model.UUIDs{},
+		UrgentChannels: model.UUIDs{},
+	}
+}
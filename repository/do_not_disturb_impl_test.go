@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_GetDoNotDisturbSetting(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.GetDoNotDisturbSetting(uuid.Nil)
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		s, err := repo.GetDoNotDisturbSetting(user.GetID())
+		if assert.NoError(t, err) {
+			assert.False(t, s.Enabled)
+			assert.Equal(t, "UTC", s.Timezone)
+			assert.Len(t, s.Schedule, 0)
+		}
+	})
+}
+
+func TestRepositoryImpl_UpdateDoNotDisturbSetting(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.UpdateDoNotDisturbSetting(uuid.Nil, UpdateDoNotDisturbSettingArgs{}), ErrNilID.Error())
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		assert.True(t, IsArgError(repo.UpdateDoNotDisturbSetting(user.GetID(), UpdateDoNotDisturbSettingArgs{Timezone: optional.StringFrom("Invalid/Zone")})))
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		assert.True(t, IsArgError(repo.UpdateDoNotDisturbSetting(user.GetID(), UpdateDoNotDisturbSettingArgs{Schedule: model.DoNotDisturbSchedule{{Start: "25:00", End: "07:00"}}})))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+		other := mustMakeUser(t, repo, rand)
+
+		if assert.NoError(t, repo.UpdateDoNotDisturbSetting(user.GetID(), UpdateDoNotDisturbSettingArgs{
+			Timezone:     optional.StringFrom("Asia/Tokyo"),
+			Schedule:     model.DoNotDisturbSchedule{{Weekdays: []time.Weekday{time.Monday}, Start: "22:00", End: "07:00"}},
+			AllowedUsers: model.UUIDs{other.GetID()},
+		})) {
+			s, err := repo.GetDoNotDisturbSetting(user.GetID())
+			if assert.NoError(t, err) {
+				assert.False(t, s.Enabled)
+				assert.Equal(t, "Asia/Tokyo", s.Timezone)
+				assert.Len(t, s.Schedule, 1)
+				assert.ElementsMatch(t, []uuid.UUID{other.GetID()}, s.AllowedUsers)
+			}
+		}
+
+		if assert.NoError(t, repo.UpdateDoNotDisturbSetting(user.GetID(), UpdateDoNotDisturbSettingArgs{Enabled: optional.BoolFrom(true)})) {
+			ss, err := repo.GetDoNotDisturbSettings(set.UUIDSetFromArray([]uuid.UUID{user.GetID(), other.GetID()}))
+			if assert.NoError(t, err) && assert.Len(t, ss, 1) {
+				assert.True(t, ss[user.GetID()].Enabled)
+				assert.Equal(t, "Asia/Tokyo", ss[user.GetID()].Timezone)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// UpdateEmailNotificationSettingArgs メール通知設定更新引数
+type UpdateEmailNotificationSettingArgs struct {
+	Address optional.String
+	Enabled optional.Bool
+	Digest  optional.String
+}
+
+// EmailNotificationRepository メール通知設定リポジトリ
+type EmailNotificationRepository interface {
+	// GetEmailNotificationSetting 指定したユーザーのメール通知設定を取得します
+	//
+	// 成功した場合、設定とnilを返します。設定が存在しない場合は初期設定を返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	GetEmailNotificationSetting(userID uuid.UUID) (*model.EmailNotificationSetting, error)
+	// GetEmailNotificationSettings 指定したユーザーのメール通知設定を取得します
+	//
+	// 成功した場合、ユーザーIDをキーとした設定のマップとnilを返します。設定が存在しないユーザーは含まれません。
+	// DBによるエラーを返すことがあります。
+	GetEmailNotificationSettings(userIDs set.UUID) (map[uuid.UUID]*model.EmailNotificationSetting, error)
+	// GetEmailDigestSettings ダイジェストを受け取る全てのユーザーのメール通知設定を取得します
+	//
+	// 成功した場合、設定の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetEmailDigestSettings() ([]*model.EmailNotificationSetting, error)
+	// UpdateEmailNotificationSetting 指定したユーザーのメール通知設定を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateEmailNotificationSetting(userID uuid.UUID, args UpdateEmailNotificationSettingArgs) error
+	// CreateEmailAddressVerification 指定したユーザーのメール通知先アドレスの確認待ち情報を作成します
+	//
+	// 成功した場合、確認待ち情報とnilを返します。既存の確認待ち情報は置き換えられます。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	CreateEmailAddressVerification(userID uuid.UUID, address string) (*model.EmailAddressVerification, error)
+	// VerifyEmailAddress 指定したトークンの確認待ちアドレスをメール通知先に設定します
+	//
+	// 成功した場合、アドレスを設定したユーザーのIDとnilを返します。
+	// トークンが存在しないか期限切れの場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	VerifyEmailAddress(token string) (uuid.UUID, error)
+	// SetEmailDigestSentAt 指定したユーザーのダイジェストの最終送信日時を設定します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// emailAddressVerificationTTL メール通知先アドレスの確認リンクの有効期間
+const emailAddressVerificationTTL = 24 * time.Hour
+
+// GetEmailNotificationSetting implements EmailNotificationRepository interface.
+func (repo *GormRepository) GetEmailNotificationSetting(userID uuid.UUID) (*model.EmailNotificationSetting, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	var s model.EmailNotificationSetting
+	if err := repo.db.First(&s, &model.EmailNotificationSetting{UserID: userID}).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return defaultEmailNotificationSetting(userID), nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetEmailNotificationSettings implements EmailNotificationRepository interface.
+func (repo *GormRepository) GetEmailNotificationSettings(userIDs set.UUID) (map[uuid.UUID]*model.EmailNotificationSetting, error) {
+	result := make(map[uuid.UUID]*model.EmailNotificationSetting)
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var tmp []*model.EmailNotificationSetting
+	if err := repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&tmp).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range tmp {
+		result[s.UserID] = s
+	}
+	return result, nil
+}
+
+// GetEmailDigestSettings implements EmailNotificationRepository interface.
+func (repo *GormRepository) GetEmailDigestSettings() ([]*model.EmailNotificationSetting, error) {
+	result := make([]*model.EmailNotificationSetting, 0)
+	return result, repo.db.
+		Where("digest <> ? AND address <> ''", model.EmailDigestNone).
+		Find(&result).
+		Error
+}
+
+// UpdateEmailNotificationSetting implements EmailNotificationRepository interface.
+func (repo *GormRepository) UpdateEmailNotificationSetting(userID uuid.UUID, args UpdateEmailNotificationSettingArgs) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	if args.Address.Valid && len(args.Address.String) > 0 {
+		if err := vd.Validate(args.Address.String, vd.RuneLength(1, 254), is.EmailFormat); err != nil {
+			return ArgError("args.Address", "invalid email address")
+		}
+	}
+	if args.Digest.Valid && !model.EmailDigestInterval(args.Digest.String).Valid() {
+		return ArgError("args.Digest", "invalid digest interval")
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var s model.EmailNotificationSetting
+		if err := tx.First(&s, &model.EmailNotificationSetting{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = *defaultEmailNotificationSetting(userID)
+		}
+
+		if args.Address.Valid {
+			s.Address = args.Address.String
+		}
+		if args.Enabled.Valid {
+			s.Enabled = args.Enabled.Bool
+		}
+		if args.Digest.Valid {
+			s.Digest = model.EmailDigestInterval(args.Digest.String)
+		}
+		return tx.Save(&s).Error
+	})
+}
+
+// CreateEmailAddressVerification implements EmailNotificationRepository interface.
+func (repo *GormRepository) CreateEmailAddressVerification(userID uuid.UUID, address string) (*model.EmailAddressVerification, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if err := vd.Validate(address, vd.Required, vd.RuneLength(1, 254), is.EmailFormat); err != nil {
+		return nil, ArgError("address", "invalid email address")
+	}
+
+	v := &model.EmailAddressVerification{
+		UserID:    userID,
+		Address:   address,
+		Token:     random.SecureAlphaNumeric(64),
+		ExpiresAt: time.Now().Add(emailAddressVerificationTTL),
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&model.EmailAddressVerification{}, &model.EmailAddressVerification{UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Create(v).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// VerifyEmailAddress implements EmailNotificationRepository interface.
+func (repo *GormRepository) VerifyEmailAddress(token string) (uuid.UUID, error) {
+	if len(token) == 0 {
+		return uuid.Nil, ErrNotFound
+	}
+
+	var userID uuid.UUID
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var v model.EmailAddressVerification
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&v, &model.EmailAddressVerification{Token: token}).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if v.IsExpired() {
+			return ErrNotFound
+		}
+		if err := tx.Delete(&v).Error; err != nil {
+			return err
+		}
+
+		var s model.EmailNotificationSetting
+		if err := tx.First(&s, &model.EmailNotificationSetting{UserID: v.UserID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = *defaultEmailNotificationSetting(v.UserID)
+		}
+		s.Address = v.Address
+		userID = v.UserID
+		return tx.Save(&s).Error
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// SetEmailDigestSentAt implements EmailNotificationRepository interface.
+func (repo *GormRepository) SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.
+		Model(&model.EmailNotificationSetting{UserID: userID}).
+		UpdateColumn("last_digest_at", sentAt).
+		Error
+}
+
+func defaultEmailNotificationSetting(userID uuid.UUID) *model.EmailNotificationSetting {
+	return &model.EmailNotificationSetting{
+		UserID:  userID,
+		Address: "",
+		Enabled: false,
+		Digest:  model.EmailDigestNone,
+	}
+}
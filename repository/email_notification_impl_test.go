@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_GetEmailNotificationSetting(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.GetEmailNotificationSetting(uuid.Nil)
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		s, err := repo.GetEmailNotificationSetting(user.GetID())
+		if assert.NoError(t, err) {
+			assert.Empty(t, s.Address)
+			assert.False(t, s.Enabled)
+			assert.Equal(t, model.EmailDigestNone, s.Digest)
+		}
+	})
+}
+
+func TestRepositoryImpl_UpdateEmailNotificationSetting(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.UpdateEmailNotificationSetting(uuid.Nil, UpdateEmailNotificationSettingArgs{}), ErrNilID.Error())
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		assert.True(t, IsArgError(repo.UpdateEmailNotificationSetting(user.GetID(), UpdateEmailNotificationSettingArgs{Address: optional.StringFrom("invalid")})))
+	})
+
+	t.Run("invalid digest", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		assert.True(t, IsArgError(repo.UpdateEmailNotificationSetting(user.GetID(), UpdateEmailNotificationSettingArgs{Digest: optional.StringFrom("weekly")})))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		if assert.NoError(t, repo.UpdateEmailNotificationSetting(user.GetID(), UpdateEmailNotificationSettingArgs{
+			Address: optional.StringFrom("test@example.com"),
+			Enabled: optional.BoolFrom(true),
+			Digest:  optional.StringFrom("hourly"),
+		})) {
+			s, err := repo.GetEmailNotificationSetting(user.GetID())
+			if assert.NoError(t, err) {
+				assert.Equal(t, "test@example.com", s.Address)
+				assert.True(t, s.Enabled)
+				assert.Equal(t, model.EmailDigestHourly, s.Digest)
+				assert.False(t, s.LastDigestAt.Valid)
+			}
+
+			ss, err := repo.GetEmailNotificationSettings(set.UUIDSetFromArray([]uuid.UUID{user.GetID()}))
+			if assert.NoError(t, err) {
+				assert.Contains(t, ss, user.GetID())
+			}
+
+			ds, err := repo.GetEmailDigestSettings()
+			if assert.NoError(t, err) {
+				found := false
+				for _, d := range ds {
+					found = found || d.UserID == user.GetID()
+				}
+				assert.True(t, found)
+			}
+		}
+	})
+}
+
+func TestRepositoryImpl_SetEmailDigestSentAt(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.SetEmailDigestSentAt(uuid.Nil, time.Now()), ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		assert.NoError(t, repo.UpdateEmailNotificationSetting(user.GetID(), UpdateEmailNotificationSettingArgs{Digest: optional.StringFrom("daily")}))
+		now := time.Now()
+		if assert.NoError(t, repo.SetEmailDigestSentAt(user.GetID(), now)) {
+			s, err := repo.GetEmailNotificationSetting(user.GetID())
+			if assert.NoError(t, err) && assert.True(t, s.LastDigestAt.Valid) {
+				assert.WithinDuration(t, now, s.LastDigestAt.Time, time.Second)
+			}
+		}
+	})
+}
+
+func TestRepositoryImpl_CreateEmailAddressVerification(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateEmailAddressVerification(uuid.Nil, "test@example.com")
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		_, err := repo.CreateEmailAddressVerification(user.GetID(), "invalid")
+		assert.True(t, IsArgError(err))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		v1, err := repo.CreateEmailAddressVerification(user.GetID(), "old@example.com")
+		if !assert.NoError(t, err) {
+			return
+		}
+		v2, err := repo.CreateEmailAddressVerification(user.GetID(), "new@example.com")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "new@example.com", v2.Address)
+			assert.Len(t, v2.Token, 64)
+			assert.NotEqual(t, v1.Token, v2.Token)
+		}
+
+		// 確認されるまでは通知先に設定されない
+		s, err := repo.GetEmailNotificationSetting(user.GetID())
+		if assert.NoError(t, err) {
+			assert.Empty(t, s.Address)
+		}
+	})
+}
+
+func TestRepositoryImpl_VerifyEmailAddress(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.VerifyEmailAddress("")
+		assert.EqualError(t, err, ErrNotFound.Error())
+		_, err = repo.VerifyEmailAddress("notfound")
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		v, err := repo.CreateEmailAddressVerification(user.GetID(), "old@example.com")
+		require.NoError(t, err)
+		_, err = repo.CreateEmailAddressVerification(user.GetID(), "new@example.com")
+		require.NoError(t, err)
+
+		_, err = repo.VerifyEmailAddress(v.Token)
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		v, err := repo.CreateEmailAddressVerification(user.GetID(), "test@example.com")
+		require.NoError(t, err)
+		require.NoError(t, getDB(repo).Model(v).UpdateColumn("expires_at", time.Now().Add(-time.Minute)).Error)
+
+		_, err = repo.VerifyEmailAddress(v.Token)
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		v, err := repo.CreateEmailAddressVerification(user.GetID(), "test@example.com")
+		require.NoError(t, err)
+
+		userID, err := repo.VerifyEmailAddress(v.Token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, user.GetID(), userID)
+			s, err := repo.GetEmailNotificationSetting(user.GetID())
+			if assert.NoError(t, err) {
+				assert.Equal(t, "test@example.com", s.Address)
+			}
+		}
+
+		// 同じトークンは再利用できない
+		_, err = repo.VerifyEmailAddress(v.Token)
+		assert.EqualError(t, err, ErrNotFound.Error())
+	})
+}
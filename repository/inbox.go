@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+// InboxEntriesQuery GetInboxEntries用クエリ
+type InboxEntriesQuery struct {
+	// Types 取得する項目の種類 (空の場合は全て)
+	Types []model.InboxEntryType
+	// UnreadOnly 未読の項目のみを取得するかどうか
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// InboxRepository インボックスリポジトリ
+type InboxRepository interface {
+	// CreateInboxEntries インボックス項目を一括で作成します
+	//
+	// 成功した場合、新たに作成されたインボックス項目の配列とnilを返します。
+	// 既に同じ項目が存在する場合、その項目は作成されません。
+	// DBによるエラーを返すことがあります。
+	CreateInboxEntries(entries []*model.InboxEntry) ([]*model.InboxEntry, error)
+	// GetInboxEntries 指定したユーザーのインボックス項目を新しい順に取得します
+	//
+	// 成功した場合、インボックス項目の配列と、続きが存在するかどうかとnilを返します。
+	// 削除されたメッセージの項目は含まれません。
+	// DBによるエラーを返すことがあります。
+	GetInboxEntries(userID uuid.UUID, query InboxEntriesQuery) (entries []*model.InboxEntry, more bool, err error)
+	// ReadInboxEntry 指定したユーザーのインボックス項目を既読にします
+	//
+	// 成功した、或いは既に既読だった場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	ReadInboxEntry(userID, entryID uuid.UUID) error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+)
+
+// CreateInboxEntries implements InboxRepository interface.
+func (repo *GormRepository) CreateInboxEntries(entries []*model.InboxEntry) ([]*model.InboxEntry, error) {
+	created := make([]*model.InboxEntry, 0, len(entries))
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, e := range entries {
+			if e.UserID == uuid.Nil || e.MessageID == uuid.Nil || e.ActorID == uuid.Nil {
+				return ErrNilID
+			}
+			if e.ID == uuid.Nil {
+				e.ID = uuid.Must(uuid.NewV4())
+			}
+			var c int
+			if err := tx.
+				Model(&model.InboxEntry{}).
+				Where(&model.InboxEntry{UserID: e.UserID, MessageID: e.MessageID, Type: e.Type, ActorID: e.ActorID}).
+				Count(&c).
+				Error; err != nil {
+				return err
+			}
+			if c > 0 {
+				continue
+			}
+			if err := tx.Create(e).Error; err != nil {
+				if gormutil.IsMySQLDuplicatedRecordErr(err) {
+					continue
+				}
+				return err
+			}
+			created = append(created, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// GetInboxEntries implements InboxRepository interface.
+func (repo *GormRepository) GetInboxEntries(userID uuid.UUID, query InboxEntriesQuery) (entries []*model.InboxEntry, more bool, err error) {
+	entries = make([]*model.InboxEntry, 0)
+	if userID == uuid.Nil {
+		return entries, false, nil
+	}
+
+	tx := repo.db.
+		Joins("INNER JOIN messages ON inbox_entries.message_id = messages.id AND messages.deleted_at IS NULL").
+		Where("inbox_entries.user_id = ?", userID).
+		Order("inbox_entries.created_at DESC")
+	if len(query.Types) > 0 {
+		tx = tx.Where("inbox_entries.type IN (?)", query.Types)
+	}
+	if query.UnreadOnly {
+		tx = tx.Where("inbox_entries.is_read = false")
+	}
+	if query.Offset > 0 {
+		tx = tx.Offset(query.Offset)
+	}
+	if query.Limit > 0 {
+		err = tx.Limit(query.Limit + 1).Find(&entries).Error
+		if len(entries) > query.Limit {
+			return entries[:len(entries)-1], true, err
+		}
+	} else {
+		err = tx.Find(&entries).Error
+	}
+	return entries, false, err
+}
+
+// ReadInboxEntry implements InboxRepository interface.
+func (repo *GormRepository) ReadInboxEntry(userID, entryID uuid.UUID) error {
+	if userID == uuid.Nil || entryID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var e model.InboxEntry
+		if err := tx.First(&e, &model.InboxEntry{ID: entryID, UserID: userID}).Error; err != nil {
+			return convertError(err)
+		}
+		if e.IsRead {
+			return nil
+		}
+		return tx.Model(&e).Update("is_read", true).Error
+	})
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateInboxEntries(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user, channel := setupWithUserAndChannel(t, common)
+
+	actor := mustMakeUser(t, repo, rand)
+	m := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateInboxEntries([]*model.InboxEntry{{Type: model.InboxEntryTypeMention}})
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		entries, err := repo.CreateInboxEntries([]*model.InboxEntry{
+			{UserID: user.GetID(), Type: model.InboxEntryTypeMention, MessageID: m.ID, ActorID: actor.GetID()},
+			{UserID: user.GetID(), Type: model.InboxEntryTypeCitation, MessageID: m.ID, ActorID: actor.GetID()},
+		})
+		if assert.NoError(t, err) {
+			assert.Len(t, entries, 2)
+			assert.NotEmpty(t, entries[0].ID)
+		}
+
+		entries, err = repo.CreateInboxEntries([]*model.InboxEntry{
+			{UserID: user.GetID(), Type: model.InboxEntryTypeMention, MessageID: m.ID, ActorID: actor.GetID()},
+		})
+		if assert.NoError(t, err) {
+			assert.Len(t, entries, 0)
+		}
+	})
+}
+
+func TestRepositoryImpl_GetInboxEntries(t *testing.T) {
+	t.Parallel()
+	repo, _, _, _, channel := setupWithUserAndChannel(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	actor := mustMakeUser(t, repo, rand)
+	m1 := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+	m2 := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+	entries, err := repo.CreateInboxEntries([]*model.InboxEntry{
+		{UserID: user.GetID(), Type: model.InboxEntryTypeMention, MessageID: m1.ID, ActorID: actor.GetID()},
+		{UserID: user.GetID(), Type: model.InboxEntryTypeCitation, MessageID: m2.ID, ActorID: actor.GetID()},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, repo.ReadInboxEntry(user.GetID(), entries[0].ID))
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		r, more, err := repo.GetInboxEntries(uuid.Nil, InboxEntriesQuery{})
+		if assert.NoError(t, err) {
+			assert.Len(t, r, 0)
+			assert.False(t, more)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+
+		r, more, err := repo.GetInboxEntries(user.GetID(), InboxEntriesQuery{})
+		if assert.NoError(t, err) {
+			assert.Len(t, r, 2)
+			assert.False(t, more)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		t.Parallel()
+
+		r, more, err := repo.GetInboxEntries(user.GetID(), InboxEntriesQuery{Limit: 1})
+		if assert.NoError(t, err) {
+			assert.Len(t, r, 1)
+			assert.True(t, more)
+		}
+	})
+
+	t.Run("types", func(t *testing.T) {
+		t.Parallel()
+
+		r, _, err := repo.GetInboxEntries(user.GetID(), InboxEntriesQuery{Types: []model.InboxEntryType{model.InboxEntryTypeCitation}})
+		if assert.NoError(t, err) && assert.Len(t, r, 1) {
+			assert.Equal(t, m2.ID, r[0].MessageID)
+		}
+	})
+
+	t.Run("unread only", func(t *testing.T) {
+		t.Parallel()
+
+		r, _, err := repo.GetInboxEntries(user.GetID(), InboxEntriesQuery{UnreadOnly: true})
+		if assert.NoError(t, err) && assert.Len(t, r, 1) {
+			assert.Equal(t, entries[1].ID, r[0].ID)
+		}
+	})
+}
+
+func TestRepositoryImpl_ReadInboxEntry(t *testing.T) {
+	t.Parallel()
+	repo, _, _, user, channel := setupWithUserAndChannel(t, common)
+
+	actor := mustMakeUser(t, repo, rand)
+	m := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+	entries, err := repo.CreateInboxEntries([]*model.InboxEntry{
+		{UserID: user.GetID(), Type: model.InboxEntryTypeMention, MessageID: m.ID, ActorID: actor.GetID()},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.ReadInboxEntry(uuid.Nil, uuid.Nil), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.ReadInboxEntry(actor.GetID(), entries[0].ID), ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NoError(t, repo.ReadInboxEntry(user.GetID(), entries[0].ID))
+		assert.NoError(t, repo.ReadInboxEntry(user.GetID(), entries[0].ID))
+	})
+}
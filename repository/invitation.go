@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+)
+
+// CreateInvitationArgs 招待リンク作成引数
+type CreateInvitationArgs struct {
+	CreatorID uuid.UUID
+	// Role 登録されるユーザーのロール
+	Role string
+	// MaxUses 最大使用回数 (0の場合は無制限)
+	MaxUses   int
+	ExpiresAt optional.Time
+	// GroupIDs 登録時に追加されるユーザーグループ
+	GroupIDs []uuid.UUID
+	// Channels 登録時に設定されるチャンネルの購読
+	Channels map[uuid.UUID]model.ChannelSubscribeLevel
+}
+
+// InvitationRepository 招待リンクリポジトリ
+type InvitationRepository interface {
+	// CreateInvitation 招待リンクを作成します
+	//
+	// 成功した場合、招待リンクとnilを返します。
+	// 存在しないロールを指定した場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	CreateInvitation(args CreateInvitationArgs) (*model.Invitation, error)
+	// GetInvitation 指定したIDの招待リンクを取得します
+	//
+	// 成功した場合、招待リンクとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetInvitation(id uuid.UUID) (*model.Invitation, error)
+	// GetInvitationByToken 指定したトークンの招待リンクを取得します
+	//
+	// 成功した場合、招待リンクとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetInvitationByToken(token string) (*model.Invitation, error)
+	// GetInvitations 招待リンクを作成日時の降順で取得します
+	//
+	// creatorIDにuuid.Nilを指定した場合、全ての招待リンクを取得します。
+	// 成功した場合、招待リンクの配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetInvitations(creatorID uuid.UUID) ([]*model.Invitation, error)
+	// UseInvitation 招待リンクの使用回数を1増やします
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// 有効期限切れ、または最大使用回数に達している場合、ErrForbiddenを返します。
+	// DBによるエラーを返すことがあります。
+	UseInvitation(id uuid.UUID) error
+	// SignUpWithInvitation 招待リンクを使用してユーザーを作成します
+	//
+	// 招待リンクの使用回数の消費、ユーザーの作成、招待リンクのユーザーグループへの追加を単一のトランザクションで行います。
+	// args.Roleは無視され、招待リンクのロールが使用されます。
+	// 成功した場合、作成されたユーザーとnilを返します。
+	// 招待リンクが存在しなかった場合、ErrNotFoundを返します。
+	// 有効期限切れ、または最大使用回数に達している場合、ErrForbiddenを返します。
+	// 既にNameが使われている場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	SignUpWithInvitation(invitationID uuid.UUID, args CreateUserArgs) (model.UserInfo, error)
+	// DeleteInvitation 招待リンクを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteInvitation(id uuid.UUID) error
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/random"
+	"time"
+)
+
+func invitationPreloads(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Groups").
+		Preload("Channels")
+}
+
+// CreateInvitation implements InvitationRepository interface.
+func (repo *GormRepository) CreateInvitation(args CreateInvitationArgs) (*model.Invitation, error) {
+	if args.CreatorID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	inv := &model.Invitation{
+		ID:        uuid.Must(uuid.NewV4()),
+		Token:     random.SecureAlphaNumeric(32),
+		CreatorID: args.CreatorID,
+		Role:      args.Role,
+		MaxUses:   args.MaxUses,
+		ExpiresAt: args.ExpiresAt,
+		Groups:    make([]*model.InvitationGroup, 0, len(args.GroupIDs)),
+		Channels:  make([]*model.InvitationChannel, 0, len(args.Channels)),
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if len(args.Role) == 0 {
+			return ArgError("args.Role", "Role is required")
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.UserRole{Name: args.Role}); err != nil {
+			return err
+		} else if !ok {
+			return ArgError("args.Role", "the Role doesn't exist")
+		}
+
+		if err := tx.Create(inv).Error; err != nil {
+			return err
+		}
+		for _, gid := range args.GroupIDs {
+			g := &model.InvitationGroup{InvitationID: inv.ID, GroupID: gid}
+			if err := tx.Create(g).Error; err != nil {
+				if gormutil.IsMySQLDuplicatedRecordErr(err) {
+					continue
+				}
+				return err
+			}
+			inv.Groups = append(inv.Groups, g)
+		}
+		for cid, level := range args.Channels {
+			c := &model.InvitationChannel{InvitationID: inv.ID, ChannelID: cid, Level: level}
+			if err := tx.Create(c).Error; err != nil {
+				return err
+			}
+			inv.Channels = append(inv.Channels, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// GetInvitation implements InvitationRepository interface.
+func (repo *GormRepository) GetInvitation(id uuid.UUID) (*model.Invitation, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var inv model.Invitation
+	if err := repo.db.Scopes(invitationPreloads).First(&inv, &model.Invitation{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &inv, nil
+}
+
+// GetInvitationByToken implements InvitationRepository interface.
+func (repo *GormRepository) GetInvitationByToken(token string) (*model.Invitation, error) {
+	if len(token) == 0 {
+		return nil, ErrNotFound
+	}
+	var inv model.Invitation
+	if err := repo.db.Scopes(invitationPreloads).First(&inv, &model.Invitation{Token: token}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &inv, nil
+}
+
+// GetInvitations implements InvitationRepository interface.
+func (repo *GormRepository) GetInvitations(creatorID uuid.UUID) ([]*model.Invitation, error) {
+	result := make([]*model.Invitation, 0)
+	tx := repo.db.Scopes(invitationPreloads)
+	if creatorID != uuid.Nil {
+		tx = tx.Where(&model.Invitation{CreatorID: creatorID})
+	}
+	return result, tx.Order("created_at DESC").Find(&result).Error
+}
+
+// UseInvitation implements InvitationRepository interface.
+func (repo *GormRepository) UseInvitation(id uuid.UUID) error {
+	return useInvitation(repo.db, id)
+}
+
+// useInvitation 招待リンクの使用回数を1増やします
+func useInvitation(tx *gorm.DB, id uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrNotFound
+	}
+	result := tx.
+		Model(&model.Invitation{}).
+		Where("id = ? AND (max_uses = 0 OR uses < max_uses) AND (expires_at IS NULL OR expires_at > ?)", id, time.Now()).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	ok, err := gormutil.RecordExists(tx, &model.Invitation{ID: id})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return ErrForbidden
+}
+
+// SignUpWithInvitation implements InvitationRepository interface.
+func (repo *GormRepository) SignUpWithInvitation(invitationID uuid.UUID, args CreateUserArgs) (model.UserInfo, error) {
+	user, err := repo.newUser(args)
+	if err != nil {
+		return nil, err
+	}
+	var groups []*model.InvitationGroup
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := useInvitation(tx, invitationID); err != nil {
+			return err
+		}
+		var inv model.Invitation
+		if err := tx.Preload("Groups").First(&inv, &model.Invitation{ID: invitationID}).Error; err != nil {
+			return convertError(err)
+		}
+
+		user.Role = inv.Role
+		if err := createUser(tx, user, args.ExternalLogin); err != nil {
+			return err
+		}
+		groups = inv.Groups
+		now := time.Now()
+		for _, g := range groups {
+			if err := tx.Create(&model.UserGroupMember{UserID: user.ID, GroupID: g.GroupID}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.UserGroup{ID: g.GroupID}).UpdateColumn("updated_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo.publishUserCreated(user)
+	for _, g := range groups {
+		repo.hub.Publish(hub.Message{
+			Name: event.UserGroupMemberAdded,
+			Fields: hub.Fields{
+				"group_id": g.GroupID,
+				"user_id":  user.ID,
+			},
+		})
+	}
+	return user, nil
+}
+
+// DeleteInvitation implements InvitationRepository interface.
+func (repo *GormRepository) DeleteInvitation(id uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrNotFound
+	}
+	result := repo.db.Delete(&model.Invitation{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_CreateInvitation(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	g := mustMakeUserGroup(t, repo, random.AlphaNumeric(20), user.GetID())
+	ch := mustMakeChannel(t, repo, rand)
+
+	_, err := repo.CreateInvitation(CreateInvitationArgs{Role: role.User})
+	assert.EqualError(err, ErrNilID.Error())
+
+	_, err = repo.CreateInvitation(CreateInvitationArgs{CreatorID: user.GetID(), Role: "unknown role"})
+	assert.True(IsArgError(err))
+
+	inv, err := repo.CreateInvitation(CreateInvitationArgs{
+		CreatorID: user.GetID(),
+		Role:      role.User,
+		MaxUses:   3,
+		ExpiresAt: optional.TimeFrom(time.Now().Add(time.Hour)),
+		GroupIDs:  []uuid.UUID{g.ID, g.ID},
+		Channels:  map[uuid.UUID]model.ChannelSubscribeLevel{ch.ID: model.ChannelSubscribeLevelMarkAndNotify},
+	})
+	require.NoError(err)
+	assert.NotEmpty(inv.Token)
+
+	inv2, err := repo.GetInvitationByToken(inv.Token)
+	require.NoError(err)
+	assert.Equal(inv.ID, inv2.ID)
+	assert.Equal(3, inv2.MaxUses)
+	assert.Equal([]uuid.UUID{g.ID}, inv2.GroupIDs())
+	if assert.Len(inv2.Channels, 1) {
+		assert.Equal(ch.ID, inv2.Channels[0].ChannelID)
+		assert.Equal(model.ChannelSubscribeLevelMarkAndNotify, inv2.Channels[0].Level)
+	}
+
+	_, err = repo.GetInvitationByToken("")
+	assert.EqualError(err, ErrNotFound.Error())
+	_, err = repo.GetInvitation(uuid.Must(uuid.NewV4()))
+	assert.EqualError(err, ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_GetInvitations(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user1 := mustMakeUser(t, repo, rand)
+	user2 := mustMakeUser(t, repo, rand)
+	i1, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: user1.GetID(), Role: role.User})
+	require.NoError(err)
+	i2, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: user1.GetID(), Role: role.User})
+	require.NoError(err)
+	_, err = repo.CreateInvitation(CreateInvitationArgs{CreatorID: user2.GetID(), Role: role.User})
+	require.NoError(err)
+
+	invs, err := repo.GetInvitations(user1.GetID())
+	require.NoError(err)
+	if assert.Len(invs, 2) {
+		assert.Equal(i2.ID, invs[0].ID)
+		assert.Equal(i1.ID, invs[1].ID)
+	}
+
+	invs, err = repo.GetInvitations(uuid.Nil)
+	require.NoError(err)
+	assert.True(len(invs) >= 3)
+}
+
+func TestRepositoryImpl_UseInvitation(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	assert.EqualError(repo.UseInvitation(uuid.Must(uuid.NewV4())), ErrNotFound.Error())
+
+	t.Run("max uses", func(t *testing.T) {
+		t.Parallel()
+		inv, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: user.GetID(), Role: role.User, MaxUses: 2})
+		require.NoError(err)
+
+		assert.NoError(repo.UseInvitation(inv.ID))
+		assert.NoError(repo.UseInvitation(inv.ID))
+		assert.EqualError(repo.UseInvitation(inv.ID), ErrForbidden.Error())
+
+		inv, err = repo.GetInvitation(inv.ID)
+		require.NoError(err)
+		assert.Equal(2, inv.Uses)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		inv, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: user.GetID(), Role: role.User, ExpiresAt: optional.TimeFrom(time.Now().Add(-time.Minute))})
+		require.NoError(err)
+
+		assert.EqualError(repo.UseInvitation(inv.ID), ErrForbidden.Error())
+	})
+}
+
+func TestRepositoryImpl_SignUpWithInvitation(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	creator := mustMakeUser(t, repo, rand)
+	g := mustMakeUserGroup(t, repo, rand, creator.GetID())
+
+	_, err := repo.SignUpWithInvitation(uuid.Must(uuid.NewV4()), CreateUserArgs{Name: random.AlphaNumeric(20)})
+	assert.EqualError(err, ErrNotFound.Error())
+
+	inv, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: creator.GetID(), Role: role.User, MaxUses: 1, GroupIDs: []uuid.UUID{g.ID}})
+	require.NoError(err)
+
+	// 名前が重複した場合は使用回数を消費しない
+	_, err = repo.SignUpWithInvitation(inv.ID, CreateUserArgs{Name: creator.GetName()})
+	assert.EqualError(err, ErrAlreadyExists.Error())
+
+	user, err := repo.SignUpWithInvitation(inv.ID, CreateUserArgs{Name: random.AlphaNumeric(20), Role: role.Admin})
+	require.NoError(err)
+	assert.Equal(role.User, user.GetRole())
+	g, err = repo.GetUserGroup(g.ID)
+	require.NoError(err)
+	assert.True(g.IsMember(user.GetID()))
+
+	_, err = repo.SignUpWithInvitation(inv.ID, CreateUserArgs{Name: random.AlphaNumeric(20)})
+	assert.EqualError(err, ErrForbidden.Error())
+}
+
+func TestRepositoryImpl_DeleteInvitation(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	inv, err := repo.CreateInvitation(CreateInvitationArgs{CreatorID: user.GetID(), Role: role.User})
+	require.NoError(err)
+
+	assert.EqualError(repo.DeleteInvitation(uuid.Nil), ErrNotFound.Error())
+	assert.NoError(repo.DeleteInvitation(inv.ID))
+	assert.EqualError(repo.DeleteInvitation(inv.ID), ErrNotFound.Error())
+	_, err = repo.GetInvitation(inv.ID)
+	assert.EqualError(err, ErrNotFound.Error())
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+// KeywordAlertRepository キーワード通知リポジトリ
+type KeywordAlertRepository interface {
+	// CreateKeywordAlert キーワード通知を作成します
+	//
+	// 成功した場合、キーワード通知とnilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// キーワードが不正な場合、ArgumentErrorを返します。
+	// 既に同じキーワードが登録されている場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	CreateKeywordAlert(userID uuid.UUID, keyword string, isRegexp bool) (*model.KeywordAlert, error)
+	// GetKeywordAlert 指定したIDのキーワード通知を取得します
+	//
+	// 成功した場合、キーワード通知とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetKeywordAlert(id uuid.UUID) (*model.KeywordAlert, error)
+	// GetKeywordAlerts 指定したユーザーのキーワード通知を全て取得します
+	//
+	// 成功した場合、キーワード通知の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetKeywordAlerts(userID uuid.UUID) ([]*model.KeywordAlert, error)
+	// GetAllKeywordAlerts 全ユーザーのキーワード通知を取得します
+	//
+	// 成功した場合、キーワード通知の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetAllKeywordAlerts() ([]*model.KeywordAlert, error)
+	// DeleteKeywordAlert 指定したキーワード通知を削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteKeywordAlert(id uuid.UUID) error
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/validator"
+	"regexp"
+)
+
+// CreateKeywordAlert implements KeywordAlertRepository interface.
+func (repo *GormRepository) CreateKeywordAlert(userID uuid.UUID, keyword string, isRegexp bool) (*model.KeywordAlert, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if err := vd.Validate(keyword, validator.KeywordAlertRuleRequired...); err != nil {
+		return nil, ArgError("keyword", "Keyword must be 1-100")
+	}
+	if isRegexp {
+		if _, err := regexp.Compile(keyword); err != nil {
+			return nil, ArgError("keyword", "Keyword is not a valid regular expression")
+		}
+	}
+
+	ka := &model.KeywordAlert{
+		ID:       uuid.Must(uuid.NewV4()),
+		UserID:   userID,
+		Keyword:  keyword,
+		IsRegexp: isRegexp,
+	}
+	if err := repo.db.Create(ka).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+
+	repo.hub.Publish(hub.Message{
+		Name: event.KeywordAlertCreated,
+		Fields: hub.Fields{
+			"user_id":          userID,
+			"keyword_alert_id": ka.ID,
+			"keyword_alert":    ka,
+		},
+	})
+	return ka, nil
+}
+
+// GetKeywordAlert implements KeywordAlertRepository interface.
+func (repo *GormRepository) GetKeywordAlert(id uuid.UUID) (*model.KeywordAlert, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var ka model.KeywordAlert
+	if err := repo.db.First(&ka, &model.KeywordAlert{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &ka, nil
+}
+
+// GetKeywordAlerts implements KeywordAlertRepository interface.
+func (repo *GormRepository) GetKeywordAlerts(userID uuid.UUID) ([]*model.KeywordAlert, error) {
+	alerts := make([]*model.KeywordAlert, 0)
+	if userID == uuid.Nil {
+		return alerts, nil
+	}
+	return alerts, repo.db.Where(&model.KeywordAlert{UserID: userID}).Order("created_at").Find(&alerts).Error
+}
+
+// GetAllKeywordAlerts implements KeywordAlertRepository interface.
+func (repo *GormRepository) GetAllKeywordAlerts() ([]*model.KeywordAlert, error) {
+	alerts := make([]*model.KeywordAlert, 0)
+	return alerts, repo.db.Find(&alerts).Error
+}
+
+// DeleteKeywordAlert implements KeywordAlertRepository interface.
+func (repo *GormRepository) DeleteKeywordAlert(id uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrNilID
+	}
+
+	var ka model.KeywordAlert
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ka, &model.KeywordAlert{ID: id}).Error; err != nil {
+			return convertError(err)
+		}
+		return tx.Delete(&ka).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	repo.hub.Publish(hub.Message{
+		Name: event.KeywordAlertDeleted,
+		Fields: hub.Fields{
+			"user_id":          ka.UserID,
+			"keyword_alert_id": id,
+		},
+	})
+	return nil
+}
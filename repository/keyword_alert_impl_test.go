@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateKeywordAlert(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateKeywordAlert(uuid.Nil, "traQ", false)
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("invalid keyword", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		_, err := repo.CreateKeywordAlert(user.GetID(), "", false)
+		assert.True(t, IsArgError(err))
+		_, err = repo.CreateKeywordAlert(user.GetID(), "(invalid", true)
+		assert.True(t, IsArgError(err))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		ka, err := repo.CreateKeywordAlert(user.GetID(), "traQ", false)
+		if assert.NoError(t, err) {
+			assert.NotEmpty(t, ka.ID)
+			assert.Equal(t, user.GetID(), ka.UserID)
+			assert.Equal(t, "traQ", ka.Keyword)
+			assert.False(t, ka.IsRegexp)
+		}
+
+		_, err = repo.CreateKeywordAlert(user.GetID(), "traQ", false)
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+	})
+}
+
+func TestRepositoryImpl_GetKeywordAlerts(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	_, err := repo.CreateKeywordAlert(user.GetID(), "a", false)
+	assert.NoError(t, err)
+	_, err = repo.CreateKeywordAlert(user.GetID(), "^b$", true)
+	assert.NoError(t, err)
+
+	alerts, err := repo.GetKeywordAlerts(user.GetID())
+	if assert.NoError(t, err) {
+		assert.Len(t, alerts, 2)
+	}
+
+	alerts, err = repo.GetAllKeywordAlerts()
+	if assert.NoError(t, err) {
+		assert.GreaterOrEqual(t, len(alerts), 2)
+	}
+}
+
+func TestRepositoryImpl_DeleteKeywordAlert(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.DeleteKeywordAlert(uuid.Nil), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.DeleteKeywordAlert(uuid.Must(uuid.NewV4())), ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		ka, err := repo.CreateKeywordAlert(user.GetID(), "traQ", false)
+		if assert.NoError(t, err) {
+			assert.NoError(t, repo.DeleteKeywordAlert(ka.ID))
+			_, err := repo.GetKeywordAlert(ka.ID)
+			assert.EqualError(t, err, ErrNotFound.Error())
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// LoginAuditLogQuery ログイン監査ログ取得用クエリ
+type LoginAuditLogQuery struct {
+	// UserID 対象ユーザー (uuid.Nilの場合は指定なし)
+	UserID uuid.UUID
+	// IP 対象IPアドレス (空文字の場合は指定なし)
+	IP     string
+	Limit  int
+	Offset int
+}
+
+// LoginAttemptRepository ログイン試行リポジトリ
+type LoginAttemptRepository interface {
+	// GetLoginLock 指定した単位のログイン試行の失敗状態を取得します
+	//
+	// 成功した場合、失敗状態とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetLoginLock(kind model.LoginLockKind, key string) (*model.LoginLock, error)
+	// GetLoginLocks ログイン試行の失敗状態を最終失敗日時の降順で全て取得します
+	//
+	// 成功した場合、失敗状態の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetLoginLocks() ([]*model.LoginLock, error)
+	// IncrementLoginFailure 指定した単位のログイン試行の失敗回数を1増やします
+	//
+	// 成功した場合、更新後の失敗状態とnilを返します。
+	// 最後の失敗からwindow以上経過していた場合は、失敗回数を1からカウントし直します。
+	// DBによるエラーを返すことがあります。
+	IncrementLoginFailure(kind model.LoginLockKind, key string, window time.Duration) (*model.LoginLock, error)
+	// SetLoginLockedUntil 指定した単位のログイン試行をuntilまでロックします
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	SetLoginLockedUntil(kind model.LoginLockKind, key string, until time.Time) error
+	// DeleteLoginLock 指定した単位のログイン試行の失敗状態を削除し、ロックを解除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteLoginLock(kind model.LoginLockKind, key string) error
+	// CreateLoginAuditLog ログイン失敗の監査ログを記録します
+	//
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	CreateLoginAuditLog(log *model.LoginAuditLog) error
+	// GetLoginAuditLogs ログイン失敗の監査ログを新しい順に取得します
+	//
+	// 成功した場合、監査ログの配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetLoginAuditLogs(query LoginAuditLogQuery) ([]*model.LoginAuditLog, error)
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"time"
+)
+
+// GetLoginLock implements LoginAttemptRepository interface.
+func (repo *GormRepository) GetLoginLock(kind model.LoginLockKind, key string) (*model.LoginLock, error) {
+	if !kind.Valid() || len(key) == 0 {
+		return nil, ErrNotFound
+	}
+	var l model.LoginLock
+	if err := repo.db.First(&l, &model.LoginLock{Kind: kind, Key: key}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &l, nil
+}
+
+// GetLoginLocks implements LoginAttemptRepository interface.
+func (repo *GormRepository) GetLoginLocks() ([]*model.LoginLock, error) {
+	result := make([]*model.LoginLock, 0)
+	return result, repo.db.Order("last_failed_at DESC").Find(&result).Error
+}
+
+// IncrementLoginFailure implements LoginAttemptRepository interface.
+func (repo *GormRepository) IncrementLoginFailure(kind model.LoginLockKind, key string, window time.Duration) (*model.LoginLock, error) {
+	if !kind.Valid() {
+		return nil, ArgError("kind", "invalid kind")
+	}
+	if len(key) == 0 {
+		return nil, ArgError("key", "key is empty")
+	}
+	var l model.LoginLock
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&l, &model.LoginLock{Kind: kind, Key: key}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			l = model.LoginLock{Kind: kind, Key: key, Failures: 1, LastFailedAt: now}
+			return tx.Create(&l).Error
+		}
+		if now.Sub(l.LastFailedAt) >= window {
+			l.Failures = 0
+		}
+		l.Failures++
+		l.LastFailedAt = now
+		return tx.Model(&model.LoginLock{Kind: kind, Key: key}).Updates(map[string]interface{}{
+			"failures":       l.Failures,
+			"last_failed_at": l.LastFailedAt,
+		}).Error
+	})
+	if err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			// 同時に初回の失敗が記録された
+			return repo.IncrementLoginFailure(kind, key, window)
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// SetLoginLockedUntil implements LoginAttemptRepository interface.
+func (repo *GormRepository) SetLoginLockedUntil(kind model.LoginLockKind, key string, until time.Time) error {
+	if !kind.Valid() || len(key) == 0 {
+		return ErrNotFound
+	}
+	result := repo.db.Model(&model.LoginLock{Kind: kind, Key: key}).Update("locked_until", until)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if ok, err := gormutil.RecordExists(repo.db, &model.LoginLock{Kind: kind, Key: key}); err != nil {
+			return err
+		} else if !ok {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// DeleteLoginLock implements LoginAttemptRepository interface.
+func (repo *GormRepository) DeleteLoginLock(kind model.LoginLockKind, key string) error {
+	if !kind.Valid() || len(key) == 0 {
+		return ErrNotFound
+	}
+	result := repo.db.Delete(&model.LoginLock{Kind: kind, Key: key})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateLoginAuditLog implements LoginAttemptRepository interface.
+func (repo *GormRepository) CreateLoginAuditLog(log *model.LoginAuditLog) error {
+	if log.ID == uuid.Nil {
+		log.ID = uuid.Must(uuid.NewV4())
+	}
+	return repo.db.Create(log).Error
+}
+
+// GetLoginAuditLogs implements LoginAttemptRepository interface.
+func (repo *GormRepository) GetLoginAuditLogs(query LoginAuditLogQuery) ([]*model.LoginAuditLog, error) {
+	result := make([]*model.LoginAuditLog, 0)
+	tx := repo.db.Order("created_at DESC").Scopes(gormutil.LimitAndOffset(query.Limit, query.Offset))
+	if query.UserID != uuid.Nil {
+		tx = tx.Where("user_id = ?", query.UserID)
+	}
+	if len(query.IP) > 0 {
+		tx = tx.Where("ip = ?", query.IP)
+	}
+	return result, tx.Find(&result).Error
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_IncrementLoginFailure(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	key := random.AlphaNumeric(20)
+
+	_, err := repo.IncrementLoginFailure("invalid", key, time.Hour)
+	assert.Error(err)
+	_, err = repo.GetLoginLock(model.LoginLockKindIP, key)
+	assert.EqualError(err, ErrNotFound.Error())
+
+	for i := 1; i <= 3; i++ {
+		l, err := repo.IncrementLoginFailure(model.LoginLockKindIP, key, time.Hour)
+		require.NoError(err)
+		assert.Equal(i, l.Failures)
+	}
+
+	// windowを過ぎた場合は1からカウントし直す
+	l, err := repo.IncrementLoginFailure(model.LoginLockKindIP, key, 0)
+	require.NoError(err)
+	assert.Equal(1, l.Failures)
+
+	l, err = repo.GetLoginLock(model.LoginLockKindIP, key)
+	if assert.NoError(err) {
+		assert.Equal(1, l.Failures)
+		assert.False(l.LockedUntil.Valid)
+	}
+}
+
+func TestRepositoryImpl_SetLoginLockedUntil(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	key := uuid.Must(uuid.NewV4()).String()
+	until := time.Now().Add(time.Hour)
+
+	assert.EqualError(repo.SetLoginLockedUntil(model.LoginLockKindUser, key, until), ErrNotFound.Error())
+
+	_, err := repo.IncrementLoginFailure(model.LoginLockKindUser, key, time.Hour)
+	require.NoError(err)
+	require.NoError(repo.SetLoginLockedUntil(model.LoginLockKindUser, key, until))
+
+	l, err := repo.GetLoginLock(model.LoginLockKindUser, key)
+	if assert.NoError(err) {
+		assert.True(l.IsLocked(time.Now()))
+	}
+
+	locks, err := repo.GetLoginLocks()
+	if assert.NoError(err) {
+		found := false
+		for _, l := range locks {
+			if l.Kind == model.LoginLockKindUser && l.Key == key {
+				found = true
+			}
+		}
+		assert.True(found)
+	}
+
+	assert.NoError(repo.DeleteLoginLock(model.LoginLockKindUser, key))
+	assert.EqualError(repo.DeleteLoginLock(model.LoginLockKindUser, key), ErrNotFound.Error())
+	_, err = repo.GetLoginLock(model.LoginLockKindUser, key)
+	assert.EqualError(err, ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_GetLoginAuditLogs(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	ip := "192.0.2." + random.AlphaNumeric(5)
+
+	require.NoError(repo.CreateLoginAuditLog(&model.LoginAuditLog{
+		UserID: optional.UUIDFrom(user.GetID()),
+		Name:   user.GetName(),
+		IP:     ip,
+		Method: "v3",
+		Reason: model.LoginFailureReasonWrongPassword,
+	}))
+	require.NoError(repo.CreateLoginAuditLog(&model.LoginAuditLog{
+		Name:   "unknown",
+		IP:     ip,
+		Method: "v1",
+		Reason: model.LoginFailureReasonUnknownUser,
+	}))
+
+	logs, err := repo.GetLoginAuditLogs(LoginAuditLogQuery{UserID: user.GetID()})
+	if assert.NoError(err) && assert.Len(logs, 1) {
+		assert.Equal(model.LoginFailureReasonWrongPassword, logs[0].Reason)
+		assert.Equal(user.GetName(), logs[0].Name)
+	}
+
+	logs, err = repo.GetLoginAuditLogs(LoginAuditLogQuery{IP: ip})
+	if assert.NoError(err) && assert.Len(logs, 2) {
+		assert.Equal(model.LoginFailureReasonUnknownUser, logs[0].Reason)
+		assert.False(logs[0].UserID.Valid)
+	}
+
+	logs, err = repo.GetLoginAuditLogs(LoginAuditLogQuery{IP: ip, Limit: 1, Offset: 1})
+	if assert.NoError(err) && assert.Len(logs, 1) {
+		assert.Equal(model.LoginFailureReasonWrongPassword, logs[0].Reason)
+	}
+}
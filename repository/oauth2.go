@@ -4,6 +4,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"time"
 )
 
 type UpdateClientArgs struct {
@@ -14,10 +15,13 @@ type UpdateClientArgs struct {
 	Secret       optional.String
 	CallbackURL  optional.String
 	Scopes       model.AccessScopes
+	RequirePKCE  optional.Bool
+	Pending      optional.Bool
 }
 
 type GetClientsQuery struct {
 	DeveloperID optional.UUID
+	Pending     optional.Bool
 }
 
 func (q GetClientsQuery) IsDevelopedBy(userID uuid.UUID) GetClientsQuery {
@@ -25,6 +29,11 @@ func (q GetClientsQuery) IsDevelopedBy(userID uuid.UUID) GetClientsQuery {
 	return q
 }
 
+func (q GetClientsQuery) IsPending() GetClientsQuery {
+	q.Pending = optional.BoolFrom(true)
+	return q
+}
+
 // OAuth2Repository OAuth2用リポジトリ
 type OAuth2Repository interface {
 	// GetClient 指定したIDのクライアントを取得します
@@ -56,6 +65,18 @@ type OAuth2Repository interface {
 	// 成功した、或いは既に存在しない場合、nilを返します。
 	// DBによるエラーを返すことがあります。
 	DeleteClient(id string) error
+	// CreateInitialAccessToken 動的クライアント登録のための初期アクセストークンを発行します
+	//
+	// 成功した場合、初期アクセストークンとnilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateInitialAccessToken(creatorID uuid.UUID, expire int) (*model.OAuth2InitialAccessToken, error)
+	// UseInitialAccessToken 初期アクセストークンを使用します
+	//
+	// 成功した場合、初期アクセストークンとnilを返します。使用したトークンは削除されます。
+	// 存在しない、或いは有効期限が切れている場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	UseInitialAccessToken(token string) (*model.OAuth2InitialAccessToken, error)
 	// SaveAuthorize 認可データを保存します
 	//
 	// 成功した場合、nilを返します。
@@ -72,11 +93,57 @@ type OAuth2Repository interface {
 	// 成功した、或いは既に存在しない場合、nilを返します。
 	// DBによるエラーを返すことがあります。
 	DeleteAuthorize(code string) error
+	// SaveDeviceAuthorization デバイス認可データを保存します
+	//
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	SaveDeviceAuthorization(data *model.OAuth2DeviceAuthorization) error
+	// GetDeviceAuthorizationByDeviceCode 指定したデバイスコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorizationByDeviceCode(deviceCode string) (*model.OAuth2DeviceAuthorization, error)
+	// GetDeviceAuthorizationByUserCode 指定したユーザーコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorizationByUserCode(userCode string) (*model.OAuth2DeviceAuthorization, error)
+	// DecideDeviceAuthorization 承認待ちのデバイス認可データを承認または拒否します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しない、或いは承認待ちでない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DecideDeviceAuthorization(userCode string, userID uuid.UUID, approved bool) error
+	// UpdateDeviceAuthorizationPolling デバイス認可データのポーリング日時と間隔を更新します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateDeviceAuthorizationPolling(deviceCode string, polledAt time.Time, interval int) error
+	// DeleteDeviceAuthorization 指定したデバイスコードのデバイス認可データを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 既に存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteDeviceAuthorization(deviceCode string) error
 	// IssueToken トークンを発行します
 	//
 	// 成功した場合、トークンとnilを返します。
 	// DBによるエラーを返すことがあります。
 	IssueToken(client *model.OAuth2Client, userID uuid.UUID, redirectURI string, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error)
+	// IssuePersonalAccessToken パーソナルアクセストークンを発行します
+	//
+	// 成功した場合、トークンとnilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 名前が不正な場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	IssuePersonalAccessToken(userID uuid.UUID, name string, scope model.AccessScopes, expire int) (*model.OAuth2Token, error)
+	// UpdateTokenLastUsedAt 指定したIDのトークンの最終使用日時を更新します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateTokenLastUsedAt(id uuid.UUID, usedAt time.Time) error
 	// GetTokenByID 指定したIDのトークンを取得します
 	//
 	// 成功した場合、トークンとnilを返します。
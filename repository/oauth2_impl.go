@@ -1,10 +1,12 @@
 package repository
 
 import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/jinzhu/gorm"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/validator"
 	"time"
 )
 
@@ -27,6 +29,9 @@ func (repo *GormRepository) GetClients(query GetClientsQuery) ([]*model.OAuth2Cl
 	if query.DeveloperID.Valid {
 		tx = tx.Where("creator_id = ?", query.DeveloperID.UUID)
 	}
+	if query.Pending.Valid {
+		tx = tx.Where("pending = ?", query.Pending.Bool)
+	}
 	return cs, tx.Find(&cs).Error
 }
 
@@ -65,6 +70,12 @@ func (repo *GormRepository) UpdateClient(clientID string, args UpdateClientArgs)
 		if args.CallbackURL.Valid {
 			changes["redirect_uri"] = args.CallbackURL.String
 		}
+		if args.RequirePKCE.Valid {
+			changes["require_pkce"] = args.RequirePKCE.Bool
+		}
+		if args.Pending.Valid {
+			changes["pending"] = args.Pending.Bool
+		}
 		if args.DeveloperID.Valid {
 			// 作成者検証
 			user, err := getUser(tx, false, "id = ?", args.DeveloperID.UUID)
@@ -98,6 +109,7 @@ func (repo *GormRepository) DeleteClient(id string) error {
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		errs := tx.Delete(&model.OAuth2Client{ID: id}).
 			Delete(&model.OAuth2Authorize{}, &model.OAuth2Authorize{ClientID: id}).
+			Delete(&model.OAuth2DeviceAuthorization{}, &model.OAuth2DeviceAuthorization{ClientID: id}).
 			Delete(&model.OAuth2Token{}, &model.OAuth2Token{ClientID: id}).
 			GetErrors()
 		if len(errs) > 0 {
@@ -108,6 +120,42 @@ func (repo *GormRepository) DeleteClient(id string) error {
 	return err
 }
 
+// CreateInitialAccessToken implements OAuth2Repository interface.
+func (repo *GormRepository) CreateInitialAccessToken(creatorID uuid.UUID, expire int) (*model.OAuth2InitialAccessToken, error) {
+	if creatorID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	now := time.Now()
+	t := &model.OAuth2InitialAccessToken{
+		Token:     random.SecureAlphaNumeric(36),
+		CreatorID: creatorID,
+		ExpiresAt: now.Add(time.Duration(expire) * time.Second),
+		CreatedAt: now,
+	}
+	return t, repo.db.Create(t).Error
+}
+
+// UseInitialAccessToken implements OAuth2Repository interface.
+func (repo *GormRepository) UseInitialAccessToken(token string) (*model.OAuth2InitialAccessToken, error) {
+	if len(token) == 0 {
+		return nil, ErrNotFound
+	}
+	var t model.OAuth2InitialAccessToken
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&t, &model.OAuth2InitialAccessToken{Token: token}).Error; err != nil {
+			return convertError(err)
+		}
+		return tx.Delete(&t).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t.IsExpired() {
+		return nil, ErrNotFound
+	}
+	return &t, nil
+}
+
 // SaveAuthorize implements OAuth2Repository interface.
 func (repo *GormRepository) SaveAuthorize(data *model.OAuth2Authorize) error {
 	return repo.db.Create(data).Error
@@ -133,6 +181,84 @@ func (repo *GormRepository) DeleteAuthorize(code string) error {
 	return repo.db.Delete(&model.OAuth2Authorize{Code: code}).Error
 }
 
+// SaveDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) SaveDeviceAuthorization(data *model.OAuth2DeviceAuthorization) error {
+	return repo.db.Create(data).Error
+}
+
+// GetDeviceAuthorizationByDeviceCode implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorizationByDeviceCode(deviceCode string) (*model.OAuth2DeviceAuthorization, error) {
+	if len(deviceCode) == 0 {
+		return nil, ErrNotFound
+	}
+	d := &model.OAuth2DeviceAuthorization{}
+	if err := repo.db.Take(d, &model.OAuth2DeviceAuthorization{DeviceCode: deviceCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return d, nil
+}
+
+// GetDeviceAuthorizationByUserCode implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorizationByUserCode(userCode string) (*model.OAuth2DeviceAuthorization, error) {
+	if len(userCode) == 0 {
+		return nil, ErrNotFound
+	}
+	d := &model.OAuth2DeviceAuthorization{}
+	if err := repo.db.Take(d, &model.OAuth2DeviceAuthorization{UserCode: userCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return d, nil
+}
+
+// DecideDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) DecideDeviceAuthorization(userCode string, userID uuid.UUID, approved bool) error {
+	if len(userCode) == 0 {
+		return ErrNotFound
+	}
+	status := model.OAuth2DeviceAuthorizationDenied
+	if approved {
+		status = model.OAuth2DeviceAuthorizationApproved
+	}
+	result := repo.db.
+		Model(&model.OAuth2DeviceAuthorization{}).
+		Where("user_code = ? AND status = ?", userCode, model.OAuth2DeviceAuthorizationPending).
+		Updates(map[string]interface{}{"user_id": userID, "status": status})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateDeviceAuthorizationPolling implements OAuth2Repository interface.
+func (repo *GormRepository) UpdateDeviceAuthorizationPolling(deviceCode string, polledAt time.Time, interval int) error {
+	if len(deviceCode) == 0 {
+		return nil
+	}
+	return repo.db.
+		Model(&model.OAuth2DeviceAuthorization{}).
+		Where("device_code = ?", deviceCode).
+		Updates(map[string]interface{}{"last_polled_at": polledAt, "polling_interval": interval}).
+		Error
+}
+
+// DeleteDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) DeleteDeviceAuthorization(deviceCode string) error {
+	if len(deviceCode) == 0 {
+		return ErrNotFound
+	}
+	result := repo.db.Delete(&model.OAuth2DeviceAuthorization{DeviceCode: deviceCode})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // IssueToken implements OAuth2Repository interface.
 func (repo *GormRepository) IssueToken(client *model.OAuth2Client, userID uuid.UUID, redirectURI string, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error) {
 	newToken := &model.OAuth2Token{
@@ -154,6 +280,35 @@ func (repo *GormRepository) IssueToken(client *model.OAuth2Client, userID uuid.U
 	return newToken, repo.db.Create(newToken).Error
 }
 
+// IssuePersonalAccessToken implements OAuth2Repository interface.
+func (repo *GormRepository) IssuePersonalAccessToken(userID uuid.UUID, name string, scope model.AccessScopes, expire int) (*model.OAuth2Token, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if err := vd.Validate(name, validator.PersonalAccessTokenNameRuleRequired...); err != nil {
+		return nil, ArgError("name", "Name must be 1-32")
+	}
+
+	newToken := &model.OAuth2Token{
+		ID:          uuid.Must(uuid.NewV4()),
+		UserID:      userID,
+		AccessToken: random.SecureAlphaNumeric(36),
+		Scopes:      scope,
+		ExpiresIn:   expire,
+		Name:        name,
+		CreatedAt:   time.Now(),
+	}
+	return newToken, repo.db.Create(newToken).Error
+}
+
+// UpdateTokenLastUsedAt implements OAuth2Repository interface.
+func (repo *GormRepository) UpdateTokenLastUsedAt(id uuid.UUID, usedAt time.Time) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	return repo.db.Model(&model.OAuth2Token{ID: id}).UpdateColumn("last_used_at", usedAt).Error
+}
+
 // GetTokenByID implements OAuth2Repository interface.
 func (repo *GormRepository) GetTokenByID(id uuid.UUID) (*model.OAuth2Token, error) {
 	if id == uuid.Nil {
@@ -18,9 +18,23 @@ type Repository interface {
 	StarRepository
 	PinRepository
 	DeviceRepository
+	WebPushRepository
+	DoNotDisturbRepository
+	KeywordAlertRepository
+	InboxRepository
+	EmailNotificationRepository
+	UserStatusRepository
+	UserBlockRepository
+	TwoFactorRepository
+	WebAuthnRepository
+	LoginAttemptRepository
+	SAMLRepository
+	InvitationRepository
 	FileRepository
 	WebhookRepository
 	OAuth2Repository
 	BotRepository
 	ClipRepository
+	RoleRepository
+	ChannelRoleBindingRepository
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+// CreateRoleArgs ユーザーロール作成引数
+type CreateRoleArgs struct {
+	Name string
+	// Permissions ロールに直接与える権限
+	Permissions []string
+	// Inheritances 継承するロール
+	Inheritances []string
+}
+
+// UpdateRoleArgs ユーザーロール更新引数
+//
+// nilのフィールドは変更しません。
+type UpdateRoleArgs struct {
+	// Permissions ロールに直接与える権限
+	Permissions []string
+	// Inheritances 継承するロール
+	Inheritances []string
+}
+
+// RoleRepository ユーザーロールリポジトリ
+type RoleRepository interface {
+	// GetRoles 全てのユーザーロールを継承関係と権限を含めて取得します
+	//
+	// 成功した場合、ロールの配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetRoles() ([]*model.UserRole, error)
+	// GetRole 指定した名前のユーザーロールを継承関係と権限を含めて取得します
+	//
+	// 成功した場合、ロールとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetRole(name string) (*model.UserRole, error)
+	// CreateRole ユーザーロールを作成します
+	//
+	// 成功した場合、ロールとnilを返します。
+	// 既に同名のロールが存在する場合、ErrAlreadyExistsを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	CreateRole(args CreateRoleArgs) (*model.UserRole, error)
+	// UpdateRole 指定したユーザーロールの権限と継承関係を置き換えます
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// システム定義ロールを指定した場合、ErrForbiddenを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateRole(name string, args UpdateRoleArgs) error
+	// DeleteRole 指定したユーザーロールを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// システム定義ロール、ユーザーやチャンネルで割り当てられているロール、他のロールに継承されているロールを指定した場合、ErrForbiddenを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteRole(name string) error
+}
+
+// ChannelRoleBindingRepository チャンネルスコープのロール割り当てリポジトリ
+type ChannelRoleBindingRepository interface {
+	// GetChannelRoleBindings 指定したチャンネルに直接割り当てられたロールを全て取得します
+	//
+	// 成功した場合、割り当ての配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelRoleBindings(channelID uuid.UUID) ([]*model.ChannelRoleBinding, error)
+	// GetChannelRoleBindingsByUserID 指定したユーザーに割り当てられたチャンネルスコープのロールを全て取得します
+	//
+	// 成功した場合、割り当ての配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetChannelRoleBindingsByUserID(userID uuid.UUID) ([]*model.ChannelRoleBinding, error)
+	// SetChannelRoleBinding 指定したチャンネルでユーザーにロールを割り当てます
+	//
+	// 既に割り当てられている場合は、ロールを置き換えます。
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 存在しない、または割り当てられないロールを指定した場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	SetChannelRoleBinding(channelID, userID uuid.UUID, role string) error
+	// DeleteChannelRoleBinding 指定したチャンネルでのユーザーへのロールの割り当てを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 割り当てが存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteChannelRoleBinding(channelID, userID uuid.UUID) error
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/validator"
+)
+
+func rolePreloads(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Inheritances").
+		Preload("Permissions")
+}
+
+// GetRoles implements RoleRepository interface.
+func (repo *GormRepository) GetRoles() ([]*model.UserRole, error) {
+	roles := make([]*model.UserRole, 0)
+	return roles, repo.db.Scopes(rolePreloads).Order("name").Find(&roles).Error
+}
+
+// GetRole implements RoleRepository interface.
+func (repo *GormRepository) GetRole(name string) (*model.UserRole, error) {
+	if len(name) == 0 {
+		return nil, ErrNotFound
+	}
+	var r model.UserRole
+	if err := repo.db.Scopes(rolePreloads).First(&r, &model.UserRole{Name: name}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &r, nil
+}
+
+// CreateRole implements RoleRepository interface.
+func (repo *GormRepository) CreateRole(args CreateRoleArgs) (*model.UserRole, error) {
+	r := &model.UserRole{Name: args.Name}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := vd.Validate(args.Name, validator.RoleNameRuleRequired...); err != nil {
+			return ArgError("args.Name", "invalid name")
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.UserRole{Name: args.Name}); err != nil {
+			return err
+		} else if ok {
+			return ErrAlreadyExists
+		}
+		if err := validateRoleComposition(tx, args.Name, args.Permissions, args.Inheritances); err != nil {
+			return err
+		}
+
+		if err := tx.Create(r).Error; err != nil {
+			return err
+		}
+		var err error
+		r.Permissions, r.Inheritances, err = replaceRoleComposition(tx, args.Name, args.Permissions, args.Inheritances)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	repo.publishRoleUpdated(r.Name)
+	return r, nil
+}
+
+// UpdateRole implements RoleRepository interface.
+func (repo *GormRepository) UpdateRole(name string, args UpdateRoleArgs) error {
+	if len(name) == 0 {
+		return ErrNotFound
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var r model.UserRole
+		if err := tx.Scopes(rolePreloads).Set("gorm:query_option", "FOR UPDATE").First(&r, &model.UserRole{Name: name}).Error; err != nil {
+			return convertError(err)
+		}
+		if r.System {
+			return ErrForbidden
+		}
+
+		perms := args.Permissions
+		if perms == nil {
+			perms = make([]string, len(r.Permissions))
+			for i, p := range r.Permissions {
+				perms[i] = p.Permission
+			}
+		}
+		inheritances := args.Inheritances
+		if inheritances == nil {
+			inheritances = make([]string, len(r.Inheritances))
+			for i, v := range r.Inheritances {
+				inheritances[i] = v.SubRole
+			}
+		}
+		if err := validateRoleComposition(tx, name, perms, inheritances); err != nil {
+			return err
+		}
+
+		_, _, err := replaceRoleComposition(tx, name, perms, inheritances)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	repo.publishRoleUpdated(name)
+	return nil
+}
+
+// DeleteRole implements RoleRepository interface.
+func (repo *GormRepository) DeleteRole(name string) error {
+	if len(name) == 0 {
+		return ErrNotFound
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var r model.UserRole
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&r, &model.UserRole{Name: name}).Error; err != nil {
+			return convertError(err)
+		}
+		if r.System {
+			return ErrForbidden
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.User{Role: name}); err != nil {
+			return err
+		} else if ok {
+			return ErrForbidden
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.ChannelRoleBinding{Role: name}); err != nil {
+			return err
+		} else if ok {
+			return ErrForbidden
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.RoleInheritance{SubRole: name}); err != nil {
+			return err
+		} else if ok {
+			return ErrForbidden
+		}
+
+		return tx.Delete(&model.UserRole{Name: name}).Error
+	})
+	if err != nil {
+		return err
+	}
+	repo.publishRoleUpdated(name)
+	return nil
+}
+
+func (repo *GormRepository) publishRoleUpdated(name string) {
+	repo.hub.Publish(hub.Message{
+		Name: event.RoleUpdated,
+		Fields: hub.Fields{
+			"role": name,
+		},
+	})
+}
+
+// validateRoleComposition ロールの権限と継承関係を検証します
+func validateRoleComposition(tx *gorm.DB, name string, perms []string, inheritances []string) error {
+	valid := map[string]bool{}
+	for _, p := range permission.List {
+		valid[p.Name()] = true
+	}
+	for _, p := range perms {
+		if !valid[p] {
+			return ArgError("args.Permissions", "unknown permission: "+p)
+		}
+	}
+
+	var all []*model.RoleInheritance
+	if err := tx.Find(&all).Error; err != nil {
+		return err
+	}
+	graph := map[string][]string{}
+	for _, v := range all {
+		if v.Role != name {
+			graph[v.Role] = append(graph[v.Role], v.SubRole)
+		}
+	}
+	for _, sub := range inheritances {
+		if sub == role.Admin {
+			return ArgError("args.Inheritances", "admin role cannot be inherited")
+		}
+		if ok, err := gormutil.RecordExists(tx, &model.UserRole{Name: sub}); err != nil {
+			return err
+		} else if !ok {
+			return ArgError("args.Inheritances", "unknown role: "+sub)
+		}
+		graph[name] = append(graph[name], sub)
+	}
+
+	// 継承関係の循環検知
+	visited := map[string]bool{}
+	var reaches func(from string) bool
+	reaches = func(from string) bool {
+		for _, sub := range graph[from] {
+			if sub == name {
+				return true
+			}
+			if !visited[sub] {
+				visited[sub] = true
+				if reaches(sub) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if reaches(name) {
+		return ArgError("args.Inheritances", "role inheritance must not be circular")
+	}
+	return nil
+}
+
+// replaceRoleComposition ロールの権限と継承関係を置き換えます
+func replaceRoleComposition(tx *gorm.DB, name string, perms []string, inheritances []string) ([]model.RolePermission, []model.RoleInheritance, error) {
+	if err := tx.Where(&model.RolePermission{Role: name}).Delete(&model.RolePermission{}).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Where(&model.RoleInheritance{Role: name}).Delete(&model.RoleInheritance{}).Error; err != nil {
+		return nil, nil, err
+	}
+
+	rps := make([]model.RolePermission, 0, len(perms))
+	added := map[string]bool{}
+	for _, p := range perms {
+		if added[p] {
+			continue
+		}
+		added[p] = true
+		rp := model.RolePermission{Role: name, Permission: p}
+		if err := tx.Create(&rp).Error; err != nil {
+			return nil, nil, err
+		}
+		rps = append(rps, rp)
+	}
+	ris := make([]model.RoleInheritance, 0, len(inheritances))
+	added = map[string]bool{}
+	for _, sub := range inheritances {
+		if added[sub] {
+			continue
+		}
+		added[sub] = true
+		ri := model.RoleInheritance{Role: name, SubRole: sub}
+		if err := tx.Create(&ri).Error; err != nil {
+			return nil, nil, err
+		}
+		ris = append(ris, ri)
+	}
+	return rps, ris, nil
+}
+
+// GetChannelRoleBindings implements ChannelRoleBindingRepository interface.
+func (repo *GormRepository) GetChannelRoleBindings(channelID uuid.UUID) ([]*model.ChannelRoleBinding, error) {
+	bindings := make([]*model.ChannelRoleBinding, 0)
+	if channelID == uuid.Nil {
+		return bindings, nil
+	}
+	return bindings, repo.db.Where(&model.ChannelRoleBinding{ChannelID: channelID}).Order("created_at").Find(&bindings).Error
+}
+
+// GetChannelRoleBindingsByUserID implements ChannelRoleBindingRepository interface.
+func (repo *GormRepository) GetChannelRoleBindingsByUserID(userID uuid.UUID) ([]*model.ChannelRoleBinding, error) {
+	bindings := make([]*model.ChannelRoleBinding, 0)
+	if userID == uuid.Nil {
+		return bindings, nil
+	}
+	return bindings, repo.db.Where(&model.ChannelRoleBinding{UserID: userID}).Find(&bindings).Error
+}
+
+// SetChannelRoleBinding implements ChannelRoleBindingRepository interface.
+func (repo *GormRepository) SetChannelRoleBinding(channelID, userID uuid.UUID, roleName string) error {
+	if channelID == uuid.Nil || userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if len(roleName) == 0 || roleName == role.Admin {
+			return ArgError("role", "the Role cannot be bound to channels")
+		}
+		var r model.UserRole
+		if err := tx.First(&r, &model.UserRole{Name: roleName}).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return ArgError("role", "the Role doesn't exist")
+			}
+			return err
+		}
+		if r.Oauth2Scope {
+			return ArgError("role", "the Role cannot be bound to channels")
+		}
+
+		var b model.ChannelRoleBinding
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&b, &model.ChannelRoleBinding{ChannelID: channelID, UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			return tx.Create(&model.ChannelRoleBinding{ChannelID: channelID, UserID: userID, Role: roleName}).Error
+		}
+		return tx.Model(&b).Update("role", roleName).Error
+	})
+}
+
+// DeleteChannelRoleBinding implements ChannelRoleBindingRepository interface.
+func (repo *GormRepository) DeleteChannelRoleBinding(channelID, userID uuid.UUID) error {
+	if channelID == uuid.Nil || userID == uuid.Nil {
+		return ErrNotFound
+	}
+	result := repo.db.Delete(&model.ChannelRoleBinding{}, &model.ChannelRoleBinding{ChannelID: channelID, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"strings"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateRole(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	_, err := repo.CreateRole(CreateRoleArgs{})
+	assert.True(IsArgError(err))
+	_, err = repo.CreateRole(CreateRoleArgs{Name: role.User})
+	assert.EqualError(err, ErrAlreadyExists.Error())
+	_, err = repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Permissions: []string{"unknown"}})
+	assert.True(IsArgError(err))
+	_, err = repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{role.Admin}})
+	assert.True(IsArgError(err))
+	_, err = repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{"unknown"}})
+	assert.True(IsArgError(err))
+
+	name := strings.ToLower(random.AlphaNumeric(20))
+	r, err := repo.CreateRole(CreateRoleArgs{
+		Name:         name,
+		Permissions:  []string{permission.ManageRole.Name(), permission.ManageRole.Name()},
+		Inheritances: []string{role.User},
+	})
+	require.NoError(err)
+	assert.Equal(name, r.Name)
+	assert.False(r.System)
+	assert.Len(r.Permissions, 1)
+	assert.Len(r.Inheritances, 1)
+
+	r2, err := repo.GetRole(name)
+	require.NoError(err)
+	if assert.Len(r2.Permissions, 1) {
+		assert.Equal(permission.ManageRole.Name(), r2.Permissions[0].Permission)
+	}
+	if assert.Len(r2.Inheritances, 1) {
+		assert.Equal(role.User, r2.Inheritances[0].SubRole)
+	}
+
+	_, err = repo.GetRole("")
+	assert.EqualError(err, ErrNotFound.Error())
+	_, err = repo.GetRole(strings.ToLower(random.AlphaNumeric(20)))
+	assert.EqualError(err, ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_UpdateRole(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	assert.EqualError(repo.UpdateRole(strings.ToLower(random.AlphaNumeric(20)), UpdateRoleArgs{}), ErrNotFound.Error())
+	assert.EqualError(repo.UpdateRole(role.User, UpdateRoleArgs{}), ErrForbidden.Error())
+
+	r1, err := repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Permissions: []string{permission.ManageRole.Name()}})
+	require.NoError(err)
+	r2, err := repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{r1.Name}})
+	require.NoError(err)
+
+	// 循環継承
+	assert.True(IsArgError(repo.UpdateRole(r1.Name, UpdateRoleArgs{Inheritances: []string{r2.Name}})))
+	assert.True(IsArgError(repo.UpdateRole(r1.Name, UpdateRoleArgs{Inheritances: []string{r1.Name}})))
+
+	if assert.NoError(repo.UpdateRole(r1.Name, UpdateRoleArgs{Inheritances: []string{role.Read}})) {
+		r, err := repo.GetRole(r1.Name)
+		require.NoError(err)
+		assert.Len(r.Permissions, 1)
+		if assert.Len(r.Inheritances, 1) {
+			assert.Equal(role.Read, r.Inheritances[0].SubRole)
+		}
+	}
+	if assert.NoError(repo.UpdateRole(r1.Name, UpdateRoleArgs{Permissions: []string{}})) {
+		r, err := repo.GetRole(r1.Name)
+		require.NoError(err)
+		assert.Len(r.Permissions, 0)
+		assert.Len(r.Inheritances, 1)
+	}
+}
+
+func TestRepositoryImpl_DeleteRole(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	assert.EqualError(repo.DeleteRole(strings.ToLower(random.AlphaNumeric(20))), ErrNotFound.Error())
+	assert.EqualError(repo.DeleteRole(role.User), ErrForbidden.Error())
+
+	r1, err := repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20))})
+	require.NoError(err)
+	r2, err := repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{r1.Name}})
+	require.NoError(err)
+	_, err = repo.CreateUser(CreateUserArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Role: r2.Name})
+	require.NoError(err)
+
+	// 継承されている・ユーザーに割り当てられているロールは削除できない
+	assert.EqualError(repo.DeleteRole(r1.Name), ErrForbidden.Error())
+	assert.EqualError(repo.DeleteRole(r2.Name), ErrForbidden.Error())
+
+	r3, err := repo.CreateRole(CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{r1.Name}})
+	require.NoError(err)
+	if assert.NoError(repo.DeleteRole(r3.Name)) {
+		_, err := repo.GetRole(r3.Name)
+		assert.EqualError(err, ErrNotFound.Error())
+	}
+}
+
+func TestRepositoryImpl_ChannelRoleBinding(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	ch := mustMakeChannel(t, repo, rand)
+
+	assert.EqualError(repo.SetChannelRoleBinding(uuid.Nil, user.GetID(), role.ChannelLead), ErrNilID.Error())
+	assert.True(IsArgError(repo.SetChannelRoleBinding(ch.ID, user.GetID(), role.Admin)))
+	assert.True(IsArgError(repo.SetChannelRoleBinding(ch.ID, user.GetID(), "unknown")))
+	assert.True(IsArgError(repo.SetChannelRoleBinding(ch.ID, user.GetID(), role.Read)))
+
+	require.NoError(repo.SetChannelRoleBinding(ch.ID, user.GetID(), role.ChannelLead))
+	require.NoError(repo.SetChannelRoleBinding(ch.ID, user.GetID(), role.Moderator))
+
+	bindings, err := repo.GetChannelRoleBindings(ch.ID)
+	require.NoError(err)
+	if assert.Len(bindings, 1) {
+		assert.Equal(user.GetID(), bindings[0].UserID)
+		assert.Equal(role.Moderator, bindings[0].Role)
+	}
+	bindings, err = repo.GetChannelRoleBindingsByUserID(user.GetID())
+	require.NoError(err)
+	assert.Len(bindings, 1)
+
+	// 割り当てられているロールは削除できない
+	assert.EqualError(repo.DeleteRole(role.Moderator), ErrForbidden.Error())
+
+	require.NoError(repo.DeleteChannelRoleBinding(ch.ID, user.GetID()))
+	assert.EqualError(repo.DeleteChannelRoleBinding(ch.ID, user.GetID()), ErrNotFound.Error())
+	bindings, err = repo.GetChannelRoleBindings(ch.ID)
+	require.NoError(err)
+	assert.Empty(bindings)
+}
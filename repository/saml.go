@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// SAMLRepository SAMLリポジトリ
+type SAMLRepository interface {
+	// UseSAMLAssertion アサーションIDを有効期限まで使用済みとして記録します
+	//
+	// 成功した場合、nilを返します。
+	// 有効期限内の同じIDが既に記録されている場合、ErrAlreadyExistsを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UseSAMLAssertion(id string, expiresAt time.Time) error
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"time"
+)
+
+// UseSAMLAssertion implements SAMLRepository interface.
+func (repo *GormRepository) UseSAMLAssertion(id string, expiresAt time.Time) error {
+	if len(id) == 0 {
+		return ArgError("id", "id is empty")
+	}
+	if len(id) > 255 {
+		return ArgError("id", "id is too long")
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		// 有効期限切れのアサーションを削除
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&model.SAMLUsedAssertion{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.SAMLUsedAssertion{ID: id, ExpiresAt: expiresAt}).Error
+	})
+	if err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"github.com/traPtitech/traQ/utils/random"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_UseSAMLAssertion(t *testing.T) {
+	t.Parallel()
+	repo, assert, _ := setup(t, common)
+
+	id := "id-" + random.AlphaNumeric(20)
+	assert.NoError(repo.UseSAMLAssertion(id, time.Now().Add(time.Hour)))
+	assert.EqualError(repo.UseSAMLAssertion(id, time.Now().Add(time.Hour)), ErrAlreadyExists.Error())
+
+	// 有効期限切れのアサーションは削除される
+	expired := "id-" + random.AlphaNumeric(20)
+	assert.NoError(repo.UseSAMLAssertion(expired, time.Now().Add(-time.Minute)))
+	assert.NoError(repo.UseSAMLAssertion(expired, time.Now().Add(time.Hour)))
+
+	assert.Error(repo.UseSAMLAssertion("", time.Now()))
+	assert.Error(repo.UseSAMLAssertion(strings.Repeat("a", 256), time.Now()))
+}
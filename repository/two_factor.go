@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+// TwoFactorRepository 二要素認証リポジトリ
+type TwoFactorRepository interface {
+	// GetUserTOTP 指定したユーザーのTOTP設定を取得します
+	//
+	// 成功した場合、TOTP設定とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserTOTP(userID uuid.UUID) (*model.UserTOTP, error)
+	// SetUserTOTPSecret 指定したユーザーのTOTPシークレットを登録手続き中の状態で保存します
+	//
+	// 成功した場合、TOTP設定とnilを返します。
+	// 登録手続き中のシークレットが既にある場合は上書きします。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 既にTOTPが有効になっている場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	SetUserTOTPSecret(userID uuid.UUID, secret string) (*model.UserTOTP, error)
+	// EnableUserTOTP 指定したユーザーの登録手続き中のTOTPを有効化し、リカバリーコードを設定します
+	//
+	// 成功した場合、nilを返します。
+	// stepには有効化に使用したコードのタイムステップを指定します。
+	// 登録手続き中のTOTPが存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	EnableUserTOTP(userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	// UpdateUserTOTPLastStep 指定したユーザーのTOTPの最終使用タイムステップを更新します
+	//
+	// 成功した場合、trueとnilを返します。
+	// stepが最終使用タイムステップ以下だった場合(コードの再利用)、falseとnilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateUserTOTPLastStep(userID uuid.UUID, step int64) (bool, error)
+	// DeleteUserTOTP 指定したユーザーのTOTP設定とリカバリーコードを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteUserTOTP(userID uuid.UUID) error
+	// ReplaceUserRecoveryCodes 指定したユーザーのリカバリーコードを置き換えます
+	//
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	ReplaceUserRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	// UseUserRecoveryCode 指定したユーザーのリカバリーコードを使用します
+	//
+	// 成功した場合、trueとnilを返します。使用したリカバリーコードは削除されます。
+	// 該当するリカバリーコードが存在しなかった場合、falseとnilを返します。
+	// DBによるエラーを返すことがあります。
+	UseUserRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	// GetUserRecoveryCodeCount 指定したユーザーの未使用のリカバリーコードの数を取得します
+	//
+	// 成功した場合、リカバリーコードの数とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserRecoveryCodeCount(userID uuid.UUID) (int, error)
+	// GetTwoFactorRequiredRoles 二要素認証が必須のロールの名前の配列を取得します
+	//
+	// 成功した場合、ロール名の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetTwoFactorRequiredRoles() ([]string, error)
+	// IsTwoFactorRequired 指定したロールで二要素認証が必須かどうかを返します
+	//
+	// 存在しないロールの場合はfalseとnilを返します。
+	// DBによるエラーを返すことがあります。
+	IsTwoFactorRequired(role string) (bool, error)
+	// SetTwoFactorRequiredRoles 二要素認証を必須とするロールを指定したロールのみに置き換えます
+	//
+	// 成功した場合、nilを返します。
+	// 存在しないロールが含まれている場合、ArgumentErrorを返し、何も変更しません。
+	// DBによるエラーを返すことがあります。
+	SetTwoFactorRequiredRoles(roles []string) error
+}
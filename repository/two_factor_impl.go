@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+)
+
+// GetUserTOTP implements TwoFactorRepository interface.
+func (repo *GormRepository) GetUserTOTP(userID uuid.UUID) (*model.UserTOTP, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var t model.UserTOTP
+	if err := repo.db.First(&t, &model.UserTOTP{UserID: userID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &t, nil
+}
+
+// SetUserTOTPSecret implements TwoFactorRepository interface.
+func (repo *GormRepository) SetUserTOTPSecret(userID uuid.UUID, secret string) (*model.UserTOTP, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	t := model.UserTOTP{UserID: userID, Secret: secret}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var old model.UserTOTP
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&old, &model.UserTOTP{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			return tx.Create(&t).Error
+		}
+		if old.Enabled {
+			return ErrAlreadyExists
+		}
+		if err := tx.Model(&old).Updates(map[string]interface{}{"secret": secret, "last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.First(&t, &model.UserTOTP{UserID: userID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// EnableUserTOTP implements TwoFactorRepository interface.
+func (repo *GormRepository) EnableUserTOTP(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	if userID == uuid.Nil {
+		return ErrNotFound
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.UserTOTP{UserID: userID}).
+			Where("enabled = ?", false).
+			Updates(map[string]interface{}{"enabled": true, "last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return replaceUserRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+// UpdateUserTOTPLastStep implements TwoFactorRepository interface.
+func (repo *GormRepository) UpdateUserTOTPLastStep(userID uuid.UUID, step int64) (bool, error) {
+	if userID == uuid.Nil {
+		return false, nil
+	}
+	result := repo.db.Model(&model.UserTOTP{UserID: userID}).
+		Where("last_step < ?", step).
+		UpdateColumn("last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteUserTOTP implements TwoFactorRepository interface.
+func (repo *GormRepository) DeleteUserTOTP(userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return ErrNotFound
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.UserTOTP{UserID: userID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Delete(&model.UserRecoveryCode{}, &model.UserRecoveryCode{UserID: userID}).Error
+	})
+}
+
+// ReplaceUserRecoveryCodes implements TwoFactorRepository interface.
+func (repo *GormRepository) ReplaceUserRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return replaceUserRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceUserRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codeHashes []string) error {
+	if err := tx.Delete(&model.UserRecoveryCode{}, &model.UserRecoveryCode{UserID: userID}).Error; err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if err := tx.Create(&model.UserRecoveryCode{UserID: userID, CodeHash: h}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseUserRecoveryCode implements TwoFactorRepository interface.
+func (repo *GormRepository) UseUserRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	if userID == uuid.Nil || len(codeHash) == 0 {
+		return false, nil
+	}
+	result := repo.db.Delete(&model.UserRecoveryCode{}, &model.UserRecoveryCode{UserID: userID, CodeHash: codeHash})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUserRecoveryCodeCount implements TwoFactorRepository interface.
+func (repo *GormRepository) GetUserRecoveryCodeCount(userID uuid.UUID) (int, error) {
+	if userID == uuid.Nil {
+		return 0, nil
+	}
+	return gormutil.Count(repo.db.Model(&model.UserRecoveryCode{}).Where(&model.UserRecoveryCode{UserID: userID}))
+}
+
+// GetTwoFactorRequiredRoles implements TwoFactorRepository interface.
+func (repo *GormRepository) GetTwoFactorRequiredRoles() ([]string, error) {
+	result := make([]string, 0)
+	return result, repo.db.Model(&model.UserRole{}).Where("two_factor_required = ?", true).Order("name").Pluck("name", &result).Error
+}
+
+// IsTwoFactorRequired implements TwoFactorRepository interface.
+func (repo *GormRepository) IsTwoFactorRequired(role string) (bool, error) {
+	if len(role) == 0 {
+		return false, nil
+	}
+	return gormutil.Exists(repo.db.Model(&model.UserRole{}).Where("name = ? AND two_factor_required = ?", role, true))
+}
+
+// SetTwoFactorRequiredRoles implements TwoFactorRepository interface.
+func (repo *GormRepository) SetTwoFactorRequiredRoles(roles []string) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, role := range roles {
+			if len(role) == 0 {
+				return ArgError("roles", "unknown role: "+role)
+			}
+			ok, err := gormutil.RecordExists(tx, &model.UserRole{Name: role})
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ArgError("roles", "unknown role: "+role)
+			}
+		}
+		if err := tx.Model(&model.UserRole{}).Where("two_factor_required = ?", true).UpdateColumn("two_factor_required", false).Error; err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+		return tx.Model(&model.UserRole{}).Where("name IN (?)", roles).UpdateColumn("two_factor_required", true).Error
+	})
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRepositoryImpl_SetUserTOTPSecret(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	_, err := repo.SetUserTOTPSecret(uuid.Nil, "AAAA")
+	assert.EqualError(err, ErrNilID.Error())
+
+	_, err = repo.GetUserTOTP(user.GetID())
+	assert.EqualError(err, ErrNotFound.Error())
+
+	totp, err := repo.SetUserTOTPSecret(user.GetID(), "AAAA")
+	require.NoError(err)
+	assert.Equal("AAAA", totp.Secret)
+	assert.False(totp.Enabled)
+
+	// 登録手続き中は上書きできる
+	totp, err = repo.SetUserTOTPSecret(user.GetID(), "BBBB")
+	require.NoError(err)
+	assert.Equal("BBBB", totp.Secret)
+
+	require.NoError(repo.EnableUserTOTP(user.GetID(), 100, []string{"h1", "h2"}))
+	_, err = repo.SetUserTOTPSecret(user.GetID(), "CCCC")
+	assert.EqualError(err, ErrAlreadyExists.Error())
+
+	totp, err = repo.GetUserTOTP(user.GetID())
+	if assert.NoError(err) {
+		assert.Equal("BBBB", totp.Secret)
+		assert.True(totp.Enabled)
+		assert.EqualValues(100, totp.LastStep)
+	}
+}
+
+func TestRepositoryImpl_EnableUserTOTP(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	assert.EqualError(repo.EnableUserTOTP(user.GetID(), 1, nil), ErrNotFound.Error())
+
+	_, err := repo.SetUserTOTPSecret(user.GetID(), "AAAA")
+	require.NoError(err)
+	require.NoError(repo.EnableUserTOTP(user.GetID(), 1, []string{"h1", "h2", "h3"}))
+
+	n, err := repo.GetUserRecoveryCodeCount(user.GetID())
+	if assert.NoError(err) {
+		assert.Equal(3, n)
+	}
+
+	// 既に有効
+	assert.EqualError(repo.EnableUserTOTP(user.GetID(), 2, nil), ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_UpdateUserTOTPLastStep(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	_, err := repo.SetUserTOTPSecret(user.GetID(), "AAAA")
+	require.NoError(err)
+	require.NoError(repo.EnableUserTOTP(user.GetID(), 10, nil))
+
+	ok, err := repo.UpdateUserTOTPLastStep(user.GetID(), 10)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+	ok, err = repo.UpdateUserTOTPLastStep(user.GetID(), 11)
+	if assert.NoError(err) {
+		assert.True(ok)
+	}
+	ok, err = repo.UpdateUserTOTPLastStep(user.GetID(), 11)
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+}
+
+func TestRepositoryImpl_DeleteUserTOTP(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	assert.EqualError(repo.DeleteUserTOTP(user.GetID()), ErrNotFound.Error())
+
+	_, err := repo.SetUserTOTPSecret(user.GetID(), "AAAA")
+	require.NoError(err)
+	require.NoError(repo.EnableUserTOTP(user.GetID(), 1, []string{"h1"}))
+
+	if assert.NoError(repo.DeleteUserTOTP(user.GetID())) {
+		_, err := repo.GetUserTOTP(user.GetID())
+		assert.EqualError(err, ErrNotFound.Error())
+		n, err := repo.GetUserRecoveryCodeCount(user.GetID())
+		if assert.NoError(err) {
+			assert.Equal(0, n)
+		}
+	}
+}
+
+func TestRepositoryImpl_UseUserRecoveryCode(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	require.NoError(repo.ReplaceUserRecoveryCodes(user.GetID(), []string{"h1", "h2"}))
+
+	ok, err := repo.UseUserRecoveryCode(user.GetID(), "h1")
+	if assert.NoError(err) {
+		assert.True(ok)
+	}
+	ok, err = repo.UseUserRecoveryCode(user.GetID(), "h1")
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+	ok, err = repo.UseUserRecoveryCode(user.GetID(), "h3")
+	if assert.NoError(err) {
+		assert.False(ok)
+	}
+
+	require.NoError(repo.ReplaceUserRecoveryCodes(user.GetID(), []string{"h3"}))
+	n, err := repo.GetUserRecoveryCodeCount(user.GetID())
+	if assert.NoError(err) {
+		assert.Equal(1, n)
+	}
+}
+
+func TestRepositoryImpl_SetTwoFactorRequiredRoles(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, ex2)
+
+	ok, err := repo.IsTwoFactorRequired("admin")
+	if assert.NoError(t, err) {
+		assert.False(t, ok)
+	}
+
+	if assert.NoError(t, repo.SetTwoFactorRequiredRoles([]string{"admin"})) {
+		ok, err := repo.IsTwoFactorRequired("admin")
+		if assert.NoError(t, err) {
+			assert.True(t, ok)
+		}
+		roles, err := repo.GetTwoFactorRequiredRoles()
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, []string{"admin"}, roles)
+		}
+	}
+
+	// 存在しないロールが含まれている場合は何も変更されない
+	assert.True(t, IsArgError(repo.SetTwoFactorRequiredRoles([]string{"user", "not_exists"})))
+	roles, err := repo.GetTwoFactorRequiredRoles()
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []string{"admin"}, roles)
+	}
+
+	if assert.NoError(t, repo.SetTwoFactorRequiredRoles([]string{"user"})) {
+		roles, err := repo.GetTwoFactorRequiredRoles()
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, []string{"user"}, roles)
+		}
+	}
+
+	if assert.NoError(t, repo.SetTwoFactorRequiredRoles(nil)) {
+		roles, err := repo.GetTwoFactorRequiredRoles()
+		if assert.NoError(t, err) {
+			assert.Len(t, roles, 0)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// UserBlockRepository ユーザーブロック・ミュートリポジトリ
+type UserBlockRepository interface {
+	// SetUserBlock 指定したユーザーをブロック・ミュートします
+	//
+	// 成功した場合、nilを返します。
+	// 既にブロック・ミュートしている場合は種類を上書きします。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 自分自身を指定した場合、種類が不正な場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	SetUserBlock(userID, targetID uuid.UUID, blockType model.UserBlockType) error
+	// DeleteUserBlock 指定したユーザーのブロック・ミュートを解除します
+	//
+	// 成功した場合、nilを返します。
+	// ブロック・ミュートしていなかった場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteUserBlock(userID, targetID uuid.UUID) error
+	// GetUserBlocks 指定したユーザーがブロック・ミュートしているユーザーの一覧を取得します
+	//
+	// 成功した場合、ブロックの配列とnilを返します。
+	// 存在しないユーザーを指定した場合は空配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserBlocks(userID uuid.UUID) ([]*model.UserBlock, error)
+	// GetUsersBlocking 指定したユーザーをブロックしているユーザーのIDのセットを取得します
+	//
+	// 成功した場合、ユーザーIDのセットとnilを返します。
+	// includeMutedがtrueの場合、ミュートしているユーザーも含めます。
+	// DBによるエラーを返すことがあります。
+	GetUsersBlocking(targetID uuid.UUID, includeMuted bool) (set.UUID, error)
+	// IsBlockedBetween 指定した2ユーザー間のどちらかが相手をブロックしているかどうかを返します
+	//
+	// ミュートは含みません。
+	// 成功した場合、ブロックの有無とnilを返します。
+	// DBによるエラーを返すことがあります。
+	IsBlockedBetween(user1, user2 uuid.UUID) (bool, error)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// SetUserBlock implements UserBlockRepository interface.
+func (repo *GormRepository) SetUserBlock(userID, targetID uuid.UUID, blockType model.UserBlockType) error {
+	if userID == uuid.Nil || targetID == uuid.Nil {
+		return ErrNilID
+	}
+	if userID == targetID {
+		return ArgError("targetID", "you cannot block yourself")
+	}
+	if !blockType.Valid() {
+		return ArgError("type", "invalid block type")
+	}
+	return repo.db.
+		Set("gorm:insert_option", "ON DUPLICATE KEY UPDATE type = VALUES(type)").
+		Create(&model.UserBlock{UserID: userID, TargetID: targetID, Type: blockType}).
+		Error
+}
+
+// DeleteUserBlock implements UserBlockRepository interface.
+func (repo *GormRepository) DeleteUserBlock(userID, targetID uuid.UUID) error {
+	if userID == uuid.Nil || targetID == uuid.Nil {
+		return ErrNilID
+	}
+	result := repo.db.Delete(&model.UserBlock{}, &model.UserBlock{UserID: userID, TargetID: targetID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetUserBlocks implements UserBlockRepository interface.
+func (repo *GormRepository) GetUserBlocks(userID uuid.UUID) ([]*model.UserBlock, error) {
+	result := make([]*model.UserBlock, 0)
+	if userID == uuid.Nil {
+		return result, nil
+	}
+	return result, repo.db.Where(&model.UserBlock{UserID: userID}).Order("created_at").Find(&result).Error
+}
+
+// GetUsersBlocking implements UserBlockRepository interface.
+func (repo *GormRepository) GetUsersBlocking(targetID uuid.UUID, includeMuted bool) (set.UUID, error) {
+	result := set.UUID{}
+	if targetID == uuid.Nil {
+		return result, nil
+	}
+	var ids []uuid.UUID
+	tx := repo.db.Model(&model.UserBlock{}).Where("target_id = ?", targetID)
+	if !includeMuted {
+		tx = tx.Where("type = ?", model.UserBlockTypeBlock)
+	}
+	if err := tx.Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	result.Add(ids...)
+	return result, nil
+}
+
+// IsBlockedBetween implements UserBlockRepository interface.
+func (repo *GormRepository) IsBlockedBetween(user1, user2 uuid.UUID) (bool, error) {
+	if user1 == uuid.Nil || user2 == uuid.Nil || user1 == user2 {
+		return false, nil
+	}
+	return gormutil.Exists(repo.db.
+		Model(&model.UserBlock{}).
+		Where("((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?)) AND type = ?", user1, user2, user2, user1, model.UserBlockTypeBlock))
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"testing"
+)
+
+func TestRepositoryImpl_SetUserBlock(t *testing.T) {
+	t.Parallel()
+	repo, assert, _ := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	target := mustMakeUser(t, repo, rand)
+
+	assert.EqualError(repo.SetUserBlock(uuid.Nil, target.GetID(), model.UserBlockTypeBlock), ErrNilID.Error())
+	assert.EqualError(repo.SetUserBlock(user.GetID(), uuid.Nil, model.UserBlockTypeBlock), ErrNilID.Error())
+	assert.Error(repo.SetUserBlock(user.GetID(), user.GetID(), model.UserBlockTypeBlock))
+	assert.Error(repo.SetUserBlock(user.GetID(), target.GetID(), "ban"))
+
+	if assert.NoError(repo.SetUserBlock(user.GetID(), target.GetID(), model.UserBlockTypeMute)) {
+		blocks, err := repo.GetUserBlocks(user.GetID())
+		if assert.NoError(err) && assert.Len(blocks, 1) {
+			assert.Equal(target.GetID(), blocks[0].TargetID)
+			assert.Equal(model.UserBlockTypeMute, blocks[0].Type)
+		}
+	}
+
+	// 上書き
+	if assert.NoError(repo.SetUserBlock(user.GetID(), target.GetID(), model.UserBlockTypeBlock)) {
+		blocks, err := repo.GetUserBlocks(user.GetID())
+		if assert.NoError(err) && assert.Len(blocks, 1) {
+			assert.Equal(model.UserBlockTypeBlock, blocks[0].Type)
+		}
+	}
+}
+
+func TestRepositoryImpl_DeleteUserBlock(t *testing.T) {
+	t.Parallel()
+	repo, assert, _ := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	target := mustMakeUser(t, repo, rand)
+
+	assert.EqualError(repo.DeleteUserBlock(uuid.Nil, target.GetID()), ErrNilID.Error())
+	assert.EqualError(repo.DeleteUserBlock(user.GetID(), target.GetID()), ErrNotFound.Error())
+
+	assert.NoError(repo.SetUserBlock(user.GetID(), target.GetID(), model.UserBlockTypeBlock))
+	if assert.NoError(repo.DeleteUserBlock(user.GetID(), target.GetID())) {
+		blocks, err := repo.GetUserBlocks(user.GetID())
+		if assert.NoError(err) {
+			assert.Len(blocks, 0)
+		}
+	}
+}
+
+func TestRepositoryImpl_GetUsersBlocking(t *testing.T) {
+	t.Parallel()
+	repo, assert, _ := setup(t, common)
+
+	target := mustMakeUser(t, repo, rand)
+	blocker := mustMakeUser(t, repo, rand)
+	muter := mustMakeUser(t, repo, rand)
+	assert.NoError(repo.SetUserBlock(blocker.GetID(), target.GetID(), model.UserBlockTypeBlock))
+	assert.NoError(repo.SetUserBlock(muter.GetID(), target.GetID(), model.UserBlockTypeMute))
+
+	ids, err := repo.GetUsersBlocking(target.GetID(), false)
+	if assert.NoError(err) {
+		assert.Len(ids, 1)
+		assert.True(ids.Contains(blocker.GetID()))
+	}
+
+	ids, err = repo.GetUsersBlocking(target.GetID(), true)
+	if assert.NoError(err) {
+		assert.Len(ids, 2)
+		assert.True(ids.Contains(blocker.GetID()))
+		assert.True(ids.Contains(muter.GetID()))
+	}
+
+	ids, err = repo.GetUsersBlocking(uuid.Nil, true)
+	if assert.NoError(err) {
+		assert.Len(ids, 0)
+	}
+}
+
+func TestRepositoryImpl_IsBlockedBetween(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	user1 := mustMakeUser(t, repo, rand)
+	user2 := mustMakeUser(t, repo, rand)
+	user3 := mustMakeUser(t, repo, rand)
+	if !assert.NoError(t, repo.SetUserBlock(user1.GetID(), user2.GetID(), model.UserBlockTypeBlock)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, repo.SetUserBlock(user1.GetID(), user3.GetID(), model.UserBlockTypeMute)) {
+		t.FailNow()
+	}
+
+	cases := []struct {
+		name     string
+		user1    uuid.UUID
+		user2    uuid.UUID
+		expected bool
+	}{
+		{"blocking", user1.GetID(), user2.GetID(), true},
+		{"blocked", user2.GetID(), user1.GetID(), true},
+		{"muted", user1.GetID(), user3.GetID(), false},
+		{"none", user2.GetID(), user3.GetID(), false},
+		{"nil", uuid.Nil, user2.GetID(), false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			ok, err := repo.IsBlockedBetween(c.user1, c.user2)
+			if assert.NoError(t, err) {
+				assert.Equal(t, c.expected, ok)
+			}
+		})
+	}
+}
@@ -17,6 +17,19 @@ import (
 
 // CreateUser implements UserRepository interface.
 func (repo *GormRepository) CreateUser(args CreateUserArgs) (model.UserInfo, error) {
+	user, err := repo.newUser(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.db.Transaction(func(tx *gorm.DB) error { return createUser(tx, user, args.ExternalLogin) }); err != nil {
+		return nil, err
+	}
+	repo.publishUserCreated(user)
+	return user, nil
+}
+
+// newUser 作成するユーザーを組み立てます
+func (repo *GormRepository) newUser(args CreateUserArgs) (*model.User, error) {
 	uid := uuid.Must(uuid.NewV4())
 	user := &model.User{
 		ID:          uid,
@@ -47,30 +60,32 @@ func (repo *GormRepository) CreateUser(args CreateUserArgs) (model.UserInfo, err
 	if args.ExternalLogin != nil {
 		args.ExternalLogin.UserID = uid
 	}
+	return user, nil
+}
 
-	err := repo.db.Transaction(func(tx *gorm.DB) error {
-		if exist, err := gormutil.RecordExists(tx, &model.User{Name: user.Name}); err != nil {
-			return err
-		} else if exist {
-			return ErrAlreadyExists
-		}
+// createUser トランザクション内でユーザーを作成します
+func createUser(tx *gorm.DB, user *model.User, externalLogin *model.ExternalProviderUser) error {
+	if exist, err := gormutil.RecordExists(tx, &model.User{Name: user.Name}); err != nil {
+		return err
+	} else if exist {
+		return ErrAlreadyExists
+	}
 
-		if err := tx.Create(user).Error; err != nil {
-			return err
-		}
-		if err := tx.Create(user.Profile).Error; err != nil {
+	if err := tx.Create(user).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(user.Profile).Error; err != nil {
+		return err
+	}
+	if externalLogin != nil {
+		if err := tx.Create(externalLogin).Error; err != nil {
 			return err
 		}
-		if args.ExternalLogin != nil {
-			if err := tx.Create(args.ExternalLogin).Error; err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
 	}
+	return nil
+}
+
+func (repo *GormRepository) publishUserCreated(user *model.User) {
 	repo.hub.Publish(hub.Message{
 		Name: event.UserCreated,
 		Fields: hub.Fields{
@@ -78,7 +93,6 @@ func (repo *GormRepository) CreateUser(args CreateUserArgs) (model.UserInfo, err
 			"user":    user,
 		},
 	})
-	return user, nil
 }
 
 // GetUser implements UserRepository interface.
@@ -204,6 +218,19 @@ func (repo *GormRepository) UpdateUser(id uuid.UUID, args UpdateUserArgs) error
 			changes["display_name"] = args.DisplayName.String
 		}
 		if args.Role.Valid {
+			var r model.UserRole
+			if len(args.Role.String) == 0 {
+				return ArgError("args.Role", "Role is required")
+			}
+			if err := tx.First(&r, &model.UserRole{Name: args.Role.String}).Error; err != nil {
+				if gorm.IsRecordNotFoundError(err) {
+					return ArgError("args.Role", "the Role doesn't exist")
+				}
+				return err
+			}
+			if r.Oauth2Scope {
+				return ArgError("args.Role", "the Role cannot be assigned to users")
+			}
 			changes["role"] = args.Role.String
 		}
 		if args.UserState.Valid {
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// UserStatusRepository ユーザーステータスリポジトリ
+type UserStatusRepository interface {
+	// GetUserStatuses 設定されている全てのユーザーステータスを取得します
+	//
+	// 成功した場合、ステータスの配列とnilを返します。
+	// ステータスを一度も設定していないユーザーのステータスは含まれません。
+	// DBによるエラーを返すことがあります。
+	GetUserStatuses() ([]*model.UserStatus, error)
+	// SetUserStatusMessage 指定したユーザーのステータスメッセージを設定します
+	//
+	// 成功した場合、更新後のステータスとnilを返します。
+	// textが空文字列かつstampIDが無効な場合、ステータスメッセージを消去します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	SetUserStatusMessage(userID uuid.UUID, text string, stampID optional.UUID, expiresAt optional.Time) (*model.UserStatus, error)
+	// SetUserPresenceMode 指定したユーザーのプレゼンスモードを設定します
+	//
+	// 成功した場合、更新後のステータスとnilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// modeが不正な場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	SetUserPresenceMode(userID uuid.UUID, mode model.PresenceMode) (*model.UserStatus, error)
+	// UpdateUsersLastOnline 指定したユーザーの最終オンライン日時を一括で更新します
+	//
+	// 成功した場合、nilを返します。
+	// UserUpdatedイベントは発生しません。
+	// DBによるエラーを返すことがあります。
+	UpdateUsersLastOnline(userIDs set.UUID, datetime time.Time) error
+}
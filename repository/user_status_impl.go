@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// GetUserStatuses implements UserStatusRepository interface.
+func (repo *GormRepository) GetUserStatuses() ([]*model.UserStatus, error) {
+	result := make([]*model.UserStatus, 0)
+	return result, repo.db.Find(&result).Error
+}
+
+// SetUserStatusMessage implements UserStatusRepository interface.
+func (repo *GormRepository) SetUserStatusMessage(userID uuid.UUID, text string, stampID optional.UUID, expiresAt optional.Time) (*model.UserStatus, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if stampID.Valid && stampID.UUID == uuid.Nil {
+		stampID = optional.UUID{}
+	}
+	if len(text) == 0 && !stampID.Valid {
+		expiresAt = optional.Time{}
+	}
+	return repo.updateUserStatus(userID, map[string]interface{}{
+		"text":       text,
+		"stamp_id":   stampID,
+		"expires_at": expiresAt,
+	})
+}
+
+// SetUserPresenceMode implements UserStatusRepository interface.
+func (repo *GormRepository) SetUserPresenceMode(userID uuid.UUID, mode model.PresenceMode) (*model.UserStatus, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if !mode.Valid() {
+		return nil, ArgError("mode", "invalid presence mode")
+	}
+	return repo.updateUserStatus(userID, map[string]interface{}{
+		"mode": mode,
+	})
+}
+
+func (repo *GormRepository) updateUserStatus(userID uuid.UUID, changes map[string]interface{}) (*model.UserStatus, error) {
+	var s model.UserStatus
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&s, &model.UserStatus{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = model.UserStatus{
+				UserID: userID,
+				Mode:   model.PresenceModeAuto,
+			}
+			if err := tx.Create(&s).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&s).Updates(changes).Error; err != nil {
+			return err
+		}
+		return tx.First(&s, &model.UserStatus{UserID: userID}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// UpdateUsersLastOnline implements UserStatusRepository interface.
+func (repo *GormRepository) UpdateUsersLastOnline(userIDs set.UUID, datetime time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return repo.db.Model(&model.UserProfile{}).Where("user_id IN (?)", userIDs.StringArray()).UpdateColumn("last_online", datetime).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_SetUserStatusMessage(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		_, err := repo.SetUserStatusMessage(uuid.Nil, "a", optional.UUID{}, optional.Time{})
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		user := mustMakeUser(t, repo, rand)
+		stamp := mustMakeStamp(t, repo, rand, uuid.Nil)
+		expires := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+
+		s, err := repo.SetUserStatusMessage(user.GetID(), "lunch", optional.UUIDFrom(stamp.ID), optional.TimeFrom(expires))
+		if assert.NoError(err) {
+			assert.Equal("lunch", s.Text)
+			assert.Equal(stamp.ID, s.StampID.UUID)
+			assert.True(s.ExpiresAt.Valid)
+			assert.Equal(model.PresenceModeAuto, s.Mode)
+		}
+
+		// 消去すると期限も消える
+		s, err = repo.SetUserStatusMessage(user.GetID(), "", optional.UUID{}, optional.TimeFrom(expires))
+		if assert.NoError(err) {
+			assert.False(s.HasMessage())
+			assert.False(s.ExpiresAt.Valid)
+		}
+	})
+}
+
+func TestRepositoryImpl_SetUserPresenceMode(t *testing.T) {
+	t.Parallel()
+	repo, assert, _ := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	_, err := repo.SetUserPresenceMode(uuid.Nil, model.PresenceModeBusy)
+	assert.EqualError(err, ErrNilID.Error())
+	_, err = repo.SetUserPresenceMode(user.GetID(), "online")
+	assert.Error(err)
+
+	_, err = repo.SetUserStatusMessage(user.GetID(), "meeting", optional.UUID{}, optional.Time{})
+	assert.NoError(err)
+	s, err := repo.SetUserPresenceMode(user.GetID(), model.PresenceModeBusy)
+	if assert.NoError(err) {
+		assert.Equal(model.PresenceModeBusy, s.Mode)
+		assert.Equal("meeting", s.Text)
+	}
+
+	statuses, err := repo.GetUserStatuses()
+	if assert.NoError(err) {
+		found := false
+		for _, v := range statuses {
+			if v.UserID == user.GetID() {
+				found = true
+				assert.Equal(model.PresenceModeBusy, v.Mode)
+			}
+		}
+		assert.True(found)
+	}
+}
+
+func TestRepositoryImpl_UpdateUsersLastOnline(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user1 := mustMakeUser(t, repo, rand)
+	user2 := mustMakeUser(t, repo, rand)
+	now := time.Now().Truncate(time.Microsecond)
+
+	assert.NoError(repo.UpdateUsersLastOnline(nil, now))
+	assert.NoError(repo.UpdateUsersLastOnline(set.UUIDSetFromArray([]uuid.UUID{user1.GetID(), user2.GetID()}), now))
+
+	for _, id := range []uuid.UUID{user1.GetID(), user2.GetID()} {
+		u, err := repo.GetUser(id, true)
+		require.NoError(err)
+		assert.True(u.GetLastOnline().Valid)
+		assert.WithinDuration(now, u.GetLastOnline().Time, time.Second)
+	}
+}
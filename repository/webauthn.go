@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+)
+
+// WebAuthnRepository WebAuthn認証情報リポジトリ
+type WebAuthnRepository interface {
+	// CreateWebAuthnCredential WebAuthn認証情報を登録します
+	//
+	// 成功した場合、登録した認証情報とnilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 同じCredential IDが既に登録されていた場合、ErrAlreadyExistsを返します。
+	// DBによるエラーを返すことがあります。
+	CreateWebAuthnCredential(userID uuid.UUID, name string, credentialID string, publicKey []byte, signCount uint32, aaguid []byte) (*model.WebAuthnCredential, error)
+	// GetWebAuthnCredentials 指定したユーザーのWebAuthn認証情報を全て取得します
+	//
+	// 成功した場合、登録日時の昇順の認証情報の配列とnilを返します。
+	// 存在しないユーザーを指定した場合は空配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetWebAuthnCredentials(userID uuid.UUID) ([]*model.WebAuthnCredential, error)
+	// GetWebAuthnCredential 指定したIDのWebAuthn認証情報を取得します
+	//
+	// 成功した場合、認証情報とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetWebAuthnCredential(id uuid.UUID) (*model.WebAuthnCredential, error)
+	// GetWebAuthnCredentialByCredentialID 指定したCredential IDのWebAuthn認証情報を取得します
+	//
+	// 成功した場合、認証情報とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetWebAuthnCredentialByCredentialID(credentialID string) (*model.WebAuthnCredential, error)
+	// HasWebAuthnCredentials 指定したユーザーがWebAuthn認証情報を登録しているかどうかを返します
+	//
+	// DBによるエラーを返すことがあります。
+	HasWebAuthnCredentials(userID uuid.UUID) (bool, error)
+	// RenameWebAuthnCredential 指定したWebAuthn認証情報の表示名を変更します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	RenameWebAuthnCredential(id uuid.UUID, name string) error
+	// UpdateWebAuthnCredentialUsage 指定したWebAuthn認証情報の署名カウンタと最終使用日時を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateWebAuthnCredentialUsage(id uuid.UUID, signCount uint32) error
+	// DeleteWebAuthnCredential 指定したWebAuthn認証情報を削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteWebAuthnCredential(id uuid.UUID) error
+}
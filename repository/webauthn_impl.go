@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// CreateWebAuthnCredential implements WebAuthnRepository interface.
+func (repo *GormRepository) CreateWebAuthnCredential(userID uuid.UUID, name string, credentialID string, publicKey []byte, signCount uint32, aaguid []byte) (*model.WebAuthnCredential, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	c := &model.WebAuthnCredential{
+		ID:           uuid.Must(uuid.NewV4()),
+		UserID:       userID,
+		Name:         name,
+		CredentialID: credentialID,
+		PublicKey:    publicKey,
+		SignCount:    signCount,
+		AAGUID:       aaguid,
+	}
+	if err := repo.db.Create(c).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetWebAuthnCredentials implements WebAuthnRepository interface.
+func (repo *GormRepository) GetWebAuthnCredentials(userID uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	result := make([]*model.WebAuthnCredential, 0)
+	if userID == uuid.Nil {
+		return result, nil
+	}
+	return result, repo.db.
+		Where(&model.WebAuthnCredential{UserID: userID}).
+		Order("created_at").
+		Find(&result).
+		Error
+}
+
+// GetWebAuthnCredential implements WebAuthnRepository interface.
+func (repo *GormRepository) GetWebAuthnCredential(id uuid.UUID) (*model.WebAuthnCredential, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var c model.WebAuthnCredential
+	if err := repo.db.First(&c, &model.WebAuthnCredential{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &c, nil
+}
+
+// GetWebAuthnCredentialByCredentialID implements WebAuthnRepository interface.
+func (repo *GormRepository) GetWebAuthnCredentialByCredentialID(credentialID string) (*model.WebAuthnCredential, error) {
+	if len(credentialID) == 0 {
+		return nil, ErrNotFound
+	}
+	var c model.WebAuthnCredential
+	if err := repo.db.First(&c, &model.WebAuthnCredential{CredentialID: credentialID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &c, nil
+}
+
+// HasWebAuthnCredentials implements WebAuthnRepository interface.
+func (repo *GormRepository) HasWebAuthnCredentials(userID uuid.UUID) (bool, error) {
+	if userID == uuid.Nil {
+		return false, nil
+	}
+	return gormutil.RecordExists(repo.db, &model.WebAuthnCredential{UserID: userID})
+}
+
+// RenameWebAuthnCredential implements WebAuthnRepository interface.
+func (repo *GormRepository) RenameWebAuthnCredential(id uuid.UUID, name string) error {
+	if id == uuid.Nil {
+		return ErrNotFound
+	}
+	result := repo.db.Model(&model.WebAuthnCredential{ID: id}).Update("name", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if ok, err := gormutil.RecordExists(repo.db, &model.WebAuthnCredential{ID: id}); err != nil {
+			return err
+		} else if !ok {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// UpdateWebAuthnCredentialUsage implements WebAuthnRepository interface.
+func (repo *GormRepository) UpdateWebAuthnCredentialUsage(id uuid.UUID, signCount uint32) error {
+	if id == uuid.Nil {
+		return ErrNotFound
+	}
+	result := repo.db.Model(&model.WebAuthnCredential{ID: id}).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"last_used_at": optional.TimeFrom(time.Now()),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWebAuthnCredential implements WebAuthnRepository interface.
+func (repo *GormRepository) DeleteWebAuthnCredential(id uuid.UUID) error {
+	if id == uuid.Nil {
+		return ErrNotFound
+	}
+	result := repo.db.Delete(&model.WebAuthnCredential{ID: id})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateWebAuthnCredential(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	_, err := repo.CreateWebAuthnCredential(uuid.Nil, "key", "cred-nil", []byte{1}, 0, nil)
+	assert.EqualError(err, ErrNilID.Error())
+
+	c, err := repo.CreateWebAuthnCredential(user.GetID(), "key", "cred-"+user.GetID().String(), []byte{1, 2, 3}, 5, make([]byte, 16))
+	require.NoError(err)
+	assert.NotEqual(uuid.Nil, c.ID)
+	assert.Equal(user.GetID(), c.UserID)
+	assert.EqualValues(5, c.SignCount)
+
+	_, err = repo.CreateWebAuthnCredential(user.GetID(), "key2", "cred-"+user.GetID().String(), []byte{1}, 0, nil)
+	assert.EqualError(err, ErrAlreadyExists.Error())
+}
+
+func TestRepositoryImpl_GetWebAuthnCredentials(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+
+	has, err := repo.HasWebAuthnCredentials(user.GetID())
+	require.NoError(err)
+	assert.False(has)
+
+	c1, err := repo.CreateWebAuthnCredential(user.GetID(), "key1", "cred1-"+user.GetID().String(), []byte{1}, 0, nil)
+	require.NoError(err)
+	c2, err := repo.CreateWebAuthnCredential(user.GetID(), "key2", "cred2-"+user.GetID().String(), []byte{2}, 0, nil)
+	require.NoError(err)
+
+	cs, err := repo.GetWebAuthnCredentials(user.GetID())
+	if assert.NoError(err) && assert.Len(cs, 2) {
+		assert.Equal(c1.ID, cs[0].ID)
+		assert.Equal(c2.ID, cs[1].ID)
+	}
+
+	has, err = repo.HasWebAuthnCredentials(user.GetID())
+	require.NoError(err)
+	assert.True(has)
+
+	cs, err = repo.GetWebAuthnCredentials(uuid.Nil)
+	if assert.NoError(err) {
+		assert.Len(cs, 0)
+	}
+
+	c, err := repo.GetWebAuthnCredentialByCredentialID(c2.CredentialID)
+	if assert.NoError(err) {
+		assert.Equal(c2.ID, c.ID)
+		assert.Equal([]byte{2}, c.PublicKey)
+	}
+	_, err = repo.GetWebAuthnCredentialByCredentialID("not-found")
+	assert.EqualError(err, ErrNotFound.Error())
+
+	c, err = repo.GetWebAuthnCredential(c1.ID)
+	if assert.NoError(err) {
+		assert.Equal("key1", c.Name)
+	}
+	_, err = repo.GetWebAuthnCredential(uuid.Must(uuid.NewV4()))
+	assert.EqualError(err, ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_UpdateWebAuthnCredential(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	c, err := repo.CreateWebAuthnCredential(user.GetID(), "key", "cred-"+user.GetID().String(), []byte{1}, 0, nil)
+	require.NoError(err)
+
+	assert.EqualError(repo.RenameWebAuthnCredential(uuid.Must(uuid.NewV4()), "a"), ErrNotFound.Error())
+	assert.NoError(repo.RenameWebAuthnCredential(c.ID, "renamed"))
+	assert.NoError(repo.RenameWebAuthnCredential(c.ID, "renamed"))
+
+	assert.EqualError(repo.UpdateWebAuthnCredentialUsage(uuid.Must(uuid.NewV4()), 1), ErrNotFound.Error())
+	assert.NoError(repo.UpdateWebAuthnCredentialUsage(c.ID, 3))
+
+	c, err = repo.GetWebAuthnCredential(c.ID)
+	if assert.NoError(err) {
+		assert.Equal("renamed", c.Name)
+		assert.EqualValues(3, c.SignCount)
+		assert.True(c.LastUsedAt.Valid)
+	}
+}
+
+func TestRepositoryImpl_DeleteWebAuthnCredential(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	user := mustMakeUser(t, repo, rand)
+	c, err := repo.CreateWebAuthnCredential(user.GetID(), "key", "cred-"+user.GetID().String(), []byte{1}, 0, nil)
+	require.NoError(err)
+
+	assert.EqualError(repo.DeleteWebAuthnCredential(uuid.Nil), ErrNotFound.Error())
+	assert.NoError(repo.DeleteWebAuthnCredential(c.ID))
+	assert.EqualError(repo.DeleteWebAuthnCredential(c.ID), ErrNotFound.Error())
+}
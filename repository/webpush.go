@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// WebPushRepository Web Push購読リポジトリ
+type WebPushRepository interface {
+	// RegisterWebPushSubscription Web Push購読を登録します
+	//
+	// 成功した場合、購読とnilを返します。
+	// 同じエンドポイントが既に登録されていた場合は、鍵と所有ユーザーを更新します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// endpoint, p256dh, authが空文字列の場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	RegisterWebPushSubscription(userID uuid.UUID, endpoint, p256dh, auth string) (*model.WebPushSubscription, error)
+	// GetWebPushSubscriptions 指定したユーザーの全Web Push購読を取得します
+	//
+	// 成功した場合、ユーザーIDをキーとした購読の配列のマップとnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetWebPushSubscriptions(userIDs set.UUID) (map[uuid.UUID][]*model.WebPushSubscription, error)
+	// DeleteWebPushSubscription 指定したユーザーのWeb Push購読を削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteWebPushSubscription(userID, id uuid.UUID) error
+	// DeleteWebPushSubscriptionsByEndpoints 指定したエンドポイントのWeb Push購読を削除します
+	//
+	// 成功した、或いは既に削除されていた場合にnilを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteWebPushSubscriptionsByEndpoints(endpoints []string) error
+}
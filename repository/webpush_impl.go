@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// RegisterWebPushSubscription implements WebPushRepository interface.
+func (repo *GormRepository) RegisterWebPushSubscription(userID uuid.UUID, endpoint, p256dh, auth string) (*model.WebPushSubscription, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNilID
+	}
+	if len(endpoint) == 0 {
+		return nil, ArgError("endpoint", "endpoint is empty")
+	}
+	if len(p256dh) == 0 || len(auth) == 0 {
+		return nil, ArgError("keys", "keys are empty")
+	}
+
+	var s model.WebPushSubscription
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&s, &model.WebPushSubscription{Endpoint: endpoint}).Error; err == nil {
+			// 同じブラウザで別のユーザーが購読した場合は所有者を付け替える
+			return tx.Model(&s).Updates(map[string]interface{}{
+				"user_id": userID,
+				"p256dh":  p256dh,
+				"auth":    auth,
+			}).Error
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		s = model.WebPushSubscription{
+			ID:       uuid.Must(uuid.NewV4()),
+			UserID:   userID,
+			Endpoint: endpoint,
+			P256dh:   p256dh,
+			Auth:     auth,
+		}
+		return tx.Create(&s).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetWebPushSubscriptions implements WebPushRepository interface.
+func (repo *GormRepository) GetWebPushSubscriptions(userIDs set.UUID) (map[uuid.UUID][]*model.WebPushSubscription, error) {
+	result := make(map[uuid.UUID][]*model.WebPushSubscription, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var tmp []*model.WebPushSubscription
+	if err := repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&tmp).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range tmp {
+		result[s.UserID] = append(result[s.UserID], s)
+	}
+	return result, nil
+}
+
+// DeleteWebPushSubscription implements WebPushRepository interface.
+func (repo *GormRepository) DeleteWebPushSubscription(userID, id uuid.UUID) error {
+	if userID == uuid.Nil || id == uuid.Nil {
+		return ErrNilID
+	}
+	result := repo.db.Delete(&model.WebPushSubscription{}, &model.WebPushSubscription{ID: id, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteWebPushSubscriptionsByEndpoints implements WebPushRepository interface.
+func (repo *GormRepository) DeleteWebPushSubscriptionsByEndpoints(endpoints []string) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	return repo.db.Where("endpoint IN (?)", endpoints).Delete(&model.WebPushSubscription{}).Error
+}
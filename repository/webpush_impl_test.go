@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	random2 "github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+)
+
+func TestRepositoryImpl_RegisterWebPushSubscription(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	endpoint := "https://push.example.com/" + random2.AlphaNumeric(20)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		_, err := repo.RegisterWebPushSubscription(uuid.Nil, endpoint, "p256dh", "auth")
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("empty args", func(t *testing.T) {
+		t.Parallel()
+		_, err := repo.RegisterWebPushSubscription(id1, "", "p256dh", "auth")
+		assert.Error(t, err)
+		_, err = repo.RegisterWebPushSubscription(id1, endpoint, "", "auth")
+		assert.Error(t, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		s1, err := repo.RegisterWebPushSubscription(id1, endpoint, "p256dh", "auth")
+		if assert.NoError(err) {
+			assert.Equal(id1, s1.UserID)
+			assert.Equal(endpoint, s1.Endpoint)
+		}
+
+		// 同じエンドポイントは付け替えられる
+		s2, err := repo.RegisterWebPushSubscription(id2, endpoint, "p256dh2", "auth2")
+		if assert.NoError(err) {
+			assert.Equal(s1.ID, s2.ID)
+			assert.Equal(id2, s2.UserID)
+			assert.Equal("p256dh2", s2.P256dh)
+		}
+		assert.EqualValues(1, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("endpoint = ?", endpoint)))
+	})
+}
+
+func TestRepositoryImpl_GetWebPushSubscriptions(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	for i := 0; i < 2; i++ {
+		_, err := repo.RegisterWebPushSubscription(id1, "https://push.example.com/"+random2.AlphaNumeric(20), "p256dh", "auth")
+		require.NoError(err)
+	}
+
+	subs, err := repo.GetWebPushSubscriptions(set.UUIDSetFromArray([]uuid.UUID{id1, id2}))
+	if assert.NoError(err) {
+		assert.Len(subs[id1], 2)
+		assert.Len(subs[id2], 0)
+	}
+}
+
+func TestRepositoryImpl_DeleteWebPushSubscription(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	s, err := repo.RegisterWebPushSubscription(id1, "https://push.example.com/"+random2.AlphaNumeric(20), "p256dh", "auth")
+	require.NoError(err)
+
+	assert.EqualError(repo.DeleteWebPushSubscription(uuid.Nil, s.ID), ErrNilID.Error())
+	assert.EqualError(repo.DeleteWebPushSubscription(id2, s.ID), ErrNotFound.Error())
+	assert.NoError(repo.DeleteWebPushSubscription(id1, s.ID))
+	assert.EqualError(repo.DeleteWebPushSubscription(id1, s.ID), ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_DeleteWebPushSubscriptionsByEndpoints(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id := mustMakeUser(t, repo, rand).GetID()
+	e1 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	e2 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	_, err := repo.RegisterWebPushSubscription(id, e1, "p256dh", "auth")
+	require.NoError(err)
+	_, err = repo.RegisterWebPushSubscription(id, e2, "p256dh", "auth")
+	require.NoError(err)
+
+	assert.NoError(repo.DeleteWebPushSubscriptionsByEndpoints(nil))
+	assert.NoError(repo.DeleteWebPushSubscriptionsByEndpoints([]string{e1}))
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("user_id = ?", id)))
+}
@@ -0,0 +1,473 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	LDAPProviderName    = "ldap"
+	ldapErrorFormat     = "ldap error: %w"
+	ldapUsernameHolder  = "{username}"
+	ldapDNHolder        = "{dn}"
+	defaultLDAPFilter   = "(uid=" + ldapUsernameHolder + ")"
+	defaultLDAPIDAttr   = "uid"
+	defaultLDAPNameAttr = "uid"
+)
+
+var (
+	errLDAPUserNotFound  = errors.New("ldap user not found")
+	errLDAPWrongPassword = errors.New("ldap wrong password")
+)
+
+type LDAPProvider struct {
+	config    LDAPProviderConfig
+	repo      repository.Repository
+	logger    *zap.Logger
+	sessStore session.Store
+}
+
+type LDAPProviderConfig struct {
+	// URL LDAPサーバーのURL (ldap://host:port または ldaps://host:port)
+	URL string
+	// StartTLS ldap://で接続した後にStartTLSを行うかどうか
+	StartTLS bool
+	// InsecureSkipVerify サーバー証明書の検証を行わないかどうか
+	InsecureSkipVerify bool
+	// BindDN ユーザー検索時にBindするDN (空の場合は匿名Bind)
+	BindDN string
+	// BindPassword ユーザー検索時にBindするパスワード
+	BindPassword string
+	// BaseDN ユーザー検索の起点DN
+	BaseDN string
+	// UserFilter ユーザー検索フィルター ({username}がエスケープ済みのユーザー名に置換されます) (default: (uid={username}))
+	UserFilter string
+	// IDAttribute ユーザーの外部IDとする属性 (default: uid)
+	IDAttribute string
+	// NameAttribute traQのユーザー名とする属性 (default: uid)
+	NameAttribute string
+	// DisplayNameAttribute 表示名とする属性 (空の場合は設定しない)
+	DisplayNameAttribute string
+	// IconAttribute アイコン画像とする属性 (空の場合は設定しない)
+	IconAttribute string
+	// GroupBaseDN グループ検索の起点DN (空の場合はBaseDN)
+	GroupBaseDN string
+	// GroupFilter グループ検索フィルター ({dn}, {username}がエスケープ済みの値に置換されます) (空の場合はグループを同期しない)
+	GroupFilter string
+	// GroupNameAttribute グループ名とする属性 (default: cn)
+	GroupNameAttribute string
+	// GroupMapping LDAPのグループ名からtraQのユーザーグループ名への対応
+	//
+	// ここに含まれるtraQのユーザーグループのメンバーのみを、ログイン時にLDAPのグループ所属に合わせて同期します。
+	// LDAPのグループ名は大文字小文字を区別しません。
+	GroupMapping           map[string]string
+	RegisterUserIfNotFound bool
+}
+
+func (c LDAPProviderConfig) Valid() bool {
+	return len(c.URL) > 0 && len(c.BaseDN) > 0
+}
+
+type ldapUserInfo struct {
+	id          string
+	name        string
+	displayName string
+	icon        []byte
+	groups      []string
+}
+
+func (u *ldapUserInfo) GetProviderName() string {
+	return LDAPProviderName
+}
+
+func (u *ldapUserInfo) GetID() string {
+	return u.id
+}
+
+func (u *ldapUserInfo) GetRawName() string {
+	return u.name
+}
+
+func (u *ldapUserInfo) GetName() string {
+	regex := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	s := regex.ReplaceAllLiteralString(u.name, "_")
+	if us := utf8string.NewString(s); us.RuneCount() > 32 {
+		s = us.Slice(0, 32)
+	}
+	return s
+}
+
+func (u *ldapUserInfo) GetDisplayName() string {
+	if s := utf8string.NewString(u.displayName); s.RuneCount() > 64 {
+		return s.Slice(0, 64)
+	}
+	return u.displayName
+}
+
+func (u *ldapUserInfo) GetProfileImage() ([]byte, error) {
+	return u.icon, nil
+}
+
+func (u *ldapUserInfo) IsLoginAllowedUser() bool {
+	return true
+}
+
+func NewLDAPProvider(repo repository.Repository, logger *zap.Logger, sessStore session.Store, config LDAPProviderConfig) *LDAPProvider {
+	if len(config.UserFilter) == 0 {
+		config.UserFilter = defaultLDAPFilter
+	}
+	if len(config.IDAttribute) == 0 {
+		config.IDAttribute = defaultLDAPIDAttr
+	}
+	if len(config.NameAttribute) == 0 {
+		config.NameAttribute = defaultLDAPNameAttr
+	}
+	if len(config.GroupBaseDN) == 0 {
+		config.GroupBaseDN = config.BaseDN
+	}
+	if len(config.GroupNameAttribute) == 0 {
+		config.GroupNameAttribute = "cn"
+	}
+	mapping := make(map[string]string, len(config.GroupMapping))
+	for k, v := range config.GroupMapping {
+		mapping[strings.ToLower(k)] = v
+	}
+	config.GroupMapping = mapping
+
+	return &LDAPProvider{
+		config:    config,
+		repo:      repo,
+		logger:    logger,
+		sessStore: sessStore,
+	}
+}
+
+// ldapLoginRequest POST /auth/ldap リクエストボディ
+type ldapLoginRequest struct {
+	Name     string `json:"name" form:"name"`
+	Password string `json:"password" form:"password"`
+}
+
+// LoginHandler POST /auth/ldap
+//
+// OAuth2を用いる他のプロバイダと異なり、ユーザー名とパスワードを直接受け取ります。
+// クエリパラメータlinkが真の場合は、ログイン中のユーザーにアカウントを関連付けます。
+// 二要素認証が必要なユーザーの場合は、パスワードログインと同様に202を返し、/api/v3/login/totp等で認証を完了させます。
+func (p *LDAPProvider) LoginHandler(c echo.Context) error {
+	if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+		return herror.BadRequest("Authorization Header must not be set.")
+	}
+
+	var req ldapLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return herror.BadRequest(err)
+	}
+	if len(req.Name) == 0 || len(req.Password) == 0 {
+		return herror.BadRequest("name and password are required")
+	}
+
+	sess, err := p.sessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	link := isTrue(c.QueryParam("link"))
+	if link {
+		// アカウント関連付けモード
+		if sess == nil || sess.UserID() == uuid.Nil {
+			return herror.Unauthorized("You are not logged in. Please login.")
+		}
+	} else {
+		// ログインモード
+		if sess != nil && sess.UserID() != uuid.Nil {
+			return herror.BadRequest("You have already logged in. Please logout once.")
+		}
+	}
+
+	// ログイン試行のロック確認 (IPアドレス)
+	attempt := &utils.LoginAttempt{Name: req.Name, IP: c.RealIP(), Method: utils.LoginMethodLDAP}
+	if d, err := utils.CheckLoginLock(p.repo, attempt); err != nil {
+		return herror.InternalServerError(err)
+	} else if d > 0 {
+		p.L().Info("an ldap login attempt failed: locked", zap.String("username", req.Name), zap.String("ip", attempt.IP))
+		return utils.LoginLockedError(c, d)
+	}
+
+	var lockErr error
+	tu, err := p.authenticate(req.Name, req.Password, func(tu *ldapUserInfo) error {
+		// ログイン試行のロック確認 (アカウント)
+		user, err := p.repo.GetUserByExternalID(LDAPProviderName, tu.id, false)
+		if err != nil && err != repository.ErrNotFound {
+			lockErr = herror.InternalServerError(err)
+			return lockErr
+		}
+		attempt.User = user
+		if d, err := utils.CheckLoginLock(p.repo, attempt); err != nil {
+			lockErr = herror.InternalServerError(err)
+		} else if d > 0 {
+			p.L().Info("an ldap login attempt failed: locked", zap.String("username", req.Name), zap.String("ip", attempt.IP))
+			lockErr = utils.LoginLockedError(c, d)
+		}
+		return lockErr
+	})
+	if err != nil {
+		var reason string
+		switch {
+		case err == errLDAPUserNotFound:
+			reason = model.LoginFailureReasonUnknownUser
+		case err == errLDAPWrongPassword:
+			reason = model.LoginFailureReasonWrongPassword
+		case err == lockErr:
+			return err
+		default:
+			return herror.InternalServerError(err)
+		}
+		p.L().Info("an ldap login attempt failed", zap.String("username", req.Name), zap.String("reason", reason))
+		if err := utils.RecordLoginFailure(p.repo, attempt, reason); err != nil {
+			return herror.InternalServerError(err)
+		}
+		return herror.Unauthorized("invalid name or password")
+	}
+
+	if link {
+		// 認証に成功した場合のみ関連付けを行う
+		if err := sess.Set(accountLinkingFlag, true); err != nil {
+			return herror.InternalServerError(err)
+		}
+		user, err := loginExternalUser(c, p.L(), tu, p.repo, p.sessStore, p.config.RegisterUserIfNotFound)
+		if err != nil {
+			return err
+		}
+		if err := p.syncGroups(user.GetID(), tu.groups); err != nil {
+			return herror.InternalServerError(err)
+		}
+		if err := utils.RecordLoginSuccess(p.repo, user.GetID()); err != nil {
+			return herror.InternalServerError(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	user, err := findOrCreateExternalUser(c, p.L(), tu, p.repo, p.sessStore, p.config.RegisterUserIfNotFound)
+	if err != nil {
+		return err
+	}
+	groupChanges, err := p.groupChanges(user.GetID(), tu.groups)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	// パスワードログインと同様に二要素認証を確認
+	// グループの同期とアカウントの連続失敗回数のリセットは二要素認証の完了後に行う
+	state, err := utils.GetTwoFactorState(p.repo, user)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if state != utils.TwoFactorNone {
+		p.L().Info("an ldap login attempt requires two-factor authentication", zap.String("username", req.Name))
+		return utils.StartLoginChallenge(c, p.repo, p.sessStore, user.GetID(), state, utils.LoginMethodLDAP, groupChanges)
+	}
+
+	if groupChanges != nil {
+		if err := groupChanges.Apply(p.repo, user.GetID()); err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
+	if err := utils.RecordLoginSuccess(p.repo, user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if _, err := p.sessStore.RenewSession(c, user.GetID(), utils.LoginMethodLDAP); err != nil {
+		return herror.InternalServerError(err)
+	}
+	p.L().Info("an ldap login attempt succeeded", zap.Stringer("id", user.GetID()), zap.String("username", req.Name))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// authenticate LDAPでユーザーを検索し、パスワードを検証します
+//
+// beforeBindはユーザーが見つかった後、パスワードを検証する前に呼ばれます。
+func (p *LDAPProvider) authenticate(name, password string, beforeBind func(tu *ldapUserInfo) error) (*ldapUserInfo, error) {
+	conn, err := p.dial()
+	if err != nil {
+		return nil, fmt.Errorf(ldapErrorFormat, err)
+	}
+	defer conn.Close()
+
+	// ユーザー検索
+	attrs := []string{p.config.IDAttribute, p.config.NameAttribute}
+	if len(p.config.DisplayNameAttribute) > 0 {
+		attrs = append(attrs, p.config.DisplayNameAttribute)
+	}
+	if len(p.config.IconAttribute) > 0 {
+		attrs = append(attrs, p.config.IconAttribute)
+	}
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		0,
+		false,
+		strings.ReplaceAll(p.config.UserFilter, ldapUsernameHolder, ldap.EscapeFilter(name)),
+		attrs,
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, errLDAPUserNotFound
+		}
+		return nil, fmt.Errorf(ldapErrorFormat, err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, errLDAPUserNotFound
+	}
+	entry := res.Entries[0]
+
+	tu := &ldapUserInfo{
+		id:   entry.GetAttributeValue(p.config.IDAttribute),
+		name: entry.GetAttributeValue(p.config.NameAttribute),
+	}
+	if len(tu.id) == 0 || len(tu.name) == 0 {
+		return nil, fmt.Errorf(ldapErrorFormat, fmt.Errorf("missing id or name attribute: %s", entry.DN))
+	}
+	if len(p.config.DisplayNameAttribute) > 0 {
+		tu.displayName = entry.GetAttributeValue(p.config.DisplayNameAttribute)
+	}
+	if len(p.config.IconAttribute) > 0 {
+		if icon := entry.GetRawAttributeValue(p.config.IconAttribute); len(icon) > 0 {
+			tu.icon = icon
+		}
+	}
+
+	// グループ検索 (ユーザーとしてBindする前に検索用のアカウントで行う)
+	if len(p.config.GroupFilter) > 0 {
+		filter := strings.NewReplacer(
+			ldapDNHolder, ldap.EscapeFilter(entry.DN),
+			ldapUsernameHolder, ldap.EscapeFilter(name),
+		).Replace(p.config.GroupFilter)
+		groups, err := conn.Search(ldap.NewSearchRequest(
+			p.config.GroupBaseDN,
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			0,
+			0,
+			false,
+			filter,
+			[]string{p.config.GroupNameAttribute},
+			nil,
+		))
+		if err != nil {
+			return nil, fmt.Errorf(ldapErrorFormat, err)
+		}
+		for _, g := range groups.Entries {
+			if n := g.GetAttributeValue(p.config.GroupNameAttribute); len(n) > 0 {
+				tu.groups = append(tu.groups, n)
+			}
+		}
+	}
+
+	if err := beforeBind(tu); err != nil {
+		return nil, err
+	}
+
+	// パスワード検証
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errLDAPWrongPassword
+		}
+		return nil, fmt.Errorf(ldapErrorFormat, err)
+	}
+	return tu, nil
+}
+
+// dial LDAPサーバーに接続し、検索用のアカウントでBindします
+//
+// BindDNが空の場合は匿名でBindします。
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(p.config.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if len(p.config.BindDN) > 0 {
+		err = conn.Bind(p.config.BindDN, p.config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// groupChanges LDAPのグループ所属をtraQのユーザーグループに同期するための変更を求めます
+//
+// GroupMappingに含まれるユーザーグループのみを対象にします。GroupFilterが空の場合はnilを返します。
+func (p *LDAPProvider) groupChanges(userID uuid.UUID, ldapGroups []string) (*utils.GroupMembershipChanges, error) {
+	if len(p.config.GroupFilter) == 0 {
+		return nil, nil
+	}
+	asserted := map[string]bool{}
+	for _, g := range ldapGroups {
+		if name, ok := p.config.GroupMapping[strings.ToLower(g)]; ok {
+			asserted[name] = true
+		}
+	}
+
+	changes := &utils.GroupMembershipChanges{}
+	done := map[string]bool{}
+	for _, name := range p.config.GroupMapping {
+		if done[name] {
+			continue
+		}
+		done[name] = true
+
+		g, err := p.repo.GetUserGroupByName(name)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				p.L().Warn("ldap group mapping refers to a nonexistent user group", zap.String("group", name))
+				continue
+			}
+			return nil, err
+		}
+		switch member := g.IsMember(userID); {
+		case asserted[name] && !member:
+			changes.Join = append(changes.Join, g.ID)
+		case !asserted[name] && member:
+			changes.Leave = append(changes.Leave, g.ID)
+		}
+	}
+	return changes, nil
+}
+
+// syncGroups LDAPのグループ所属をtraQのユーザーグループに同期します
+func (p *LDAPProvider) syncGroups(userID uuid.UUID, ldapGroups []string) error {
+	changes, err := p.groupChanges(userID, ldapGroups)
+	if err != nil || changes == nil {
+		return err
+	}
+	return changes.Apply(p.repo, userID)
+}
+
+func (p *LDAPProvider) L() *zap.Logger {
+	return p.logger
+}
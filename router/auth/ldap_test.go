@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"errors"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/testutils/ldaptest"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func newTestLDAPProvider(t *testing.T, config LDAPProviderConfig) (*LDAPProvider, *ldaptest.Server) {
+	t.Helper()
+	s, err := ldaptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+
+	s.AddEntry("dc=example,dc=com", map[string][]string{"objectClass": {"domain"}})
+	s.AddEntry("cn=reader,dc=example,dc=com", map[string][]string{"userPassword": {"readerpass"}})
+	s.AddEntry("uid=alice,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass":  {"inetOrgPerson"},
+		"uid":          {"alice"},
+		"entryUUID":    {"5b8f1d2e-0000-4000-8000-000000000001"},
+		"displayName":  {"Alice Liddell"},
+		"jpegPhoto":    {"\xff\xd8\xff"},
+		"userPassword": {"alicepass"},
+	})
+	s.AddEntry("uid=bob,ou=people,dc=example,dc=com", map[string][]string{
+		"objectClass":  {"inetOrgPerson"},
+		"uid":          {"bob"},
+		"entryUUID":    {"5b8f1d2e-0000-4000-8000-000000000002"},
+		"userPassword": {"bobpass"},
+	})
+	s.AddEntry("cn=lab,ou=groups,dc=example,dc=com", map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"lab"},
+		"member":      {"uid=alice,ou=people,dc=example,dc=com"},
+	})
+	s.AddEntry("cn=staff,ou=groups,dc=example,dc=com", map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"staff"},
+		"member":      {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+	})
+
+	config.URL = s.URL
+	config.BaseDN = "dc=example,dc=com"
+	return NewLDAPProvider(nil, zap.NewNop(), nil, config), s
+}
+
+func noop(*ldapUserInfo) error { return nil }
+
+func TestLDAPProvider_authenticate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{
+			BindDN:               "cn=reader,dc=example,dc=com",
+			BindPassword:         "readerpass",
+			IDAttribute:          "entryUUID",
+			DisplayNameAttribute: "displayName",
+			IconAttribute:        "jpegPhoto",
+			GroupFilter:          "(&(objectClass=groupOfNames)(member={dn}))",
+		})
+
+		called := false
+		tu, err := p.authenticate("alice", "alicepass", func(tu *ldapUserInfo) error {
+			called = true
+			assert.Equal(t, "5b8f1d2e-0000-4000-8000-000000000001", tu.GetID())
+			return nil
+		})
+		require.NoError(t, err)
+		assert.True(t, called)
+		assert.Equal(t, LDAPProviderName, tu.GetProviderName())
+		assert.Equal(t, "alice", tu.GetName())
+		assert.Equal(t, "Alice Liddell", tu.GetDisplayName())
+		assert.ElementsMatch(t, []string{"lab", "staff"}, tu.groups)
+		icon, err := tu.GetProfileImage()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("\xff\xd8\xff"), icon)
+	})
+
+	t.Run("anonymous search", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{})
+
+		tu, err := p.authenticate("bob", "bobpass", noop)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", tu.GetID())
+		assert.Empty(t, tu.GetDisplayName())
+		assert.Empty(t, tu.groups)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{})
+
+		_, err := p.authenticate("alice", "wrong", noop)
+		assert.Equal(t, errLDAPWrongPassword, err)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{})
+
+		_, err := p.authenticate("carol", "carolpass", noop)
+		assert.Equal(t, errLDAPUserNotFound, err)
+	})
+
+	t.Run("filter injection", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{})
+
+		_, err := p.authenticate("*", "alicepass", noop)
+		assert.Equal(t, errLDAPUserNotFound, err)
+		_, err = p.authenticate("alice)(uid=*", "alicepass", noop)
+		assert.Equal(t, errLDAPUserNotFound, err)
+	})
+
+	t.Run("ambiguous user", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{UserFilter: "(objectClass=inetOrgPerson)"})
+
+		_, err := p.authenticate("alice", "alicepass", noop)
+		assert.Equal(t, errLDAPUserNotFound, err)
+	})
+
+	t.Run("hook error", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{})
+
+		hookErr := errors.New("locked")
+		_, err := p.authenticate("alice", "alicepass", func(*ldapUserInfo) error { return hookErr })
+		assert.Equal(t, hookErr, err)
+	})
+
+	t.Run("service account bind failure", func(t *testing.T) {
+		t.Parallel()
+		p, _ := newTestLDAPProvider(t, LDAPProviderConfig{
+			BindDN:       "cn=reader,dc=example,dc=com",
+			BindPassword: "wrong",
+		})
+
+		_, err := p.authenticate("alice", "alicepass", noop)
+		var lerr *ldap.Error
+		if assert.True(t, errors.As(err, &lerr)) {
+			assert.EqualValues(t, ldap.LDAPResultInvalidCredentials, lerr.ResultCode)
+		}
+		assert.NotEqual(t, errLDAPWrongPassword, err)
+	})
+}
+
+func TestLDAPUserInfo_GetName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "taro_yamada", (&ldapUserInfo{name: "taro.yamada"}).GetName())
+	assert.Equal(t, "abcdefghijklmnopqrstuvwxyz012345", (&ldapUserInfo{name: "abcdefghijklmnopqrstuvwxyz0123456789"}).GetName())
+}
+
+func TestLDAPProvider_groupChanges(t *testing.T) {
+	t.Parallel()
+
+	repo := testutils.NewTestRepository()
+	admin, err := repo.GetUserByName("traq", false)
+	require.NoError(t, err)
+	user, err := repo.CreateUser(repository.CreateUserArgs{Name: "alice", Role: role.User})
+	require.NoError(t, err)
+	lab, err := repo.CreateUserGroup("lab-a", "", "", admin.GetID())
+	require.NoError(t, err)
+	staff, err := repo.CreateUserGroup("staff", "", "", admin.GetID())
+	require.NoError(t, err)
+	old, err := repo.CreateUserGroup("old", "", "", admin.GetID())
+	require.NoError(t, err)
+	require.NoError(t, repo.AddUserToGroup(user.GetID(), staff.ID, ""))
+	require.NoError(t, repo.AddUserToGroup(user.GetID(), old.ID, ""))
+
+	p := NewLDAPProvider(repo, zap.NewNop(), nil, LDAPProviderConfig{
+		GroupFilter:  "(member={dn})",
+		GroupMapping: map[string]string{"Lab": "lab-a", "staff": "staff", "old": "old"},
+	})
+	changes, err := p.groupChanges(user.GetID(), []string{"lab", "staff"})
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, []uuid.UUID{lab.ID}, changes.Join)
+		assert.ElementsMatch(t, []uuid.UUID{old.ID}, changes.Leave)
+	}
+
+	// 変更は反映されるまで所属に影響しない
+	ids, err := repo.GetUserBelongingGroupIDs(user.GetID())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{staff.ID, old.ID}, ids)
+
+	require.NoError(t, changes.Apply(repo, user.GetID()))
+	ids, err = repo.GetUserBelongingGroupIDs(user.GetID())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{staff.ID, lab.ID}, ids)
+
+	p.config.GroupFilter = ""
+	changes, err = p.groupChanges(user.GetID(), []string{"lab"})
+	assert.NoError(t, err)
+	assert.Nil(t, changes)
+}
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/coreos/go-oidc"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/utils/optional"
 	"go.uber.org/zap"
 	"golang.org/x/exp/utf8string"
@@ -15,12 +18,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 const (
 	OIDCProviderName          = "oidc"
 	oidcAPIRequestErrorFormat = "oidc api request error: %w"
+	defaultOIDCGroupType      = "oidc"
+	defaultOIDCGroupAdmin     = "traq"
 )
 
 type OIDCProvider struct {
@@ -39,6 +45,44 @@ type OIDCProviderConfig struct {
 	Issuer                 string
 	Scopes                 []string
 	RegisterUserIfNotFound bool
+	// GroupsClaim ユーザーの所属グループを含むIDトークンのクレーム名 (空の場合はグループを同期しない)
+	//
+	// "realm_access.roles"のように"."区切りで入れ子のクレームを指定できます。
+	GroupsClaim string
+	// GroupMapping クレームの値からtraQのユーザーグループへの対応 (空の場合はクレームの値をそのままグループ名とする)
+	//
+	// クレームの値は大文字小文字を区別しません。
+	GroupMapping map[string]OIDCGroupMapping
+	// GroupType 同期の対象とするユーザーグループのタイプ (default: oidc)
+	//
+	// GroupMappingに含まれるユーザーグループと、このタイプのユーザーグループのメンバーのみを、ログイン時にクレームに合わせて同期します。
+	GroupType string
+	// CreateMissingGroups クレームに含まれるユーザーグループが存在しない場合に作成するかどうか
+	CreateMissingGroups bool
+	// GroupAdmin 作成したユーザーグループの管理者とするユーザーの名前 (default: traq)
+	GroupAdmin string
+	// RoleMapping GroupsClaimのクレームの値からtraQのユーザーロールへの対応 (空の場合はロールを同期しない)
+	//
+	// 先頭から順に評価し、最初にクレームに含まれていた値のロールをログイン時に設定します。
+	// いずれの値も含まれない場合、ユーザーのロールが対応に含まれるロールであればuserに戻します。
+	// クレームの値は大文字小文字を区別しません。
+	RoleMapping []OIDCRoleMapping
+}
+
+// OIDCGroupMapping クレームの値に対応するユーザーグループ
+type OIDCGroupMapping struct {
+	// Group ユーザーグループ名
+	Group string
+	// Role グループ内での役割
+	Role string
+}
+
+// OIDCRoleMapping クレームの値に対応するユーザーロール
+type OIDCRoleMapping struct {
+	// Value クレームの値
+	Value string
+	// Role ユーザーロール名
+	Role string
 }
 
 func (c OIDCProviderConfig) Valid() bool {
@@ -51,6 +95,7 @@ type oidcUserInfo struct {
 	sub     string
 	name    string
 	picture string
+	groups  []string
 }
 
 func (u *oidcUserInfo) GetProviderName() string {
@@ -107,7 +152,26 @@ func (u *oidcUserInfo) IsLoginAllowedUser() bool {
 	return true // TODO
 }
 
+func (u *oidcUserInfo) syncUser(user model.UserInfo) error {
+	if err := u.p.syncUserRole(user, u); err != nil {
+		return err
+	}
+	return u.p.syncUserGroups(user.GetID(), u)
+}
+
 func NewOIDCProvider(repo repository.Repository, logger *zap.Logger, sessStore session.Store, config OIDCProviderConfig) (*OIDCProvider, error) {
+	if len(config.GroupType) == 0 {
+		config.GroupType = defaultOIDCGroupType
+	}
+	if len(config.GroupAdmin) == 0 {
+		config.GroupAdmin = defaultOIDCGroupAdmin
+	}
+	mapping := make(map[string]OIDCGroupMapping, len(config.GroupMapping))
+	for k, v := range config.GroupMapping {
+		mapping[strings.ToLower(k)] = v
+	}
+	config.GroupMapping = mapping
+
 	p, err := oidc.NewProvider(context.Background(), config.Issuer)
 	if err != nil {
 		return nil, err
@@ -168,9 +232,224 @@ func (p *OIDCProvider) FetchUserInfo(t *oauth2.Token) (UserInfo, error) {
 	ui.name = claims.Name
 	ui.picture = claims.Picture.String
 
+	if len(p.config.GroupsClaim) > 0 {
+		var raw map[string]interface{}
+		if err := idToken.Claims(&raw); err != nil {
+			return nil, fmt.Errorf(oidcAPIRequestErrorFormat, errors.New("malformed id_token"))
+		}
+		ui.groups = extractGroupsClaim(raw, p.config.GroupsClaim)
+	}
+
 	return &ui, nil
 }
 
+// extractGroupsClaim クレームから文字列の配列を取り出します
+//
+// クレームが文字列の場合は、その文字列のみを含む配列を返します。
+func extractGroupsClaim(claims map[string]interface{}, name string) []string {
+	keys := strings.Split(name, ".")
+	var v interface{} = claims
+	for _, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// syncUserGroups IDトークンのグループのクレームをtraQのユーザーグループに同期します
+//
+// 同期の対象はGroupMappingに含まれるユーザーグループと、タイプがGroupTypeのユーザーグループのみです。
+func (p *OIDCProvider) syncUserGroups(userID uuid.UUID, tu UserInfo) error {
+	ui, ok := tu.(*oidcUserInfo)
+	if !ok || len(p.config.GroupsClaim) == 0 {
+		return nil
+	}
+
+	// グループ名 → 役割
+	asserted := map[string]string{}
+	claimed := append([]string{}, ui.groups...)
+	sort.Strings(claimed)
+	for _, v := range claimed {
+		if len(p.config.GroupMapping) == 0 {
+			asserted[v] = ""
+			continue
+		}
+		m, ok := p.config.GroupMapping[strings.ToLower(v)]
+		if !ok {
+			continue
+		}
+		// 同じグループに複数の値が対応する場合は、役割が設定されている方を優先する
+		if role, ok := asserted[m.Group]; !ok || len(role) == 0 {
+			asserted[m.Group] = m.Role
+		}
+	}
+
+	// クレームに含まれるグループへの追加
+	names := make([]string, 0, len(asserted))
+	for name := range asserted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g, err := p.repo.GetUserGroupByName(name)
+		if err != nil {
+			if err != repository.ErrNotFound {
+				return err
+			}
+			if !p.config.CreateMissingGroups {
+				continue
+			}
+			g, err = p.createUserGroup(name)
+			if err != nil {
+				return err
+			}
+		}
+		if !p.isSyncedGroup(g) {
+			p.L().Warn("oidc groups claim refers to a user group which is not synchronized", zap.String("group", name))
+			continue
+		}
+
+		role := asserted[name]
+		if m := groupMember(g, userID); m == nil || m.Role != role {
+			if err := p.repo.AddUserToGroup(userID, g.ID, role); err != nil {
+				return err
+			}
+		}
+	}
+
+	// クレームに含まれなくなったグループからの削除
+	groupIDs, err := p.repo.GetUserBelongingGroupIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range groupIDs {
+		g, err := p.repo.GetUserGroup(id)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				continue
+			}
+			return err
+		}
+		if _, ok := asserted[g.Name]; ok || !p.isSyncedGroup(g) {
+			continue
+		}
+		if err := p.repo.RemoveUserFromGroup(userID, g.ID); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUserRole IDトークンのグループのクレームをRoleMappingに従ってtraQのユーザーロールに同期します
+func (p *OIDCProvider) syncUserRole(user model.UserInfo, ui *oidcUserInfo) error {
+	if len(p.config.GroupsClaim) == 0 || len(p.config.RoleMapping) == 0 {
+		return nil
+	}
+
+	claimed := make(map[string]bool, len(ui.groups))
+	for _, v := range ui.groups {
+		claimed[strings.ToLower(v)] = true
+	}
+	var newRole string
+	for _, m := range p.config.RoleMapping {
+		if claimed[strings.ToLower(m.Value)] {
+			newRole = m.Role
+			break
+		}
+	}
+	if len(newRole) == 0 {
+		// クレームに含まれなくなった場合、対応に含まれるロールのみを外す
+		if !p.isSyncedRole(user.GetRole()) {
+			return nil
+		}
+		newRole = role.User
+	}
+	if user.GetRole() == newRole {
+		return nil
+	}
+
+	if err := p.repo.UpdateUser(user.GetID(), repository.UpdateUserArgs{Role: optional.StringFrom(newRole)}); err != nil {
+		return err
+	}
+	p.L().Info("user role was changed by oidc role sync",
+		zap.Stringer("id", user.GetID()),
+		zap.String("name", user.GetName()),
+		zap.String("from", user.GetRole()),
+		zap.String("to", newRole))
+	return nil
+}
+
+// isSyncedRole ユーザーロールがRoleMappingに含まれるかどうか
+func (p *OIDCProvider) isSyncedRole(r string) bool {
+	for _, m := range p.config.RoleMapping {
+		if m.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// isSyncedGroup ユーザーグループが同期の対象かどうか
+func (p *OIDCProvider) isSyncedGroup(g *model.UserGroup) bool {
+	if g.Type == p.config.GroupType {
+		return true
+	}
+	for _, m := range p.config.GroupMapping {
+		if m.Group == g.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// createUserGroup GroupAdminを管理者として、同期の対象のユーザーグループを作成します
+func (p *OIDCProvider) createUserGroup(name string) (*model.UserGroup, error) {
+	admin, err := p.repo.GetUserByName(p.config.GroupAdmin, false)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("oidc group admin user not found: %s", p.config.GroupAdmin)
+		}
+		return nil, err
+	}
+
+	g, err := p.repo.CreateUserGroup(name, "", p.config.GroupType, admin.GetID())
+	if err == repository.ErrAlreadyExists {
+		// 同時にログインした他のユーザーによって作成された
+		return p.repo.GetUserGroupByName(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.L().Info("a user group was created by oidc group sync", zap.Stringer("id", g.ID), zap.String("name", g.Name))
+	return g, nil
+}
+
+func groupMember(g *model.UserGroup, userID uuid.UUID) *model.UserGroupMember {
+	for _, m := range g.Members {
+		if m.UserID == userID {
+			return m
+		}
+	}
+	return nil
+}
+
 func (p *OIDCProvider) L() *zap.Logger {
 	return p.logger
 }
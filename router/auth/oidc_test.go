@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/testutils"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestExtractGroupsClaim(t *testing.T) {
+	t.Parallel()
+
+	claims := map[string]interface{}{
+		"groups":       []interface{}{"a", "b", 1},
+		"group":        "single",
+		"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}},
+		"number":       1,
+	}
+	assert.Equal(t, []string{"a", "b"}, extractGroupsClaim(claims, "groups"))
+	assert.Equal(t, []string{"single"}, extractGroupsClaim(claims, "group"))
+	assert.Equal(t, []string{"admin"}, extractGroupsClaim(claims, "realm_access.roles"))
+	assert.Nil(t, extractGroupsClaim(claims, "number"))
+	assert.Nil(t, extractGroupsClaim(claims, "missing"))
+	assert.Nil(t, extractGroupsClaim(claims, "group.nested"))
+}
+
+func TestOIDCProvider_syncUserGroups(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, config OIDCProviderConfig) (*OIDCProvider, *testutils.TestRepository, uuid.UUID) {
+		t.Helper()
+		repo := testutils.NewTestRepository()
+		admin, err := repo.GetUserByName(defaultOIDCGroupAdmin, false)
+		require.NoError(t, err)
+		user, err := repo.CreateUser(repository.CreateUserArgs{Name: "alice", Role: role.User})
+		require.NoError(t, err)
+
+		_, err = repo.CreateUserGroup("lab-a", "", defaultOIDCGroupType, admin.GetID())
+		require.NoError(t, err)
+		_, err = repo.CreateUserGroup("lab-b", "", defaultOIDCGroupType, admin.GetID())
+		require.NoError(t, err)
+		_, err = repo.CreateUserGroup("manual", "", "", admin.GetID())
+		require.NoError(t, err)
+
+		config.GroupsClaim = "groups"
+		config.GroupType = defaultOIDCGroupType
+		config.GroupAdmin = defaultOIDCGroupAdmin
+		return &OIDCProvider{config: config, repo: repo, logger: zap.NewNop()}, repo, user.GetID()
+	}
+	memberOf := func(t *testing.T, repo *testutils.TestRepository, userID uuid.UUID) []string {
+		t.Helper()
+		ids, err := repo.GetUserBelongingGroupIDs(userID)
+		require.NoError(t, err)
+		names := make([]string, 0, len(ids))
+		for _, id := range ids {
+			g, err := repo.GetUserGroup(id)
+			require.NoError(t, err)
+			names = append(names, g.Name)
+		}
+		return names
+	}
+	addTo := func(t *testing.T, repo *testutils.TestRepository, userID uuid.UUID, name string) {
+		t.Helper()
+		g, err := repo.GetUserGroupByName(name)
+		require.NoError(t, err)
+		require.NoError(t, repo.AddUserToGroup(userID, g.ID, ""))
+	}
+
+	t.Run("claim values as group names", func(t *testing.T) {
+		t.Parallel()
+		p, repo, uid := setup(t, OIDCProviderConfig{})
+		addTo(t, repo, uid, "lab-b")
+		addTo(t, repo, uid, "manual")
+
+		err := p.syncUserGroups(uid, &oidcUserInfo{groups: []string{"lab-a", "unknown", "manual"}})
+		if assert.NoError(t, err) {
+			// 同期の対象外のグループは追加も削除もしない
+			assert.ElementsMatch(t, []string{"lab-a", "manual"}, memberOf(t, repo, uid))
+		}
+		_, err = repo.GetUserGroupByName("unknown")
+		assert.Equal(t, repository.ErrNotFound, err)
+	})
+
+	t.Run("create missing groups", func(t *testing.T) {
+		t.Parallel()
+		p, repo, uid := setup(t, OIDCProviderConfig{CreateMissingGroups: true})
+
+		err := p.syncUserGroups(uid, &oidcUserInfo{groups: []string{"lab-c"}})
+		if assert.NoError(t, err) {
+			assert.ElementsMatch(t, []string{"lab-c"}, memberOf(t, repo, uid))
+			g, err := repo.GetUserGroupByName("lab-c")
+			if assert.NoError(t, err) {
+				assert.Equal(t, defaultOIDCGroupType, g.Type)
+				assert.Len(t, g.Admins, 1)
+			}
+		}
+
+		// 作成されたグループは次回以降同期の対象になる
+		err = p.syncUserGroups(uid, &oidcUserInfo{})
+		if assert.NoError(t, err) {
+			assert.Empty(t, memberOf(t, repo, uid))
+		}
+	})
+
+	t.Run("missing group admin", func(t *testing.T) {
+		t.Parallel()
+		p, _, uid := setup(t, OIDCProviderConfig{CreateMissingGroups: true})
+		p.config.GroupAdmin = "nobody"
+
+		assert.Error(t, p.syncUserGroups(uid, &oidcUserInfo{groups: []string{"lab-c"}}))
+	})
+
+	t.Run("mapping", func(t *testing.T) {
+		t.Parallel()
+		p, repo, uid := setup(t, OIDCProviderConfig{
+			GroupMapping: map[string]OIDCGroupMapping{
+				"/labs/a":         {Group: "lab-a"},
+				"/labs/a/leaders": {Group: "lab-a", Role: "leader"},
+				"staff":           {Group: "manual"},
+			},
+		})
+		addTo(t, repo, uid, "lab-b")
+		addTo(t, repo, uid, "manual")
+
+		err := p.syncUserGroups(uid, &oidcUserInfo{groups: []string{"/labs/a", "/labs/a/leaders", "lab-b"}})
+		if assert.NoError(t, err) {
+			// lab-bは同期の対象のタイプで、manualは対応に含まれるため、クレームに含まれなければ削除される
+			assert.ElementsMatch(t, []string{"lab-a"}, memberOf(t, repo, uid))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		p, repo, uid := setup(t, OIDCProviderConfig{})
+		p.config.GroupsClaim = ""
+		addTo(t, repo, uid, "lab-a")
+
+		assert.NoError(t, p.syncUserGroups(uid, &oidcUserInfo{}))
+		assert.ElementsMatch(t, []string{"lab-a"}, memberOf(t, repo, uid))
+	})
+}
+
+func TestOIDCProvider_syncUserRole(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T, userRole string) (*OIDCProvider, *testutils.TestRepository, model.UserInfo) {
+		t.Helper()
+		repo := testutils.NewTestRepository()
+		user, err := repo.CreateUser(repository.CreateUserArgs{Name: "alice", Role: userRole})
+		require.NoError(t, err)
+		p := &OIDCProvider{
+			config: OIDCProviderConfig{
+				GroupsClaim: "groups",
+				RoleMapping: []OIDCRoleMapping{
+					{Value: "traq-admins", Role: role.Admin},
+					{Value: "Guests", Role: role.Read},
+				},
+			},
+			repo:   repo,
+			logger: zap.NewNop(),
+		}
+		return p, repo, user
+	}
+	roleOf := func(t *testing.T, repo *testutils.TestRepository, userID uuid.UUID) string {
+		t.Helper()
+		user, err := repo.GetUser(userID, false)
+		require.NoError(t, err)
+		return user.GetRole()
+	}
+
+	t.Run("first matched value", func(t *testing.T) {
+		t.Parallel()
+		p, repo, user := setup(t, role.User)
+
+		err := p.syncUserRole(user, &oidcUserInfo{groups: []string{"guests", "traq-admins"}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, role.Admin, roleOf(t, repo, user.GetID()))
+		}
+	})
+
+	t.Run("revoke mapped role", func(t *testing.T) {
+		t.Parallel()
+		p, repo, user := setup(t, role.Admin)
+
+		err := p.syncUserRole(user, &oidcUserInfo{groups: []string{"lab-a"}})
+		if assert.NoError(t, err) {
+			assert.Equal(t, role.User, roleOf(t, repo, user.GetID()))
+		}
+	})
+
+	t.Run("keep unmapped role", func(t *testing.T) {
+		t.Parallel()
+		p, repo, user := setup(t, role.ManageBot)
+
+		err := p.syncUserRole(user, &oidcUserInfo{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, role.ManageBot, roleOf(t, repo, user.GetID()))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		p, repo, user := setup(t, role.Admin)
+		p.config.RoleMapping = nil
+
+		assert.NoError(t, p.syncUserRole(user, &oidcUserInfo{}))
+		assert.Equal(t, role.Admin, roleOf(t, repo, user.GetID()))
+	})
+}
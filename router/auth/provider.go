@@ -35,6 +35,11 @@ type Provider interface {
 	L() *zap.Logger
 }
 
+// userSynchronizer ログイン時にtraQユーザーのロールやユーザーグループを同期する外部ユーザー情報
+type userSynchronizer interface {
+	syncUser(user model.UserInfo) error
+}
+
 type UserInfo interface {
 	GetProviderName() string
 	GetID() string
@@ -47,41 +52,52 @@ type UserInfo interface {
 
 func defaultLoginHandler(sessStore session.Store, oac *oauth2.Config) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
-			return herror.BadRequest("Authorization Header must not be set.")
-		}
-
-		sess, err := sessStore.GetSession(c, false)
+		state, err := beginExternalLogin(c, sessStore)
 		if err != nil {
-			return herror.InternalServerError(err)
+			return err
 		}
+		return c.Redirect(http.StatusFound, oac.AuthCodeURL(state))
+	}
+}
 
-		if isTrue(c.QueryParam("link")) {
-			// アカウント関連付けモード
-			if sess == nil || sess.UserID() == uuid.Nil {
-				return herror.Unauthorized("You are not logged in. Please login.")
-			}
-			if err := sess.Set(accountLinkingFlag, true); err != nil {
-				return herror.InternalServerError(err)
-			}
-		} else {
-			// ログインモード
-			if sess != nil && sess.UserID() != uuid.Nil {
-				return herror.BadRequest("You have already logged in. Please logout once.")
-			}
-		}
+// beginExternalLogin 外部認証を開始し、stateを発行してクッキーに設定します
+//
+// クエリパラメータlinkが真の場合は、セッションをアカウント関連付けモードにします。
+func beginExternalLogin(c echo.Context, sessStore session.Store) (string, error) {
+	if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+		return "", herror.BadRequest("Authorization Header must not be set.")
+	}
 
-		state := random.SecureAlphaNumeric(32)
-		c.SetCookie(&http.Cookie{
-			Name:     cookieName,
-			Value:    state,
-			Path:     "/",
-			Expires:  time.Now().Add(cookieMaxAge * time.Second),
-			MaxAge:   cookieMaxAge,
-			HttpOnly: true,
-		})
-		return c.Redirect(http.StatusFound, oac.AuthCodeURL(state))
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return "", herror.InternalServerError(err)
+	}
+
+	if isTrue(c.QueryParam("link")) {
+		// アカウント関連付けモード
+		if sess == nil || sess.UserID() == uuid.Nil {
+			return "", herror.Unauthorized("You are not logged in. Please login.")
+		}
+		if err := sess.Set(accountLinkingFlag, true); err != nil {
+			return "", herror.InternalServerError(err)
+		}
+	} else {
+		// ログインモード
+		if sess != nil && sess.UserID() != uuid.Nil {
+			return "", herror.BadRequest("You have already logged in. Please logout once.")
+		}
 	}
+
+	state := random.SecureAlphaNumeric(32)
+	c.SetCookie(&http.Cookie{
+		Name:     cookieName,
+		Value:    state,
+		Path:     "/",
+		Expires:  time.Now().Add(cookieMaxAge * time.Second),
+		MaxAge:   cookieMaxAge,
+		HttpOnly: true,
+	})
+	return state, nil
 }
 
 func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repository, sessStore session.Store, allowSignUp bool) echo.HandlerFunc {
@@ -118,122 +134,165 @@ func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repo
 			return c.String(http.StatusForbidden, "You are not permitted to access traQ")
 		}
 
-		sess, err := sessStore.GetSession(c, false)
-		if err != nil {
-			return herror.InternalServerError(err)
+		if _, err := loginExternalUser(c, p.L(), tu, repo, sessStore, allowSignUp); err != nil {
+			return err
 		}
-		if sess != nil {
-			if v, err := sess.Get(accountLinkingFlag); err != nil {
-				return herror.InternalServerError(err)
-			} else if v == true {
-				// アカウント関連付けモード
-				if err := sess.Delete(accountLinkingFlag); err != nil {
-					return herror.InternalServerError(err)
-				}
-				if sess.UserID() == uuid.Nil {
-					return herror.Unauthorized("You are not logged in. Please login.")
-				}
-
-				// ユーザーアカウント状態を確認
-				user, err := repo.GetUser(sess.UserID(), false)
-				if err != nil {
-					return herror.InternalServerError(err)
-				}
-				if !user.IsActive() {
-					return herror.Forbidden("this account is currently suspended")
-				}
+		return c.Redirect(http.StatusFound, "/") // TODO アカウント関連付けモードのリダイレクト先を設定画面に
+	}
+}
 
-				// アカウントにリンク
-				if err := repo.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
-					ProviderName: tu.GetProviderName(),
-					ExternalID:   tu.GetID(),
-					Extra:        model.JSON{"externalName": tu.GetRawName()},
-				}); err != nil {
-					switch err {
-					case repository.ErrAlreadyExists:
-						return herror.BadRequest("this account has already been linked")
-					default:
-						return herror.InternalServerError(err)
-					}
-				}
-				p.L().Info("an external user account has been linked to traQ user",
-					zap.Stringer("id", user.GetID()),
-					zap.String("name", user.GetName()),
-					zap.String("providerName", tu.GetProviderName()),
-					zap.String("externalId", tu.GetID()),
-					zap.String("externalName", tu.GetRawName()))
-
-				return c.Redirect(http.StatusFound, "/") // TODO リダイレクト先を設定画面に
+// loginExternalUser 外部認証で確認されたユーザーでログイン、またはアカウントの関連付けを行います
+//
+// セッションがアカウント関連付けモードの場合は、ログイン中のユーザーにアカウントを関連付けます。
+// それ以外の場合は、関連付けられたユーザーでログインします。ユーザーが存在しない場合は、allowSignUpがtrueの場合のみユーザーを作成します。
+func loginExternalUser(c echo.Context, l *zap.Logger, tu UserInfo, repo repository.Repository, sessStore session.Store, allowSignUp bool) (model.UserInfo, error) {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if sess != nil {
+		if v, err := sess.Get(accountLinkingFlag); err != nil {
+			return nil, herror.InternalServerError(err)
+		} else if v == true {
+			// アカウント関連付けモード
+			if err := sess.Delete(accountLinkingFlag); err != nil {
+				return nil, herror.InternalServerError(err)
 			}
-		}
-
-		// ログインモード
-
-		// ログインしていないことを確認
-		if sess != nil && sess.UserID() != uuid.Nil {
-			return herror.BadRequest("You have already logged in. Please logout once.")
-		}
-
-		user, err := repo.GetUserByExternalID(tu.GetProviderName(), tu.GetID(), false)
-		if err != nil {
-			if err != repository.ErrNotFound {
-				return herror.InternalServerError(err)
+			if sess.UserID() == uuid.Nil {
+				return nil, herror.Unauthorized("You are not logged in. Please login.")
 			}
 
-			if !allowSignUp {
-				return herror.Unauthorized("You are not a member of traQ")
+			// ユーザーアカウント状態を確認
+			user, err := repo.GetUser(sess.UserID(), false)
+			if err != nil {
+				return nil, herror.InternalServerError(err)
 			}
-
-			args := repository.CreateUserArgs{
-				Name:        tu.GetName(),
-				DisplayName: tu.GetDisplayName(),
-				Role:        role.User,
-				ExternalLogin: &model.ExternalProviderUser{
-					ProviderName: tu.GetProviderName(),
-					ExternalID:   tu.GetID(),
-					Extra:        model.JSON{"externalName": tu.GetRawName()},
-				},
+			if !user.IsActive() {
+				return nil, herror.Forbidden("this account is currently suspended")
 			}
 
-			if b, err := tu.GetProfileImage(); err == nil && b != nil {
-				fid, err := processProfileIcon(repo, b)
-				if err == nil {
-					args.IconFileID = optional.UUIDFrom(fid)
+			// アカウントにリンク
+			if err := repo.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
+				ProviderName: tu.GetProviderName(),
+				ExternalID:   tu.GetID(),
+				Extra:        model.JSON{"externalName": tu.GetRawName()},
+			}); err != nil {
+				switch err {
+				case repository.ErrAlreadyExists:
+					return nil, herror.BadRequest("this account has already been linked")
+				default:
+					return nil, herror.InternalServerError(err)
 				}
 			}
-
-			user, err = repo.CreateUser(args)
-			if err != nil {
-				if err == repository.ErrAlreadyExists {
-					return herror.Conflict("name conflicts") // TODO 名前被りをどうするか
-				}
-				return herror.InternalServerError(err)
+			if err := syncExternalUser(user, tu); err != nil {
+				return nil, herror.InternalServerError(err)
 			}
-			p.L().Info("New user was created by external auth",
+			l.Info("an external user account has been linked to traQ user",
 				zap.Stringer("id", user.GetID()),
 				zap.String("name", user.GetName()),
 				zap.String("providerName", tu.GetProviderName()),
 				zap.String("externalId", tu.GetID()),
 				zap.String("externalName", tu.GetRawName()))
+
+			return user, nil
 		}
+	}
+
+	// ログインモード
+	user, err := findOrCreateExternalUser(c, l, tu, repo, sessStore, allowSignUp)
+	if err != nil {
+		return nil, err
+	}
 
-		// ユーザーのアカウント状態の確認
-		if !user.IsActive() {
-			return herror.Forbidden("this account is currently suspended")
+	if _, err := sessStore.RenewSession(c, user.GetID(), tu.GetProviderName()); err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	l.Info("User was logged in by external auth",
+		zap.Stringer("id", user.GetID()),
+		zap.String("name", user.GetName()),
+		zap.String("providerName", tu.GetProviderName()),
+		zap.String("externalId", tu.GetID()),
+		zap.String("externalName", tu.GetRawName()))
+
+	return user, nil
+}
+
+// findOrCreateExternalUser 外部アカウントに関連付けられたtraQユーザーを取得します
+//
+// 存在しない場合、allowSignUpが真であればユーザーを作成します。
+// 外部ユーザー情報が対応している場合は、ユーザーのロールとユーザーグループを同期します。
+// セッションの発行は行いません。
+func findOrCreateExternalUser(c echo.Context, l *zap.Logger, tu UserInfo, repo repository.Repository, sessStore session.Store, allowSignUp bool) (model.UserInfo, error) {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+
+	// ログインしていないことを確認
+	if sess != nil && sess.UserID() != uuid.Nil {
+		return nil, herror.BadRequest("You have already logged in. Please logout once.")
+	}
+
+	user, err := repo.GetUserByExternalID(tu.GetProviderName(), tu.GetID(), false)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			return nil, herror.InternalServerError(err)
 		}
 
-		if _, err := sessStore.RenewSession(c, user.GetID()); err != nil {
-			return herror.InternalServerError(err)
+		if !allowSignUp {
+			return nil, herror.Unauthorized("You are not a member of traQ")
+		}
+
+		args := repository.CreateUserArgs{
+			Name:        tu.GetName(),
+			DisplayName: tu.GetDisplayName(),
+			Role:        role.User,
+			ExternalLogin: &model.ExternalProviderUser{
+				ProviderName: tu.GetProviderName(),
+				ExternalID:   tu.GetID(),
+				Extra:        model.JSON{"externalName": tu.GetRawName()},
+			},
+		}
+
+		if b, err := tu.GetProfileImage(); err == nil && b != nil {
+			fid, err := processProfileIcon(repo, b)
+			if err == nil {
+				args.IconFileID = optional.UUIDFrom(fid)
+			}
 		}
-		p.L().Info("User was logged in by external auth",
+
+		user, err = repo.CreateUser(args)
+		if err != nil {
+			if err == repository.ErrAlreadyExists {
+				return nil, herror.Conflict("name conflicts") // TODO 名前被りをどうするか
+			}
+			return nil, herror.InternalServerError(err)
+		}
+		l.Info("New user was created by external auth",
 			zap.Stringer("id", user.GetID()),
 			zap.String("name", user.GetName()),
 			zap.String("providerName", tu.GetProviderName()),
 			zap.String("externalId", tu.GetID()),
 			zap.String("externalName", tu.GetRawName()))
+	}
+
+	// ユーザーのアカウント状態の確認
+	if !user.IsActive() {
+		return nil, herror.Forbidden("this account is currently suspended")
+	}
+
+	if err := syncExternalUser(user, tu); err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	return user, nil
+}
 
-		return c.Redirect(http.StatusFound, "/")
+// syncExternalUser 外部ユーザー情報が対応している場合、traQユーザーのロールやユーザーグループを同期します
+func syncExternalUser(user model.UserInfo, tu UserInfo) error {
+	if s, ok := tu.(userSynchronizer); ok {
+		return s.syncUser(user)
 	}
+	return nil
 }
 
 func processProfileIcon(repo repository.Repository, src []byte) (uuid.UUID, error) {
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/utils/saml"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	SAMLProviderName  = "saml"
+	samlMetadataMIME  = "application/samlmetadata+xml"
+	samlResubmitField = "resubmitted"
+)
+
+type SAMLProvider struct {
+	config    SAMLProviderConfig
+	repo      repository.Repository
+	logger    *zap.Logger
+	sessStore session.Store
+	sp        *saml.ServiceProvider
+}
+
+type SAMLProviderConfig struct {
+	// EntityID SPのエンティティID
+	EntityID string
+	// ACSURL AssertionConsumerServiceのURL
+	ACSURL string
+	// CertificateFile SPの証明書ファイル (PEM)
+	CertificateFile string
+	// PrivateKeyFile SPのRSA秘密鍵ファイル (PEM)
+	PrivateKeyFile string
+	// IdPMetadata IdPのメタデータのURLまたはファイルパス
+	IdPMetadata string
+	// IDAttribute ユーザーの外部IDとする属性 (空の場合はNameID)
+	IDAttribute string
+	// NameAttribute traQのユーザー名とする属性 (空の場合はNameID)
+	NameAttribute string
+	// DisplayNameAttribute 表示名とする属性 (空の場合は設定しない)
+	DisplayNameAttribute   string
+	RegisterUserIfNotFound bool
+}
+
+func (c SAMLProviderConfig) Valid() bool {
+	return len(c.EntityID) > 0 && len(c.ACSURL) > 0 && len(c.CertificateFile) > 0 && len(c.PrivateKeyFile) > 0 && len(c.IdPMetadata) > 0
+}
+
+type samlUserInfo struct {
+	id          string
+	name        string
+	displayName string
+}
+
+func (u *samlUserInfo) GetProviderName() string {
+	return SAMLProviderName
+}
+
+func (u *samlUserInfo) GetID() string {
+	return u.id
+}
+
+func (u *samlUserInfo) GetRawName() string {
+	return u.name
+}
+
+func (u *samlUserInfo) GetName() string {
+	regex := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	s := regex.ReplaceAllLiteralString(u.name, "_")
+	if us := utf8string.NewString(s); us.RuneCount() > 32 {
+		s = us.Slice(0, 32)
+	}
+	return s
+}
+
+func (u *samlUserInfo) GetDisplayName() string {
+	if s := utf8string.NewString(u.displayName); s.RuneCount() > 64 {
+		return s.Slice(0, 64)
+	}
+	return u.displayName
+}
+
+func (u *samlUserInfo) GetProfileImage() ([]byte, error) {
+	return nil, nil
+}
+
+func (u *samlUserInfo) IsLoginAllowedUser() bool {
+	return true
+}
+
+func NewSAMLProvider(repo repository.Repository, logger *zap.Logger, sessStore session.Store, config SAMLProviderConfig) (*SAMLProvider, error) {
+	cert, err := loadCertificate(config.CertificateFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadRSAPrivateKey(config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := loadSAMLMetadata(config.IdPMetadata)
+	if err != nil {
+		return nil, err
+	}
+	idp, err := saml.ParseIdPMetadata(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SAMLProvider{
+		config:    config,
+		repo:      repo,
+		logger:    logger,
+		sessStore: sessStore,
+		sp: &saml.ServiceProvider{
+			EntityID:    config.EntityID,
+			ACSURL:      config.ACSURL,
+			Key:         key,
+			Certificate: cert,
+			IdP:         *idp,
+			ReplayCache: &samlReplayCache{repo: repo},
+		},
+	}, nil
+}
+
+// samlReplayCache 使用済みのアサーションをDBに記録するsaml.ReplayCache
+type samlReplayCache struct {
+	repo repository.Repository
+}
+
+// MarkUsed implements saml.ReplayCache interface.
+func (c *samlReplayCache) MarkUsed(id string, expiresAt time.Time) (bool, error) {
+	if err := c.repo.UseSAMLAssertion(id, expiresAt); err != nil {
+		if err == repository.ErrAlreadyExists {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// LoginHandler GET /auth/saml
+//
+// 署名付きのAuthnRequestと共にIdPへリダイレクトします。
+func (p *SAMLProvider) LoginHandler(c echo.Context) error {
+	state, err := beginExternalLogin(c, p.sessStore)
+	if err != nil {
+		return err
+	}
+	u, err := p.sp.AuthnRequestURL(samlRequestID(state), state, time.Now())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.Redirect(http.StatusFound, u)
+}
+
+// samlResubmitTemplate クッキーを付与してACSへ再送信するためのフォーム
+var samlResubmitTemplate = template.Must(template.New("resubmit").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"></head>
+<body onload="document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}">
+<input type="hidden" name="RelayState" value="{{.RelayState}}">
+<input type="hidden" name="` + samlResubmitField + `" value="true">
+<noscript><button type="submit">Continue</button></noscript>
+</form>
+</body></html>
+`))
+
+// AssertionConsumerServiceHandler POST /auth/saml/acs
+func (p *SAMLProvider) AssertionConsumerServiceHandler(c echo.Context) error {
+	if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+		return herror.BadRequest("Authorization Header must not be set.")
+	}
+
+	samlResponse := c.FormValue("SAMLResponse")
+	relayState := c.FormValue("RelayState")
+	if len(samlResponse) == 0 || len(relayState) == 0 {
+		return herror.BadRequest("missing SAMLResponse or RelayState")
+	}
+
+	cookie, err := c.Cookie(cookieName)
+	if err != nil {
+		if isTrue(c.FormValue(samlResubmitField)) {
+			return herror.BadRequest("missing cookie")
+		}
+		// IdPからのクロスサイトPOSTにはSameSite=Laxのクッキーが付与されないため、同一サイトから再送信させる
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+		c.Response().Header().Set("Cache-Control", "no-store")
+		c.Response().WriteHeader(http.StatusOK)
+		return samlResubmitTemplate.Execute(c.Response(), map[string]string{
+			"Action":       p.config.ACSURL,
+			"SAMLResponse": samlResponse,
+			"RelayState":   relayState,
+		})
+	}
+	if cookie.Value != relayState {
+		return herror.BadRequest("invalid state")
+	}
+
+	assertion, err := p.sp.ParseResponse(samlResponse, samlRequestID(relayState), time.Now())
+	if err != nil {
+		if !errors.Is(err, saml.ErrInvalidResponse) {
+			return herror.InternalServerError(err)
+		}
+		p.logger.Info("invalid saml response", zap.Error(err))
+		return herror.BadRequest("invalid SAML response")
+	}
+
+	tu, err := p.userInfo(assertion)
+	if err != nil {
+		return herror.BadRequest(err.Error())
+	}
+
+	if _, err := loginExternalUser(c, p.logger, tu, p.repo, p.sessStore, p.config.RegisterUserIfNotFound); err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusFound, "/") // TODO アカウント関連付けモードのリダイレクト先を設定画面に
+}
+
+// MetadataHandler GET /auth/saml/metadata
+func (p *SAMLProvider) MetadataHandler(c echo.Context) error {
+	return c.Blob(http.StatusOK, samlMetadataMIME, p.sp.Metadata())
+}
+
+// userInfo アサーションの属性をtraQのユーザー情報に対応付けます
+func (p *SAMLProvider) userInfo(a *saml.Assertion) (*samlUserInfo, error) {
+	attr := func(name string) string {
+		if len(name) == 0 {
+			return a.NameID
+		}
+		return a.Attribute(name)
+	}
+
+	tu := &samlUserInfo{
+		id:   attr(p.config.IDAttribute),
+		name: attr(p.config.NameAttribute),
+	}
+	if len(p.config.DisplayNameAttribute) > 0 {
+		tu.displayName = a.Attribute(p.config.DisplayNameAttribute)
+	}
+	if len(tu.id) == 0 {
+		return nil, fmt.Errorf("missing attribute: %s", p.config.IDAttribute)
+	}
+	if len(tu.name) == 0 {
+		return nil, fmt.Errorf("missing attribute: %s", p.config.NameAttribute)
+	}
+	return tu, nil
+}
+
+func (p *SAMLProvider) L() *zap.Logger {
+	return p.logger
+}
+
+// samlRequestID stateに対応するAuthnRequestのID
+//
+// IDはxsd:IDのため、英字から始める必要があります。
+func samlRequestID(state string) string {
+	return "id-" + state
+}
+
+func loadCertificate(file string) (*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: certificate not found", file)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func loadRSAPrivateKey(file string) (*rsa.PrivateKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: private key not found", file)
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if rk, ok := k.(*rsa.PrivateKey); ok {
+			return rk, nil
+		}
+		return nil, fmt.Errorf("%s: not a RSA private key", file)
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %s", file, block.Type)
+	}
+}
+
+// loadSAMLMetadata メタデータをURLまたはファイルから読み込みます
+func loadSAMLMetadata(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "https://") && !strings.HasPrefix(location, "http://") {
+		return ioutil.ReadFile(location)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("failed to fetch saml metadata: " + resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/beevik/etree"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/utils/saml"
+	"github.com/traPtitech/traQ/utils/saml/samltest"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSAMLProvider(t *testing.T, config SAMLProviderConfig) (*SAMLProvider, *samltest.IdP) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "traq-saml-test")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	idp, err := samltest.NewIdP("https://idp.example.com", "https://idp.example.com/sso")
+	require.NoError(t, err)
+	key, cert, err := samltest.GenerateCertificate("traq")
+	require.NoError(t, err)
+
+	write := func(name string, b []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, b, 0600))
+		return path
+	}
+	config.EntityID = "https://traq.example.com/api/auth/saml/metadata"
+	config.ACSURL = "https://traq.example.com/api/auth/saml/acs"
+	config.CertificateFile = write("cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	config.PrivateKeyFile = write("key.pem", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	config.IdPMetadata = write("idp.xml", idp.Metadata())
+
+	p, err := NewSAMLProvider(nil, zap.NewNop(), session.NewMemorySessionStore(), config)
+	require.NoError(t, err)
+	return p, idp
+}
+
+func assertHTTPError(t *testing.T, code int, err error) {
+	t.Helper()
+	var he *echo.HTTPError
+	if assert.True(t, errors.As(err, &he), err) {
+		assert.Equal(t, code, he.Code)
+	}
+}
+
+func TestSAMLProvider_LoginHandler(t *testing.T) {
+	t.Parallel()
+
+	p, idp := newTestSAMLProvider(t, SAMLProviderConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/saml", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, p.LoginHandler(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusFound, rec.Code)
+	res := rec.Result()
+	defer res.Body.Close()
+	cookies := res.Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, cookieName, cookies[0].Name)
+
+	ar, err := idp.ParseAuthnRequest(rec.Header().Get(echo.HeaderLocation), p.sp.Certificate)
+	if assert.NoError(t, err) {
+		assert.Equal(t, cookies[0].Value, ar.RelayState)
+		assert.Equal(t, samlRequestID(cookies[0].Value), ar.ID)
+		assert.Equal(t, p.config.ACSURL, ar.ACSURL)
+		assert.Equal(t, p.config.EntityID, ar.Issuer)
+	}
+}
+
+func TestSAMLProvider_AssertionConsumerServiceHandler(t *testing.T) {
+	t.Parallel()
+
+	p, idp := newTestSAMLProvider(t, SAMLProviderConfig{})
+	post := func(form url.Values, state string) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/saml/acs", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		if len(state) > 0 {
+			req.AddCookie(&http.Cookie{Name: cookieName, Value: state})
+		}
+		rec := httptest.NewRecorder()
+		return rec, p.AssertionConsumerServiceHandler(echo.New().NewContext(req, rec))
+	}
+	response := func(t *testing.T, state string) string {
+		t.Helper()
+		res, err := idp.NewResponse(samltest.ResponseOptions{
+			Audience:      p.config.EntityID,
+			Recipient:     p.config.ACSURL,
+			InResponseTo:  samlRequestID(state),
+			NameID:        "user-1",
+			SignAssertion: true,
+		})
+		require.NoError(t, err)
+		return res
+	}
+
+	t.Run("missing parameters", func(t *testing.T) {
+		t.Parallel()
+		_, err := post(url.Values{"RelayState": {"state"}}, "state")
+		assertHTTPError(t, http.StatusBadRequest, err)
+	})
+
+	t.Run("resubmit without cookie", func(t *testing.T) {
+		t.Parallel()
+		rec, err := post(url.Values{"SAMLResponse": {"<x>\"'"}, "RelayState": {"state"}}, "")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, `action="`+p.config.ACSURL+`"`)
+		assert.Contains(t, body, `name="resubmitted" value="true"`)
+		assert.NotContains(t, body, `<x>"'`)
+	})
+
+	t.Run("missing cookie after resubmission", func(t *testing.T) {
+		t.Parallel()
+		_, err := post(url.Values{"SAMLResponse": {"x"}, "RelayState": {"state"}, "resubmitted": {"true"}}, "")
+		assertHTTPError(t, http.StatusBadRequest, err)
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		t.Parallel()
+		_, err := post(url.Values{"SAMLResponse": {response(t, "state1")}, "RelayState": {"state1"}}, "state2")
+		assertHTTPError(t, http.StatusBadRequest, err)
+	})
+
+	t.Run("response to another request", func(t *testing.T) {
+		t.Parallel()
+		_, err := post(url.Values{"SAMLResponse": {response(t, "state1")}, "RelayState": {"state2"}}, "state2")
+		assertHTTPError(t, http.StatusBadRequest, err)
+	})
+}
+
+func TestSAMLProvider_MetadataHandler(t *testing.T) {
+	t.Parallel()
+
+	p, _ := newTestSAMLProvider(t, SAMLProviderConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/saml/metadata", nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, p.MetadataHandler(echo.New().NewContext(req, rec)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, samlMetadataMIME, rec.Header().Get(echo.HeaderContentType))
+	doc := etree.NewDocument()
+	if assert.NoError(t, doc.ReadFromBytes(rec.Body.Bytes())) {
+		assert.Equal(t, p.config.EntityID, doc.Root().SelectAttrValue("entityID", ""))
+	}
+}
+
+func TestSAMLProvider_userInfo(t *testing.T) {
+	t.Parallel()
+
+	a := &saml.Assertion{
+		NameID: "transient-id",
+		Attributes: map[string][]string{
+			"uid":         {"alice.liddell"},
+			"employeeID":  {"0001"},
+			"displayName": {"Alice Liddell"},
+		},
+	}
+
+	t.Run("NameID", func(t *testing.T) {
+		t.Parallel()
+		p := &SAMLProvider{}
+		tu, err := p.userInfo(a)
+		if assert.NoError(t, err) {
+			assert.Equal(t, SAMLProviderName, tu.GetProviderName())
+			assert.Equal(t, "transient-id", tu.GetID())
+			assert.Equal(t, "transient-id", tu.GetName())
+			assert.Empty(t, tu.GetDisplayName())
+		}
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		t.Parallel()
+		p := &SAMLProvider{config: SAMLProviderConfig{
+			IDAttribute:          "employeeID",
+			NameAttribute:        "uid",
+			DisplayNameAttribute: "displayName",
+		}}
+		tu, err := p.userInfo(a)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "0001", tu.GetID())
+			assert.Equal(t, "alice.liddell", tu.GetRawName())
+			assert.Equal(t, "alice_liddell", tu.GetName())
+			assert.Equal(t, "Alice Liddell", tu.GetDisplayName())
+		}
+	})
+
+	t.Run("missing attribute", func(t *testing.T) {
+		t.Parallel()
+		p := &SAMLProvider{config: SAMLProviderConfig{IDAttribute: "mail"}}
+		_, err := p.userInfo(a)
+		assert.Error(t, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/traPtitech/traQ/router/auth"
 	"github.com/traPtitech/traQ/router/oauth2"
 	v3 "github.com/traPtitech/traQ/router/v3"
+	"github.com/traPtitech/traQ/utils/webauthn"
 )
 
 // Config APIサーバー設定
@@ -18,14 +19,24 @@ type Config struct {
 	AccessLogging bool
 	// Gzipped レスポンスをGzip圧縮するかどうか
 	Gzipped bool
+	// Origin サーバーオリジン
+	Origin string
 	// AccessTokenExp アクセストークンの有効時間(秒)
 	AccessTokenExp int
 	// IsRefreshEnabled リフレッシュトークンを発行するかどうか
 	IsRefreshEnabled bool
+	// IsJWTAccessTokenEnabled アクセストークンを署名付きJWTで発行するかどうか
+	IsJWTAccessTokenEnabled bool
+	// IsOpenClientRegistrationEnabled 初期アクセストークン無しのOAuth2動的クライアント登録を受け付けるかどうか
+	IsOpenClientRegistrationEnabled bool
 	// SkyWaySecretKey SkyWayクレデンシャル用シークレットキー
 	SkyWaySecretKey string
 	// ExternalAuth 外部認証設定
 	ExternalAuth ExternalAuthConfig
+	// WebPushVAPIDPublicKey Web PushのVAPID公開鍵
+	WebPushVAPIDPublicKey string
+	// WebAuthn WebAuthnのRelying Party設定
+	WebAuthn webauthn.RelyingParty
 }
 
 // ExternalAuth 外部認証設定
@@ -38,6 +49,10 @@ type ExternalAuthConfig struct {
 	TraQ auth.TraQProviderConfig
 	// OIDC OpenID Connect
 	OIDC auth.OIDCProviderConfig
+	// LDAP LDAP
+	LDAP auth.LDAPProviderConfig
+	// SAML SAML 2.0
+	SAML auth.SAMLProviderConfig
 }
 
 func (c ExternalAuthConfig) ValidProviders() map[string]bool {
@@ -54,13 +69,23 @@ func (c ExternalAuthConfig) ValidProviders() map[string]bool {
 	if c.OIDC.Valid() {
 		res[auth.OIDCProviderName] = true
 	}
+	if c.LDAP.Valid() {
+		res[auth.LDAPProviderName] = true
+	}
+	if c.SAML.Valid() {
+		res[auth.SAMLProviderName] = true
+	}
 	return res
 }
 
 func provideOAuth2Config(c *Config) oauth2.Config {
 	return oauth2.Config{
-		AccessTokenExp:   c.AccessTokenExp,
-		IsRefreshEnabled: c.IsRefreshEnabled,
+		AccessTokenExp:                  c.AccessTokenExp,
+		IsRefreshEnabled:                c.IsRefreshEnabled,
+		Issuer:                          c.Origin + "/api/v3/oauth2",
+		Origin:                          c.Origin,
+		IsJWTAccessTokenEnabled:         c.IsJWTAccessTokenEnabled,
+		IsOpenClientRegistrationEnabled: c.IsOpenClientRegistrationEnabled,
 	}
 }
 
@@ -70,5 +95,7 @@ func provideV3Config(c *Config) v3.Config {
 		Revision:                        c.Revision,
 		SkyWaySecretKey:                 c.SkyWaySecretKey,
 		EnabledExternalAccountProviders: c.ExternalAuth.ValidProviders(),
+		WebPushVAPIDPublicKey:           c.WebPushVAPIDPublicKey,
+		WebAuthn:                        c.WebAuthn,
 	}
 }
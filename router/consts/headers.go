@@ -7,6 +7,7 @@ const (
 	HeaderIfNoneMatch       = "If-None-Match"
 	HeaderIfModifiedSince   = "If-Modified-Since"
 	HeaderIfUnmodifiedSince = "If-Unmodified-Since"
+	HeaderRetryAfter        = "Retry-After"
 	HeaderFileMetaType      = "X-TRAQ-FILE-TYPE"
 	HeaderCacheFile         = "X-TRAQ-FILE-CACHE"
 	HeaderSignature         = "X-TRAQ-Signature"
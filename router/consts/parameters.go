@@ -16,4 +16,11 @@ const (
 	ParamBotID          = "botID"
 	ParamClientID       = "clientID"
 	ParamClipFolderID   = "folderID"
+	ParamKeywordAlertID = "alertID"
+	ParamInboxEntryID   = "entryID"
+	ParamSubscriptionID = "subscriptionID"
+	ParamCredentialID   = "credentialID"
+	ParamInvitationID   = "invitationID"
+	ParamInviteToken    = "inviteToken"
+	ParamRoleName       = "roleName"
 )
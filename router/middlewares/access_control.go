@@ -1,7 +1,9 @@
 package middlewares
 
 import (
+	"errors"
 	"fmt"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
@@ -44,6 +46,52 @@ func AccessControlMiddlewareGenerator(r rbac.RBAC) func(p ...permission.Permissi
 	}
 }
 
+// ChannelAccessControlMiddlewareGenerator チャンネルスコープのロールを考慮したアクセスコントロールミドルウェアのジェネレーターを返します
+//
+// ユーザー自身のロールに加え、リクエスト対象のチャンネル(メッセージの場合はそのチャンネル)とその祖先チャンネルで割り当てられたロールを考慮します。
+// ParamRetrieverによってチャンネルまたはメッセージが取得済みである必要があります。
+func ChannelAccessControlMiddlewareGenerator(r rbac.RBAC, repo repository.Repository, cm channel.Manager) func(p ...permission.Permission) echo.MiddlewareFunc {
+	return func(p ...permission.Permission) echo.MiddlewareFunc {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				// OAuth2スコープ権限検証
+				if scopes, ok := c.Get(consts.KeyOAuth2AccessScopes).(model.AccessScopes); ok {
+					for _, v := range p {
+						if !r.IsAnyGranted(scopes.StringArray(), v) {
+							// NG
+							return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("you are not permitted to request to '%s'", c.Request().URL.Path))
+						}
+					}
+				}
+
+				var channelID uuid.UUID
+				if ch, ok := c.Get(consts.KeyParamChannel).(*model.Channel); ok {
+					channelID = ch.ID
+				} else if m, ok := c.Get(consts.KeyParamMessage).(*model.Message); ok {
+					channelID = m.ChannelID
+				} else {
+					return herror.InternalServerError(errors.New("channel or message is not retrieved"))
+				}
+
+				// ユーザー権限検証
+				user := c.Get(consts.KeyUser).(model.UserInfo)
+				for _, v := range p {
+					ok, err := rbac.IsGrantedInChannel(r, repo, cm.PublicChannelTree(), user, channelID, v)
+					if err != nil {
+						return herror.InternalServerError(err)
+					}
+					if !ok {
+						// NG
+						return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("you are not permitted to request to '%s'", c.Request().URL.Path))
+					}
+				}
+
+				return next(c) // OK
+			}
+		}
+	}
+}
+
 // AdminOnly 管理者ユーザーのみを通すミドルウェア
 func AdminOnly(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
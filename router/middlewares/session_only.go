@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/router/extension/herror"
+)
+
+// SessionOnly Authorizationヘッダーによる認証を拒否し、クッキーセッションでの認証のみを許可するミドルウェア
+//
+// UserAuthenticateの前に置く必要があります。
+func SessionOnly() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+				return herror.Forbidden("this endpoint can be accessed only with a browser session")
+			}
+			return next(c)
+		}
+	}
+}
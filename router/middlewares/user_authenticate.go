@@ -10,11 +10,16 @@ import (
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
 	"golang.org/x/sync/singleflight"
+	"time"
 )
 
 const authScheme = "Bearer"
 
+// tokenLastUsedAtPrecision パーソナルアクセストークンの最終使用日時の更新間隔
+const tokenLastUsedAtPrecision = time.Minute
+
 // UserAuthenticate リクエスト認証ミドルウェア
 func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.MiddlewareFunc {
 	var sfUser singleflight.Group
@@ -33,7 +38,7 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 				}
 
 				// OAuth2 Token検証
-				token, err := repo.GetTokenByAccess(ah[l+1:])
+				token, err := utils.GetOAuth2Token(repo, ah[l+1:])
 				if err != nil {
 					switch err {
 					case repository.ErrNotFound:
@@ -48,6 +53,13 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 					return herror.Unauthorized("invalid token")
 				}
 
+				// パーソナルアクセストークンの最終使用日時を記録
+				if now := time.Now(); token.IsPersonal() && (!token.LastUsedAt.Valid || now.Sub(token.LastUsedAt.Time) >= tokenLastUsedAtPrecision) {
+					if err := repo.UpdateTokenLastUsedAt(token.ID, now); err != nil {
+						return herror.InternalServerError(err)
+					}
+				}
+
 				c.Set(consts.KeyOAuth2AccessScopes, token.Scopes)
 				uid = token.UserID
 			} else {
@@ -60,6 +72,11 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 					return herror.Unauthorized("You are not logged in")
 				}
 
+				// 最終アクティビティ日時を記録
+				if err := sessStore.UpdateSessionActivity(sess, c.RealIP()); err != nil {
+					return herror.InternalServerError(err)
+				}
+
 				uid = sess.UserID()
 			}
 
@@ -81,7 +81,7 @@ func (h *Handler) AuthorizationEndpointHandler(c echo.Context) error {
 			return herror.InternalServerError(err)
 		}
 	}
-	if len(client.RedirectURI) == 0 {
+	if len(client.RedirectURI) == 0 || client.Pending {
 		return herror.Forbidden("invalid client")
 	}
 
@@ -97,6 +97,12 @@ func (h *Handler) AuthorizationEndpointHandler(c echo.Context) error {
 	}
 
 	// PKCE確認
+	if client.RequirePKCE && (len(req.CodeChallenge) == 0 || req.CodeChallengeMethod != "S256") {
+		q.Set("error", errInvalidRequest)
+		q.Set("error_description", "PKCE with S256 is required for this client")
+		redirectURI.RawQuery = q.Encode()
+		return c.Redirect(http.StatusFound, redirectURI.String())
+	}
 	if len(req.CodeChallengeMethod) > 0 {
 		if req.CodeChallengeMethod != "plain" && req.CodeChallengeMethod != "S256" {
 			q.Set("error", errInvalidRequest)
@@ -306,7 +312,7 @@ func (h *Handler) AuthorizationDecideHandler(c echo.Context) error {
 			return herror.InternalServerError(err)
 		}
 	}
-	if client.RedirectURI == "" { // RedirectURIが事前登録されていない
+	if client.RedirectURI == "" || client.Pending { // RedirectURIが事前登録されていない、或いは承認待ち
 		return herror.Forbidden("invalid client")
 	}
 	redirectURI, _ := url.ParseRequestURI(client.RedirectURI)
@@ -241,6 +241,35 @@ func TestHandlers_AuthorizationEndpointHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("Found (pkce required)", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		client := &model.OAuth2Client{
+			ID:           random.AlphaNumeric(36),
+			Name:         "test client",
+			Confidential: false,
+			CreatorID:    uuid.Must(uuid.NewV4()),
+			RedirectURI:  "http://example.com",
+			Scopes:       scopesRead,
+			RequirePKCE:  true,
+		}
+		require.NoError(t, env.Repository.SaveClient(client))
+		e := env.R(t)
+		for _, method := range []string{"", "plain"} {
+			res := e.POST("/oauth2/authorize").
+				WithFormField("client_id", client.ID).
+				WithFormField("response_type", "code").
+				WithFormField("code_challenge_method", method).
+				WithFormField("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM").
+				Expect()
+			res.Status(http.StatusFound)
+			loc, err := res.Raw().Location()
+			if assert.NoError(err) {
+				assert.Equal(errInvalidRequest, loc.Query().Get("error"))
+			}
+		}
+	})
+
 	t.Run("Found (invalid scope)", func(t *testing.T) {
 		t.Parallel()
 		assert := assert.New(t)
@@ -0,0 +1,414 @@
+package oauth2
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/gob"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// userCodeCharset ユーザーコードに使用する文字 (RFC 8628 6.1 推奨の母音を除く子音)
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+func init() {
+	gob.Register(deviceVerificationContext{})
+}
+
+// deviceVerificationContext 確認画面を表示したセッションに保存するデバイス認可の確認情報
+type deviceVerificationContext struct {
+	UserCode  string
+	CSRFToken string
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorizationEndpointHandler デバイス認可エンドポイントのハンドラ
+func (h *Handler) DeviceAuthorizationEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req struct {
+		Scope        string `form:"scope"`
+		ClientID     string `form:"client_id"`
+		ClientSecret string `form:"client_secret"`
+	}
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if client.Pending || (client.Confidential && client.Secret != pw) {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	// 要求スコープ確認
+	reqScopes, err := h.splitAndValidateScope(req.Scope)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+	validScopes := client.GetAvailableScopes(reqScopes)
+	if len(reqScopes) == 0 {
+		validScopes = client.Scopes
+	} else if len(validScopes) == 0 {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	data := &model.OAuth2DeviceAuthorization{
+		DeviceCode:      random.SecureAlphaNumeric(36),
+		UserCode:        userCode,
+		ClientID:        client.ID,
+		Status:          model.OAuth2DeviceAuthorizationPending,
+		Scopes:          validScopes,
+		PollingInterval: deviceCodeInterval,
+		ExpiresIn:       deviceCodeExp,
+		CreatedAt:       time.Now(),
+	}
+	if err := h.Repo.SaveDeviceAuthorization(data); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
+	verificationURI := h.Origin + "/device"
+	return c.JSON(http.StatusOK, deviceAuthorizationResponse{
+		DeviceCode:              data.DeviceCode,
+		UserCode:                formatUserCode(data.UserCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {formatUserCode(data.UserCode)}}.Encode(),
+		ExpiresIn:               data.ExpiresIn,
+		Interval:                data.PollingInterval,
+	})
+}
+
+type deviceVerificationResponse struct {
+	ClientID    string   `json:"clientId"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+	CSRFToken   string   `json:"csrfToken"`
+}
+
+// GetDeviceVerificationHandler デバイス認可の確認画面用の情報を返すハンドラ
+func (h *Handler) GetDeviceVerificationHandler(c echo.Context) error {
+	d, err := h.getPendingDeviceAuthorization(c.QueryParam("user_code"))
+	if err != nil {
+		return err
+	}
+	client, err := h.Repo.GetClient(d.ClientID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound("unknown user code")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	// 承認・拒否リクエストの偽造防止のため、セッションにトークンを保存
+	se, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if se == nil {
+		return herror.Forbidden("bad session")
+	}
+	vc := deviceVerificationContext{
+		UserCode:  d.UserCode,
+		CSRFToken: random.SecureAlphaNumeric(32),
+	}
+	if err := se.Set(deviceVerificationSession, vc); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusOK, deviceVerificationResponse{
+		ClientID:    client.ID,
+		Name:        client.Name,
+		Description: client.Description,
+		Scopes:      d.Scopes.StringArray(),
+		CSRFToken:   vc.CSRFToken,
+	})
+}
+
+type deviceVerificationDecideRequest struct {
+	UserCode  string `form:"user_code"`
+	Submit    string `form:"submit"`
+	CSRFToken string `form:"csrf_token"`
+}
+
+func (r deviceVerificationDecideRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.UserCode, vd.Required),
+		vd.Field(&r.Submit, vd.Required),
+		vd.Field(&r.CSRFToken, vd.Required),
+	)
+}
+
+// DeviceVerificationDecideHandler デバイス認可の承認・拒否のハンドラ
+func (h *Handler) DeviceVerificationDecideHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req deviceVerificationDecideRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	// セッション確認
+	se, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if se == nil {
+		return herror.Forbidden("bad session")
+	}
+	_vc, err := se.Get(deviceVerificationSession)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	vc, ok := _vc.(deviceVerificationContext)
+	if !ok || vc.UserCode != normalizeUserCode(req.UserCode) || subtle.ConstantTimeCompare([]byte(vc.CSRFToken), []byte(req.CSRFToken)) != 1 {
+		return herror.Forbidden("bad session")
+	}
+	if err := se.Delete(deviceVerificationSession); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	d, err := h.getPendingDeviceAuthorization(req.UserCode)
+	if err != nil {
+		return err
+	}
+	user := c.Get(consts.KeyUser).(model.UserInfo)
+	if err := h.Repo.DecideDeviceAuthorization(d.UserCode, user.GetID(), req.Submit == "approve"); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound("unknown user code")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getPendingDeviceAuthorization ユーザーが入力したユーザーコードの承認待ちのデバイス認可データを取得します
+func (h *Handler) getPendingDeviceAuthorization(userCode string) (*model.OAuth2DeviceAuthorization, error) {
+	d, err := h.Repo.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound("unknown user code")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if d.Status != model.OAuth2DeviceAuthorizationPending || d.IsExpired() {
+		return nil, herror.NotFound("unknown user code")
+	}
+	return d, nil
+}
+
+type tokenEndpointDeviceCodeHandlerRequest struct {
+	DeviceCode   string `form:"device_code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+func (r tokenEndpointDeviceCodeHandlerRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.DeviceCode, vd.Required),
+	)
+}
+
+func (h *Handler) tokenEndpointDeviceCodeHandler(c echo.Context) error {
+	var req tokenEndpointDeviceCodeHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	// デバイスコード確認
+	d, err := h.Repo.GetDeviceAuthorizationByDeviceCode(req.DeviceCode)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(d.ClientID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+	if client.ID != id || (client.Confidential && client.Secret != pw) {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	if d.IsExpired() {
+		if err := h.Repo.DeleteDeviceAuthorization(d.DeviceCode); err != nil && err != repository.ErrNotFound {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errExpiredToken})
+	}
+
+	switch d.Status {
+	case model.OAuth2DeviceAuthorizationPending:
+		// ポーリング間隔が短すぎる場合は間隔を5秒延ばす (RFC 8628 3.5)
+		now := time.Now()
+		errType := errAuthorizationPending
+		interval := d.PollingInterval
+		if d.IsPolledTooFrequently(now) {
+			errType = errSlowDown
+			interval += deviceCodeInterval
+		}
+		if err := h.Repo.UpdateDeviceAuthorizationPolling(d.DeviceCode, now, interval); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errType})
+
+	case model.OAuth2DeviceAuthorizationDenied:
+		if err := h.Repo.DeleteDeviceAuthorization(d.DeviceCode); err != nil && err != repository.ErrNotFound {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errAccessDenied})
+	}
+
+	// デバイスコードは２回使えない
+	// 同時にポーリングされた場合は、削除に成功したリクエストのみがトークンを得る
+	if err := h.Repo.DeleteDeviceAuthorization(d.DeviceCode); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	// トークン発行
+	newToken, err := h.Repo.IssueToken(client, d.UserID, client.RedirectURI, d.Scopes, h.AccessTokenExp, h.IsRefreshEnabled)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
+	accessToken, err := h.accessToken(newToken)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
+	res := &tokenResponse{
+		TokenType:   authScheme,
+		AccessToken: accessToken,
+		ExpiresIn:   newToken.ExpiresIn,
+	}
+	if newToken.IsRefreshEnabled() {
+		res.RefreshToken = newToken.RefreshToken
+	}
+	res.IDToken, err = h.issueIDToken(d.UserID, client.ID, newToken.Scopes, "")
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// generateUserCode ユーザーコードを生成します
+func generateUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeCharset)))
+	b := make([]byte, userCodeLength)
+	for i := range b {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = userCodeCharset[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// formatUserCode 入力しやすいようにユーザーコードを XXXX-XXXX の形式にします
+func formatUserCode(code string) string {
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
+
+// normalizeUserCode ユーザーが入力したユーザーコードを正規化します
+//
+// 区切り文字や空白を除き、大文字に揃えます。
+func normalizeUserCode(input string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		case 'A' <= r && r <= 'Z':
+			return r
+		default:
+			return -1
+		}
+	}, input)
+}
@@ -0,0 +1,257 @@
+package oauth2
+
+import (
+	"github.com/gavv/httpexpect/v2"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	random2 "github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserCode(t *testing.T) {
+	t.Parallel()
+
+	code, err := generateUserCode()
+	require.NoError(t, err)
+	assert.Len(t, code, userCodeLength)
+	for _, r := range code {
+		assert.True(t, strings.ContainsRune(userCodeCharset, r))
+	}
+
+	formatted := formatUserCode(code)
+	assert.Equal(t, code[:4]+"-"+code[4:], formatted)
+	assert.Equal(t, code, normalizeUserCode(formatted))
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode(" bcdf-ghjk "))
+}
+
+func TestHandlers_DeviceAuthorizationFlow(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db2)
+	user := env.CreateUser(t, rand)
+
+	scopesRead := model.AccessScopes{}
+	scopesRead.Add("read")
+	client := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "test device client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random2.AlphaNumeric(36),
+		Scopes:       scopesRead,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	makeDeviceAuthorization := func(t *testing.T, status model.OAuth2DeviceAuthorizationStatus) *model.OAuth2DeviceAuthorization {
+		t.Helper()
+		code, err := generateUserCode()
+		require.NoError(t, err)
+		d := &model.OAuth2DeviceAuthorization{
+			DeviceCode:      random2.SecureAlphaNumeric(36),
+			UserCode:        code,
+			ClientID:        client.ID,
+			Status:          status,
+			Scopes:          scopesRead,
+			PollingInterval: deviceCodeInterval,
+			ExpiresIn:       deviceCodeExp,
+			CreatedAt:       time.Now(),
+		}
+		if status != model.OAuth2DeviceAuthorizationPending {
+			d.UserID = user.GetID()
+		}
+		require.NoError(t, env.Repository.SaveDeviceAuthorization(d))
+		return d
+	}
+	poll := func(t *testing.T, deviceCode string) *httpexpect.Response {
+		t.Helper()
+		return env.R(t).POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", deviceCode).
+			WithFormField("client_id", client.ID).
+			Expect()
+	}
+
+	t.Run("device authorization", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.POST("/oauth2/device_authorization").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("device_code").String().NotEmpty()
+		obj.Value("user_code").String().Match(`^[A-Z]{4}-[A-Z]{4}$`)
+		obj.Value("verification_uri").String().Equal("http://example.com/device")
+		obj.Value("expires_in").Number().Equal(deviceCodeExp)
+		obj.Value("interval").Number().Equal(deviceCodeInterval)
+	})
+
+	t.Run("device authorization with unknown client", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device_authorization").
+			WithFormField("client_id", "unknown").
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Object().
+			Value("error").String().Equal(errInvalidClient)
+	})
+
+	t.Run("verification", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		e := env.R(t)
+		s := env.S(t, user.GetID())
+		csrfToken := e.GET("/oauth2/device/verify").
+			WithQuery("user_code", formatUserCode(d.UserCode)).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			ValueEqual("clientId", client.ID).
+			Value("csrfToken").String().NotEmpty().Raw()
+
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", strings.ToLower(d.UserCode)).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", csrfToken).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNoContent)
+
+		a, err := env.Repository.GetDeviceAuthorizationByDeviceCode(d.DeviceCode)
+		require.NoError(t, err)
+		assert.Equal(t, model.OAuth2DeviceAuthorizationApproved, a.Status)
+		assert.Equal(t, user.GetID(), a.UserID)
+
+		// 承認済みのユーザーコードは使えない
+		e.GET("/oauth2/device/verify").
+			WithQuery("user_code", d.UserCode).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("verification with bad csrf token", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		e := env.R(t)
+		s := env.S(t, user.GetID())
+		csrfToken := e.GET("/oauth2/device/verify").
+			WithQuery("user_code", d.UserCode).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("csrfToken").String().Raw()
+
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", d.UserCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", "wrong").
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusForbidden)
+
+		// 別セッションでは確認画面のトークンを使えない
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", d.UserCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", csrfToken).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusForbidden)
+
+		a, err := env.Repository.GetDeviceAuthorizationByDeviceCode(d.DeviceCode)
+		require.NoError(t, err)
+		assert.Equal(t, model.OAuth2DeviceAuthorizationPending, a.Status)
+	})
+
+	t.Run("verification with access token", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		token := env.IssueToken(t, client, user.GetID(), false)
+		e := env.R(t)
+		e.GET("/oauth2/device/verify").
+			WithQuery("user_code", d.UserCode).
+			WithHeader(echo.HeaderAuthorization, authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusForbidden)
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", d.UserCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", "dummy").
+			WithHeader(echo.HeaderAuthorization, authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("verification without login", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		e := env.R(t)
+		e.GET("/oauth2/device/verify").
+			WithQuery("user_code", d.UserCode).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("authorization pending and slow down", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		poll(t, d.DeviceCode).Status(http.StatusBadRequest).JSON().Object().Value("error").String().Equal(errAuthorizationPending)
+		poll(t, d.DeviceCode).Status(http.StatusBadRequest).JSON().Object().Value("error").String().Equal(errSlowDown)
+
+		a, err := env.Repository.GetDeviceAuthorizationByDeviceCode(d.DeviceCode)
+		require.NoError(t, err)
+		assert.Equal(t, deviceCodeInterval*2, a.PollingInterval)
+	})
+
+	t.Run("approved", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationApproved)
+		obj := poll(t, d.DeviceCode).Status(http.StatusOK).JSON().Object()
+		obj.Value("access_token").String().NotEmpty()
+		obj.Value("token_type").String().Equal(authScheme)
+
+		_, err := env.Repository.GetDeviceAuthorizationByDeviceCode(d.DeviceCode)
+		assert.EqualError(t, err, repository.ErrNotFound.Error())
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationDenied)
+		poll(t, d.DeviceCode).Status(http.StatusBadRequest).JSON().Object().Value("error").String().Equal(errAccessDenied)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationPending)
+		d.CreatedAt = time.Now().Add(-time.Hour)
+		require.NoError(t, env.DB.Save(d).Error)
+		poll(t, d.DeviceCode).Status(http.StatusBadRequest).JSON().Object().Value("error").String().Equal(errExpiredToken)
+	})
+
+	t.Run("another client", func(t *testing.T) {
+		t.Parallel()
+		d := makeDeviceAuthorization(t, model.OAuth2DeviceAuthorizationApproved)
+		env.R(t).POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", d.DeviceCode).
+			WithFormField("client_id", "another").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+}
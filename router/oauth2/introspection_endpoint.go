@@ -0,0 +1,203 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/utils"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	introspectionCacheSize = 2048
+	// introspectionCacheTTL イントロスペクション結果のキャッシュ時間
+	//
+	// 無効化エンドポイント以外でトークンが削除された場合、最大でこの時間だけ有効と判定されます。
+	introspectionCacheTTL = 30 * time.Second
+)
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+type introspectionCacheEntry struct {
+	tokenID   uuid.UUID
+	res       introspectionResponse
+	expiresAt time.Time
+}
+
+// IntrospectionEndpointHandler トークンイントロスペクションエンドポイントのハンドラ (RFC 7662)
+func (h *Handler) IntrospectionEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req struct {
+		Token         string `form:"token"`
+		TokenTypeHint string `form:"token_type_hint"`
+		ClientID      string `form:"client_id"`
+		ClientSecret  string `form:"client_secret"`
+	}
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	// クライアント確認 (リソースサーバーはコンフィデンシャルクライアントとして認証する)
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+	client, err := h.Repo.GetClient(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if !client.Confidential || client.Pending || client.Secret != pw {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	if len(req.Token) == 0 {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	if v, ok := h.getIntrospectionCache().Get(req.Token); ok {
+		if e := v.(*introspectionCacheEntry); time.Now().Before(e.expiresAt) {
+			return c.JSON(http.StatusOK, e.res)
+		}
+		h.getIntrospectionCache().Remove(req.Token)
+	}
+
+	res, entry, err := h.introspect(req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	if entry != nil {
+		h.getIntrospectionCache().Add(req.Token, entry)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// introspect トークンの状態を調べます
+//
+// キャッシュ可能なアクセストークンの場合、キャッシュエントリも返します。
+func (h *Handler) introspect(tokenString, hint string) (introspectionResponse, *introspectionCacheEntry, error) {
+	inactive := introspectionResponse{Active: false}
+
+	lookups := []func() (*model.OAuth2Token, bool, error){
+		func() (*model.OAuth2Token, bool, error) {
+			t, err := utils.GetOAuth2Token(h.Repo, tokenString)
+			return t, false, err
+		},
+		func() (*model.OAuth2Token, bool, error) {
+			t, err := h.Repo.GetTokenByRefresh(tokenString)
+			return t, true, err
+		},
+	}
+	if hint == grantTypeRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	var (
+		token     *model.OAuth2Token
+		isRefresh bool
+	)
+	for _, lookup := range lookups {
+		t, r, err := lookup()
+		if err == nil {
+			token, isRefresh = t, r
+			break
+		}
+		if err != repository.ErrNotFound {
+			return inactive, nil, err
+		}
+	}
+	if token == nil || (!isRefresh && token.IsExpired()) || (isRefresh && !token.IsRefreshEnabled()) {
+		return inactive, nil, nil
+	}
+
+	res := introspectionResponse{
+		Active:   true,
+		Scope:    token.Scopes.String(),
+		ClientID: token.ClientID,
+		Iat:      token.CreatedAt.Unix(),
+		Sub:      token.ClientID,
+		Iss:      h.Issuer,
+		Jti:      token.ID.String(),
+	}
+	if !isRefresh {
+		res.TokenType = authScheme
+		res.Exp = token.CreatedAt.Add(time.Duration(token.ExpiresIn) * time.Second).Unix()
+	}
+	if token.UserID != uuid.Nil {
+		user, err := h.Repo.GetUser(token.UserID, false)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return inactive, nil, nil
+			}
+			return inactive, nil, err
+		}
+		if !user.IsActive() {
+			return inactive, nil, nil
+		}
+		res.Sub = user.GetID().String()
+		res.Username = user.GetName()
+	}
+	if utils.IsJWT(tokenString) {
+		res.Aud = h.Origin
+	}
+
+	if isRefresh {
+		return res, nil, nil
+	}
+	expiresAt := time.Now().Add(introspectionCacheTTL)
+	if exp := time.Unix(res.Exp, 0); exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	return res, &introspectionCacheEntry{tokenID: token.ID, res: res, expiresAt: expiresAt}, nil
+}
+
+// accessToken レスポンスで返すアクセストークン
+func (h *Handler) accessToken(token *model.OAuth2Token) (string, error) {
+	if !h.IsJWTAccessTokenEnabled {
+		return token.AccessToken, nil
+	}
+	return utils.SignAccessToken(token, h.Issuer, h.Origin)
+}
+
+func (h *Handler) getIntrospectionCache() *lru.Cache {
+	h.introspectionCacheOnce.Do(func() {
+		h.introspectionCache, _ = lru.New(introspectionCacheSize)
+	})
+	return h.introspectionCache
+}
+
+// purgeIntrospectionCache 指定したトークンのイントロスペクション結果のキャッシュを削除します
+func (h *Handler) purgeIntrospectionCache(tokenID uuid.UUID) {
+	cache := h.getIntrospectionCache()
+	for _, k := range cache.Keys() {
+		if v, ok := cache.Peek(k); ok && v.(*introspectionCacheEntry).tokenID == tokenID {
+			cache.Remove(k)
+		}
+	}
+}
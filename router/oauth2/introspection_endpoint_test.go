@@ -0,0 +1,147 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/router/utils"
+	random2 "github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_IntrospectionEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopesRead := model.AccessScopes{}
+	scopesRead.Add("read")
+	client := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random2.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopesRead,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+	resourceServer := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "resource server",
+		Confidential: true,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random2.AlphaNumeric(36),
+		Scopes:       scopesRead,
+	}
+	require.NoError(t, env.Repository.SaveClient(resourceServer))
+
+	t.Run("active token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("active").Boolean().True()
+		obj.Value("scope").String().Equal("read")
+		obj.Value("client_id").String().Equal(client.ID)
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("username").String().Equal(user.GetName())
+		obj.Value("token_type").String().Equal(authScheme)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.RefreshToken).
+			WithFormField("token_type_hint", "refresh_token").
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("active").Boolean().True()
+		obj.NotContainsKey("exp")
+	})
+
+	t.Run("JWT access token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), false)
+		jwt, err := utils.SignAccessToken(token, "http://example.com/oauth2", "http://example.com")
+		require.NoError(t, err)
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", jwt).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("active").Boolean().True()
+		obj.Value("jti").String().Equal(token.ID.String())
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), false)
+		jwt, err := utils.SignAccessToken(token, "http://example.com/oauth2", "http://example.com")
+		require.NoError(t, err)
+		e := env.R(t)
+		// 有効な結果がキャッシュされる
+		e.POST("/oauth2/introspect").
+			WithFormField("token", jwt).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().True()
+
+		e.POST("/oauth2/revoke").
+			WithFormField("token", jwt).
+			Expect().
+			Status(http.StatusOK)
+
+		e.POST("/oauth2/introspect").
+			WithFormField("token", jwt).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().False()
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", "unknown").
+			WithFormField("client_id", resourceServer.ID).
+			WithFormField("client_secret", resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			ValueEqual("active", false).
+			NotContainsKey("scope")
+	})
+
+	t.Run("public client", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", "unknown").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+}
@@ -2,6 +2,7 @@ package oauth2
 
 import (
 	"errors"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/labstack/echo/v4"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
@@ -10,6 +11,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/rbac"
 	"go.uber.org/zap"
+	"sync"
 )
 
 const (
@@ -17,6 +19,7 @@ const (
 	grantTypePassword          = "password"
 	grantTypeClientCredentials = "client_credentials"
 	grantTypeRefreshToken      = "refresh_token"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 
 	errInvalidRequest          = "invalid_request"
 	errUnauthorizedClient      = "unauthorized_client"
@@ -29,11 +32,20 @@ const (
 	errUnsupportedGrantType    = "unsupported_grant_type"
 	errLoginRequired           = "login_required"
 	errConsentRequired         = "consent_required"
+	errAuthorizationPending    = "authorization_pending"
+	errSlowDown                = "slow_down"
+	errExpiredToken            = "expired_token"
+	errInvalidToken            = "invalid_token"
+	errInvalidRedirectURI      = "invalid_redirect_uri"
+	errInvalidClientMetadata   = "invalid_client_metadata"
 
-	oauth2ContextSession = "oauth2_context"
-	authScheme           = "Bearer"
+	oauth2ContextSession      = "oauth2_context"
+	deviceVerificationSession = "oauth2_device_verification"
+	authScheme                = "Bearer"
 
 	authorizationCodeExp = 60 * 5
+	deviceCodeExp        = 60 * 10
+	deviceCodeInterval   = 5
 )
 
 type Handler struct {
@@ -42,6 +54,9 @@ type Handler struct {
 	Logger    *zap.Logger
 	SessStore session.Store
 	Config
+
+	introspectionCache     *lru.Cache `wire:"-"`
+	introspectionCacheOnce sync.Once  `wire:"-"`
 }
 
 type Config struct {
@@ -49,6 +64,16 @@ type Config struct {
 	AccessTokenExp int
 	// IsRefreshEnabled リフレッシュトークンを発行するかどうか
 	IsRefreshEnabled bool
+	// Issuer OpenID ConnectのIssuer Identifier (このエンドポイントのURL)
+	Issuer string
+	// Origin サーバーオリジン
+	Origin string
+	// IsJWTAccessTokenEnabled アクセストークンを署名付きJWT (RFC 9068) で発行するかどうか
+	IsJWTAccessTokenEnabled bool
+	// IsOpenClientRegistrationEnabled 初期アクセストークン無しの動的クライアント登録を受け付けるかどうか
+	//
+	// 受け付けたクライアントは管理者の承認待ちになります。
+	IsOpenClientRegistrationEnabled bool
 }
 
 func (h *Handler) Setup(e *echo.Group) {
@@ -57,6 +82,18 @@ func (h *Handler) Setup(e *echo.Group) {
 	e.POST("/authorize", h.AuthorizationEndpointHandler)
 	e.POST("/token", h.TokenEndpointHandler)
 	e.POST("/revoke", h.RevokeTokenEndpointHandler)
+	e.POST("/introspect", h.IntrospectionEndpointHandler)
+	e.POST("/device_authorization", h.DeviceAuthorizationEndpointHandler)
+	e.GET("/device/verify", h.GetDeviceVerificationHandler, middlewares.SessionOnly(), middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockBot(h.Repo))
+	e.POST("/device/verify", h.DeviceVerificationDecideHandler, middlewares.SessionOnly(), middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockBot(h.Repo))
+	e.POST("/register", h.RegistrationEndpointHandler)
+	e.GET("/register/:clientID", h.GetClientConfigurationHandler)
+	e.PUT("/register/:clientID", h.UpdateClientConfigurationHandler)
+	e.DELETE("/register/:clientID", h.DeleteClientConfigurationHandler)
+	e.GET("/userinfo", h.UserInfoEndpointHandler)
+	e.POST("/userinfo", h.UserInfoEndpointHandler)
+	e.GET("/jwks", h.JWKSHandler)
+	e.GET("/.well-known/openid-configuration", h.OpenIDConfigurationHandler)
 }
 
 // splitAndValidateScope スペース区切りのスコープ文字列を分解し、検証します
@@ -16,6 +16,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/jwt"
 	"github.com/traPtitech/traQ/utils/random"
 	"github.com/traPtitech/traQ/utils/storage"
 	"go.uber.org/zap"
@@ -48,6 +49,11 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
+	privRaw, _ := random.GenerateECDSAKey()
+	if err := jwt.SetupSigner(privRaw); err != nil {
+		panic(err)
+	}
+
 	for _, key := range dbs {
 		env := &Env{}
 
@@ -90,6 +96,10 @@ func TestMain(m *testing.M) {
 			Config: Config{
 				AccessTokenExp:   1000,
 				IsRefreshEnabled: true,
+				Issuer:           "http://example.com/oauth2",
+				Origin:           "http://example.com",
+
+				IsOpenClientRegistrationEnabled: true,
 			},
 		}
 		config.Setup(e.Group("/oauth2"))
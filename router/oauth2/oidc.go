@@ -0,0 +1,153 @@
+package oauth2
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	jwt2 "github.com/traPtitech/traQ/utils/jwt"
+	"net/http"
+	"time"
+)
+
+// idTokenExp IDトークンの有効時間(秒)
+const idTokenExp = 60 * 60
+
+// issueIDToken OpenID ConnectのIDトークンを発行します
+//
+// scopesにopenidが含まれない場合は空文字列を返します。
+func (h *Handler) issueIDToken(userID uuid.UUID, clientID string, scopes model.AccessScopes, nonce string) (string, error) {
+	if userID == uuid.Nil || !scopes.Contains(model.ScopeOpenID) {
+		return "", nil
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": h.Issuer,
+		"sub": userID.String(),
+		"aud": clientID,
+		"exp": now.Add(idTokenExp * time.Second).Unix(),
+		"iat": now.Unix(),
+	}
+	if len(nonce) > 0 {
+		claims["nonce"] = nonce
+	}
+	if scopes.Contains(model.ScopeProfile) {
+		user, err := h.Repo.GetUser(userID, false)
+		if err != nil {
+			return "", err
+		}
+		for k, v := range h.profileClaims(user) {
+			claims[k] = v
+		}
+	}
+	return jwt2.Sign(claims)
+}
+
+// profileClaims profileスコープで返すクレーム
+func (h *Handler) profileClaims(user model.UserInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"name":               user.GetResponseDisplayName(),
+		"preferred_username": user.GetName(),
+		"picture":            h.Origin + "/api/v3/public/icon/" + user.GetName(),
+		"updated_at":         user.GetUpdatedAt().Unix(),
+	}
+}
+
+// UserInfoEndpointHandler UserInfoエンドポイントのハンドラ
+func (h *Handler) UserInfoEndpointHandler(c echo.Context) error {
+	ah := c.Request().Header.Get(echo.HeaderAuthorization)
+	l := len(authScheme)
+	if !(len(ah) > l+1 && ah[:l] == authScheme) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme)
+		return herror.Unauthorized("invalid authorization scheme")
+	}
+
+	token, err := utils.GetOAuth2Token(h.Repo, ah[l+1:])
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+			return herror.Unauthorized("invalid token")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if token.IsExpired() || token.UserID == uuid.Nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+		return herror.Unauthorized("invalid token")
+	}
+	if !token.Scopes.Contains(model.ScopeOpenID) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="insufficient_scope"`)
+		return herror.Forbidden("insufficient scope")
+	}
+
+	user, err := h.Repo.GetUser(token.UserID, false)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.Unauthorized("invalid token")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	res := map[string]interface{}{"sub": user.GetID().String()}
+	if token.Scopes.Contains(model.ScopeProfile) {
+		for k, v := range h.profileClaims(user) {
+			res[k] = v
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+type openIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// OpenIDConfigurationHandler OpenID Provider Metadataのハンドラ
+func (h *Handler) OpenIDConfigurationHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, openIDConfiguration{
+		Issuer:                            h.Issuer,
+		AuthorizationEndpoint:             h.Issuer + "/authorize",
+		TokenEndpoint:                     h.Issuer + "/token",
+		UserInfoEndpoint:                  h.Issuer + "/userinfo",
+		RevocationEndpoint:                h.Issuer + "/revoke",
+		IntrospectionEndpoint:             h.Issuer + "/introspect",
+		DeviceAuthorizationEndpoint:       h.Issuer + "/device_authorization",
+		RegistrationEndpoint:              h.Issuer + "/register",
+		JWKSURI:                           h.Issuer + "/jwks",
+		ScopesSupported:                   []string{string(model.ScopeOpenID), string(model.ScopeProfile), "read", "write", "manage_bot"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypeRefreshToken, grantTypePassword, grantTypeClientCredentials, grantTypeDeviceCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodES256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"plain", "S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "name", "preferred_username", "picture", "updated_at"},
+	})
+}
+
+// JWKSHandler IDトークンの検証鍵の公開ハンドラ
+func (h *Handler) JWKSHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, jwt2.JWKS())
+}
@@ -0,0 +1,146 @@
+package oauth2
+
+import (
+	jwt2 "github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/jwt"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlers_OpenIDConnect(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", model.ScopeOpenID, model.ScopeProfile)
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    uuid.Must(uuid.NewV4()),
+		Secret:       random.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	t.Run("ID token", func(t *testing.T) {
+		t.Parallel()
+		authorize := &model.OAuth2Authorize{
+			Code:           random.AlphaNumeric(36),
+			ClientID:       client.ID,
+			UserID:         user.GetID(),
+			CreatedAt:      time.Now(),
+			ExpiresIn:      1000,
+			RedirectURI:    "http://example.com",
+			Scopes:         scopes,
+			OriginalScopes: scopes,
+			Nonce:          "nonce",
+		}
+		require.NoError(t, env.Repository.SaveAuthorize(authorize))
+
+		e := env.R(t)
+		obj := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeAuthorizationCode).
+			WithFormField("code", authorize.Code).
+			WithFormField("redirect_uri", "http://example.com").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		claims := jwt2.MapClaims{}
+		if assert.NoError(t, jwt.Verify(obj.Value("id_token").String().Raw(), claims)) {
+			assert.Equal(t, "http://example.com/oauth2", claims["iss"])
+			assert.Equal(t, user.GetID().String(), claims["sub"])
+			assert.Equal(t, client.ID, claims["aud"])
+			assert.Equal(t, "nonce", claims["nonce"])
+			assert.Equal(t, user.GetName(), claims["preferred_username"])
+		}
+	})
+
+	t.Run("no ID token without openid scope", func(t *testing.T) {
+		t.Parallel()
+		authorize := env.MakeAuthorizeData(t, client.ID, user.GetID())
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeAuthorizationCode).
+			WithFormField("code", authorize.Code).
+			WithFormField("redirect_uri", "http://example.com").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			NotContainsKey("id_token")
+	})
+
+	t.Run("userinfo", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), false)
+		e := env.R(t)
+		obj := e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("preferred_username").String().Equal(user.GetName())
+		obj.Value("picture").String().Equal("http://example.com/api/v3/public/icon/" + user.GetName())
+	})
+
+	t.Run("userinfo without openid scope", func(t *testing.T) {
+		t.Parallel()
+		token, err := env.Repository.IssueToken(client, user.GetID(), client.RedirectURI, model.AccessScopes{"read": {}}, 1000, false)
+		require.NoError(t, err)
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("userinfo with invalid token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" invalid").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("discovery", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/oauth2/.well-known/openid-configuration").
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("issuer").String().Equal("http://example.com/oauth2")
+		obj.Value("jwks_uri").String().Equal("http://example.com/oauth2/jwks")
+	})
+
+	t.Run("jwks", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/jwks").
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("keys").
+			Array().
+			Length().
+			Equal(1)
+	})
+}
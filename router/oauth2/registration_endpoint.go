@@ -0,0 +1,398 @@
+package oauth2
+
+import (
+	"crypto/subtle"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tokenEndpointAuthMethodNone   = "none"
+	tokenEndpointAuthMethodBasic  = "client_secret_basic"
+	tokenEndpointAuthMethodPost   = "client_secret_post"
+	maxDynamicClientNameRuneCount = 32
+)
+
+// dynamicClientScopes 動的クライアント登録で要求できるスコープ
+//
+// これ以外のスコープは管理者がクライアント管理APIで付与します。
+var dynamicClientScopes = []model.AccessScope{model.ScopeOpenID, model.ScopeProfile, "read", "write"}
+
+// clientMetadata クライアントメタデータ (RFC 7591 2.)
+type clientMetadata struct {
+	ClientID                string   `json:"client_id,omitempty"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	ClientName              string   `json:"client_name"`
+	Scope                   string   `json:"scope"`
+}
+
+// clientInformationResponse クライアント情報レスポンス (RFC 7591 3.2.1., RFC 7592 3.)
+type clientInformationResponse struct {
+	ClientID                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64    `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   *int64   `json:"client_secret_expires_at,omitempty"`
+	RegistrationAccessToken string   `json:"registration_access_token"`
+	RegistrationClientURI   string   `json:"registration_client_uri"`
+	RedirectURIs            []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	ClientName              string   `json:"client_name"`
+	Scope                   string   `json:"scope"`
+	// ApprovalPending 管理者の承認待ちかどうか (traQ拡張)
+	ApprovalPending bool `json:"approval_pending,omitempty"`
+}
+
+// validatedClientMetadata 検証済みのクライアントメタデータ
+type validatedClientMetadata struct {
+	redirectURI   string
+	confidential  bool
+	grantTypes    []string
+	responseTypes []string
+	name          string
+	scopes        model.AccessScopes
+}
+
+// RegistrationEndpointHandler 動的クライアント登録エンドポイントのハンドラ (RFC 7591)
+//
+// 初期アクセストークンを指定した場合、トークンの発行者を開発者としてクライアントを登録します。
+// 指定しなかった場合、クライアントは管理者の承認待ちになります。
+func (h *Handler) RegistrationEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req clientMetadata
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClientMetadata})
+	}
+	meta, errRes := h.validateClientMetadata(req, nil)
+	if errRes != nil {
+		return c.JSON(http.StatusBadRequest, errRes)
+	}
+
+	client := &model.OAuth2Client{
+		ID:                      random.SecureAlphaNumeric(36),
+		Name:                    meta.name,
+		Confidential:            meta.confidential,
+		RedirectURI:             meta.redirectURI,
+		Scopes:                  meta.scopes,
+		RequirePKCE:             !meta.confidential,
+		RegistrationAccessToken: random.SecureAlphaNumeric(36),
+	}
+	if meta.confidential {
+		client.Secret = random.SecureAlphaNumeric(36)
+	}
+
+	// 初期アクセストークン確認
+	if ah := c.Request().Header.Get(echo.HeaderAuthorization); len(ah) > 0 {
+		l := len(authScheme)
+		if !(len(ah) > l+1 && ah[:l] == authScheme) {
+			return h.invalidRegistrationToken(c)
+		}
+		t, err := h.Repo.UseInitialAccessToken(ah[l+1:])
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return h.invalidRegistrationToken(c)
+			default:
+				h.L(c).Error(err.Error(), zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+			}
+		}
+		user, err := h.Repo.GetUser(t.CreatorID, false)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return h.invalidRegistrationToken(c)
+			default:
+				h.L(c).Error(err.Error(), zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+			}
+		}
+		if !user.IsActive() {
+			return h.invalidRegistrationToken(c)
+		}
+		client.CreatorID = user.GetID()
+	} else {
+		if !h.IsOpenClientRegistrationEnabled {
+			return h.invalidRegistrationToken(c)
+		}
+		client.Pending = true
+	}
+
+	if err := h.Repo.SaveClient(client); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	if client.Pending {
+		h.L(c).Info("a client was registered and is pending approval", zap.String("clientId", client.ID), zap.String("clientName", client.Name))
+	}
+
+	return c.JSON(http.StatusCreated, h.formatClientInformation(client, meta))
+}
+
+// GetClientConfigurationHandler クライアント設定取得エンドポイントのハンドラ (RFC 7592 2.1.)
+func (h *Handler) GetClientConfigurationHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	client, err := h.authenticateClientConfiguration(c)
+	if err != nil || client == nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, h.formatClientInformation(client, nil))
+}
+
+// UpdateClientConfigurationHandler クライアント設定更新エンドポイントのハンドラ (RFC 7592 2.2.)
+//
+// スコープは現在のスコープの範囲内でのみ変更できます。
+// トークンエンドポイントの認証方式は変更できません。
+func (h *Handler) UpdateClientConfigurationHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	client, err := h.authenticateClientConfiguration(c)
+	if err != nil || client == nil {
+		return err
+	}
+
+	var req clientMetadata
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClientMetadata})
+	}
+	if req.ClientID != client.ID {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClientMetadata, ErrorDescription: "client_id does not match"})
+	}
+	meta, errRes := h.validateClientMetadata(req, client)
+	if errRes != nil {
+		return c.JSON(http.StatusBadRequest, errRes)
+	}
+
+	args := repository.UpdateClientArgs{
+		Name:        optional.StringFrom(meta.name),
+		CallbackURL: optional.StringFrom(meta.redirectURI),
+		Scopes:      meta.scopes,
+	}
+	if err := h.Repo.UpdateClient(client.ID, args); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	client.Name = meta.name
+	client.RedirectURI = meta.redirectURI
+	client.Scopes = meta.scopes
+
+	return c.JSON(http.StatusOK, h.formatClientInformation(client, meta))
+}
+
+// DeleteClientConfigurationHandler クライアント削除エンドポイントのハンドラ (RFC 7592 2.3.)
+func (h *Handler) DeleteClientConfigurationHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	client, err := h.authenticateClientConfiguration(c)
+	if err != nil || client == nil {
+		return err
+	}
+	if err := h.Repo.DeleteClient(client.ID); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// authenticateClientConfiguration クライアント設定エンドポイントのリクエストを登録アクセストークンで認証します
+//
+// 認証に失敗した場合、レスポンスを書き込み、nilのクライアントを返します。
+func (h *Handler) authenticateClientConfiguration(c echo.Context) (*model.OAuth2Client, error) {
+	ah := c.Request().Header.Get(echo.HeaderAuthorization)
+	l := len(authScheme)
+	if !(len(ah) > l+1 && ah[:l] == authScheme) {
+		return nil, h.invalidRegistrationToken(c)
+	}
+
+	client, err := h.Repo.GetClient(c.Param(consts.ParamClientID))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, h.invalidRegistrationToken(c)
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return nil, c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if !client.IsDynamicallyRegistered() || subtle.ConstantTimeCompare([]byte(client.RegistrationAccessToken), []byte(ah[l+1:])) != 1 {
+		return nil, h.invalidRegistrationToken(c)
+	}
+	return client, nil
+}
+
+// invalidRegistrationToken 初期アクセストークン・登録アクセストークンが不正な場合のレスポンスを返します
+func (h *Handler) invalidRegistrationToken(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` error="invalid_token"`)
+	return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidToken})
+}
+
+// validateClientMetadata クライアントメタデータを検証します
+//
+// currentには更新対象のクライアントを指定します。新規登録の場合はnilを指定します。
+func (h *Handler) validateClientMetadata(req clientMetadata, current *model.OAuth2Client) (*validatedClientMetadata, *oauth2ErrorResponse) {
+	invalid := func(desc string) *oauth2ErrorResponse {
+		return &oauth2ErrorResponse{ErrorType: errInvalidClientMetadata, ErrorDescription: desc}
+	}
+	meta := &validatedClientMetadata{}
+
+	// トークンエンドポイントの認証方式
+	switch req.TokenEndpointAuthMethod {
+	case "", tokenEndpointAuthMethodBasic, tokenEndpointAuthMethodPost:
+		meta.confidential = true
+	case tokenEndpointAuthMethodNone:
+		meta.confidential = false
+	default:
+		return nil, invalid("unsupported token_endpoint_auth_method")
+	}
+	if current != nil && current.Confidential != meta.confidential {
+		return nil, invalid("token_endpoint_auth_method cannot be changed")
+	}
+
+	// グラントタイプ
+	meta.grantTypes = req.GrantTypes
+	if len(meta.grantTypes) == 0 {
+		meta.grantTypes = []string{grantTypeAuthorizationCode}
+	}
+	usesRedirect := false
+	for _, v := range meta.grantTypes {
+		switch v {
+		case grantTypeAuthorizationCode:
+			usesRedirect = true
+		case grantTypeRefreshToken, grantTypeDeviceCode:
+		case grantTypeClientCredentials:
+			if !meta.confidential {
+				return nil, invalid("client_credentials grant requires a confidential client")
+			}
+		default:
+			return nil, invalid("unsupported grant_type: " + v)
+		}
+	}
+
+	// レスポンスタイプ
+	meta.responseTypes = req.ResponseTypes
+	if len(meta.responseTypes) == 0 && usesRedirect {
+		meta.responseTypes = []string{"code"}
+	}
+	for _, v := range meta.responseTypes {
+		if v != "code" {
+			return nil, invalid("unsupported response_type: " + v)
+		}
+	}
+
+	// リダイレクトURI
+	switch {
+	case len(req.RedirectURIs) > 1:
+		return nil, &oauth2ErrorResponse{ErrorType: errInvalidRedirectURI, ErrorDescription: "only one redirect_uri is supported"}
+	case len(req.RedirectURIs) == 1:
+		u, err := url.ParseRequestURI(req.RedirectURIs[0])
+		if err != nil || len(u.Scheme) == 0 || len(u.Fragment) > 0 {
+			return nil, &oauth2ErrorResponse{ErrorType: errInvalidRedirectURI}
+		}
+		meta.redirectURI = u.String()
+	case usesRedirect:
+		return nil, &oauth2ErrorResponse{ErrorType: errInvalidRedirectURI, ErrorDescription: "redirect_uris is required"}
+	}
+
+	// クライアント名
+	meta.name = strings.TrimSpace(req.ClientName)
+	if n := utf8.RuneCountInString(meta.name); n == 0 || n > maxDynamicClientNameRuneCount {
+		return nil, invalid("client_name must be 1-32 characters")
+	}
+
+	// スコープ
+	scopes, err := h.splitAndValidateScope(req.Scope)
+	if err != nil {
+		return nil, invalid("invalid scope")
+	}
+	if len(scopes) == 0 {
+		scopes.Add("read")
+	}
+	for s := range scopes {
+		if current != nil {
+			if !current.Scopes.Contains(s) {
+				return nil, invalid("scope cannot be extended: " + string(s))
+			}
+			continue
+		}
+		if !containsScope(dynamicClientScopes, s) {
+			return nil, invalid("scope is not allowed for dynamic registration: " + string(s))
+		}
+	}
+	meta.scopes = scopes
+
+	return meta, nil
+}
+
+// formatClientInformation クライアント情報レスポンスを作成します
+//
+// metaがnilの場合、クライアントの情報から推定したメタデータを返します。
+func (h *Handler) formatClientInformation(client *model.OAuth2Client, meta *validatedClientMetadata) *clientInformationResponse {
+	res := &clientInformationResponse{
+		ClientID:                client.ID,
+		ClientSecret:            client.Secret,
+		ClientIDIssuedAt:        client.CreatedAt.Unix(),
+		RegistrationAccessToken: client.RegistrationAccessToken,
+		RegistrationClientURI:   h.Issuer + "/register/" + client.ID,
+		RedirectURIs:            []string{},
+		TokenEndpointAuthMethod: tokenEndpointAuthMethodNone,
+		ClientName:              client.Name,
+		Scope:                   client.Scopes.String(),
+		ApprovalPending:         client.Pending,
+	}
+	if client.Confidential {
+		var never int64
+		res.ClientSecretExpiresAt = &never
+		res.TokenEndpointAuthMethod = tokenEndpointAuthMethodBasic
+	}
+	if len(client.RedirectURI) > 0 {
+		res.RedirectURIs = []string{client.RedirectURI}
+	}
+
+	if meta != nil {
+		res.GrantTypes = meta.grantTypes
+		res.ResponseTypes = meta.responseTypes
+	} else {
+		res.GrantTypes = []string{grantTypeRefreshToken, grantTypeDeviceCode}
+		res.ResponseTypes = []string{}
+		if len(client.RedirectURI) > 0 {
+			res.GrantTypes = append(res.GrantTypes, grantTypeAuthorizationCode)
+			res.ResponseTypes = append(res.ResponseTypes, "code")
+		}
+		if client.Confidential {
+			res.GrantTypes = append(res.GrantTypes, grantTypeClientCredentials)
+		}
+	}
+	if res.ResponseTypes == nil {
+		res.ResponseTypes = []string{}
+	}
+	return res
+}
+
+func containsScope(scopes []model.AccessScope, s model.AccessScope) bool {
+	for _, v := range scopes {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package oauth2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_RegistrationEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	t.Run("with initial access token", func(t *testing.T) {
+		t.Parallel()
+		iat, err := env.Repository.CreateInitialAccessToken(user.GetID(), 60)
+		require.NoError(t, err)
+		e := env.R(t)
+
+		obj := e.POST("/oauth2/register").
+			WithHeader("Authorization", "Bearer "+iat.Token).
+			WithJSON(map[string]interface{}{
+				"redirect_uris":              []string{"http://localhost:8080/callback"},
+				"token_endpoint_auth_method": "none",
+				"client_name":                "cli tool",
+				"scope":                      "read write",
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object()
+		obj.NotContainsKey("client_secret")
+		obj.NotContainsKey("approval_pending")
+		obj.Value("token_endpoint_auth_method").String().Equal("none")
+		obj.Value("redirect_uris").Array().ContainsOnly("http://localhost:8080/callback")
+		clientID := obj.Value("client_id").String().Raw()
+
+		client, err := env.Repository.GetClient(clientID)
+		require.NoError(t, err)
+		assert.Equal(t, user.GetID(), client.CreatorID)
+		assert.False(t, client.Confidential)
+		assert.True(t, client.RequirePKCE)
+		assert.False(t, client.Pending)
+
+		// 初期アクセストークンは1回のみ使用できる
+		e.POST("/oauth2/register").
+			WithHeader("Authorization", "Bearer "+iat.Token).
+			WithJSON(map[string]interface{}{
+				"redirect_uris":              []string{"http://localhost:8080/callback"},
+				"token_endpoint_auth_method": "none",
+				"client_name":                "cli tool",
+			}).
+			Expect().
+			Status(http.StatusUnauthorized).
+			JSON().
+			Object().
+			Value("error").
+			String().
+			Equal(errInvalidToken)
+	})
+
+	t.Run("without initial access token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+
+		obj := e.POST("/oauth2/register").
+			WithJSON(map[string]interface{}{
+				"redirect_uris": []string{"https://example.com/callback"},
+				"client_name":   "web app",
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object()
+		obj.Value("client_secret").String().NotEmpty()
+		obj.Value("client_secret_expires_at").Number().Equal(0)
+		obj.Value("approval_pending").Boolean().True()
+		obj.Value("scope").String().Equal("read")
+		clientID := obj.Value("client_id").String().Raw()
+		secret := obj.Value("client_secret").String().Raw()
+
+		// 承認待ちのクライアントは使用できない
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeClientCredentials).
+			WithBasicAuth(clientID, secret).
+			Expect().
+			Status(http.StatusUnauthorized)
+		e.GET("/oauth2/authorize").
+			WithQuery("client_id", clientID).
+			WithQuery("response_type", "code").
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+
+		for _, req := range []map[string]interface{}{
+			{"client_name": "no redirect"},
+			{"redirect_uris": []string{"https://example.com/a", "https://example.com/b"}, "client_name": "too many"},
+			{"redirect_uris": []string{"https://example.com/callback"}, "client_name": ""},
+			{"redirect_uris": []string{"https://example.com/callback"}, "client_name": "bot", "scope": "manage_bot"},
+			{"redirect_uris": []string{"https://example.com/callback"}, "client_name": "implicit", "response_types": []string{"token"}},
+			{"client_name": "public", "token_endpoint_auth_method": "none", "grant_types": []string{grantTypeClientCredentials}},
+		} {
+			e.POST("/oauth2/register").
+				WithJSON(req).
+				Expect().
+				Status(http.StatusBadRequest)
+		}
+	})
+}
+
+func TestHandlers_ClientConfigurationHandlers(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	e := env.R(t)
+
+	obj := e.POST("/oauth2/register").
+		WithJSON(map[string]interface{}{
+			"redirect_uris": []string{"https://example.com/callback"},
+			"client_name":   "web app",
+			"scope":         "read write",
+		}).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().
+		Object()
+	clientID := obj.Value("client_id").String().Raw()
+	token := obj.Value("registration_access_token").String().Raw()
+	obj.Value("registration_client_uri").String().Equal("http://example.com/oauth2/register/" + clientID)
+
+	e.GET("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer wrong").
+		Expect().
+		Status(http.StatusUnauthorized)
+
+	e.GET("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("client_name").
+		String().
+		Equal("web app")
+
+	// スコープを拡大することはできない
+	e.PUT("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{
+			"client_id":     clientID,
+			"redirect_uris": []string{"https://example.com/callback"},
+			"client_name":   "web app",
+			"scope":         "read write openid",
+		}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	e.PUT("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer "+token).
+		WithJSON(map[string]interface{}{
+			"client_id":     clientID,
+			"redirect_uris": []string{"https://example.com/new-callback"},
+			"client_name":   "renamed app",
+			"scope":         "read",
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	client, err := env.Repository.GetClient(clientID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed app", client.Name)
+	assert.Equal(t, "https://example.com/new-callback", client.RedirectURI)
+	assert.Equal(t, "read", client.Scopes.String())
+
+	e.DELETE("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusNoContent)
+	e.GET("/oauth2/register/{clientID}", clientID).
+		WithHeader("Authorization", "Bearer "+token).
+		Expect().
+		Status(http.StatusUnauthorized)
+}
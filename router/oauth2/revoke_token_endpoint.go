@@ -2,7 +2,9 @@ package oauth2
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
 	"net/http"
 )
 
@@ -19,12 +21,22 @@ func (h *Handler) RevokeTokenEndpointHandler(c echo.Context) error {
 		return c.NoContent(http.StatusOK)
 	}
 
-	if err := h.Repo.DeleteTokenByAccess(req.Token); err != nil {
+	// アクセストークン(JWTを含む)、リフレッシュトークンの順に探す
+	token, err := utils.GetOAuth2Token(h.Repo, req.Token)
+	if err == repository.ErrNotFound && !utils.IsJWT(req.Token) {
+		token, err = h.Repo.GetTokenByRefresh(req.Token)
+	}
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return c.NoContent(http.StatusOK)
+		}
 		return herror.InternalServerError(err)
 	}
-	if err := h.Repo.DeleteTokenByRefresh(req.Token); err != nil {
+
+	if err := h.Repo.DeleteTokenByID(token.ID); err != nil {
 		return herror.InternalServerError(err)
 	}
+	h.purgeIntrospectionCache(token.ID)
 
 	return c.NoContent(http.StatusOK)
 }
@@ -4,8 +4,10 @@ import (
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/utils"
 	"go.uber.org/zap"
 	"net/http"
 )
@@ -22,6 +24,7 @@ type tokenResponse struct {
 	ExpiresIn    int    `json:"expires_in,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
 }
 
 // TokenEndpointHandler トークンエンドポイントのハンドラ
@@ -38,6 +41,8 @@ func (h *Handler) TokenEndpointHandler(c echo.Context) error {
 		return h.tokenEndpointClientCredentialsHandler(c)
 	case grantTypeRefreshToken:
 		return h.tokenEndpointRefreshTokenHandler(c)
+	case grantTypeDeviceCode:
+		return h.tokenEndpointDeviceCodeHandler(c)
 	default:
 		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errUnsupportedGrantType})
 	}
@@ -123,9 +128,15 @@ func (h *Handler) tokenEndpointAuthorizationCodeHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
 
+	accessToken, err := h.accessToken(newToken)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
 	res := &tokenResponse{
 		TokenType:   authScheme,
-		AccessToken: newToken.AccessToken,
+		AccessToken: accessToken,
 		ExpiresIn:   newToken.ExpiresIn,
 	}
 	if len(code.OriginalScopes) != len(newToken.Scopes) {
@@ -134,6 +145,11 @@ func (h *Handler) tokenEndpointAuthorizationCodeHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	res.IDToken, err = h.issueIDToken(code.UserID, client.ID, newToken.Scopes, code.Nonce)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
 	return c.JSON(http.StatusOK, res)
 }
 
@@ -178,24 +194,48 @@ func (h *Handler) tokenEndpointPasswordHandler(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 		}
 	}
-	if client.Confidential && client.Secret != cpw {
+	if client.Pending || (client.Confidential && client.Secret != cpw) {
 		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
 	}
 
 	// ユーザー確認
+	attempt := &utils.LoginAttempt{Name: req.Username, IP: c.RealIP(), Method: utils.LoginMethodOAuth2Password}
 	user, err := h.Repo.GetUserByName(req.Username, false)
-	if err != nil {
-		switch err {
-		case repository.ErrNotFound:
-			return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidGrant})
-		default:
+	if err != nil && err != repository.ErrNotFound {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	attempt.User = user
+	if d, err := utils.CheckLoginLock(h.Repo, attempt); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	} else if d > 0 {
+		utils.SetRetryAfter(c, d)
+		return c.JSON(http.StatusTooManyRequests, oauth2ErrorResponse{ErrorType: errInvalidGrant, ErrorDescription: "too many failed login attempts"})
+	}
+	if user == nil || user.Authenticate(req.Password) != nil {
+		reason := model.LoginFailureReasonWrongPassword
+		if user == nil {
+			reason = model.LoginFailureReasonUnknownUser
+		}
+		if err := utils.RecordLoginFailure(h.Repo, attempt, reason); err != nil {
 			h.L(c).Error(err.Error(), zap.Error(err))
 			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 		}
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidGrant})
 	}
-	if user.Authenticate(req.Password) != nil {
+
+	// 二要素認証が必要なアカウントはパスワードグラントを利用できない
+	if state, err := utils.GetTwoFactorState(h.Repo, user); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	} else if state != utils.TwoFactorNone {
 		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidGrant})
 	}
+	if err := utils.RecordLoginSuccess(h.Repo, user.GetID()); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
 
 	// 要求スコープ確認
 	reqScopes, err := h.splitAndValidateScope(req.Scope)
@@ -216,9 +256,15 @@ func (h *Handler) tokenEndpointPasswordHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
 
+	accessToken, err := h.accessToken(newToken)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
 	res := &tokenResponse{
 		TokenType:   authScheme,
-		AccessToken: newToken.AccessToken,
+		AccessToken: accessToken,
 		ExpiresIn:   newToken.ExpiresIn,
 	}
 	if len(reqScopes) != len(validScopes) {
@@ -227,6 +273,11 @@ func (h *Handler) tokenEndpointPasswordHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	res.IDToken, err = h.issueIDToken(user.GetID(), client.ID, newToken.Scopes, "")
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
 	return c.JSON(http.StatusOK, res)
 }
 
@@ -263,7 +314,7 @@ func (h *Handler) tokenEndpointClientCredentialsHandler(c echo.Context) error {
 	if !client.Confidential {
 		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errUnauthorizedClient})
 	}
-	if client.Secret != pw {
+	if client.Pending || client.Secret != pw {
 		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
 	}
 
@@ -286,9 +337,15 @@ func (h *Handler) tokenEndpointClientCredentialsHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
 
+	accessToken, err := h.accessToken(newToken)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
 	res := &tokenResponse{
 		TokenType:   authScheme,
-		AccessToken: newToken.AccessToken,
+		AccessToken: accessToken,
 		ExpiresIn:   newToken.ExpiresIn,
 	}
 	if len(reqScopes) != len(validScopes) {
@@ -376,9 +433,15 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
 
+	accessToken, err := h.accessToken(newToken)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
 	res := &tokenResponse{
 		TokenType:   authScheme,
-		AccessToken: newToken.AccessToken,
+		AccessToken: accessToken,
 		ExpiresIn:   newToken.ExpiresIn,
 	}
 	if len(token.Scopes) != len(newToken.Scopes) {
@@ -387,5 +450,10 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	res.IDToken, err = h.issueIDToken(token.UserID, client.ID, newToken.Scopes, "")
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
 	return c.JSON(http.StatusOK, res)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/middlewares"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/v1"
 	"github.com/traPtitech/traQ/router/v3"
@@ -27,10 +28,11 @@ type Router struct {
 	v1        *v1.Handlers
 	v3        *v3.Handlers
 	oauth2    *oauth2.Handler
+	scim      *scim.Handler
 }
 
-func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Services, logger *zap.Logger, config *Config) *echo.Echo {
-	r := newRouter(hub, db, repo, ss, logger.Named("router"), config)
+func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Services, sessStore session.Store, logger *zap.Logger, config *Config) *echo.Echo {
+	r := newRouter(hub, db, repo, ss, sessStore, logger.Named("router"), config)
 
 	api := r.e.Group("/api")
 	api.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
@@ -40,6 +42,7 @@ func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Se
 	r.oauth2.Setup(api.Group("/oauth2"))
 	r.oauth2.Setup(api.Group("/1.0/oauth2"))
 	r.oauth2.Setup(api.Group("/v3/oauth2"))
+	r.scim.Setup(api.Group("/scim/v2"))
 
 	// 外部authハンドラ
 	extAuth := api.Group("/auth")
@@ -66,6 +69,19 @@ func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Se
 		extAuth.GET("/oidc", p.LoginHandler)
 		extAuth.GET("/oidc/callback", p.CallbackHandler)
 	}
+	if config.ExternalAuth.LDAP.Valid() {
+		p := auth.NewLDAPProvider(repo, logger.Named("ext_auth"), r.sessStore, config.ExternalAuth.LDAP)
+		extAuth.POST("/ldap", p.LoginHandler)
+	}
+	if config.ExternalAuth.SAML.Valid() {
+		p, err := auth.NewSAMLProvider(repo, logger.Named("ext_auth"), r.sessStore, config.ExternalAuth.SAML)
+		if err != nil {
+			panic(err)
+		}
+		extAuth.GET("/saml", p.LoginHandler)
+		extAuth.POST("/saml/acs", p.AssertionConsumerServiceHandler)
+		extAuth.GET("/saml/metadata", p.MetadataHandler)
+	}
 
 	return r.e
 }
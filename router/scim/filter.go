@@ -0,0 +1,397 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidFilter 不正なフィルター式です
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter SCIMのフィルター式 (RFC 7644 3.4.2.2)
+type Filter interface {
+	// Match 指定したリソース(JSONオブジェクト)がフィルターに一致するかどうか
+	Match(resource map[string]interface{}) bool
+}
+
+type andFilter struct{ left, right Filter }
+
+func (f *andFilter) Match(r map[string]interface{}) bool { return f.left.Match(r) && f.right.Match(r) }
+
+type orFilter struct{ left, right Filter }
+
+func (f *orFilter) Match(r map[string]interface{}) bool { return f.left.Match(r) || f.right.Match(r) }
+
+type notFilter struct{ f Filter }
+
+func (f *notFilter) Match(r map[string]interface{}) bool { return !f.f.Match(r) }
+
+// compareFilter attrPath op value
+type compareFilter struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (f *compareFilter) Match(r map[string]interface{}) bool {
+	for _, v := range lookupAttr(r, f.path) {
+		if compareValue(v, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePathFilter attrPath[filter]
+type valuePathFilter struct {
+	path   []string
+	filter Filter
+}
+
+func (f *valuePathFilter) Match(r map[string]interface{}) bool {
+	for _, v := range lookupAttr(r, f.path) {
+		if m, ok := v.(map[string]interface{}); ok && f.filter.Match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFilter フィルター式をパースします
+func ParseFilter(s string) (Filter, error) {
+	p := &filterParser{tokens: tokenizeFilter(s)}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected token %q", ErrInvalidFilter, p.tokens[p.pos])
+	}
+	return f, nil
+}
+
+// ParsePath PATCH操作のパス(attrPath[valFilter].subAttr)をパースします
+//
+// 属性名(小文字)、値フィルター(ない場合はnil)、サブ属性名(小文字、ない場合は空文字)を返します。
+func ParsePath(s string) (attr string, filter Filter, subAttr string, err error) {
+	s = stripSchemaURN(s)
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		parts := strings.SplitN(strings.ToLower(s), ".", 2)
+		if len(parts) == 2 {
+			return parts[0], nil, parts[1], nil
+		}
+		return parts[0], nil, "", nil
+	}
+	j := strings.LastIndexByte(s, ']')
+	if j < i {
+		return "", nil, "", fmt.Errorf("%w: unclosed bracket", ErrInvalidFilter)
+	}
+	filter, err = ParseFilter(s[i+1 : j])
+	if err != nil {
+		return "", nil, "", err
+	}
+	rest := s[j+1:]
+	if len(rest) > 0 {
+		if rest[0] != '.' {
+			return "", nil, "", fmt.Errorf("%w: invalid path %q", ErrInvalidFilter, s)
+		}
+		subAttr = strings.ToLower(rest[1:])
+	}
+	return strings.ToLower(s[:i]), filter, subAttr, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) expect(t string) error {
+	if p.next() != t {
+		return fmt.Errorf("%w: %q expected", ErrInvalidFilter, t)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (Filter, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("%w: unexpected end of filter", ErrInvalidFilter)
+	case t == "(":
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case strings.EqualFold(t, "not") && p.peek() == "(":
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{f: f}, p.expect(")")
+	case !isAttrPath(t):
+		return nil, fmt.Errorf("%w: attribute expected but got %q", ErrInvalidFilter, t)
+	}
+
+	path := splitAttrPath(t)
+	if p.peek() == "[" {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valuePathFilter{path: path, filter: f}, nil
+	}
+
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		v, err := parseFilterValue(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return &compareFilter{path: path, op: op, value: v}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, op)
+	}
+}
+
+func tokenizeFilter(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				// 閉じられていない文字列はそのままトークンにしてパース時にエラーにする
+				tokens = append(tokens, s[i:])
+				return tokens
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+func isAttrPath(t string) bool {
+	if len(t) == 0 {
+		return false
+	}
+	for _, r := range t {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:$", r)) {
+			return false
+		}
+	}
+	return unicode.IsLetter(rune(t[0])) || t[0] == '$' || strings.HasPrefix(t, "urn:")
+}
+
+func splitAttrPath(t string) []string {
+	return strings.Split(strings.ToLower(stripSchemaURN(t)), ".")
+}
+
+// stripSchemaURN 属性名に付いているスキーマURNを取り除きます
+func stripSchemaURN(t string) string {
+	for _, schema := range []string{UserSchema, GroupSchema} {
+		if len(t) > len(schema) && strings.EqualFold(t[:len(schema)+1], schema+":") {
+			return t[len(schema)+1:]
+		}
+	}
+	return t
+}
+
+func parseFilterValue(t string) (interface{}, error) {
+	switch strings.ToLower(t) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(t), &v); err != nil {
+		return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, t)
+	}
+	switch v.(type) {
+	case string, float64:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, t)
+	}
+}
+
+// lookupAttr 属性パスに対応する値を全て返します
+//
+// 複数値属性の場合は、各要素を展開して返します。属性名は大文字小文字を区別しません。
+func lookupAttr(r map[string]interface{}, path []string) []interface{} {
+	var v interface{}
+	found := false
+	for k, val := range r {
+		if strings.EqualFold(k, path[0]) {
+			v, found = val, true
+			break
+		}
+	}
+	if !found || v == nil {
+		return nil
+	}
+
+	var values []interface{}
+	if arr, ok := v.([]interface{}); ok {
+		values = arr
+	} else {
+		values = []interface{}{v}
+	}
+	if len(path) == 1 {
+		return values
+	}
+
+	var result []interface{}
+	for _, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			result = append(result, lookupAttr(m, path[1:])...)
+		}
+	}
+	return result
+}
+
+func compareValue(actual interface{}, op string, expected interface{}) bool {
+	if op == "pr" {
+		if s, ok := actual.(string); ok {
+			return len(s) > 0
+		}
+		return actual != nil
+	}
+
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return op == "ne"
+		}
+		a, e = strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case "eq":
+			return a == e
+		case "ne":
+			return a != e
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case bool:
+		e, ok := expected.(bool)
+		switch op {
+		case "eq":
+			return ok && a == e
+		case "ne":
+			return !ok || a != e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return op == "ne"
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "ne":
+			return a != e
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	}
+	return false
+}
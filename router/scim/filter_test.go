@@ -0,0 +1,109 @@
+package scim
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+
+	resource := map[string]interface{}{
+		"id":          "2819c223-7f76-453a-919d-413861904646",
+		"userName":    "Bjensen",
+		"displayName": "Barbara Jensen",
+		"active":      true,
+		"meta": map[string]interface{}{
+			"lastModified": "2011-05-13T04:42:34Z",
+		},
+		"groups": []interface{}{
+			map[string]interface{}{"value": "g1", "display": "admins"},
+			map[string]interface{}{"value": "g2", "display": "users"},
+		},
+	}
+
+	cases := []struct {
+		filter   string
+		expected bool
+	}{
+		{`userName eq "bjensen"`, true},
+		{`USERNAME eq "Bjensen"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`, true},
+		{`userName ne "bjensen"`, false},
+		{`userName co "jen"`, true},
+		{`userName sw "B"`, true},
+		{`userName ew "sen"`, true},
+		{`displayName pr`, true},
+		{`title pr`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`meta.lastModified gt "2011-05-13T04:42:34Z"`, false},
+		{`meta.lastModified ge "2011-05-13T04:42:34Z"`, true},
+		{`groups.value eq "g2"`, true},
+		{`groups[value eq "g1" and display eq "admins"]`, true},
+		{`groups[value eq "g1" and display eq "users"]`, false},
+		{`userName eq "x" or active eq true`, true},
+		{`userName eq "x" or userName eq "y" and active eq true`, false},
+		{`(userName eq "x" or userName eq "bjensen") and active eq true`, true},
+		{`not (userName eq "bjensen")`, false},
+		{`userName eq "with \"quote\""`, false},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.filter)
+		if assert.NoError(t, err, c.filter) {
+			assert.Equal(t, c.expected, f.Match(resource), c.filter)
+		}
+	}
+
+	for _, invalid := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName foo "x"`,
+		`userName eq "x" and`,
+		`(userName eq "x"`,
+		`userName eq "unclosed`,
+		`userName eq x`,
+		`groups[value eq "g1"`,
+	} {
+		_, err := ParseFilter(invalid)
+		assert.True(t, errors.Is(err, ErrInvalidFilter), invalid)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	t.Parallel()
+
+	attr, filter, sub, err := ParsePath("displayName")
+	require.NoError(t, err)
+	assert.Equal(t, "displayname", attr)
+	assert.Nil(t, filter)
+	assert.Empty(t, sub)
+
+	attr, filter, sub, err = ParsePath("name.givenName")
+	require.NoError(t, err)
+	assert.Equal(t, "name", attr)
+	assert.Nil(t, filter)
+	assert.Equal(t, "givenname", sub)
+
+	attr, filter, sub, err = ParsePath(`members[value eq "abc"]`)
+	require.NoError(t, err)
+	assert.Equal(t, "members", attr)
+	if assert.NotNil(t, filter) {
+		assert.True(t, filter.Match(map[string]interface{}{"value": "abc"}))
+		assert.False(t, filter.Match(map[string]interface{}{"value": "def"}))
+	}
+	assert.Empty(t, sub)
+
+	attr, _, sub, err = ParsePath(`emails[type eq "work"].value`)
+	require.NoError(t, err)
+	assert.Equal(t, "emails", attr)
+	assert.Equal(t, "value", sub)
+
+	_, _, _, err = ParsePath(`members[value eq "abc"`)
+	assert.Error(t, err)
+	_, _, _, err = ParsePath(`members[value eq "abc"]x`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,421 @@
+package scim
+
+import (
+	"encoding/json"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// Group SCIMのGroupリソース
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	DisplayName string      `json:"displayName"`
+	Members     []MemberRef `json:"members"`
+	Meta        Meta        `json:"meta"`
+}
+
+// groupRequest POST, PUT /Groups リクエストボディ
+type groupRequest struct {
+	DisplayName string      `json:"displayName"`
+	Members     []MemberRef `json:"members"`
+}
+
+func (r *groupRequest) validate() error {
+	if err := vd.Validate(r.DisplayName, vd.Required, vd.RuneLength(1, 30)); err != nil {
+		return newError(http.StatusBadRequest, errInvalidValue, "displayName: "+err.Error())
+	}
+	return nil
+}
+
+func formatGroup(c echo.Context, g *model.UserGroup, names map[uuid.UUID]string) *Group {
+	base := baseURL(c)
+	members := make([]MemberRef, len(g.Members))
+	for i, m := range g.Members {
+		members[i] = MemberRef{
+			Value:   m.UserID.String(),
+			Display: names[m.UserID],
+			Type:    "User",
+			Ref:     base + "/Users/" + m.UserID.String(),
+		}
+	}
+	return &Group{
+		Schemas:     []string{GroupSchema},
+		ID:          g.ID.String(),
+		DisplayName: g.Name,
+		Members:     members,
+		Meta: Meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: g.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     base + "/Groups/" + g.ID.String(),
+		},
+	}
+}
+
+// getUserNames 全ユーザーのIDとユーザー名の対応を取得します
+func (h *Handler) getUserNames() (map[uuid.UUID]string, error) {
+	users, err := h.Repo.GetUsers(repository.UsersQuery{})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]string, len(users))
+	for _, u := range users {
+		result[u.GetID()] = u.GetName()
+	}
+	return result, nil
+}
+
+// respondGroup グループリソースを返します
+func (h *Handler) respondGroup(c echo.Context, code int, groupID uuid.UUID) error {
+	g, err := h.Repo.GetUserGroup(groupID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	names, err := h.getUserNames()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	res := formatGroup(c, g, names)
+	if code == http.StatusCreated {
+		c.Response().Header().Set(echo.HeaderLocation, res.Meta.Location)
+	}
+	return respond(c, code, res)
+}
+
+// getParamGroup パスパラメータのグループを取得します
+func (h *Handler) getParamGroup(c echo.Context) (*model.UserGroup, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, notFound("group")
+	}
+	g, err := h.Repo.GetUserGroup(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, notFound("group")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	return g, nil
+}
+
+// parseMembers メンバー参照をユーザーIDの集合に変換します
+//
+// 存在しないユーザーやBotが含まれている場合はエラーを返します。
+func (h *Handler) parseMembers(refs []MemberRef) (set.UUID, error) {
+	result := set.UUID{}
+	for _, ref := range refs {
+		id, err := uuid.FromString(ref.Value)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid member: "+ref.Value)
+		}
+		user, err := h.Repo.GetUser(id, false)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid member: "+ref.Value)
+			default:
+				return nil, herror.InternalServerError(err)
+			}
+		}
+		if user.IsBot() {
+			return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid member: "+ref.Value)
+		}
+		result.Add(id)
+	}
+	return result, nil
+}
+
+func parseMemberRefs(raw json.RawMessage) ([]MemberRef, error) {
+	var refs []MemberRef
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		// 単一のオブジェクトで送ってくるIdPもある
+		var ref MemberRef
+		if err := json.Unmarshal(raw, &ref); err != nil {
+			return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid members")
+		}
+		refs = []MemberRef{ref}
+	}
+	return refs, nil
+}
+
+// updateGroup グループ名とメンバーを更新します
+func (h *Handler) updateGroup(c echo.Context, g *model.UserGroup, name optional.String, members set.UUID) error {
+	if name.Valid && name.String != g.Name {
+		if err := h.Repo.UpdateUserGroup(g.ID, repository.UpdateUserGroupNameArgs{Name: name}); err != nil {
+			switch {
+			case err == repository.ErrAlreadyExists:
+				return newError(http.StatusConflict, errUniqueness, "displayName conflicts")
+			case repository.IsArgError(err):
+				return newError(http.StatusBadRequest, errInvalidValue, err.Error())
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+	if members == nil {
+		return nil
+	}
+
+	current := set.UUID{}
+	for _, m := range g.Members {
+		current.Add(m.UserID)
+	}
+	for id := range members {
+		if !current.Contains(id) {
+			if err := h.Repo.AddUserToGroup(id, g.ID, ""); err != nil {
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+	for id := range current {
+		if !members.Contains(id) {
+			if err := h.Repo.RemoveUserFromGroup(id, g.ID); err != nil && err != repository.ErrNotFound {
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+	h.L(c).Info("group members synced by SCIM", zap.Stringer("groupId", g.ID), zap.Int("members", len(members)))
+	return nil
+}
+
+// GetGroups GET /Groups
+func (h *Handler) GetGroups(c echo.Context) error {
+	q, err := parseListQuery(c)
+	if err != nil {
+		return err
+	}
+
+	groups, err := h.Repo.GetAllUserGroups()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	names, err := h.getUserNames()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	resources := make([]map[string]interface{}, 0, len(groups))
+	for _, g := range groups {
+		m, err := toMap(formatGroup(c, g, names))
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		resources = append(resources, m)
+	}
+	return respond(c, http.StatusOK, q.apply(resources))
+}
+
+// GetGroup GET /Groups/:id
+func (h *Handler) GetGroup(c echo.Context) error {
+	g, err := h.getParamGroup(c)
+	if err != nil {
+		return err
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// CreateGroup POST /Groups
+//
+// 作成したグループの管理者は、リクエストしたユーザー(IdPのサービスアカウント)になります。
+func (h *Handler) CreateGroup(c echo.Context) error {
+	var req groupRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	members, err := h.parseMembers(req.Members)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.Repo.CreateUserGroup(req.DisplayName, "", "", c.Get(consts.KeyUser).(model.UserInfo).GetID())
+	if err != nil {
+		switch {
+		case err == repository.ErrAlreadyExists:
+			return newError(http.StatusConflict, errUniqueness, "displayName conflicts")
+		case repository.IsArgError(err):
+			return newError(http.StatusBadRequest, errInvalidValue, err.Error())
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("group provisioned by SCIM", zap.Stringer("groupId", g.ID), zap.String("name", g.Name))
+
+	if err := h.updateGroup(c, g, optional.String{}, members); err != nil {
+		return err
+	}
+	return h.respondGroup(c, http.StatusCreated, g.ID)
+}
+
+// ReplaceGroup PUT /Groups/:id
+func (h *Handler) ReplaceGroup(c echo.Context) error {
+	g, err := h.getParamGroup(c)
+	if err != nil {
+		return err
+	}
+
+	var req groupRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	members, err := h.parseMembers(req.Members)
+	if err != nil {
+		return err
+	}
+
+	if err := h.updateGroup(c, g, optional.StringFrom(req.DisplayName), members); err != nil {
+		return err
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// groupPatch PATCH /Groups/:id の操作を適用した結果
+type groupPatch struct {
+	h       *Handler
+	name    optional.String
+	members set.UUID
+}
+
+// applyMembers members属性への操作を適用します
+func (p *groupPatch) applyMembers(op string, filter Filter, value json.RawMessage) error {
+	switch op {
+	case patchOpAdd, patchOpReplace:
+		if filter != nil {
+			return newError(http.StatusBadRequest, errInvalidPath, "value filters are not supported for "+op)
+		}
+		refs, err := parseMemberRefs(value)
+		if err != nil {
+			return err
+		}
+		ids, err := p.h.parseMembers(refs)
+		if err != nil {
+			return err
+		}
+		if op == patchOpReplace {
+			p.members = set.UUID{}
+		}
+		p.members.Add(ids.Array()...)
+	case patchOpRemove:
+		switch {
+		case filter != nil:
+			for id := range p.members {
+				if filter.Match(map[string]interface{}{"value": id.String(), "type": "User"}) {
+					p.members.Remove(id)
+				}
+			}
+		case len(value) > 0:
+			refs, err := parseMemberRefs(value)
+			if err != nil {
+				return err
+			}
+			for _, ref := range refs {
+				p.members.Remove(uuid.FromStringOrNil(ref.Value))
+			}
+		default:
+			p.members = set.UUID{}
+		}
+	}
+	return nil
+}
+
+// apply 1つの属性への操作を適用します
+func (p *groupPatch) apply(op, attr string, filter Filter, value json.RawMessage) error {
+	switch attr {
+	case "displayname":
+		s, ok := parseString(value)
+		if op == patchOpRemove || !ok || vd.Validate(s, vd.Required, vd.RuneLength(1, 30)) != nil {
+			return newError(http.StatusBadRequest, errInvalidValue, "invalid value for displayName")
+		}
+		p.name = optional.StringFrom(s)
+	case "members":
+		return p.applyMembers(op, filter, value)
+	default:
+		return newError(http.StatusBadRequest, errInvalidPath, "unsupported attribute: "+attr)
+	}
+	return nil
+}
+
+// PatchGroup PATCH /Groups/:id
+func (h *Handler) PatchGroup(c echo.Context) error {
+	g, err := h.getParamGroup(c)
+	if err != nil {
+		return err
+	}
+
+	var req PatchRequest
+	if err := bindPatch(c, &req); err != nil {
+		return err
+	}
+
+	p := &groupPatch{h: h, members: set.UUID{}}
+	for _, m := range g.Members {
+		p.members.Add(m.UserID)
+	}
+	for _, op := range req.Operations {
+		if len(op.Path) == 0 {
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return newError(http.StatusBadRequest, errInvalidValue, "value must be an object when path is omitted")
+			}
+			for k, v := range values {
+				attr, _, _, err := ParsePath(k)
+				if err != nil {
+					return newError(http.StatusBadRequest, errInvalidPath, err.Error())
+				}
+				if err := p.apply(op.Op, attr, nil, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		attr, filter, _, err := ParsePath(op.Path)
+		if err != nil {
+			return newError(http.StatusBadRequest, errInvalidPath, err.Error())
+		}
+		if err := p.apply(op.Op, attr, filter, op.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := h.updateGroup(c, g, p.name, p.members); err != nil {
+		return err
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// DeleteGroup DELETE /Groups/:id
+func (h *Handler) DeleteGroup(c echo.Context) error {
+	g, err := h.getParamGroup(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Repo.DeleteUserGroup(g.ID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return notFound("group")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("group deleted by SCIM", zap.Stringer("groupId", g.ID))
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,83 @@
+package scim
+
+import (
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strings"
+)
+
+// PatchRequest PATCHリクエストボディ (RFC 7644 3.5.2)
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation PATCH操作
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// 操作の種類
+const (
+	patchOpAdd     = "add"
+	patchOpRemove  = "remove"
+	patchOpReplace = "replace"
+)
+
+// bindPatch PATCHリクエストボディをデコードし、検証します
+func bindPatch(c echo.Context, req *PatchRequest) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(req); err != nil {
+		return newError(http.StatusBadRequest, errInvalidSyntax, err.Error())
+	}
+	if len(req.Operations) == 0 {
+		return newError(http.StatusBadRequest, errInvalidValue, "no operations")
+	}
+	for i, op := range req.Operations {
+		// Azure ADなどは大文字始まりで送ってくる
+		req.Operations[i].Op = strings.ToLower(op.Op)
+		switch req.Operations[i].Op {
+		case patchOpAdd, patchOpReplace:
+			if len(op.Value) == 0 {
+				return newError(http.StatusBadRequest, errInvalidValue, "value is required for "+req.Operations[i].Op)
+			}
+		case patchOpRemove:
+			if len(op.Path) == 0 {
+				return newError(http.StatusBadRequest, errNoTarget, "path is required for remove")
+			}
+		default:
+			return newError(http.StatusBadRequest, errInvalidSyntax, "unknown op: "+op.Op)
+		}
+	}
+	return nil
+}
+
+// parseBool 真偽値をパースします
+//
+// 一部のIdPは"True", "False"のような文字列で送ってくるため、文字列も受け付けます。
+func parseBool(raw json.RawMessage) (bool, bool) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func parseString(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
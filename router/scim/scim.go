@@ -0,0 +1,362 @@
+// Package scim SCIM 2.0 (RFC 7643, RFC 7644) によるユーザー・グループのプロビジョニングAPIを提供します
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/middlewares"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// スキーマURN
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// エラーのscimType (RFC 7644 3.12)
+const (
+	errInvalidFilter = "invalidFilter"
+	errUniqueness    = "uniqueness"
+	errMutability    = "mutability"
+	errInvalidSyntax = "invalidSyntax"
+	errInvalidPath   = "invalidPath"
+	errNoTarget      = "noTarget"
+	errInvalidValue  = "invalidValue"
+)
+
+const (
+	contentType = "application/scim+json; charset=UTF-8"
+
+	defaultCount = 100
+	maxCount     = 1000
+)
+
+// Handler SCIMハンドラ
+//
+// IdPからは、permission.ProvisionUsersを持つユーザーのOAuth2アクセストークンをBearerトークンとして使用します。
+type Handler struct {
+	RBAC      rbac.RBAC
+	Repo      repository.Repository
+	Logger    *zap.Logger
+	SessStore session.Store
+}
+
+// Setup SCIMのルーティングを行います
+func (h *Handler) Setup(e *echo.Group) {
+	requires := middlewares.AccessControlMiddlewareGenerator(h.RBAC)
+
+	g := e.Group("", errorHandler, middlewares.UserAuthenticate(h.Repo, h.SessStore), requires(permission.ProvisionUsers), middlewares.BlockBot(h.Repo))
+	g.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+	g.GET("/ResourceTypes", h.GetResourceTypes)
+
+	g.GET("/Users", h.GetUsers)
+	g.POST("/Users", h.CreateUser)
+	g.GET("/Users/:id", h.GetUser)
+	g.PUT("/Users/:id", h.ReplaceUser)
+	g.PATCH("/Users/:id", h.PatchUser)
+	g.DELETE("/Users/:id", h.DeleteUser)
+
+	g.GET("/Groups", h.GetGroups)
+	g.POST("/Groups", h.CreateGroup)
+	g.GET("/Groups/:id", h.GetGroup)
+	g.PUT("/Groups/:id", h.ReplaceGroup)
+	g.PATCH("/Groups/:id", h.PatchGroup)
+	g.DELETE("/Groups/:id", h.DeleteGroup)
+}
+
+// Error SCIMエラーレスポンス
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func newError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func notFound(resource string) *Error {
+	return newError(http.StatusNotFound, "", resource+" not found")
+}
+
+// errorHandler ハンドラ・ミドルウェアのエラーをSCIMのエラーレスポンスに変換します
+//
+// 内部エラーはそのまま返し、ログ出力を共通のエラーハンドラに任せます。
+func errorHandler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		switch e := err.(type) {
+		case *Error:
+			code, _ := strconv.Atoi(e.Status)
+			return respond(c, code, e)
+		case *echo.HTTPError:
+			return respond(c, e.Code, newError(e.Code, "", fmt.Sprint(e.Message)))
+		default:
+			return err
+		}
+	}
+}
+
+func respond(c echo.Context, code int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(code, contentType, b)
+}
+
+// bind リクエストボディをデコードします
+//
+// SCIMのContent-Typeはapplication/scim+jsonのため、echoのBindは使用しません。
+func bind(c echo.Context, v interface{}) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return newError(http.StatusBadRequest, errInvalidSyntax, err.Error())
+	}
+	return nil
+}
+
+// baseURL SCIMエンドポイントのベースURLを返します
+func baseURL(c echo.Context) string {
+	path := c.Path()
+	for _, endpoint := range []string{"/Users", "/Groups", "/ServiceProviderConfig", "/ResourceTypes"} {
+		if i := strings.LastIndex(path, endpoint); i >= 0 {
+			path = path[:i]
+			break
+		}
+	}
+	return c.Scheme() + "://" + c.Request().Host + path
+}
+
+// ListResponse SCIMのリストレスポンス
+type ListResponse struct {
+	Schemas      []string                 `json:"schemas"`
+	TotalResults int                      `json:"totalResults"`
+	StartIndex   int                      `json:"startIndex"`
+	ItemsPerPage int                      `json:"itemsPerPage"`
+	Resources    []map[string]interface{} `json:"Resources"`
+}
+
+// listQuery リスト取得のクエリパラメータ
+type listQuery struct {
+	filter     Filter
+	startIndex int
+	count      int
+	attributes []string
+	excluded   []string
+}
+
+func parseListQuery(c echo.Context) (*listQuery, error) {
+	q := &listQuery{startIndex: 1, count: defaultCount}
+	if s := c.QueryParam("filter"); len(s) > 0 {
+		f, err := ParseFilter(s)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, errInvalidFilter, err.Error())
+		}
+		q.filter = f
+	}
+	if s := c.QueryParam("startIndex"); len(s) > 0 {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid startIndex")
+		}
+		if i > 1 {
+			q.startIndex = i
+		}
+	}
+	if s := c.QueryParam("count"); len(s) > 0 {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, errInvalidValue, "invalid count")
+		}
+		switch {
+		case i < 0:
+			q.count = 0
+		case i > maxCount:
+			q.count = maxCount
+		default:
+			q.count = i
+		}
+	}
+	q.attributes = splitAttributes(c.QueryParam("attributes"))
+	q.excluded = splitAttributes(c.QueryParam("excludedAttributes"))
+	return q, nil
+}
+
+func splitAttributes(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	var result []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.ToLower(stripSchemaURN(strings.TrimSpace(a))); len(a) > 0 {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// apply フィルター・ページング・属性の絞り込みを適用したリストレスポンスを返します
+func (q *listQuery) apply(resources []map[string]interface{}) *ListResponse {
+	matched := make([]map[string]interface{}, 0, len(resources))
+	for _, r := range resources {
+		if q.filter == nil || q.filter.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+
+	page := make([]map[string]interface{}, 0)
+	if start := q.startIndex - 1; start < len(matched) {
+		end := start + q.count
+		if end > len(matched) {
+			end = len(matched)
+		}
+		for _, r := range matched[start:end] {
+			page = append(page, q.project(r))
+		}
+	}
+	return &ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: len(matched),
+		StartIndex:   q.startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}
+}
+
+// project attributes, excludedAttributesに従って返す属性を絞り込みます
+//
+// schemas, idは常に返します。
+func (q *listQuery) project(r map[string]interface{}) map[string]interface{} {
+	if len(q.attributes) == 0 && len(q.excluded) == 0 {
+		return r
+	}
+	result := make(map[string]interface{}, len(r))
+	for k, v := range r {
+		lk := strings.ToLower(k)
+		if lk == "schemas" || lk == "id" {
+			result[k] = v
+			continue
+		}
+		if len(q.attributes) > 0 && !containsAttr(q.attributes, lk) {
+			continue
+		}
+		if containsAttr(q.excluded, lk) {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func containsAttr(attrs []string, attr string) bool {
+	for _, a := range attrs {
+		if a == attr || strings.HasPrefix(a, attr+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// toMap リソース構造体をJSONオブジェクトに変換します
+func toMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	return m, json.Unmarshal(b, &m)
+}
+
+// Meta リソースのメタデータ
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+// GetServiceProviderConfig GET /ServiceProviderConfig
+func (h *Handler) GetServiceProviderConfig(c echo.Context) error {
+	supported := func(b bool) map[string]interface{} { return map[string]interface{}{"supported": b} }
+	return respond(c, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{ServiceProviderConfigSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxCount},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "OAuth Bearer Token",
+				"description": "Authentication scheme using the OAuth Bearer Token Standard",
+				"specUri":     "https://www.rfc-editor.org/info/rfc6750",
+				"primary":     true,
+			},
+		},
+		"meta": map[string]interface{}{
+			"resourceType": "ServiceProviderConfig",
+			"location":     baseURL(c) + "/ServiceProviderConfig",
+		},
+	})
+}
+
+// GetResourceTypes GET /ResourceTypes
+func (h *Handler) GetResourceTypes(c echo.Context) error {
+	base := baseURL(c)
+	resourceType := func(name, endpoint, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":  []string{ResourceTypeSchema},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta": map[string]interface{}{
+				"resourceType": "ResourceType",
+				"location":     base + "/ResourceTypes/" + name,
+			},
+		}
+	}
+	resources := []map[string]interface{}{
+		resourceType("User", "/Users", UserSchema),
+		resourceType("Group", "/Groups", GroupSchema),
+	}
+	return respond(c, http.StatusOK, &ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// L ロガーを返します
+func (h *Handler) L(c echo.Context) *zap.Logger {
+	return h.Logger.With(zap.String("requestId", extension.GetRequestID(c)))
+}
@@ -0,0 +1,398 @@
+package scim
+
+import (
+	"fmt"
+	"github.com/gavv/httpexpect/v2"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/migration"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/storage"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+const (
+	dbPrefix = "traq-test-router-scim-"
+	common   = "common"
+	rand     = "random"
+)
+
+var envs = map[string]*Env{}
+
+func TestMain(m *testing.M) {
+	user := getEnvOrDefault("MARIADB_USERNAME", "root")
+	pass := getEnvOrDefault("MARIADB_PASSWORD", "password")
+	host := getEnvOrDefault("MARIADB_HOSTNAME", "127.0.0.1")
+	port := getEnvOrDefault("MARIADB_PORT", "3306")
+	dbs := []string{
+		common,
+	}
+	if err := migration.CreateDatabasesIfNotExists("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/?charset=utf8mb4&parseTime=true", user, pass, host, port), dbPrefix, dbs...); err != nil {
+		panic(err)
+	}
+
+	for _, key := range dbs {
+		env := &Env{}
+
+		// テスト用データベース接続
+		db, err := gorm.Open("mysql", fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=true", user, pass, host, port, fmt.Sprintf("%s%s", dbPrefix, key)))
+		if err != nil {
+			panic(err)
+		}
+		db.DB().SetMaxOpenConns(20)
+		if err := migration.DropAll(db); err != nil {
+			panic(err)
+		}
+
+		env.DB = db
+		env.Hub = hub.New()
+		env.SessStore = session.NewMemorySessionStore()
+
+		// テスト用リポジトリ作成
+		repo, err := repository.NewGormRepository(db, storage.NewInMemoryFileStorage(), env.Hub, zap.NewNop())
+		if err != nil {
+			panic(err)
+		}
+		if _, err := repo.Sync(); err != nil {
+			panic(err)
+		}
+		env.Repository = repo
+
+		// テスト用サーバー作成
+		e := echo.New()
+		e.HideBanner = true
+		e.HidePort = true
+		e.HTTPErrorHandler = extension.ErrorHandler(zap.NewNop())
+		e.Use(extension.Wrap(repo, nil))
+
+		h := &Handler{
+			RBAC:      testutils.NewTestRBAC(),
+			Repo:      env.Repository,
+			Logger:    zap.NewNop(),
+			SessStore: env.SessStore,
+		}
+		h.Setup(e.Group("/scim/v2"))
+		env.Server = httptest.NewServer(e)
+
+		envs[key] = env
+	}
+
+	// テスト実行
+	code := m.Run()
+
+	// 後始末
+	for _, env := range envs {
+		env.Server.Close()
+		env.DB.Close()
+		env.Hub.Close()
+	}
+	os.Exit(code)
+}
+
+type Env struct {
+	Server     *httptest.Server
+	DB         *gorm.DB
+	Repository repository.Repository
+	Hub        *hub.Hub
+	SessStore  session.Store
+}
+
+// Setup テストセットアップ
+func Setup(t *testing.T, server string) *Env {
+	t.Helper()
+	env, ok := envs[server]
+	if !ok {
+		t.FailNow()
+	}
+	return env
+}
+
+// S 指定ユーザーのAPIセッショントークンを発行
+func (env *Env) S(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	s, err := env.SessStore.IssueSession(userID, nil)
+	require.NoError(t, err)
+	return s.Token()
+}
+
+// R リクエストテスターを作成
+func (env *Env) R(t *testing.T) *httpexpect.Expect {
+	t.Helper()
+	return httpexpect.WithConfig(httpexpect.Config{
+		BaseURL:  env.Server.URL,
+		Reporter: httpexpect.NewAssertReporter(t),
+		Printers: []httpexpect.Printer{
+			httpexpect.NewCurlPrinter(t),
+			httpexpect.NewDebugPrinter(t, true),
+		},
+		Client: &http.Client{
+			Jar:     nil, // クッキーは保持しない
+			Timeout: time.Second * 30,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse // リダイレクトを自動処理しない
+			},
+		},
+	})
+}
+
+// CreateUser ユーザーを必ず作成します
+func (env *Env) CreateUser(t *testing.T, userName string, r string) model.UserInfo {
+	t.Helper()
+	if userName == rand {
+		userName = random.AlphaNumeric(32)
+	}
+	u, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: userName, Password: "testtesttesttest", Role: r})
+	require.NoError(t, err)
+	return u
+}
+
+func getEnvOrDefault(env string, def string) string {
+	s := os.Getenv(env)
+	if len(s) == 0 {
+		return def
+	}
+	return s
+}
+
+func TestHandler_GetServiceProviderConfig(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	admin := env.CreateUser(t, rand, role.Admin)
+	user := env.CreateUser(t, rand, role.User)
+
+	t.Run("not logged in", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/ServiceProviderConfig").
+			Expect().
+			Status(http.StatusUnauthorized).
+			ContentType("application/scim+json").
+			JSON().Object().
+			Value("status").Equal("401")
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/ServiceProviderConfig").
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusForbidden).
+			JSON().Object().
+			Value("schemas").Array().Contains(ErrorSchema)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/scim/v2/ServiceProviderConfig").
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		obj.Value("patch").Object().Value("supported").Boolean().True()
+		obj.Value("bulk").Object().Value("supported").Boolean().False()
+	})
+}
+
+func TestHandler_Users(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	admin := env.CreateUser(t, rand, role.Admin)
+	s := env.S(t, admin.GetID())
+
+	name := random.AlphaNumeric(20)
+	e := env.R(t)
+	obj := e.POST("/scim/v2/Users").
+		WithCookie(session.CookieName, s).
+		WithHeader(echo.HeaderContentType, "application/scim+json").
+		WithJSON(map[string]interface{}{
+			"schemas":  []string{UserSchema},
+			"userName": name,
+			"name":     map[string]interface{}{"givenName": "Taro", "familyName": "Yamada"},
+			"active":   true,
+		}).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object()
+	obj.Value("userName").String().Equal(name)
+	obj.Value("active").Boolean().True()
+	id := obj.Value("id").String().Raw()
+
+	t.Run("conflict", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/scim/v2/Users").
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"schemas": []string{UserSchema}, "userName": name}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON().Object().
+			Value("scimType").Equal(errUniqueness)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		list := e.GET("/scim/v2/Users").
+			WithCookie(session.CookieName, s).
+			WithQuery("filter", fmt.Sprintf(`userName eq "%s"`, name)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		list.Value("totalResults").Equal(1)
+		list.Value("Resources").Array().First().Object().Value("id").Equal(id)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users").
+			WithCookie(session.CookieName, s).
+			WithQuery("filter", "userName eq").
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().
+			Value("scimType").Equal(errInvalidFilter)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users/{id}", uuid.Must(uuid.NewV4())).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("patch and delete", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand, role.User)
+		userSess := env.S(t, target.GetID())
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", target.GetID()).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{PatchOpSchema},
+				"Operations": []map[string]interface{}{
+					{"op": "Replace", "path": "displayName", "value": "patched"},
+					{"op": "replace", "value": map[string]interface{}{"active": "False"}},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ContainsMap(map[string]interface{}{"displayName": "patched", "active": false})
+
+		// 無効化されたユーザーのセッションは破棄される
+		_, err := env.SessStore.GetSessionByToken(userSess)
+		require.Equal(t, session.ErrSessionNotFound, err)
+
+		e.DELETE("/scim/v2/Users/{id}", target.GetID()).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNoContent)
+	})
+
+	t.Run("password change", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand, role.User)
+		userSess := env.S(t, target.GetID())
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", target.GetID()).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{PatchOpSchema},
+				"Operations": []map[string]interface{}{
+					{"op": "replace", "path": "password", "value": "newpasswordnewpassword"},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().
+			ContainsMap(map[string]interface{}{"active": true})
+
+		// パスワードが変更されたユーザーのセッションは破棄される
+		_, err := env.SessStore.GetSessionByToken(userSess)
+		require.Equal(t, session.ErrSessionNotFound, err)
+	})
+}
+
+func TestHandler_Groups(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	admin := env.CreateUser(t, rand, role.Admin)
+	s := env.S(t, admin.GetID())
+	user1 := env.CreateUser(t, rand, role.User)
+	user2 := env.CreateUser(t, rand, role.User)
+
+	name := random.AlphaNumeric(20)
+	e := env.R(t)
+	obj := e.POST("/scim/v2/Groups").
+		WithCookie(session.CookieName, s).
+		WithJSON(map[string]interface{}{
+			"schemas":     []string{GroupSchema},
+			"displayName": name,
+			"members":     []map[string]interface{}{{"value": user1.GetID()}},
+		}).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object()
+	obj.Value("displayName").String().Equal(name)
+	obj.Value("members").Array().Length().Equal(1)
+	id := obj.Value("id").String().Raw()
+
+	e.PATCH("/scim/v2/Groups/{id}", id).
+		WithCookie(session.CookieName, s).
+		WithJSON(map[string]interface{}{
+			"schemas": []string{PatchOpSchema},
+			"Operations": []map[string]interface{}{
+				{"op": "add", "path": "members", "value": []map[string]interface{}{{"value": user2.GetID()}}},
+				{"op": "remove", "path": fmt.Sprintf(`members[value eq "%s"]`, user1.GetID())},
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("members").Array().Length().Equal(1)
+
+	g, err := env.Repository.GetUserGroup(uuid.FromStringOrNil(id))
+	require.NoError(t, err)
+	require.True(t, g.IsMember(user2.GetID()))
+	require.False(t, g.IsMember(user1.GetID()))
+
+	e.PATCH("/scim/v2/Groups/{id}", id).
+		WithCookie(session.CookieName, s).
+		WithJSON(map[string]interface{}{
+			"schemas":    []string{PatchOpSchema},
+			"Operations": []map[string]interface{}{{"op": "add", "path": "members", "value": []map[string]interface{}{{"value": uuid.Must(uuid.NewV4())}}}},
+		}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	e.DELETE("/scim/v2/Groups/{id}", id).
+		WithCookie(session.CookieName, s).
+		Expect().
+		Status(http.StatusNoContent)
+	e.GET("/scim/v2/Groups/{id}", id).
+		WithCookie(session.CookieName, s).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -0,0 +1,475 @@
+package scim
+
+import (
+	"encoding/json"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/validator"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// User SCIMのUserリソース
+type User struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName"`
+	Active      bool        `json:"active"`
+	Groups      []MemberRef `json:"groups"`
+	Meta        Meta        `json:"meta"`
+}
+
+// MemberRef 他のリソースへの参照
+type MemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// Name SCIMのname属性
+type Name struct {
+	Formatted  string `json:"formatted"`
+	GivenName  string `json:"givenName"`
+	FamilyName string `json:"familyName"`
+}
+
+// displayName name属性から表示名を組み立てます
+func (n *Name) displayName() string {
+	if n == nil {
+		return ""
+	}
+	if len(n.Formatted) > 0 {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+// userRequest POST, PUT /Users リクエストボディ
+type userRequest struct {
+	UserName    string  `json:"userName"`
+	DisplayName *string `json:"displayName"`
+	Name        *Name   `json:"name"`
+	Active      *bool   `json:"active"`
+	Password    string  `json:"password"`
+}
+
+// displayName displayNameがない場合はname属性から表示名を組み立てます
+func (r *userRequest) displayName() string {
+	if r.DisplayName != nil {
+		return *r.DisplayName
+	}
+	return r.Name.displayName()
+}
+
+func (r *userRequest) validate() error {
+	displayName := r.displayName()
+	err := vd.ValidateStruct(r,
+		vd.Field(&r.UserName, validator.UserNameRuleRequired...),
+		vd.Field(&r.Password, validator.PasswordRule...),
+	)
+	if err == nil {
+		err = vd.Validate(displayName, vd.RuneLength(0, 64))
+	}
+	if err != nil {
+		return newError(http.StatusBadRequest, errInvalidValue, err.Error())
+	}
+	return nil
+}
+
+func formatUser(c echo.Context, user model.UserInfo, groups []MemberRef) *User {
+	if groups == nil {
+		groups = []MemberRef{}
+	}
+	return &User{
+		Schemas:     []string{UserSchema},
+		ID:          user.GetID().String(),
+		UserName:    user.GetName(),
+		DisplayName: user.GetDisplayName(),
+		Active:      user.IsActive(),
+		Groups:      groups,
+		Meta: Meta{
+			ResourceType: "User",
+			Created:      user.GetCreatedAt().UTC().Format(time.RFC3339),
+			LastModified: user.GetUpdatedAt().UTC().Format(time.RFC3339),
+			Location:     baseURL(c) + "/Users/" + user.GetID().String(),
+		},
+	}
+}
+
+// getUserGroupRefs ユーザーごとの所属グループの参照を取得します
+func (h *Handler) getUserGroupRefs(c echo.Context) (map[uuid.UUID][]MemberRef, error) {
+	groups, err := h.Repo.GetAllUserGroups()
+	if err != nil {
+		return nil, err
+	}
+	base := baseURL(c)
+	result := map[uuid.UUID][]MemberRef{}
+	for _, g := range groups {
+		for _, m := range g.Members {
+			result[m.UserID] = append(result[m.UserID], MemberRef{
+				Value:   g.ID.String(),
+				Display: g.Name,
+				Type:    "direct",
+				Ref:     base + "/Groups/" + g.ID.String(),
+			})
+		}
+	}
+	return result, nil
+}
+
+// respondUser ユーザーリソースを返します
+func (h *Handler) respondUser(c echo.Context, code int, userID uuid.UUID) error {
+	user, err := h.Repo.GetUser(userID, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	refs, err := h.getUserGroupRefs(c)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	res := formatUser(c, user, refs[user.GetID()])
+	if code == http.StatusCreated {
+		c.Response().Header().Set(echo.HeaderLocation, res.Meta.Location)
+	}
+	return respond(c, code, res)
+}
+
+// getParamUser パスパラメータのユーザーを取得します
+//
+// Bot・Webhookユーザーはプロビジョニングの対象外です。
+func (h *Handler) getParamUser(c echo.Context) (model.UserInfo, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, notFound("user")
+	}
+	user, err := h.Repo.GetUser(id, false)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, notFound("user")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if user.IsBot() {
+		return nil, notFound("user")
+	}
+	return user, nil
+}
+
+// setUserActive ユーザーアカウントの有効・無効を切り替えます
+//
+// 無効化した場合は、そのユーザーの全てのセッションとOAuth2トークンを破棄します。
+func (h *Handler) setUserActive(c echo.Context, user model.UserInfo, active bool) error {
+	if active {
+		if user.GetState() == model.UserAccountStatusActive {
+			return nil
+		}
+		args := repository.UpdateUserArgs{}
+		args.UserState.Valid = true
+		args.UserState.State = model.UserAccountStatusActive
+		if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+			return herror.InternalServerError(err)
+		}
+		h.L(c).Info("user activated by SCIM", zap.Stringer("userId", user.GetID()))
+		return nil
+	}
+
+	if user.GetState() == model.UserAccountStatusActive {
+		args := repository.UpdateUserArgs{}
+		args.UserState.Valid = true
+		args.UserState.State = model.UserAccountStatusDeactivated
+		if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+			return herror.InternalServerError(err)
+		}
+		h.L(c).Info("user deactivated by SCIM", zap.Stringer("userId", user.GetID()))
+	}
+	return h.revokeUserLogins(user)
+}
+
+// revokeUserLogins ユーザーの全てのセッションとOAuth2トークンを無効化します
+func (h *Handler) revokeUserLogins(user model.UserInfo) error {
+	if err := h.SessStore.RevokeSessionsByUserID(user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if err := h.Repo.DeleteTokenByUser(user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return nil
+}
+
+// GetUsers GET /Users
+func (h *Handler) GetUsers(c echo.Context) error {
+	q, err := parseListQuery(c)
+	if err != nil {
+		return err
+	}
+
+	users, err := h.Repo.GetUsers(repository.UsersQuery{}.NotBot())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	refs, err := h.getUserGroupRefs(c)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	resources := make([]map[string]interface{}, 0, len(users))
+	for _, u := range users {
+		m, err := toMap(formatUser(c, u, refs[u.GetID()]))
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		resources = append(resources, m)
+	}
+	return respond(c, http.StatusOK, q.apply(resources))
+}
+
+// GetUser GET /Users/:id
+func (h *Handler) GetUser(c echo.Context) error {
+	user, err := h.getParamUser(c)
+	if err != nil {
+		return err
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// CreateUser POST /Users
+func (h *Handler) CreateUser(c echo.Context) error {
+	var req userRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+
+	user, err := h.Repo.CreateUser(repository.CreateUserArgs{
+		Name:        req.UserName,
+		DisplayName: req.displayName(),
+		Password:    req.Password,
+		Role:        role.User,
+	})
+	if err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return newError(http.StatusConflict, errUniqueness, "userName conflicts")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("user provisioned by SCIM", zap.Stringer("userId", user.GetID()), zap.String("name", user.GetName()))
+
+	if req.Active != nil && !*req.Active {
+		if err := h.setUserActive(c, user, false); err != nil {
+			return err
+		}
+	}
+	return h.respondUser(c, http.StatusCreated, user.GetID())
+}
+
+// ReplaceUser PUT /Users/:id
+func (h *Handler) ReplaceUser(c echo.Context) error {
+	user, err := h.getParamUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req userRequest
+	if err := bind(c, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	if req.UserName != user.GetName() {
+		return newError(http.StatusBadRequest, errMutability, "userName is immutable")
+	}
+
+	args := repository.UpdateUserArgs{DisplayName: optional.StringFrom(req.displayName())}
+	if len(req.Password) > 0 {
+		args.Password = optional.StringFrom(req.Password)
+	}
+	if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if args.Password.Valid {
+		// パスワードが変更された場合は、既存のログインを全て無効化
+		if err := h.revokeUserLogins(user); err != nil {
+			return err
+		}
+		h.L(c).Info("user password changed by SCIM", zap.Stringer("userId", user.GetID()))
+	}
+	if req.Active != nil {
+		if err := h.setUserActive(c, user, *req.Active); err != nil {
+			return err
+		}
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// userPatch PATCH /Users/:id の操作を適用した結果
+type userPatch struct {
+	displayName optional.String
+	password    optional.String
+	active      optional.Bool
+}
+
+// apply 1つの属性への操作を適用します
+//
+// 対応していない属性(emailsなど)は無視します。
+func (p *userPatch) apply(user model.UserInfo, op, attr, subAttr string, value json.RawMessage) error {
+	invalid := func(attr string) error {
+		return newError(http.StatusBadRequest, errInvalidValue, "invalid value for "+attr)
+	}
+	remove := op == patchOpRemove
+
+	switch attr {
+	case "active":
+		if remove {
+			return newError(http.StatusBadRequest, errMutability, "active cannot be removed")
+		}
+		b, ok := parseBool(value)
+		if !ok {
+			return invalid(attr)
+		}
+		p.active = optional.BoolFrom(b)
+	case "displayname":
+		if remove {
+			p.displayName = optional.StringFrom("")
+			return nil
+		}
+		s, ok := parseString(value)
+		if !ok || vd.Validate(s, vd.RuneLength(0, 64)) != nil {
+			return invalid(attr)
+		}
+		p.displayName = optional.StringFrom(s)
+	case "name":
+		// name.formattedのみ表示名として扱う
+		if subAttr != "" && subAttr != "formatted" {
+			return nil
+		}
+		if remove {
+			p.displayName = optional.StringFrom("")
+			return nil
+		}
+		var name string
+		if subAttr == "formatted" {
+			s, ok := parseString(value)
+			if !ok {
+				return invalid("name.formatted")
+			}
+			name = s
+		} else {
+			var n Name
+			if err := json.Unmarshal(value, &n); err != nil {
+				return invalid(attr)
+			}
+			name = n.displayName()
+		}
+		if vd.Validate(name, vd.RuneLength(0, 64)) != nil {
+			return invalid(attr)
+		}
+		p.displayName = optional.StringFrom(name)
+	case "username":
+		s, ok := parseString(value)
+		if remove || !ok || s != user.GetName() {
+			return newError(http.StatusBadRequest, errMutability, "userName is immutable")
+		}
+	case "password":
+		s, ok := parseString(value)
+		if remove || !ok || vd.Validate(s, validator.PasswordRuleRequired...) != nil {
+			return invalid(attr)
+		}
+		p.password = optional.StringFrom(s)
+	}
+	return nil
+}
+
+// PatchUser PATCH /Users/:id
+func (h *Handler) PatchUser(c echo.Context) error {
+	user, err := h.getParamUser(c)
+	if err != nil {
+		return err
+	}
+
+	var req PatchRequest
+	if err := bindPatch(c, &req); err != nil {
+		return err
+	}
+
+	var p userPatch
+	for _, op := range req.Operations {
+		if len(op.Path) == 0 {
+			// パスがない場合は、valueのオブジェクトの各属性に操作を適用する
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return newError(http.StatusBadRequest, errInvalidValue, "value must be an object when path is omitted")
+			}
+			for k, v := range values {
+				attr, _, subAttr, err := ParsePath(k)
+				if err != nil {
+					return newError(http.StatusBadRequest, errInvalidPath, err.Error())
+				}
+				if err := p.apply(user, op.Op, attr, subAttr, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		attr, filter, subAttr, err := ParsePath(op.Path)
+		if err != nil {
+			return newError(http.StatusBadRequest, errInvalidPath, err.Error())
+		}
+		if filter != nil {
+			return newError(http.StatusBadRequest, errInvalidPath, "value filters are not supported for users")
+		}
+		if err := p.apply(user, op.Op, attr, subAttr, op.Value); err != nil {
+			return err
+		}
+	}
+
+	if p.displayName.Valid || p.password.Valid {
+		if err := h.Repo.UpdateUser(user.GetID(), repository.UpdateUserArgs{DisplayName: p.displayName, Password: p.password}); err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
+	if p.password.Valid {
+		// パスワードが変更された場合は、既存のログインを全て無効化
+		if err := h.revokeUserLogins(user); err != nil {
+			return err
+		}
+		h.L(c).Info("user password changed by SCIM", zap.Stringer("userId", user.GetID()))
+	}
+	if p.active.Valid {
+		if err := h.setUserActive(c, user, p.active.Bool); err != nil {
+			return err
+		}
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// DeleteUser DELETE /Users/:id
+//
+// traQではユーザーを削除できないため、アカウントを無効化します。
+func (h *Handler) DeleteUser(c echo.Context) error {
+	user, err := h.getParamUser(c)
+	if err != nil {
+		return err
+	}
+	if err := h.setUserActive(c, user, false); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
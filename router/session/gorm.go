@@ -23,6 +23,7 @@ type session struct {
 	refID     uuid.UUID
 	userID    uuid.UUID
 	createdAt time.Time
+	md        metadata
 
 	loaded bool
 	db     *gorm.DB
@@ -30,12 +31,13 @@ type session struct {
 	sync.Mutex
 }
 
-func newSession(db *gorm.DB, t string, refID uuid.UUID, userID uuid.UUID, createdAt time.Time, data map[string]interface{}) *session {
+func newSession(db *gorm.DB, t string, refID uuid.UUID, userID uuid.UUID, createdAt time.Time, md metadata, data map[string]interface{}) *session {
 	return &session{
 		t:         t,
 		refID:     refID,
 		userID:    userID,
 		createdAt: createdAt,
+		md:        md,
 		loaded:    data != nil,
 		db:        db,
 		data:      data,
@@ -62,6 +64,26 @@ func (s *session) LoggedIn() bool {
 	return s.userID != uuid.Nil
 }
 
+func (s *session) UserAgent() string {
+	return s.md.userAgent
+}
+
+func (s *session) IP() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.ip
+}
+
+func (s *session) LoginMethod() string {
+	return s.md.loginMethod
+}
+
+func (s *session) LastActivityAt() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.lastActivityAt
+}
+
 func (s *session) Get(key string) (interface{}, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -136,6 +158,16 @@ type cachedSession struct {
 	refID     uuid.UUID
 	userID    uuid.UUID
 	createdAt time.Time
+	md        metadata
+}
+
+func recordMetadata(r *model.SessionRecord) metadata {
+	return metadata{
+		userAgent:      r.UserAgent,
+		ip:             r.IP,
+		loginMethod:    r.LoginMethod,
+		lastActivityAt: r.LastActivity,
+	}
 }
 
 type sessionStore struct {
@@ -171,7 +203,7 @@ func (ss *sessionStore) GetSession(c echo.Context, createIfNotExist bool) (Sessi
 			return s, nil
 		}
 		if s.Refreshable() {
-			return ss.RenewSession(c, s.UserID())
+			return ss.RenewSession(c, s.UserID(), s.LoginMethod())
 		}
 	}
 
@@ -180,7 +212,7 @@ func (ss *sessionStore) GetSession(c echo.Context, createIfNotExist bool) (Sessi
 	}
 
 	// セッション発行
-	return ss.RenewSession(c, uuid.Nil)
+	return ss.RenewSession(c, uuid.Nil, "")
 }
 
 func (ss *sessionStore) GetSessionByToken(token string) (Session, error) {
@@ -190,21 +222,21 @@ func (ss *sessionStore) GetSessionByToken(token string) (Session, error) {
 
 	if _v, ok := ss.cache.Get(token); ok {
 		v := _v.(*cachedSession)
-		return newSession(ss.db, v.t, v.refID, v.userID, v.createdAt, nil), nil
+		return newSession(ss.db, v.t, v.refID, v.userID, v.createdAt, v.md, nil), nil
 	}
 
 	var r model.SessionRecord
 	err := ss.db.First(&r, &model.SessionRecord{Token: token}).Error
 	if err == nil {
 		if r.UserID != uuid.Nil {
-			ss.cache.Add(r.Token, &cachedSession{t: r.Token, refID: r.ReferenceID, userID: r.UserID, createdAt: r.Created})
+			ss.cache.Add(r.Token, &cachedSession{t: r.Token, refID: r.ReferenceID, userID: r.UserID, createdAt: r.Created, md: recordMetadata(&r)})
 		}
 
 		data, err := r.GetData()
 		if err != nil {
 			return nil, err
 		}
-		return newSession(ss.db, r.Token, r.ReferenceID, r.UserID, r.Created, data), nil
+		return newSession(ss.db, r.Token, r.ReferenceID, r.UserID, r.Created, recordMetadata(&r), data), nil
 	}
 
 	if gorm.IsRecordNotFoundError(err) {
@@ -229,7 +261,7 @@ func (ss *sessionStore) GetSessionsByUserID(userID uuid.UUID) ([]Session, error)
 		if err != nil {
 			return nil, err
 		}
-		s := newSession(ss.db, r.Token, r.ReferenceID, r.UserID, r.Created, data)
+		s := newSession(ss.db, r.Token, r.ReferenceID, r.UserID, r.Created, recordMetadata(r), data)
 		if s.Refreshable() {
 			result = append(result, s)
 		}
@@ -294,7 +326,7 @@ func (ss *sessionStore) RevokeSessionsByUserID(userID uuid.UUID) error {
 	return nil
 }
 
-func (ss *sessionStore) RenewSession(c echo.Context, userID uuid.UUID) (Session, error) {
+func (ss *sessionStore) RenewSession(c echo.Context, userID uuid.UUID, loginMethod string) (Session, error) {
 	cookie, _ := c.Cookie(CookieName)
 	if cookie != nil && len(cookie.Value) > 0 {
 		if err := ss.db.Delete(&model.SessionRecord{Token: cookie.Value}).Error; err != nil {
@@ -305,7 +337,7 @@ func (ss *sessionStore) RenewSession(c echo.Context, userID uuid.UUID) (Session,
 		cookie = &http.Cookie{}
 	}
 
-	s, err := ss.IssueSession(userID, nil)
+	s, err := ss.issueSession(userID, nil, newMetadata(c, loginMethod))
 	if err != nil {
 		return nil, err
 	}
@@ -322,15 +354,23 @@ func (ss *sessionStore) RenewSession(c echo.Context, userID uuid.UUID) (Session,
 }
 
 func (ss *sessionStore) IssueSession(userID uuid.UUID, data map[string]interface{}) (Session, error) {
+	return ss.issueSession(userID, data, metadata{lastActivityAt: time.Now()})
+}
+
+func (ss *sessionStore) issueSession(userID uuid.UUID, data map[string]interface{}, md metadata) (*session, error) {
 	if data == nil {
 		data = map[string]interface{}{}
 	}
 
 	s := &model.SessionRecord{
-		Token:       random.SecureAlphaNumeric(50),
-		ReferenceID: uuid.Must(uuid.NewV4()),
-		UserID:      userID,
-		Created:     time.Now(),
+		Token:        random.SecureAlphaNumeric(50),
+		ReferenceID:  uuid.Must(uuid.NewV4()),
+		UserID:       userID,
+		UserAgent:    md.userAgent,
+		IP:           md.ip,
+		LoginMethod:  md.loginMethod,
+		LastActivity: md.lastActivityAt,
+		Created:      time.Now(),
 	}
 	s.SetData(data)
 
@@ -342,7 +382,32 @@ func (ss *sessionStore) IssueSession(userID uuid.UUID, data map[string]interface
 		refID:     s.ReferenceID,
 		userID:    s.UserID,
 		createdAt: s.Created,
+		md:        md,
 	})
 
-	return newSession(ss.db, s.Token, s.ReferenceID, s.UserID, s.Created, data), nil
+	return newSession(ss.db, s.Token, s.ReferenceID, s.UserID, s.Created, md, data), nil
+}
+
+func (ss *sessionStore) UpdateSessionActivity(s Session, ip string) error {
+	gs, ok := s.(*session)
+	if !ok {
+		return nil
+	}
+	gs.Lock()
+	defer gs.Unlock()
+	if !gs.md.needsActivityUpdate(ip) {
+		return nil
+	}
+
+	now := time.Now()
+	if err := ss.db.Model(&model.SessionRecord{Token: gs.t}).Updates(map[string]interface{}{"ip": ip, "last_activity": now}).Error; err != nil {
+		return err
+	}
+	gs.md.ip = ip
+	gs.md.lastActivityAt = now
+
+	if _, ok := ss.cache.Peek(gs.t); ok {
+		ss.cache.Add(gs.t, &cachedSession{t: gs.t, refID: gs.refID, userID: gs.userID, createdAt: gs.createdAt, md: gs.md})
+	}
+	return nil
 }
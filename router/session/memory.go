@@ -14,16 +14,18 @@ type memorySession struct {
 	refID     uuid.UUID
 	userID    uuid.UUID
 	createdAt time.Time
+	md        metadata
 	data      map[string]interface{}
 	sync.Mutex
 }
 
-func newMemorySession(t string, refID uuid.UUID, userID uuid.UUID, createdAt time.Time, data map[string]interface{}) *memorySession {
+func newMemorySession(t string, refID uuid.UUID, userID uuid.UUID, createdAt time.Time, md metadata, data map[string]interface{}) *memorySession {
 	return &memorySession{
 		t:         t,
 		refID:     refID,
 		userID:    userID,
 		createdAt: createdAt,
+		md:        md,
 		data:      data,
 	}
 }
@@ -48,6 +50,26 @@ func (s *memorySession) LoggedIn() bool {
 	return s.userID != uuid.Nil
 }
 
+func (s *memorySession) UserAgent() string {
+	return s.md.userAgent
+}
+
+func (s *memorySession) IP() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.ip
+}
+
+func (s *memorySession) LoginMethod() string {
+	return s.md.loginMethod
+}
+
+func (s *memorySession) LastActivityAt() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.lastActivityAt
+}
+
 func (s *memorySession) Get(key string) (interface{}, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -107,7 +129,7 @@ func (ms *memoryStore) GetSession(c echo.Context, createIfNotExist bool) (Sessio
 			return s, nil
 		}
 		if s.Refreshable() {
-			return ms.RenewSession(c, s.UserID())
+			return ms.RenewSession(c, s.UserID(), s.LoginMethod())
 		}
 	}
 
@@ -116,7 +138,7 @@ func (ms *memoryStore) GetSession(c echo.Context, createIfNotExist bool) (Sessio
 	}
 
 	// セッション発行
-	return ms.RenewSession(c, uuid.Nil)
+	return ms.RenewSession(c, uuid.Nil, "")
 }
 
 func (ms *memoryStore) GetSessionByToken(token string) (Session, error) {
@@ -195,7 +217,7 @@ func (ms *memoryStore) RevokeSessionsByUserID(userID uuid.UUID) error {
 	return nil
 }
 
-func (ms *memoryStore) RenewSession(c echo.Context, userID uuid.UUID) (Session, error) {
+func (ms *memoryStore) RenewSession(c echo.Context, userID uuid.UUID, loginMethod string) (Session, error) {
 	cookie, _ := c.Cookie(CookieName)
 	if cookie != nil && len(cookie.Value) > 0 {
 		ms.Lock()
@@ -205,10 +227,7 @@ func (ms *memoryStore) RenewSession(c echo.Context, userID uuid.UUID) (Session,
 		cookie = &http.Cookie{}
 	}
 
-	s, err := ms.IssueSession(userID, nil)
-	if err != nil {
-		return nil, err
-	}
+	s := ms.issueSession(userID, nil, newMetadata(c, loginMethod))
 
 	cookie.Name = CookieName
 	cookie.Value = s.Token()
@@ -222,12 +241,30 @@ func (ms *memoryStore) RenewSession(c echo.Context, userID uuid.UUID) (Session,
 }
 
 func (ms *memoryStore) IssueSession(userID uuid.UUID, data map[string]interface{}) (Session, error) {
+	return ms.issueSession(userID, data, metadata{lastActivityAt: time.Now()}), nil
+}
+
+func (ms *memoryStore) issueSession(userID uuid.UUID, data map[string]interface{}, md metadata) *memorySession {
 	if data == nil {
 		data = map[string]interface{}{}
 	}
-	s := newMemorySession(random.SecureAlphaNumeric(50), uuid.Must(uuid.NewV4()), userID, time.Now(), data)
+	s := newMemorySession(random.SecureAlphaNumeric(50), uuid.Must(uuid.NewV4()), userID, time.Now(), md, data)
 	ms.Lock()
 	ms.sessions[s.Token()] = s
 	ms.Unlock()
-	return s, nil
+	return s
+}
+
+func (ms *memoryStore) UpdateSessionActivity(s Session, ip string) error {
+	ss, ok := s.(*memorySession)
+	if !ok {
+		return nil
+	}
+	ss.Lock()
+	defer ss.Unlock()
+	if ss.md.needsActivityUpdate(ip) {
+		ss.md.ip = ip
+		ss.md.lastActivityAt = time.Now()
+	}
+	return nil
 }
@@ -0,0 +1,456 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/go-redis/redis/v7"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Redisのセッションハッシュのフィールド名
+const (
+	redisFieldRefID        = "ref_id"
+	redisFieldUserID       = "user_id"
+	redisFieldData         = "data"
+	redisFieldUserAgent    = "user_agent"
+	redisFieldIP           = "ip"
+	redisFieldLoginMethod  = "login_method"
+	redisFieldLastActivity = "last_activity"
+	redisFieldCreated      = "created"
+)
+
+type redisSession struct {
+	t         string
+	refID     uuid.UUID
+	userID    uuid.UUID
+	createdAt time.Time
+	md        metadata
+	data      map[string]interface{}
+	store     *redisStore
+	sync.Mutex
+}
+
+func (s *redisSession) Token() string {
+	return s.t
+}
+
+func (s *redisSession) RefID() uuid.UUID {
+	return s.refID
+}
+
+func (s *redisSession) UserID() uuid.UUID {
+	return s.userID
+}
+
+func (s *redisSession) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+func (s *redisSession) LoggedIn() bool {
+	return s.userID != uuid.Nil
+}
+
+func (s *redisSession) UserAgent() string {
+	return s.md.userAgent
+}
+
+func (s *redisSession) IP() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.ip
+}
+
+func (s *redisSession) LoginMethod() string {
+	return s.md.loginMethod
+}
+
+func (s *redisSession) LastActivityAt() time.Time {
+	s.Lock()
+	defer s.Unlock()
+	return s.md.lastActivityAt
+}
+
+func (s *redisSession) Get(key string) (interface{}, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.data[key], nil
+}
+
+func (s *redisSession) Set(key string, value interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+	s.data[key] = value
+	return s.save()
+}
+
+func (s *redisSession) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.data, key)
+	return s.save()
+}
+
+func (s *redisSession) Expired() bool {
+	return time.Since(s.createdAt) > time.Duration(sessionMaxAge)*time.Second
+}
+
+func (s *redisSession) Refreshable() bool {
+	return time.Since(s.createdAt) <= time.Duration(sessionMaxAge+sessionKeepAge)*time.Second
+}
+
+func (s *redisSession) save() error {
+	// 無効化済みのセッションを復活させないように、存在する場合のみ書き込む
+	return s.store.client.Watch(func(tx *redis.Tx) error {
+		n, err := tx.Exists(s.store.tokenKey(s.t)).Result()
+		if err != nil || n == 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(func(p redis.Pipeliner) error {
+			return p.HSet(s.store.tokenKey(s.t), redisFieldData, encodeSessionData(s.data)).Err()
+		})
+		return err
+	}, s.store.tokenKey(s.t))
+}
+
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisStore Redisをバックエンドとするセッションストアを生成します
+//
+// セッションはRedisのTTLにより自動で削除されます。
+// prefixは全てのキーの先頭に付与されます。
+func NewRedisStore(client redis.UniversalClient, prefix string) Store {
+	return &redisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// tokenKey セッションのハッシュのキー
+func (rs *redisStore) tokenKey(token string) string {
+	return rs.prefix + "token:" + token
+}
+
+// refKey 参照IDからトークンへのインデックスのキー
+func (rs *redisStore) refKey(refID uuid.UUID) string {
+	return rs.prefix + "ref:" + refID.String()
+}
+
+// userKey ユーザーIDからトークンの集合へのインデックスのキー
+func (rs *redisStore) userKey(userID uuid.UUID) string {
+	return rs.prefix + "user:" + userID.String()
+}
+
+func (rs *redisStore) GetSession(c echo.Context, createIfNotExist bool) (Session, error) {
+	var token string
+	cookie, err := c.Cookie(CookieName)
+	if err == nil {
+		token = cookie.Value
+	}
+
+	var s Session
+	if len(token) > 0 {
+		s, err = rs.GetSessionByToken(token)
+		if err != nil && err != ErrSessionNotFound {
+			return nil, err
+		}
+	}
+
+	if s != nil {
+		if !s.Expired() {
+			return s, nil
+		}
+		if s.Refreshable() {
+			return rs.RenewSession(c, s.UserID(), s.LoginMethod())
+		}
+	}
+
+	if !createIfNotExist {
+		return nil, rs.RevokeSession(c)
+	}
+
+	// セッション発行
+	return rs.RenewSession(c, uuid.Nil, "")
+}
+
+func (rs *redisStore) GetSessionByToken(token string) (Session, error) {
+	if len(token) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	h, err := rs.client.HGetAll(rs.tokenKey(token)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(h) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	return rs.decodeSession(token, h)
+}
+
+func (rs *redisStore) GetSessionsByUserID(userID uuid.UUID) ([]Session, error) {
+	if userID == uuid.Nil {
+		return []Session{}, nil
+	}
+
+	tokens, err := rs.client.SMembers(rs.userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Session, 0)
+	for _, token := range tokens {
+		s, err := rs.GetSessionByToken(token)
+		if err != nil {
+			if err == ErrSessionNotFound {
+				// TTLで削除済みのセッションをインデックスから除く
+				if err := rs.client.SRem(rs.userKey(userID), token).Err(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+		if s.Refreshable() {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (rs *redisStore) RevokeSession(c echo.Context) error {
+	cookie, err := c.Cookie(CookieName)
+	if err != nil {
+		return nil
+	}
+
+	if err := rs.deleteSession(cookie.Value); err != nil {
+		return err
+	}
+
+	cookie.Value = ""
+	cookie.Expires = time.Unix(0, 0)
+	cookie.MaxAge = -1
+	c.SetCookie(cookie)
+	return nil
+}
+
+func (rs *redisStore) RevokeSessionByRefID(refID uuid.UUID) error {
+	if refID == uuid.Nil {
+		return nil
+	}
+
+	token, err := rs.client.Get(rs.refKey(refID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil
+		}
+		return err
+	}
+	return rs.deleteSession(token)
+}
+
+func (rs *redisStore) RevokeSessionsByUserID(userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return nil
+	}
+
+	tokens, err := rs.client.SMembers(rs.userKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := rs.deleteSession(token); err != nil {
+			return err
+		}
+	}
+	return rs.client.Del(rs.userKey(userID)).Err()
+}
+
+func (rs *redisStore) RenewSession(c echo.Context, userID uuid.UUID, loginMethod string) (Session, error) {
+	cookie, _ := c.Cookie(CookieName)
+	if cookie != nil && len(cookie.Value) > 0 {
+		if err := rs.deleteSession(cookie.Value); err != nil {
+			return nil, err
+		}
+	} else {
+		cookie = &http.Cookie{}
+	}
+
+	s, err := rs.issueSession(userID, nil, newMetadata(c, loginMethod))
+	if err != nil {
+		return nil, err
+	}
+
+	cookie.Name = CookieName
+	cookie.Value = s.Token()
+	cookie.Expires = time.Now().Add(time.Duration(sessionMaxAge+sessionKeepAge) * time.Second)
+	cookie.MaxAge = sessionMaxAge + sessionKeepAge
+	cookie.Path = "/"
+	cookie.HttpOnly = true
+	c.SetCookie(cookie)
+
+	return s, nil
+}
+
+func (rs *redisStore) IssueSession(userID uuid.UUID, data map[string]interface{}) (Session, error) {
+	return rs.issueSession(userID, data, metadata{lastActivityAt: time.Now()})
+}
+
+func (rs *redisStore) issueSession(userID uuid.UUID, data map[string]interface{}, md metadata) (*redisSession, error) {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	s := &redisSession{
+		t:         random.SecureAlphaNumeric(50),
+		refID:     uuid.Must(uuid.NewV4()),
+		userID:    userID,
+		createdAt: time.Now(),
+		md:        md,
+		data:      data,
+		store:     rs,
+	}
+	ttl := time.Duration(sessionMaxAge+sessionKeepAge) * time.Second
+
+	_, err := rs.client.TxPipelined(func(p redis.Pipeliner) error {
+		p.HSet(rs.tokenKey(s.t), map[string]interface{}{
+			redisFieldRefID:        s.refID.String(),
+			redisFieldUserID:       s.userID.String(),
+			redisFieldData:         encodeSessionData(s.data),
+			redisFieldUserAgent:    s.md.userAgent,
+			redisFieldIP:           s.md.ip,
+			redisFieldLoginMethod:  s.md.loginMethod,
+			redisFieldLastActivity: formatUnixNano(s.md.lastActivityAt),
+			redisFieldCreated:      formatUnixNano(s.createdAt),
+		})
+		p.Expire(rs.tokenKey(s.t), ttl)
+		p.Set(rs.refKey(s.refID), s.t, ttl)
+		if s.userID != uuid.Nil {
+			// インデックスは最後に発行したセッションと同時に期限切れになる
+			p.SAdd(rs.userKey(s.userID), s.t)
+			p.Expire(rs.userKey(s.userID), ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (rs *redisStore) UpdateSessionActivity(s Session, ip string) error {
+	rss, ok := s.(*redisSession)
+	if !ok {
+		return nil
+	}
+	rss.Lock()
+	defer rss.Unlock()
+	if !rss.md.needsActivityUpdate(ip) {
+		return nil
+	}
+
+	now := time.Now()
+	// 無効化済みのセッションを復活させないように、存在する場合のみ書き込む
+	err := rs.client.Watch(func(tx *redis.Tx) error {
+		n, err := tx.Exists(rs.tokenKey(rss.t)).Result()
+		if err != nil || n == 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(func(p redis.Pipeliner) error {
+			return p.HSet(rs.tokenKey(rss.t), redisFieldIP, ip, redisFieldLastActivity, formatUnixNano(now)).Err()
+		})
+		return err
+	}, rs.tokenKey(rss.t))
+	if err != nil {
+		return err
+	}
+	rss.md.ip = ip
+	rss.md.lastActivityAt = now
+	return nil
+}
+
+func (rs *redisStore) deleteSession(token string) error {
+	ids, err := rs.client.HMGet(rs.tokenKey(token), redisFieldRefID, redisFieldUserID).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = rs.client.TxPipelined(func(p redis.Pipeliner) error {
+		p.Del(rs.tokenKey(token))
+		if refID, ok := ids[0].(string); ok {
+			p.Del(rs.refKey(uuid.FromStringOrNil(refID)))
+		}
+		if userID, ok := ids[1].(string); ok {
+			p.SRem(rs.userKey(uuid.FromStringOrNil(userID)), token)
+		}
+		return nil
+	})
+	return err
+}
+
+func (rs *redisStore) decodeSession(token string, h map[string]string) (*redisSession, error) {
+	refID, err := uuid.FromString(h[redisFieldRefID])
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.FromString(h[redisFieldUserID])
+	if err != nil {
+		return nil, err
+	}
+	createdAt, err := parseUnixNano(h[redisFieldCreated])
+	if err != nil {
+		return nil, err
+	}
+	lastActivityAt, err := parseUnixNano(h[redisFieldLastActivity])
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := gob.NewDecoder(bytes.NewReader([]byte(h[redisFieldData]))).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return &redisSession{
+		t:         token,
+		refID:     refID,
+		userID:    userID,
+		createdAt: createdAt,
+		md: metadata{
+			userAgent:      h[redisFieldUserAgent],
+			ip:             h[redisFieldIP],
+			loginMethod:    h[redisFieldLoginMethod],
+			lastActivityAt: lastActivityAt,
+		},
+		data:  data,
+		store: rs,
+	}, nil
+}
+
+func encodeSessionData(data map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		panic(err) // gobにdataの中身の構造体が登録されていない
+	}
+	return buf.Bytes()
+}
+
+func formatUnixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func parseUnixNano(s string) (time.Time, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, n), nil
+}
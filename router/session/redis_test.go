@@ -0,0 +1,191 @@
+package session
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupRedisStore(t *testing.T) (*miniredis.Miniredis, Store) {
+	t.Helper()
+	m, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return m, NewRedisStore(client, "test:")
+}
+
+func TestRedisStore_IssueSession(t *testing.T) {
+	t.Parallel()
+	m, store := setupRedisStore(t)
+	userID := uuid.Must(uuid.NewV4())
+
+	s, err := store.IssueSession(userID, map[string]interface{}{"a": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(sessionMaxAge+sessionKeepAge)*time.Second, m.TTL("test:token:"+s.Token()))
+	assert.Equal(t, time.Duration(sessionMaxAge+sessionKeepAge)*time.Second, m.TTL("test:ref:"+s.RefID().String()))
+	assert.True(t, m.Exists("test:user:"+userID.String()))
+
+	if assert.NoError(t, s.Set("c", "d")) {
+		got, err := store.GetSessionByToken(s.Token())
+		require.NoError(t, err)
+		assert.Equal(t, s.RefID(), got.RefID())
+		assert.Equal(t, userID, got.UserID())
+		assert.True(t, got.LoggedIn())
+		assert.WithinDuration(t, s.CreatedAt(), got.CreatedAt(), time.Millisecond)
+		v, err := got.Get("a")
+		require.NoError(t, err)
+		assert.Equal(t, "b", v)
+		v, err = got.Get("c")
+		require.NoError(t, err)
+		assert.Equal(t, "d", v)
+	}
+
+	// 匿名セッションはユーザーインデックスに入らない
+	s, err = store.IssueSession(uuid.Nil, nil)
+	require.NoError(t, err)
+	got, err := store.GetSessionByToken(s.Token())
+	require.NoError(t, err)
+	assert.False(t, got.LoggedIn())
+	assert.False(t, m.Exists("test:user:"+uuid.Nil.String()))
+
+	_, err = store.GetSessionByToken("not found")
+	assert.Equal(t, ErrSessionNotFound, err)
+}
+
+func TestRedisStore_GetSessionsByUserID(t *testing.T) {
+	t.Parallel()
+	m, store := setupRedisStore(t)
+	userID := uuid.Must(uuid.NewV4())
+
+	s1, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+	s2, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+	_, err = store.IssueSession(uuid.Must(uuid.NewV4()), nil)
+	require.NoError(t, err)
+
+	ses, err := store.GetSessionsByUserID(userID)
+	require.NoError(t, err)
+	refIDs := make([]uuid.UUID, len(ses))
+	for i, s := range ses {
+		refIDs[i] = s.RefID()
+	}
+	assert.ElementsMatch(t, []uuid.UUID{s1.RefID(), s2.RefID()}, refIDs)
+
+	// TTLで消えたセッションはインデックスからも除かれる
+	m.Del("test:token:" + s1.Token())
+	ses, err = store.GetSessionsByUserID(userID)
+	require.NoError(t, err)
+	if assert.Len(t, ses, 1) {
+		assert.Equal(t, s2.RefID(), ses[0].RefID())
+	}
+	members, err := m.Members("test:user:" + userID.String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{s2.Token()}, members)
+
+	ses, err = store.GetSessionsByUserID(uuid.Nil)
+	require.NoError(t, err)
+	assert.Empty(t, ses)
+}
+
+func TestRedisStore_RevokeSessionByRefID(t *testing.T) {
+	t.Parallel()
+	m, store := setupRedisStore(t)
+	userID := uuid.Must(uuid.NewV4())
+
+	s1, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+	s2, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeSessionByRefID(s1.RefID()))
+	_, err = store.GetSessionByToken(s1.Token())
+	assert.Equal(t, ErrSessionNotFound, err)
+	assert.False(t, m.Exists("test:ref:"+s1.RefID().String()))
+	_, err = store.GetSessionByToken(s2.Token())
+	assert.NoError(t, err)
+
+	// 無効化済みのセッションへの書き込みでセッションが復活しない
+	require.NoError(t, s1.Set("a", "b"))
+	assert.False(t, m.Exists("test:token:"+s1.Token()))
+
+	assert.NoError(t, store.RevokeSessionByRefID(uuid.Must(uuid.NewV4())))
+}
+
+func TestRedisStore_RevokeSessionsByUserID(t *testing.T) {
+	t.Parallel()
+	m, store := setupRedisStore(t)
+	userID := uuid.Must(uuid.NewV4())
+
+	s1, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+	s2, err := store.IssueSession(userID, nil)
+	require.NoError(t, err)
+	other, err := store.IssueSession(uuid.Must(uuid.NewV4()), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeSessionsByUserID(userID))
+	for _, s := range []Session{s1, s2} {
+		_, err = store.GetSessionByToken(s.Token())
+		assert.Equal(t, ErrSessionNotFound, err)
+	}
+	assert.False(t, m.Exists("test:user:"+userID.String()))
+	_, err = store.GetSessionByToken(other.Token())
+	assert.NoError(t, err)
+}
+
+func TestRedisStore_RenewSession(t *testing.T) {
+	t.Parallel()
+	_, store := setupRedisStore(t)
+	userID := uuid.Must(uuid.NewV4())
+
+	old, err := store.IssueSession(uuid.Nil, nil)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(echo.HeaderXForwardedFor, "192.0.2.1")
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: old.Token()})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	s, err := store.RenewSession(c, userID, "test")
+	require.NoError(t, err)
+	assert.NotEqual(t, old.Token(), s.Token())
+	assert.Contains(t, rec.Header().Get(echo.HeaderSetCookie), s.Token())
+
+	_, err = store.GetSessionByToken(old.Token())
+	assert.Equal(t, ErrSessionNotFound, err)
+
+	got, err := store.GetSessionByToken(s.Token())
+	require.NoError(t, err)
+	assert.Equal(t, userID, got.UserID())
+	assert.Equal(t, "test-agent", got.UserAgent())
+	assert.Equal(t, "192.0.2.1", got.IP())
+	assert.Equal(t, "test", got.LoginMethod())
+}
+
+func TestRedisStore_UpdateSessionActivity(t *testing.T) {
+	t.Parallel()
+	_, store := setupRedisStore(t)
+
+	s, err := store.IssueSession(uuid.Must(uuid.NewV4()), nil)
+	require.NoError(t, err)
+	lastActivityAt := s.LastActivityAt()
+
+	require.NoError(t, store.UpdateSessionActivity(s, "192.0.2.2"))
+	got, err := store.GetSessionByToken(s.Token())
+	require.NoError(t, err)
+	assert.Equal(t, "192.0.2.2", got.IP())
+	assert.True(t, got.LastActivityAt().After(lastActivityAt))
+}
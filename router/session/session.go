@@ -13,6 +13,10 @@ const (
 	sessionMaxAge  = 60 * 60 * 24 * 14 // 2 weeks
 	sessionKeepAge = 60 * 60 * 24 * 14 // 2 weeks
 	cacheSize      = 2048
+	// activityUpdateInterval 最終アクティビティ日時の更新間隔
+	activityUpdateInterval = time.Minute
+	// maxUserAgentLength 記録するUser-Agentの最大長
+	maxUserAgentLength = 255
 )
 
 var ErrSessionNotFound = errors.New("session not found")
@@ -23,6 +27,14 @@ type Session interface {
 	UserID() uuid.UUID
 	CreatedAt() time.Time
 	LoggedIn() bool
+	// UserAgent セッション発行時のUser-Agent
+	UserAgent() string
+	// IP 最後にアクセスしたIPアドレス
+	IP() string
+	// LoginMethod ログイン方法
+	LoginMethod() string
+	// LastActivityAt 最終アクティビティ日時
+	LastActivityAt() time.Time
 
 	Get(key string) (interface{}, error)
 	Set(key string, value interface{}) error
@@ -39,6 +51,38 @@ type Store interface {
 	RevokeSession(c echo.Context) error
 	RevokeSessionByRefID(refID uuid.UUID) error
 	RevokeSessionsByUserID(userID uuid.UUID) error
-	RenewSession(c echo.Context, userID uuid.UUID) (Session, error)
+	// RenewSession セッションを再発行し、クッキーに設定します
+	//
+	// リクエストのUser-AgentとIPアドレス、ログイン方法をセッションに記録します。
+	RenewSession(c echo.Context, userID uuid.UUID, loginMethod string) (Session, error)
 	IssueSession(userID uuid.UUID, data map[string]interface{}) (Session, error)
+	// UpdateSessionActivity セッションの最終アクティビティ日時とIPアドレスを更新します
+	//
+	// 前回の更新から一定時間経過していない、かつIPアドレスが変わっていない場合は何もしません。
+	UpdateSessionActivity(s Session, ip string) error
+}
+
+// metadata セッションのメタデータ
+type metadata struct {
+	userAgent      string
+	ip             string
+	loginMethod    string
+	lastActivityAt time.Time
+}
+
+func newMetadata(c echo.Context, loginMethod string) metadata {
+	ua := c.Request().UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = ua[:maxUserAgentLength]
+	}
+	return metadata{
+		userAgent:      ua,
+		ip:             c.RealIP(),
+		loginMethod:    loginMethod,
+		lastActivityAt: time.Now(),
+	}
+}
+
+func (m metadata) needsActivityUpdate(ip string) bool {
+	return m.ip != ip || time.Since(m.lastActivityAt) >= activityUpdateInterval
 }
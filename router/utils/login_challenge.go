@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"encoding/gob"
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	loginChallengeSessionKey  = "login_challenge"
+	loginChallengeTimeout     = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+
+	// LoginChallengeTypeTOTP TOTPコードの入力が必要
+	LoginChallengeTypeTOTP = "totp"
+	// LoginChallengeTypeTOTPEnrollment TOTPの登録が必要
+	LoginChallengeTypeTOTPEnrollment = "totp_enrollment"
+	// LoginChallengeTypeWebAuthn WebAuthnによる認証が必要
+	LoginChallengeTypeWebAuthn = "webauthn"
+
+	// TwoFactorMethodTOTP TOTPコード(またはリカバリーコード)による二要素認証
+	TwoFactorMethodTOTP = "totp"
+	// TwoFactorMethodWebAuthn WebAuthnによる二要素認証
+	TwoFactorMethodWebAuthn = "webauthn"
+)
+
+func init() {
+	gob.Register(LoginChallenge{})
+}
+
+// LoginChallenge パスワード検証後の二要素認証待ちの状態
+type LoginChallenge struct {
+	UserID    uuid.UUID
+	Type      string
+	ExpiresAt time.Time
+	Attempts  int
+	Redirect  string
+	// LoginMethod 二要素認証の完了後に発行するセッションのログイン方法
+	LoginMethod string
+	// GroupChanges 二要素認証の完了後に反映するユーザーグループの所属の変更
+	GroupChanges *GroupMembershipChanges
+}
+
+// GroupMembershipChanges ログイン時に反映するユーザーグループの所属の変更
+type GroupMembershipChanges struct {
+	// Join 追加するユーザーグループのID
+	Join []uuid.UUID
+	// Leave 削除するユーザーグループのID
+	Leave []uuid.UUID
+}
+
+// Apply ユーザーグループの所属の変更を反映します
+//
+// 既に存在しないユーザーグループは無視します。
+func (ch *GroupMembershipChanges) Apply(repo repository.Repository, userID uuid.UUID) error {
+	for _, gid := range ch.Join {
+		if err := repo.AddUserToGroup(userID, gid, ""); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	for _, gid := range ch.Leave {
+		if err := repo.RemoveUserFromGroup(userID, gid); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoginChallengeResponse 二要素認証が必要な場合のログインAPIのレスポンス
+type LoginChallengeResponse struct {
+	Type    string   `json:"type"`
+	Methods []string `json:"methods"`
+}
+
+// StartLoginChallenge セッションに二要素認証待ちの状態を保存し、202 Acceptedを返します
+//
+// stateにはTwoFactorNone以外を指定してください。
+// groupChangesがnilでない場合、二要素認証の完了後にユーザーグループの所属の変更を反映します。
+func StartLoginChallenge(c echo.Context, repo repository.Repository, sessStore session.Store, userID uuid.UUID, state TwoFactorState, loginMethod string, groupChanges *GroupMembershipChanges) error {
+	var challengeType string
+	switch state {
+	case TwoFactorTOTP:
+		challengeType = LoginChallengeTypeTOTP
+	case TwoFactorWebAuthn:
+		challengeType = LoginChallengeTypeWebAuthn
+	case TwoFactorEnrollmentRequired:
+		challengeType = LoginChallengeTypeTOTPEnrollment
+	default:
+		return herror.InternalServerError(errors.New("two-factor authentication is not required"))
+	}
+
+	sess, err := sessStore.GetSession(c, true)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if err := sess.Set(loginChallengeSessionKey, LoginChallenge{
+		UserID:       userID,
+		Type:         challengeType,
+		ExpiresAt:    time.Now().Add(loginChallengeTimeout),
+		Redirect:     LoginRedirect(c),
+		LoginMethod:  loginMethod,
+		GroupChanges: groupChanges,
+	}); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	res := &LoginChallengeResponse{Type: challengeType}
+	switch challengeType {
+	case LoginChallengeTypeTOTP:
+		res.Methods = append(res.Methods, TwoFactorMethodTOTP)
+		hasWebAuthn, err := repo.HasWebAuthnCredentials(userID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if hasWebAuthn {
+			res.Methods = append(res.Methods, TwoFactorMethodWebAuthn)
+		}
+	case LoginChallengeTypeWebAuthn:
+		res.Methods = []string{TwoFactorMethodWebAuthn}
+	case LoginChallengeTypeTOTPEnrollment:
+		res.Methods = []string{TwoFactorMethodTOTP}
+	}
+	return c.JSON(http.StatusAccepted, res)
+}
+
+// GetLoginChallenge セッションから有効な二要素認証待ちの状態を取得します
+func GetLoginChallenge(c echo.Context, sessStore session.Store) (*LoginChallenge, error) {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if sess == nil {
+		return nil, herror.Unauthorized("no login challenge")
+	}
+	v, err := sess.Get(loginChallengeSessionKey)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	lc, ok := v.(LoginChallenge)
+	if !ok {
+		return nil, herror.Unauthorized("no login challenge")
+	}
+	if time.Now().After(lc.ExpiresAt) || lc.Attempts >= loginChallengeMaxAttempts {
+		if err := sess.Delete(loginChallengeSessionKey); err != nil {
+			return nil, herror.InternalServerError(err)
+		}
+		return nil, herror.Unauthorized("login challenge expired")
+	}
+	return &lc, nil
+}
+
+// FailLoginChallenge 二要素認証の失敗回数を記録し、401 Unauthorizedを返します
+//
+// パスワードの誤りと同様に、IPアドレスとアカウントの連続失敗回数も増やします。
+// アカウントがロックされた場合は、二要素認証待ちの状態を破棄します。
+func FailLoginChallenge(c echo.Context, repo repository.Repository, sessStore session.Store, lc *LoginChallenge) error {
+	sess, err := sessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	attempt := &LoginAttempt{IP: c.RealIP(), Method: lc.LoginMethod}
+	user, err := repo.GetUser(lc.UserID, false)
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	if user != nil {
+		attempt.Name = user.GetName()
+		attempt.User = user
+	}
+	if err := RecordLoginFailure(repo, attempt, model.LoginFailureReasonWrongTwoFactor); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	l, err := repo.GetLoginLock(model.LoginLockKindUser, lc.UserID.String())
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	if l != nil && l.IsLocked(time.Now()) {
+		if err := sess.Delete(loginChallengeSessionKey); err != nil {
+			return herror.InternalServerError(err)
+		}
+		return herror.Unauthorized("invalid code")
+	}
+
+	lc.Attempts++
+	if err := sess.Set(loginChallengeSessionKey, *lc); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return herror.Unauthorized("invalid code")
+}
+
+// CompleteLoginChallenge 二要素認証の成功を記録し、ログインセッションを発行します
+//
+// アカウントの連続失敗回数は、二要素認証まで全て成功した場合のみリセットします。
+// 保留されていたユーザーグループの所属の変更もここで反映します。
+func CompleteLoginChallenge(c echo.Context, repo repository.Repository, sessStore session.Store, lc *LoginChallenge) error {
+	if err := RecordLoginSuccess(repo, lc.UserID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if lc.GroupChanges != nil {
+		if err := lc.GroupChanges.Apply(repo, lc.UserID); err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
+	if _, err := sessStore.RenewSession(c, lc.UserID, lc.LoginMethod); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return nil
+}
+
+// LoginRedirect リクエストのredirectクエリパラメータを返します
+//
+// 同一オリジンの相対パスでない場合は空文字列を返します。
+func LoginRedirect(c echo.Context) string {
+	redirect := c.QueryParam("redirect")
+	if !isSafeRedirect(redirect) {
+		return ""
+	}
+	return redirect
+}
+
+// isSafeRedirect 同一オリジンの相対パス("/"から始まるパス)かどうか
+func isSafeRedirect(redirect string) bool {
+	// "//host"や"/\host"はブラウザによって別オリジンとして解釈される
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.ContainsAny(redirect, "\\\r\n\t") {
+		return false
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return false
+	}
+	return len(u.Scheme) == 0 && len(u.Host) == 0 && u.User == nil
+}
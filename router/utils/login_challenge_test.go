@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsSafeRedirect(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		redirect string
+		expected bool
+	}{
+		{"/", true},
+		{"/channels/general", true},
+		{"/settings?tab=security#2fa", true},
+		{"", false},
+		{"channels/general", false},
+		{"https://example.com/", false},
+		{"javascript:alert(1)", false},
+		{"//example.com/", false},
+		{"/\\example.com/", false},
+		{"/\texample.com", false},
+		{"/%0a", true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, isSafeRedirect(c.redirect), c.redirect)
+	}
+}
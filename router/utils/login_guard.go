@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ログイン方法
+const (
+	LoginMethodV1             = "v1"
+	LoginMethodV3             = "v3"
+	LoginMethodOAuth2Password = "oauth2_password"
+	LoginMethodLDAP           = "ldap"
+	LoginMethodWebAuthn       = "webauthn"
+	LoginMethodInvitation     = "invitation"
+)
+
+// LoginBackoffPolicy ログイン試行失敗時のロックの方針
+type LoginBackoffPolicy struct {
+	// Threshold ロックが始まる連続失敗回数
+	Threshold int
+	// Base 初回のロック時間 (以降失敗するごとに2倍)
+	Base time.Duration
+	// Max ロック時間の上限
+	Max time.Duration
+}
+
+// LockDuration 連続失敗回数に応じたロック時間を返します
+func (p LoginBackoffPolicy) LockDuration(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < failures; i++ {
+		d *= 2
+		if d >= p.Max {
+			return p.Max
+		}
+	}
+	return d
+}
+
+var (
+	// AccountLoginBackoff アカウント単位のロックの方針
+	AccountLoginBackoff = LoginBackoffPolicy{Threshold: 5, Base: 30 * time.Second, Max: time.Hour}
+	// IPLoginBackoff IPアドレス単位のロックの方針
+	IPLoginBackoff = LoginBackoffPolicy{Threshold: 20, Base: time.Minute, Max: time.Hour}
+	// LoginFailureWindow 最後の失敗からこの時間が経過すると連続失敗回数がリセットされます
+	LoginFailureWindow = 24 * time.Hour
+)
+
+// LoginAttempt ログイン試行
+type LoginAttempt struct {
+	// Name 試行されたユーザー名
+	Name string
+	// User 対象ユーザー (存在しないユーザー名の場合はnil)
+	User model.UserInfo
+	// IP 試行元IPアドレス
+	IP string
+	// Method ログイン方法
+	Method string
+}
+
+func (a *LoginAttempt) userID() uuid.UUID {
+	if a.User == nil {
+		return uuid.Nil
+	}
+	return a.User.GetID()
+}
+
+// CheckLoginLock ログイン試行がロックされているかを確認します
+//
+// ロックされている場合は、ロック解除までの残り時間を返します。ロックされていない場合は0を返します。
+// ロックされている場合は監査ログを記録します。
+func CheckLoginLock(repo repository.Repository, attempt *LoginAttempt) (time.Duration, error) {
+	now := time.Now()
+	var remaining time.Duration
+	check := func(kind model.LoginLockKind, key string) error {
+		l, err := repo.GetLoginLock(kind, key)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		if l.IsLocked(now) {
+			if d := l.LockedUntil.Time.Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+		return nil
+	}
+
+	if len(attempt.IP) > 0 {
+		if err := check(model.LoginLockKindIP, attempt.IP); err != nil {
+			return 0, err
+		}
+	}
+	if id := attempt.userID(); id != uuid.Nil {
+		if err := check(model.LoginLockKindUser, id.String()); err != nil {
+			return 0, err
+		}
+	}
+
+	if remaining > 0 {
+		if err := createLoginAuditLog(repo, attempt, model.LoginFailureReasonLocked); err != nil {
+			return 0, err
+		}
+	}
+	return remaining, nil
+}
+
+// RecordLoginFailure ログイン試行の失敗を記録します
+//
+// IPアドレスとアカウントの連続失敗回数を増やし、必要に応じてロックします。
+func RecordLoginFailure(repo repository.Repository, attempt *LoginAttempt, reason string) error {
+	record := func(kind model.LoginLockKind, key string, policy LoginBackoffPolicy) error {
+		l, err := repo.IncrementLoginFailure(kind, key, LoginFailureWindow)
+		if err != nil {
+			return err
+		}
+		if d := policy.LockDuration(l.Failures); d > 0 {
+			return repo.SetLoginLockedUntil(kind, key, l.LastFailedAt.Add(d))
+		}
+		return nil
+	}
+
+	if len(attempt.IP) > 0 {
+		if err := record(model.LoginLockKindIP, attempt.IP, IPLoginBackoff); err != nil {
+			return err
+		}
+	}
+	if id := attempt.userID(); id != uuid.Nil {
+		if err := record(model.LoginLockKindUser, id.String(), AccountLoginBackoff); err != nil {
+			return err
+		}
+	}
+	return createLoginAuditLog(repo, attempt, reason)
+}
+
+// RecordLoginSuccess ログイン試行の成功を記録し、アカウントの連続失敗回数をリセットします
+//
+// IPアドレスの連続失敗回数はリセットしません。
+func RecordLoginSuccess(repo repository.Repository, userID uuid.UUID) error {
+	if err := repo.DeleteLoginLock(model.LoginLockKindUser, userID.String()); err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func createLoginAuditLog(repo repository.Repository, attempt *LoginAttempt, reason string) error {
+	name := attempt.Name
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return repo.CreateLoginAuditLog(&model.LoginAuditLog{
+		UserID: optional.NewUUID(attempt.userID(), attempt.userID() != uuid.Nil),
+		Name:   name,
+		IP:     attempt.IP,
+		Method: attempt.Method,
+		Reason: reason,
+	})
+}
+
+// SetRetryAfter Retry-Afterヘッダーにロック解除までの秒数を設定します
+func SetRetryAfter(c echo.Context, d time.Duration) {
+	c.Response().Header().Set(consts.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
+
+// LoginLockedError ログイン試行がロックされている場合のエラーを返します
+func LoginLockedError(c echo.Context, d time.Duration) error {
+	SetRetryAfter(c, d)
+	return herror.HTTPError(http.StatusTooManyRequests, "too many failed login attempts. try again later")
+}
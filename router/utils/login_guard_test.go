@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLoginBackoffPolicy_LockDuration(t *testing.T) {
+	t.Parallel()
+
+	p := LoginBackoffPolicy{Threshold: 3, Base: time.Second, Max: 10 * time.Second}
+	cases := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, p.LockDuration(c.failures), "failures: %d", c.failures)
+	}
+}
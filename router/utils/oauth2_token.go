@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	jwt2 "github.com/traPtitech/traQ/utils/jwt"
+	"strings"
+	"time"
+)
+
+// JWTAccessTokenType JWTアクセストークンのtypヘッダー (RFC 9068)
+const JWTAccessTokenType = "at+jwt"
+
+// AccessTokenClaims JWTアクセストークンのクレーム (RFC 9068)
+type AccessTokenClaims struct {
+	jwt.StandardClaims
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// SignAccessToken トークンに対応するJWTアクセストークンを発行します
+//
+// jtiにはトークンのIDを設定します。ユーザーのいないトークンのsubはクライアントIDになります。
+func SignAccessToken(token *model.OAuth2Token, issuer, audience string) (string, error) {
+	sub := token.ClientID
+	if token.UserID != uuid.Nil {
+		sub = token.UserID.String()
+	}
+	return jwt2.SignWithType(JWTAccessTokenType, AccessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  audience,
+			ExpiresAt: token.CreatedAt.Add(time.Duration(token.ExpiresIn) * time.Second).Unix(),
+			Id:        token.ID.String(),
+			IssuedAt:  token.CreatedAt.Unix(),
+			Issuer:    issuer,
+			Subject:   sub,
+		},
+		ClientID: token.ClientID,
+		Scope:    token.Scopes.String(),
+	})
+}
+
+// IsJWT JWTの形式の文字列かどうか
+func IsJWT(s string) bool {
+	return strings.Count(s, ".") == 2
+}
+
+// GetOAuth2Token アクセストークンに対応するOAuth2トークンを取得します
+//
+// 不透明なアクセストークンとJWTアクセストークンの両方に対応します。
+// JWTアクセストークンの場合も、失効を確認するためにリポジトリからトークンを取得します。
+// 存在しない、或いはJWTの検証に失敗した場合、repository.ErrNotFoundを返します。
+// DBによるエラーを返すことがあります。
+func GetOAuth2Token(repo repository.Repository, accessToken string) (*model.OAuth2Token, error) {
+	if !IsJWT(accessToken) {
+		return repo.GetTokenByAccess(accessToken)
+	}
+
+	var claims AccessTokenClaims
+	if err := jwt2.VerifyWithType(accessToken, JWTAccessTokenType, &claims); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	id, err := uuid.FromString(claims.Id)
+	if err != nil {
+		return nil, repository.ErrNotFound
+	}
+	token, err := repo.GetTokenByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if token.ClientID != claims.ClientID {
+		return nil, repository.ErrNotFound
+	}
+	return token, nil
+}
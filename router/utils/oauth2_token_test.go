@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	jwt2 "github.com/traPtitech/traQ/utils/jwt"
+	"github.com/traPtitech/traQ/utils/random"
+	"testing"
+	"time"
+)
+
+func TestSignAccessToken(t *testing.T) {
+	privRaw, _ := random.GenerateECDSAKey()
+	require.NoError(t, jwt2.SetupSigner(privRaw))
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	token := &model.OAuth2Token{
+		ID:          uuid.Must(uuid.NewV4()),
+		ClientID:    "client",
+		UserID:      uuid.Must(uuid.NewV4()),
+		AccessToken: random.SecureAlphaNumeric(36),
+		Scopes:      scopes,
+		ExpiresIn:   3600,
+		CreatedAt:   time.Now(),
+	}
+
+	s, err := SignAccessToken(token, "https://example.com/oauth2", "https://example.com")
+	require.NoError(t, err)
+	assert.True(t, IsJWT(s))
+	assert.False(t, IsJWT(token.AccessToken))
+
+	var claims AccessTokenClaims
+	if assert.NoError(t, jwt2.VerifyWithType(s, JWTAccessTokenType, &claims)) {
+		assert.Equal(t, token.ID.String(), claims.Id)
+		assert.Equal(t, token.UserID.String(), claims.Subject)
+		assert.Equal(t, "client", claims.ClientID)
+		assert.Equal(t, "read", claims.Scope)
+		assert.Equal(t, "https://example.com", claims.Audience)
+		assert.Equal(t, token.CreatedAt.Add(time.Hour).Unix(), claims.ExpiresAt)
+	}
+
+	// ユーザーのいないトークン
+	token.UserID = uuid.Nil
+	s, err = SignAccessToken(token, "https://example.com/oauth2", "https://example.com")
+	require.NoError(t, err)
+	claims = AccessTokenClaims{}
+	if assert.NoError(t, jwt2.Verify(s, &claims)) {
+		assert.Equal(t, "client", claims.Subject)
+	}
+
+	t.Run("GetOAuth2Token rejects other JWTs", func(t *testing.T) {
+		// IDトークンなどのアクセストークン以外のJWTはリポジトリを参照せずに拒否される
+		other, err := jwt2.Sign(jwt.MapClaims{"jti": token.ID.String(), "client_id": "client"})
+		require.NoError(t, err)
+		_, err = GetOAuth2Token(nil, other)
+		assert.Equal(t, repository.ErrNotFound, err)
+
+		_, err = GetOAuth2Token(nil, "a.b.c")
+		assert.Equal(t, repository.ErrNotFound, err)
+	})
+}
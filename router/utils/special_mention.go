@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/utils/message"
+)
+
+// CheckSpecialMention 特殊メンション(@here, @online, @channel)を含むメッセージをチャンネルに投稿できるかどうかを確認します
+//
+// チャンネルスコープのロールを考慮し、権限がない場合は403 Forbiddenを返します。
+// DMチャンネルでは特殊メンションによる通知が行われないため、確認しません。
+func CheckSpecialMention(r rbac.RBAC, repo repository.Repository, cm channel.Manager, user model.UserInfo, ch *model.Channel, content string) error {
+	if ch.IsDMChannel() {
+		return nil
+	}
+	parsed := message.Parse(content)
+	if !parsed.HereMention && !parsed.ChannelMention {
+		return nil
+	}
+	ok, err := rbac.IsGrantedInChannel(r, repo, cm.PublicChannelTree(), user, ch.ID, permission.UseSpecialMention)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if !ok {
+		return herror.Forbidden("you are not permitted to use special mentions (@here, @online, @channel) in this channel")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/totp"
+	"time"
+)
+
+// TwoFactorState ユーザーの二要素認証の状態
+type TwoFactorState int
+
+const (
+	// TwoFactorNone 二要素認証は不要
+	TwoFactorNone TwoFactorState = iota
+	// TwoFactorTOTP TOTPによる二要素認証が必要 (WebAuthnも登録されている場合はそれも使用可能)
+	TwoFactorTOTP
+	// TwoFactorWebAuthn WebAuthnによる二要素認証が必要
+	TwoFactorWebAuthn
+	// TwoFactorEnrollmentRequired ロールで二要素認証が必須だが、未登録
+	TwoFactorEnrollmentRequired
+)
+
+// GetTwoFactorState パスワードログイン時に必要な二要素認証の状態を返します
+func GetTwoFactorState(repo repository.Repository, user model.UserInfo) (TwoFactorState, error) {
+	t, err := repo.GetUserTOTP(user.GetID())
+	if err != nil && err != repository.ErrNotFound {
+		return TwoFactorNone, err
+	}
+	if t != nil && t.Enabled {
+		return TwoFactorTOTP, nil
+	}
+
+	hasWebAuthn, err := repo.HasWebAuthnCredentials(user.GetID())
+	if err != nil {
+		return TwoFactorNone, err
+	}
+	if hasWebAuthn {
+		return TwoFactorWebAuthn, nil
+	}
+
+	required, err := repo.IsTwoFactorRequired(user.GetRole())
+	if err != nil {
+		return TwoFactorNone, err
+	}
+	if required {
+		return TwoFactorEnrollmentRequired, nil
+	}
+	return TwoFactorNone, nil
+}
+
+// VerifyTwoFactorCode 有効なTOTPのコード、またはリカバリーコードを検証します
+//
+// codeが空でない場合はTOTPのコードを、そうでない場合はrecoveryCodeを検証します。
+// 検証に成功したコードは再利用できなくなります。
+func VerifyTwoFactorCode(repo repository.Repository, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	if len(code) > 0 {
+		t, err := repo.GetUserTOTP(userID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		if !t.Enabled {
+			return false, nil
+		}
+		step, ok := totp.Validate(code, t.Secret, t.LastStep, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return repo.UpdateUserTOTPLastStep(userID, step)
+	}
+	if len(recoveryCode) > 0 {
+		return repo.UseUserRecoveryCode(userID, totp.HashRecoveryCode(recoveryCode))
+	}
+	return false, nil
+}
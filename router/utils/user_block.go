@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// GetBlockedUserIDs userIDのユーザーがブロックしているユーザーのIDのセットを返します
+//
+// ミュートしているユーザーは含みません。
+// ブロックしているユーザーのメッセージは、クライアントで折りたたんで表示されます。
+func GetBlockedUserIDs(repo repository.Repository, userID uuid.UUID) (set.UUID, error) {
+	blocks, err := repo.GetUserBlocks(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := set.UUID{}
+	for _, b := range blocks {
+		if b.Type == model.UserBlockTypeBlock {
+			result.Add(b.TargetID)
+		}
+	}
+	return result, nil
+}
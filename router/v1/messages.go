@@ -7,6 +7,8 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/utils/optional"
 	"net/http"
 	"strconv"
@@ -16,7 +18,15 @@ import (
 // GetMessageByID GET /messages/:messageID
 func (h *Handlers) GetMessageByID(c echo.Context) error {
 	m := getMessageFromContext(c)
-	return c.JSON(http.StatusOK, formatMessage(m))
+	res := formatMessage(m)
+
+	blocked, err := utils.GetBlockedUserIDs(h.Repo, getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	res.Blocked = blocked.Contains(m.UserID)
+
+	return c.JSON(http.StatusOK, res)
 }
 
 // PutMessageByIDRequest PUT /messages/:messageID リクエストボディ
@@ -54,6 +64,10 @@ func (h *Handlers) PutMessageByID(c echo.Context) error {
 		return herror.Forbidden("This is not your message")
 	}
 
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, getRequestUser(c), ch, req.Text); err != nil {
+		return err
+	}
+
 	if err := h.Repo.UpdateMessage(m.ID, req.Text); err != nil {
 		return herror.InternalServerError(err)
 	}
@@ -146,10 +160,24 @@ func (h *Handlers) PostMessage(c echo.Context) error {
 		return err
 	}
 
+	if ch.IsDMChannel() {
+		blocked, err := h.ChannelManager.IsDMBlocked(userID, ch.ID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if blocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
+	}
+
 	if req.Embed {
 		req.Text = h.Replacer.Replace(req.Text)
 	}
 
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, getRequestUser(c), ch, req.Text); err != nil {
+		return err
+	}
+
 	m, err := h.Repo.CreateMessage(userID, ch.ID, req.Text)
 	if err != nil {
 		return herror.InternalServerError(err)
@@ -171,6 +199,9 @@ func (h *Handlers) GetDirectMessages(c echo.Context) error {
 	// DMチャンネルを取得
 	ch, err := h.ChannelManager.GetDMChannel(myID, targetID)
 	if err != nil {
+		if err == channel.ErrUserBlocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
 		return herror.InternalServerError(err)
 	}
 
@@ -199,9 +230,21 @@ func (h *Handlers) PostDirectMessage(c echo.Context) error {
 		return err
 	}
 
+	// ブロックの確認
+	blocked, err := h.Repo.IsBlockedBetween(myID, targetID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if blocked {
+		return herror.Forbidden("direct messages with this user are blocked")
+	}
+
 	// DMチャンネルを取得
 	ch, err := h.ChannelManager.GetDMChannel(myID, targetID)
 	if err != nil {
+		if err == channel.ErrUserBlocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
 		return herror.InternalServerError(err)
 	}
 
@@ -332,6 +375,15 @@ func (h *Handlers) getMessages(c echo.Context, query repository.MessagesQuery, f
 	}
 	res := formatMessages(messages)
 
+	// ブロックしているユーザーのメッセージ
+	blocked, err := utils.GetBlockedUserIDs(h.Repo, getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	for _, v := range res {
+		v.Blocked = blocked.Contains(v.UserID)
+	}
+
 	if filterByReport {
 		userID := getRequestUserID(c)
 
@@ -134,6 +134,7 @@ type messageResponse struct {
 	UpdatedAt       time.Time            `json:"updatedAt"`
 	Pin             bool                 `json:"pin"`
 	Reported        bool                 `json:"reported"`
+	Blocked         bool                 `json:"blocked"`
 	StampList       []model.MessageStamp `json:"stampList"`
 }
 
@@ -69,7 +69,7 @@ func TestMain(m *testing.M) {
 		env.Hub = hub.New()
 		env.SessStore = session.NewMemorySessionStore()
 		env.RBAC = testutils.NewTestRBAC()
-		env.ChannelManager, _ = channel.InitChannelManager(env.Repository, zap.NewNop())
+		env.ChannelManager, _ = channel.InitChannelManager(env.Repository, env.Repository, zap.NewNop())
 
 		e := echo.New()
 		e.HideBanner = true
@@ -31,15 +31,27 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 		return err
 	}
 
+	attempt := &utils.LoginAttempt{Name: req.Name, IP: c.RealIP(), Method: utils.LoginMethodV1}
 	user, err := h.Repo.GetUserByName(req.Name, false)
-	if err != nil {
-		switch err {
-		case repository.ErrNotFound:
-			h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
-		default:
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	attempt.User = user
+
+	// ログイン試行のロック確認
+	if d, err := utils.CheckLoginLock(h.Repo, attempt); err != nil {
+		return herror.InternalServerError(err)
+	} else if d > 0 {
+		h.L(c).Info("an api login attempt failed: locked", zap.String("username", req.Name), zap.String("ip", attempt.IP))
+		return utils.LoginLockedError(c, d)
+	}
+
+	if user == nil {
+		h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
+		if err := utils.RecordLoginFailure(h.Repo, attempt, model.LoginFailureReasonUnknownUser); err != nil {
 			return herror.InternalServerError(err)
 		}
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
 	}
 
 	// ユーザーのアカウント状態の確認
@@ -51,15 +63,29 @@ func (h *Handlers) PostLogin(c echo.Context) error {
 	// パスワード検証
 	if err := user.Authenticate(req.Pass); err != nil {
 		h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
+		if err := utils.RecordLoginFailure(h.Repo, attempt, model.LoginFailureReasonWrongPassword); err != nil {
+			return herror.InternalServerError(err)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err)
 	}
+
+	// 二要素認証が必要なアカウントはv3でのみログイン可能
+	if state, err := utils.GetTwoFactorState(h.Repo, user); err != nil {
+		return herror.InternalServerError(err)
+	} else if state != utils.TwoFactorNone {
+		h.L(c).Info("an api login attempt failed: two-factor authentication required", zap.String("username", req.Name))
+		return herror.Forbidden("two-factor authentication is required for this account. use /api/v3/login")
+	}
 	h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 
-	if _, err := h.SessStore.RenewSession(c, user.GetID()); err != nil {
+	if err := utils.RecordLoginSuccess(h.Repo, user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if _, err := h.SessStore.RenewSession(c, user.GetID(), utils.LoginMethodV1); err != nil {
 		return herror.InternalServerError(err)
 	}
 
-	if redirect := c.QueryParam("redirect"); len(redirect) > 0 {
+	if redirect := utils.LoginRedirect(c); len(redirect) > 0 {
 		return c.Redirect(http.StatusFound, redirect)
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -70,7 +96,7 @@ func (h *Handlers) PostLogout(c echo.Context) error {
 	if err := h.SessStore.RevokeSession(c); err != nil {
 		return herror.InternalServerError(err)
 	}
-	if redirect := c.QueryParam("redirect"); len(redirect) > 0 {
+	if redirect := utils.LoginRedirect(c); len(redirect) > 0 {
 		return c.Redirect(http.StatusFound, redirect)
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -134,7 +160,12 @@ func (h *Handlers) PatchUserByID(c echo.Context) error {
 	}
 
 	if err := h.Repo.UpdateUser(userID, repository.UpdateUserArgs{DisplayName: req.DisplayName, TwitterID: req.TwitterID, Role: req.Role}); err != nil {
-		return herror.InternalServerError(err)
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -198,6 +198,14 @@ func (h *Handlers) PostWebhook(c echo.Context) error {
 		body = []byte(h.Replacer.Replace(string(body)))
 	}
 
+	botUser, err := h.Repo.GetUser(w.GetBotUserID(), false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, botUser, ch, string(body)); err != nil {
+		return err
+	}
+
 	if _, err := h.Repo.CreateMessage(w.GetBotUserID(), ch.ID, string(body)); err != nil {
 		return herror.InternalServerError(err)
 	}
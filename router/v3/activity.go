@@ -13,7 +13,7 @@ import (
 
 // GetOnlineUsers GET /activity/onlines
 func (h *Handlers) GetOnlineUsers(c echo.Context) error {
-	return c.JSON(http.StatusOK, h.OC.GetOnlineUserIDs())
+	return c.JSON(http.StatusOK, h.Presence.GetOnlineUserIDs())
 }
 
 // GetActivityTimelineRequest GET /activity/timeline リクエストボディ
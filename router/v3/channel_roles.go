@@ -0,0 +1,126 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+)
+
+// GetChannelRoleBindings GET /channels/:channelID/roles
+func (h *Handlers) GetChannelRoleBindings(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	// 祖先チャンネルで割り当てられたロールも含める
+	channelIDs := append([]uuid.UUID{channelID}, h.ChannelManager.PublicChannelTree().GetAscendantIDs(channelID)...)
+	bindings := make([]*model.ChannelRoleBinding, 0)
+	for _, id := range channelIDs {
+		bs, err := h.Repo.GetChannelRoleBindings(id)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		bindings = append(bindings, bs...)
+	}
+	return c.JSON(http.StatusOK, formatChannelRoleBindings(bindings))
+}
+
+// PutChannelRoleBindingRequest PUT /channels/:channelID/roles/:userID リクエストボディ
+type PutChannelRoleBindingRequest struct {
+	Role string `json:"role"`
+}
+
+func (r PutChannelRoleBindingRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Role, validator.RoleNameRuleRequired...),
+	)
+}
+
+// SetChannelRoleBinding PUT /channels/:channelID/roles/:userID
+func (h *Handlers) SetChannelRoleBinding(c echo.Context) error {
+	ch := getParamChannel(c)
+	userID := getParamAsUUID(c, consts.ParamUserID)
+
+	var req PutChannelRoleBindingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if !h.ChannelManager.PublicChannelTree().IsChannelPresent(ch.ID) {
+		return herror.BadRequest("roles can be bound to public channels only")
+	}
+	if err := h.checkChannelRoleGrantable(c, ch.ID, req.Role); err != nil {
+		return err
+	}
+
+	if err := h.Repo.SetChannelRoleBinding(ch.ID, userID, req.Role); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteChannelRoleBinding DELETE /channels/:channelID/roles/:userID
+func (h *Handlers) DeleteChannelRoleBinding(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+	userID := getParamAsUUID(c, consts.ParamUserID)
+
+	bindings, err := h.Repo.GetChannelRoleBindings(channelID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	var binding *model.ChannelRoleBinding
+	for _, b := range bindings {
+		if b.UserID == userID {
+			binding = b
+			break
+		}
+	}
+	if binding == nil {
+		return herror.NotFound()
+	}
+	if err := h.checkChannelRoleGrantable(c, channelID, binding.Role); err != nil {
+		return err
+	}
+
+	if err := h.Repo.DeleteChannelRoleBinding(channelID, userID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkChannelRoleGrantable リクエストしたユーザーが指定したチャンネルでロールを割り当て・解除できるかどうかを確認します
+//
+// 自分がそのチャンネルで持っていない権限を含むロールは扱えません。
+func (h *Handlers) checkChannelRoleGrantable(c echo.Context, channelID uuid.UUID, role string) error {
+	perms, err := rbac.GetGrantedPermissionsInChannel(h.RBAC, h.Repo, h.ChannelManager.PublicChannelTree(), getRequestUser(c), channelID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return checkPermissionsGrantable(perms, h.RBAC.GetGrantedPermissions(role))
+}
+
+// checkPermissionsGrantable 付与しようとしている権限が全て自分の持つ権限に含まれているかどうかを確認します
+func checkPermissionsGrantable(granted permission.Permissions, perms []permission.Permission) error {
+	for _, p := range perms {
+		if !granted.Contains(p) {
+			return herror.Forbidden("you cannot manage the role which has permissions you don't have")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package v3
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func mustMakeChannelTree(t *testing.T, env *Env, creatorID uuid.UUID) (parent, child, sibling *model.Channel) {
+	t.Helper()
+	parent, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, creatorID)
+	require.NoError(t, err)
+	child, err = env.CM.CreatePublicChannel(random.AlphaNumeric(20), parent.ID, creatorID)
+	require.NoError(t, err)
+	sibling, err = env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, creatorID)
+	require.NoError(t, err)
+	return
+}
+
+func TestHandlers_GetChannelRoleBindings(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/channels/{channelId}/roles"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	lead := env.CreateUser(t, rand)
+	parent, child, sibling := mustMakeChannelTree(t, env, user.GetID())
+	require.NoError(t, env.Repository.SetChannelRoleBinding(parent.ID, lead.GetID(), role.ChannelLead))
+	s := env.S(t, user.GetID())
+
+	t.Run("inherited", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET(path, child.ID).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		obj.Length().Equal(1)
+		b := obj.First().Object()
+		b.Value("channelId").String().Equal(parent.ID.String())
+		b.Value("userId").String().Equal(lead.GetID().String())
+		b.Value("role").String().Equal(role.ChannelLead)
+	})
+
+	t.Run("other subtree", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path, sibling.ID).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array().
+			Empty()
+	})
+}
+
+func TestHandlers_SetChannelRoleBinding(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/channels/{channelId}/roles/{userId}"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	lead := env.CreateUser(t, rand)
+	parent, child, sibling := mustMakeChannelTree(t, env, user.GetID())
+	require.NoError(t, env.Repository.SetChannelRoleBinding(parent.ID, lead.GetID(), role.ChannelLead))
+	userSession := env.S(t, user.GetID())
+	leadSession := env.S(t, lead.GetID())
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.PUT(path, child.ID, target.GetID()).
+			WithCookie(session.CookieName, userSession).
+			WithJSON(&PutChannelRoleBindingRequest{Role: role.ChannelLead}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("outside of the lead's subtree", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.PUT(path, sibling.ID, target.GetID()).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(&PutChannelRoleBindingRequest{Role: role.ChannelLead}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("role with permissions the lead doesn't have", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.PUT(path, child.ID, target.GetID()).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(&PutChannelRoleBindingRequest{Role: role.Moderator}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		target := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.PUT(path, child.ID, target.GetID()).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(&PutChannelRoleBindingRequest{Role: role.ChannelLead}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		bindings, err := env.Repository.GetChannelRoleBindingsByUserID(target.GetID())
+		require.NoError(t, err)
+		if assert.Len(t, bindings, 1) {
+			assert.Equal(t, child.ID, bindings[0].ChannelID)
+			assert.Equal(t, role.ChannelLead, bindings[0].Role)
+		}
+
+		e.DELETE(path, child.ID, target.GetID()).
+			WithCookie(session.CookieName, leadSession).
+			Expect().
+			Status(http.StatusNoContent)
+
+		bindings, err = env.Repository.GetChannelRoleBindingsByUserID(target.GetID())
+		require.NoError(t, err)
+		assert.Empty(t, bindings)
+	})
+}
+
+func TestHandlers_ChannelScopedPermissions(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	lead := env.CreateUser(t, rand)
+	parent, child, sibling := mustMakeChannelTree(t, env, user.GetID())
+	require.NoError(t, env.Repository.SetChannelRoleBinding(parent.ID, lead.GetID(), role.ChannelLead))
+	userSession := env.S(t, user.GetID())
+	leadSession := env.S(t, lead.GetID())
+
+	t.Run("edit channel", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.PATCH("/api/v3/channels/{channelId}", child.ID).
+			WithCookie(session.CookieName, userSession).
+			WithJSON(map[string]interface{}{"force": true}).
+			Expect().
+			Status(http.StatusForbidden)
+		e.PATCH("/api/v3/channels/{channelId}", sibling.ID).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(map[string]interface{}{"force": true}).
+			Expect().
+			Status(http.StatusForbidden)
+		e.PATCH("/api/v3/channels/{channelId}", child.ID).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(map[string]interface{}{"force": true}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		// 権限のないチャンネルの下には移動できない
+		e.PATCH("/api/v3/channels/{channelId}", child.ID).
+			WithCookie(session.CookieName, leadSession).
+			WithJSON(map[string]interface{}{"parent": sibling.ID}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("delete others' message", func(t *testing.T) {
+		t.Parallel()
+		m1, err := env.Repository.CreateMessage(user.GetID(), child.ID, "a")
+		require.NoError(t, err)
+		m2, err := env.Repository.CreateMessage(user.GetID(), sibling.ID, "b")
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.DELETE("/api/v3/messages/{messageId}", m2.ID).
+			WithCookie(session.CookieName, leadSession).
+			Expect().
+			Status(http.StatusForbidden)
+		e.DELETE("/api/v3/messages/{messageId}", m1.ID).
+			WithCookie(session.CookieName, leadSession).
+			Expect().
+			Status(http.StatusNoContent)
+
+		_, err = env.Repository.GetMessageByID(m1.ID)
+		assert.Equal(t, repository.ErrNotFound, err)
+	})
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/set"
@@ -106,8 +108,20 @@ func (h *Handlers) EditChannel(c echo.Context) error {
 		return err
 	}
 
+	// チャンネルスコープのロールで編集する場合は、移動先でも編集権限が必要
+	user := getRequestUser(c)
+	if req.Parent.Valid && !h.RBAC.IsGranted(user.GetRole(), permission.EditChannel) {
+		ok, err := rbac.IsGrantedInChannel(h.RBAC, h.Repo, h.ChannelManager.PublicChannelTree(), user, req.Parent.UUID, permission.EditChannel)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if req.Parent.UUID == uuid.Nil || !ok {
+			return herror.Forbidden("you are not permitted to move the channel to the parent")
+		}
+	}
+
 	args := repository.UpdateChannelArgs{
-		UpdaterID:          getRequestUserID(c),
+		UpdaterID:          user.GetID(),
 		Name:               req.Name,
 		Visibility:         optional.NewBool(!req.Archived.Bool, req.Archived.Valid),
 		ForcedNotification: req.Force,
@@ -196,7 +210,15 @@ func (h *Handlers) GetChannelPins(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
-	return c.JSON(http.StatusOK, formatPins(pins))
+	res := formatPins(pins)
+	messages := make([]*Message, len(res))
+	for i, p := range res {
+		messages[i] = p.Message
+	}
+	if err := markBlockedMessages(h.Repo, getRequestUserID(c), messages...); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 type channelEventsQuery struct {
@@ -361,6 +383,9 @@ func (h *Handlers) GetUserDMChannel(c echo.Context) error {
 	// DMチャンネルを取得
 	ch, err := h.ChannelManager.GetDMChannel(myID, userID)
 	if err != nil {
+		if err == channel.ErrUserBlocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
 		return herror.InternalServerError(err)
 	}
 
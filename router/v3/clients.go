@@ -18,8 +18,17 @@ import (
 func (h *Handlers) GetClients(c echo.Context) error {
 	var q repository.GetClientsQuery
 
-	if !isTrue(c.QueryParam("all")) {
-		q = q.IsDevelopedBy(getRequestUserID(c))
+	if isTrue(c.QueryParam("pending")) {
+		// 承認待ちのクライアントは管理者のみ取得可能
+		if !h.RBAC.IsGranted(getRequestUser(c).GetRole(), permission.ManageOthersClient) {
+			return herror.Forbidden()
+		}
+		q = q.IsPending()
+	} else {
+		q.Pending = optional.BoolFrom(false)
+		if !isTrue(c.QueryParam("all")) {
+			q = q.IsDevelopedBy(getRequestUserID(c))
+		}
 	}
 
 	ocs, err := h.Repo.GetClients(q)
@@ -89,10 +98,12 @@ func (h *Handlers) GetClient(c echo.Context) error {
 
 // PatchClientRequest PATCH /clients/:clientID リクエストボディ
 type PatchClientRequest struct {
-	Name        optional.String `json:"name"`
-	Description optional.String `json:"description"`
-	CallbackURL optional.String `json:"callbackUrl"`
-	DeveloperID optional.UUID   `json:"developerId"`
+	Name        optional.String    `json:"name"`
+	Description optional.String    `json:"description"`
+	CallbackURL optional.String    `json:"callbackUrl"`
+	DeveloperID optional.UUID      `json:"developerId"`
+	Scopes      model.AccessScopes `json:"scopes"`
+	RequirePKCE optional.Bool      `json:"requirePkce"`
 }
 
 func (r PatchClientRequest) Validate() error {
@@ -101,6 +112,7 @@ func (r PatchClientRequest) Validate() error {
 		vd.Field(&r.Description, vd.RuneLength(1, 1000)),
 		vd.Field(&r.CallbackURL, is.URL),
 		vd.Field(&r.DeveloperID, validator.NotNilUUID),
+		vd.Field(&r.Scopes, vd.NilOrNotEmpty),
 	)
 }
 
@@ -118,14 +130,77 @@ func (h *Handlers) EditClient(c echo.Context) error {
 		Description: req.Description,
 		DeveloperID: req.DeveloperID,
 		CallbackURL: req.CallbackURL,
+		Scopes:      req.Scopes,
+		RequirePKCE: req.RequirePKCE,
 	}
 	if err := h.Repo.UpdateClient(oc.ID, args); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RotateClientSecret POST /clients/:clientID/secret
+func (h *Handlers) RotateClientSecret(c echo.Context) error {
+	oc := getParamClient(c)
+
+	// 古いシークレットは即座に無効になります
+	oc.Secret = random.SecureAlphaNumeric(36)
+	if err := h.Repo.UpdateClient(oc.ID, repository.UpdateClientArgs{Secret: optional.StringFrom(oc.Secret)}); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusOK, formatOAuth2ClientDetail(oc))
+}
+
+// ApproveClient POST /clients/:clientID/approve
+func (h *Handlers) ApproveClient(c echo.Context) error {
+	oc := getParamClient(c)
+
+	if !oc.Pending {
+		return herror.BadRequest("the client is not pending approval")
+	}
+	if err := h.Repo.UpdateClient(oc.ID, repository.UpdateClientArgs{Pending: optional.BoolFrom(false)}); err != nil {
 		return herror.InternalServerError(err)
 	}
 
 	return c.NoContent(http.StatusNoContent)
 }
 
+// PostClientInitialAccessTokenRequest POST /clients/initial-access-tokens リクエストボディ
+type PostClientInitialAccessTokenRequest struct {
+	ExpiresIn int `json:"expiresIn"`
+}
+
+func (r PostClientInitialAccessTokenRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.ExpiresIn, vd.Required, vd.Min(60), vd.Max(60*60*24*7)),
+	)
+}
+
+// CreateClientInitialAccessToken POST /clients/initial-access-tokens
+func (h *Handlers) CreateClientInitialAccessToken(c echo.Context) error {
+	var req PostClientInitialAccessTokenRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	t, err := h.Repo.CreateInitialAccessToken(getRequestUserID(c), req.ExpiresIn)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"token":     t.Token,
+		"expiresAt": t.ExpiresAt,
+	})
+}
+
 // DeleteClient DELETE /clients/:clientID
 func (h *Handlers) DeleteClient(c echo.Context) error {
 	oc := getParamClient(c)
@@ -155,7 +155,11 @@ func (h *Handlers) PostClipFolderMessage(c echo.Context) error {
 	}
 	cfm.Message = *m
 
-	return c.JSON(http.StatusOK, formatClipFolderMessage(cfm))
+	res := formatClipFolderMessage(cfm)
+	if err := markBlockedMessages(h.Repo, getRequestUserID(c), res.Message); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 type clipFolderMessageQuery struct {
@@ -196,9 +200,18 @@ func (h *Handlers) GetClipFolderMessages(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
+	res := formatClipFolderMessages(messages)
+	ms := make([]*Message, len(res))
+	for i, cfm := range res {
+		ms[i] = cfm.Message
+	}
+	if err := markBlockedMessages(h.Repo, getRequestUserID(c), ms...); err != nil {
+		return herror.InternalServerError(err)
+	}
+
 	c.Response().Header().Set(consts.HeaderMore, strconv.FormatBool(more))
 
-	return c.JSON(http.StatusOK, formatClipFolderMessages(messages))
+	return c.JSON(http.StatusOK, res)
 }
 
 // DeleteClipFolderMessages DELETE /clip-folders/:folderID/messages/:messageID
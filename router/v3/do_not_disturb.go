@@ -0,0 +1,103 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"net/http"
+	"time"
+)
+
+// GetMyDoNotDisturbSetting GET /users/me/do-not-disturb
+func (h *Handlers) GetMyDoNotDisturbSetting(c echo.Context) error {
+	s, err := h.Repo.GetDoNotDisturbSetting(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatDoNotDisturbSetting(s))
+}
+
+// PatchMyDoNotDisturbSettingRequest PATCH /users/me/do-not-disturb リクエストボディ
+type PatchMyDoNotDisturbSettingRequest struct {
+	Enabled        optional.Bool              `json:"enabled"`
+	Timezone       optional.String            `json:"timezone"`
+	Schedule       model.DoNotDisturbSchedule `json:"schedule"`
+	AllowedUsers   []uuid.UUID                `json:"allowedUsers"`
+	UrgentChannels []uuid.UUID                `json:"urgentChannels"`
+}
+
+func (r PatchMyDoNotDisturbSettingRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Timezone, vd.By(func(value interface{}) error {
+			tz := value.(optional.String)
+			if !tz.Valid {
+				return nil
+			}
+			if _, err := time.LoadLocation(tz.String); err != nil || len(tz.String) == 0 {
+				return vd.NewError("validation_invalid_timezone", "must be a valid IANA time zone name")
+			}
+			return nil
+		})),
+		vd.Field(&r.Schedule, vd.By(func(value interface{}) error {
+			// model.DoNotDisturbScheduleはValuerを実装しているため、vd.Lengthは使えない
+			s := value.(model.DoNotDisturbSchedule)
+			if len(s) > 50 {
+				return vd.NewError("validation_length_too_long", "the length must be no more than 50")
+			}
+			return s.Validate()
+		})),
+		vd.Field(&r.AllowedUsers, vd.Length(0, 100)),
+		vd.Field(&r.UrgentChannels, vd.Length(0, 100)),
+	)
+}
+
+// EditMyDoNotDisturbSetting PATCH /users/me/do-not-disturb
+func (h *Handlers) EditMyDoNotDisturbSetting(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PatchMyDoNotDisturbSettingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	args := repository.UpdateDoNotDisturbSettingArgs{
+		Enabled:  req.Enabled,
+		Timezone: req.Timezone,
+		Schedule: req.Schedule,
+	}
+	if req.AllowedUsers != nil {
+		args.AllowedUsers = model.UUIDs{}
+		for _, id := range req.AllowedUsers {
+			if ok, err := h.Repo.UserExists(id); err != nil {
+				return herror.InternalServerError(err)
+			} else if !ok {
+				return herror.BadRequest("invalid allowedUsers")
+			}
+			args.AllowedUsers = append(args.AllowedUsers, id)
+		}
+	}
+	if req.UrgentChannels != nil {
+		// 緊急指定できるのは強制通知チャンネルのみ
+		args.UrgentChannels = model.UUIDs{}
+		for _, id := range req.UrgentChannels {
+			if !h.ChannelManager.PublicChannelTree().IsForceChannel(id) {
+				return herror.BadRequest("urgentChannels must be forced notification channels")
+			}
+			args.UrgentChannels = append(args.UrgentChannels, id)
+		}
+	}
+
+	if err := h.Repo.UpdateDoNotDisturbSetting(userID, args); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
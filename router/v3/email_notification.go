@@ -0,0 +1,103 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"net/http"
+)
+
+// GetMyEmailNotificationSetting GET /users/me/email-notification
+func (h *Handlers) GetMyEmailNotificationSetting(c echo.Context) error {
+	s, err := h.Repo.GetEmailNotificationSetting(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatEmailNotificationSetting(s))
+}
+
+// PatchMyEmailNotificationSettingRequest PATCH /users/me/email-notification リクエストボディ
+type PatchMyEmailNotificationSettingRequest struct {
+	Address optional.String `json:"address"`
+	Enabled optional.Bool   `json:"enabled"`
+	Digest  optional.String `json:"digest"`
+}
+
+func (r PatchMyEmailNotificationSettingRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Address, vd.RuneLength(0, 254), is.EmailFormat),
+		vd.Field(&r.Digest, vd.By(func(value interface{}) error {
+			d := value.(optional.String)
+			if d.Valid && !model.EmailDigestInterval(d.String).Valid() {
+				return vd.NewError("validation_invalid_digest", "must be one of none, hourly, daily")
+			}
+			return nil
+		})),
+	)
+}
+
+// EditMyEmailNotificationSetting PATCH /users/me/email-notification
+//
+// 通知先アドレスの変更は確認メールのリンクが開かれるまで反映されません。
+func (h *Handlers) EditMyEmailNotificationSetting(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PatchMyEmailNotificationSettingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	args := repository.UpdateEmailNotificationSettingArgs{
+		Enabled: req.Enabled,
+		Digest:  req.Digest,
+	}
+	// 通知先アドレスの解除は確認不要
+	if req.Address.Valid && len(req.Address.String) == 0 {
+		args.Address = req.Address
+	}
+	if err := h.Repo.UpdateEmailNotificationSetting(userID, args); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	if req.Address.Valid && len(req.Address.String) > 0 {
+		s, err := h.Repo.GetEmailNotificationSetting(userID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if s.Address != req.Address.String {
+			v, err := h.Repo.CreateEmailAddressVerification(userID, req.Address.String)
+			if err != nil {
+				switch {
+				case repository.IsArgError(err):
+					return herror.BadRequest(err)
+				default:
+					return herror.InternalServerError(err)
+				}
+			}
+			h.Email.SendVerification(v.Address, v.Token)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// VerifyEmailNotificationAddress GET /email-notification/verify
+func (h *Handlers) VerifyEmailNotificationAddress(c echo.Context) error {
+	if _, err := h.Repo.VerifyEmailAddress(c.QueryParam("token")); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound("the verification link is invalid or has expired")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.Redirect(http.StatusFound, "/settings/notification")
+}
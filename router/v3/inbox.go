@@ -0,0 +1,75 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"net/http"
+	"strconv"
+)
+
+// GetMyInboxRequest GET /users/me/inbox リクエストクエリ
+type GetMyInboxRequest struct {
+	Types  []string `query:"type"`
+	Unread bool     `query:"unread"`
+	Limit  int      `query:"limit"`
+	Offset int      `query:"offset"`
+}
+
+func (q *GetMyInboxRequest) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+	return vd.ValidateStruct(q,
+		vd.Field(&q.Types, vd.Each(vd.By(func(value interface{}) error {
+			if !model.InboxEntryType(value.(string)).Valid() {
+				return vd.NewError("validation_invalid_inbox_type", "must be a valid inbox entry type")
+			}
+			return nil
+		}))),
+		vd.Field(&q.Limit, vd.Min(1), vd.Max(200)),
+		vd.Field(&q.Offset, vd.Min(0)),
+	)
+}
+
+// GetMyInbox GET /users/me/inbox
+func (h *Handlers) GetMyInbox(c echo.Context) error {
+	var req GetMyInboxRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	q := repository.InboxEntriesQuery{
+		UnreadOnly: req.Unread,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+	}
+	for _, t := range req.Types {
+		q.Types = append(q.Types, model.InboxEntryType(t))
+	}
+
+	entries, more, err := h.Repo.GetInboxEntries(getRequestUserID(c), q)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	c.Response().Header().Set(consts.HeaderMore, strconv.FormatBool(more))
+	return c.JSON(http.StatusOK, formatInboxEntries(entries))
+}
+
+// ReadMyInboxEntry POST /users/me/inbox/:entryID/read
+func (h *Handlers) ReadMyInboxEntry(c echo.Context) error {
+	entryID := getParamAsUUID(c, consts.ParamInboxEntryID)
+
+	if err := h.Repo.ReadInboxEntry(getRequestUserID(c), entryID); err != nil {
+		switch err {
+		case repository.ErrNilID, repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
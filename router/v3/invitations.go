@@ -0,0 +1,341 @@
+package v3
+
+import (
+	"fmt"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"github.com/traPtitech/traQ/utils/validator"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	// groupAdminInvitationMaxUses 全ての招待リンクの管理権限がない場合の招待リンクの最大使用回数の上限
+	groupAdminInvitationMaxUses = 50
+	// groupAdminInvitationMaxAge 全ての招待リンクの管理権限がない場合の招待リンクの有効期間の上限
+	groupAdminInvitationMaxAge = 7 * 24 * time.Hour
+)
+
+// GetInvitations GET /invitations
+func (h *Handlers) GetInvitations(c echo.Context) error {
+	user := getRequestUser(c)
+
+	creatorID := user.GetID()
+	if h.RBAC.IsGranted(user.GetRole(), permission.ManageInvitation) {
+		creatorID = uuid.Nil
+	}
+	invs, err := h.Repo.GetInvitations(creatorID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatInvitations(invs))
+}
+
+// PostInvitationChannel POST /invitations リクエストボディのチャンネル購読設定
+type PostInvitationChannel struct {
+	ChannelID uuid.UUID `json:"channelId"`
+	Level     int       `json:"level"`
+}
+
+func (r PostInvitationChannel) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.ChannelID, vd.Required, validator.NotNilUUID),
+		vd.Field(&r.Level, vd.Min(0), vd.Max(2)),
+	)
+}
+
+// PostInvitationRequest POST /invitations リクエストボディ
+type PostInvitationRequest struct {
+	Role      optional.String         `json:"role"`
+	MaxUses   int                     `json:"maxUses"`
+	ExpiresAt optional.Time           `json:"expiresAt"`
+	Groups    set.UUID                `json:"groups"`
+	Channels  []PostInvitationChannel `json:"channels"`
+}
+
+func (r PostInvitationRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Role, vd.RuneLength(1, 30)),
+		vd.Field(&r.MaxUses, vd.Min(0), vd.Max(10000)),
+		vd.Field(&r.ExpiresAt, vd.By(func(interface{}) error {
+			if r.ExpiresAt.Valid && !r.ExpiresAt.Time.After(time.Now()) {
+				return vd.NewError("validation_invalid_expires_at", "must be in the future")
+			}
+			return nil
+		})),
+		vd.Field(&r.Groups, vd.Length(0, 20)),
+		vd.Field(&r.Channels, vd.Length(0, 50)),
+	)
+}
+
+// CreateInvitation POST /invitations
+func (h *Handlers) CreateInvitation(c echo.Context) error {
+	user := getRequestUser(c)
+
+	var req PostInvitationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	// 全ての招待リンクの管理権限がない場合は、自分が管理者のグループへの一般ユーザーの、使用回数と有効期限が制限された招待のみ許可
+	manage := h.RBAC.IsGranted(user.GetRole(), permission.ManageInvitation)
+	r := req.Role.ValueOrZero()
+	if len(r) == 0 {
+		r = role.User
+	}
+	if manage {
+		// 自分より強いロールでの招待は不可
+		if err := h.checkInvitationRoleGrantable(user, r); err != nil {
+			return err
+		}
+	} else {
+		if r != role.User {
+			return herror.Forbidden("you are not permitted to invite users with the role")
+		}
+		if len(req.Groups) == 0 {
+			return herror.Forbidden("you must specify groups you administer")
+		}
+		if req.MaxUses <= 0 || req.MaxUses > groupAdminInvitationMaxUses {
+			return herror.BadRequest(fmt.Sprintf("maxUses must be between 1 and %d", groupAdminInvitationMaxUses))
+		}
+		if !req.ExpiresAt.Valid || req.ExpiresAt.Time.After(time.Now().Add(groupAdminInvitationMaxAge)) {
+			return herror.BadRequest(fmt.Sprintf("expiresAt must be within %d days", groupAdminInvitationMaxAge/(24*time.Hour)))
+		}
+	}
+
+	groupIDs := req.Groups.Array()
+	for _, gid := range groupIDs {
+		g, err := h.Repo.GetUserGroup(gid)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return herror.BadRequest("invalid group: " + gid.String())
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+		if !manage && !g.IsAdmin(user.GetID()) {
+			return herror.Forbidden("you are not the group's admin: " + gid.String())
+		}
+	}
+
+	channels := make(map[uuid.UUID]model.ChannelSubscribeLevel, len(req.Channels))
+	tree := h.ChannelManager.PublicChannelTree()
+	for _, ch := range req.Channels {
+		if !tree.IsChannelPresent(ch.ChannelID) || tree.IsArchivedChannel(ch.ChannelID) {
+			return herror.BadRequest("invalid channel: " + ch.ChannelID.String())
+		}
+		if tree.IsForceChannel(ch.ChannelID) {
+			return herror.BadRequest("the channel's subscriptions is not configurable: " + ch.ChannelID.String())
+		}
+		channels[ch.ChannelID] = model.ChannelSubscribeLevel(ch.Level)
+	}
+
+	inv, err := h.Repo.CreateInvitation(repository.CreateInvitationArgs{
+		CreatorID: user.GetID(),
+		Role:      r,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+		GroupIDs:  groupIDs,
+		Channels:  channels,
+	})
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("invitation created", zap.Stringer("invitationId", inv.ID), zap.Stringer("creatorId", user.GetID()), zap.String("role", r))
+
+	return c.JSON(http.StatusCreated, formatInvitation(inv))
+}
+
+// checkInvitationRoleGrantable リクエストしたユーザーが指定したロールで招待できるかどうかを確認します
+//
+// adminロールでの招待はadminのみが可能です。それ以外のロールは、自分が持っていない権限を含まない場合のみ招待できます。
+func (h *Handlers) checkInvitationRoleGrantable(user model.UserInfo, r string) error {
+	if r == role.Admin {
+		if user.GetRole() != role.Admin {
+			return herror.Forbidden("you are not permitted to invite users with the role")
+		}
+		return nil
+	}
+
+	roles, err := h.getRoleMap()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	target, ok := roles[r]
+	if !ok {
+		return herror.BadRequest("invalid role: " + r)
+	}
+	var granted permission.Permissions
+	if mine, ok := roles[user.GetRole()]; ok {
+		granted = role.ResolvePermissions(mine, roles)
+	}
+	return checkPermissionsGrantable(granted, role.ResolvePermissions(target, roles).Array())
+}
+
+// GetInvitation GET /invitations/:invitationID
+func (h *Handlers) GetInvitation(c echo.Context) error {
+	inv, err := h.getParamInvitation(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, formatInvitation(inv))
+}
+
+// DeleteInvitation DELETE /invitations/:invitationID
+func (h *Handlers) DeleteInvitation(c echo.Context) error {
+	inv, err := h.getParamInvitation(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Repo.DeleteInvitation(inv.ID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getParamInvitation パスパラメータの招待リンクを取得します
+//
+// 他人が作成した招待リンクは、全ての招待リンクの管理権限がある場合のみ取得できます。
+func (h *Handlers) getParamInvitation(c echo.Context) (*model.Invitation, error) {
+	user := getRequestUser(c)
+	id, err := uuid.FromString(c.Param(consts.ParamInvitationID))
+	if err != nil {
+		return nil, herror.NotFound()
+	}
+	inv, err := h.Repo.GetInvitation(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound()
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if inv.CreatorID != user.GetID() && !h.RBAC.IsGranted(user.GetRole(), permission.ManageInvitation) {
+		return nil, herror.NotFound()
+	}
+	return inv, nil
+}
+
+// getAvailableInvitation パスパラメータのトークンに対応する、使用可能な招待リンクを取得します
+func (h *Handlers) getAvailableInvitation(c echo.Context) (*model.Invitation, error) {
+	inv, err := h.Repo.GetInvitationByToken(c.Param(consts.ParamInviteToken))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound()
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if !inv.IsAvailable(time.Now()) {
+		return nil, herror.Forbidden("this invitation is no longer available")
+	}
+	return inv, nil
+}
+
+// GetSignUpInvitation GET /signup/:inviteToken
+func (h *Handlers) GetSignUpInvitation(c echo.Context) error {
+	inv, err := h.getAvailableInvitation(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, &SignUpInvitation{
+		ExpiresAt: inv.ExpiresAt,
+		Groups:    inv.GroupIDs(),
+	})
+}
+
+// PostSignUpRequest POST /signup/:inviteToken リクエストボディ
+type PostSignUpRequest struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	Password    string `json:"password"`
+}
+
+func (r PostSignUpRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.UserNameRuleRequired...),
+		vd.Field(&r.DisplayName, vd.RuneLength(0, 64)),
+		vd.Field(&r.Password, validator.PasswordRuleRequired...),
+	)
+}
+
+// SignUp POST /signup/:inviteToken
+func (h *Handlers) SignUp(c echo.Context) error {
+	var req PostSignUpRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	inv, err := h.getAvailableInvitation(c)
+	if err != nil {
+		return err
+	}
+
+	// アイコンを生成する前に名前の重複を確認しておく
+	if _, err := h.Repo.GetUserByName(req.Name, false); err == nil {
+		return herror.Conflict("name conflicts")
+	} else if err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+
+	user, err := h.Repo.SignUpWithInvitation(inv.ID, repository.CreateUserArgs{Name: req.Name, DisplayName: req.DisplayName, Password: req.Password})
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		case repository.ErrForbidden:
+			return herror.Forbidden("this invitation is no longer available")
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("name conflicts")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("a user signed up with invitation", zap.Stringer("userId", user.GetID()), zap.Stringer("invitationId", inv.ID))
+
+	groupIDs := inv.GroupIDs()
+	for _, ch := range inv.Channels {
+		err := h.ChannelManager.ChangeChannelSubscriptions(ch.ChannelID, map[uuid.UUID]model.ChannelSubscribeLevel{user.GetID(): ch.Level}, false, user.GetID())
+		if err != nil {
+			switch err {
+			case channel.ErrInvalidChannel, channel.ErrForcedNotification:
+				// 招待リンク作成後に購読設定ができなくなったチャンネルは無視
+				continue
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+
+	if _, err := h.SessStore.RenewSession(c, user.GetID(), utils.LoginMethodInvitation); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusCreated, formatUserDetail(user, []model.UserTag{}, groupIDs, h.Presence.GetStatus(user.GetID()), h.Presence.GetPresence(user.GetID())))
+}
@@ -0,0 +1,267 @@
+package v3
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlers_CreateInvitation(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/invitations"
+	env := Setup(t, common)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	user := env.CreateUser(t, rand)
+	s := env.S(t, user.GetID())
+	g, err := env.Repository.CreateUserGroup(random.AlphaNumeric(20), "", "", user.GetID())
+	require.NoError(t, err)
+	other, err := env.Repository.CreateUserGroup(random.AlphaNumeric(20), "", "", admin.GetID())
+	require.NoError(t, err)
+
+	t.Run("NotLoggedIn", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(&PostInvitationRequest{}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("group admin without groups", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(&PostInvitationRequest{}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("group admin of other group", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"groups": []uuid.UUID{other.ID}}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("group admin with admin role", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"role": role.Admin, "groups": []uuid.UUID{g.ID}}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("group admin without limits", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"groups": []uuid.UUID{g.ID}, "expiresAt": time.Now().Add(time.Hour)}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"maxUses": 1, "groups": []uuid.UUID{g.ID}}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"maxUses": 1, "groups": []uuid.UUID{g.ID}, "expiresAt": time.Now().Add(30 * 24 * time.Hour)}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("group admin", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"maxUses": 1, "groups": []uuid.UUID{g.ID}, "expiresAt": time.Now().Add(time.Hour)}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object()
+		obj.Value("role").String().Equal(role.User)
+		obj.Value("creatorId").String().Equal(user.GetID().String())
+		obj.Value("groups").Array().Elements(g.ID.String())
+		obj.Value("token").String().NotEmpty()
+
+		// 他人の招待リンクは見えない
+		e.GET(path+"/"+obj.Value("id").String().Raw()).
+			WithCookie(session.CookieName, env.S(t, env.CreateUser(t, rand).GetID())).
+			Expect().
+			Status(http.StatusNotFound)
+		e.GET(path+"/"+obj.Value("id").String().Raw()).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			Expect().
+			Status(http.StatusOK)
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		r, err := env.Repository.CreateRole(repository.CreateRoleArgs{
+			Name:         strings.ToLower(random.AlphaNumeric(20)),
+			Permissions:  []string{permission.ManageInvitation.Name()},
+			Inheritances: []string{role.User},
+		})
+		require.NoError(t, err)
+		manager, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: r.Name})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			return env.RBAC.IsGranted(r.Name, permission.ManageInvitation)
+		}, 5*time.Second, 50*time.Millisecond)
+		s := env.S(t, manager.GetID())
+
+		// 自分より強いロールでは招待できない
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"role": role.Admin}).
+			Expect().
+			Status(http.StatusForbidden)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"role": role.Moderator}).
+			Expect().
+			Status(http.StatusForbidden)
+		e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"role": r.Name}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object().
+			Value("role").
+			String().
+			Equal(r.Name)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"role": "unknown role"}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST(path).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"role": role.Admin, "expiresAt": "2000-01-01T00:00:00Z"}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST(path).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			WithJSON(map[string]interface{}{"role": role.Admin}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object().
+			Value("role").
+			String().
+			Equal(role.Admin)
+	})
+}
+
+func TestHandlers_SignUp(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	creator := env.CreateUser(t, rand)
+	g, err := env.Repository.CreateUserGroup(random.AlphaNumeric(20), "", "", creator.GetID())
+	require.NoError(t, err)
+	ch, err := env.CM.CreatePublicChannel(random.AlphaNumeric(20), uuid.Nil, creator.GetID())
+	require.NoError(t, err)
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/api/v3/signup/{token}", "invalid").
+			Expect().
+			Status(http.StatusNotFound)
+		e.POST("/api/v3/signup/{token}", "invalid").
+			WithJSON(&PostSignUpRequest{Name: random.AlphaNumeric(20), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		inv, err := env.Repository.CreateInvitation(repository.CreateInvitationArgs{
+			CreatorID: creator.GetID(),
+			Role:      role.User,
+			MaxUses:   1,
+			GroupIDs:  []uuid.UUID{g.ID},
+			Channels:  map[uuid.UUID]model.ChannelSubscribeLevel{ch.ID: model.ChannelSubscribeLevelMarkAndNotify},
+		})
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.GET("/api/v3/signup/{token}", inv.Token).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("groups").
+			Array().
+			Elements(g.ID.String())
+
+		name := random.AlphaNumeric(20)
+		res := e.POST("/api/v3/signup/{token}", inv.Token).
+			WithJSON(&PostSignUpRequest{Name: name, Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusCreated)
+		res.Cookie(session.CookieName).Value().NotEmpty()
+		obj := res.JSON().Object()
+		obj.Value("name").String().Equal(name)
+		obj.Value("groups").Array().Elements(g.ID.String())
+
+		uid := uuid.FromStringOrNil(obj.Value("id").String().Raw())
+		user, err := env.Repository.GetUser(uid, false)
+		require.NoError(t, err)
+		assert.Equal(t, role.User, user.GetRole())
+		subs, err := env.Repository.GetChannelSubscriptions(repository.ChannelSubscriptionQuery{}.SetUser(uid))
+		require.NoError(t, err)
+		if assert.Len(t, subs, 1) {
+			assert.Equal(t, ch.ID, subs[0].ChannelID)
+			assert.Equal(t, model.ChannelSubscribeLevelMarkAndNotify, subs[0].GetLevel())
+		}
+
+		// 最大使用回数に達した
+		e.POST("/api/v3/signup/{token}", inv.Token).
+			WithJSON(&PostSignUpRequest{Name: random.AlphaNumeric(20), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("name conflicts", func(t *testing.T) {
+		t.Parallel()
+		inv, err := env.Repository.CreateInvitation(repository.CreateInvitationArgs{CreatorID: creator.GetID(), Role: role.User, MaxUses: 1})
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.POST("/api/v3/signup/{token}", inv.Token).
+			WithJSON(&PostSignUpRequest{Name: creator.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusConflict)
+
+		inv, err = env.Repository.GetInvitation(inv.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, inv.Uses)
+	})
+}
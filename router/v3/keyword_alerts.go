@@ -0,0 +1,95 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"net/http"
+	"regexp"
+)
+
+// maxKeywordAlertsPerUser 1ユーザーあたりのキーワード通知の最大数
+const maxKeywordAlertsPerUser = 50
+
+// GetMyKeywordAlerts GET /users/me/keyword-alerts
+func (h *Handlers) GetMyKeywordAlerts(c echo.Context) error {
+	alerts, err := h.Repo.GetKeywordAlerts(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatKeywordAlerts(alerts))
+}
+
+// PostKeywordAlertRequest POST /users/me/keyword-alerts リクエストボディ
+type PostKeywordAlertRequest struct {
+	Keyword  string `json:"keyword"`
+	IsRegexp bool   `json:"isRegexp"`
+}
+
+func (r PostKeywordAlertRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Keyword, vd.Required, vd.RuneLength(1, 100), vd.When(r.IsRegexp, vd.By(func(value interface{}) error {
+			if _, err := regexp.Compile(value.(string)); err != nil {
+				return vd.NewError("validation_invalid_regexp", "must be a valid regular expression")
+			}
+			return nil
+		}))),
+	)
+}
+
+// CreateMyKeywordAlert POST /users/me/keyword-alerts
+func (h *Handlers) CreateMyKeywordAlert(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PostKeywordAlertRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	alerts, err := h.Repo.GetKeywordAlerts(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if len(alerts) >= maxKeywordAlertsPerUser {
+		return herror.BadRequest("too many keyword alerts")
+	}
+
+	ka, err := h.Repo.CreateKeywordAlert(userID, req.Keyword, req.IsRegexp)
+	if err != nil {
+		switch {
+		case err == repository.ErrAlreadyExists:
+			return herror.Conflict("the keyword has already been registered")
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, formatKeywordAlert(ka))
+}
+
+// DeleteMyKeywordAlert DELETE /users/me/keyword-alerts/:alertID
+func (h *Handlers) DeleteMyKeywordAlert(c echo.Context) error {
+	alertID := getParamAsUUID(c, consts.ParamKeywordAlertID)
+
+	ka, err := h.Repo.GetKeywordAlert(alertID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if ka.UserID != getRequestUserID(c) {
+		return herror.NotFound()
+	}
+
+	if err := h.Repo.DeleteKeywordAlert(ka.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// GetLoginLocks GET /login-locks
+func (h *Handlers) GetLoginLocks(c echo.Context) error {
+	locks, err := h.Repo.GetLoginLocks()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatLoginLocks(locks))
+}
+
+// deleteLoginLock ログイン試行のロックを解除します
+func (h *Handlers) deleteLoginLock(c echo.Context, kind model.LoginLockKind, key string) error {
+	if err := h.Repo.DeleteLoginLock(kind, key); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("login lock was removed",
+		zap.String("kind", string(kind)),
+		zap.String("key", key),
+		zap.Stringer("by", getRequestUserID(c)))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteUserLoginLock DELETE /users/:userID/login-lock
+func (h *Handlers) DeleteUserLoginLock(c echo.Context) error {
+	return h.deleteLoginLock(c, model.LoginLockKindUser, getParamUser(c).GetID().String())
+}
+
+// DeleteIPLoginLock DELETE /login-locks/ips/:ip
+func (h *Handlers) DeleteIPLoginLock(c echo.Context) error {
+	return h.deleteLoginLock(c, model.LoginLockKindIP, c.Param("ip"))
+}
+
+// GetLoginAuditLogsRequest GET /login-audit-logs クエリパラメータ
+type GetLoginAuditLogsRequest struct {
+	UserID uuid.UUID `query:"userId"`
+	IP     string    `query:"ip"`
+	Limit  int       `query:"limit"`
+	Offset int       `query:"offset"`
+}
+
+func (r *GetLoginAuditLogsRequest) Validate() error {
+	if r.Limit == 0 {
+		r.Limit = 50
+	}
+	return vd.ValidateStruct(r,
+		vd.Field(&r.IP, vd.Length(0, 64)),
+		vd.Field(&r.Limit, vd.Min(1), vd.Max(200)),
+		vd.Field(&r.Offset, vd.Min(0)),
+	)
+}
+
+// GetLoginAuditLogs GET /login-audit-logs
+func (h *Handlers) GetLoginAuditLogs(c echo.Context) error {
+	var req GetLoginAuditLogsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	logs, err := h.Repo.GetLoginAuditLogs(repository.LoginAuditLogQuery{
+		UserID: req.UserID,
+		IP:     req.IP,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatLoginAuditLogs(logs))
+}
@@ -0,0 +1,147 @@
+package v3
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_Login_Lockout(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/login"
+	env := Setup(t, common)
+
+	t.Run("account lockout and admin unlock", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+		require.NoError(t, err)
+		e := env.R(t)
+
+		for i := 0; i < utils.AccountLoginBackoff.Threshold; i++ {
+			e.POST(path).
+				WithHeader("X-Forwarded-For", "192.0.2.1").
+				WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "wrong password"}).
+				Expect().
+				Status(http.StatusUnauthorized)
+		}
+
+		// 正しいパスワードでもロック中はログインできない
+		e.POST(path).
+			WithHeader("X-Forwarded-For", "192.0.2.1").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusTooManyRequests).
+			Header("Retry-After").
+			NotEmpty()
+
+		logs, err := env.Repository.GetLoginAuditLogs(repository.LoginAuditLogQuery{UserID: user.GetID()})
+		require.NoError(t, err)
+		if assert.Len(t, logs, utils.AccountLoginBackoff.Threshold+1) {
+			assert.Equal(t, model.LoginFailureReasonLocked, logs[0].Reason)
+			assert.Equal(t, model.LoginFailureReasonWrongPassword, logs[1].Reason)
+			assert.Equal(t, utils.LoginMethodV3, logs[1].Method)
+		}
+
+		// 一般ユーザーは解除できない
+		e.DELETE("/api/v3/users/{userID}/login-lock", user.GetID()).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusForbidden)
+
+		e.DELETE("/api/v3/users/{userID}/login-lock", user.GetID()).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			Expect().
+			Status(http.StatusNoContent)
+		e.DELETE("/api/v3/users/{userID}/login-lock", user.GetID()).
+			WithCookie(session.CookieName, env.S(t, admin.GetID())).
+			Expect().
+			Status(http.StatusNotFound)
+
+		e.POST(path).
+			WithHeader("X-Forwarded-For", "192.0.2.1").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusNoContent)
+	})
+
+	t.Run("two-factor lockout", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		_, err := env.Repository.SetUserTOTPSecret(user.GetID(), "JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		require.NoError(t, env.Repository.EnableUserTOTP(user.GetID(), 0, nil))
+		e := env.R(t)
+
+		// パスワードを知っていても、二要素認証待ちをやり直して試行回数を増やすことはできない
+		for i := 0; i < utils.AccountLoginBackoff.Threshold; i++ {
+			s := e.POST(path).
+				WithHeader("X-Forwarded-For", "192.0.2.2").
+				WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+				Expect().
+				Status(http.StatusAccepted).
+				Cookie(session.CookieName).
+				Value().
+				Raw()
+			e.POST("/api/v3/login/totp").
+				WithHeader("X-Forwarded-For", "192.0.2.2").
+				WithCookie(session.CookieName, s).
+				WithJSON(&PostLoginTOTPRequest{RecoveryCode: "wrong-recovery-code"}).
+				Expect().
+				Status(http.StatusUnauthorized)
+		}
+
+		e.POST(path).
+			WithHeader("X-Forwarded-For", "192.0.2.2").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusTooManyRequests)
+
+		logs, err := env.Repository.GetLoginAuditLogs(repository.LoginAuditLogQuery{UserID: user.GetID()})
+		require.NoError(t, err)
+		if assert.Len(t, logs, utils.AccountLoginBackoff.Threshold+1) {
+			assert.Equal(t, model.LoginFailureReasonLocked, logs[0].Reason)
+			assert.Equal(t, model.LoginFailureReasonWrongTwoFactor, logs[1].Reason)
+			assert.Equal(t, utils.LoginMethodV3, logs[1].Method)
+		}
+	})
+
+	t.Run("ip lockout", func(t *testing.T) {
+		t.Parallel()
+		ip := "198.51.100.1"
+		e := env.R(t)
+
+		for i := 0; i < utils.IPLoginBackoff.Threshold; i++ {
+			e.POST(path).
+				WithHeader("X-Forwarded-For", ip).
+				WithJSON(&PostLoginRequest{Name: random.AlphaNumeric(20), Password: "password"}).
+				Expect().
+				Status(http.StatusUnauthorized)
+		}
+
+		user := env.CreateUser(t, rand)
+		e.POST(path).
+			WithHeader("X-Forwarded-For", ip).
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusTooManyRequests)
+
+		// 他のIPアドレスからはログインできる
+		e.POST(path).
+			WithHeader("X-Forwarded-For", "198.51.100.2").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		logs, err := env.Repository.GetLoginAuditLogs(repository.LoginAuditLogQuery{IP: ip})
+		require.NoError(t, err)
+		assert.Len(t, logs, utils.IPLoginBackoff.Threshold+1)
+	})
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
 	"net/http"
 )
 
@@ -37,7 +41,11 @@ func (h *Handlers) ReadChannel(c echo.Context) error {
 
 // GetMessage GET /messages/:messageID
 func (h *Handlers) GetMessage(c echo.Context) error {
-	return c.JSON(http.StatusOK, formatMessage(getParamMessage(c)))
+	res := formatMessage(getParamMessage(c))
+	if err := markBlockedMessages(h.Repo, getRequestUserID(c), res); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 // PostMessageRequest POST /channels/:channelID/messages等リクエストボディ
@@ -80,6 +88,10 @@ func (h *Handlers) EditMessage(c echo.Context) error {
 		req.Content = h.Replacer.Replace(req.Content)
 	}
 
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, getRequestUser(c), ch, req.Content); err != nil {
+		return err
+	}
+
 	if err := h.Repo.UpdateMessage(m.ID, req.Content); err != nil {
 		return herror.InternalServerError(err)
 	}
@@ -92,7 +104,17 @@ func (h *Handlers) DeleteMessage(c echo.Context) error {
 	userID := getRequestUserID(c)
 	m := getParamMessage(c)
 
+	// 他ユーザーのメッセージの削除権限(チャンネルスコープのロールを含む)の確認
+	deleteOthers := false
 	if m.UserID != userID {
+		ok, err := rbac.IsGrantedInChannel(h.RBAC, h.Repo, h.ChannelManager.PublicChannelTree(), getRequestUser(c), m.ChannelID, permission.DeleteOthersMessage)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		deleteOthers = ok
+	}
+
+	if m.UserID != userID && !deleteOthers {
 		mUser, err := h.Repo.GetUser(m.UserID, false)
 		if err != nil {
 			return herror.InternalServerError(err)
@@ -278,10 +300,24 @@ func (h *Handlers) PostMessage(c echo.Context) error {
 		return err
 	}
 
+	if ch.IsDMChannel() {
+		blocked, err := h.ChannelManager.IsDMBlocked(userID, ch.ID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if blocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
+	}
+
 	if req.Embed {
 		req.Content = h.Replacer.Replace(req.Content)
 	}
 
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, getRequestUser(c), ch, req.Content); err != nil {
+		return err
+	}
+
 	m, err := h.Repo.CreateMessage(userID, ch.ID, req.Content)
 	if err != nil {
 		return herror.InternalServerError(err)
@@ -303,6 +339,9 @@ func (h *Handlers) GetDirectMessages(c echo.Context) error {
 	// DMチャンネルを取得
 	ch, err := h.ChannelManager.GetDMChannel(myID, targetID)
 	if err != nil {
+		if err == channel.ErrUserBlocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
 		return herror.InternalServerError(err)
 	}
 
@@ -319,9 +358,21 @@ func (h *Handlers) PostDirectMessage(c echo.Context) error {
 		return err
 	}
 
+	// ブロックの確認
+	blocked, err := h.Repo.IsBlockedBetween(myID, targetID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if blocked {
+		return herror.Forbidden("direct messages with this user are blocked")
+	}
+
 	// DMチャンネルを取得
 	ch, err := h.ChannelManager.GetDMChannel(myID, targetID)
 	if err != nil {
+		if err == channel.ErrUserBlocked {
+			return herror.Forbidden("direct messages with this user are blocked")
+		}
 		return herror.InternalServerError(err)
 	}
 
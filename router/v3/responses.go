@@ -6,6 +6,10 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"sort"
 )
 
 type Channel struct {
@@ -92,22 +96,24 @@ func formatUsers(users []model.UserInfo) []User {
 }
 
 type UserDetail struct {
-	ID          uuid.UUID     `json:"id"`
-	State       int           `json:"state"`
-	Bot         bool          `json:"bot"`
-	IconFileID  uuid.UUID     `json:"iconFileId"`
-	DisplayName string        `json:"displayName"`
-	Name        string        `json:"name"`
-	TwitterID   string        `json:"twitterId"`
-	LastOnline  optional.Time `json:"lastOnline"`
-	UpdatedAt   time.Time     `json:"updatedAt"`
-	Tags        []UserTag     `json:"tags"`
-	Groups      []uuid.UUID   `json:"groups"`
-	Bio         string        `json:"bio"`
-	HomeChannel optional.UUID `json:"homeChannel"`
-}
-
-func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID) *UserDetail {
+	ID          uuid.UUID      `json:"id"`
+	State       int            `json:"state"`
+	Bot         bool           `json:"bot"`
+	IconFileID  uuid.UUID      `json:"iconFileId"`
+	DisplayName string         `json:"displayName"`
+	Name        string         `json:"name"`
+	TwitterID   string         `json:"twitterId"`
+	LastOnline  optional.Time  `json:"lastOnline"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	Tags        []UserTag      `json:"tags"`
+	Groups      []uuid.UUID    `json:"groups"`
+	Bio         string         `json:"bio"`
+	HomeChannel optional.UUID  `json:"homeChannel"`
+	Status      UserStatus     `json:"status"`
+	Presence    model.Presence `json:"presence"`
+}
+
+func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID, s *model.UserStatus, p model.Presence) *UserDetail {
 	return &UserDetail{
 		ID:          user.GetID(),
 		State:       user.GetState().Int(),
@@ -122,6 +128,22 @@ func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID) *
 		Groups:      g,
 		Bio:         user.GetBio(),
 		HomeChannel: user.GetHomeChannel(),
+		Status:      formatUserStatus(s),
+		Presence:    p,
+	}
+}
+
+type UserStatus struct {
+	Text      string        `json:"text"`
+	StampID   optional.UUID `json:"stampId"`
+	ExpiresAt optional.Time `json:"expiresAt"`
+}
+
+func formatUserStatus(s *model.UserStatus) UserStatus {
+	return UserStatus{
+		Text:      s.Text,
+		StampID:   s.StampID,
+		ExpiresAt: s.ExpiresAt,
 	}
 }
 
@@ -241,6 +263,8 @@ type Message struct {
 	Pinned    bool                 `json:"pinned"`
 	Stamps    []model.MessageStamp `json:"stamps"`
 	ThreadID  optional.UUID        `json:"threadId"` // TODO
+	// Blocked リクエストしたユーザーが投稿者をブロックしているかどうか
+	Blocked bool `json:"blocked"`
 }
 
 func formatMessage(m *model.Message) *Message {
@@ -457,6 +481,8 @@ type OAuth2ClientDetail struct {
 	Scopes      model.AccessScopes `json:"scopes"`
 	CallbackURL string             `json:"callbackUrl"`
 	Secret      string             `json:"secret"`
+	RequirePKCE bool               `json:"requirePkce"`
+	Pending     bool               `json:"pending"`
 }
 
 func formatOAuth2ClientDetail(oc *model.OAuth2Client) *OAuth2ClientDetail {
@@ -468,6 +494,8 @@ func formatOAuth2ClientDetail(oc *model.OAuth2Client) *OAuth2ClientDetail {
 		Scopes:      oc.Scopes,
 		CallbackURL: oc.RedirectURI,
 		Secret:      oc.Secret,
+		RequirePKCE: oc.RequirePKCE,
+		Pending:     oc.Pending,
 	}
 }
 
@@ -546,3 +574,351 @@ func formatStampPalettes(cfs []*model.StampPalette) []*StampPalette {
 	}
 	return res
 }
+
+type DoNotDisturbSetting struct {
+	Enabled        bool                       `json:"enabled"`
+	Active         bool                       `json:"active"`
+	Timezone       string                     `json:"timezone"`
+	Schedule       model.DoNotDisturbSchedule `json:"schedule"`
+	AllowedUsers   model.UUIDs                `json:"allowedUsers"`
+	UrgentChannels model.UUIDs                `json:"urgentChannels"`
+}
+
+func formatDoNotDisturbSetting(s *model.DoNotDisturbSetting) *DoNotDisturbSetting {
+	res := &DoNotDisturbSetting{
+		Enabled:        s.Enabled,
+		Active:         s.IsActive(time.Now()),
+		Timezone:       s.Timezone,
+		Schedule:       s.Schedule,
+		AllowedUsers:   s.AllowedUsers,
+		UrgentChannels: s.UrgentChannels,
+	}
+	if res.Schedule == nil {
+		res.Schedule = model.DoNotDisturbSchedule{}
+	}
+	if res.AllowedUsers == nil {
+		res.AllowedUsers = model.UUIDs{}
+	}
+	if res.UrgentChannels == nil {
+		res.UrgentChannels = model.UUIDs{}
+	}
+	return res
+}
+
+type KeywordAlert struct {
+	ID        uuid.UUID `json:"id"`
+	Keyword   string    `json:"keyword"`
+	IsRegexp  bool      `json:"isRegexp"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func formatKeywordAlert(ka *model.KeywordAlert) *KeywordAlert {
+	return &KeywordAlert{
+		ID:        ka.ID,
+		Keyword:   ka.Keyword,
+		IsRegexp:  ka.IsRegexp,
+		CreatedAt: ka.CreatedAt,
+	}
+}
+
+func formatKeywordAlerts(kas []*model.KeywordAlert) []*KeywordAlert {
+	res := make([]*KeywordAlert, len(kas))
+	for i, ka := range kas {
+		res[i] = formatKeywordAlert(ka)
+	}
+	return res
+}
+
+type InboxEntry struct {
+	ID        uuid.UUID            `json:"id"`
+	Type      model.InboxEntryType `json:"type"`
+	MessageID uuid.UUID            `json:"messageId"`
+	ActorID   uuid.UUID            `json:"actorId"`
+	StampID   optional.UUID        `json:"stampId"`
+	IsRead    bool                 `json:"isRead"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+func formatInboxEntry(e *model.InboxEntry) *InboxEntry {
+	return &InboxEntry{
+		ID:        e.ID,
+		Type:      e.Type,
+		MessageID: e.MessageID,
+		ActorID:   e.ActorID,
+		StampID:   e.StampID,
+		IsRead:    e.IsRead,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+func formatInboxEntries(es []*model.InboxEntry) []*InboxEntry {
+	res := make([]*InboxEntry, len(es))
+	for i, e := range es {
+		res[i] = formatInboxEntry(e)
+	}
+	return res
+}
+
+type EmailNotificationSetting struct {
+	Address string                    `json:"address"`
+	Enabled bool                      `json:"enabled"`
+	Digest  model.EmailDigestInterval `json:"digest"`
+}
+
+func formatEmailNotificationSetting(s *model.EmailNotificationSetting) *EmailNotificationSetting {
+	return &EmailNotificationSetting{
+		Address: s.Address,
+		Enabled: s.Enabled,
+		Digest:  s.Digest,
+	}
+}
+
+type WebPushSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func formatWebPushSubscription(s *model.WebPushSubscription) *WebPushSubscription {
+	return &WebPushSubscription{
+		ID:        s.ID,
+		Endpoint:  s.Endpoint,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+type UserBlock struct {
+	UserID    uuid.UUID           `json:"userId"`
+	Type      model.UserBlockType `json:"type"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+func formatUserBlocks(blocks []*model.UserBlock) []*UserBlock {
+	res := make([]*UserBlock, len(blocks))
+	for i, b := range blocks {
+		res[i] = &UserBlock{
+			UserID:    b.TargetID,
+			Type:      b.Type,
+			CreatedAt: b.CreatedAt,
+		}
+	}
+	return res
+}
+
+type WebAuthnCredential struct {
+	ID         uuid.UUID     `json:"id"`
+	Name       string        `json:"name"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	LastUsedAt optional.Time `json:"lastUsedAt"`
+}
+
+func formatWebAuthnCredential(cred *model.WebAuthnCredential) *WebAuthnCredential {
+	return &WebAuthnCredential{
+		ID:         cred.ID,
+		Name:       cred.Name,
+		CreatedAt:  cred.CreatedAt,
+		LastUsedAt: cred.LastUsedAt,
+	}
+}
+
+func formatWebAuthnCredentials(creds []*model.WebAuthnCredential) []*WebAuthnCredential {
+	res := make([]*WebAuthnCredential, len(creds))
+	for i, cred := range creds {
+		res[i] = formatWebAuthnCredential(cred)
+	}
+	return res
+}
+
+type LoginLock struct {
+	Kind         model.LoginLockKind `json:"kind"`
+	Key          string              `json:"key"`
+	Failures     int                 `json:"failures"`
+	LastFailedAt time.Time           `json:"lastFailedAt"`
+	LockedUntil  optional.Time       `json:"lockedUntil"`
+}
+
+func formatLoginLocks(locks []*model.LoginLock) []*LoginLock {
+	res := make([]*LoginLock, len(locks))
+	for i, l := range locks {
+		res[i] = &LoginLock{
+			Kind:         l.Kind,
+			Key:          l.Key,
+			Failures:     l.Failures,
+			LastFailedAt: l.LastFailedAt,
+			LockedUntil:  l.LockedUntil,
+		}
+	}
+	return res
+}
+
+type LoginAuditLog struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    optional.UUID `json:"userId"`
+	Name      string        `json:"name"`
+	IP        string        `json:"ip"`
+	Method    string        `json:"method"`
+	Reason    string        `json:"reason"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+func formatLoginAuditLogs(logs []*model.LoginAuditLog) []*LoginAuditLog {
+	res := make([]*LoginAuditLog, len(logs))
+	for i, l := range logs {
+		res[i] = &LoginAuditLog{
+			ID:        l.ID,
+			UserID:    l.UserID,
+			Name:      l.Name,
+			IP:        l.IP,
+			Method:    l.Method,
+			Reason:    l.Reason,
+			CreatedAt: l.CreatedAt,
+		}
+	}
+	return res
+}
+
+type InvitationChannel struct {
+	ChannelID uuid.UUID                   `json:"channelId"`
+	Level     model.ChannelSubscribeLevel `json:"level"`
+}
+
+type Invitation struct {
+	ID        uuid.UUID            `json:"id"`
+	Token     string               `json:"token"`
+	CreatorID uuid.UUID            `json:"creatorId"`
+	Role      string               `json:"role"`
+	MaxUses   int                  `json:"maxUses"`
+	Uses      int                  `json:"uses"`
+	ExpiresAt optional.Time        `json:"expiresAt"`
+	Groups    []uuid.UUID          `json:"groups"`
+	Channels  []*InvitationChannel `json:"channels"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+func formatInvitation(inv *model.Invitation) *Invitation {
+	channels := make([]*InvitationChannel, len(inv.Channels))
+	for i, ch := range inv.Channels {
+		channels[i] = &InvitationChannel{ChannelID: ch.ChannelID, Level: ch.Level}
+	}
+	return &Invitation{
+		ID:        inv.ID,
+		Token:     inv.Token,
+		CreatorID: inv.CreatorID,
+		Role:      inv.Role,
+		MaxUses:   inv.MaxUses,
+		Uses:      inv.Uses,
+		ExpiresAt: inv.ExpiresAt,
+		Groups:    inv.GroupIDs(),
+		Channels:  channels,
+		CreatedAt: inv.CreatedAt,
+	}
+}
+
+func formatInvitations(invs []*model.Invitation) []*Invitation {
+	res := make([]*Invitation, len(invs))
+	for i, inv := range invs {
+		res[i] = formatInvitation(inv)
+	}
+	return res
+}
+
+type SignUpInvitation struct {
+	ExpiresAt optional.Time `json:"expiresAt"`
+	Groups    []uuid.UUID   `json:"groups"`
+}
+
+type LoginSession struct {
+	ID             uuid.UUID `json:"id"`
+	IssuedAt       time.Time `json:"issuedAt"`
+	UserAgent      string    `json:"userAgent"`
+	IP             string    `json:"ip"`
+	LoginMethod    string    `json:"loginMethod"`
+	LastActivityAt time.Time `json:"lastActivityAt"`
+	Current        bool      `json:"current"`
+}
+
+func formatLoginSessions(ses []session.Session, current uuid.UUID) []LoginSession {
+	res := make([]LoginSession, len(ses))
+	for i, s := range ses {
+		res[i] = LoginSession{
+			ID:             s.RefID(),
+			IssuedAt:       s.CreatedAt(),
+			UserAgent:      s.UserAgent(),
+			IP:             s.IP(),
+			LoginMethod:    s.LoginMethod(),
+			LastActivityAt: s.LastActivityAt(),
+			Current:        current != uuid.Nil && s.RefID() == current,
+		}
+	}
+	return res
+}
+
+type Role struct {
+	Name                 string   `json:"name"`
+	System               bool     `json:"system"`
+	Oauth2Scope          bool     `json:"oauth2Scope"`
+	Permissions          []string `json:"permissions"`
+	Inheritances         []string `json:"inheritances"`
+	EffectivePermissions []string `json:"effectivePermissions"`
+}
+
+func formatRole(r *model.UserRole, roles map[string]*model.UserRole) *Role {
+	perms := permission.Permissions{}
+	for _, p := range r.Permissions {
+		perms.Add(permission.Permission(p.Permission))
+	}
+	inheritances := make([]string, len(r.Inheritances))
+	for i, v := range r.Inheritances {
+		inheritances[i] = v.SubRole
+	}
+	sort.Strings(inheritances)
+	return &Role{
+		Name:                 r.Name,
+		System:               r.System,
+		Oauth2Scope:          r.Oauth2Scope,
+		Permissions:          formatPermissions(perms),
+		Inheritances:         inheritances,
+		EffectivePermissions: formatPermissions(role.ResolvePermissions(r, roles)),
+	}
+}
+
+func formatRoles(rs []*model.UserRole) []*Role {
+	roles := make(map[string]*model.UserRole, len(rs))
+	for _, r := range rs {
+		roles[r.Name] = r
+	}
+	res := make([]*Role, len(rs))
+	for i, r := range rs {
+		res[i] = formatRole(r, roles)
+	}
+	return res
+}
+
+func formatPermissions(perms permission.Permissions) []string {
+	res := make([]string, 0, len(perms))
+	for p := range perms {
+		res = append(res, p.Name())
+	}
+	sort.Strings(res)
+	return res
+}
+
+type ChannelRoleBinding struct {
+	ChannelID uuid.UUID `json:"channelId"`
+	UserID    uuid.UUID `json:"userId"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func formatChannelRoleBindings(bindings []*model.ChannelRoleBinding) []ChannelRoleBinding {
+	res := make([]ChannelRoleBinding, len(bindings))
+	for i, b := range bindings {
+		res[i] = ChannelRoleBinding{
+			ChannelID: b.ChannelID,
+			UserID:    b.UserID,
+			Role:      b.Role,
+			CreatedAt: b.CreatedAt,
+		}
+	}
+	return res
+}
@@ -0,0 +1,193 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+)
+
+// GetRoles GET /roles
+func (h *Handlers) GetRoles(c echo.Context) error {
+	roles, err := h.Repo.GetRoles()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatRoles(roles))
+}
+
+// PostRoleRequest POST /roles リクエストボディ
+type PostRoleRequest struct {
+	Name         string   `json:"name"`
+	Permissions  []string `json:"permissions"`
+	Inheritances []string `json:"inheritances"`
+}
+
+func (r PostRoleRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.RoleNameRuleRequired...),
+		vd.Field(&r.Permissions, vd.Length(0, 200)),
+		vd.Field(&r.Inheritances, vd.Length(0, 50), vd.Each(validator.RoleNameRuleRequired...)),
+	)
+}
+
+// CreateRole POST /roles
+func (h *Handlers) CreateRole(c echo.Context) error {
+	var req PostRoleRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	r, err := h.Repo.CreateRole(repository.CreateRoleArgs{
+		Name:         req.Name,
+		Permissions:  req.Permissions,
+		Inheritances: req.Inheritances,
+	})
+	if err != nil {
+		switch {
+		case err == repository.ErrAlreadyExists:
+			return herror.Conflict("name conflicts")
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	roles, err := h.getRoleMap()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusCreated, formatRole(r, roles))
+}
+
+// GetRole GET /roles/:roleName
+func (h *Handlers) GetRole(c echo.Context) error {
+	roles, err := h.getRoleMap()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	r, ok := roles[c.Param(consts.ParamRoleName)]
+	if !ok {
+		return herror.NotFound()
+	}
+	return c.JSON(http.StatusOK, formatRole(r, roles))
+}
+
+// PatchRoleRequest PATCH /roles/:roleName リクエストボディ
+type PatchRoleRequest struct {
+	Permissions  []string `json:"permissions"`
+	Inheritances []string `json:"inheritances"`
+}
+
+func (r PatchRoleRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Permissions, vd.Length(0, 200)),
+		vd.Field(&r.Inheritances, vd.Length(0, 50), vd.Each(validator.RoleNameRuleRequired...)),
+	)
+}
+
+// EditRole PATCH /roles/:roleName
+func (h *Handlers) EditRole(c echo.Context) error {
+	var req PatchRoleRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	err := h.Repo.UpdateRole(c.Param(consts.ParamRoleName), repository.UpdateRoleArgs{
+		Permissions:  req.Permissions,
+		Inheritances: req.Inheritances,
+	})
+	if err != nil {
+		switch {
+		case err == repository.ErrNotFound:
+			return herror.NotFound()
+		case err == repository.ErrForbidden:
+			return herror.Forbidden("system roles cannot be edited")
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteRole DELETE /roles/:roleName
+func (h *Handlers) DeleteRole(c echo.Context) error {
+	if err := h.Repo.DeleteRole(c.Param(consts.ParamRoleName)); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		case repository.ErrForbidden:
+			return herror.Forbidden("the role is a system role or in use")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostRolePreviewRequest POST /roles/preview リクエストボディ
+type PostRolePreviewRequest struct {
+	Permissions  []string `json:"permissions"`
+	Inheritances []string `json:"inheritances"`
+}
+
+func (r PostRolePreviewRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Permissions, vd.Length(0, 200)),
+		vd.Field(&r.Inheritances, vd.Length(0, 50), vd.Each(validator.RoleNameRuleRequired...)),
+	)
+}
+
+// PreviewRole POST /roles/preview
+func (h *Handlers) PreviewRole(c echo.Context) error {
+	var req PostRolePreviewRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	roles, err := h.getRoleMap()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	valid := permission.PermissionsFromArray(permission.List)
+	r := &model.UserRole{}
+	for _, p := range req.Permissions {
+		if !valid.Contains(permission.Permission(p)) {
+			return herror.BadRequest("unknown permission: " + p)
+		}
+		r.Permissions = append(r.Permissions, model.RolePermission{Permission: p})
+	}
+	for _, sub := range req.Inheritances {
+		if _, ok := roles[sub]; !ok {
+			return herror.BadRequest("unknown role: " + sub)
+		}
+		r.Inheritances = append(r.Inheritances, model.RoleInheritance{SubRole: sub})
+	}
+
+	type response struct {
+		Permissions []string `json:"permissions"`
+	}
+	return c.JSON(http.StatusOK, response{Permissions: formatPermissions(role.ResolvePermissions(r, roles))})
+}
+
+// getRoleMap 全ロールをロール名をキーとするマップで取得します
+func (h *Handlers) getRoleMap() (map[string]*model.UserRole, error) {
+	rs, err := h.Repo.GetRoles()
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]*model.UserRole, len(rs))
+	for _, r := range rs {
+		roles[r.Name] = r
+	}
+	return roles, nil
+}
@@ -0,0 +1,238 @@
+package v3
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandlers_GetRoles(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/roles"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	commonSession := env.S(t, user.GetID())
+	adminSession := env.S(t, admin.GetID())
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			WithCookie(session.CookieName, commonSession).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET(path).
+			WithCookie(session.CookieName, adminSession).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		obj.Length().Gt(0)
+		for _, v := range obj.Iter() {
+			o := v.Object()
+			if o.Value("name").String().Raw() == role.Moderator {
+				o.Value("system").Boolean().False()
+				o.Value("inheritances").Array().Equal([]string{role.User})
+				o.Value("effectivePermissions").Array().Contains(permission.GetMessageReports.Name(), permission.PostMessage.Name())
+			}
+		}
+	})
+}
+
+func TestHandlers_CreateRole(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/roles"
+	env := Setup(t, common)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	adminSession := env.S(t, admin.GetID())
+
+	t.Run("bad request", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRoleRequest{Name: "Invalid Name"}).
+			Expect().
+			Status(http.StatusBadRequest)
+		e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRoleRequest{Name: strings.ToLower(random.AlphaNumeric(20)), Inheritances: []string{role.Admin}}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRoleRequest{Name: role.User}).
+			Expect().
+			Status(http.StatusConflict)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		name := strings.ToLower(random.AlphaNumeric(20))
+		obj := e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRoleRequest{Name: name, Permissions: []string{permission.ManageRole.Name()}, Inheritances: []string{role.Read}}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object()
+
+		obj.Value("name").String().Equal(name)
+		obj.Value("permissions").Array().Equal([]string{permission.ManageRole.Name()})
+		obj.Value("inheritances").Array().Equal([]string{role.Read})
+		obj.Value("effectivePermissions").Array().Contains(permission.ManageRole.Name(), permission.GetMessage.Name())
+
+		// 作成したロールをユーザーに割り当てる
+		user := env.CreateUser(t, rand)
+		e.PATCH("/api/v3/users/{userID}", user.GetID()).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(map[string]interface{}{"role": name}).
+			Expect().
+			Status(http.StatusNoContent)
+		u, err := env.Repository.GetUser(user.GetID(), false)
+		require.NoError(t, err)
+		require.Equal(t, name, u.GetRole())
+	})
+}
+
+func TestHandlers_EditRole(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/roles/{roleName}"
+	env := Setup(t, common)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	adminSession := env.S(t, admin.GetID())
+
+	t.Run("system role", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.PATCH(path, role.User).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PatchRoleRequest{Permissions: []string{}}).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.PATCH(path, "notfound").
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PatchRoleRequest{Permissions: []string{}}).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		r, err := env.Repository.CreateRole(repository.CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20))})
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.PATCH(path, r.Name).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PatchRoleRequest{Permissions: []string{permission.ManageRole.Name()}}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		e.GET(path, r.Name).
+			WithCookie(session.CookieName, adminSession).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("effectivePermissions").
+			Array().
+			Equal([]string{permission.ManageRole.Name()})
+	})
+}
+
+func TestHandlers_DeleteRole(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/roles/{roleName}"
+	env := Setup(t, common)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	adminSession := env.S(t, admin.GetID())
+
+	t.Run("system role", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.DELETE(path, role.User).
+			WithCookie(session.CookieName, adminSession).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		r, err := env.Repository.CreateRole(repository.CreateRoleArgs{Name: strings.ToLower(random.AlphaNumeric(20))})
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.DELETE(path, r.Name).
+			WithCookie(session.CookieName, adminSession).
+			Expect().
+			Status(http.StatusNoContent)
+
+		e.GET(path, r.Name).
+			WithCookie(session.CookieName, adminSession).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestHandlers_PreviewRole(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/roles/preview"
+	env := Setup(t, common)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+	require.NoError(t, err)
+	adminSession := env.S(t, admin.GetID())
+
+	t.Run("bad request", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRolePreviewRequest{Permissions: []string{"unknown"}}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(&PostRolePreviewRequest{Permissions: []string{permission.ManageRole.Name()}, Inheritances: []string{role.Moderator}}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("permissions").
+			Array().
+			Contains(permission.ManageRole.Name(), permission.GetMessageReports.Name(), permission.PostMessage.Name())
+	})
+}
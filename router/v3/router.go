@@ -9,13 +9,16 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/presence"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
 	"github.com/traPtitech/traQ/utils/message"
+	"github.com/traPtitech/traQ/utils/webauthn"
 	"go.uber.org/zap"
 )
 
@@ -26,12 +29,14 @@ type Handlers struct {
 	Hub            *hub.Hub
 	Logger         *zap.Logger
 	OC             *counter.OnlineCounter
+	Presence       *presence.Manager
 	VM             *viewer.Manager
 	WebRTC         *webrtcv3.Manager
 	Imaging        imaging.Processor
 	SessStore      session.Store
 	ChannelManager channel.Manager
 	Replacer       *message.Replacer
+	Email          email.Client
 	Config
 }
 
@@ -44,12 +49,19 @@ type Config struct {
 
 	// EnabledExternalAccountLink リンク可能な外部認証アカウントのプロバイダ
 	EnabledExternalAccountProviders map[string]bool
+
+	// WebPushVAPIDPublicKey Web PushのVAPID公開鍵 (空の場合はWeb Pushが無効)
+	WebPushVAPIDPublicKey string
+
+	// WebAuthn WebAuthnのRelying Party設定
+	WebAuthn webauthn.RelyingParty
 }
 
 // Setup APIルーティングを行います
 func (h *Handlers) Setup(e *echo.Group) {
 	// middleware preparation
 	requires := middlewares.AccessControlMiddlewareGenerator(h.RBAC)
+	requiresInChannel := middlewares.ChannelAccessControlMiddlewareGenerator(h.RBAC, h.Repo, h.ChannelManager)
 	bodyLimit := middlewares.RequestBodyLengthLimit
 	retrieve := middlewares.NewParamRetriever(h.Repo, h.ChannelManager)
 	blockBot := middlewares.BlockBot(h.Repo)
@@ -80,6 +92,13 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiUsersUID.GET("/icon", h.GetUserIcon, requires(permission.DownloadFile))
 				apiUsersUID.PUT("/icon", h.ChangeUserIcon, requires(permission.EditOtherUsers))
 				apiUsersUID.PUT("/password", h.ChangeUserPassword, requires(permission.EditOtherUsers))
+				apiUsersUID.DELETE("/login-lock", h.DeleteUserLoginLock, requires(permission.ManageLoginLock))
+				apiUsersUIDSessions := apiUsersUID.Group("/sessions", requires(permission.ManageOthersSessions), blockBot)
+				{
+					apiUsersUIDSessions.GET("", h.GetUserSessions)
+					apiUsersUIDSessions.DELETE("", h.RevokeUserSessions)
+					apiUsersUIDSessions.DELETE("/:referenceID", h.RevokeUserSession)
+				}
 				apiUsersUIDTags := apiUsersUID.Group("/tags")
 				{
 					apiUsersUIDTags.GET("", h.GetUserTags, requires(permission.GetUserTag))
@@ -100,7 +119,43 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiUsersMe.GET("/icon", h.GetMyIcon, requires(permission.DownloadFile))
 				apiUsersMe.PUT("/icon", h.ChangeMyIcon, requires(permission.ChangeMyIcon))
 				apiUsersMe.PUT("/password", h.PutMyPassword, requires(permission.ChangeMyPassword), blockBot)
+				apiUsersMe.GET("/status", h.GetMyStatus, requires(permission.GetMe), blockBot)
+				apiUsersMe.PUT("/status", h.PutMyStatus, requires(permission.EditMyStatus), blockBot)
+				apiUsersMe.DELETE("/status", h.DeleteMyStatus, requires(permission.EditMyStatus), blockBot)
+				apiUsersMe.PUT("/presence", h.PutMyPresence, requires(permission.EditMyStatus), blockBot)
 				apiUsersMe.POST("/fcm-device", h.PostMyFCMDevice, requires(permission.RegisterFCMDevice), blockBot)
+				apiUsersMeWebPush := apiUsersMe.Group("/webpush-subscriptions", blockBot)
+				{
+					apiUsersMeWebPush.POST("", h.PostMyWebPushSubscription, requires(permission.RegisterWebPushSubscription))
+					apiUsersMeWebPush.DELETE("/:subscriptionID", h.DeleteMyWebPushSubscription, requires(permission.RegisterWebPushSubscription))
+				}
+				apiUsersMeTOTP := apiUsersMe.Group("/totp", requires(permission.ManageMyTwoFactor), blockBot)
+				{
+					apiUsersMeTOTP.GET("", h.GetMyTOTP)
+					apiUsersMeTOTP.POST("", h.PostMyTOTP)
+					apiUsersMeTOTP.GET("/qr-code", h.GetMyTOTPQRCode)
+					apiUsersMeTOTP.POST("/confirm", h.PostMyTOTPConfirm)
+					apiUsersMeTOTP.POST("/recovery-codes", h.PostMyRecoveryCodes)
+					apiUsersMeTOTP.POST("/disable", h.PostMyTOTPDisable)
+				}
+				apiUsersMeWebAuthn := apiUsersMe.Group("/webauthn-credentials", requires(permission.ManageMyWebAuthn), blockBot)
+				{
+					apiUsersMeWebAuthn.GET("", h.GetMyWebAuthnCredentials)
+					apiUsersMeWebAuthn.POST("", h.PostMyWebAuthnCredential)
+					apiUsersMeWebAuthn.POST("/options", h.PostMyWebAuthnCredentialOptions)
+					apiUsersMeWebAuthn.PATCH("/:credentialID", h.PatchMyWebAuthnCredential)
+					apiUsersMeWebAuthn.DELETE("/:credentialID", h.DeleteMyWebAuthnCredential)
+				}
+				apiUsersMeBlocks := apiUsersMe.Group("/blocks", blockBot)
+				{
+					apiUsersMeBlocks.GET("", h.GetMyUserBlocks, requires(permission.GetUserBlock))
+					apiUsersMeBlocks.PUT("/:userID", h.PutMyUserBlock, requires(permission.EditUserBlock), retrieve.UserID(false))
+					apiUsersMeBlocks.DELETE("/:userID", h.DeleteMyUserBlock, requires(permission.EditUserBlock))
+				}
+				apiUsersMe.GET("/do-not-disturb", h.GetMyDoNotDisturbSetting, requires(permission.GetDoNotDisturbSetting), blockBot)
+				apiUsersMe.PATCH("/do-not-disturb", h.EditMyDoNotDisturbSetting, requires(permission.EditDoNotDisturbSetting), blockBot)
+				apiUsersMe.GET("/email-notification", h.GetMyEmailNotificationSetting, requires(permission.GetEmailNotificationSetting), blockBot)
+				apiUsersMe.PATCH("/email-notification", h.EditMyEmailNotificationSetting, requires(permission.EditEmailNotificationSetting), blockBot)
 				apiUsersMeTags := apiUsersMe.Group("/tags")
 				{
 					apiUsersMeTags.GET("", h.GetMyUserTags, requires(permission.GetUserTag))
@@ -122,19 +177,32 @@ func (h *Handlers) Setup(e *echo.Group) {
 					apiUsersMeUnread.GET("", h.GetMyUnreadChannels, requires(permission.GetUnread))
 					apiUsersMeUnread.DELETE("/:channelID", h.ReadChannel, requires(permission.DeleteUnread))
 				}
+				apiUsersMeInbox := apiUsersMe.Group("/inbox", blockBot)
+				{
+					apiUsersMeInbox.GET("", h.GetMyInbox, requires(permission.GetUnread))
+					apiUsersMeInbox.POST("/:entryID/read", h.ReadMyInboxEntry, requires(permission.DeleteUnread))
+				}
 				apiUsersMeSubscriptions := apiUsersMe.Group("/subscriptions", blockBot)
 				{
 					apiUsersMeSubscriptions.GET("", h.GetMyChannelSubscriptions, requires(permission.GetChannelSubscription))
 					apiUsersMeSubscriptions.PUT("/:channelID", h.SetChannelSubscribeLevel, requires(permission.EditChannelSubscription))
 				}
+				apiUsersMeKeywordAlerts := apiUsersMe.Group("/keyword-alerts", blockBot)
+				{
+					apiUsersMeKeywordAlerts.GET("", h.GetMyKeywordAlerts, requires(permission.GetKeywordAlert))
+					apiUsersMeKeywordAlerts.POST("", h.CreateMyKeywordAlert, requires(permission.EditKeywordAlert))
+					apiUsersMeKeywordAlerts.DELETE("/:alertID", h.DeleteMyKeywordAlert, requires(permission.EditKeywordAlert))
+				}
 				apiUsersMeSessions := apiUsersMe.Group("/sessions", blockBot)
 				{
 					apiUsersMeSessions.GET("", h.GetMySessions, requires(permission.GetMySessions))
+					apiUsersMeSessions.DELETE("", h.RevokeMyOtherSessions, requires(permission.DeleteMySessions))
 					apiUsersMeSessions.DELETE("/:referenceID", h.RevokeMySession, requires(permission.DeleteMySessions))
 				}
 				apiUsersMeTokens := apiUsersMe.Group("/tokens", blockBot)
 				{
 					apiUsersMeTokens.GET("", h.GetMyTokens, requires(permission.GetMyTokens))
+					apiUsersMeTokens.POST("", h.CreateMyToken, requires(permission.CreateMyToken))
 					apiUsersMeTokens.DELETE("/:tokenID", h.RevokeMyToken, requires(permission.RevokeMyToken))
 				}
 				apiUsersMeExAccounts := apiUsersMe.Group("/ex-accounts", blockBot)
@@ -152,12 +220,12 @@ func (h *Handlers) Setup(e *echo.Group) {
 			apiChannelsCID := apiChannels.Group("/:channelID", retrieve.ChannelID(), requiresChannelAccessPerm)
 			{
 				apiChannelsCID.GET("", h.GetChannel, requires(permission.GetChannel))
-				apiChannelsCID.PATCH("", h.EditChannel, requires(permission.EditChannel))
+				apiChannelsCID.PATCH("", h.EditChannel, requiresInChannel(permission.EditChannel))
 				apiChannelsCID.GET("/messages", h.GetMessages, requires(permission.GetMessage))
 				apiChannelsCID.POST("/messages", h.PostMessage, bodyLimit(100), requires(permission.PostMessage))
 				apiChannelsCID.GET("/stats", h.GetChannelStats, requires(permission.GetChannel))
 				apiChannelsCID.GET("/topic", h.GetChannelTopic, requires(permission.GetChannel))
-				apiChannelsCID.PUT("/topic", h.EditChannelTopic, requires(permission.EditChannelTopic))
+				apiChannelsCID.PUT("/topic", h.EditChannelTopic, requiresInChannel(permission.EditChannelTopic))
 				apiChannelsCID.GET("/viewers", h.GetChannelViewers, requires(permission.GetChannel))
 				apiChannelsCID.GET("/pins", h.GetChannelPins, requires(permission.GetMessage))
 				apiChannelsCID.GET("/subscribers", h.GetChannelSubscribers, requires(permission.GetChannelSubscription))
@@ -165,6 +233,12 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiChannelsCID.PATCH("/subscribers", h.EditChannelSubscribers, requires(permission.EditChannelSubscription))
 				apiChannelsCID.GET("/bots", h.GetChannelBots, requires(permission.GetChannel))
 				apiChannelsCID.GET("/events", h.GetChannelEvents, requires(permission.GetChannel))
+				apiChannelsCIDRoles := apiChannelsCID.Group("/roles")
+				{
+					apiChannelsCIDRoles.GET("", h.GetChannelRoleBindings, requires(permission.GetChannel))
+					apiChannelsCIDRoles.PUT("/:userID", h.SetChannelRoleBinding, requiresInChannel(permission.ManageChannelRole), blockBot, retrieve.UserID(true))
+					apiChannelsCIDRoles.DELETE("/:userID", h.DeleteChannelRoleBinding, requiresInChannel(permission.ManageChannelRole), blockBot)
+				}
 			}
 		}
 		apiMessages := api.Group("/messages")
@@ -173,10 +247,10 @@ func (h *Handlers) Setup(e *echo.Group) {
 			{
 				apiMessagesMID.GET("", h.GetMessage, requires(permission.GetMessage))
 				apiMessagesMID.PUT("", h.EditMessage, bodyLimit(100), requires(permission.EditMessage))
-				apiMessagesMID.DELETE("", h.DeleteMessage, requires(permission.DeleteMessage))
+				apiMessagesMID.DELETE("", h.DeleteMessage, requiresInChannel(permission.DeleteMessage))
 				apiMessagesMID.GET("/pin", h.GetPin, requires(permission.GetMessage))
-				apiMessagesMID.POST("/pin", h.CreatePin, requires(permission.CreateMessagePin))
-				apiMessagesMID.DELETE("/pin", h.RemovePin, requires(permission.DeleteMessagePin))
+				apiMessagesMID.POST("/pin", h.CreatePin, requiresInChannel(permission.CreateMessagePin))
+				apiMessagesMID.DELETE("/pin", h.RemovePin, requiresInChannel(permission.DeleteMessagePin))
 				apiMessagesMID.GET("/clips", h.GetMessageClips, requires(permission.GetClipFolder))
 				apiMessagesMIDStamps := apiMessagesMID.Group("/stamps")
 				{
@@ -285,11 +359,14 @@ func (h *Handlers) Setup(e *echo.Group) {
 		{
 			apiClients.GET("", h.GetClients, requires(permission.GetClients))
 			apiClients.POST("", h.CreateClient, requires(permission.CreateClient))
+			apiClients.POST("/initial-access-tokens", h.CreateClientInitialAccessToken, requires(permission.CreateClient))
 			apiClientsCID := apiClients.Group("/:clientID", retrieve.ClientID())
 			{
 				apiClientsCID.GET("", h.GetClient, requires(permission.GetClients))
 				apiClientsCID.PATCH("", h.EditClient, requiresClientAccessPerm, requires(permission.EditMyClient))
 				apiClientsCID.DELETE("", h.DeleteClient, requiresClientAccessPerm, requires(permission.DeleteMyClient))
+				apiClientsCID.POST("/secret", h.RotateClientSecret, requiresClientAccessPerm, requires(permission.EditMyClient))
+				apiClientsCID.POST("/approve", h.ApproveClient, requires(permission.ManageOthersClient))
 			}
 		}
 		apiBots := api.Group("/bots")
@@ -336,15 +413,66 @@ func (h *Handlers) Setup(e *echo.Group) {
 				}
 			}
 		}
+		apiTwoFactorPolicy := api.Group("/two-factor-policy", requires(permission.ManageTwoFactorPolicy), blockBot)
+		{
+			apiTwoFactorPolicy.GET("", h.GetTwoFactorPolicy)
+			apiTwoFactorPolicy.PUT("", h.PutTwoFactorPolicy)
+		}
+		apiLoginLocks := api.Group("/login-locks", requires(permission.ManageLoginLock), blockBot)
+		{
+			apiLoginLocks.GET("", h.GetLoginLocks)
+			apiLoginLocks.DELETE("/ips/:ip", h.DeleteIPLoginLock)
+		}
+		api.GET("/login-audit-logs", h.GetLoginAuditLogs, requires(permission.GetLoginAuditLog), blockBot)
+		apiInvitations := api.Group("/invitations", requires(permission.CreateInvitation), blockBot)
+		{
+			apiInvitations.GET("", h.GetInvitations)
+			apiInvitations.POST("", h.CreateInvitation)
+			apiInvitationsIID := apiInvitations.Group("/:invitationID")
+			{
+				apiInvitationsIID.GET("", h.GetInvitation)
+				apiInvitationsIID.DELETE("", h.DeleteInvitation)
+			}
+		}
+		apiRoles := api.Group("/roles", requires(permission.ManageRole), blockBot)
+		{
+			apiRoles.GET("", h.GetRoles)
+			apiRoles.POST("", h.CreateRole)
+			apiRoles.POST("/preview", h.PreviewRole)
+			apiRolesRName := apiRoles.Group("/:roleName")
+			{
+				apiRolesRName.GET("", h.GetRole)
+				apiRolesRName.PATCH("", h.EditRole)
+				apiRolesRName.DELETE("", h.DeleteRole)
+			}
+		}
 		api.GET("/ws", echo.WrapHandler(h.WS), requires(permission.ConnectNotificationStream), blockBot)
 	}
 
 	apiNoAuth := e.Group("/v3")
 	{
 		apiNoAuth.GET("/version", h.GetVersion)
+		apiNoAuth.GET("/webpush/vapid-public-key", h.GetWebPushVAPIDPublicKey)
 		apiNoAuth.POST("/login", h.Login, nologin)
+		apiNoAuthLoginTOTP := apiNoAuth.Group("/login/totp", nologin)
+		{
+			apiNoAuthLoginTOTP.POST("", h.PostLoginTOTP)
+			apiNoAuthLoginTOTP.POST("/enrollment", h.PostLoginTOTPEnrollment)
+			apiNoAuthLoginTOTP.GET("/enrollment/qr-code", h.GetLoginTOTPEnrollmentQRCode)
+		}
+		apiNoAuthLoginWebAuthn := apiNoAuth.Group("/login/webauthn", nologin)
+		{
+			apiNoAuthLoginWebAuthn.POST("", h.PostLoginWebAuthn)
+			apiNoAuthLoginWebAuthn.POST("/options", h.PostLoginWebAuthnOptions)
+		}
 		apiNoAuth.POST("/logout", h.Logout)
+		apiNoAuthSignUp := apiNoAuth.Group("/signup/:inviteToken", nologin)
+		{
+			apiNoAuthSignUp.GET("", h.GetSignUpInvitation)
+			apiNoAuthSignUp.POST("", h.SignUp)
+		}
 		apiNoAuth.POST("/webhooks/:webhookID", h.PostWebhook, retrieve.WebhookID())
+		apiNoAuth.GET("/email-notification/verify", h.VerifyEmailNotificationAddress)
 		apiNoAuthPublic := apiNoAuth.Group("/public")
 		{
 			apiNoAuthPublic.GET("/icon/:username", h.GetPublicUserIcon)
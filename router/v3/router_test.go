@@ -14,11 +14,15 @@ import (
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/presence"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/utils/random"
 	"github.com/traPtitech/traQ/utils/storage"
+	"github.com/traPtitech/traQ/utils/webauthn"
 	"go.uber.org/zap"
 	"image"
 	"net/http"
@@ -38,6 +42,12 @@ const (
 
 var envs = map[string]*Env{}
 
+var testWebAuthnRP = webauthn.RelyingParty{
+	ID:     "example.com",
+	Name:   "traQ",
+	Origin: "https://example.com",
+}
+
 func TestMain(m *testing.M) {
 	user := getEnvOrDefault("MARIADB_USERNAME", "root")
 	pass := getEnvOrDefault("MARIADB_PASSWORD", "password")
@@ -76,7 +86,7 @@ func TestMain(m *testing.M) {
 			panic(err)
 		}
 		env.Repository = repo
-		env.CM, _ = channel.InitChannelManager(repo, zap.NewNop())
+		env.CM, _ = channel.InitChannelManager(repo, repo, zap.NewNop())
 
 		// テスト用サーバー作成
 		e := echo.New()
@@ -85,7 +95,13 @@ func TestMain(m *testing.M) {
 		e.HTTPErrorHandler = extension.ErrorHandler(zap.NewNop())
 		e.Use(extension.Wrap(repo, env.CM))
 
-		r, err := rbac.New(db)
+		r, err := rbac.New(db, env.Hub, zap.NewNop())
+		if err != nil {
+			panic(err)
+		}
+		env.RBAC = r
+		oc := counter.NewOnlineCounter(env.Hub)
+		pm, err := presence.NewManager(repo, env.Hub, oc, zap.NewNop())
 		if err != nil {
 			panic(err)
 		}
@@ -95,7 +111,10 @@ func TestMain(m *testing.M) {
 			Hub:            env.Hub,
 			SessStore:      env.SessStore,
 			ChannelManager: env.CM,
+			OC:             oc,
+			Presence:       pm,
 			Logger:         zap.NewNop(),
+			Email:          email.NewNullClient(),
 			Imaging: imaging.NewProcessor(imaging.Config{
 				MaxPixels:        1000 * 1000,
 				Concurrency:      1,
@@ -105,6 +124,7 @@ func TestMain(m *testing.M) {
 			Config: Config{
 				Version:  "version",
 				Revision: "revision",
+				WebAuthn: testWebAuthnRP,
 			},
 		}
 		handlers.Setup(e.Group("/api"))
@@ -132,6 +152,7 @@ type Env struct {
 	CM         channel.Manager
 	Hub        *hub.Hub
 	SessStore  session.Store
+	RBAC       rbac.RBAC
 }
 
 // Setup テストセットアップ
@@ -8,6 +8,9 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/validator"
 	"go.uber.org/zap"
 	"net/http"
@@ -36,15 +39,27 @@ func (h *Handlers) Login(c echo.Context) error {
 		return err
 	}
 
+	attempt := &utils.LoginAttempt{Name: req.Name, IP: c.RealIP(), Method: utils.LoginMethodV3}
 	user, err := h.Repo.GetUserByName(req.Name, false)
-	if err != nil {
-		switch err {
-		case repository.ErrNotFound:
-			h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
-			return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
-		default:
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	attempt.User = user
+
+	// ログイン試行のロック確認
+	if d, err := utils.CheckLoginLock(h.Repo, attempt); err != nil {
+		return herror.InternalServerError(err)
+	} else if d > 0 {
+		h.L(c).Info("an api login attempt failed: locked", zap.String("username", req.Name), zap.String("ip", attempt.IP))
+		return utils.LoginLockedError(c, d)
+	}
+
+	if user == nil {
+		h.L(c).Info("an api login attempt failed: unknown user", zap.String("username", req.Name))
+		if err := utils.RecordLoginFailure(h.Repo, attempt, model.LoginFailureReasonUnknownUser); err != nil {
 			return herror.InternalServerError(err)
 		}
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid name")
 	}
 
 	// ユーザーのアカウント状態の確認
@@ -56,15 +71,32 @@ func (h *Handlers) Login(c echo.Context) error {
 	// パスワード検証
 	if err := user.Authenticate(req.Password); err != nil {
 		h.L(c).Info("an api login attempt failed: wrong password", zap.String("username", req.Name))
+		if err := utils.RecordLoginFailure(h.Repo, attempt, model.LoginFailureReasonWrongPassword); err != nil {
+			return herror.InternalServerError(err)
+		}
 		return echo.NewHTTPError(http.StatusUnauthorized, err)
 	}
+
+	// 二要素認証の確認
+	// アカウントの連続失敗回数は二要素認証の完了時にリセットする
+	state, err := utils.GetTwoFactorState(h.Repo, user)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if state != utils.TwoFactorNone {
+		h.L(c).Info("an api login attempt requires two-factor authentication", zap.String("username", req.Name))
+		return utils.StartLoginChallenge(c, h.Repo, h.SessStore, user.GetID(), state, utils.LoginMethodV3, nil)
+	}
 	h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 
-	if _, err := h.SessStore.RenewSession(c, user.GetID()); err != nil {
+	if err := utils.RecordLoginSuccess(h.Repo, user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if _, err := h.SessStore.RenewSession(c, user.GetID(), utils.LoginMethodV3); err != nil {
 		return herror.InternalServerError(err)
 	}
 
-	if redirect := c.QueryParam("redirect"); len(redirect) > 0 {
+	if redirect := utils.LoginRedirect(c); len(redirect) > 0 {
 		return c.Redirect(http.StatusFound, redirect)
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -86,7 +118,7 @@ func (h *Handlers) Logout(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
-	if redirect := c.QueryParam("redirect"); len(redirect) > 0 {
+	if redirect := utils.LoginRedirect(c); len(redirect) > 0 {
 		return c.Redirect(http.StatusFound, redirect)
 	}
 	return c.NoContent(http.StatusNoContent)
@@ -94,36 +126,107 @@ func (h *Handlers) Logout(c echo.Context) error {
 
 // GetMySessions GET /users/me/sessions
 func (h *Handlers) GetMySessions(c echo.Context) error {
-	userID := getRequestUserID(c)
+	current, err := h.getCurrentSessionRefID(c)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return h.getUserSessions(c, getRequestUserID(c), current)
+}
 
-	ses, err := h.SessStore.GetSessionsByUserID(userID)
+// RevokeMySession DELETE /users/me/sessions/:referenceID
+func (h *Handlers) RevokeMySession(c echo.Context) error {
+	return h.revokeUserSession(c, getRequestUserID(c), getParamAsUUID(c, consts.ParamReferenceID))
+}
+
+// RevokeMyOtherSessions DELETE /users/me/sessions
+func (h *Handlers) RevokeMyOtherSessions(c echo.Context) error {
+	current, err := h.getCurrentSessionRefID(c)
 	if err != nil {
 		return herror.InternalServerError(err)
 	}
+	return h.revokeUserSessions(c, getRequestUserID(c), current)
+}
 
-	type response struct {
-		ID       uuid.UUID `json:"id"`
-		IssuedAt time.Time `json:"issuedAt"`
+// GetUserSessions GET /users/:userID/sessions
+func (h *Handlers) GetUserSessions(c echo.Context) error {
+	return h.getUserSessions(c, getParamUser(c).GetID(), uuid.Nil)
+}
+
+// RevokeUserSession DELETE /users/:userID/sessions/:referenceID
+func (h *Handlers) RevokeUserSession(c echo.Context) error {
+	return h.revokeUserSession(c, getParamUser(c).GetID(), getParamAsUUID(c, consts.ParamReferenceID))
+}
+
+// RevokeUserSessions DELETE /users/:userID/sessions
+func (h *Handlers) RevokeUserSessions(c echo.Context) error {
+	return h.revokeUserSessions(c, getParamUser(c).GetID(), uuid.Nil)
+}
+
+// getCurrentSessionRefID リクエストのセッションの参照IDを取得します
+//
+// OAuth2トークンによるリクエストなどセッションがない場合はuuid.Nilを返します。
+func (h *Handlers) getCurrentSessionRefID(c echo.Context) (uuid.UUID, error) {
+	cookie, err := c.Cookie(session.CookieName)
+	if err != nil {
+		return uuid.Nil, nil
+	}
+	sess, err := h.SessStore.GetSessionByToken(cookie.Value)
+	if err != nil {
+		if err == session.ErrSessionNotFound {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
+	}
+	return sess.RefID(), nil
+}
+
+func (h *Handlers) getUserSessions(c echo.Context, userID uuid.UUID, current uuid.UUID) error {
+	ses, err := h.SessStore.GetSessionsByUserID(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
 	}
+	return c.JSON(http.StatusOK, formatLoginSessions(ses, current))
+}
 
-	res := make([]response, len(ses))
-	for k, v := range ses {
-		res[k] = response{
-			ID:       v.RefID(),
-			IssuedAt: v.CreatedAt(),
+func (h *Handlers) revokeUserSession(c echo.Context, userID uuid.UUID, referenceID uuid.UUID) error {
+	ses, err := h.SessStore.GetSessionsByUserID(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	// 他ユーザーのセッションは存在しないものとして扱う
+	for _, s := range ses {
+		if s.RefID() == referenceID {
+			if err := h.SessStore.RevokeSessionByRefID(referenceID); err != nil {
+				return herror.InternalServerError(err)
+			}
+			break
 		}
 	}
 
-	return c.JSON(http.StatusOK, res)
+	return c.NoContent(http.StatusNoContent)
 }
 
-// RevokeMySession DELETE /users/me/sessions/:referenceID
-func (h *Handlers) RevokeMySession(c echo.Context) error {
-	referenceID := getParamAsUUID(c, consts.ParamReferenceID)
+// revokeUserSessions ユーザーの、exceptを除く全てのセッションを無効化します
+func (h *Handlers) revokeUserSessions(c echo.Context, userID uuid.UUID, except uuid.UUID) error {
+	if except == uuid.Nil {
+		if err := h.SessStore.RevokeSessionsByUserID(userID); err != nil {
+			return herror.InternalServerError(err)
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
 
-	if err := h.SessStore.RevokeSessionByRefID(referenceID); err != nil {
+	ses, err := h.SessStore.GetSessionsByUserID(userID)
+	if err != nil {
 		return herror.InternalServerError(err)
 	}
+	for _, s := range ses {
+		if s.RefID() == except {
+			continue
+		}
+		if err := h.SessStore.RevokeSessionByRefID(s.RefID()); err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
 
 	return c.NoContent(http.StatusNoContent)
 }
@@ -138,25 +241,109 @@ func (h *Handlers) GetMyTokens(c echo.Context) error {
 	}
 
 	type response struct {
-		ID       uuid.UUID          `json:"id"`
-		ClientID string             `json:"clientId"`
-		Scopes   model.AccessScopes `json:"scopes"`
-		IssuedAt time.Time          `json:"issuedAt"`
+		ID         uuid.UUID          `json:"id"`
+		ClientID   string             `json:"clientId"`
+		Name       string             `json:"name"`
+		Scopes     model.AccessScopes `json:"scopes"`
+		IssuedAt   time.Time          `json:"issuedAt"`
+		ExpiresAt  time.Time          `json:"expiresAt"`
+		LastUsedAt optional.Time      `json:"lastUsedAt"`
 	}
 
 	res := make([]response, len(ot))
 	for i, v := range ot {
 		res[i] = response{
-			ID:       v.ID,
-			ClientID: v.ClientID,
-			Scopes:   v.Scopes,
-			IssuedAt: v.CreatedAt,
+			ID:         v.ID,
+			ClientID:   v.ClientID,
+			Name:       v.Name,
+			Scopes:     v.Scopes,
+			IssuedAt:   v.CreatedAt,
+			ExpiresAt:  v.ExpiresAt(),
+			LastUsedAt: v.LastUsedAt,
 		}
 	}
 
 	return c.JSON(http.StatusOK, res)
 }
 
+// PostMyTokenRequest POST /users/me/tokens リクエストボディ
+type PostMyTokenRequest struct {
+	Name      string             `json:"name"`
+	Scopes    model.AccessScopes `json:"scopes"`
+	ExpiresIn int                `json:"expiresIn"`
+}
+
+func (r PostMyTokenRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, validator.PersonalAccessTokenNameRuleRequired...),
+		vd.Field(&r.Scopes, vd.Required, vd.By(func(value interface{}) error {
+			// OpenID Connectのスコープはパーソナルアクセストークンでは使えない
+			return vd.Validate(value.(model.AccessScopes).StringArray(), vd.Each(vd.In("read", "write", "manage_bot")))
+		})),
+		vd.Field(&r.ExpiresIn, vd.Required, vd.Min(minPersonalAccessTokenExpiresIn), vd.Max(maxPersonalAccessTokenExpiresIn)),
+	)
+}
+
+const (
+	// maxPersonalAccessTokensPerUser 1ユーザーあたりのパーソナルアクセストークンの最大数
+	maxPersonalAccessTokensPerUser = 50
+	// minPersonalAccessTokenExpiresIn パーソナルアクセストークンの有効期間の最小値(秒)
+	minPersonalAccessTokenExpiresIn = 60 * 60
+	// maxPersonalAccessTokenExpiresIn パーソナルアクセストークンの有効期間の最大値(秒)
+	maxPersonalAccessTokenExpiresIn = 60 * 60 * 24 * 365
+)
+
+// CreateMyToken POST /users/me/tokens
+func (h *Handlers) CreateMyToken(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PostMyTokenRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ot, err := h.Repo.GetTokensByUser(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	count := 0
+	for _, v := range ot {
+		if v.IsPersonal() {
+			count++
+		}
+	}
+	if count >= maxPersonalAccessTokensPerUser {
+		return herror.BadRequest("too many personal access tokens")
+	}
+
+	t, err := h.Repo.IssuePersonalAccessToken(userID, req.Name, req.Scopes, req.ExpiresIn)
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	type response struct {
+		ID        uuid.UUID          `json:"id"`
+		Name      string             `json:"name"`
+		Scopes    model.AccessScopes `json:"scopes"`
+		Token     string             `json:"token"`
+		IssuedAt  time.Time          `json:"issuedAt"`
+		ExpiresAt time.Time          `json:"expiresAt"`
+	}
+	return c.JSON(http.StatusCreated, &response{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		Token:     t.AccessToken,
+		IssuedAt:  t.CreatedAt,
+		ExpiresAt: t.ExpiresAt(),
+	})
+}
+
 // RevokeMyToken DELETE /users/me/tokens/:tokenID
 func (h *Handlers) RevokeMyToken(c echo.Context) error {
 	tokenID := getParamAsUUID(c, consts.ParamTokenID)
@@ -0,0 +1,183 @@
+package v3
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_GetMySessions(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/users/me/sessions"
+	env := Setup(t, common)
+
+	t.Run("not logged in", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		other := env.S(t, user.GetID())
+		e := env.R(t)
+
+		s := e.POST("/api/v3/login").
+			WithHeader("User-Agent", "traQ-test").
+			WithHeader("X-Forwarded-For", "192.0.2.10").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusNoContent).
+			Cookie(session.CookieName).Value().Raw()
+
+		obj := e.GET(path).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		obj.Length().Equal(2)
+		for _, v := range obj.Iter() {
+			o := v.Object()
+			if o.Value("current").Boolean().Raw() {
+				o.Value("userAgent").String().Equal("traQ-test")
+				o.Value("ip").String().Equal("192.0.2.10")
+				o.Value("loginMethod").String().Equal(utils.LoginMethodV3)
+			} else {
+				sess, err := env.SessStore.GetSessionByToken(other)
+				require.NoError(t, err)
+				o.Value("id").String().Equal(sess.RefID().String())
+			}
+		}
+	})
+}
+
+func TestHandlers_RevokeMySession(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/users/me/sessions/{referenceID}"
+	env := Setup(t, common)
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		sess, err := env.SessStore.IssueSession(user.GetID(), nil)
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.DELETE(path, sess.RefID()).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusNoContent)
+
+		_, err = env.SessStore.GetSessionByToken(sess.Token())
+		assert.Equal(t, session.ErrSessionNotFound, err)
+	})
+
+	t.Run("other user's session", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		other := env.CreateUser(t, rand)
+		sess, err := env.SessStore.IssueSession(other.GetID(), nil)
+		require.NoError(t, err)
+		e := env.R(t)
+
+		e.DELETE(path, sess.RefID()).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusNoContent)
+
+		_, err = env.SessStore.GetSessionByToken(sess.Token())
+		assert.NoError(t, err)
+	})
+}
+
+func TestHandlers_RevokeMyOtherSessions(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/users/me/sessions"
+	env := Setup(t, common)
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		other := env.S(t, user.GetID())
+		current := env.S(t, user.GetID())
+		e := env.R(t)
+
+		e.DELETE(path).
+			WithCookie(session.CookieName, current).
+			Expect().
+			Status(http.StatusNoContent)
+
+		_, err := env.SessStore.GetSessionByToken(other)
+		assert.Equal(t, session.ErrSessionNotFound, err)
+		_, err = env.SessStore.GetSessionByToken(current)
+		assert.NoError(t, err)
+	})
+}
+
+func TestHandlers_UserSessions(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("forbidden", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		other := env.CreateUser(t, rand)
+		e := env.R(t)
+
+		e.GET("/api/v3/users/{userID}/sessions", other.GetID()).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin})
+		require.NoError(t, err)
+		user := env.CreateUser(t, rand)
+		s1, err := env.SessStore.IssueSession(user.GetID(), nil)
+		require.NoError(t, err)
+		s2, err := env.SessStore.IssueSession(user.GetID(), nil)
+		require.NoError(t, err)
+		s3, err := env.SessStore.IssueSession(user.GetID(), nil)
+		require.NoError(t, err)
+		adminSess := env.S(t, admin.GetID())
+		e := env.R(t)
+
+		e.GET("/api/v3/users/{userID}/sessions", user.GetID()).
+			WithCookie(session.CookieName, adminSess).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array().
+			Length().
+			Equal(3)
+
+		e.DELETE("/api/v3/users/{userID}/sessions/{referenceID}", user.GetID(), s1.RefID()).
+			WithCookie(session.CookieName, adminSess).
+			Expect().
+			Status(http.StatusNoContent)
+		_, err = env.SessStore.GetSessionByToken(s1.Token())
+		assert.Equal(t, session.ErrSessionNotFound, err)
+
+		e.DELETE("/api/v3/users/{userID}/sessions", user.GetID()).
+			WithCookie(session.CookieName, adminSess).
+			Expect().
+			Status(http.StatusNoContent)
+		for _, s := range []session.Session{s2, s3} {
+			_, err = env.SessStore.GetSessionByToken(s.Token())
+			assert.Equal(t, session.ErrSessionNotFound, err)
+		}
+	})
+}
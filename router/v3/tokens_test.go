@@ -0,0 +1,106 @@
+package v3
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/router/session"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_CreateMyToken(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/users/me/tokens"
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	s := env.S(t, user.GetID())
+
+	t.Run("not logged in", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithJSON(map[string]interface{}{"name": "script", "scopes": []string{"read"}, "expiresIn": 86400}).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		for _, req := range []map[string]interface{}{
+			{"name": "", "scopes": []string{"read"}, "expiresIn": 86400},
+			{"name": "script", "scopes": []string{}, "expiresIn": 86400},
+			{"name": "script", "scopes": []string{"openid"}, "expiresIn": 86400},
+			{"name": "script", "scopes": []string{"read"}, "expiresIn": 60},
+			{"name": "script", "scopes": []string{"read"}, "expiresIn": 60 * 60 * 24 * 366},
+		} {
+			e.POST(path).
+				WithCookie(session.CookieName, s).
+				WithJSON(req).
+				Expect().
+				Status(http.StatusBadRequest)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		s := env.S(t, user.GetID())
+		e := env.R(t)
+
+		obj := e.POST(path).
+			WithCookie(session.CookieName, s).
+			WithJSON(map[string]interface{}{"name": "script", "scopes": []string{"read"}, "expiresIn": 86400}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().
+			Object()
+		obj.Value("name").String().Equal("script")
+		obj.Value("scopes").Array().ContainsOnly("read")
+		token := obj.Value("token").String().NotEmpty().Raw()
+		tokenID := obj.Value("id").String().Raw()
+
+		// OAuth2トークンと同様にBearerで認証できる
+		e.GET("/api/v3/users/me").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("id").
+			String().
+			Equal(user.GetID().String())
+
+		// スコープ外の操作はできない
+		e.POST(path).
+			WithHeader("Authorization", "Bearer "+token).
+			WithJSON(map[string]interface{}{"name": "script", "scopes": []string{"read"}, "expiresIn": 86400}).
+			Expect().
+			Status(http.StatusForbidden)
+
+		ot, err := env.Repository.GetTokenByAccess(token)
+		require.NoError(t, err)
+		assert.True(t, ot.IsPersonal())
+		assert.True(t, ot.LastUsedAt.Valid)
+
+		arr := e.GET(path).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+		arr.Length().Equal(1)
+		arr.First().Object().Value("name").String().Equal("script")
+		arr.First().Object().Value("lastUsedAt").NotNull()
+
+		e.DELETE(path+"/{tokenID}", tokenID).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNoContent)
+
+		e.GET("/api/v3/users/me").
+			WithHeader("Authorization", "Bearer "+token).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+}
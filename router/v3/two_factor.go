@@ -0,0 +1,379 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/skip2/go-qrcode"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/utils/totp"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+var errInvalidTwoFactorCode = herror.BadRequest("invalid code")
+
+// TOTPEnrollment TOTP登録情報
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes リカバリーコード
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// PostLoginTOTPRequest POST /login/totp リクエストボディ
+type PostLoginTOTPRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (r PostLoginTOTPRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Code, vd.Required.When(len(r.RecoveryCode) == 0), vd.Length(6, 6)),
+		vd.Field(&r.RecoveryCode, vd.Length(0, 32)),
+	)
+}
+
+// PostLoginTOTP POST /login/totp
+func (h *Handlers) PostLoginTOTP(c echo.Context) error {
+	var req PostLoginTOTPRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	lc, err := utils.GetLoginChallenge(c, h.SessStore)
+	if err != nil {
+		return err
+	}
+	user, err := h.Repo.GetUser(lc.UserID, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if !user.IsActive() {
+		return herror.Forbidden("this account is currently suspended")
+	}
+
+	var codes []string
+	switch lc.Type {
+	case utils.LoginChallengeTypeTOTP:
+		ok, err := utils.VerifyTwoFactorCode(h.Repo, user.GetID(), req.Code, req.RecoveryCode)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if !ok {
+			h.L(c).Info("an api login attempt failed: wrong two-factor code", zap.String("username", user.GetName()))
+			return utils.FailLoginChallenge(c, h.Repo, h.SessStore, lc)
+		}
+
+	case utils.LoginChallengeTypeTOTPEnrollment:
+		codes, err = h.enableTOTP(user.GetID(), req.Code)
+		if err != nil {
+			if err == errInvalidTwoFactorCode {
+				return utils.FailLoginChallenge(c, h.Repo, h.SessStore, lc)
+			}
+			return err
+		}
+
+	case utils.LoginChallengeTypeWebAuthn:
+		return herror.BadRequest("totp is not enabled")
+
+	default:
+		return herror.Unauthorized("no login challenge")
+	}
+	h.L(c).Info("an api login attempt succeeded", zap.String("username", user.GetName()))
+
+	if err := utils.CompleteLoginChallenge(c, h.Repo, h.SessStore, lc); err != nil {
+		return err
+	}
+
+	if codes != nil {
+		return c.JSON(http.StatusOK, &RecoveryCodes{RecoveryCodes: codes})
+	}
+	if len(lc.Redirect) > 0 {
+		return c.Redirect(http.StatusFound, lc.Redirect)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostLoginTOTPEnrollment POST /login/totp/enrollment
+func (h *Handlers) PostLoginTOTPEnrollment(c echo.Context) error {
+	lc, err := utils.GetLoginChallenge(c, h.SessStore)
+	if err != nil {
+		return err
+	}
+	if lc.Type != utils.LoginChallengeTypeTOTPEnrollment {
+		return herror.BadRequest("totp enrollment is not required")
+	}
+	user, err := h.Repo.GetUser(lc.UserID, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return h.startTOTPEnrollment(c, user)
+}
+
+// GetLoginTOTPEnrollmentQRCode GET /login/totp/enrollment/qr-code
+func (h *Handlers) GetLoginTOTPEnrollmentQRCode(c echo.Context) error {
+	lc, err := utils.GetLoginChallenge(c, h.SessStore)
+	if err != nil {
+		return err
+	}
+	if lc.Type != utils.LoginChallengeTypeTOTPEnrollment {
+		return herror.BadRequest("totp enrollment is not required")
+	}
+	user, err := h.Repo.GetUser(lc.UserID, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return h.serveTOTPEnrollmentQRCode(c, user)
+}
+
+// TwoFactorStatus GET /users/me/totp レスポンス
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+// GetMyTOTP GET /users/me/totp
+func (h *Handlers) GetMyTOTP(c echo.Context) error {
+	user := getRequestUser(c)
+
+	res := &TwoFactorStatus{}
+	t, err := h.Repo.GetUserTOTP(user.GetID())
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	res.Enabled = t != nil && t.Enabled
+	res.Required, err = h.Repo.IsTwoFactorRequired(user.GetRole())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if res.Enabled {
+		res.RecoveryCodesRemaining, err = h.Repo.GetUserRecoveryCodeCount(user.GetID())
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// PostMyTOTP POST /users/me/totp
+func (h *Handlers) PostMyTOTP(c echo.Context) error {
+	return h.startTOTPEnrollment(c, getRequestUser(c))
+}
+
+// GetMyTOTPQRCode GET /users/me/totp/qr-code
+func (h *Handlers) GetMyTOTPQRCode(c echo.Context) error {
+	return h.serveTOTPEnrollmentQRCode(c, getRequestUser(c))
+}
+
+// PostTOTPCodeRequest TOTPコードを含むリクエストボディ
+type PostTOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r PostTOTPCodeRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Code, vd.Required, vd.Length(6, 6)),
+	)
+}
+
+// PostMyTOTPConfirm POST /users/me/totp/confirm
+func (h *Handlers) PostMyTOTPConfirm(c echo.Context) error {
+	var req PostTOTPCodeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	codes, err := h.enableTOTP(getRequestUserID(c), req.Code)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, &RecoveryCodes{RecoveryCodes: codes})
+}
+
+// PostMyRecoveryCodes POST /users/me/totp/recovery-codes
+func (h *Handlers) PostMyRecoveryCodes(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PostTOTPCodeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	ok, err := utils.VerifyTwoFactorCode(h.Repo, userID, req.Code, "")
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	codes := totp.GenerateRecoveryCodes()
+	if err := h.Repo.ReplaceUserRecoveryCodes(userID, hashRecoveryCodes(codes)); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, &RecoveryCodes{RecoveryCodes: codes})
+}
+
+// PostMyTOTPDisable POST /users/me/totp/disable
+func (h *Handlers) PostMyTOTPDisable(c echo.Context) error {
+	user := getRequestUser(c)
+
+	var req PostLoginTOTPRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	required, err := h.Repo.IsTwoFactorRequired(user.GetRole())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if required {
+		return herror.Forbidden("two-factor authentication is required for your role")
+	}
+
+	ok, err := utils.VerifyTwoFactorCode(h.Repo, user.GetID(), req.Code, req.RecoveryCode)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if !ok {
+		return errInvalidTwoFactorCode
+	}
+
+	if err := h.Repo.DeleteUserTOTP(user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TwoFactorPolicy GET /two-factor-policy レスポンス
+type TwoFactorPolicy struct {
+	RequiredRoles []string `json:"requiredRoles"`
+}
+
+func (r TwoFactorPolicy) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.RequiredRoles, vd.NotNil),
+	)
+}
+
+// GetTwoFactorPolicy GET /two-factor-policy
+func (h *Handlers) GetTwoFactorPolicy(c echo.Context) error {
+	roles, err := h.Repo.GetTwoFactorRequiredRoles()
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, &TwoFactorPolicy{RequiredRoles: roles})
+}
+
+// PutTwoFactorPolicy PUT /two-factor-policy
+func (h *Handlers) PutTwoFactorPolicy(c echo.Context) error {
+	var req TwoFactorPolicy
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.Repo.SetTwoFactorRequiredRoles(req.RequiredRoles); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	h.L(c).Info("two-factor policy updated", zap.Strings("requiredRoles", req.RequiredRoles), zap.Stringer("updaterId", getRequestUserID(c)))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// startTOTPEnrollment 新しいTOTPシークレットを生成し、登録手続きを開始します
+func (h *Handlers) startTOTPEnrollment(c echo.Context, user model.UserInfo) error {
+	secret, uri, err := totp.Generate(user.GetName())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if _, err := h.Repo.SetUserTOTPSecret(user.GetID(), secret); err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("totp is already enabled")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, &TOTPEnrollment{Secret: secret, URI: uri})
+}
+
+// serveTOTPEnrollmentQRCode 登録手続き中のTOTPのQRコード画像を返します
+func (h *Handlers) serveTOTPEnrollmentQRCode(c echo.Context, user model.UserInfo) error {
+	t, err := h.Repo.GetUserTOTP(user.GetID())
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound("totp enrollment has not been started")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if t.Enabled {
+		return herror.NotFound("totp enrollment has not been started")
+	}
+
+	png, err := qrcode.Encode(totp.URL(user.GetName(), t.Secret), qrcode.Medium, 256)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	c.Response().Header().Set(consts.HeaderCacheControl, "no-store")
+	return c.Blob(http.StatusOK, consts.MimeImagePNG, png)
+}
+
+// enableTOTP 登録手続き中のTOTPをコードで検証して有効化し、リカバリーコードを返します
+func (h *Handlers) enableTOTP(userID uuid.UUID, code string) ([]string, error) {
+	t, err := h.Repo.GetUserTOTP(userID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound("totp enrollment has not been started")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if t.Enabled {
+		return nil, herror.Conflict("totp is already enabled")
+	}
+
+	step, ok := totp.Validate(code, t.Secret, t.LastStep, time.Now())
+	if !ok {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	codes := totp.GenerateRecoveryCodes()
+	if err := h.Repo.EnableUserTOTP(userID, step, hashRecoveryCodes(codes)); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.Conflict("totp is already enabled")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	return codes, nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	return hashes
+}
@@ -0,0 +1,71 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"net/http"
+)
+
+// GetMyUserBlocks GET /users/me/blocks
+func (h *Handlers) GetMyUserBlocks(c echo.Context) error {
+	blocks, err := h.Repo.GetUserBlocks(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusOK, formatUserBlocks(blocks))
+}
+
+// PutMyUserBlockRequest PUT /users/me/blocks/:userID リクエストボディ
+type PutMyUserBlockRequest struct {
+	Type model.UserBlockType `json:"type"`
+}
+
+func (r PutMyUserBlockRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Type, vd.Required, vd.In(model.UserBlockTypeBlock, model.UserBlockTypeMute)),
+	)
+}
+
+// PutMyUserBlock PUT /users/me/blocks/:userID
+func (h *Handlers) PutMyUserBlock(c echo.Context) error {
+	userID := getRequestUserID(c)
+	targetID := getParamAsUUID(c, consts.ParamUserID)
+
+	var req PutMyUserBlockRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.Repo.SetUserBlock(userID, targetID, req.Type); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteMyUserBlock DELETE /users/me/blocks/:userID
+func (h *Handlers) DeleteMyUserBlock(c echo.Context) error {
+	userID := getRequestUserID(c)
+	targetID := getParamAsUUID(c, consts.ParamUserID)
+
+	if err := h.Repo.DeleteUserBlock(userID, targetID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,119 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"net/http"
+	"time"
+)
+
+// MyStatus GET /users/me/status レスポンス
+type MyStatus struct {
+	UserStatus
+	Presence     model.Presence     `json:"presence"`
+	PresenceMode model.PresenceMode `json:"presenceMode"`
+}
+
+func (h *Handlers) formatMyStatus(s *model.UserStatus) *MyStatus {
+	return &MyStatus{
+		UserStatus:   formatUserStatus(s),
+		Presence:     h.Presence.GetPresence(s.UserID),
+		PresenceMode: s.Mode,
+	}
+}
+
+// GetMyStatus GET /users/me/status
+func (h *Handlers) GetMyStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.formatMyStatus(h.Presence.GetStatus(getRequestUserID(c))))
+}
+
+// PutMyStatusRequest PUT /users/me/status リクエストボディ
+type PutMyStatusRequest struct {
+	Text      string        `json:"text"`
+	StampID   optional.UUID `json:"stampId"`
+	ExpiresAt optional.Time `json:"expiresAt"`
+}
+
+func (r PutMyStatusRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Text, vd.RuneLength(0, 100)),
+		vd.Field(&r.ExpiresAt, vd.By(func(value interface{}) error {
+			t := value.(optional.Time)
+			if t.Valid && !t.Time.After(time.Now()) {
+				return vd.NewError("validation_invalid_expires_at", "must be in the future")
+			}
+			return nil
+		})),
+	)
+}
+
+// PutMyStatus PUT /users/me/status
+func (h *Handlers) PutMyStatus(c echo.Context) error {
+	var req PutMyStatusRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if req.StampID.Valid {
+		ok, err := h.Repo.StampExists(req.StampID.UUID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if !ok {
+			return herror.BadRequest("stamp not found")
+		}
+	}
+
+	s, err := h.Presence.SetStatusMessage(getRequestUserID(c), req.Text, req.StampID, req.ExpiresAt)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, h.formatMyStatus(s))
+}
+
+// DeleteMyStatus DELETE /users/me/status
+func (h *Handlers) DeleteMyStatus(c echo.Context) error {
+	if _, err := h.Presence.SetStatusMessage(getRequestUserID(c), "", optional.UUID{}, optional.Time{}); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PutMyPresenceRequest PUT /users/me/presence リクエストボディ
+type PutMyPresenceRequest struct {
+	Mode model.PresenceMode `json:"mode"`
+}
+
+func (r PutMyPresenceRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Mode, vd.Required, vd.By(func(value interface{}) error {
+			if !value.(model.PresenceMode).Valid() {
+				return vd.NewError("validation_invalid_presence_mode", "must be one of auto, away, busy, invisible")
+			}
+			return nil
+		})),
+	)
+}
+
+// PutMyPresence PUT /users/me/presence
+func (h *Handlers) PutMyPresence(c echo.Context) error {
+	var req PutMyPresenceRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	s, err := h.Presence.SetPresenceMode(getRequestUserID(c), req.Mode)
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusOK, h.formatMyStatus(s))
+}
@@ -66,7 +66,7 @@ func (h *Handlers) CreateUser(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusCreated, formatUserDetail(user, []model.UserTag{}, []uuid.UUID{}))
+	return c.JSON(http.StatusCreated, formatUserDetail(user, []model.UserTag{}, []uuid.UUID{}, h.Presence.GetStatus(user.GetID()), h.Presence.GetPresence(user.GetID())))
 }
 
 // GetMe GET /users/me
@@ -83,21 +83,25 @@ func (h *Handlers) GetMe(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
+	status := h.Presence.GetStatus(me.GetID())
 	return c.JSON(http.StatusOK, echo.Map{
-		"id":          me.GetID(),
-		"bio":         me.GetBio(),
-		"groups":      groups,
-		"tags":        formatUserTags(tags),
-		"updatedAt":   me.GetUpdatedAt(),
-		"lastOnline":  me.GetLastOnline(),
-		"twitterId":   me.GetTwitterID(),
-		"name":        me.GetName(),
-		"displayName": me.GetResponseDisplayName(),
-		"iconFileId":  me.GetIconFileID(),
-		"bot":         me.IsBot(),
-		"state":       me.GetState().Int(),
-		"permissions": h.RBAC.GetGrantedPermissions(me.GetRole()),
-		"homeChannel": me.GetHomeChannel(),
+		"id":           me.GetID(),
+		"bio":          me.GetBio(),
+		"groups":       groups,
+		"tags":         formatUserTags(tags),
+		"updatedAt":    me.GetUpdatedAt(),
+		"lastOnline":   me.GetLastOnline(),
+		"twitterId":    me.GetTwitterID(),
+		"name":         me.GetName(),
+		"displayName":  me.GetResponseDisplayName(),
+		"iconFileId":   me.GetIconFileID(),
+		"bot":          me.IsBot(),
+		"state":        me.GetState().Int(),
+		"permissions":  h.RBAC.GetGrantedPermissions(me.GetRole()),
+		"homeChannel":  me.GetHomeChannel(),
+		"status":       formatUserStatus(status),
+		"presence":     h.Presence.GetPresence(me.GetID()),
+		"presenceMode": status.Mode,
 	})
 }
 
@@ -324,7 +328,7 @@ func (h *Handlers) GetUser(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
-	return c.JSON(http.StatusOK, formatUserDetail(user, tags, groups))
+	return c.JSON(http.StatusOK, formatUserDetail(user, tags, groups, h.Presence.GetStatus(user.GetID()), h.Presence.GetPresence(user.GetID())))
 }
 
 // PatchUserRequest PATCH /users/:userID リクエストボディ
@@ -364,7 +368,12 @@ func (h *Handlers) EditUser(c echo.Context) error {
 	}
 
 	if err := h.Repo.UpdateUser(userID, args); err != nil {
-		return herror.InternalServerError(err)
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -14,6 +14,7 @@ import (
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
 )
 
 // NotImplemented 未実装API. 501 NotImplementedを返す
@@ -153,6 +154,22 @@ func serveMessages(c echo.Context, repo repository.Repository, query repository.
 	if err != nil {
 		return herror.InternalServerError(err)
 	}
+	res := formatMessages(messages)
+	if err := markBlockedMessages(repo, getRequestUserID(c), res...); err != nil {
+		return herror.InternalServerError(err)
+	}
 	c.Response().Header().Set(consts.HeaderMore, strconv.FormatBool(more))
-	return c.JSON(http.StatusOK, formatMessages(messages))
+	return c.JSON(http.StatusOK, res)
+}
+
+// markBlockedMessages リクエストしたユーザーがブロックしているユーザーのメッセージのBlockedをtrueにします
+func markBlockedMessages(repo repository.Repository, userID uuid.UUID, messages ...*Message) error {
+	blocked, err := utils.GetBlockedUserIDs(repo, userID)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		m.Blocked = blocked.Contains(m.UserID)
+	}
+	return nil
 }
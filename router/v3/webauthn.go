@@ -0,0 +1,475 @@
+package v3
+
+import (
+	"encoding/gob"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/utils/webauthn"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	webAuthnChallengeSessionKey = "webauthn_challenge"
+	webAuthnTimeout             = 5 * time.Minute
+
+	webAuthnPurposeRegistration = "registration"
+	webAuthnPurposeLogin        = "login"
+)
+
+func init() {
+	gob.Register(webAuthnChallenge{})
+}
+
+// webAuthnChallenge 発行済みのWebAuthnチャレンジ
+type webAuthnChallenge struct {
+	Challenge []byte
+	Purpose   string
+	// UserID 登録時は登録するユーザー、二要素認証時は認証中のユーザー、パスワードレスログイン時はuuid.Nil
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+// PublicKeyCredentialDescriptor WebAuthn PublicKeyCredentialDescriptor
+type PublicKeyCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// PublicKeyCredentialParameters WebAuthn PublicKeyCredentialParameters
+type PublicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// PublicKeyCredentialRpEntity WebAuthn PublicKeyCredentialRpEntity
+type PublicKeyCredentialRpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PublicKeyCredentialUserEntity WebAuthn PublicKeyCredentialUserEntity
+type PublicKeyCredentialUserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// AuthenticatorSelectionCriteria WebAuthn AuthenticatorSelectionCriteria
+type AuthenticatorSelectionCriteria struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PublicKeyCredentialCreationOptions POST /users/me/webauthn-credentials/options レスポンス
+type PublicKeyCredentialCreationOptions struct {
+	Challenge              string                          `json:"challenge"`
+	RP                     PublicKeyCredentialRpEntity     `json:"rp"`
+	User                   PublicKeyCredentialUserEntity   `json:"user"`
+	PubKeyCredParams       []PublicKeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                           `json:"timeout"`
+	ExcludeCredentials     []PublicKeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelectionCriteria  `json:"authenticatorSelection"`
+	Attestation            string                          `json:"attestation"`
+}
+
+// PublicKeyCredentialRequestOptions POST /login/webauthn/options レスポンス
+type PublicKeyCredentialRequestOptions struct {
+	Challenge        string                          `json:"challenge"`
+	RPID             string                          `json:"rpId"`
+	Timeout          int64                           `json:"timeout"`
+	AllowCredentials []PublicKeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                          `json:"userVerification"`
+}
+
+// saveWebAuthnChallenge 新しいチャレンジを生成してセッションに保存します
+func (h *Handlers) saveWebAuthnChallenge(c echo.Context, purpose string, userID uuid.UUID) ([]byte, error) {
+	sess, err := h.SessStore.GetSession(c, true)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	challenge := webauthn.NewChallenge()
+	if err := sess.Set(webAuthnChallengeSessionKey, webAuthnChallenge{
+		Challenge: challenge,
+		Purpose:   purpose,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(webAuthnTimeout),
+	}); err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	return challenge, nil
+}
+
+// popWebAuthnChallenge セッションから有効なチャレンジを取り出します
+//
+// チャレンジは一度しか使用できません。
+func (h *Handlers) popWebAuthnChallenge(c echo.Context, purpose string) (*webAuthnChallenge, error) {
+	sess, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if sess == nil {
+		return nil, herror.BadRequest("no webauthn challenge")
+	}
+	v, err := sess.Get(webAuthnChallengeSessionKey)
+	if err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	if err := sess.Delete(webAuthnChallengeSessionKey); err != nil {
+		return nil, herror.InternalServerError(err)
+	}
+	wc, ok := v.(webAuthnChallenge)
+	if !ok || wc.Purpose != purpose {
+		return nil, herror.BadRequest("no webauthn challenge")
+	}
+	if time.Now().After(wc.ExpiresAt) {
+		return nil, herror.BadRequest("webauthn challenge expired")
+	}
+	return &wc, nil
+}
+
+func formatPublicKeyCredentialDescriptors(creds []*model.WebAuthnCredential) []PublicKeyCredentialDescriptor {
+	res := make([]PublicKeyCredentialDescriptor, len(creds))
+	for i, cred := range creds {
+		res[i] = PublicKeyCredentialDescriptor{Type: "public-key", ID: cred.CredentialID}
+	}
+	return res
+}
+
+func toWebAuthnCredential(cred *model.WebAuthnCredential) *webauthn.Credential {
+	id, _ := webauthn.DecodeBase64URL(cred.CredentialID)
+	return &webauthn.Credential{
+		ID:        id,
+		PublicKey: cred.PublicKey,
+		SignCount: cred.SignCount,
+		AAGUID:    cred.AAGUID,
+	}
+}
+
+// GetMyWebAuthnCredentials GET /users/me/webauthn-credentials
+func (h *Handlers) GetMyWebAuthnCredentials(c echo.Context) error {
+	creds, err := h.Repo.GetWebAuthnCredentials(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatWebAuthnCredentials(creds))
+}
+
+// PostMyWebAuthnCredentialOptions POST /users/me/webauthn-credentials/options
+func (h *Handlers) PostMyWebAuthnCredentialOptions(c echo.Context) error {
+	user := getRequestUser(c)
+
+	creds, err := h.Repo.GetWebAuthnCredentials(user.GetID())
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	challenge, err := h.saveWebAuthnChallenge(c, webAuthnPurposeRegistration, user.GetID())
+	if err != nil {
+		return err
+	}
+
+	params := make([]PublicKeyCredentialParameters, len(webauthn.SupportedAlgorithms))
+	for i, alg := range webauthn.SupportedAlgorithms {
+		params[i] = PublicKeyCredentialParameters{Type: "public-key", Alg: alg}
+	}
+	return c.JSON(http.StatusOK, &PublicKeyCredentialCreationOptions{
+		Challenge: webauthn.EncodeBase64URL(challenge),
+		RP: PublicKeyCredentialRpEntity{
+			ID:   h.WebAuthn.ID,
+			Name: h.WebAuthn.Name,
+		},
+		User: PublicKeyCredentialUserEntity{
+			ID:          webauthn.EncodeBase64URL(user.GetID().Bytes()),
+			Name:        user.GetName(),
+			DisplayName: user.GetResponseDisplayName(),
+		},
+		PubKeyCredParams:   params,
+		Timeout:            webAuthnTimeout.Milliseconds(),
+		ExcludeCredentials: formatPublicKeyCredentialDescriptors(creds),
+		AuthenticatorSelection: AuthenticatorSelectionCriteria{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	})
+}
+
+// PostMyWebAuthnCredentialRequest POST /users/me/webauthn-credentials リクエストボディ
+type PostMyWebAuthnCredentialRequest struct {
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject"`
+}
+
+func (r PostMyWebAuthnCredentialRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, vd.Required, vd.RuneLength(1, 32)),
+		vd.Field(&r.ClientDataJSON, vd.Required),
+		vd.Field(&r.AttestationObject, vd.Required),
+	)
+}
+
+// PostMyWebAuthnCredential POST /users/me/webauthn-credentials
+func (h *Handlers) PostMyWebAuthnCredential(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PostMyWebAuthnCredentialRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.ClientDataJSON)
+	if err != nil {
+		return herror.BadRequest("invalid clientDataJSON")
+	}
+	attestationObject, err := webauthn.DecodeBase64URL(req.AttestationObject)
+	if err != nil {
+		return herror.BadRequest("invalid attestationObject")
+	}
+
+	wc, err := h.popWebAuthnChallenge(c, webAuthnPurposeRegistration)
+	if err != nil {
+		return err
+	}
+	if wc.UserID != userID {
+		return herror.BadRequest("no webauthn challenge")
+	}
+
+	cred, err := h.WebAuthn.VerifyRegistration(wc.Challenge, clientDataJSON, attestationObject, false)
+	if err != nil {
+		return herror.BadRequest(err)
+	}
+
+	created, err := h.Repo.CreateWebAuthnCredential(userID, req.Name, webauthn.EncodeBase64URL(cred.ID), cred.PublicKey, cred.SignCount, cred.AAGUID)
+	if err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("this credential is already registered")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, formatWebAuthnCredential(created))
+}
+
+// getMyWebAuthnCredential パスパラメータで指定された自分のWebAuthn認証情報を取得します
+func (h *Handlers) getMyWebAuthnCredential(c echo.Context) (*model.WebAuthnCredential, error) {
+	id, err := uuid.FromString(c.Param(consts.ParamCredentialID))
+	if err != nil {
+		return nil, herror.NotFound()
+	}
+	cred, err := h.Repo.GetWebAuthnCredential(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound()
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if cred.UserID != getRequestUserID(c) {
+		return nil, herror.NotFound()
+	}
+	return cred, nil
+}
+
+// PatchMyWebAuthnCredentialRequest PATCH /users/me/webauthn-credentials/:credentialID リクエストボディ
+type PatchMyWebAuthnCredentialRequest struct {
+	Name string `json:"name"`
+}
+
+func (r PatchMyWebAuthnCredentialRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, vd.Required, vd.RuneLength(1, 32)),
+	)
+}
+
+// PatchMyWebAuthnCredential PATCH /users/me/webauthn-credentials/:credentialID
+func (h *Handlers) PatchMyWebAuthnCredential(c echo.Context) error {
+	var req PatchMyWebAuthnCredentialRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	cred, err := h.getMyWebAuthnCredential(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Repo.RenameWebAuthnCredential(cred.ID, req.Name); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteMyWebAuthnCredential DELETE /users/me/webauthn-credentials/:credentialID
+func (h *Handlers) DeleteMyWebAuthnCredential(c echo.Context) error {
+	cred, err := h.getMyWebAuthnCredential(c)
+	if err != nil {
+		return err
+	}
+	if err := h.Repo.DeleteWebAuthnCredential(cred.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// PostLoginWebAuthnOptions POST /login/webauthn/options
+//
+// パスワード検証後の二要素認証待ちの状態であれば、そのユーザーの認証情報に限定したオプションを返します。
+// そうでない場合は、パスワードレスログイン(discoverable credential)用のオプションを返します。
+func (h *Handlers) PostLoginWebAuthnOptions(c echo.Context) error {
+	res := &PublicKeyCredentialRequestOptions{
+		RPID:             h.WebAuthn.ID,
+		Timeout:          webAuthnTimeout.Milliseconds(),
+		AllowCredentials: []PublicKeyCredentialDescriptor{},
+		UserVerification: "required",
+	}
+
+	userID := uuid.Nil
+	if lc, err := utils.GetLoginChallenge(c, h.SessStore); err == nil && lc.Type != utils.LoginChallengeTypeTOTPEnrollment {
+		creds, err := h.Repo.GetWebAuthnCredentials(lc.UserID)
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		if len(creds) == 0 {
+			return herror.BadRequest("webauthn is not enabled")
+		}
+		userID = lc.UserID
+		res.AllowCredentials = formatPublicKeyCredentialDescriptors(creds)
+		res.UserVerification = "discouraged"
+	}
+
+	challenge, err := h.saveWebAuthnChallenge(c, webAuthnPurposeLogin, userID)
+	if err != nil {
+		return err
+	}
+	res.Challenge = webauthn.EncodeBase64URL(challenge)
+	return c.JSON(http.StatusOK, res)
+}
+
+// PostLoginWebAuthnRequest POST /login/webauthn リクエストボディ
+type PostLoginWebAuthnRequest struct {
+	CredentialID      string `json:"credentialId"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+func (r PostLoginWebAuthnRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.CredentialID, vd.Required, vd.Length(1, 255)),
+		vd.Field(&r.ClientDataJSON, vd.Required),
+		vd.Field(&r.AuthenticatorData, vd.Required),
+		vd.Field(&r.Signature, vd.Required),
+	)
+}
+
+// PostLoginWebAuthn POST /login/webauthn
+func (h *Handlers) PostLoginWebAuthn(c echo.Context) error {
+	var req PostLoginWebAuthnRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	clientDataJSON, err := webauthn.DecodeBase64URL(req.ClientDataJSON)
+	if err != nil {
+		return herror.BadRequest("invalid clientDataJSON")
+	}
+	authenticatorData, err := webauthn.DecodeBase64URL(req.AuthenticatorData)
+	if err != nil {
+		return herror.BadRequest("invalid authenticatorData")
+	}
+	signature, err := webauthn.DecodeBase64URL(req.Signature)
+	if err != nil {
+		return herror.BadRequest("invalid signature")
+	}
+
+	wc, err := h.popWebAuthnChallenge(c, webAuthnPurposeLogin)
+	if err != nil {
+		return err
+	}
+
+	// 二要素認証の場合は、パスワード検証済みのユーザーの認証情報のみ受け付ける
+	var lc *utils.LoginChallenge
+	if wc.UserID != uuid.Nil {
+		lc, err = utils.GetLoginChallenge(c, h.SessStore)
+		if err != nil {
+			return err
+		}
+		if lc.UserID != wc.UserID {
+			return herror.Unauthorized("no login challenge")
+		}
+	}
+
+	cred, err := h.Repo.GetWebAuthnCredentialByCredentialID(req.CredentialID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			h.L(c).Info("an api login attempt failed: unknown webauthn credential")
+			if lc != nil {
+				return utils.FailLoginChallenge(c, h.Repo, h.SessStore, lc)
+			}
+			return herror.Unauthorized("invalid credential")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if lc != nil && cred.UserID != lc.UserID {
+		h.L(c).Info("an api login attempt failed: webauthn credential of another user")
+		return utils.FailLoginChallenge(c, h.Repo, h.SessStore, lc)
+	}
+
+	user, err := h.Repo.GetUser(cred.UserID, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	// パスワードレスログインの場合はユーザー検証(生体認証・PIN等)を必須とする
+	signCount, err := h.WebAuthn.VerifyAssertion(toWebAuthnCredential(cred), wc.Challenge, clientDataJSON, authenticatorData, signature, lc == nil)
+	if err != nil {
+		h.L(c).Info("an api login attempt failed: webauthn verification failed", zap.String("username", user.GetName()), zap.Error(err))
+		if lc != nil {
+			return utils.FailLoginChallenge(c, h.Repo, h.SessStore, lc)
+		}
+		return herror.Unauthorized("invalid credential")
+	}
+
+	// ユーザーのアカウント状態の確認
+	if !user.IsActive() {
+		h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", user.GetName()))
+		return herror.Forbidden("this account is currently suspended")
+	}
+
+	if err := h.Repo.UpdateWebAuthnCredentialUsage(cred.ID, signCount); err != nil {
+		return herror.InternalServerError(err)
+	}
+	h.L(c).Info("an api login attempt succeeded", zap.String("username", user.GetName()))
+
+	if lc != nil {
+		if err := utils.CompleteLoginChallenge(c, h.Repo, h.SessStore, lc); err != nil {
+			return err
+		}
+	} else {
+		if err := utils.RecordLoginSuccess(h.Repo, user.GetID()); err != nil {
+			return herror.InternalServerError(err)
+		}
+		if _, err := h.SessStore.RenewSession(c, user.GetID(), utils.LoginMethodWebAuthn); err != nil {
+			return herror.InternalServerError(err)
+		}
+	}
+
+	redirect := utils.LoginRedirect(c)
+	if lc != nil {
+		redirect = lc.Redirect
+	}
+	if len(redirect) > 0 {
+		return c.Redirect(http.StatusFound, redirect)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
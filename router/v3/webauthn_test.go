@@ -0,0 +1,254 @@
+package v3
+
+import (
+	"github.com/gavv/httpexpect/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/utils/webauthn"
+	"github.com/traPtitech/traQ/utils/webauthn/webauthntest"
+	"net/http"
+	"testing"
+)
+
+// registerWebAuthnCredential APIを通してソフトウェア認証器の認証情報を登録します
+func registerWebAuthnCredential(t *testing.T, env *Env, a *webauthntest.Authenticator, sess string) (*httpexpect.Object, []byte) {
+	t.Helper()
+	e := env.R(t)
+	opts := e.POST("/api/v3/users/me/webauthn-credentials/options").
+		WithCookie(session.CookieName, sess).
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+	opts.Value("rp").Object().Value("id").String().Equal(testWebAuthnRP.ID)
+	challenge, err := webauthn.DecodeBase64URL(opts.Value("challenge").String().Raw())
+	require.NoError(t, err)
+	userHandle, err := webauthn.DecodeBase64URL(opts.Value("user").Object().Value("id").String().Raw())
+	require.NoError(t, err)
+
+	res, err := a.Register(challenge, userHandle)
+	require.NoError(t, err)
+	obj := e.POST("/api/v3/users/me/webauthn-credentials").
+		WithCookie(session.CookieName, sess).
+		WithJSON(&PostMyWebAuthnCredentialRequest{
+			Name:              "test key",
+			ClientDataJSON:    webauthn.EncodeBase64URL(res.ClientDataJSON),
+			AttestationObject: webauthn.EncodeBase64URL(res.AttestationObject),
+		}).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().
+		Object()
+	return obj, res.CredentialID
+}
+
+// assertWebAuthnLogin /login/webauthn/options で取得したチャレンジに署名し、/login/webauthn に送信します
+func assertWebAuthnLogin(t *testing.T, env *Env, a *webauthntest.Authenticator, credentialID []byte, sess string) *httpexpect.Response {
+	t.Helper()
+	e := env.R(t)
+	req := e.POST("/api/v3/login/webauthn/options")
+	if len(sess) > 0 {
+		req = req.WithCookie(session.CookieName, sess)
+	}
+	optsRes := req.Expect().Status(http.StatusOK)
+	if len(sess) == 0 {
+		sess = optsRes.Cookie(session.CookieName).Value().Raw()
+	}
+	challenge, err := webauthn.DecodeBase64URL(optsRes.JSON().Object().Value("challenge").String().Raw())
+	require.NoError(t, err)
+
+	res, err := a.Assert(credentialID, challenge)
+	require.NoError(t, err)
+	return e.POST("/api/v3/login/webauthn").
+		WithCookie(session.CookieName, sess).
+		WithJSON(&PostLoginWebAuthnRequest{
+			CredentialID:      webauthn.EncodeBase64URL(res.CredentialID),
+			ClientDataJSON:    webauthn.EncodeBase64URL(res.ClientDataJSON),
+			AuthenticatorData: webauthn.EncodeBase64URL(res.AuthenticatorData),
+			Signature:         webauthn.EncodeBase64URL(res.Signature),
+		}).
+		Expect()
+}
+
+func TestHandlers_MyWebAuthnCredentials(t *testing.T) {
+	t.Parallel()
+	path := "/api/v3/users/me/webauthn-credentials"
+	env := Setup(t, common)
+
+	t.Run("NotLoggedIn", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET(path).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("register without challenge", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST(path).
+			WithCookie(session.CookieName, env.S(t, env.CreateUser(t, rand).GetID())).
+			WithJSON(echo.Map{"name": "key", "clientDataJSON": "e30", "attestationObject": "oA"}).
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("register, rename and delete", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		s := env.S(t, user.GetID())
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+
+		obj, _ := registerWebAuthnCredential(t, env, a, s)
+		obj.Value("name").String().Equal("test key")
+		id := obj.Value("id").String().Raw()
+
+		e := env.R(t)
+		e.GET(path).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array().
+			Length().
+			Equal(1)
+
+		e.PATCH(path+"/"+id).
+			WithCookie(session.CookieName, s).
+			WithJSON(&PatchMyWebAuthnCredentialRequest{Name: "renamed"}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		creds, err := env.Repository.GetWebAuthnCredentials(user.GetID())
+		require.NoError(t, err)
+		if assert.Len(t, creds, 1) {
+			assert.Equal(t, "renamed", creds[0].Name)
+		}
+
+		// 他人の認証情報は操作できない
+		e.DELETE(path+"/"+id).
+			WithCookie(session.CookieName, env.S(t, env.CreateUser(t, rand).GetID())).
+			Expect().
+			Status(http.StatusNotFound)
+
+		e.DELETE(path+"/"+id).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNoContent)
+		e.DELETE(path+"/"+id).
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestHandlers_PostLoginWebAuthn(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+
+	t.Run("passwordless", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		_, credID := registerWebAuthnCredential(t, env, a, env.S(t, user.GetID()))
+
+		res := assertWebAuthnLogin(t, env, a, credID, "")
+		res.Status(http.StatusNoContent)
+		res.Cookie(session.CookieName).Value().NotEmpty()
+
+		creds, err := env.Repository.GetWebAuthnCredentials(user.GetID())
+		require.NoError(t, err)
+		if assert.Len(t, creds, 1) {
+			assert.EqualValues(t, 1, creds[0].SignCount)
+			assert.True(t, creds[0].LastUsedAt.Valid)
+		}
+	})
+
+	t.Run("passwordless requires user verification", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		_, credID := registerWebAuthnCredential(t, env, a, env.S(t, user.GetID()))
+
+		a.UserVerification = false
+		assertWebAuthnLogin(t, env, a, credID, "").Status(http.StatusUnauthorized)
+	})
+
+	t.Run("unknown credential", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		res, err := a.Register(webauthn.NewChallenge(), []byte("user"))
+		require.NoError(t, err)
+
+		assertWebAuthnLogin(t, env, a, res.CredentialID, "").Status(http.StatusUnauthorized)
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		_, credID := registerWebAuthnCredential(t, env, a, env.S(t, user.GetID()))
+
+		args := repository.UpdateUserArgs{}
+		args.UserState.Valid = true
+		args.UserState.State = model.UserAccountStatusSuspended
+		require.NoError(t, env.Repository.UpdateUser(user.GetID(), args))
+
+		assertWebAuthnLogin(t, env, a, credID, "").Status(http.StatusForbidden)
+	})
+
+	t.Run("second factor", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		_, credID := registerWebAuthnCredential(t, env, a, env.S(t, user.GetID()))
+
+		e := env.R(t)
+		res := e.POST("/api/v3/login").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusAccepted)
+		obj := res.JSON().Object()
+		obj.Value("type").String().Equal(utils.LoginChallengeTypeWebAuthn)
+		obj.Value("methods").Array().Elements(utils.TwoFactorMethodWebAuthn)
+		s := res.Cookie(session.CookieName).Value().Raw()
+
+		// 二要素認証待ちでは、そのユーザーの認証情報のみが許可される
+		e.POST("/api/v3/login/webauthn/options").
+			WithCookie(session.CookieName, s).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("allowCredentials").
+			Array().
+			Length().
+			Equal(1)
+
+		// ユーザー検証がなくても二要素目としては受け付ける
+		a.UserVerification = false
+		assertWebAuthnLogin(t, env, a, credID, s).Status(http.StatusNoContent)
+	})
+
+	t.Run("second factor with another user's credential", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		a := webauthntest.New(testWebAuthnRP.ID, testWebAuthnRP.Origin)
+		registerWebAuthnCredential(t, env, a, env.S(t, user.GetID()))
+		_, otherCredID := registerWebAuthnCredential(t, env, a, env.S(t, env.CreateUser(t, rand).GetID()))
+
+		e := env.R(t)
+		res := e.POST("/api/v3/login").
+			WithJSON(&PostLoginRequest{Name: user.GetName(), Password: "testtesttesttest"}).
+			Expect().
+			Status(http.StatusAccepted)
+		s := res.Cookie(session.CookieName).Value().Raw()
+
+		assertWebAuthnLogin(t, env, a, otherCredID, s).Status(http.StatusUnauthorized)
+	})
+}
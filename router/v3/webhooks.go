@@ -205,6 +205,19 @@ func (h *Handlers) PostWebhook(c echo.Context) error {
 		body = []byte(h.Replacer.Replace(string(body)))
 	}
 
+	// 特殊メンションの権限確認
+	ch, err := h.ChannelManager.GetChannel(channelID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	botUser, err := h.Repo.GetUser(w.GetBotUserID(), false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if err := utils.CheckSpecialMention(h.RBAC, h.Repo, h.ChannelManager, botUser, ch, string(body)); err != nil {
+		return err
+	}
+
 	// メッセージ投稿
 	if _, err := h.Repo.CreateMessage(w.GetBotUserID(), channelID, string(body)); err != nil {
 		return herror.InternalServerError(err)
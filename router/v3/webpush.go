@@ -0,0 +1,77 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+)
+
+// GetWebPushVAPIDPublicKey GET /webpush/vapid-public-key
+func (h *Handlers) GetWebPushVAPIDPublicKey(c echo.Context) error {
+	if len(h.WebPushVAPIDPublicKey) == 0 {
+		return herror.NotFound("web push is not enabled")
+	}
+	return c.JSON(http.StatusOK, echo.Map{"publicKey": h.WebPushVAPIDPublicKey})
+}
+
+// PostMyWebPushSubscriptionRequest POST /users/me/webpush-subscriptions リクエストボディ
+type PostMyWebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (r PostMyWebPushSubscriptionRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Endpoint, vd.Required, vd.RuneLength(1, 2000), is.URL, validator.NotInternalURL),
+		vd.Field(&r.Keys.P256dh, vd.Required, vd.RuneLength(1, 100)),
+		vd.Field(&r.Keys.Auth, vd.Required, vd.RuneLength(1, 50)),
+	)
+}
+
+// PostMyWebPushSubscription POST /users/me/webpush-subscriptions
+func (h *Handlers) PostMyWebPushSubscription(c echo.Context) error {
+	if len(h.WebPushVAPIDPublicKey) == 0 {
+		return herror.NotFound("web push is not enabled")
+	}
+
+	var req PostMyWebPushSubscriptionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	s, err := h.Repo.RegisterWebPushSubscription(getRequestUserID(c), req.Endpoint, req.Keys.P256dh, req.Keys.Auth)
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, formatWebPushSubscription(s))
+}
+
+// DeleteMyWebPushSubscription DELETE /users/me/webpush-subscriptions/:subscriptionID
+func (h *Handlers) DeleteMyWebPushSubscription(c echo.Context) error {
+	subscriptionID := getParamAsUUID(c, consts.ParamSubscriptionID)
+
+	if err := h.Repo.DeleteWebPushSubscription(getRequestUserID(c), subscriptionID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
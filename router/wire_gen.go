@@ -10,6 +10,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/router/v1"
@@ -21,10 +22,9 @@ import (
 
 // Injectors from router_wire.go:
 
-func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Services, logger *zap.Logger, config *Config) *Router {
+func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Services, sessStore session.Store, logger *zap.Logger, config *Config) *Router {
 	manager := ss.ChannelManager
 	echo := newEcho(logger, config, repo, manager)
-	store := session.NewGormStore(db)
 	rbac := ss.RBAC
 	streamer := ss.SSE
 	onlineCounter := ss.OnlineCounter
@@ -43,12 +43,14 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		VM:             viewerManager,
 		HeartBeats:     heartbeatManager,
 		Imaging:        processor,
-		SessStore:      store,
+		SessStore:      sessStore,
 		ChannelManager: manager,
 		Replacer:       replacer,
 	}
 	wsStreamer := ss.WS
+	presenceManager := ss.Presence
 	webrtcv3Manager := ss.WebRTCv3
+	client := ss.Email
 	v3Config := provideV3Config(config)
 	v3Handlers := &v3.Handlers{
 		RBAC:           rbac,
@@ -57,12 +59,14 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		Hub:            hub2,
 		Logger:         logger,
 		OC:             onlineCounter,
+		Presence:       presenceManager,
 		VM:             viewerManager,
 		WebRTC:         webrtcv3Manager,
 		Imaging:        processor,
-		SessStore:      store,
+		SessStore:      sessStore,
 		ChannelManager: manager,
 		Replacer:       replacer,
+		Email:          client,
 		Config:         v3Config,
 	}
 	oauth2Config := provideOAuth2Config(config)
@@ -70,15 +74,22 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		RBAC:      rbac,
 		Repo:      repo,
 		Logger:    logger,
-		SessStore: store,
+		SessStore: sessStore,
 		Config:    oauth2Config,
 	}
+	scimHandler := &scim.Handler{
+		RBAC:      rbac,
+		Repo:      repo,
+		Logger:    logger,
+		SessStore: sessStore,
+	}
 	router := &Router{
 		e:         echo,
-		sessStore: store,
+		sessStore: sessStore,
 		v1:        handlers,
 		v3:        v3Handlers,
 		oauth2:    handler,
+		scim:      scimHandler,
 	}
 	return router
 }
@@ -16,6 +16,7 @@ var (
 	ErrChannelArchived      = errors.New("channel archived")
 	ErrForcedNotification   = errors.New("forced notification channel")
 	ErrInvalidChannel       = errors.New("invalid channel")
+	ErrUserBlocked          = errors.New("user blocked")
 )
 
 type Manager interface {
@@ -28,6 +29,8 @@ type Manager interface {
 
 	GetDMChannel(user1, user2 uuid.UUID) (*model.Channel, error)
 	GetDMChannelMembers(id uuid.UUID) ([]uuid.UUID, error)
+	// IsDMBlocked 指定したDMチャンネルでuserIDのユーザーと相手との間にブロックがあるかどうかを返します
+	IsDMBlocked(userID, channelID uuid.UUID) (bool, error)
 	GetDMChannelMapping(userID uuid.UUID) (map[uuid.UUID]uuid.UUID, error)
 
 	IsChannelAccessibleToUser(userID, channelID uuid.UUID) (bool, error)
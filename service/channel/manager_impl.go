@@ -20,6 +20,7 @@ var (
 
 type managerImpl struct {
 	R repository.ChannelRepository
+	B repository.UserBlockRepository
 	L *zap.Logger
 	T *treeImpl
 	P sync.WaitGroup
@@ -27,7 +28,7 @@ type managerImpl struct {
 	MaxChannelDepth int
 }
 
-func InitChannelManager(repo repository.ChannelRepository, logger *zap.Logger) (Manager, error) {
+func InitChannelManager(repo repository.ChannelRepository, blockRepo repository.UserBlockRepository, logger *zap.Logger) (Manager, error) {
 	channels, err := repo.GetPublicChannels()
 	if err != nil {
 		return nil, fmt.Errorf("failed to init channel.Manager: %w", err)
@@ -35,6 +36,7 @@ func InitChannelManager(repo repository.ChannelRepository, logger *zap.Logger) (
 
 	m := &managerImpl{
 		R:               repo,
+		B:               blockRepo,
 		L:               logger.Named("channel_manager"),
 		MaxChannelDepth: 5,
 	}
@@ -264,6 +266,15 @@ func (m *managerImpl) GetDMChannel(user1, user2 uuid.UUID) (*model.Channel, erro
 		return nil, fmt.Errorf("failed to GetDirectMessageChannel: %w", err)
 	}
 
+	// どちらかがブロックしている場合は作成しない
+	blocked, err := m.B.IsBlockedBetween(user1, user2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to IsBlockedBetween: %w", err)
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
 	// 存在しなかったので作成
 	ch, err = m.R.CreateChannel(
 		model.Channel{
@@ -288,6 +299,26 @@ func (m *managerImpl) GetDMChannelMembers(id uuid.UUID) ([]uuid.UUID, error) {
 	return members, nil
 }
 
+func (m *managerImpl) IsDMBlocked(userID, channelID uuid.UUID) (bool, error) {
+	members, err := m.GetDMChannelMembers(channelID)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range members {
+		if member == userID {
+			continue
+		}
+		blocked, err := m.B.IsBlockedBetween(userID, member)
+		if err != nil {
+			return false, fmt.Errorf("failed to IsBlockedBetween: %w", err)
+		}
+		if blocked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (m *managerImpl) GetDMChannelMapping(userID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
 	mappings, err := m.R.GetDirectMessageChannelMapping(userID)
 	if err != nil {
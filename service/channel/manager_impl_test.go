@@ -18,9 +18,19 @@ import (
 	"time"
 )
 
+type fakeUserBlockRepository struct {
+	repository.UserBlockRepository
+	blocked bool
+}
+
+func (r *fakeUserBlockRepository) IsBlockedBetween(_, _ uuid.UUID) (bool, error) {
+	return r.blocked, nil
+}
+
 func initCM(t *testing.T, repo repository.ChannelRepository) *managerImpl {
 	return &managerImpl{
 		R:               repo,
+		B:               &fakeUserBlockRepository{},
 		L:               zap.NewNop(),
 		T:               makeTestChannelTree(t),
 		MaxChannelDepth: 5,
@@ -41,7 +51,7 @@ func TestInitChannelManager(t *testing.T) {
 			Return(nil, mockErr).
 			Times(1)
 
-		_, err := InitChannelManager(repo, zap.NewNop())
+		_, err := InitChannelManager(repo, &fakeUserBlockRepository{}, zap.NewNop())
 		if assert.Error(t, err) {
 			assert.Equal(t, mockErr, errors.Unwrap(err))
 		}
@@ -61,7 +71,7 @@ func TestInitChannelManager(t *testing.T) {
 			}, nil).
 			Times(1)
 
-		_, err := InitChannelManager(repo, zap.NewNop())
+		_, err := InitChannelManager(repo, &fakeUserBlockRepository{}, zap.NewNop())
 		assert.Error(t, err)
 	})
 
@@ -75,7 +85,7 @@ func TestInitChannelManager(t *testing.T) {
 			Return([]*model.Channel{}, nil).
 			Times(1)
 
-		m, err := InitChannelManager(repo, zap.NewNop())
+		m, err := InitChannelManager(repo, &fakeUserBlockRepository{}, zap.NewNop())
 		if assert.NoError(t, err) {
 			assert.NotNil(t, m)
 		}
@@ -702,6 +712,22 @@ func TestManagerImpl_GetDMChannel(t *testing.T) {
 		}
 	})
 
+	t.Run("ErrUserBlocked", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		repo := mock_repository.NewMockChannelRepository(ctrl)
+		cm := initCM(t, repo)
+		cm.B = &fakeUserBlockRepository{blocked: true}
+
+		repo.EXPECT().
+			GetDirectMessageChannel(gomock.Any(), gomock.Any()).
+			Return(nil, repository.ErrNotFound).
+			Times(1)
+
+		_, err := cm.GetDMChannel(uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()))
+		assert.EqualError(t, err, ErrUserBlocked.Error())
+	})
+
 	t.Run("success (found)", func(t *testing.T) {
 		t.Parallel()
 		ctrl := gomock.NewController(t)
@@ -808,6 +834,34 @@ func TestManagerImpl_GetDMChannelMembers(t *testing.T) {
 	})
 }
 
+func TestManagerImpl_IsDMBlocked(t *testing.T) {
+	t.Parallel()
+
+	uid1 := uuid.NewV3(uuid.Nil, "u1")
+	uid2 := uuid.NewV3(uuid.Nil, "u2")
+	cid := uuid.NewV3(uuid.Nil, "c 1-2")
+
+	for _, blocked := range []bool{true, false} {
+		blocked := blocked
+		t.Run(strconv.FormatBool(blocked), func(t *testing.T) {
+			t.Parallel()
+			ctrl := gomock.NewController(t)
+			repo := mock_repository.NewMockChannelRepository(ctrl)
+			cm := initCM(t, repo)
+			cm.B = &fakeUserBlockRepository{blocked: blocked}
+
+			repo.EXPECT().
+				GetPrivateChannelMemberIDs(cid).
+				Return([]uuid.UUID{uid1, uid2}, nil).
+				Times(1)
+
+			if ok, err := cm.IsDMBlocked(uid1, cid); assert.NoError(t, err) {
+				assert.Equal(t, blocked, ok)
+			}
+		})
+	}
+}
+
 func TestManagerImpl_GetDMChannelMapping(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,102 @@
+package dnd
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Source 通知の発生源
+type Source struct {
+	// UserID 通知の発生源のユーザーのID
+	UserID uuid.UUID
+	// ChannelID 通知の発生源のチャンネルのID
+	ChannelID uuid.UUID
+	// IsDM 発生源がDMチャンネルかどうか
+	IsDM bool
+	// IsForced 発生源が強制通知チャンネルかどうか
+	IsForced bool
+}
+
+// Filter おやすみモード中のユーザーへの通知を抑制するフィルター
+//
+// 抑制した通知はユーザー毎に数を記録し、おやすみモード終了後にまとめて取り出すことができます。
+type Filter struct {
+	repo       repository.DoNotDisturbRepository
+	logger     *zap.Logger
+	suppressed map[uuid.UUID]int
+	mu         sync.Mutex
+}
+
+// NewFilter おやすみモードフィルターを生成します
+func NewFilter(repo repository.DoNotDisturbRepository, logger *zap.Logger) *Filter {
+	return &Filter{
+		repo:       repo,
+		logger:     logger.Named("dnd"),
+		suppressed: map[uuid.UUID]int{},
+	}
+}
+
+// Apply おやすみモード中のユーザーを除外した通知対象を返します
+//
+// srcがnilの場合は例外設定を考慮しません。除外したユーザーへの通知は抑制された通知として記録されます。
+func (f *Filter) Apply(targets set.UUID, src *Source, now time.Time) set.UUID {
+	if len(targets) == 0 {
+		return targets
+	}
+
+	settings, err := f.repo.GetDoNotDisturbSettings(targets)
+	if err != nil {
+		f.logger.Error("failed to GetDoNotDisturbSettings", zap.Error(err))
+		return targets // 取得できなかった場合は通知を優先する
+	}
+
+	result := make(set.UUID, len(targets))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id := range targets {
+		s, ok := settings[id]
+		if ok && s.IsActive(now) && (src == nil || !s.Allows(src.UserID, src.ChannelID, src.IsDM, src.IsForced)) {
+			f.suppressed[id]++
+			continue
+		}
+		result.Add(id)
+	}
+	return result
+}
+
+// PopEnded おやすみモードが終了したユーザーの抑制された通知数を返します
+//
+// 返したユーザーの記録は削除されます。
+func (f *Filter) PopEnded(now time.Time) map[uuid.UUID]int {
+	f.mu.Lock()
+	users := make(set.UUID, len(f.suppressed))
+	for id := range f.suppressed {
+		users.Add(id)
+	}
+	f.mu.Unlock()
+	if len(users) == 0 {
+		return nil
+	}
+
+	settings, err := f.repo.GetDoNotDisturbSettings(users)
+	if err != nil {
+		f.logger.Error("failed to GetDoNotDisturbSettings", zap.Error(err))
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := map[uuid.UUID]int{}
+	for id := range users {
+		if s, ok := settings[id]; ok && s.IsActive(now) {
+			continue
+		}
+		result[id] = f.suppressed[id]
+		delete(f.suppressed, id)
+	}
+	return result
+}
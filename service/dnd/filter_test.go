@@ -0,0 +1,80 @@
+package dnd
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type settingsRepository map[uuid.UUID]*model.DoNotDisturbSetting
+
+func (r settingsRepository) GetDoNotDisturbSetting(userID uuid.UUID) (*model.DoNotDisturbSetting, error) {
+	return r[userID], nil
+}
+
+func (r settingsRepository) GetDoNotDisturbSettings(userIDs set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error) {
+	result := map[uuid.UUID]*model.DoNotDisturbSetting{}
+	for id := range userIDs {
+		if s, ok := r[id]; ok {
+			result[id] = s
+		}
+	}
+	return result, nil
+}
+
+func (r settingsRepository) UpdateDoNotDisturbSetting(uuid.UUID, repository.UpdateDoNotDisturbSettingArgs) error {
+	panic("implement me")
+}
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		normal  = uuid.Must(uuid.NewV4())
+		manual  = uuid.Must(uuid.NewV4())
+		night   = uuid.Must(uuid.NewV4())
+		sender  = uuid.Must(uuid.NewV4())
+		channel = uuid.Must(uuid.NewV4())
+	)
+	repo := settingsRepository{
+		manual: &model.DoNotDisturbSetting{
+			UserID:         manual,
+			Enabled:        true,
+			AllowedUsers:   model.UUIDs{sender},
+			UrgentChannels: model.UUIDs{channel},
+		},
+		night: &model.DoNotDisturbSetting{
+			UserID:   night,
+			Timezone: "UTC",
+			Schedule: model.DoNotDisturbSchedule{{Start: "22:00", End: "07:00"}},
+		},
+	}
+	f := NewFilter(repo, zap.NewNop())
+	targets := set.UUIDSetFromArray([]uuid.UUID{normal, manual, night})
+	midnight := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	noon := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// 通常のメッセージ
+	assert.ElementsMatch(t, []uuid.UUID{normal}, f.Apply(targets, &Source{UserID: uuid.Must(uuid.NewV4())}, midnight).Array())
+	assert.ElementsMatch(t, []uuid.UUID{normal, night}, f.Apply(targets, nil, noon).Array())
+
+	// 許可ユーザーからのDM
+	assert.ElementsMatch(t, []uuid.UUID{normal, manual}, f.Apply(targets, &Source{UserID: sender, IsDM: true}, midnight).Array())
+	// 緊急指定した強制通知チャンネル
+	assert.ElementsMatch(t, []uuid.UUID{normal, manual}, f.Apply(targets, &Source{ChannelID: channel, IsForced: true}, midnight).Array())
+
+	// nightはまだおやすみモード中
+	assert.Empty(t, f.PopEnded(midnight))
+	// nightのおやすみモードが終了
+	assert.EqualValues(t, map[uuid.UUID]int{night: 3}, f.PopEnded(noon))
+	assert.Empty(t, f.PopEnded(noon))
+
+	// manualのおやすみモードを解除
+	repo[manual].Enabled = false
+	assert.EqualValues(t, map[uuid.UUID]int{manual: 2}, f.PopEnded(noon))
+}
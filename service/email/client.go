@@ -0,0 +1,64 @@
+package email
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+// Client メール通知クライアント
+type Client interface {
+	// Send targetユーザーのうち、メール通知を有効にしているユーザーにnを送信します
+	Send(targetUserIDs set.UUID, n *Notification)
+	// SendVerification addressにメール通知先アドレスの確認リンクを送信します
+	SendVerification(address string, token string)
+	Close()
+}
+
+// Config メール通知設定
+type Config struct {
+	// Host SMTPサーバーのホスト名
+	Host string
+	// Port SMTPサーバーのポート番号
+	Port int
+	// Username SMTP認証のユーザー名 (空の場合は認証を行いません)
+	Username string
+	// Password SMTP認証のパスワード
+	Password string
+	// From 送信元アドレス
+	From string
+	// DailyDigestHour 1日毎のダイジェストを送信する時刻(時)
+	DailyDigestHour int
+}
+
+// Repository メール通知クライアントが使用するリポジトリ
+type Repository interface {
+	repository.EmailNotificationRepository
+	GetUser(id uuid.UUID, withProfile bool) (model.UserInfo, error)
+	GetUserBelongingGroupIDs(userID uuid.UUID) ([]uuid.UUID, error)
+	GetUnreadMessagesByUserID(userID uuid.UUID) ([]*model.Message, error)
+}
+
+// NotificationType メール通知の種類
+type NotificationType string
+
+const (
+	// NotificationTypeDM ダイレクトメッセージ
+	NotificationTypeDM NotificationType = "dm"
+	// NotificationTypeMention メンション
+	NotificationTypeMention NotificationType = "mention"
+)
+
+// Notification メール通知の内容
+type Notification struct {
+	Type NotificationType
+	// SenderName 送信者の表示名
+	SenderName string
+	// ChannelPath 投稿先チャンネルのパス (DMの場合は空)
+	ChannelPath string
+	// Text メッセージ本文
+	Text string
+	// URL メッセージのURL
+	URL string
+}
@@ -0,0 +1,273 @@
+package email
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/variable"
+	"github.com/traPtitech/traQ/utils/message"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+type clientImpl struct {
+	repo   Repository
+	cm     channel.Manager
+	logger *zap.Logger
+	config Config
+	origin string
+	sender *sender
+
+	queue         chan *mailMessage
+	close         chan struct{}
+	sendingDigest int32
+}
+
+// NewClient メール通知クライアントを生成します
+func NewClient(repo Repository, cm channel.Manager, logger *zap.Logger, config Config, origin variable.ServerOriginString) (Client, error) {
+	s, err := newSender(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.DailyDigestHour < 0 || config.DailyDigestHour > 23 {
+		return nil, errors.New("dailyDigestHour must be in 0-23")
+	}
+
+	c := &clientImpl{
+		repo:   repo,
+		cm:     cm,
+		logger: logger.Named("email"),
+		config: config,
+		origin: string(origin),
+		sender: s,
+		queue:  make(chan *mailMessage, queueSize),
+		close:  make(chan struct{}),
+	}
+	go c.worker()
+
+	return c, nil
+}
+
+func (c *clientImpl) Close() {
+	if !c.isClosed() {
+		close(c.close)
+	}
+}
+
+func (c *clientImpl) isClosed() bool {
+	select {
+	case <-c.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *clientImpl) Send(targetUserIDs set.UUID, n *Notification) {
+	if c.isClosed() || len(targetUserIDs) == 0 {
+		return
+	}
+
+	settings, err := c.repo.GetEmailNotificationSettings(targetUserIDs)
+	if err != nil {
+		c.logger.Error("failed to GetEmailNotificationSettings", zap.Error(err), zap.Strings("target_user_ids", targetUserIDs.StringArray()))
+		return
+	}
+
+	subject, body, err := renderNotification(n, c.settingsURL())
+	if err != nil {
+		c.logger.Error("failed to render notification", zap.Error(err))
+		return
+	}
+	for _, s := range settings {
+		if !s.Enabled || len(s.Address) == 0 {
+			continue
+		}
+		c.enqueue(&mailMessage{To: s.Address, Subject: subject, Body: body})
+	}
+}
+
+func (c *clientImpl) SendVerification(address string, token string) {
+	if c.isClosed() {
+		return
+	}
+
+	subject, body, err := renderVerification(c.verificationURL(token))
+	if err != nil {
+		c.logger.Error("failed to render verification", zap.Error(err))
+		return
+	}
+	c.enqueue(&mailMessage{To: address, Subject: subject, Body: body})
+}
+
+// enqueue 送信キューにmを追加します
+//
+// キューが一杯の場合は送信側をブロックせず、mを破棄します。
+func (c *clientImpl) enqueue(m *mailMessage) {
+	select {
+	case c.queue <- m:
+	default:
+		emailSendCounter.WithLabelValues("dropped").Inc()
+		c.logger.Warn("email queue is full, dropped a message", zap.String("subject", m.Subject))
+	}
+}
+
+func (c *clientImpl) worker() {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.close:
+			return
+
+		case m := <-c.queue:
+			if err := c.sender.send(m); err != nil {
+				emailSendCounter.WithLabelValues("error").Inc()
+				c.logger.Warn("failed to send email", zap.Error(err))
+				continue
+			}
+			emailSendCounter.WithLabelValues("ok").Inc()
+
+		case now := <-ticker.C:
+			go c.sendDigests(now)
+		}
+	}
+}
+
+// sendDigests 送信時刻になったユーザーに未読メンション・DMのダイジェストを送信します
+func (c *clientImpl) sendDigests(now time.Time) {
+	// 前回の送信処理が終わっていない場合はスキップ
+	if !atomic.CompareAndSwapInt32(&c.sendingDigest, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&c.sendingDigest, 0)
+
+	settings, err := c.repo.GetEmailDigestSettings()
+	if err != nil {
+		c.logger.Error("failed to GetEmailDigestSettings", zap.Error(err))
+		return
+	}
+
+	now = now.Local()
+	for _, s := range settings {
+		if !s.IsDigestDue(now, c.config.DailyDigestHour) {
+			continue
+		}
+
+		d, err := c.buildDigest(s, now)
+		if err != nil {
+			c.logger.Error("failed to build digest", zap.Error(err), zap.Stringer("userId", s.UserID))
+			continue
+		}
+		if err := c.repo.SetEmailDigestSentAt(s.UserID, now); err != nil {
+			c.logger.Error("failed to SetEmailDigestSentAt", zap.Error(err), zap.Stringer("userId", s.UserID))
+			continue
+		}
+		if d.Total == 0 {
+			continue
+		}
+
+		subject, body, err := renderDigest(d)
+		if err != nil {
+			c.logger.Error("failed to render digest", zap.Error(err))
+			continue
+		}
+		c.enqueue(&mailMessage{To: s.Address, Subject: subject, Body: body})
+	}
+}
+
+// buildDigest 前回のダイジェスト送信以降の未読メンション・DMのダイジェストを作成します
+func (c *clientImpl) buildDigest(s *model.EmailNotificationSetting, now time.Time) (*digestData, error) {
+	since := now.Add(-s.Digest.Duration())
+	if s.LastDigestAt.Valid {
+		since = s.LastDigestAt.Time
+	}
+
+	unreads, err := c.repo.GetUnreadMessagesByUserID(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	groupIDs, err := c.repo.GetUserBelongingGroupIDs(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	groups := set.UUIDSetFromArray(groupIDs)
+
+	tree := c.cm.PublicChannelTree()
+	senderNames := map[uuid.UUID]string{}
+	d := &digestData{SettingsURL: c.settingsURL()}
+	for _, m := range unreads {
+		if !m.CreatedAt.After(since) || m.UserID == s.UserID {
+			continue
+		}
+
+		parsed := message.Parse(m.Text)
+		isDM := !tree.IsChannelPresent(m.ChannelID)
+		if !isDM && !isMentioned(parsed, s.UserID, groups) {
+			continue
+		}
+
+		d.Total++
+		if len(d.Items) >= maxDigestItems {
+			continue
+		}
+
+		name, ok := senderNames[m.UserID]
+		if !ok {
+			u, err := c.repo.GetUser(m.UserID, false)
+			if err != nil {
+				return nil, err
+			}
+			name = u.GetResponseDisplayName()
+			senderNames[m.UserID] = name
+		}
+		location := "DM"
+		if !isDM {
+			location = "#" + tree.GetChannelPath(m.ChannelID)
+		}
+		d.Items = append(d.Items, &digestItem{
+			SenderName: name,
+			Location:   location,
+			Text:       ellipsis(parsed.OneLine(), maxTextLength),
+			URL:        c.origin + "/messages/" + m.ID.String(),
+			CreatedAt:  m.CreatedAt.In(now.Location()),
+		})
+	}
+	return d, nil
+}
+
+func (c *clientImpl) settingsURL() string {
+	return c.origin + "/settings/notification"
+}
+
+func (c *clientImpl) verificationURL(token string) string {
+	return c.origin + "/api/v3/email-notification/verify?token=" + url.QueryEscape(token)
+}
+
+func isMentioned(parsed *message.ParseResult, userID uuid.UUID, groups set.UUID) bool {
+	for _, id := range parsed.Mentions {
+		if id == userID {
+			return true
+		}
+	}
+	for _, id := range parsed.GroupMentions {
+		if groups.Contains(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// ellipsis n文字を超える場合は...で省略します
+func ellipsis(s string, n int) string {
+	if us := utf8string.NewString(s); us.RuneCount() > n {
+		return us.Slice(0, n) + "..."
+	}
+	return s
+}
@@ -0,0 +1,261 @@
+package email
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRepository struct {
+	settings map[uuid.UUID]*model.EmailNotificationSetting
+	users    map[uuid.UUID]*model.User
+	unreads  map[uuid.UUID][]*model.Message
+	sentAt   map[uuid.UUID]time.Time
+	mu       sync.Mutex
+}
+
+func (r *fakeRepository) GetEmailNotificationSetting(userID uuid.UUID) (*model.EmailNotificationSetting, error) {
+	return r.settings[userID], nil
+}
+
+func (r *fakeRepository) GetEmailNotificationSettings(userIDs set.UUID) (map[uuid.UUID]*model.EmailNotificationSetting, error) {
+	result := map[uuid.UUID]*model.EmailNotificationSetting{}
+	for id := range userIDs {
+		if s, ok := r.settings[id]; ok {
+			result[id] = s
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRepository) GetEmailDigestSettings() ([]*model.EmailNotificationSetting, error) {
+	var result []*model.EmailNotificationSetting
+	for _, s := range r.settings {
+		if s.Digest != model.EmailDigestNone {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRepository) UpdateEmailNotificationSetting(uuid.UUID, repository.UpdateEmailNotificationSettingArgs) error {
+	panic("implement me")
+}
+
+func (r *fakeRepository) CreateEmailAddressVerification(uuid.UUID, string) (*model.EmailAddressVerification, error) {
+	panic("implement me")
+}
+
+func (r *fakeRepository) VerifyEmailAddress(string) (uuid.UUID, error) {
+	panic("implement me")
+}
+
+func (r *fakeRepository) SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sentAt[userID] = sentAt
+	return nil
+}
+
+func (r *fakeRepository) GetUser(id uuid.UUID, _ bool) (model.UserInfo, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *fakeRepository) GetUserBelongingGroupIDs(uuid.UUID) ([]uuid.UUID, error) {
+	return []uuid.UUID{}, nil
+}
+
+func (r *fakeRepository) GetUnreadMessagesByUserID(userID uuid.UUID) ([]*model.Message, error) {
+	return r.unreads[userID], nil
+}
+
+// fakeChannelManager 1つの公開チャンネルのみが存在するチャンネルマネージャー
+type fakeChannelManager struct {
+	channel.Manager
+	channel.Tree
+	channelID uuid.UUID
+}
+
+func (m *fakeChannelManager) PublicChannelTree() channel.Tree {
+	return m
+}
+
+func (m *fakeChannelManager) IsChannelPresent(id uuid.UUID) bool {
+	return id == m.channelID
+}
+
+func (m *fakeChannelManager) GetChannelPath(uuid.UUID) string {
+	return "general"
+}
+
+func TestClientImpl_Send(t *testing.T) {
+	t.Parallel()
+
+	sink := newSMTPSink(t)
+	defer sink.Close()
+
+	var (
+		enabled  = uuid.Must(uuid.NewV4())
+		disabled = uuid.Must(uuid.NewV4())
+		none     = uuid.Must(uuid.NewV4())
+	)
+	repo := &fakeRepository{
+		settings: map[uuid.UUID]*model.EmailNotificationSetting{
+			enabled:  {UserID: enabled, Address: "enabled@example.com", Enabled: true},
+			disabled: {UserID: disabled, Address: "disabled@example.com", Enabled: false},
+		},
+	}
+	c, err := NewClient(repo, &fakeChannelManager{}, zap.NewNop(), sink.config(), "https://traq.example.com")
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.Send(set.UUIDSetFromArray([]uuid.UUID{enabled, disabled, none}), &Notification{
+		Type:        NotificationTypeMention,
+		SenderName:  "traQ",
+		ChannelPath: "general",
+		Text:        "@enabled hello",
+		URL:         "https://traq.example.com/messages/xxx",
+	})
+
+	m := sink.wait(t)
+	assert.Equal(t, []string{"enabled@example.com"}, m.To)
+	subject, body := parseMail(t, m.Data)
+	assert.Equal(t, "#general でtraQさんからメンションされました", subject)
+	assert.Contains(t, body, "@enabled hello")
+	assert.Contains(t, body, "https://traq.example.com/messages/xxx")
+	assert.Contains(t, body, "https://traq.example.com/settings/notification")
+
+	select {
+	case m := <-sink.received:
+		t.Errorf("unexpected mail to %v", m.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClientImpl_sendDigests(t *testing.T) {
+	t.Parallel()
+
+	sink := newSMTPSink(t)
+	defer sink.Close()
+
+	var (
+		user      = uuid.Must(uuid.NewV4())
+		sender    = uuid.Must(uuid.NewV4())
+		channelID = uuid.Must(uuid.NewV4())
+		dmID      = uuid.Must(uuid.NewV4())
+		now       = time.Now()
+	)
+	repo := &fakeRepository{
+		settings: map[uuid.UUID]*model.EmailNotificationSetting{
+			user: {
+				UserID:       user,
+				Address:      "user@example.com",
+				Digest:       model.EmailDigestHourly,
+				LastDigestAt: optional.TimeFrom(now.Add(-2 * time.Hour)),
+			},
+		},
+		users: map[uuid.UUID]*model.User{
+			sender: {ID: sender, Name: "sender", DisplayName: "送信者"},
+		},
+		unreads: map[uuid.UUID][]*model.Message{
+			user: {
+				// 前回のダイジェスト以前
+				{ID: uuid.Must(uuid.NewV4()), UserID: sender, ChannelID: dmID, Text: "old", CreatedAt: now.Add(-3 * time.Hour)},
+				// メンションでない
+				{ID: uuid.Must(uuid.NewV4()), UserID: sender, ChannelID: channelID, Text: "not mentioned", CreatedAt: now.Add(-time.Hour)},
+				// メンション
+				{ID: uuid.Must(uuid.NewV4()), UserID: sender, ChannelID: channelID, Text: `!{"type":"user","raw":"@user","id":"` + user.String() + `"} mentioned`, CreatedAt: now.Add(-time.Hour)},
+				// DM
+				{ID: uuid.Must(uuid.NewV4()), UserID: sender, ChannelID: dmID, Text: "direct", CreatedAt: now.Add(-time.Minute)},
+			},
+		},
+		sentAt: map[uuid.UUID]time.Time{},
+	}
+	c, err := NewClient(repo, &fakeChannelManager{channelID: channelID}, zap.NewNop(), sink.config(), "https://traq.example.com")
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.(*clientImpl).sendDigests(now)
+
+	m := sink.wait(t)
+	assert.Equal(t, []string{"user@example.com"}, m.To)
+	subject, body := parseMail(t, m.Data)
+	assert.Equal(t, "未読のメンション・DMが2件あります", subject)
+	assert.Contains(t, body, "送信者 (#general)")
+	assert.Contains(t, body, "@user mentioned")
+	assert.Contains(t, body, "送信者 (DM)")
+	assert.Contains(t, body, "direct")
+	assert.False(t, strings.Contains(body, "old"))
+	assert.False(t, strings.Contains(body, "not mentioned"))
+
+	repo.mu.Lock()
+	assert.Equal(t, now.Local(), repo.sentAt[user])
+	repo.mu.Unlock()
+}
+
+func TestEllipsis(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "あいう", ellipsis("あいう", 3))
+	assert.Equal(t, "あい...", ellipsis("あいう", 2))
+}
+
+func TestClientImpl_SendVerification(t *testing.T) {
+	t.Parallel()
+
+	sink := newSMTPSink(t)
+	defer sink.Close()
+
+	c, err := NewClient(&fakeRepository{}, &fakeChannelManager{}, zap.NewNop(), sink.config(), "https://traq.example.com")
+	require.NoError(t, err)
+	defer c.Close()
+
+	c.SendVerification("user@example.com", "token")
+
+	m := sink.wait(t)
+	assert.Equal(t, []string{"user@example.com"}, m.To)
+	subject, body := parseMail(t, m.Data)
+	assert.Equal(t, "メール通知先アドレスの確認", subject)
+	assert.Contains(t, body, "https://traq.example.com/api/v3/email-notification/verify?token=token")
+}
+
+func TestClientImpl_enqueue(t *testing.T) {
+	t.Parallel()
+
+	// workerを起動しないため、キューが一杯になる
+	c := &clientImpl{
+		logger: zap.NewNop(),
+		queue:  make(chan *mailMessage, 1),
+		close:  make(chan struct{}),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.enqueue(&mailMessage{To: "1@example.com"})
+		c.enqueue(&mailMessage{To: "2@example.com"})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue blocked")
+	}
+	if assert.Len(t, c.queue, 1) {
+		assert.Equal(t, "1@example.com", (<-c.queue).To)
+	}
+}
@@ -0,0 +1,23 @@
+package email
+
+import (
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+var nullC = &nullClient{}
+
+type nullClient struct{}
+
+// NewNullClient 何もしないメール通知クライアントを返します
+func NewNullClient() Client {
+	return nullC
+}
+
+func (n *nullClient) Send(set.UUID, *Notification) {
+}
+
+func (n *nullClient) SendVerification(string, string) {
+}
+
+func (n *nullClient) Close() {
+}
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// mailMessage 送信するメール
+type mailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// sender SMTPサーバーにメールを送信します
+type sender struct {
+	addr string
+	auth smtp.Auth
+	from *mail.Address
+}
+
+func newSender(c Config) (*sender, error) {
+	from, err := mail.ParseAddress(c.From)
+	if err != nil {
+		return nil, err
+	}
+	s := &sender{
+		addr: net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		from: from,
+	}
+	if len(c.Username) > 0 {
+		s.auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return s, nil
+}
+
+func (s *sender) send(m *mailMessage) error {
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from.Address, []string{to.Address}, s.build(to, m, time.Now()))
+}
+
+// build RFC 5322形式のメッセージを組み立てます
+func (s *sender) build(to *mail.Address, m *mailMessage, date time.Time) []byte {
+	var b bytes.Buffer
+	writeHeader := func(k, v string) {
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(v)
+		b.WriteString("\r\n")
+	}
+	writeHeader("From", s.from.String())
+	writeHeader("To", to.String())
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	writeHeader("Date", date.Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=UTF-8")
+	writeHeader("Content-Transfer-Encoding", "base64")
+	b.WriteString("\r\n")
+
+	// 1行76文字で折り返す
+	encoded := base64.StdEncoding.EncodeToString([]byte(m.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,166 @@
+package email
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSink テスト用の受信したメールを保持するだけのSMTPサーバー
+type smtpSink struct {
+	ln       net.Listener
+	received chan *receivedMail
+}
+
+type receivedMail struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpSink{
+		ln:       ln,
+		received: make(chan *receivedMail, 10),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) config() Config {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return Config{
+		Host: host,
+		Port: p,
+		From: "traQ <noreply@example.com>",
+	}
+}
+
+func (s *smtpSink) Close() {
+	_ = s.ln.Close()
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	m := &receivedMail{}
+	_ = tp.PrintfLine("220 localhost ESMTP sink")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			m.From = extractPath(line)
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			m.To = append(m.To, extractPath(line))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = data
+			_ = tp.PrintfLine("250 OK")
+			s.received <- m
+			m = &receivedMail{}
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *smtpSink) wait(t *testing.T) *receivedMail {
+	t.Helper()
+	select {
+	case m := <-s.received:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for mail")
+		return nil
+	}
+}
+
+func extractPath(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+// parseMail 受信したメールの件名と本文をデコードします
+func parseMail(t *testing.T, data []byte) (subject, body string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=UTF-8", msg.Header.Get("Content-Type"))
+
+	b, err := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, msg.Body))
+	require.NoError(t, err)
+	return subject, string(b)
+}
+
+func TestSender_send(t *testing.T) {
+	t.Parallel()
+
+	sink := newSMTPSink(t)
+	defer sink.Close()
+
+	s, err := newSender(sink.config())
+	require.NoError(t, err)
+
+	body := strings.Repeat("本文", 100)
+	require.NoError(t, s.send(&mailMessage{To: "user@example.com", Subject: "件名", Body: body}))
+
+	m := sink.wait(t)
+	assert.Equal(t, "noreply@example.com", m.From)
+	assert.Equal(t, []string{"user@example.com"}, m.To)
+
+	subject, b := parseMail(t, m.Data)
+	assert.Equal(t, "件名", subject)
+	assert.Equal(t, body, b)
+}
+
+func TestNewSender(t *testing.T) {
+	t.Parallel()
+
+	_, err := newSender(Config{Host: "localhost", Port: 25, From: "invalid"})
+	assert.Error(t, err)
+}
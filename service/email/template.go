@@ -0,0 +1,113 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	notificationSubjectTemplate = template.Must(template.New("notification_subject").Parse(
+		`{{if eq .Type "dm"}}{{.SenderName}}さんからダイレクトメッセージが届きました{{else}}#{{.ChannelPath}} で{{.SenderName}}さんからメンションされました{{end}}`,
+	))
+	notificationBodyTemplate = template.Must(template.New("notification_body").Parse(`{{if eq .Type "dm"}}{{.SenderName}}さんからダイレクトメッセージが届きました。{{else}}#{{.ChannelPath}} で{{.SenderName}}さんからメンションされました。{{end}}
+
+{{.Text}}
+
+メッセージを開く: {{.URL}}
+{{template "footer" .}}`))
+
+	digestSubjectTemplate = template.Must(template.New("digest_subject").Parse(
+		`未読のメンション・DMが{{.Total}}件あります`,
+	))
+	digestBodyTemplate = template.Must(template.New("digest_body").Parse(`未読のメンション・DMが{{.Total}}件あります。
+{{range .Items}}
+{{.SenderName}} ({{.Location}}) {{.CreatedAt.Format "2006/01/02 15:04"}}
+{{.Text}}
+{{.URL}}
+{{end}}{{if gt .Total (len .Items)}}
+他{{.Omitted}}件
+{{end}}{{template "footer" .}}`))
+
+	verificationSubjectTemplate = template.Must(template.New("verification_subject").Parse(
+		`メール通知先アドレスの確認`,
+	))
+	verificationBodyTemplate = template.Must(template.New("verification_body").Parse(`traQのメール通知先にこのアドレスが指定されました。
+以下のリンクを開くと、このアドレスへのメール通知が有効になります。
+
+{{.URL}}
+
+このリンクの有効期限は24時間です。
+心当たりがない場合は、このメールを破棄してください。
+`))
+)
+
+const footerTemplate = `{{define "footer"}}
+--
+このメールはtraQのメール通知設定に基づいて送信されています。
+通知設定の変更: {{.SettingsURL}}
+{{end}}`
+
+func init() {
+	for _, t := range []*template.Template{notificationBodyTemplate, digestBodyTemplate} {
+		template.Must(t.Parse(footerTemplate))
+	}
+}
+
+// notificationData 即時通知テンプレートのデータ
+type notificationData struct {
+	*Notification
+	SettingsURL string
+}
+
+// digestItem ダイジェストの1項目
+type digestItem struct {
+	SenderName string
+	// Location 投稿先 (#チャンネルパス または DM)
+	Location  string
+	Text      string
+	URL       string
+	CreatedAt time.Time
+}
+
+// digestData ダイジェストテンプレートのデータ
+type digestData struct {
+	Items       []*digestItem
+	Total       int
+	SettingsURL string
+}
+
+// Omitted ダイジェストに含まれなかった項目の数
+func (d *digestData) Omitted() int {
+	return d.Total - len(d.Items)
+}
+
+func renderNotification(n *Notification, settingsURL string) (subject, body string, err error) {
+	return render(notificationSubjectTemplate, notificationBodyTemplate, &notificationData{Notification: n, SettingsURL: settingsURL})
+}
+
+// verificationData アドレス確認テンプレートのデータ
+type verificationData struct {
+	URL string
+}
+
+func renderVerification(url string) (subject, body string, err error) {
+	return render(verificationSubjectTemplate, verificationBodyTemplate, &verificationData{URL: url})
+}
+
+func renderDigest(d *digestData) (subject, body string, err error) {
+	return render(digestSubjectTemplate, digestBodyTemplate, d)
+}
+
+func render(subjectTmpl, bodyTmpl *template.Template, data interface{}) (subject, body string, err error) {
+	var s, b bytes.Buffer
+	if err := subjectTmpl.Execute(&s, data); err != nil {
+		return "", "", err
+	}
+	if err := bodyTmpl.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	// 件名に改行が含まれるとヘッダーインジェクションになるため除去
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(s.String()), b.String(), nil
+}
@@ -0,0 +1,21 @@
+package email
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+const (
+	queueSize           = 100
+	digestCheckInterval = time.Minute
+	maxDigestItems      = 50
+	maxTextLength       = 200
+)
+
+var (
+	emailSendCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "traq",
+		Name:      "email_send_count_total",
+	}, []string{"result"})
+)
@@ -0,0 +1,192 @@
+package fcm
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Sender プッシュ通知の送信手段
+//
+// おやすみモードの適用とメッセージのバッチ化はDispatcherが行うため、Senderはメッセージの作成と送信のみを行います。
+type Sender interface {
+	// Name 送信手段の名前
+	Name() string
+	// BatchSize 1度に送信するメッセージの最大数
+	BatchSize() int
+	// Messages targetユーザーの端末に送信するメッセージを作成します
+	//
+	// unreadがnilでない場合、メッセージにユーザーの未読数を含めます。
+	Messages(targetUserIDs set.UUID, p *Payload, unread func(userID uuid.UUID) int) ([]interface{}, error)
+	// SendMessages メッセージを送信します
+	//
+	// messagesはMessagesで作成したメッセージで、最大BatchSize件です。
+	SendMessages(messages []interface{})
+}
+
+type dispatcher struct {
+	workers       []*senderWorker
+	logger        *zap.Logger
+	unreadCounter counter.UnreadMessageCounter
+	dnd           *dnd.Filter
+	close         chan struct{}
+}
+
+type senderWorker struct {
+	sender Sender
+	queue  chan []interface{}
+}
+
+// NewDispatcher 複数の送信手段に同じペイロードを送信するクライアントを返します
+//
+// おやすみモードは全ての送信手段で共通のフィルターにより適用され、
+// おやすみモード終了後のまとめ通知も全ての送信手段に1度だけ送信されます。
+// sendersが空の場合は何もしないクライアントを返します。
+func NewDispatcher(repo repository.DoNotDisturbRepository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, senders ...Sender) Client {
+	if len(senders) == 0 {
+		return NewNullClient()
+	}
+
+	d := &dispatcher{
+		logger:        logger.Named("push"),
+		unreadCounter: unreadCounter,
+		dnd:           dnd.NewFilter(repo, logger),
+		close:         make(chan struct{}),
+	}
+	for _, s := range senders {
+		w := &senderWorker{sender: s, queue: make(chan []interface{})}
+		d.workers = append(d.workers, w)
+		go d.worker(w)
+	}
+	go d.dndWorker()
+
+	return d
+}
+
+func (d *dispatcher) Close() {
+	if !d.isClosed() {
+		close(d.close)
+	}
+}
+
+func (d *dispatcher) isClosed() bool {
+	select {
+	case <-d.close:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *dispatcher) Send(targetUserIDs set.UUID, payload *Payload, withUnreadCount bool) {
+	if d.isClosed() || len(targetUserIDs) == 0 {
+		return
+	}
+
+	// おやすみモード中のユーザーを除外
+	targets := d.dnd.Apply(targetUserIDs, payload.Source, time.Now())
+	d.send(targets, payload, withUnreadCount)
+}
+
+func (d *dispatcher) send(targetUserIDs set.UUID, p *Payload, withUnreadCount bool) {
+	if len(targetUserIDs) == 0 {
+		return
+	}
+
+	var unread func(userID uuid.UUID) int
+	if withUnreadCount {
+		unread = d.unreadCounter.Get
+	}
+	for _, w := range d.workers {
+		messages, err := w.sender.Messages(targetUserIDs, p, unread)
+		if err != nil {
+			d.logger.Error("failed to create messages", zap.Error(err), zap.String("sender", w.sender.Name()), zap.Reflect("payload", p))
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		select {
+		case <-d.close:
+			return
+		case w.queue <- messages:
+		}
+	}
+}
+
+// worker 送信手段毎にメッセージをまとめて送信します
+func (d *dispatcher) worker(w *senderWorker) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	size := w.sender.BatchSize()
+	batch := make([]interface{}, 0, size)
+	flush := func(async bool) {
+		for len(batch) > 0 {
+			n := size
+			if len(batch) < n {
+				n = len(batch)
+			}
+			chunk := batch[:n:n]
+			if async {
+				go w.sender.SendMessages(chunk)
+			} else {
+				w.sender.SendMessages(chunk)
+			}
+			batch = batch[n:]
+		}
+		batch = make([]interface{}, 0, size)
+	}
+
+	for {
+		select {
+		case <-d.close:
+			flush(false)
+			return
+
+		case messages := <-w.queue:
+			batch = append(batch, messages...)
+			if len(batch) >= size {
+				flush(true)
+			}
+
+		case <-ticker.C:
+			flush(true)
+		}
+	}
+}
+
+// dndWorker 定期的におやすみモード中の通知のまとめを送信します
+func (d *dispatcher) dndWorker() {
+	ticker := time.NewTicker(dndSummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.close:
+			return
+		case <-ticker.C:
+			d.sendDNDSummaries()
+		}
+	}
+}
+
+// sendDNDSummaries おやすみモードが終了したユーザーに、おやすみモード中の通知のまとめを送信します
+func (d *dispatcher) sendDNDSummaries() {
+	for uid, count := range d.dnd.PopEnded(time.Now()) {
+		p := &Payload{
+			Type:  "dnd_summary",
+			Title: "おやすみモード中の通知",
+			Body:  fmt.Sprintf("おやすみモード中に%d件の通知がありました", count),
+			Path:  "/",
+			Tag:   "dnd_summary",
+		}
+		d.send(set.UUIDSetFromArray([]uuid.UUID{uid}), p, true)
+	}
+}
@@ -0,0 +1,158 @@
+package fcm
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dndRepository struct {
+	settings map[uuid.UUID]*model.DoNotDisturbSetting
+	mu       sync.Mutex
+}
+
+func (r *dndRepository) GetDoNotDisturbSetting(uuid.UUID) (*model.DoNotDisturbSetting, error) {
+	panic("implement me")
+}
+
+func (r *dndRepository) GetDoNotDisturbSettings(userIDs set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := map[uuid.UUID]*model.DoNotDisturbSetting{}
+	for id := range userIDs {
+		if s, ok := r.settings[id]; ok {
+			result[id] = s
+		}
+	}
+	return result, nil
+}
+
+func (r *dndRepository) UpdateDoNotDisturbSetting(uuid.UUID, repository.UpdateDoNotDisturbSettingArgs) error {
+	panic("implement me")
+}
+
+type fakeUnreadCounter map[uuid.UUID]int
+
+func (f fakeUnreadCounter) Get(userID uuid.UUID) int {
+	return f[userID]
+}
+
+func (f fakeUnreadCounter) GetChanges(bool) map[uuid.UUID]int {
+	return nil
+}
+
+// fakeSender 作成したメッセージを記録する送信手段
+type fakeSender struct {
+	batchSize int
+	sent      chan []interface{}
+}
+
+type fakeMessage struct {
+	userID uuid.UUID
+	data   map[string]string
+}
+
+func (s *fakeSender) Name() string {
+	return "fake"
+}
+
+func (s *fakeSender) BatchSize() int {
+	return s.batchSize
+}
+
+func (s *fakeSender) Messages(targetUserIDs set.UUID, p *Payload, unread func(userID uuid.UUID) int) ([]interface{}, error) {
+	var messages []interface{}
+	for id := range targetUserIDs {
+		count := -1
+		if unread != nil {
+			count = unread(id)
+		}
+		messages = append(messages, &fakeMessage{userID: id, data: p.Data(count)})
+	}
+	return messages, nil
+}
+
+func (s *fakeSender) SendMessages(messages []interface{}) {
+	s.sent <- messages
+}
+
+func (s *fakeSender) wait(t *testing.T) []*fakeMessage {
+	t.Helper()
+	select {
+	case messages := <-s.sent:
+		result := make([]*fakeMessage, len(messages))
+		for i, m := range messages {
+			result[i] = m.(*fakeMessage)
+		}
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages were not sent")
+		return nil
+	}
+}
+
+func TestNewDispatcher(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, NewNullClient(), NewDispatcher(&dndRepository{}, zap.NewNop(), fakeUnreadCounter{}))
+}
+
+func TestDispatcher_Send(t *testing.T) {
+	t.Parallel()
+
+	var (
+		user1 = uuid.Must(uuid.NewV4())
+		user2 = uuid.Must(uuid.NewV4())
+		user3 = uuid.Must(uuid.NewV4())
+	)
+	repo := &dndRepository{settings: map[uuid.UUID]*model.DoNotDisturbSetting{
+		user2: {UserID: user2, Enabled: true},
+	}}
+	s1 := &fakeSender{batchSize: 2, sent: make(chan []interface{}, 10)}
+	s2 := &fakeSender{batchSize: 10, sent: make(chan []interface{}, 10)}
+	c := NewDispatcher(repo, zap.NewNop(), fakeUnreadCounter{user1: 3}, s1, s2)
+	defer c.Close()
+
+	c.Send(set.UUIDSetFromArray([]uuid.UUID{user1, user2, user3}), &Payload{Type: "new_message"}, true)
+
+	// おやすみモード中のユーザーは全ての送信手段で除外される
+	for _, s := range []*fakeSender{s1, s2} {
+		messages := s.wait(t)
+		users := make([]uuid.UUID, len(messages))
+		for i, m := range messages {
+			users[i] = m.userID
+			if m.userID == user1 {
+				assert.Equal(t, "3", m.data["unread"])
+			}
+		}
+		assert.ElementsMatch(t, []uuid.UUID{user1, user3}, users)
+	}
+
+	// おやすみモードが終了すると、まとめ通知が全ての送信手段に1度ずつ送信される
+	repo.mu.Lock()
+	repo.settings = nil
+	repo.mu.Unlock()
+	c.(*dispatcher).sendDNDSummaries()
+	for _, s := range []*fakeSender{s1, s2} {
+		messages := s.wait(t)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, user2, messages[0].userID)
+			assert.Equal(t, "dnd_summary", messages[0].data["type"])
+			assert.Equal(t, "おやすみモード中に1件の通知がありました", messages[0].data["body"])
+		}
+	}
+	c.(*dispatcher).sendDNDSummaries()
+	for _, s := range []*fakeSender{s1, s2} {
+		select {
+		case <-s.sent:
+			t.Error("summary was sent twice")
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
@@ -2,11 +2,10 @@ package fcm
 
 import (
 	"context"
-	"errors"
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/messaging"
+	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/repository"
-	"github.com/traPtitech/traQ/service/counter"
 	"github.com/traPtitech/traQ/service/variable"
 	"github.com/traPtitech/traQ/utils/set"
 	"go.uber.org/zap"
@@ -15,22 +14,19 @@ import (
 	"time"
 )
 
-type clientImpl struct {
-	c             *messaging.Client
-	repo          repository.Repository
-	logger        *zap.Logger
-	unreadCounter counter.UnreadMessageCounter
-	queue         chan []*messaging.Message
-	close         chan struct{}
+type sender struct {
+	c      *messaging.Client
+	repo   repository.DeviceRepository
+	logger *zap.Logger
 }
 
-// NewClientWithCredentialsFile Firebase Cloud Messaging Clientを生成します
-func NewClientWithCredentialsFile(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, file variable.FirebaseCredentialsFilePathString) (Client, error) {
-	return NewClient(repo, logger, unreadCounter, option.WithCredentialsFile(string(file)))
+// NewSenderWithCredentialsFile Firebase Cloud Messagingの送信手段を生成します
+func NewSenderWithCredentialsFile(repo repository.DeviceRepository, logger *zap.Logger, file variable.FirebaseCredentialsFilePathString) (Sender, error) {
+	return NewSender(repo, logger, option.WithCredentialsFile(string(file)))
 }
 
-// NewClient Firebase Cloud Messaging Clientを生成します
-func NewClient(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, options ...option.ClientOption) (Client, error) {
+// NewSender Firebase Cloud Messagingの送信手段を生成します
+func NewSender(repo repository.DeviceRepository, logger *zap.Logger, options ...option.ClientOption) (Sender, error) {
 	app, err := firebase.NewApp(context.Background(), nil, options...)
 	if err != nil {
 		return nil, err
@@ -41,78 +37,39 @@ func NewClient(repo repository.Repository, logger *zap.Logger, unreadCounter cou
 		return nil, err
 	}
 
-	c := &clientImpl{
-		c:             mc,
-		repo:          repo,
-		logger:        logger.Named("fcm"),
-		unreadCounter: unreadCounter,
-		queue:         make(chan []*messaging.Message),
-		close:         make(chan struct{}),
-	}
-	go c.worker()
-
-	return c, nil
+	return &sender{
+		c:      mc,
+		repo:   repo,
+		logger: logger.Named("fcm"),
+	}, nil
 }
 
-func (c *clientImpl) Close() {
-	if !c.isClosed() {
-		close(c.close)
-		close(c.queue)
-	}
+func (c *sender) Name() string {
+	return "fcm"
 }
 
-func (c *clientImpl) isClosed() bool {
-	select {
-	case <-c.close:
-		return true
-	default:
-		return false
-	}
+func (c *sender) BatchSize() int {
+	return batchSize // 1度に送信できるのは500メッセージまで
 }
 
-func (c *clientImpl) Send(targetUserIDs set.UUID, payload *Payload, withUnreadCount bool) {
-	_ = c.send(targetUserIDs, payload, withUnreadCount)
-}
-
-func (c *clientImpl) send(targetUserIDs set.UUID, p *Payload, withUnreadCount bool) error {
-	if c.isClosed() {
-		return errors.New("fcm client has already been closed")
-	}
-
-	logger := c.logger.With(zap.Reflect("payload", p))
-
+func (c *sender) Messages(targetUserIDs set.UUID, p *Payload, unread func(userID uuid.UUID) int) ([]interface{}, error) {
 	tokensMap, err := c.repo.GetDeviceTokens(targetUserIDs)
 	if err != nil {
-		logger.Error("failed to GetDeviceTokens", zap.Error(err), zap.Strings("target_user_ids", targetUserIDs.StringArray()))
-		return err
-	}
-	if len(tokensMap) == 0 {
-		return nil
+		return nil, err
 	}
 
 	var (
-		messages            []*messaging.Message
+		messages            []interface{}
 		apnsHeaders         = map[string]string{"apns-expiration": strconv.FormatInt(time.Now().Add(messageTTL).Unix(), 10)}
 		apnsPayloadApsAlert = &messaging.ApsAlert{
 			Title: p.Title,
 			Body:  p.Body,
 		}
 	)
-	if withUnreadCount {
+	if unread != nil {
 		for uid, tokens := range tokensMap {
-			unread := c.unreadCounter.Get(uid)
-			data := map[string]string{
-				"type":   p.Type,
-				"title":  p.Title,
-				"body":   p.Body,
-				"path":   p.Path,
-				"tag":    p.Tag,
-				"icon":   p.Icon,
-				"unread": strconv.Itoa(unread),
-			}
-			if p.Image.Valid {
-				data["image"] = p.Image.String
-			}
+			count := unread(uid)
+			data := p.Data(count)
 			apns := &messaging.APNSConfig{
 				Headers: apnsHeaders,
 				Payload: &messaging.APNSPayload{
@@ -120,7 +77,7 @@ func (c *clientImpl) send(targetUserIDs set.UUID, p *Payload, withUnreadCount bo
 						Alert:    apnsPayloadApsAlert,
 						Sound:    "default",
 						ThreadID: p.Tag,
-						Badge:    &unread,
+						Badge:    &count,
 					},
 				},
 			}
@@ -136,17 +93,7 @@ func (c *clientImpl) send(targetUserIDs set.UUID, p *Payload, withUnreadCount bo
 			}
 		}
 	} else {
-		data := map[string]string{
-			"type":  p.Type,
-			"title": p.Title,
-			"body":  p.Body,
-			"path":  p.Path,
-			"tag":   p.Tag,
-			"icon":  p.Icon,
-		}
-		if p.Image.Valid {
-			data["image"] = p.Image.String
-		}
+		data := p.Data(-1)
 		apns := &messaging.APNSConfig{
 			Headers: apnsHeaders,
 			Payload: &messaging.APNSPayload{
@@ -170,54 +117,19 @@ func (c *clientImpl) send(targetUserIDs set.UUID, p *Payload, withUnreadCount bo
 			}
 		}
 	}
-
-	if c.isClosed() {
-		return errors.New("fcm client has already been closed")
-	}
-	c.queue <- messages
-	return nil
+	return messages, nil
 }
 
-func (c *clientImpl) worker() {
-	ticker := time.NewTicker(250 * time.Millisecond)
-	defer ticker.Stop()
-
-	batch := make([]*messaging.Message, 0, batchSize)
-	for {
-		select {
-		case <-c.close:
-			if len(batch) > 0 {
-				c.sendMessages(batch)
-			}
-			return
-
-		case messages := <-c.queue:
-			batch = append(batch, messages...)
-			if len(batch) >= batchSize {
-				go c.sendMessages(batch)
-				batch = make([]*messaging.Message, 0, batchSize)
-			}
-
-		case <-ticker.C:
-			if len(batch) > 0 {
-				go c.sendMessages(batch)
-				batch = make([]*messaging.Message, 0, batchSize)
-			}
-		}
+func (c *sender) SendMessages(messages []interface{}) {
+	chunk := make([]*messaging.Message, len(messages))
+	for i, m := range messages {
+		chunk[i] = m.(*messaging.Message)
 	}
-}
 
-func (c *clientImpl) sendMessages(messages []*messaging.Message) {
-	var invalidTokens []string
-	for _, v := range chunkMessages(messages, batchSize) { // 1度に送信できるのは500メッセージまで
-		ng, err := c.sendOneChunk(v)
-		if err != nil {
-			c.logger.Error("an error occurred in sending fcm", zap.Error(err))
-			return
-		}
-		if len(ng) > 0 {
-			invalidTokens = append(invalidTokens, ng...)
-		}
+	invalidTokens, err := c.sendOneChunk(chunk)
+	if err != nil {
+		c.logger.Error("an error occurred in sending fcm", zap.Error(err))
+		return
 	}
 	if len(invalidTokens) > 0 {
 		err := c.repo.DeleteDeviceTokens(invalidTokens)
@@ -228,7 +140,7 @@ func (c *clientImpl) sendMessages(messages []*messaging.Message) {
 	}
 }
 
-func (c *clientImpl) sendOneChunk(messages []*messaging.Message) (invalidTokens []string, err error) {
+func (c *sender) sendOneChunk(messages []*messaging.Message) (invalidTokens []string, err error) {
 	res, err := c.c.SendAll(context.Background(), messages)
 	if err != nil {
 		fcmBatchRequestCounter.WithLabelValues("error").Inc()
@@ -252,10 +164,3 @@ func (c *clientImpl) sendOneChunk(messages []*messaging.Message) (invalidTokens
 	}
 	return invalidTokens, nil
 }
-
-func chunkMessages(s []*messaging.Message, size int) (r [][]*messaging.Message) {
-	for size < len(s) {
-		s, r = s[size:], append(r, s[0:size:size])
-	}
-	return append(r, s)
-}
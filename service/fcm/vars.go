@@ -4,6 +4,7 @@ import (
 	"firebase.google.com/go/messaging"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/traPtitech/traQ/service/dnd"
 	"github.com/traPtitech/traQ/utils/optional"
 	"golang.org/x/exp/utf8string"
 	"strconv"
@@ -14,6 +15,7 @@ const (
 	batchSize            = 500
 	messageTTLSeconds    = 60 * 60 * 24 * 2 // 2日
 	notificationPriority = "high"
+	dndSummaryInterval   = time.Minute
 )
 
 var (
@@ -48,6 +50,30 @@ type Payload struct {
 	Path  string
 	Tag   string
 	Image optional.String
+
+	// Source 通知の発生源 (おやすみモードの例外判定に使用されます)
+	Source *dnd.Source
+}
+
+// Data 送信するデータを返します
+//
+// unreadが負の場合、未読数は含まれません。
+func (p *Payload) Data(unread int) map[string]string {
+	data := map[string]string{
+		"type":  p.Type,
+		"title": p.Title,
+		"body":  p.Body,
+		"path":  p.Path,
+		"tag":   p.Tag,
+		"icon":  p.Icon,
+	}
+	if unread >= 0 {
+		data["unread"] = strconv.Itoa(unread)
+	}
+	if p.Image.Valid {
+		data["image"] = p.Image.String
+	}
+	return data
 }
 
 // SetBodyWithEllipsis 100文字を超える場合は...で省略
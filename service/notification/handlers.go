@@ -2,22 +2,25 @@ package notification
 
 import (
 	"fmt"
-	"github.com/traPtitech/traQ/utils/optional"
-	"strings"
-	"time"
-
 	"github.com/gofrs/uuid"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/ws"
 	"github.com/traPtitech/traQ/utils/message"
+	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/set"
 	"go.uber.org/zap"
+	"strings"
+	"time"
 )
 
 type eventHandler func(ns *Service, ev hub.Message)
@@ -43,6 +46,7 @@ var handlerMap = map[string]eventHandler{
 	event.UserIconUpdated:           userIconUpdatedHandler,
 	event.UserOnline:                userOnlineHandler,
 	event.UserOffline:               userOfflineHandler,
+	event.UserStatusUpdated:         userStatusUpdatedHandler,
 	event.UserTagAdded:              userTagUpdatedHandler,
 	event.UserTagRemoved:            userTagUpdatedHandler,
 	event.UserTagUpdated:            userTagUpdatedHandler,
@@ -54,6 +58,8 @@ var handlerMap = map[string]eventHandler{
 	event.StampUpdated:              stampUpdatedHandler,
 	event.StampDeleted:              stampDeletedHandler,
 	event.StampPaletteCreated:       stampPaletteCreatedHandler,
+	event.KeywordAlertCreated:       keywordAlertChangedHandler,
+	event.KeywordAlertDeleted:       keywordAlertChangedHandler,
 	event.StampPaletteUpdated:       stampPaletteUpdatedHandler,
 	event.StampPaletteDeleted:       stampPaletteDeletedHandler,
 	event.UserWebRTCv3StateChanged:  userWebRTCv3StateChangedHandler,
@@ -85,6 +91,12 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		Type: "new_message",
 		Icon: fmt.Sprintf("%s/api/v3/public/icon/%s", ns.origin, strings.ReplaceAll(mUser.GetName(), "#", "%23")),
 		Tag:  "c:" + m.ChannelID.String(),
+		Source: &dnd.Source{
+			UserID:    m.UserID,
+			ChannelID: m.ChannelID,
+			IsDM:      isDM,
+			IsForced:  forceNotify,
+		},
 	}
 	ssePayload := &sse.EventData{
 		EventType: "MESSAGE_CREATED",
@@ -97,6 +109,7 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 	notifiedUsers := set.UUID{} // チャンネル通知購読ユーザー
 	markedUsers := set.UUID{}   // チャンネル未読管理ユーザー
 	noticeable := set.UUID{}    // noticeableな未読追加対象のユーザー
+	mentioned := set.UUID{}     // メンション・グループメンションを受けたユーザー
 
 	// メッセージボディ作成
 	if !isDM {
@@ -164,6 +177,7 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 			notifiedUsers.Add(uid)
 			markedUsers.Add(uid)
 			noticeable.Add(uid)
+			mentioned.Add(uid)
 		}
 		for _, gid := range parsed.GroupMentions {
 			gs, err := ns.repo.GetUserIDs(q.GMemberOf(gid))
@@ -174,7 +188,77 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 			notifiedUsers.Add(gs...)
 			markedUsers.Add(gs...)
 			noticeable.Add(gs...)
+			mentioned.Add(gs...)
 		}
+
+		// 特殊メンション (権限はチャンネルスコープのロールを含めて投稿時に確認済み)
+		specialMention := false
+		if parsed.HereMention || parsed.ChannelMention {
+			specialMention, err = rbac.IsGrantedInChannel(ns.rbac, ns.repo, ns.cm.PublicChannelTree(), mUser, chID, permission.UseSpecialMention)
+			if err != nil {
+				logger.Error("failed to IsGrantedInChannel", zap.Error(err), zap.Stringer("channelId", m.ChannelID)) // 失敗
+				return
+			}
+		}
+		if specialMention {
+			subscribers, err := ns.repo.GetUserIDs(q.SubscriberAtMarkLevelOf(chID))
+			if err != nil {
+				logger.Error("failed to GetUserIDs", zap.Error(err), zap.Stringer("channelId", m.ChannelID)) // 失敗
+				return
+			}
+			if parsed.ChannelMention {
+				// @channel: 全てのチャンネル購読者
+				notifiedUsers.Add(subscribers...)
+				markedUsers.Add(subscribers...)
+				noticeable.Add(subscribers...)
+			}
+			if parsed.HereMention {
+				// @here, @online: オンラインのチャンネル購読者・閲覧者
+				candidates := set.UUIDSetFromArray(subscribers)
+				for uid := range ns.vm.GetChannelViewers(chID) {
+					candidates.Add(uid)
+				}
+				for uid := range candidates {
+					if ns.oc.IsOnline(uid) {
+						notifiedUsers.Add(uid)
+						markedUsers.Add(uid)
+						noticeable.Add(uid)
+					}
+				}
+			}
+		}
+
+		// キーワード通知ユーザー取得
+		alerted, err := ns.keywords.Match(parsed.PlainText)
+		if err != nil {
+			logger.Error("failed to match keyword alerts", zap.Error(err)) // 失敗
+		}
+		if len(alerted) > 0 {
+			// 凍結ユーザー・Botを除外
+			users, err := ns.repo.GetUserIDs(q)
+			if err != nil {
+				logger.Error("failed to GetUserIDs", zap.Error(err)) // 失敗
+				return
+			}
+			for _, uid := range users {
+				if alerted.Contains(uid) {
+					notifiedUsers.Add(uid)
+					markedUsers.Add(uid)
+					noticeable.Add(uid)
+				}
+			}
+		}
+	}
+
+	// 投稿者をブロック・ミュートしているユーザーへの通知を抑制
+	blockers, err := ns.repo.GetUsersBlocking(m.UserID, true)
+	if err != nil {
+		logger.Error("failed to GetUsersBlocking", zap.Error(err), zap.Stringer("userId", m.UserID)) // 失敗
+		blockers = set.UUID{}
+	}
+	for uid := range blockers {
+		noticeable.Remove(uid)
+		mentioned.Remove(uid)
 	}
 
 	// チャンネル閲覧者取得
@@ -194,6 +278,13 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		}
 	}
 
+	// インボックス追加
+	var inboxMembers set.UUID
+	if isDM {
+		inboxMembers = notifiedUsers
+	}
+	addInboxEntries(ns, messageInboxEntries(ns, m, parsed, inboxMembers))
+
 	// WS送信
 	var targetFunc ws.TargetFunc
 	if isDM {
@@ -214,7 +305,30 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 	// FCM送信
 	targets := notifiedUsers.Clone()
 	targets.Remove(m.UserID)
+	targets.Remove(blockers.Array()...)
 	ns.fcm.Send(targets, fcmPayload, true)
+
+	// メール送信 (オフラインのユーザーのみ)
+	emailPayload := &email.Notification{
+		Type:       email.NotificationTypeMention,
+		SenderName: mUser.GetResponseDisplayName(),
+		Text:       parsed.OneLine(),
+		URL:        ns.origin + "/messages/" + m.ID.String(),
+	}
+	emailTargets := mentioned
+	if isDM {
+		emailPayload.Type = email.NotificationTypeDM
+		emailTargets = notifiedUsers
+	} else {
+		emailPayload.ChannelPath = chTree.GetChannelPath(chID)
+	}
+	offline := set.UUID{}
+	for uid := range emailTargets {
+		if uid != m.UserID && !blockers.Contains(uid) && !ns.oc.IsOnline(uid) {
+			offline.Add(uid)
+		}
+	}
+	ns.email.Send(offline, emailPayload)
 }
 
 func messageUpdatedHandler(ns *Service, ev hub.Message) {
@@ -292,16 +406,23 @@ func messageUnpinnedHandler(ns *Service, ev hub.Message) {
 }
 
 func messageStampedHandler(ns *Service, ev hub.Message) {
-	messageViewerMulticast(ns, ev.Fields["message_id"].(uuid.UUID), &sse.EventData{
+	messageID := ev.Fields["message_id"].(uuid.UUID)
+	userID := ev.Fields["user_id"].(uuid.UUID)
+	stampID := ev.Fields["stamp_id"].(uuid.UUID)
+	messageViewerMulticast(ns, messageID, &sse.EventData{
 		EventType: "MESSAGE_STAMPED",
 		Payload: map[string]interface{}{
-			"message_id": ev.Fields["message_id"].(uuid.UUID),
-			"user_id":    ev.Fields["user_id"].(uuid.UUID),
-			"stamp_id":   ev.Fields["stamp_id"].(uuid.UUID),
+			"message_id": messageID,
+			"user_id":    userID,
+			"stamp_id":   stampID,
 			"count":      ev.Fields["count"].(int),
 			"created_at": ev.Fields["created_at"].(time.Time),
 		},
 	})
+
+	if e := stampInboxEntry(ns, messageID, userID, stampID); e != nil {
+		addInboxEntries(ns, []*model.InboxEntry{e})
+	}
 }
 
 func messageUnstampedHandler(ns *Service, ev hub.Message) {
@@ -418,6 +539,9 @@ func userIconUpdatedHandler(ns *Service, ev hub.Message) {
 }
 
 func userOnlineHandler(ns *Service, ev hub.Message) {
+	if ns.pm.IsInvisible(ev.Fields["user_id"].(uuid.UUID)) {
+		return // オフライン表示中のユーザーはオンラインにならない
+	}
 	broadcast(ns, &sse.EventData{
 		EventType: "USER_ONLINE",
 		Payload: map[string]interface{}{
@@ -427,6 +551,9 @@ func userOnlineHandler(ns *Service, ev hub.Message) {
 }
 
 func userOfflineHandler(ns *Service, ev hub.Message) {
+	if ns.pm.IsInvisible(ev.Fields["user_id"].(uuid.UUID)) {
+		return
+	}
 	broadcast(ns, &sse.EventData{
 		EventType: "USER_OFFLINE",
 		Payload: map[string]interface{}{
@@ -435,6 +562,16 @@ func userOfflineHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func userStatusUpdatedHandler(ns *Service, ev hub.Message) {
+	broadcast(ns, &sse.EventData{
+		EventType: "USER_STATUS_UPDATED",
+		Payload: map[string]interface{}{
+			"id":       ev.Fields["user_id"].(uuid.UUID),
+			"presence": ev.Fields["presence"].(model.Presence),
+		},
+	})
+}
+
 func userTagUpdatedHandler(ns *Service, ev hub.Message) {
 	broadcast(ns, &sse.EventData{
 		EventType: "USER_TAGS_UPDATED",
@@ -525,6 +662,10 @@ func stampPaletteDeletedHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func keywordAlertChangedHandler(ns *Service, _ hub.Message) {
+	ns.keywords.Invalidate()
+}
+
 func userWebRTCv3StateChangedHandler(ns *Service, ev hub.Message) {
 	type StateSession struct {
 		State     string `json:"state"`
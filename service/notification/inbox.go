@@ -0,0 +1,133 @@
+package notification
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/sse"
+	"github.com/traPtitech/traQ/utils/message"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+)
+
+// messageInboxEntries メッセージの投稿によって作成されるインボックス項目を返します
+//
+// membersがnilでない場合、membersに含まれるユーザーのみを対象にします。
+func messageInboxEntries(ns *Service, m *model.Message, parsed *message.ParseResult, members set.UUID) []*model.InboxEntry {
+	var entries []*model.InboxEntry
+	added := set.UUID{}
+	add := func(uid uuid.UUID, t model.InboxEntryType) {
+		if uid == m.UserID || (members != nil && !members.Contains(uid)) {
+			return
+		}
+		// 同じメッセージで複数の理由に該当する場合は最初の一件のみ
+		if added.Contains(uid) {
+			return
+		}
+		added.Add(uid)
+		entries = append(entries, &model.InboxEntry{
+			UserID:    uid,
+			Type:      t,
+			MessageID: m.ID,
+			ActorID:   m.UserID,
+		})
+	}
+
+	for _, uid := range parsed.Mentions {
+		add(uid, model.InboxEntryTypeMention)
+	}
+	q := repository.UsersQuery{}.Active().NotBot()
+	for _, gid := range parsed.GroupMentions {
+		gs, err := ns.repo.GetUserIDs(q.GMemberOf(gid))
+		if err != nil {
+			ns.logger.Error("failed to GetUserIDs", zap.Error(err), zap.Stringer("groupId", gid)) // 失敗
+			continue
+		}
+		for _, uid := range gs {
+			add(uid, model.InboxEntryTypeGroupMention)
+		}
+	}
+	for _, mid := range parsed.Citation {
+		cited, err := ns.repo.GetMessageByID(mid)
+		if err != nil {
+			if err != repository.ErrNotFound {
+				ns.logger.Error("failed to GetMessageByID", zap.Error(err), zap.Stringer("messageId", mid)) // 失敗
+			}
+			continue
+		}
+		// 同じチャンネルのメッセージの引用は返信とみなす
+		if cited.ChannelID == m.ChannelID {
+			add(cited.UserID, model.InboxEntryTypeReply)
+		} else {
+			add(cited.UserID, model.InboxEntryTypeCitation)
+		}
+	}
+	return entries
+}
+
+// stampInboxEntry スタンプによって作成されるインボックス項目を返します
+func stampInboxEntry(ns *Service, messageID, userID, stampID uuid.UUID) *model.InboxEntry {
+	m, err := ns.repo.GetMessageByID(messageID)
+	if err != nil {
+		ns.logger.Error("failed to GetMessageByID", zap.Error(err), zap.Stringer("messageId", messageID)) // 失敗
+		return nil
+	}
+	if m.UserID == userID {
+		return nil
+	}
+	return &model.InboxEntry{
+		UserID:    m.UserID,
+		Type:      model.InboxEntryTypeStamp,
+		MessageID: messageID,
+		ActorID:   userID,
+		StampID:   optional.UUIDFrom(stampID),
+	}
+}
+
+// filterBlockedInboxEntries 受信者がActorをブロック・ミュートしている項目を除外します
+func filterBlockedInboxEntries(ns *Service, entries []*model.InboxEntry) []*model.InboxEntry {
+	blockers := map[uuid.UUID]set.UUID{}
+	result := make([]*model.InboxEntry, 0, len(entries))
+	for _, e := range entries {
+		b, ok := blockers[e.ActorID]
+		if !ok {
+			var err error
+			b, err = ns.repo.GetUsersBlocking(e.ActorID, true)
+			if err != nil {
+				ns.logger.Error("failed to GetUsersBlocking", zap.Error(err), zap.Stringer("userId", e.ActorID)) // 失敗
+				b = set.UUID{}
+			}
+			blockers[e.ActorID] = b
+		}
+		if !b.Contains(e.UserID) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// addInboxEntries インボックス項目を保存し、受信者に通知します
+//
+// 受信者が項目を発生させたユーザーをブロック・ミュートしている場合、その項目は保存しません。
+func addInboxEntries(ns *Service, entries []*model.InboxEntry) {
+	entries = filterBlockedInboxEntries(ns, entries)
+	if len(entries) == 0 {
+		return
+	}
+	created, err := ns.repo.CreateInboxEntries(entries)
+	if err != nil {
+		ns.logger.Error("failed to CreateInboxEntries", zap.Error(err)) // 失敗
+		return
+	}
+	for _, e := range created {
+		userMulticast(ns, e.UserID, &sse.EventData{
+			EventType: "INBOX_ENTRY_ADDED",
+			Payload: map[string]interface{}{
+				"id":         e.ID,
+				"type":       e.Type,
+				"message_id": e.MessageID,
+			},
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package notification
+
+import (
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/keyword"
+	"github.com/traPtitech/traQ/utils/set"
+	"sync"
+)
+
+// keywordAlerts 全ユーザーのキーワード通知のマッチャー
+//
+// マッチャーは初回の照合時に構築され、キーワード通知が変更されると破棄されます。
+type keywordAlerts struct {
+	repo    repository.KeywordAlertRepository
+	matcher *keyword.Matcher
+	mu      sync.RWMutex
+}
+
+func newKeywordAlerts(repo repository.KeywordAlertRepository) *keywordAlerts {
+	return &keywordAlerts{repo: repo}
+}
+
+// Match textにキーワードが含まれるユーザーのIDを返します
+func (ka *keywordAlerts) Match(text string) (set.UUID, error) {
+	ka.mu.RLock()
+	m := ka.matcher
+	ka.mu.RUnlock()
+
+	if m == nil {
+		ka.mu.Lock()
+		if ka.matcher == nil {
+			alerts, err := ka.repo.GetAllKeywordAlerts()
+			if err != nil {
+				ka.mu.Unlock()
+				return nil, err
+			}
+			patterns := make([]keyword.Pattern, len(alerts))
+			for i, a := range alerts {
+				patterns[i] = keyword.Pattern{UserID: a.UserID, Keyword: a.Keyword, IsRegexp: a.IsRegexp}
+			}
+			ka.matcher = keyword.NewMatcher(patterns)
+		}
+		m = ka.matcher
+		ka.mu.Unlock()
+	}
+	return m.Match(text), nil
+}
+
+// Invalidate マッチャーを破棄します
+func (ka *keywordAlerts) Invalidate() {
+	ka.mu.Lock()
+	ka.matcher = nil
+	ka.mu.Unlock()
+}
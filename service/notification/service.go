@@ -4,7 +4,11 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/service/presence"
+	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/variable"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -19,24 +23,36 @@ type Service struct {
 	hub    *hub.Hub
 	logger *zap.Logger
 	fcm    fcm.Client
+	email  email.Client
 	sse    *sse.Streamer
 	ws     *ws.Streamer
 	vm     *viewer.Manager
+	oc     *counter.OnlineCounter
+	pm     *presence.Manager
+	rbac   rbac.RBAC
 	origin string
+
+	keywords *keywordAlerts
 }
 
 // NewService 通知サービスを作成して起動します
-func NewService(repo repository.Repository, cm channel.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, sse *sse.Streamer, ws *ws.Streamer, vm *viewer.Manager, origin variable.ServerOriginString) *Service {
+func NewService(repo repository.Repository, cm channel.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, email email.Client, sse *sse.Streamer, ws *ws.Streamer, vm *viewer.Manager, oc *counter.OnlineCounter, pm *presence.Manager, rbac rbac.RBAC, origin variable.ServerOriginString) *Service {
 	service := &Service{
 		repo:   repo,
 		cm:     cm,
 		hub:    hub,
 		logger: logger.Named("notification"),
 		fcm:    fcm,
+		email:  email,
 		sse:    sse,
 		ws:     ws,
 		vm:     vm,
+		oc:     oc,
+		pm:     pm,
+		rbac:   rbac,
 		origin: string(origin),
+
+		keywords: newKeywordAlerts(repo),
 	}
 	go func() {
 		topics := make([]string, 0, len(handlerMap))
@@ -0,0 +1,231 @@
+package presence
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	expireCheckInterval     = time.Minute
+	lastOnlineFlushInterval = 5 * time.Minute
+)
+
+// Manager ユーザーのステータス・プレゼンスマネージャー
+//
+// counter.OnlineCounterの接続状態に、ユーザーが設定したステータスとプレゼンスモードを重ねます。
+type Manager struct {
+	repo     repository.UserStatusRepository
+	hub      *hub.Hub
+	oc       *counter.OnlineCounter
+	logger   *zap.Logger
+	statuses map[uuid.UUID]*model.UserStatus
+	mu       sync.RWMutex
+	close    chan struct{}
+	done     chan struct{}
+}
+
+// NewManager ステータス・プレゼンスマネージャーを生成します
+func NewManager(repo repository.Repository, hub *hub.Hub, oc *counter.OnlineCounter, logger *zap.Logger) (*Manager, error) {
+	return newManager(repo, hub, oc, logger)
+}
+
+func newManager(repo repository.UserStatusRepository, hub *hub.Hub, oc *counter.OnlineCounter, logger *zap.Logger) (*Manager, error) {
+	statuses, err := repo.GetUserStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		repo:     repo,
+		hub:      hub,
+		oc:       oc,
+		logger:   logger.Named("presence"),
+		statuses: make(map[uuid.UUID]*model.UserStatus, len(statuses)),
+		close:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, s := range statuses {
+		m.statuses[s.UserID] = s
+	}
+	go m.run()
+	return m, nil
+}
+
+// Close マネージャーを停止します
+//
+// オンラインのユーザーの最終オンライン日時を保存します。
+func (m *Manager) Close() {
+	select {
+	case <-m.close:
+		return
+	default:
+		close(m.close)
+		<-m.done
+	}
+}
+
+func (m *Manager) run() {
+	defer close(m.done)
+	sub := m.hub.Subscribe(10, event.UserOffline)
+	defer m.hub.Unsubscribe(sub)
+	expireTicker := time.NewTicker(expireCheckInterval)
+	defer expireTicker.Stop()
+	flushTicker := time.NewTicker(lastOnlineFlushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-m.close:
+			m.flushLastOnline(time.Now())
+			return
+
+		case ev, ok := <-sub.Receiver:
+			if !ok {
+				return
+			}
+			userID := ev.Fields["user_id"].(uuid.UUID)
+			if m.isInvisible(userID) {
+				continue // オフライン表示中は最終オンライン日時を更新しない
+			}
+			if err := m.repo.UpdateUsersLastOnline(set.UUIDSetFromArray([]uuid.UUID{userID}), ev.Fields["datetime"].(time.Time)); err != nil {
+				m.logger.Error("failed to UpdateUsersLastOnline", zap.Error(err), zap.Stringer("userId", userID))
+			}
+
+		case <-expireTicker.C:
+			m.expireStatuses(time.Now())
+
+		case <-flushTicker.C:
+			m.flushLastOnline(time.Now())
+		}
+	}
+}
+
+// flushLastOnline オンラインのユーザーの最終オンライン日時を保存します
+//
+// サーバーが異常終了した場合でも、最終オンライン日時がおおよそ保たれるようにします。
+func (m *Manager) flushLastOnline(now time.Time) {
+	users := set.UUID{}
+	for _, id := range m.oc.GetOnlineUserIDs() {
+		if !m.isInvisible(id) {
+			users.Add(id)
+		}
+	}
+	if err := m.repo.UpdateUsersLastOnline(users, now); err != nil {
+		m.logger.Error("failed to UpdateUsersLastOnline", zap.Error(err))
+	}
+}
+
+// expireStatuses 期限切れのステータスメッセージを消去します
+func (m *Manager) expireStatuses(now time.Time) {
+	m.mu.RLock()
+	var expired []uuid.UUID
+	for id, s := range m.statuses {
+		if s.HasMessage() && s.IsExpired(now) {
+			expired = append(expired, id)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, id := range expired {
+		if _, err := m.SetStatusMessage(id, "", optional.UUID{}, optional.Time{}); err != nil {
+			m.logger.Error("failed to clear expired status", zap.Error(err), zap.Stringer("userId", id))
+		}
+	}
+}
+
+// GetStatus 指定したユーザーのステータスを取得します
+//
+// 期限切れのステータスメッセージは含まれません。
+func (m *Manager) GetStatus(userID uuid.UUID) *model.UserStatus {
+	m.mu.RLock()
+	s, ok := m.statuses[userID]
+	m.mu.RUnlock()
+	if !ok {
+		return &model.UserStatus{UserID: userID, Mode: model.PresenceModeAuto}
+	}
+
+	c := *s
+	if c.IsExpired(time.Now()) {
+		c.Text = ""
+		c.StampID = optional.UUID{}
+		c.ExpiresAt = optional.Time{}
+	}
+	return &c
+}
+
+// GetPresence 他のユーザーから見える指定したユーザーのプレゼンスを取得します
+func (m *Manager) GetPresence(userID uuid.UUID) model.Presence {
+	return m.GetStatus(userID).Presence(m.oc.IsOnline(userID))
+}
+
+// IsInvisible 指定したユーザーがオフライン表示中かどうか
+func (m *Manager) IsInvisible(userID uuid.UUID) bool {
+	return m.isInvisible(userID)
+}
+
+func (m *Manager) isInvisible(userID uuid.UUID) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.statuses[userID]
+	return ok && s.Mode == model.PresenceModeInvisible
+}
+
+// GetOnlineUserIDs 他のユーザーから見えるオンラインなユーザーのUUIDの配列を取得します
+//
+// オフライン表示中のユーザーは含まれません。
+func (m *Manager) GetOnlineUserIDs() []uuid.UUID {
+	online := m.oc.GetOnlineUserIDs()
+	result := make([]uuid.UUID, 0, len(online))
+	for _, id := range online {
+		if !m.isInvisible(id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// SetStatusMessage 指定したユーザーのステータスメッセージを設定します
+//
+// textが空文字列かつstampIDが無効な場合、ステータスメッセージを消去します。
+func (m *Manager) SetStatusMessage(userID uuid.UUID, text string, stampID optional.UUID, expiresAt optional.Time) (*model.UserStatus, error) {
+	s, err := m.repo.SetUserStatusMessage(userID, text, stampID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	m.update(s)
+	return m.GetStatus(userID), nil
+}
+
+// SetPresenceMode 指定したユーザーのプレゼンスモードを設定します
+func (m *Manager) SetPresenceMode(userID uuid.UUID, mode model.PresenceMode) (*model.UserStatus, error) {
+	s, err := m.repo.SetUserPresenceMode(userID, mode)
+	if err != nil {
+		return nil, err
+	}
+	m.update(s)
+	return m.GetStatus(userID), nil
+}
+
+func (m *Manager) update(s *model.UserStatus) {
+	m.mu.Lock()
+	m.statuses[s.UserID] = s
+	m.mu.Unlock()
+
+	m.hub.Publish(hub.Message{
+		Name: event.UserStatusUpdated,
+		Fields: hub.Fields{
+			"user_id":  s.UserID,
+			"status":   m.GetStatus(s.UserID),
+			"presence": m.GetPresence(s.UserID),
+		},
+	})
+}
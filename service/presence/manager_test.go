@@ -0,0 +1,205 @@
+package presence
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRepository struct {
+	statuses   map[uuid.UUID]*model.UserStatus
+	lastOnline map[uuid.UUID]time.Time
+	mu         sync.Mutex
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		statuses:   map[uuid.UUID]*model.UserStatus{},
+		lastOnline: map[uuid.UUID]time.Time{},
+	}
+}
+
+func (r *fakeRepository) GetUserStatuses() ([]*model.UserStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]*model.UserStatus, 0, len(r.statuses))
+	for _, s := range r.statuses {
+		c := *s
+		result = append(result, &c)
+	}
+	return result, nil
+}
+
+func (r *fakeRepository) get(userID uuid.UUID) *model.UserStatus {
+	s, ok := r.statuses[userID]
+	if !ok {
+		s = &model.UserStatus{UserID: userID, Mode: model.PresenceModeAuto}
+		r.statuses[userID] = s
+	}
+	return s
+}
+
+func (r *fakeRepository) SetUserStatusMessage(userID uuid.UUID, text string, stampID optional.UUID, expiresAt optional.Time) (*model.UserStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.get(userID)
+	s.Text, s.StampID, s.ExpiresAt = text, stampID, expiresAt
+	c := *s
+	return &c, nil
+}
+
+func (r *fakeRepository) SetUserPresenceMode(userID uuid.UUID, mode model.PresenceMode) (*model.UserStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.get(userID)
+	s.Mode = mode
+	c := *s
+	return &c, nil
+}
+
+func (r *fakeRepository) UpdateUsersLastOnline(userIDs set.UUID, datetime time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id := range userIDs {
+		r.lastOnline[id] = datetime
+	}
+	return nil
+}
+
+func (r *fakeRepository) getLastOnline(userID uuid.UUID) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.lastOnline[userID]
+	return t, ok
+}
+
+func connect(t *testing.T, h *hub.Hub, oc *counter.OnlineCounter, userID uuid.UUID) {
+	t.Helper()
+	h.Publish(hub.Message{Name: event.WSConnected, Fields: hub.Fields{"user_id": userID}})
+	require.Eventually(t, func() bool { return oc.IsOnline(userID) }, time.Second, 10*time.Millisecond)
+}
+
+func setup(t *testing.T) (*Manager, *fakeRepository, *hub.Hub, *counter.OnlineCounter) {
+	t.Helper()
+	h := hub.New()
+	oc := counter.NewOnlineCounter(h)
+	// OnlineCounterの購読開始を待つ
+	probe := uuid.Must(uuid.NewV4())
+	require.Eventually(t, func() bool {
+		h.Publish(hub.Message{Name: event.WSConnected, Fields: hub.Fields{"user_id": probe}})
+		return oc.IsOnline(probe)
+	}, time.Second, 10*time.Millisecond)
+	repo := newFakeRepository()
+	m, err := newManager(repo, h, oc, zap.NewNop())
+	require.NoError(t, err)
+	return m, repo, h, oc
+}
+
+func TestManager_Presence(t *testing.T) {
+	t.Parallel()
+	m, _, h, oc := setup(t)
+	defer m.Close()
+
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	user3 := uuid.Must(uuid.NewV4())
+	connect(t, h, oc, user1)
+	connect(t, h, oc, user2)
+
+	_, err := m.SetPresenceMode(user1, model.PresenceModeBusy)
+	require.NoError(t, err)
+	_, err = m.SetPresenceMode(user2, model.PresenceModeInvisible)
+	require.NoError(t, err)
+
+	assert.Equal(t, model.PresenceBusy, m.GetPresence(user1))
+	assert.Equal(t, model.PresenceOffline, m.GetPresence(user2))
+	assert.Equal(t, model.PresenceOffline, m.GetPresence(user3))
+	assert.True(t, m.IsInvisible(user2))
+	assert.Contains(t, m.GetOnlineUserIDs(), user1)
+	assert.NotContains(t, m.GetOnlineUserIDs(), user2)
+}
+
+func TestManager_SetStatusMessage(t *testing.T) {
+	t.Parallel()
+	m, _, h, _ := setup(t)
+	defer m.Close()
+
+	sub := h.Subscribe(1, event.UserStatusUpdated)
+	defer h.Unsubscribe(sub)
+
+	user := uuid.Must(uuid.NewV4())
+	stamp := uuid.Must(uuid.NewV4())
+	s, err := m.SetStatusMessage(user, "lunch", optional.UUIDFrom(stamp), optional.TimeFrom(time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "lunch", s.Text)
+	assert.Equal(t, "lunch", m.GetStatus(user).Text)
+
+	select {
+	case ev := <-sub.Receiver:
+		assert.Equal(t, user, ev.Fields["user_id"])
+		assert.Equal(t, "lunch", ev.Fields["status"].(*model.UserStatus).Text)
+		assert.Equal(t, model.PresenceOffline, ev.Fields["presence"])
+	case <-time.After(time.Second):
+		t.Fatal("UserStatusUpdated was not published")
+	}
+}
+
+func TestManager_expireStatuses(t *testing.T) {
+	t.Parallel()
+	m, repo, _, _ := setup(t)
+	defer m.Close()
+
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	now := time.Now()
+	_, err := m.SetStatusMessage(user1, "lunch", optional.UUID{}, optional.TimeFrom(now.Add(-time.Second)))
+	require.NoError(t, err)
+	_, err = m.SetStatusMessage(user2, "vacation", optional.UUID{}, optional.TimeFrom(now.Add(time.Hour)))
+	require.NoError(t, err)
+
+	// 期限切れのメッセージは取得時点で見えない
+	assert.False(t, m.GetStatus(user1).HasMessage())
+
+	m.expireStatuses(now)
+	assert.Empty(t, repo.statuses[user1].Text)
+	assert.Equal(t, "vacation", repo.statuses[user2].Text)
+}
+
+func TestManager_LastOnline(t *testing.T) {
+	t.Parallel()
+	m, repo, h, oc := setup(t)
+
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	user3 := uuid.Must(uuid.NewV4())
+	_, err := m.SetPresenceMode(user3, model.PresenceModeInvisible)
+	require.NoError(t, err)
+	connect(t, h, oc, user1)
+	connect(t, h, oc, user2)
+	connect(t, h, oc, user3)
+
+	// 切断時に保存される
+	h.Publish(hub.Message{Name: event.WSDisconnected, Fields: hub.Fields{"user_id": user1}})
+	require.Eventually(t, func() bool {
+		_, ok := repo.getLastOnline(user1)
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// 終了時にオンラインのユーザーの分が保存される
+	m.Close()
+	_, ok := repo.getLastOnline(user2)
+	assert.True(t, ok)
+	// オフライン表示中のユーザーは保存されない
+	_, ok = repo.getLastOnline(user3)
+	assert.False(t, ok)
+}
@@ -0,0 +1,69 @@
+package rbac
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+)
+
+// ChannelRoleBindingGetter チャンネルスコープのロール割り当ての取得元
+type ChannelRoleBindingGetter interface {
+	GetChannelRoleBindingsByUserID(userID uuid.UUID) ([]*model.ChannelRoleBinding, error)
+}
+
+// GetChannelRoles 指定したチャンネルでユーザーに割り当てられているロールを取得します
+//
+// チャンネルとその祖先チャンネルに割り当てられたロールを返します。ユーザー自身のロールは含みません。
+func GetChannelRoles(repo ChannelRoleBindingGetter, tree channel.Tree, userID, channelID uuid.UUID) ([]string, error) {
+	bindings, err := repo.GetChannelRoleBindingsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0)
+	if len(bindings) == 0 {
+		return roles, nil
+	}
+
+	scope := map[uuid.UUID]bool{channelID: true}
+	for _, id := range tree.GetAscendantIDs(channelID) {
+		scope[id] = true
+	}
+	for _, b := range bindings {
+		if scope[b.ChannelID] {
+			roles = append(roles, b.Role)
+		}
+	}
+	return roles, nil
+}
+
+// IsGrantedInChannel 指定したユーザーが指定したチャンネルで指定した権限を持っているかどうか
+//
+// ユーザー自身のロールに加え、チャンネルとその祖先チャンネルで割り当てられたロールを考慮します。
+func IsGrantedInChannel(r RBAC, repo ChannelRoleBindingGetter, tree channel.Tree, user model.UserInfo, channelID uuid.UUID, perm permission.Permission) (bool, error) {
+	if r.IsGranted(user.GetRole(), perm) {
+		return true, nil
+	}
+	roles, err := GetChannelRoles(repo, tree, user.GetID(), channelID)
+	if err != nil {
+		return false, err
+	}
+	return r.IsAnyGranted(roles, perm), nil
+}
+
+// GetGrantedPermissionsInChannel 指定したユーザーが指定したチャンネルで持つ全ての権限を取得します
+//
+// ユーザー自身のロールに加え、チャンネルとその祖先チャンネルで割り当てられたロールを考慮します。
+func GetGrantedPermissionsInChannel(r RBAC, repo ChannelRoleBindingGetter, tree channel.Tree, user model.UserInfo, channelID uuid.UUID) (permission.Permissions, error) {
+	roles, err := GetChannelRoles(repo, tree, user.GetID(), channelID)
+	if err != nil {
+		return nil, err
+	}
+	result := permission.PermissionsFromArray(r.GetGrantedPermissions(user.GetRole()))
+	for _, role := range roles {
+		for _, p := range r.GetGrantedPermissions(role) {
+			result.Add(p)
+		}
+	}
+	return result, nil
+}
@@ -13,6 +13,8 @@ const (
 	ChangeParentChannel = Permission("change_parent_channel")
 	// EditChannelTopic チャンネルトピック変更権限
 	EditChannelTopic = Permission("edit_channel_topic")
+	// ManageChannelRole チャンネルスコープのロールの割り当て権限
+	ManageChannelRole = Permission("manage_channel_role")
 	// GetChannelStar チャンネルスター取得権限
 	GetChannelStar = Permission("get_channel_star")
 	// EditChannelStar チャンネルスター編集権限
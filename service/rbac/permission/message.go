@@ -9,6 +9,8 @@ const (
 	EditMessage = Permission("edit_message")
 	// DeleteMessage メッセージ削除権限
 	DeleteMessage = Permission("delete_message")
+	// DeleteOthersMessage 他ユーザーのメッセージ削除権限
+	DeleteOthersMessage = Permission("delete_others_message")
 	// ReportMessage メッセージ通報権限
 	ReportMessage = Permission("report_message")
 	// GetMessageReports メッセージ通報取得権限
@@ -17,4 +19,6 @@ const (
 	CreateMessagePin = Permission("create_message_pin")
 	// DeleteMessagePin ピン留め削除権限
 	DeleteMessagePin = Permission("delete_message_pin")
+	// UseSpecialMention 特殊メンション(@here, @online, @channel)使用権限
+	UseSpecialMention = Permission("use_special_mention")
 )
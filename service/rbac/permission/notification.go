@@ -9,4 +9,18 @@ const (
 	ConnectNotificationStream = Permission("connect_notification_stream")
 	// RegisterFCMDevice FCMデバイスの登録権限
 	RegisterFCMDevice = Permission("register_fcm_device")
+	// RegisterWebPushSubscription Web Push購読の登録権限
+	RegisterWebPushSubscription = Permission("register_webpush_subscription")
+	// GetDoNotDisturbSetting おやすみモード設定取得権限
+	GetDoNotDisturbSetting = Permission("get_dnd_setting")
+	// EditDoNotDisturbSetting おやすみモード設定変更権限
+	EditDoNotDisturbSetting = Permission("edit_dnd_setting")
+	// GetKeywordAlert キーワード通知設定取得権限
+	GetKeywordAlert = Permission("get_keyword_alert")
+	// EditKeywordAlert キーワード通知設定変更権限
+	EditKeywordAlert = Permission("edit_keyword_alert")
+	// GetEmailNotificationSetting メール通知設定取得権限
+	GetEmailNotificationSetting = Permission("get_email_setting")
+	// EditEmailNotificationSetting メール通知設定変更権限
+	EditEmailNotificationSetting = Permission("edit_email_setting")
 )
@@ -66,8 +66,10 @@ var List = []Permission{
 	DeleteChannel,
 	ChangeParentChannel,
 	EditChannelTopic,
+	ManageChannelRole,
 
 	GetMyTokens,
+	CreateMyToken,
 	RevokeMyToken,
 	GetClients,
 	CreateClient,
@@ -86,22 +88,40 @@ var List = []Permission{
 	PostMessage,
 	EditMessage,
 	DeleteMessage,
+	DeleteOthersMessage,
 	ReportMessage,
 	GetMessageReports,
+	UseSpecialMention,
 
 	GetChannelSubscription,
 	EditChannelSubscription,
 	ConnectNotificationStream,
 	RegisterFCMDevice,
+	GetDoNotDisturbSetting,
+	EditDoNotDisturbSetting,
+	GetKeywordAlert,
+	EditKeywordAlert,
+	GetEmailNotificationSetting,
+	EditEmailNotificationSetting,
+	RegisterWebPushSubscription,
 
 	CreateMessagePin,
 	DeleteMessagePin,
 
 	GetMySessions,
 	DeleteMySessions,
+	ManageOthersSessions,
+	ManageMyTwoFactor,
+	ManageTwoFactorPolicy,
+	ManageMyWebAuthn,
+	ManageRole,
+	ManageLoginLock,
+	GetLoginAuditLog,
 
 	GetMyExternalAccount,
 	EditMyExternalAccount,
+	GetUserBlock,
+	EditUserBlock,
 
 	GetStamp,
 	CreateStamp,
@@ -120,10 +140,14 @@ var List = []Permission{
 
 	GetUser,
 	RegisterUser,
+	CreateInvitation,
+	ManageInvitation,
+	ProvisionUsers,
 	GetMe,
 	EditMe,
 	ChangeMyIcon,
 	ChangeMyPassword,
+	EditMyStatus,
 	EditOtherUsers,
 	GetUserQRCode,
 	GetUserGroup,
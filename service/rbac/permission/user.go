@@ -5,6 +5,12 @@ const (
 	GetUser = Permission("get_user")
 	// RegisterUser 新規ユーザー登録権限
 	RegisterUser = Permission("register_user")
+	// CreateInvitation 自分が管理者のユーザーグループへの招待リンク作成権限
+	CreateInvitation = Permission("create_invitation")
+	// ManageInvitation 全ての招待リンクの作成・取得・削除権限
+	ManageInvitation = Permission("manage_invitation")
+	// ProvisionUsers SCIMによるユーザー・グループのプロビジョニング権限
+	ProvisionUsers = Permission("provision_users")
 	// GetMe 自ユーザー情報取得権限
 	GetMe = Permission("get_me")
 	// EditMe 自ユーザー情報変更権限
@@ -13,6 +19,8 @@ const (
 	ChangeMyIcon = Permission("change_my_icon")
 	// ChangeMyPassword 自ユーザーパスワード変更権限
 	ChangeMyPassword = Permission("change_my_password")
+	// EditMyStatus 自ユーザーのステータス・プレゼンス変更権限
+	EditMyStatus = Permission("edit_my_status")
 	// EditOtherUsers 他ユーザー情報変更権限
 	EditOtherUsers = Permission("edit_other_users")
 	// GetUserQRCode ユーザーQRコード取得権限
@@ -41,10 +49,28 @@ const (
 	GetMySessions = Permission("get_my_sessions")
 	// DeleteMySessions セッション削除権限
 	DeleteMySessions = Permission("delete_my_sessions")
+	// ManageOthersSessions 他ユーザーのセッションの取得・削除権限
+	ManageOthersSessions = Permission("manage_others_sessions")
+	// ManageMyTwoFactor 自分の二要素認証設定の管理権限
+	ManageMyTwoFactor = Permission("manage_my_two_factor")
+	// ManageTwoFactorPolicy ロールごとの二要素認証の必須化設定権限
+	ManageTwoFactorPolicy = Permission("manage_two_factor_policy")
+	// ManageMyWebAuthn 自分のWebAuthn認証情報の管理権限
+	ManageMyWebAuthn = Permission("manage_my_webauthn")
+	// ManageRole ユーザーロールの作成・編集・削除権限
+	ManageRole = Permission("manage_role")
+	// ManageLoginLock ログイン試行のロックの取得・解除権限
+	ManageLoginLock = Permission("manage_login_lock")
+	// GetLoginAuditLog ログイン失敗の監査ログ取得権限
+	GetLoginAuditLog = Permission("get_login_audit_log")
 	// GetMyExternalAccount 外部ログインアカウント情報取得権限
 	GetMyExternalAccount = Permission("get_my_external_account")
 	// EditMyExternalAccount 外部ログインアカウント情報編集権限
 	EditMyExternalAccount = Permission("edit_my_external_account")
+	// GetUserBlock ブロック・ミュートしたユーザーの取得権限
+	GetUserBlock = Permission("get_user_block")
+	// EditUserBlock ユーザーのブロック・ミュート権限
+	EditUserBlock = Permission("edit_user_block")
 	// GetUnread 未読メッセージ一覧の取得権限
 	GetUnread = Permission("get_unread")
 	// DeleteUnread メッセージ既読化権限
@@ -3,9 +3,12 @@ package rbac
 import (
 	"fmt"
 	"github.com/jinzhu/gorm"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/service/rbac/permission"
 	"github.com/traPtitech/traQ/service/rbac/role"
+	"go.uber.org/zap"
 	"sync"
 )
 
@@ -16,7 +19,9 @@ type rbacImpl struct {
 }
 
 // New RBACを初期化
-func New(db *gorm.DB) (RBAC, error) {
+//
+// ロールが更新されると自動で再読み込みされます
+func New(db *gorm.DB, hub *hub.Hub, logger *zap.Logger) (RBAC, error) {
 	rbac := &rbacImpl{
 		roles: role.Roles{},
 		db:    db,
@@ -24,6 +29,14 @@ func New(db *gorm.DB) (RBAC, error) {
 	if err := rbac.reload(); err != nil {
 		return nil, fmt.Errorf("failed to init rbac: %w", err)
 	}
+	logger = logger.Named("rbac")
+	go func() {
+		for range hub.Subscribe(8, event.RoleUpdated).Receiver {
+			if err := rbac.reload(); err != nil {
+				logger.Error("failed to reload roles", zap.Error(err))
+			}
+		}
+	}()
 	return rbac, nil
 }
 
@@ -93,8 +106,6 @@ func (r *rbacImpl) reload() error {
 		}
 	}
 
-	// TODO 木の循環検知
-
 	result := role.Roles{}
 	for _, v := range roles {
 		result.Add(v)
@@ -0,0 +1,19 @@
+package role
+
+import (
+	"github.com/traPtitech/traQ/service/rbac/permission"
+)
+
+// ChannelLead チャンネルリーダーロール
+//
+// チャンネルスコープのロールとして割り当てることを想定したカスタムロールとして初期投入されます。
+const ChannelLead = "channel_lead"
+
+var channelLeadPerms = []permission.Permission{
+	permission.EditChannel,
+	permission.EditChannelTopic,
+	permission.ManageChannelRole,
+	permission.CreateMessagePin,
+	permission.DeleteMessagePin,
+	permission.DeleteOthersMessage,
+}
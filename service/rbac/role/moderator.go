@@ -0,0 +1,19 @@
+package role
+
+import (
+	"github.com/traPtitech/traQ/service/rbac/permission"
+)
+
+// Moderator モデレーターユーザーロール
+//
+// userロールを継承するカスタムロールとして初期投入されます。
+const Moderator = "moderator"
+
+var moderatorPerms = []permission.Permission{
+	permission.GetMessageReports,
+	permission.ManageLoginLock,
+	permission.GetLoginAuditLog,
+	permission.ManageOthersSessions,
+	permission.EditStampCreatedByOthers,
+	permission.DeleteOthersMessage,
+}
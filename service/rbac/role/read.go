@@ -11,6 +11,10 @@ var readPerms = []permission.Permission{
 	permission.GetChannel,
 	permission.GetMessage,
 	permission.GetChannelSubscription,
+	permission.GetDoNotDisturbSetting,
+	permission.GetKeywordAlert,
+	permission.GetEmailNotificationSetting,
+	permission.GetUserBlock,
 	permission.ConnectNotificationStream,
 	permission.GetUser,
 	permission.GetMe,
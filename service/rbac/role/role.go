@@ -58,6 +58,54 @@ func SystemRoleModels() []*model.UserRole {
 	return result
 }
 
+// DefaultRoleModels 初期投入するカスタムロールのモデルを返します
+//
+// システム定義ロールと異なり、実行時に編集・削除できます。
+func DefaultRoleModels() []*model.UserRole {
+	return []*model.UserRole{
+		{
+			Name:         Moderator,
+			Inheritances: []model.RoleInheritance{{Role: Moderator, SubRole: User}},
+			Permissions:  convertRolePermissions(Moderator, permission.PermissionsFromArray(moderatorPerms)),
+		},
+		{
+			Name:        ChannelLead,
+			Permissions: convertRolePermissions(ChannelLead, permission.PermissionsFromArray(channelLeadPerms)),
+		},
+	}
+}
+
+// ResolvePermissions ロールの実効権限を継承を辿って求めます
+//
+// rolesには継承先のロールを全て含める必要があります。adminロールを継承している場合は全ての権限を返します。
+func ResolvePermissions(r *model.UserRole, roles map[string]*model.UserRole) permission.Permissions {
+	result := permission.Permissions{}
+	visited := map[string]bool{}
+	var walk func(r *model.UserRole)
+	walk = func(r *model.UserRole) {
+		if visited[r.Name] {
+			return
+		}
+		visited[r.Name] = true
+		if r.Name == Admin {
+			for _, p := range permission.List {
+				result.Add(p)
+			}
+			return
+		}
+		for _, p := range r.Permissions {
+			result.Add(permission.Permission(p.Permission))
+		}
+		for _, i := range r.Inheritances {
+			if sub, ok := roles[i.SubRole]; ok {
+				walk(sub)
+			}
+		}
+	}
+	walk(r)
+	return result
+}
+
 func convertRolePermissions(role string, perms permission.Permissions) []model.RolePermission {
 	result := make([]model.RolePermission, 0, len(perms))
 	for p := range perms {
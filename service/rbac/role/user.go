@@ -13,7 +13,11 @@ var userPerms = []permission.Permission{
 	permission.GetUserQRCode,
 	permission.GetMySessions,
 	permission.DeleteMySessions,
+	permission.ManageMyTwoFactor,
+	permission.ManageMyWebAuthn,
+	permission.CreateInvitation,
 	permission.GetMyTokens,
+	permission.CreateMyToken,
 	permission.RevokeMyToken,
 	permission.GetMyExternalAccount,
 	permission.EditMyExternalAccount,
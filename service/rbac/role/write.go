@@ -14,12 +14,19 @@ var writePerms = []permission.Permission{
 	permission.EditMessage,
 	permission.DeleteMessage,
 	permission.ReportMessage,
+	permission.UseSpecialMention,
 	permission.CreateMessagePin,
 	permission.DeleteMessagePin,
 	permission.EditChannelSubscription,
 	permission.RegisterFCMDevice,
+	permission.RegisterWebPushSubscription,
+	permission.EditDoNotDisturbSetting,
+	permission.EditKeywordAlert,
+	permission.EditEmailNotificationSetting,
 	permission.EditMe,
 	permission.ChangeMyIcon,
+	permission.EditMyStatus,
+	permission.EditUserBlock,
 	permission.EditChannelStar,
 	permission.DeleteUnread,
 	permission.EditUserTag,
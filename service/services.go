@@ -4,10 +4,12 @@ import (
 	"github.com/traPtitech/traQ/service/bot"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/heartbeat"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/presence"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -22,10 +24,12 @@ type Services struct {
 	UnreadMessageCounter counter.UnreadMessageCounter
 	MessageCounter       counter.MessageCounter
 	ChannelCounter       counter.ChannelCounter
+	Email                email.Client
 	FCM                  fcm.Client
 	HeartBeats           *heartbeat.Manager
 	Imaging              imaging.Processor
 	Notification         *notification.Service
+	Presence             *presence.Manager
 	RBAC                 rbac.RBAC
 	SSE                  *sse.Streamer
 	ViewerManager        *viewer.Manager
@@ -13,10 +13,12 @@ var ProviderSet = wire.NewSet(wire.FieldsOf(new(*Services),
 	"UnreadMessageCounter",
 	"MessageCounter",
 	"ChannelCounter",
+	"Email",
 	"FCM",
 	"HeartBeats",
 	"Imaging",
 	"Notification",
+	"Presence",
 	"RBAC",
 	"SSE",
 	"ViewerManager",
@@ -0,0 +1,151 @@
+package webpush
+
+import (
+	"encoding/json"
+	"errors"
+	wp "github.com/SherClockHolmes/webpush-go"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Config Web Push設定
+type Config struct {
+	// VAPIDPublicKey VAPID公開鍵 (base64url)
+	VAPIDPublicKey string
+	// VAPIDPrivateKey VAPID秘密鍵 (base64url)
+	VAPIDPrivateKey string
+	// Subscriber プッシュサービスに通知する連絡先 (mailto:またはhttps:のURL)
+	Subscriber string
+}
+
+type message struct {
+	sub  *model.WebPushSubscription
+	body []byte
+}
+
+type sender struct {
+	repo       repository.WebPushRepository
+	logger     *zap.Logger
+	config     Config
+	httpClient wp.HTTPClient
+}
+
+// NewSender VAPIDを用いたWeb Pushの送信手段を生成します
+//
+// 生成した送信手段はfcm.Senderとして、FCMと同じペイロードを送信します。
+func NewSender(repo repository.WebPushRepository, logger *zap.Logger, config Config) (fcm.Sender, error) {
+	return newSender(repo, logger, config, &http.Client{Timeout: requestTimeout})
+}
+
+func newSender(repo repository.WebPushRepository, logger *zap.Logger, config Config, httpClient wp.HTTPClient) (*sender, error) {
+	if len(config.VAPIDPublicKey) == 0 || len(config.VAPIDPrivateKey) == 0 {
+		return nil, errors.New("webpush: VAPID keys are required")
+	}
+
+	return &sender{
+		repo:       repo,
+		logger:     logger.Named("webpush"),
+		config:     config,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (c *sender) Name() string {
+	return "webpush"
+}
+
+func (c *sender) BatchSize() int {
+	return batchSize
+}
+
+func (c *sender) Messages(targetUserIDs set.UUID, p *fcm.Payload, unread func(userID uuid.UUID) int) ([]interface{}, error) {
+	subsMap, err := c.repo.GetWebPushSubscriptions(targetUserIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []interface{}
+	if unread != nil {
+		for uid, subs := range subsMap {
+			body, err := json.Marshal(p.Data(unread(uid)))
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range subs {
+				messages = append(messages, &message{sub: s, body: body})
+			}
+		}
+	} else {
+		body, err := json.Marshal(p.Data(-1))
+		if err != nil {
+			return nil, err
+		}
+		for _, subs := range subsMap {
+			for _, s := range subs {
+				messages = append(messages, &message{sub: s, body: body})
+			}
+		}
+	}
+	return messages, nil
+}
+
+func (c *sender) SendMessages(messages []interface{}) {
+	var expired []string
+	for _, v := range messages {
+		m := v.(*message)
+		if c.sendOne(m) {
+			expired = append(expired, m.sub.Endpoint)
+		}
+	}
+	if len(expired) > 0 {
+		if err := c.repo.DeleteWebPushSubscriptionsByEndpoints(expired); err != nil {
+			c.logger.Error("failed to DeleteWebPushSubscriptionsByEndpoints", zap.Error(err), zap.Strings("endpoints", expired))
+		}
+	}
+}
+
+// sendOne 1件のWeb Push通知を送信します
+//
+// 購読が失効していた場合はtrueを返します。
+func (c *sender) sendOne(m *message) (expired bool) {
+	res, err := wp.SendNotification(m.body, &wp.Subscription{
+		Endpoint: m.sub.Endpoint,
+		Keys: wp.Keys{
+			Auth:   m.sub.Auth,
+			P256dh: m.sub.P256dh,
+		},
+	}, &wp.Options{
+		HTTPClient:      c.httpClient,
+		Subscriber:      c.config.Subscriber,
+		TTL:             messageTTLSeconds,
+		Urgency:         wp.UrgencyHigh,
+		VAPIDPublicKey:  c.config.VAPIDPublicKey,
+		VAPIDPrivateKey: c.config.VAPIDPrivateKey,
+	})
+	if err != nil {
+		webPushSendCounter.WithLabelValues("error").Inc()
+		c.logger.Warn("webpush: "+err.Error(), zap.Stringer("subscriptionId", m.sub.ID))
+		return false
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		webPushSendCounter.WithLabelValues("expired").Inc()
+		return true
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		webPushSendCounter.WithLabelValues("ok").Inc()
+	default:
+		webPushSendCounter.WithLabelValues("error").Inc()
+		c.logger.Warn("webpush: unexpected response status", zap.Int("status", res.StatusCode), zap.Stringer("subscriptionId", m.sub.ID))
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	wp "github.com/SherClockHolmes/webpush-go"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRepository struct {
+	subs    map[uuid.UUID][]*model.WebPushSubscription
+	deleted chan []string
+}
+
+func (r *fakeRepository) RegisterWebPushSubscription(uuid.UUID, string, string, string) (*model.WebPushSubscription, error) {
+	panic("implement me")
+}
+
+func (r *fakeRepository) GetWebPushSubscriptions(userIDs set.UUID) (map[uuid.UUID][]*model.WebPushSubscription, error) {
+	result := map[uuid.UUID][]*model.WebPushSubscription{}
+	for id := range userIDs {
+		if s, ok := r.subs[id]; ok {
+			result[id] = s
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeRepository) DeleteWebPushSubscription(uuid.UUID, uuid.UUID) error {
+	panic("implement me")
+}
+
+func (r *fakeRepository) DeleteWebPushSubscriptionsByEndpoints(endpoints []string) error {
+	r.deleted <- endpoints
+	return nil
+}
+
+func (r *fakeRepository) GetDoNotDisturbSetting(uuid.UUID) (*model.DoNotDisturbSetting, error) {
+	panic("implement me")
+}
+
+func (r *fakeRepository) GetDoNotDisturbSettings(set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error) {
+	return map[uuid.UUID]*model.DoNotDisturbSetting{}, nil
+}
+
+func (r *fakeRepository) UpdateDoNotDisturbSetting(uuid.UUID, repository.UpdateDoNotDisturbSettingArgs) error {
+	panic("implement me")
+}
+
+type fakeUnreadCounter map[uuid.UUID]int
+
+func (f fakeUnreadCounter) Get(userID uuid.UUID) int {
+	return f[userID]
+}
+
+func (f fakeUnreadCounter) GetChanges(bool) map[uuid.UUID]int {
+	return nil
+}
+
+// userAgent ブラウザ側の購読鍵
+type userAgent struct {
+	priv   []byte
+	pub    []byte
+	secret []byte
+}
+
+func newUserAgent(t *testing.T) *userAgent {
+	t.Helper()
+	priv, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 16)
+	_, err = rand.Read(secret)
+	require.NoError(t, err)
+	return &userAgent{priv: priv, pub: elliptic.Marshal(elliptic.P256(), x, y), secret: secret}
+}
+
+// decrypt RFC 8291 (aes128gcm) で暗号化されたメッセージを復号します
+func (ua *userAgent) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("too short")
+	}
+	salt := body[:16]
+	rs := binary.BigEndian.Uint32(body[16:20])
+	idLen := int(body[20])
+	if len(body) < 21+idLen || rs < 18 {
+		return nil, errors.New("invalid header")
+	}
+	asPub := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	curve := elliptic.P256()
+	x, y := elliptic.Unmarshal(curve, asPub)
+	if x == nil {
+		return nil, errors.New("invalid key")
+	}
+	sx, _ := curve.ScalarMult(x, y, ua.priv)
+	shared := make([]byte, curve.Params().BitSize/8)
+	sx.FillBytes(shared)
+
+	info := append(append([]byte("WebPush: info\x00"), ua.pub...), asPub...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, ua.secret, info), ikm); err != nil {
+		return nil, err
+	}
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// パディングを除去 (最終レコードの区切りは0x02)
+	plain = bytes.TrimRight(plain, "\x00")
+	if len(plain) == 0 || plain[len(plain)-1] != 0x02 {
+		return nil, errors.New("invalid padding")
+	}
+	return plain[:len(plain)-1], nil
+}
+
+func (ua *userAgent) subscription(userID uuid.UUID, endpoint string) *model.WebPushSubscription {
+	return &model.WebPushSubscription{
+		ID:       uuid.Must(uuid.NewV4()),
+		UserID:   userID,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(ua.pub),
+		Auth:     base64.RawURLEncoding.EncodeToString(ua.secret),
+	}
+}
+
+type pushRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newTestClient(t *testing.T, repo *fakeRepository, unread fakeUnreadCounter, httpClient *http.Client) fcm.Client {
+	t.Helper()
+	priv, pub, err := wp.GenerateVAPIDKeys()
+	require.NoError(t, err)
+	s, err := newSender(repo, zap.NewNop(), Config{
+		VAPIDPublicKey:  pub,
+		VAPIDPrivateKey: priv,
+		Subscriber:      "mailto:admin@example.com",
+	}, httpClient)
+	require.NoError(t, err)
+	return fcm.NewDispatcher(repo, zap.NewNop(), unread, s)
+}
+
+func TestNewSender(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSender(&fakeRepository{}, zap.NewNop(), Config{})
+	assert.Error(t, err)
+}
+
+func TestSender_Send(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan pushRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- pushRequest{path: r.URL.Path, header: r.Header, body: body}
+		if strings.HasPrefix(r.URL.Path, "/gone") {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	ua1 := newUserAgent(t)
+	ua2 := newUserAgent(t)
+	repo := &fakeRepository{
+		subs: map[uuid.UUID][]*model.WebPushSubscription{
+			user1: {ua1.subscription(user1, server.URL+"/ok")},
+			user2: {ua2.subscription(user2, server.URL+"/gone")},
+		},
+		deleted: make(chan []string, 1),
+	}
+	c := newTestClient(t, repo, fakeUnreadCounter{user1: 3}, server.Client())
+	defer c.Close()
+
+	c.Send(set.UUIDSetFromArray([]uuid.UUID{user1, user2}), &fcm.Payload{
+		Type:  "new_message",
+		Title: "#general",
+		Body:  "hello",
+		Path:  "/channels/general",
+		Tag:   "c:general",
+	}, true)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case req := <-received:
+			assert.Equal(t, "aes128gcm", req.header.Get("Content-Encoding"))
+			assert.Equal(t, "high", req.header.Get("Urgency"))
+			assert.True(t, strings.HasPrefix(req.header.Get("Authorization"), "vapid "))
+
+			if req.path != "/ok" {
+				continue
+			}
+			plain, err := ua1.decrypt(req.body)
+			if assert.NoError(t, err) {
+				var data map[string]string
+				if assert.NoError(t, json.Unmarshal(plain, &data)) {
+					assert.Equal(t, "new_message", data["type"])
+					assert.Equal(t, "hello", data["body"])
+					assert.Equal(t, "3", data["unread"])
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("push request timed out")
+		}
+	}
+
+	select {
+	case endpoints := <-repo.deleted:
+		assert.ElementsMatch(t, []string{server.URL + "/gone"}, endpoints)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expired subscription was not deleted")
+	}
+}
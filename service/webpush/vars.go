@@ -0,0 +1,20 @@
+package webpush
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+const (
+	batchSize         = 100
+	messageTTLSeconds = 60 * 60 * 24 * 2 // 2日
+	requestTimeout    = 10 * time.Second
+)
+
+var (
+	webPushSendCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "traq",
+		Name:      "webpush_send_count_total",
+	}, []string{"result"})
+)
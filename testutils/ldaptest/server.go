@@ -0,0 +1,380 @@
+// Package ldaptest テスト用のインメモリLDAPサーバーを提供します
+package ldaptest
+
+import (
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"net"
+	"strings"
+	"sync"
+)
+
+// PasswordAttribute Bindで検証するパスワードの属性名
+const PasswordAttribute = "userPassword"
+
+// Server Bind, Searchのみに応答するインメモリLDAPサーバー
+type Server struct {
+	// URL サーバーのURL (ldap://127.0.0.1:port)
+	URL string
+
+	l       net.Listener
+	mu      sync.RWMutex
+	entries []*ldap.Entry
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewServer ループバックアドレスでサーバーを起動します
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		URL:   "ldap://" + l.Addr().String(),
+		l:     l,
+		conns: map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// AddEntry エントリを追加します
+//
+// PasswordAttribute属性を設定したエントリはBindできます。
+func (s *Server) AddEntry(dn string, attrs map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, ldap.NewEntry(dn, attrs))
+}
+
+// SetAttribute 既存のエントリの属性を置き換えます
+func (s *Server) SetAttribute(dn, name string, values ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.find(dn)
+	if e == nil {
+		return
+	}
+	attrs := make([]*ldap.EntryAttribute, 0, len(e.Attributes)+1)
+	for _, a := range e.Attributes {
+		if !strings.EqualFold(a.Name, name) {
+			attrs = append(attrs, a)
+		}
+	}
+	if len(values) > 0 {
+		attrs = append(attrs, ldap.NewEntryAttribute(name, values))
+	}
+	e.Attributes = attrs
+}
+
+// Close サーバーを停止します
+func (s *Server) Close() {
+	_ = s.l.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	for {
+		msg, err := ber.ReadPacket(conn)
+		if err != nil || len(msg.Children) < 2 {
+			return
+		}
+		id, ok := msg.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		op := msg.Children[1]
+		reply := func(res *ber.Packet) bool {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "Message ID"))
+			envelope.AppendChild(res)
+			_, err := conn.Write(envelope.Bytes())
+			return err == nil
+		}
+
+		if op.ClassType != ber.ClassApplication {
+			return
+		}
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			if !reply(s.bind(op)) {
+				return
+			}
+		case ldap.ApplicationSearchRequest:
+			entries, done := s.search(op)
+			for _, e := range entries {
+				if !reply(e) {
+					return
+				}
+			}
+			if !reply(done) {
+				return
+			}
+		case ldap.ApplicationExtendedRequest:
+			if !reply(result(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, "extended operations are not supported")) {
+				return
+			}
+		default:
+			// UnbindRequest, その他の操作では接続を閉じる
+			return
+		}
+	}
+}
+
+func result(tag ber.Tag, code uint16, message string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return p
+}
+
+func (s *Server) bind(op *ber.Packet) *ber.Packet {
+	if len(op.Children) < 3 {
+		return result(ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "malformed request")
+	}
+	dn := stringValue(op.Children[1])
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return result(ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported, "only simple bind is supported")
+	}
+	password := stringValue(auth)
+	if len(dn) == 0 && len(password) == 0 {
+		return result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e := s.find(dn); e != nil && len(password) > 0 {
+		for _, p := range e.GetAttributeValues(PasswordAttribute) {
+			if p == password {
+				return result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")
+			}
+		}
+	}
+	return result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *Server) search(op *ber.Packet) ([]*ber.Packet, *ber.Packet) {
+	if len(op.Children) < 8 {
+		return nil, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "malformed request")
+	}
+	base := normalizeDN(stringValue(op.Children[0]))
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+	var attrs []string
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, stringValue(a))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(base) > 0 && s.find(base) == nil {
+		return nil, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, "no such object")
+	}
+
+	var entries []*ber.Packet
+	for _, e := range s.entries {
+		if !inScope(normalizeDN(e.DN), base, scope) || !match(filter, e) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(entries)) >= sizeLimit {
+			return entries, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, "size limit exceeded")
+		}
+		entries = append(entries, encodeEntry(e, attrs))
+	}
+	return entries, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, "")
+}
+
+func (s *Server) find(dn string) *ldap.Entry {
+	dn = normalizeDN(dn)
+	for _, e := range s.entries {
+		if normalizeDN(e.DN) == dn {
+			return e
+		}
+	}
+	return nil
+}
+
+func encodeEntry(e *ldap.Entry, attrs []string) *ber.Packet {
+	list := ber.NewSequence("Attributes")
+	for _, a := range e.Attributes {
+		if strings.EqualFold(a.Name, PasswordAttribute) || !requested(attrs, a.Name) {
+			continue
+		}
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range a.Values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr := ber.NewSequence("Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a.Name, "Type"))
+		attr.AppendChild(vals)
+		list.AppendChild(attr)
+	}
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+	p.AppendChild(list)
+	return p
+}
+
+// stringValue 文字列のパケットの値を返します
+//
+// Context Specificなどのクラスの値はデコードされないため、生のデータを返します。
+func stringValue(p *ber.Packet) string {
+	if v, ok := p.Value.(string); ok {
+		return v
+	}
+	return p.Data.String()
+}
+
+func requested(attrs []string, name string) bool {
+	if len(attrs) == 0 {
+		return true
+	}
+	for _, a := range attrs {
+		if a == "*" || strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(p))
+	}
+	return strings.Join(parts, ",")
+}
+
+func inScope(dn, base string, scope int64) bool {
+	if len(base) == 0 {
+		return scope != ldap.ScopeBaseObject
+	}
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == base
+	case ldap.ScopeSingleLevel:
+		i := strings.IndexByte(dn, ',')
+		return i >= 0 && dn[i+1:] == base
+	default:
+		return dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+func match(f *ber.Packet, e *ldap.Entry) bool {
+	if f.ClassType != ber.ClassContext {
+		return false
+	}
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !match(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if match(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(f.Children) == 1 && !match(f.Children[0], e)
+	case ldap.FilterPresent:
+		return len(e.GetAttributeValues(stringValue(f))) > 0
+	case ldap.FilterSubstrings:
+		if len(f.Children) < 2 {
+			return false
+		}
+		for _, v := range e.GetAttributeValues(stringValue(f.Children[0])) {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(f.Children) < 2 {
+			return false
+		}
+		expected := strings.ToLower(stringValue(f.Children[1]))
+		for _, v := range e.GetAttributeValues(stringValue(f.Children[0])) {
+			v = strings.ToLower(v)
+			switch f.Tag {
+			case ldap.FilterGreaterOrEqual:
+				if v >= expected {
+					return true
+				}
+			case ldap.FilterLessOrEqual:
+				if v <= expected {
+					return true
+				}
+			default:
+				if v == expected {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchSubstrings(v string, subs []*ber.Packet) bool {
+	for _, sub := range subs {
+		s := strings.ToLower(stringValue(sub))
+		switch sub.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(v, s) {
+				return false
+			}
+			v = v[len(s):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(v, s)
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(s):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(v, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
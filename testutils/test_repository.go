@@ -1349,6 +1349,174 @@ func (repo *TestRepository) GetDeviceTokens(set.UUID) (tokens map[uuid.UUID][]st
 	panic("implement me")
 }
 
+func (repo *TestRepository) GetDoNotDisturbSetting(userID uuid.UUID) (*model.DoNotDisturbSetting, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetDoNotDisturbSettings(userIDs set.UUID) (map[uuid.UUID]*model.DoNotDisturbSetting, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateDoNotDisturbSetting(userID uuid.UUID, args repository.UpdateDoNotDisturbSettingArgs) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateKeywordAlert(userID uuid.UUID, keyword string, isRegexp bool) (*model.KeywordAlert, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetKeywordAlert(id uuid.UUID) (*model.KeywordAlert, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetKeywordAlerts(userID uuid.UUID) ([]*model.KeywordAlert, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetAllKeywordAlerts() ([]*model.KeywordAlert, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteKeywordAlert(id uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateInboxEntries(entries []*model.InboxEntry) ([]*model.InboxEntry, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetInboxEntries(userID uuid.UUID, query repository.InboxEntriesQuery) (entries []*model.InboxEntry, more bool, err error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) ReadInboxEntry(userID, entryID uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetEmailNotificationSetting(userID uuid.UUID) (*model.EmailNotificationSetting, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetEmailNotificationSettings(userIDs set.UUID) (map[uuid.UUID]*model.EmailNotificationSetting, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetEmailDigestSettings() ([]*model.EmailNotificationSetting, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateEmailNotificationSetting(userID uuid.UUID, args repository.UpdateEmailNotificationSettingArgs) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateEmailAddressVerification(userID uuid.UUID, address string) (*model.EmailAddressVerification, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) VerifyEmailAddress(token string) (uuid.UUID, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) RegisterWebPushSubscription(userID uuid.UUID, endpoint, p256dh, auth string) (*model.WebPushSubscription, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetWebPushSubscriptions(userIDs set.UUID) (map[uuid.UUID][]*model.WebPushSubscription, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteWebPushSubscription(userID, id uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteWebPushSubscriptionsByEndpoints(endpoints []string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserStatuses() ([]*model.UserStatus, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetUserStatusMessage(userID uuid.UUID, text string, stampID optional.UUID, expiresAt optional.Time) (*model.UserStatus, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetUserPresenceMode(userID uuid.UUID, mode model.PresenceMode) (*model.UserStatus, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateUsersLastOnline(userIDs set.UUID, datetime time.Time) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetUserBlock(userID, targetID uuid.UUID, blockType model.UserBlockType) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteUserBlock(userID, targetID uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserBlocks(userID uuid.UUID) ([]*model.UserBlock, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUsersBlocking(targetID uuid.UUID, includeMuted bool) (set.UUID, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) IsBlockedBetween(user1, user2 uuid.UUID) (bool, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserTOTP(userID uuid.UUID) (*model.UserTOTP, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetUserTOTPSecret(userID uuid.UUID, secret string) (*model.UserTOTP, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) EnableUserTOTP(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateUserTOTPLastStep(userID uuid.UUID, step int64) (bool, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteUserTOTP(userID uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) ReplaceUserRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UseUserRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetUserRecoveryCodeCount(userID uuid.UUID) (int, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetTwoFactorRequiredRoles() ([]string, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) IsTwoFactorRequired(role string) (bool, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetTwoFactorRequiredRoles(roles []string) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetFileMeta(fileID uuid.UUID) (model.FileMeta, error) {
 	if fileID == uuid.Nil {
 		return nil, repository.ErrNotFound
@@ -1713,6 +1881,14 @@ func (repo *TestRepository) DeleteClient(string) error {
 	panic("implement me")
 }
 
+func (repo *TestRepository) CreateInitialAccessToken(uuid.UUID, int) (*model.OAuth2InitialAccessToken, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UseInitialAccessToken(string) (*model.OAuth2InitialAccessToken, error) {
+	panic("implement me")
+}
+
 func (repo *TestRepository) SaveAuthorize(*model.OAuth2Authorize) error {
 	panic("implement me")
 }
@@ -1725,10 +1901,42 @@ func (repo *TestRepository) DeleteAuthorize(string) error {
 	panic("implement me")
 }
 
+func (repo *TestRepository) SaveDeviceAuthorization(*model.OAuth2DeviceAuthorization) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetDeviceAuthorizationByDeviceCode(string) (*model.OAuth2DeviceAuthorization, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetDeviceAuthorizationByUserCode(string) (*model.OAuth2DeviceAuthorization, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DecideDeviceAuthorization(string, uuid.UUID, bool) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateDeviceAuthorizationPolling(string, time.Time, int) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteDeviceAuthorization(string) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) IssueToken(*model.OAuth2Client, uuid.UUID, string, model.AccessScopes, int, bool) (*model.OAuth2Token, error) {
 	panic("implement me")
 }
 
+func (repo *TestRepository) IssuePersonalAccessToken(uuid.UUID, string, model.AccessScopes, int) (*model.OAuth2Token, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateTokenLastUsedAt(uuid.UUID, time.Time) error {
+	panic("implement me")
+}
+
 func (repo *TestRepository) GetTokenByID(uuid.UUID) (*model.OAuth2Token, error) {
 	panic("implement me")
 }
@@ -1837,6 +2045,42 @@ func (repo *TestRepository) GetMessageClips(uuid.UUID, uuid.UUID) ([]*model.Clip
 	panic("implement me")
 }
 
+func (repo *TestRepository) GetRoles() ([]*model.UserRole, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetRole(string) (*model.UserRole, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateRole(repository.CreateRoleArgs) (*model.UserRole, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateRole(string, repository.UpdateRoleArgs) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteRole(string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelRoleBindings(uuid.UUID) ([]*model.ChannelRoleBinding, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetChannelRoleBindingsByUserID(uuid.UUID) ([]*model.ChannelRoleBinding, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetChannelRoleBinding(uuid.UUID, uuid.UUID, string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteChannelRoleBinding(uuid.UUID, uuid.UUID) error {
+	panic("implement me")
+}
+
 type fileMetaImpl struct {
 	meta *model.File
 	fs   storage.FileStorage
@@ -1913,3 +2157,95 @@ func (f *fileMetaImpl) GetAlternativeURL() string {
 func (repo *TestRepository) GetFiles(repository.FilesQuery) (result []model.FileMeta, more bool, err error) {
 	panic("implement me")
 }
+
+func (repo *TestRepository) CreateWebAuthnCredential(userID uuid.UUID, name string, credentialID string, publicKey []byte, signCount uint32, aaguid []byte) (*model.WebAuthnCredential, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetWebAuthnCredentials(userID uuid.UUID) ([]*model.WebAuthnCredential, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetWebAuthnCredential(id uuid.UUID) (*model.WebAuthnCredential, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetWebAuthnCredentialByCredentialID(credentialID string) (*model.WebAuthnCredential, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) HasWebAuthnCredentials(userID uuid.UUID) (bool, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) RenameWebAuthnCredential(id uuid.UUID, name string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UpdateWebAuthnCredentialUsage(id uuid.UUID, signCount uint32) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteWebAuthnCredential(id uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetLoginLock(kind model.LoginLockKind, key string) (*model.LoginLock, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetLoginLocks() ([]*model.LoginLock, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) IncrementLoginFailure(kind model.LoginLockKind, key string, window time.Duration) (*model.LoginLock, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SetLoginLockedUntil(kind model.LoginLockKind, key string, until time.Time) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteLoginLock(kind model.LoginLockKind, key string) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateLoginAuditLog(log *model.LoginAuditLog) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetLoginAuditLogs(query repository.LoginAuditLogQuery) ([]*model.LoginAuditLog, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UseSAMLAssertion(id string, expiresAt time.Time) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) CreateInvitation(args repository.CreateInvitationArgs) (*model.Invitation, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetInvitation(id uuid.UUID) (*model.Invitation, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetInvitationByToken(token string) (*model.Invitation, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) GetInvitations(creatorID uuid.UUID) ([]*model.Invitation, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) UseInvitation(id uuid.UUID) error {
+	panic("implement me")
+}
+
+func (repo *TestRepository) SignUpWithInvitation(invitationID uuid.UUID, args repository.CreateUserArgs) (model.UserInfo, error) {
+	panic("implement me")
+}
+
+func (repo *TestRepository) DeleteInvitation(id uuid.UUID) error {
+	panic("implement me")
+}
@@ -3,39 +3,115 @@ package jwt
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
+	"strings"
 )
 
-var (
-	pub  *ecdsa.PublicKey
+// signingKey 鍵IDと鍵の組
+type signingKey struct {
+	kid  string
 	priv *ecdsa.PrivateKey
+}
+
+// ErrNotInitialized SetupSignerが呼ばれていません
+var ErrNotInitialized = errors.New("jwt signer is not initialized")
+
+var (
+	// current 署名に使用する鍵
+	current *signingKey
+	// keys 検証に使用する鍵 (先頭は署名に使用する鍵)
+	keys []*signingKey
 )
 
 // SetupSigner JWTを発行・検証するためのSignerのセットアップ
-func SetupSigner(privRaw []byte) error {
-	_priv, err := jwt.ParseECPrivateKeyFromPEM(bytes.TrimSpace(privRaw))
-	if err != nil {
-		return err
+//
+// privRawで署名を行います。previousRawsに指定した鍵は、鍵のローテーション前に発行されたJWTの検証とJWKSの公開にのみ使用します。
+// 鍵はP-256のECDSA秘密鍵(PEM)でなければなりません。
+func SetupSigner(privRaw []byte, previousRaws ...[]byte) error {
+	ks := make([]*signingKey, 0, len(previousRaws)+1)
+	for _, raw := range append([][]byte{privRaw}, previousRaws...) {
+		k, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+		ks = append(ks, k)
 	}
 
-	pub = &_priv.PublicKey
-	priv = _priv
+	current = ks[0]
+	keys = ks
 	return nil
 }
 
+func parseKey(raw []byte) (*signingKey, error) {
+	priv, err := jwt.ParseECPrivateKeyFromPEM(bytes.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	if priv.Curve != elliptic.P256() {
+		return nil, errors.New("the key must be a P-256 ECDSA key")
+	}
+	return &signingKey{kid: thumbprint(&priv.PublicKey), priv: priv}, nil
+}
+
 // Sign JWTの発行を行う
 func Sign(claims jwt.Claims) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(priv)
+	return SignWithType("JWT", claims)
+}
+
+// SignWithType typヘッダーを指定してJWTの発行を行う
+func SignWithType(typ string, claims jwt.Claims) (string, error) {
+	if current == nil {
+		return "", ErrNotInitialized
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = typ
+	token.Header["kid"] = current.kid
+	return token.SignedString(current.priv)
 }
 
 // Verify JWTの検証を行う
+//
+// kidヘッダーがないJWTは署名に使用している鍵で検証します。
 func Verify(tokenString string, claims jwt.Claims) error {
+	return verify(tokenString, "", claims)
+}
+
+// VerifyWithType typヘッダーが一致することを含めてJWTの検証を行う
+//
+// typは大文字小文字を区別せず、"application/"の接頭辞を省略したものとして比較します。
+func VerifyWithType(tokenString string, typ string, claims jwt.Claims) error {
+	return verify(tokenString, typ, claims)
+}
+
+func verify(tokenString string, typ string, claims jwt.Claims) error {
+	if current == nil {
+		return ErrNotInitialized
+	}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return pub, nil
+		if len(typ) > 0 {
+			t, _ := token.Header["typ"].(string)
+			if strings.TrimPrefix(strings.ToLower(t), "application/") != strings.ToLower(typ) {
+				return nil, fmt.Errorf("unexpected token type: %s", t)
+			}
+		}
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return &current.priv.PublicKey, nil
+		}
+		for _, k := range keys {
+			if k.kid == kid {
+				return &k.priv.PublicKey, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id: %s", kid)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to parse token: %v", err)
@@ -45,3 +121,54 @@ func Verify(tokenString string, claims jwt.Claims) error {
 	}
 	return nil
 }
+
+// JSONWebKey JSON Web Key (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JSONWebKeySet JSON Web Key Set (RFC 7517)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS 検証に使用する公開鍵の一覧を返します
+func JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, k := range keys {
+		x, y := encodeCoordinates(&k.priv.PublicKey)
+		set.Keys = append(set.Keys, JSONWebKey{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   x,
+			Y:   y,
+			Kid: k.kid,
+			Use: "sig",
+			Alg: jwt.SigningMethodES256.Alg(),
+		})
+	}
+	return set
+}
+
+func encodeCoordinates(pub *ecdsa.PublicKey) (string, string) {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	xb, yb := pub.X.Bytes(), pub.Y.Bytes()
+	copy(x[size-len(xb):], xb)
+	copy(y[size-len(yb):], yb)
+	return base64.RawURLEncoding.EncodeToString(x), base64.RawURLEncoding.EncodeToString(y)
+}
+
+// thumbprint JWK Thumbprint (RFC 7638)
+func thumbprint(pub *ecdsa.PublicKey) string {
+	x, y := encodeCoordinates(pub)
+	sum := sha256.Sum256([]byte(`{"crv":"P-256","kty":"EC","x":"` + x + `","y":"` + y + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/utils/random"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	oldRaw, _ := random.GenerateECDSAKey()
+	require.NoError(t, SetupSigner(oldRaw))
+	oldToken, err := Sign(jwt.MapClaims{"sub": "old"})
+	require.NoError(t, err)
+	oldKid := current.kid
+
+	// 鍵のローテーション
+	newRaw, _ := random.GenerateECDSAKey()
+	require.NoError(t, SetupSigner(newRaw, oldRaw))
+	newToken, err := Sign(jwt.MapClaims{"sub": "new"})
+	require.NoError(t, err)
+	assert.NotEqual(t, oldKid, current.kid)
+
+	t.Run("kid header", func(t *testing.T) {
+		token, _, err := new(jwt.Parser).ParseUnverified(newToken, jwt.MapClaims{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, current.kid, token.Header["kid"])
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		claims := jwt.MapClaims{}
+		if assert.NoError(t, Verify(newToken, claims)) {
+			assert.Equal(t, "new", claims["sub"])
+		}
+		// ローテーション前の鍵で署名されたトークン
+		claims = jwt.MapClaims{}
+		if assert.NoError(t, Verify(oldToken, claims)) {
+			assert.Equal(t, "old", claims["sub"])
+		}
+	})
+
+	t.Run("typ header", func(t *testing.T) {
+		token, err := SignWithType("at+jwt", jwt.MapClaims{"sub": "at"})
+		require.NoError(t, err)
+		assert.NoError(t, VerifyWithType(token, "at+jwt", jwt.MapClaims{}))
+		assert.NoError(t, Verify(token, jwt.MapClaims{}))
+		assert.Error(t, VerifyWithType(newToken, "at+jwt", jwt.MapClaims{}))
+	})
+
+	t.Run("unknown kid", func(t *testing.T) {
+		require.NoError(t, SetupSigner(newRaw))
+		defer func() { require.NoError(t, SetupSigner(newRaw, oldRaw)) }()
+		assert.Error(t, Verify(oldToken, jwt.MapClaims{}))
+	})
+
+	t.Run("JWKS", func(t *testing.T) {
+		set := JWKS()
+		if assert.Len(t, set.Keys, 2) {
+			assert.Equal(t, current.kid, set.Keys[0].Kid)
+			assert.Equal(t, oldKid, set.Keys[1].Kid)
+			assert.Equal(t, "EC", set.Keys[0].Kty)
+			assert.Equal(t, "P-256", set.Keys[0].Crv)
+			assert.Equal(t, "ES256", set.Keys[0].Alg)
+		}
+	})
+
+	t.Run("non P-256 key", func(t *testing.T) {
+		priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		require.NoError(t, err)
+		b, err := x509.MarshalECPrivateKey(priv)
+		require.NoError(t, err)
+		assert.Error(t, SetupSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b})))
+	})
+}
@@ -0,0 +1,208 @@
+package keyword
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/set"
+	"regexp"
+	"strings"
+)
+
+// Pattern キーワードパターン
+type Pattern struct {
+	// UserID パターンの所有者
+	UserID uuid.UUID
+	// Keyword キーワードまたは正規表現
+	Keyword string
+	// IsRegexp Keywordが正規表現かどうか
+	IsRegexp bool
+}
+
+// Matcher 複数ユーザーのキーワードを一度に照合するマッチャー
+//
+// 通常のキーワードはAho-Corasick法により大文字小文字を区別せずに照合します。
+// 正規表現はユーザー毎に1つの選択(|)にまとめ、さらに全ての正規表現をまとめた選択で事前に照合するため、
+// どのパターンにも一致しないテキストは正規表現の数によらず1回の照合で済みます。
+// 生成後のMatcherは不変で、複数のgoroutineから安全に使用できます。
+type Matcher struct {
+	nodes []node
+	// regexps ユーザー毎にまとめた正規表現
+	regexps []regexpPattern
+	// anyRegexp 全ての正規表現をまとめた正規表現 (正規表現が無い場合はnil)
+	anyRegexp *regexp.Regexp
+	// numRegexps 登録されている正規表現の数
+	numRegexps int
+}
+
+type node struct {
+	next   map[byte]int32
+	fail   int32
+	owners []uuid.UUID // このノードで終わるキーワードの所有者
+}
+
+type regexpPattern struct {
+	re     *regexp.Regexp
+	userID uuid.UUID
+}
+
+// NewMatcher パターンからマッチャーを生成します
+//
+// コンパイルできない正規表現は無視されます。
+func NewMatcher(patterns []Pattern) *Matcher {
+	m := &Matcher{nodes: []node{{}}}
+	var (
+		regexpUsers   []uuid.UUID
+		regexpSources = map[uuid.UUID][]string{}
+	)
+	for _, p := range patterns {
+		if p.IsRegexp {
+			if _, err := regexp.Compile(p.Keyword); err != nil {
+				continue
+			}
+			if _, ok := regexpSources[p.UserID]; !ok {
+				regexpUsers = append(regexpUsers, p.UserID)
+			}
+			regexpSources[p.UserID] = append(regexpSources[p.UserID], p.Keyword)
+			m.numRegexps++
+			continue
+		}
+
+		kw := strings.ToLower(p.Keyword)
+		if len(kw) == 0 {
+			continue
+		}
+		cur := int32(0)
+		for i := 0; i < len(kw); i++ {
+			if m.nodes[cur].next == nil {
+				m.nodes[cur].next = map[byte]int32{}
+			}
+			nxt, ok := m.nodes[cur].next[kw[i]]
+			if !ok {
+				nxt = int32(len(m.nodes))
+				m.nodes = append(m.nodes, node{})
+				m.nodes[cur].next[kw[i]] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].owners = append(m.nodes[cur].owners, p.UserID)
+	}
+	m.buildFailureLinks()
+	m.buildRegexps(regexpUsers, regexpSources)
+	return m
+}
+
+// buildRegexps 正規表現をユーザー毎、及び全体で1つの選択にまとめてコンパイルします
+func (m *Matcher) buildRegexps(users []uuid.UUID, sources map[uuid.UUID][]string) {
+	all := make([]string, 0, m.numRegexps)
+	for _, id := range users {
+		re, err := regexp.Compile(alternate(sources[id]))
+		if err != nil {
+			// まとめると大きすぎる場合は個別にコンパイルする
+			for _, src := range sources[id] {
+				m.regexps = append(m.regexps, regexpPattern{re: regexp.MustCompile(src), userID: id})
+			}
+		} else {
+			m.regexps = append(m.regexps, regexpPattern{re: re, userID: id})
+		}
+		all = append(all, sources[id]...)
+	}
+	if len(all) > 0 {
+		// まとめられない場合は事前照合を行わない
+		m.anyRegexp, _ = regexp.Compile(alternate(all))
+	}
+}
+
+// alternate 正規表現のリストを1つの選択にまとめます
+//
+// 各正規表現は非キャプチャグループで囲むため、フラグやアンカーは元の正規表現内でのみ有効です。
+func alternate(sources []string) string {
+	var sb strings.Builder
+	for i, src := range sources {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		sb.WriteString("(?:")
+		sb.WriteString(src)
+		sb.WriteByte(')')
+	}
+	return sb.String()
+}
+
+// buildFailureLinks 幅優先探索で失敗遷移を構築します
+func (m *Matcher) buildFailureLinks() {
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		m.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[c]; ok {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					m.nodes[child].fail = 0
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// Len 登録されているパターン数を返します
+func (m *Matcher) Len() int {
+	n := m.numRegexps
+	for _, v := range m.nodes {
+		n += len(v.owners)
+	}
+	return n
+}
+
+// Match textにいずれかのパターンが含まれるユーザーのIDを返します
+func (m *Matcher) Match(text string) set.UUID {
+	result := set.UUID{}
+	if len(m.nodes) > 1 {
+		lower := strings.ToLower(text)
+		visited := map[int32]struct{}{}
+		cur := int32(0)
+		for i := 0; i < len(lower); i++ {
+			c := lower[i]
+			for {
+				if nxt, ok := m.nodes[cur].next[c]; ok {
+					cur = nxt
+					break
+				}
+				if cur == 0 {
+					break
+				}
+				cur = m.nodes[cur].fail
+			}
+			// 出力リンクを辿る (同じノードは一度だけ)
+			for n := cur; n != 0; n = m.nodes[n].fail {
+				if _, ok := visited[n]; ok {
+					break
+				}
+				visited[n] = struct{}{}
+				result.Add(m.nodes[n].owners...)
+			}
+		}
+	}
+	if len(m.regexps) == 0 || (m.anyRegexp != nil && !m.anyRegexp.MatchString(text)) {
+		return result
+	}
+	for _, p := range m.regexps {
+		if result.Contains(p.userID) {
+			continue
+		}
+		if p.re.MatchString(text) {
+			result.Add(p.userID)
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package keyword
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	var (
+		user1 = uuid.Must(uuid.NewV4())
+		user2 = uuid.Must(uuid.NewV4())
+		user3 = uuid.Must(uuid.NewV4())
+		user4 = uuid.Must(uuid.NewV4())
+	)
+	m := NewMatcher([]Pattern{
+		{UserID: user1, Keyword: "traQ"},
+		{UserID: user1, Keyword: "部内"},
+		{UserID: user2, Keyword: "he"},
+		{UserID: user3, Keyword: "she"},
+		{UserID: user3, Keyword: "hers"},
+		{UserID: user4, Keyword: `^release v\d+\.\d+`, IsRegexp: true},
+		{UserID: user4, Keyword: `(invalid`, IsRegexp: true},
+		{UserID: user4, Keyword: ""},
+	})
+	assert.Equal(t, 6, m.Len())
+
+	tt := []struct {
+		text   string
+		expect []uuid.UUID
+	}{
+		{"", []uuid.UUID{}},
+		{"no match", []uuid.UUID{}},
+		{"TRAQ is great", []uuid.UUID{user1}},
+		{"部内の連絡", []uuid.UUID{user1}},
+		{"ushers", []uuid.UUID{user2, user3}},
+		{"she", []uuid.UUID{user2, user3}},
+		{"hhe", []uuid.UUID{user2}},
+		{"release v3.1 is out", []uuid.UUID{user4}},
+		{"new release v3.1", []uuid.UUID{}},
+		{"release v3.1 of traq", []uuid.UUID{user1, user4}},
+	}
+	for _, tc := range tt {
+		assert.ElementsMatch(t, tc.expect, m.Match(tc.text).Array(), tc.text)
+	}
+}
+
+func TestMatcher_MatchRegexps(t *testing.T) {
+	t.Parallel()
+
+	var (
+		user1 = uuid.Must(uuid.NewV4())
+		user2 = uuid.Must(uuid.NewV4())
+	)
+	m := NewMatcher([]Pattern{
+		{UserID: user1, Keyword: `(?i)^abc`, IsRegexp: true},
+		{UserID: user1, Keyword: `xyz$`, IsRegexp: true},
+		{UserID: user2, Keyword: `ABC`, IsRegexp: true},
+		{UserID: user2, Keyword: `a|b)(c`, IsRegexp: true},
+	})
+	assert.Equal(t, 3, m.Len())
+	assert.Len(t, m.regexps, 2)
+
+	tt := []struct {
+		text   string
+		expect []uuid.UUID
+	}{
+		{"none", []uuid.UUID{}},
+		// フラグは元の正規表現内でのみ有効
+		{"abc", []uuid.UUID{user1}},
+		{"ABC", []uuid.UUID{user1, user2}},
+		{"_ABC", []uuid.UUID{user2}},
+		// アンカーは元の正規表現内でのみ有効
+		{"xyz abc", []uuid.UUID{}},
+		{"abc xyz", []uuid.UUID{user1}},
+	}
+	for _, tc := range tt {
+		assert.ElementsMatch(t, tc.expect, m.Match(tc.text).Array(), tc.text)
+	}
+}
+
+func TestMatcher_Empty(t *testing.T) {
+	t.Parallel()
+
+	m := NewMatcher(nil)
+	assert.Equal(t, 0, m.Len())
+	assert.Len(t, m.Match("anything"), 0)
+}
@@ -12,6 +12,8 @@ const embURLRegexFragment = `/(files|messages)/[\da-f]{8}-[\da-f]{4}-[\da-f]{4}-
 var (
 	embJSONRegex = regexp.MustCompile(`(?m)!({(?:[ \t\n]*"(?:[^"]|\\.)*"[ \t\n]*:[ \t\n]*"(?:[^"]|\\.)*",)*(?:[ \t\n]*"(?:[^"]|\\.)*"[ \t\n]*:[ \t\n]*"(?:[^"]|\\.)*")})`)
 	embURLRegex  = regexp.MustCompile("http://localhost:3000" + embURLRegexFragment)
+
+	specialMentionRegex = regexp.MustCompile(`@(here|online|channel)`)
 )
 
 // SetOrigin URL型埋め込みのURLのオリジンを設定します
@@ -27,6 +29,11 @@ type ParseResult struct {
 	ChannelLink   []uuid.UUID
 	Attachments   []uuid.UUID
 	Citation      []uuid.UUID
+
+	// HereMention @here, @online (オンラインのチャンネル購読者・閲覧者へのメンション)が含まれているかどうか
+	HereMention bool
+	// ChannelMention @channel (全てのチャンネル購読者へのメンション)が含まれているかどうか
+	ChannelMention bool
 }
 
 // OneLine PlainTextを１行化したものを返します
@@ -85,6 +92,24 @@ func Parse(m string) *ParseResult {
 		}
 	})
 
+	// 特殊メンション (埋め込み部分は対象外)
+	plain := embJSONRegex.ReplaceAllLiteralString(m, " ")
+	for _, loc := range specialMentionRegex.FindAllStringSubmatchIndex(plain, -1) {
+		if (loc[0] > 0 && isMentionNameChar(plain[loc[0]-1])) || (loc[1] < len(plain) && isMentionNameChar(plain[loc[1]])) {
+			continue // @hereabouts や foo@here などは対象外
+		}
+		switch plain[loc[2]:loc[3]] {
+		case "here", "online":
+			r.HereMention = true
+		case "channel":
+			r.ChannelMention = true
+		}
+	}
+
 	r.PlainText = tmp
 	return &r
 }
+
+func isMentionNameChar(c byte) bool {
+	return c == '_' || c == '-' || c == '@' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -64,6 +64,30 @@ func TestParse(t *testing.T) {
 			Attachments: []uuid.UUID{u1},
 			Citation:    []uuid.UUID{u2},
 		},
+		`@here test message`: {
+			PlainText:   `@here test message`,
+			HereMention: true,
+		},
+		`test message @online`: {
+			PlainText:   `test message @online`,
+			HereMention: true,
+		},
+		`@here @channel test message`: {
+			PlainText:      `@here @channel test message`,
+			HereMention:    true,
+			ChannelMention: true,
+		},
+		`(@channel) test message`: {
+			PlainText:      `(@channel) test message`,
+			ChannelMention: true,
+		},
+		`@hereabouts foo@here @channel_ @@here`: {
+			PlainText: `@hereabouts foo@here @channel_ @@here`,
+		},
+		`!{"raw": "@here","type":"user","id":"ee764d5f-71d9-4a40-bc7b-547d8d097c91"}`: {
+			PlainText: `@here`,
+			Mentions:  []uuid.UUID{u1},
+		},
 	}
 
 	for m, exp := range cases {
@@ -0,0 +1,543 @@
+// Package saml SAML 2.0 Web Browser SSOプロファイルのサービスプロバイダ (SP) を提供します
+//
+// AuthnRequestはHTTP-Redirectバインディングで送信し、レスポンスはHTTP-POSTバインディングで受け取ります。
+// 暗号化されたアサーションはサポートしていません。
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/russellhaering/goxmldsig/etreeutils"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SAMLの名前空間・識別子
+const (
+	NSAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	NSProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	NSMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	NSDSig      = dsig.Namespace
+
+	BindingHTTPPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	BindingHTTPRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+
+	StatusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+	NameIDFormatPersistent  = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	NameIDFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+
+	SubjectConfirmationBearer = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	// AlgRSASHA256 HTTP-Redirectバインディングの署名アルゴリズム
+	AlgRSASHA256 = dsig.RSASHA256SignatureMethod
+)
+
+const (
+	defaultClockSkew = 3 * time.Minute
+	timeFormat       = "2006-01-02T15:04:05Z"
+)
+
+var (
+	// ErrInvalidResponse 不正なSAMLレスポンスです
+	ErrInvalidResponse = errors.New("invalid saml response")
+	// ErrInvalidMetadata 不正なメタデータです
+	ErrInvalidMetadata = errors.New("invalid saml metadata")
+)
+
+// insecureAlgorithms 受け付けないXML署名のアルゴリズム (SHA-1)
+var insecureAlgorithms = map[string]bool{
+	dsig.RSASHA1SignatureMethod:              true,
+	dsig.ECDSASHA1SignatureMethod:            true,
+	"http://www.w3.org/2000/09/xmldsig#sha1": true,
+}
+
+// IdentityProvider IdPの設定
+type IdentityProvider struct {
+	// EntityID IdPのエンティティID
+	EntityID string
+	// SSOURL HTTP-RedirectバインディングのSingleSignOnServiceのURL
+	SSOURL string
+	// Certificates 署名の検証に用いる証明書
+	Certificates []*x509.Certificate
+}
+
+// ParseIdPMetadata IdPのメタデータ (EntityDescriptor) をパースします
+func ParseIdPMetadata(b []byte) (*IdentityProvider, error) {
+	root, err := parseXML(b)
+	if err != nil {
+		return nil, err
+	}
+	if !is(root, NSMetadata, "EntityDescriptor") {
+		return nil, fmt.Errorf("%w: EntityDescriptor expected", ErrInvalidMetadata)
+	}
+	desc := findChild(root, NSMetadata, "IDPSSODescriptor")
+	if desc == nil {
+		return nil, fmt.Errorf("%w: IDPSSODescriptor not found", ErrInvalidMetadata)
+	}
+
+	idp := &IdentityProvider{EntityID: root.SelectAttrValue("entityID", "")}
+	for _, sso := range findChildren(desc, NSMetadata, "SingleSignOnService") {
+		if sso.SelectAttrValue("Binding", "") == BindingHTTPRedirect {
+			idp.SSOURL = sso.SelectAttrValue("Location", "")
+			break
+		}
+	}
+	for _, kd := range findChildren(desc, NSMetadata, "KeyDescriptor") {
+		if use := kd.SelectAttrValue("use", ""); use != "" && use != "signing" {
+			continue
+		}
+		keyInfo := findChild(kd, NSDSig, "KeyInfo")
+		if keyInfo == nil {
+			continue
+		}
+		for _, data := range findChildren(keyInfo, NSDSig, "X509Data") {
+			for _, c := range findChildren(data, NSDSig, "X509Certificate") {
+				der, err := decodeBase64(c.Text())
+				if err != nil {
+					return nil, fmt.Errorf("%w: malformed certificate", ErrInvalidMetadata)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+				}
+				idp.Certificates = append(idp.Certificates, cert)
+			}
+		}
+	}
+
+	if len(idp.EntityID) == 0 || len(idp.SSOURL) == 0 || len(idp.Certificates) == 0 {
+		return nil, fmt.Errorf("%w: entityID, HTTP-Redirect SingleSignOnService and signing certificate are required", ErrInvalidMetadata)
+	}
+	return idp, nil
+}
+
+// ServiceProvider SPの設定
+type ServiceProvider struct {
+	// EntityID SPのエンティティID
+	EntityID string
+	// ACSURL AssertionConsumerServiceのURL
+	ACSURL string
+	// Key AuthnRequestの署名に用いる秘密鍵
+	Key *rsa.PrivateKey
+	// Certificate Keyに対応する証明書
+	Certificate *x509.Certificate
+	// NameIDFormat 要求するNameIDの形式 (空の場合はpersistent)
+	NameIDFormat string
+	// ClockSkew 時刻の検証で許容するずれ (0の場合は3分)
+	ClockSkew time.Duration
+	// IdP IdPの設定
+	IdP IdentityProvider
+	// ReplayCache 使用済みのアサーションを記録するキャッシュ
+	ReplayCache ReplayCache
+}
+
+// ReplayCache 使用済みのアサーションIDを記録するキャッシュ
+//
+// 複数のプロセスで同じアサーションが受け付けられないように、プロセス間で共有される記憶域を用いてください。
+type ReplayCache interface {
+	// MarkUsed アサーションIDを有効期限expiresAtまで使用済みとして記録します
+	//
+	// 既に使用済みだった場合はfalseを返します。
+	MarkUsed(id string, expiresAt time.Time) (bool, error)
+}
+
+// Assertion 検証済みのアサーション
+type Assertion struct {
+	ID           string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	Attributes   map[string][]string
+	// ExpiresAt アサーションの有効期限 (許容する時刻のずれを含む)
+	ExpiresAt time.Time
+}
+
+// Attribute 指定した名前の属性の最初の値を返します。存在しない場合は空文字を返します
+func (a *Assertion) Attribute(name string) string {
+	if v := a.Attributes[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (sp *ServiceProvider) nameIDFormat() string {
+	if len(sp.NameIDFormat) == 0 {
+		return NameIDFormatPersistent
+	}
+	return sp.NameIDFormat
+}
+
+func (sp *ServiceProvider) clockSkew() time.Duration {
+	if sp.ClockSkew == 0 {
+		return defaultClockSkew
+	}
+	return sp.ClockSkew
+}
+
+// Metadata SPのメタデータ (EntityDescriptor) を返します
+func (sp *ServiceProvider) Metadata() []byte {
+	doc := etree.NewDocument()
+	doc.CreateProcInst("xml", `version="1.0" encoding="UTF-8"`)
+	root := doc.CreateElement("md:EntityDescriptor")
+	root.CreateAttr("xmlns:md", NSMetadata)
+	root.CreateAttr("entityID", sp.EntityID)
+
+	desc := root.CreateElement("md:SPSSODescriptor")
+	desc.CreateAttr("AuthnRequestsSigned", "true")
+	desc.CreateAttr("WantAssertionsSigned", "true")
+	desc.CreateAttr("protocolSupportEnumeration", NSProtocol)
+	if sp.Certificate != nil {
+		kd := desc.CreateElement("md:KeyDescriptor")
+		kd.CreateAttr("use", "signing")
+		keyInfo := kd.CreateElement("ds:KeyInfo")
+		keyInfo.CreateAttr("xmlns:ds", NSDSig)
+		keyInfo.CreateElement("ds:X509Data").
+			CreateElement("ds:X509Certificate").
+			SetText(base64.StdEncoding.EncodeToString(sp.Certificate.Raw))
+	}
+	desc.CreateElement("md:NameIDFormat").SetText(sp.nameIDFormat())
+	acs := desc.CreateElement("md:AssertionConsumerService")
+	acs.CreateAttr("Binding", BindingHTTPPost)
+	acs.CreateAttr("Location", sp.ACSURL)
+	acs.CreateAttr("index", "1")
+
+	b, _ := doc.WriteToBytes()
+	return b
+}
+
+// NewRequestID AuthnRequestのIDを生成します
+func NewRequestID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return "id-" + hex.EncodeToString(b)
+}
+
+// AuthnRequestURL 署名付きのAuthnRequestを含むIdPへのリダイレクトURLを返します
+func (sp *ServiceProvider) AuthnRequestURL(requestID, relayState string, now time.Time) (string, error) {
+	doc := etree.NewDocument()
+	req := doc.CreateElement("samlp:AuthnRequest")
+	req.CreateAttr("xmlns:samlp", NSProtocol)
+	req.CreateAttr("xmlns:saml", NSAssertion)
+	req.CreateAttr("ID", requestID)
+	req.CreateAttr("Version", "2.0")
+	req.CreateAttr("IssueInstant", now.UTC().Format(timeFormat))
+	req.CreateAttr("Destination", sp.IdP.SSOURL)
+	req.CreateAttr("AssertionConsumerServiceURL", sp.ACSURL)
+	req.CreateAttr("ProtocolBinding", BindingHTTPPost)
+	req.CreateElement("saml:Issuer").SetText(sp.EntityID)
+	policy := req.CreateElement("samlp:NameIDPolicy")
+	policy.CreateAttr("Format", sp.nameIDFormat())
+	policy.CreateAttr("AllowCreate", "true")
+
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	if _, err := doc.WriteTo(w); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// HTTP-Redirectバインディングの署名 (SAML Bindings 3.4.4.1)
+	query := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if len(relayState) > 0 {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	query += "&SigAlg=" + url.QueryEscape(AlgRSASHA256)
+	sum := sha256.Sum256([]byte(query))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, sp.Key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+
+	sep := "?"
+	if strings.Contains(sp.IdP.SSOURL, "?") {
+		sep = "&"
+	}
+	return sp.IdP.SSOURL + sep + query, nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidResponse, fmt.Sprintf(format, args...))
+}
+
+// ParseResponse HTTP-POSTバインディングで受け取ったSAMLResponseを検証し、アサーションを返します
+//
+// requestIDには送信したAuthnRequestのIDを指定します。IdP起点のログインは受け付けません。
+// 同じアサーションはReplayCacheに記録され、一度しか受け付けません。
+func (sp *ServiceProvider) ParseResponse(samlResponse, requestID string, now time.Time) (*Assertion, error) {
+	if sp.ReplayCache == nil {
+		return nil, errors.New("saml: ReplayCache is not configured")
+	}
+
+	b, err := decodeBase64(samlResponse)
+	if err != nil {
+		return nil, invalid("malformed base64")
+	}
+	res, err := parseXML(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if !is(res, NSProtocol, "Response") {
+		return nil, invalid("Response expected")
+	}
+	if err := checkUniqueIDs(res); err != nil {
+		return nil, err
+	}
+
+	// 署名の検証 (レスポンスかアサーションの少なくとも一方に署名が必要)
+	// 以降は署名の検証で得られた要素のみを参照する
+	signedRes, err := sp.verifySignature(res, now)
+	if err != nil {
+		return nil, err
+	}
+	responseSigned := signedRes != nil
+	if responseSigned {
+		res = signedRes
+	}
+
+	// レスポンスの検証
+	if v := res.SelectAttrValue("Version", ""); v != "2.0" {
+		return nil, invalid("unsupported version %q", v)
+	}
+	if d := res.SelectAttrValue("Destination", ""); len(d) > 0 && d != sp.ACSURL {
+		return nil, invalid("unexpected destination %q", d)
+	}
+	if irt := res.SelectAttrValue("InResponseTo", ""); irt != requestID {
+		return nil, invalid("unexpected InResponseTo %q", irt)
+	}
+	if issuer := findChild(res, NSAssertion, "Issuer"); issuer != nil && text(issuer) != sp.IdP.EntityID {
+		return nil, invalid("unexpected issuer %q", issuer.Text())
+	}
+	status := findChild(res, NSProtocol, "Status")
+	if status == nil {
+		return nil, invalid("missing Status")
+	}
+	if code := findChild(status, NSProtocol, "StatusCode"); code == nil || code.SelectAttrValue("Value", "") != StatusSuccess {
+		msg := ""
+		if m := findChild(status, NSProtocol, "StatusMessage"); m != nil {
+			msg = m.Text()
+		}
+		return nil, invalid("authentication failed: %s", msg)
+	}
+
+	if len(findChildren(res, NSAssertion, "EncryptedAssertion")) > 0 {
+		return nil, invalid("encrypted assertions are not supported")
+	}
+	assertions := findChildren(res, NSAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, invalid("exactly one assertion is required")
+	}
+	a := assertions[0]
+	signedAssertion, err := sp.verifySignature(a, now)
+	if err != nil {
+		return nil, err
+	}
+	if signedAssertion != nil {
+		a = signedAssertion
+	} else if !responseSigned {
+		return nil, invalid("neither the response nor the assertion is signed")
+	}
+
+	assertion, err := sp.validateAssertion(a, requestID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// リプレイ攻撃の防止
+	ok, err := sp.ReplayCache.MarkUsed(assertion.ID, assertion.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, invalid("the assertion has already been used")
+	}
+	return assertion, nil
+}
+
+// verifySignature 要素eの直下にあるエンベロープ署名をIdPの証明書で検証します
+//
+// 署名が存在しない場合はnil, nilを返します。
+// 署名が正しい場合は、署名の対象として検証された要素のコピーを返します。
+func (sp *ServiceProvider) verifySignature(e *etree.Element, now time.Time) (*etree.Element, error) {
+	sigs := findChildren(e, NSDSig, "Signature")
+	if len(sigs) == 0 {
+		return nil, nil
+	}
+	if len(sigs) > 1 {
+		return nil, invalid("multiple signatures")
+	}
+	if signedInfo := findChild(sigs[0], NSDSig, "SignedInfo"); signedInfo != nil {
+		for _, el := range signedInfo.FindElements(".//*[@Algorithm]") {
+			if alg := el.SelectAttrValue("Algorithm", ""); insecureAlgorithms[alg] {
+				return nil, invalid("unsupported algorithm %q", alg)
+			}
+		}
+	}
+
+	// 親要素で宣言された名前空間を引き継いで切り離す
+	ctx, err := etreeutils.NSBuildParentContext(e)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	detached, err := etreeutils.NSDetatch(ctx, e)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	vctx := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: sp.IdP.Certificates})
+	vctx.Clock = dsig.NewFakeClockAt(now)
+	signed, err := vctx.Validate(detached)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return signed, nil
+}
+
+// validateAssertion 署名検証済みのアサーションの内容を検証します
+func (sp *ServiceProvider) validateAssertion(a *etree.Element, requestID string, now time.Time) (*Assertion, error) {
+	skew := sp.clockSkew()
+	var expiry time.Time
+
+	if v := a.SelectAttrValue("Version", ""); v != "2.0" {
+		return nil, invalid("unsupported assertion version %q", v)
+	}
+	if issuer := findChild(a, NSAssertion, "Issuer"); issuer == nil || text(issuer) != sp.IdP.EntityID {
+		return nil, invalid("unexpected assertion issuer")
+	}
+
+	// Subject
+	subject := findChild(a, NSAssertion, "Subject")
+	if subject == nil {
+		return nil, invalid("missing Subject")
+	}
+	nameID := findChild(subject, NSAssertion, "NameID")
+	if nameID == nil || len(text(nameID)) == 0 {
+		return nil, invalid("missing NameID")
+	}
+	confirmed := false
+	for _, sc := range findChildren(subject, NSAssertion, "SubjectConfirmation") {
+		if sc.SelectAttrValue("Method", "") != SubjectConfirmationBearer {
+			continue
+		}
+		data := findChild(sc, NSAssertion, "SubjectConfirmationData")
+		if data == nil || data.SelectAttrValue("Recipient", "") != sp.ACSURL || data.SelectAttrValue("InResponseTo", "") != requestID {
+			continue
+		}
+		exp, err := time.Parse(time.RFC3339Nano, data.SelectAttrValue("NotOnOrAfter", ""))
+		if err != nil || !now.Before(exp.Add(skew)) {
+			continue
+		}
+		if data.SelectAttr("NotBefore") != nil {
+			continue // Bearerのデータに NotBefore は含めてはならない (SAML Profiles 4.1.4.2)
+		}
+		confirmed = true
+		expiry = exp
+		break
+	}
+	if !confirmed {
+		return nil, invalid("no valid bearer subject confirmation")
+	}
+
+	// Conditions
+	cond := findChild(a, NSAssertion, "Conditions")
+	if cond == nil {
+		return nil, invalid("missing Conditions")
+	}
+	if s := cond.SelectAttrValue("NotBefore", ""); len(s) > 0 {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil || now.Add(skew).Before(t) {
+			return nil, invalid("the assertion is not yet valid")
+		}
+	}
+	if s := cond.SelectAttrValue("NotOnOrAfter", ""); len(s) > 0 {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil || !now.Before(t.Add(skew)) {
+			return nil, invalid("the assertion has expired")
+		}
+		if t.After(expiry) {
+			expiry = t
+		}
+	}
+	restrictions := findChildren(cond, NSAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, invalid("missing AudienceRestriction")
+	}
+	for _, r := range restrictions {
+		ok := false
+		for _, aud := range findChildren(r, NSAssertion, "Audience") {
+			if text(aud) == sp.EntityID {
+				ok = true
+			}
+		}
+		if !ok {
+			return nil, invalid("the assertion is not intended for this service provider")
+		}
+	}
+
+	assertion := &Assertion{
+		ID:           a.SelectAttrValue("ID", ""),
+		NameID:       text(nameID),
+		NameIDFormat: nameID.SelectAttrValue("Format", ""),
+		Attributes:   map[string][]string{},
+		ExpiresAt:    expiry.Add(skew),
+	}
+	if len(assertion.ID) == 0 {
+		return nil, invalid("missing assertion ID")
+	}
+	if stmt := findChild(a, NSAssertion, "AuthnStatement"); stmt != nil {
+		assertion.SessionIndex = stmt.SelectAttrValue("SessionIndex", "")
+	} else {
+		return nil, invalid("missing AuthnStatement")
+	}
+	for _, stmt := range findChildren(a, NSAssertion, "AttributeStatement") {
+		for _, attr := range findChildren(stmt, NSAssertion, "Attribute") {
+			var values []string
+			for _, v := range findChildren(attr, NSAssertion, "AttributeValue") {
+				values = append(values, text(v))
+			}
+			for _, name := range []string{attr.SelectAttrValue("Name", ""), attr.SelectAttrValue("FriendlyName", "")} {
+				if len(name) > 0 {
+					assertion.Attributes[name] = append(assertion.Attributes[name], values...)
+				}
+			}
+		}
+	}
+	return assertion, nil
+}
+
+// checkUniqueIDs 文書中のID属性が一意であることを確認します
+//
+// 同じIDの要素を紛れ込ませる署名ラッピング攻撃を防ぎます。
+func checkUniqueIDs(root *etree.Element) error {
+	seen := map[string]bool{}
+	for _, e := range append([]*etree.Element{root}, root.FindElements(".//*[@ID]")...) {
+		id := e.SelectAttrValue("ID", "")
+		if len(id) == 0 {
+			continue
+		}
+		if seen[id] {
+			return invalid("duplicated ID %q", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
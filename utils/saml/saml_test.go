@@ -0,0 +1,238 @@
+package saml_test
+
+import (
+	"errors"
+	"github.com/beevik/etree"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/utils/saml"
+	"github.com/traPtitech/traQ/utils/saml/samltest"
+	"testing"
+	"time"
+)
+
+func TestParseIdPMetadata_InvalidXML(t *testing.T) {
+	t.Parallel()
+
+	for _, doc := range []string{
+		`<!DOCTYPE a [<!ENTITY x "y">]><a>&x;</a>`,
+		`<a><b></a>`,
+		`<a></a><b></b>`,
+		`<x:a></x:a>`,
+		``,
+	} {
+		_, err := saml.ParseIdPMetadata([]byte(doc))
+		assert.True(t, errors.Is(err, saml.ErrInvalidXML), doc)
+	}
+}
+
+const (
+	spEntityID  = "https://sp.example.com/metadata"
+	spACSURL    = "https://sp.example.com/acs"
+	idpEntityID = "https://idp.example.com"
+)
+
+func setupSP(t *testing.T) (*saml.ServiceProvider, *samltest.IdP) {
+	t.Helper()
+	idp, err := samltest.NewIdP(idpEntityID, "https://idp.example.com/sso?tenant=1")
+	require.NoError(t, err)
+	key, cert, err := samltest.GenerateCertificate(spEntityID)
+	require.NoError(t, err)
+	return &saml.ServiceProvider{
+		EntityID:    spEntityID,
+		ACSURL:      spACSURL,
+		Key:         key,
+		Certificate: cert,
+		IdP:         idp.Provider(),
+		ReplayCache: samltest.NewReplayCache(),
+	}, idp
+}
+
+func TestParseIdPMetadata(t *testing.T) {
+	t.Parallel()
+
+	_, idp := setupSP(t)
+	p, err := saml.ParseIdPMetadata(idp.Metadata())
+	if assert.NoError(t, err) {
+		assert.Equal(t, idp.EntityID, p.EntityID)
+		assert.Equal(t, idp.SSOURL, p.SSOURL)
+		if assert.Len(t, p.Certificates, 1) {
+			assert.True(t, p.Certificates[0].Equal(idp.Certificate))
+		}
+	}
+
+	_, err = saml.ParseIdPMetadata([]byte(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="x"></md:EntityDescriptor>`))
+	assert.True(t, errors.Is(err, saml.ErrInvalidMetadata))
+}
+
+func TestServiceProvider_Metadata(t *testing.T) {
+	t.Parallel()
+
+	sp, _ := setupSP(t)
+	doc := etree.NewDocument()
+	require.NoError(t, doc.ReadFromBytes(sp.Metadata()))
+	root := doc.Root()
+	assert.Equal(t, "EntityDescriptor", root.Tag)
+	assert.Equal(t, saml.NSMetadata, root.NamespaceURI())
+	assert.Equal(t, spEntityID, root.SelectAttrValue("entityID", ""))
+	desc := root.SelectElement("SPSSODescriptor")
+	require.NotNil(t, desc)
+	assert.Equal(t, "true", desc.SelectAttrValue("AuthnRequestsSigned", ""))
+	acs := desc.SelectElement("AssertionConsumerService")
+	require.NotNil(t, acs)
+	assert.Equal(t, spACSURL, acs.SelectAttrValue("Location", ""))
+	assert.Equal(t, saml.BindingHTTPPost, acs.SelectAttrValue("Binding", ""))
+	assert.NotNil(t, desc.SelectElement("KeyDescriptor"))
+}
+
+func TestServiceProvider_AuthnRequestURL(t *testing.T) {
+	t.Parallel()
+
+	sp, idp := setupSP(t)
+	id := saml.NewRequestID()
+	u, err := sp.AuthnRequestURL(id, "state&value", time.Now())
+	require.NoError(t, err)
+
+	req, err := idp.ParseAuthnRequest(u, sp.Certificate)
+	if assert.NoError(t, err) {
+		assert.Equal(t, id, req.ID)
+		assert.Equal(t, spEntityID, req.Issuer)
+		assert.Equal(t, spACSURL, req.ACSURL)
+		assert.Equal(t, "state&value", req.RelayState)
+	}
+
+	_, otherCert, err := samltest.GenerateCertificate("other")
+	require.NoError(t, err)
+	_, err = idp.ParseAuthnRequest(u, otherCert)
+	assert.Error(t, err)
+}
+
+func TestServiceProvider_ParseResponse(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	opts := func(requestID string) samltest.ResponseOptions {
+		return samltest.ResponseOptions{
+			Audience:      spEntityID,
+			Recipient:     spACSURL,
+			InResponseTo:  requestID,
+			NameID:        "user-1",
+			Attributes:    map[string][]string{"uid": {"user1"}, "groups": {"a", "b"}},
+			Now:           now,
+			SignAssertion: true,
+		}
+	}
+
+	for name, sign := range map[string][2]bool{
+		"signed assertion":          {false, true},
+		"signed response":           {true, false},
+		"signed response+assertion": {true, true},
+	} {
+		sign := sign
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			sp, idp := setupSP(t)
+			o := opts("req1")
+			o.SignResponse, o.SignAssertion = sign[0], sign[1]
+			res, err := idp.NewResponse(o)
+			require.NoError(t, err)
+
+			a, err := sp.ParseResponse(res, "req1", now)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "user-1", a.NameID)
+				assert.Equal(t, saml.NameIDFormatPersistent, a.NameIDFormat)
+				assert.Equal(t, "user1", a.Attribute("uid"))
+				assert.Equal(t, []string{"a", "b"}, a.Attributes["groups"])
+				assert.Empty(t, a.Attribute("unknown"))
+			}
+
+			// リプレイ
+			_, err = sp.ParseResponse(res, "req1", now)
+			assert.True(t, errors.Is(err, saml.ErrInvalidResponse))
+		})
+	}
+
+	failures := map[string]func(o *samltest.ResponseOptions) (requestID string, at time.Time){
+		"unsigned": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.SignAssertion = false
+			return "req1", now
+		},
+		"in response to another request": func(o *samltest.ResponseOptions) (string, time.Time) {
+			return "req2", now
+		},
+		"wrong audience": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Audience = "https://other.example.com"
+			return "req1", now
+		},
+		"wrong recipient": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Recipient = "https://other.example.com/acs"
+			return "req1", now
+		},
+		"expired": func(o *samltest.ResponseOptions) (string, time.Time) {
+			return "req1", now.Add(time.Hour)
+		},
+		"not yet valid": func(o *samltest.ResponseOptions) (string, time.Time) {
+			return "req1", now.Add(-time.Hour)
+		},
+		"sha1 signature": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Tamper = func(res *etree.Element) {
+				res.FindElement("./Assertion/Signature/SignedInfo/SignatureMethod").
+					CreateAttr("Algorithm", "http://www.w3.org/2000/09/xmldsig#rsa-sha1")
+			}
+			return "req1", now
+		},
+		"failed status": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Status = "urn:oasis:names:tc:SAML:2.0:status:Requester"
+			return "req1", now
+		},
+		"tampered assertion": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Tamper = func(res *etree.Element) {
+				res.FindElement("./Assertion/Subject/NameID").SetText("admin")
+			}
+			return "req1", now
+		},
+		"signature wrapping": func(o *samltest.ResponseOptions) (string, time.Time) {
+			o.Tamper = func(res *etree.Element) {
+				// 署名済みのアサーションと同じIDを持つ偽のアサーションを追加する
+				a := res.SelectElement("Assertion")
+				fake := etree.NewElement("saml:Assertion")
+				fake.CreateAttr("ID", a.SelectAttrValue("ID", ""))
+				res.InsertChildAt(0, fake)
+			}
+			return "req1", now
+		},
+	}
+	for name, f := range failures {
+		f := f
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			sp, idp := setupSP(t)
+			o := opts("req1")
+			requestID, at := f(&o)
+			res, err := idp.NewResponse(o)
+			require.NoError(t, err)
+
+			_, err = sp.ParseResponse(res, requestID, at)
+			assert.True(t, errors.Is(err, saml.ErrInvalidResponse), err)
+		})
+	}
+
+	t.Run("signed by another idp", func(t *testing.T) {
+		t.Parallel()
+		sp, _ := setupSP(t)
+		other, err := samltest.NewIdP(idpEntityID, "https://idp.example.com/sso")
+		require.NoError(t, err)
+		res, err := other.NewResponse(opts("req1"))
+		require.NoError(t, err)
+
+		_, err = sp.ParseResponse(res, "req1", now)
+		assert.True(t, errors.Is(err, saml.ErrInvalidResponse))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		t.Parallel()
+		sp, _ := setupSP(t)
+		_, err := sp.ParseResponse("!!!", "req1", now)
+		assert.True(t, errors.Is(err, saml.ErrInvalidResponse))
+	})
+}
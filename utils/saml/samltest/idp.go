@@ -0,0 +1,280 @@
+// Package samltest テスト用のSAML IdPを提供します
+package samltest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/traPtitech/traQ/utils/saml"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"time"
+)
+
+// GenerateCertificate RSA鍵と自己署名証明書を生成します
+func GenerateCertificate(commonName string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, cert, nil
+}
+
+// IdP テスト用のIdP
+type IdP struct {
+	EntityID    string
+	SSOURL      string
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// NewIdP 鍵を生成してIdPを作成します
+func NewIdP(entityID, ssoURL string) (*IdP, error) {
+	key, cert, err := GenerateCertificate(entityID)
+	if err != nil {
+		return nil, err
+	}
+	return &IdP{EntityID: entityID, SSOURL: ssoURL, Key: key, Certificate: cert}, nil
+}
+
+// Provider SPに設定するIdPの情報を返します
+func (idp *IdP) Provider() saml.IdentityProvider {
+	return saml.IdentityProvider{
+		EntityID:     idp.EntityID,
+		SSOURL:       idp.SSOURL,
+		Certificates: []*x509.Certificate{idp.Certificate},
+	}
+}
+
+// Metadata IdPのメタデータを返します
+func (idp *IdP) Metadata() []byte {
+	doc := etree.NewDocument()
+	root := doc.CreateElement("md:EntityDescriptor")
+	root.CreateAttr("xmlns:md", saml.NSMetadata)
+	root.CreateAttr("xmlns:ds", saml.NSDSig)
+	root.CreateAttr("entityID", idp.EntityID)
+
+	desc := root.CreateElement("md:IDPSSODescriptor")
+	desc.CreateAttr("protocolSupportEnumeration", saml.NSProtocol)
+	kd := desc.CreateElement("md:KeyDescriptor")
+	kd.CreateAttr("use", "signing")
+	kd.CreateElement("ds:KeyInfo").
+		CreateElement("ds:X509Data").
+		CreateElement("ds:X509Certificate").
+		SetText(base64.StdEncoding.EncodeToString(idp.Certificate.Raw))
+	sso := desc.CreateElement("md:SingleSignOnService")
+	sso.CreateAttr("Binding", saml.BindingHTTPRedirect)
+	sso.CreateAttr("Location", idp.SSOURL)
+
+	b, _ := doc.WriteToBytes()
+	return b
+}
+
+// AuthnRequest IdPが受け取ったAuthnRequest
+type AuthnRequest struct {
+	ID         string
+	Issuer     string
+	ACSURL     string
+	RelayState string
+}
+
+// ParseAuthnRequest HTTP-RedirectバインディングのURLからAuthnRequestを取り出し、spCertで署名を検証します
+func (idp *IdP) ParseAuthnRequest(u string, spCert *x509.Certificate) (*AuthnRequest, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	q := parsed.Query()
+
+	signed := "SAMLRequest=" + url.QueryEscape(q.Get("SAMLRequest"))
+	if _, ok := q["RelayState"]; ok {
+		signed += "&RelayState=" + url.QueryEscape(q.Get("RelayState"))
+	}
+	signed += "&SigAlg=" + url.QueryEscape(q.Get("SigAlg"))
+	if q.Get("SigAlg") != saml.AlgRSASHA256 {
+		return nil, errors.New("unexpected SigAlg")
+	}
+	sig, err := base64.StdEncoding.DecodeString(q.Get("Signature"))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(spCert.PublicKey.(*rsa.PublicKey), crypto.SHA256, sum[:], sig); err != nil {
+		return nil, err
+	}
+
+	deflated, err := base64.StdEncoding.DecodeString(q.Get("SAMLRequest"))
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		return nil, err
+	}
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(b); err != nil {
+		return nil, err
+	}
+	req := doc.Root()
+	if req == nil || req.Tag != "AuthnRequest" || req.NamespaceURI() != saml.NSProtocol {
+		return nil, errors.New("AuthnRequest expected")
+	}
+	ar := &AuthnRequest{
+		ID:         req.SelectAttrValue("ID", ""),
+		ACSURL:     req.SelectAttrValue("AssertionConsumerServiceURL", ""),
+		RelayState: q.Get("RelayState"),
+	}
+	for _, c := range req.ChildElements() {
+		if c.Tag == "Issuer" && c.NamespaceURI() == saml.NSAssertion {
+			ar.Issuer = c.Text()
+		}
+	}
+	return ar, nil
+}
+
+// ResponseOptions 生成するレスポンスの内容
+type ResponseOptions struct {
+	// Audience SPのエンティティID
+	Audience string
+	// Recipient SPのACSのURL
+	Recipient string
+	// InResponseTo AuthnRequestのID
+	InResponseTo string
+	// NameID ユーザーの識別子
+	NameID string
+	// Attributes ユーザーの属性
+	Attributes map[string][]string
+	// Now 発行日時 (ゼロ値の場合は現在時刻)
+	Now time.Time
+	// Status ステータスコード (空の場合は成功)
+	Status string
+	// SignResponse レスポンスに署名するかどうか
+	SignResponse bool
+	// SignAssertion アサーションに署名するかどうか
+	SignAssertion bool
+	// Tamper 署名後のレスポンスを書き換える関数
+	Tamper func(res *etree.Element)
+}
+
+// NewResponse HTTP-POSTバインディングで送信するSAMLResponseを生成します
+func (idp *IdP) NewResponse(opts ResponseOptions) (string, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	instant := now.UTC().Format(time.RFC3339)
+	expiry := now.Add(5 * time.Minute).UTC().Format(time.RFC3339)
+	status := opts.Status
+	if len(status) == 0 {
+		status = saml.StatusSuccess
+	}
+
+	doc := etree.NewDocument()
+	res := doc.CreateElement("samlp:Response")
+	res.CreateAttr("xmlns:samlp", saml.NSProtocol)
+	res.CreateAttr("xmlns:saml", saml.NSAssertion)
+	res.CreateAttr("ID", saml.NewRequestID())
+	res.CreateAttr("Version", "2.0")
+	res.CreateAttr("IssueInstant", instant)
+	res.CreateAttr("Destination", opts.Recipient)
+	res.CreateAttr("InResponseTo", opts.InResponseTo)
+	res.CreateElement("saml:Issuer").SetText(idp.EntityID)
+	res.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", status)
+
+	if status == saml.StatusSuccess {
+		assertion := res.CreateElement("saml:Assertion")
+		assertion.CreateAttr("xmlns:saml", saml.NSAssertion)
+		assertion.CreateAttr("ID", saml.NewRequestID())
+		assertion.CreateAttr("Version", "2.0")
+		assertion.CreateAttr("IssueInstant", instant)
+		assertion.CreateElement("saml:Issuer").SetText(idp.EntityID)
+
+		subject := assertion.CreateElement("saml:Subject")
+		nameID := subject.CreateElement("saml:NameID")
+		nameID.CreateAttr("Format", saml.NameIDFormatPersistent)
+		nameID.SetText(opts.NameID)
+		sc := subject.CreateElement("saml:SubjectConfirmation")
+		sc.CreateAttr("Method", saml.SubjectConfirmationBearer)
+		scd := sc.CreateElement("saml:SubjectConfirmationData")
+		scd.CreateAttr("InResponseTo", opts.InResponseTo)
+		scd.CreateAttr("NotOnOrAfter", expiry)
+		scd.CreateAttr("Recipient", opts.Recipient)
+
+		cond := assertion.CreateElement("saml:Conditions")
+		cond.CreateAttr("NotBefore", now.Add(-time.Minute).UTC().Format(time.RFC3339))
+		cond.CreateAttr("NotOnOrAfter", expiry)
+		cond.CreateElement("saml:AudienceRestriction").CreateElement("saml:Audience").SetText(opts.Audience)
+
+		authn := assertion.CreateElement("saml:AuthnStatement")
+		authn.CreateAttr("AuthnInstant", instant)
+		authn.CreateAttr("SessionIndex", saml.NewRequestID())
+
+		attrs := assertion.CreateElement("saml:AttributeStatement")
+		for name, values := range opts.Attributes {
+			attr := attrs.CreateElement("saml:Attribute")
+			attr.CreateAttr("Name", name)
+			for _, v := range values {
+				attr.CreateElement("saml:AttributeValue").SetText(v)
+			}
+		}
+
+		if opts.SignAssertion {
+			if err := idp.sign(assertion); err != nil {
+				return "", err
+			}
+		}
+	}
+	if opts.SignResponse {
+		if err := idp.sign(res); err != nil {
+			return "", err
+		}
+	}
+	if opts.Tamper != nil {
+		opts.Tamper(res)
+	}
+	b, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// sign 要素のIssuerの直後にエンベロープ署名を挿入します
+func (idp *IdP) sign(e *etree.Element) error {
+	ctx, err := dsig.NewSigningContext(idp.Key, [][]byte{idp.Certificate.Raw})
+	if err != nil {
+		return err
+	}
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	sig, err := ctx.ConstructSignature(e, true)
+	if err != nil {
+		return err
+	}
+	e.InsertChildAt(1, sig)
+	return nil
+}
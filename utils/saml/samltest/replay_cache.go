@@ -0,0 +1,28 @@
+package samltest
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayCache テスト用のメモリ上のsaml.ReplayCache
+type ReplayCache struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewReplayCache ReplayCacheを作成します
+func NewReplayCache() *ReplayCache {
+	return &ReplayCache{used: map[string]time.Time{}}
+}
+
+// MarkUsed implements saml.ReplayCache interface.
+func (c *ReplayCache) MarkUsed(id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.used[id]; ok {
+		return false, nil
+	}
+	c.used[id] = expiresAt
+	return true, nil
+}
@@ -0,0 +1,87 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/beevik/etree"
+	"io"
+	"strings"
+)
+
+// ErrInvalidXML 不正なXML文書です
+var ErrInvalidXML = errors.New("invalid xml")
+
+// parseXML XML文書をパースし、ルート要素を返します
+//
+// 実体参照による攻撃を防ぐため、DTDを含む文書は受け付けません。
+func parseXML(b []byte) (*etree.Element, error) {
+	// etreeはタグの対応を検査しないため、先にencoding/xmlで整形式であることを確認する
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXML, err)
+		}
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidXML, err)
+	}
+	roots := 0
+	for _, t := range doc.Child {
+		switch t.(type) {
+		case *etree.Directive:
+			return nil, fmt.Errorf("%w: DTD is not allowed", ErrInvalidXML)
+		case *etree.Element:
+			roots++
+		}
+	}
+	if roots > 1 {
+		return nil, fmt.Errorf("%w: multiple root elements", ErrInvalidXML)
+	}
+	root := doc.Root()
+	if root == nil {
+		return nil, fmt.Errorf("%w: root element not found", ErrInvalidXML)
+	}
+	for _, e := range append([]*etree.Element{root}, root.FindElements(".//*")...) {
+		if len(e.Space) > 0 && len(e.NamespaceURI()) == 0 {
+			return nil, fmt.Errorf("%w: undeclared namespace prefix %q", ErrInvalidXML, e.Space)
+		}
+	}
+	return root, nil
+}
+
+// is 要素が指定した名前空間・ローカル名を持つかどうか
+func is(e *etree.Element, space, local string) bool {
+	return e.Tag == local && e.NamespaceURI() == space
+}
+
+// findChildren 指定した名前空間・ローカル名を持つ子要素を全て返します
+func findChildren(e *etree.Element, space, local string) []*etree.Element {
+	var result []*etree.Element
+	for _, c := range e.ChildElements() {
+		if is(c, space, local) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// findChild 指定した名前空間・ローカル名を持つ最初の子要素を返します。存在しない場合はnilを返します
+func findChild(e *etree.Element, space, local string) *etree.Element {
+	for _, c := range e.ChildElements() {
+		if is(c, space, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// text 要素のテキストの前後の空白を除いたものを返します
+func text(e *etree.Element) string {
+	return strings.TrimSpace(e.Text())
+}
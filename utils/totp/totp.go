@@ -0,0 +1,112 @@
+package totp
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Issuer TOTPの発行者名
+	Issuer = "traQ"
+	// RecoveryCodeCount 一度に発行するリカバリーコードの数
+	RecoveryCodeCount = 10
+
+	period             = 30
+	skew               = 1
+	recoveryCodeLength = 10
+)
+
+var validateOpts = totp.ValidateOpts{
+	Period:    period,
+	Skew:      skew,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// Generate 新しいTOTPシークレットを生成します
+//
+// シークレット(Base32)と、認証アプリに登録するためのotpauth URLを返します。
+func Generate(accountName string) (secret string, keyURL string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      Issuer,
+		AccountName: accountName,
+		Period:      period,
+		Digits:      validateOpts.Digits,
+		Algorithm:   validateOpts.Algorithm,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return key.Secret(), URL(accountName, key.Secret()), nil
+}
+
+// URL 認証アプリに登録するためのotpauth URLを返します
+func URL(accountName, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", Issuer)
+	v.Set("period", strconv.Itoa(period))
+	v.Set("digits", validateOpts.Digits.String())
+	v.Set("algorithm", validateOpts.Algorithm.String())
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + Issuer + ":" + accountName,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+// Validate TOTPコードを検証します
+//
+// 検証に成功した場合、コードのタイムステップとtrueを返します。
+// lastStep以下のタイムステップのコードは再利用とみなし、検証に失敗します。
+func Validate(code, secret string, lastStep int64, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != validateOpts.Digits.Length() {
+		return 0, false
+	}
+	for i := -skew; i <= skew; i++ {
+		t := now.Add(time.Duration(i*period) * time.Second)
+		step := t.Unix() / period
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, t, validateOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes リカバリーコードをRecoveryCodeCount個生成します
+func GenerateRecoveryCodes() []string {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		c := strings.ToLower(random.SecureAlphaNumeric(recoveryCodeLength))
+		codes[i] = c[:recoveryCodeLength/2] + "-" + c[recoveryCodeLength/2:]
+	}
+	return codes
+}
+
+// HashRecoveryCode リカバリーコードの保存用ハッシュ値を計算します
+//
+// 大文字・小文字、ハイフン、空白の違いは無視されます。
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
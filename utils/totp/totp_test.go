@@ -0,0 +1,94 @@
+package totp
+
+import (
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	secret, url, err := Generate("takashi_trap")
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, secret)
+		assert.Contains(t, url, "otpauth://totp/traQ:takashi_trap")
+		assert.Contains(t, url, "secret="+secret)
+	}
+}
+
+func TestURL(t *testing.T) {
+	t.Parallel()
+
+	u := URL("takashi_trap", "JBSWY3DPEHPK3PXP")
+	key, err := otp.NewKeyFromURL(u)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "totp", key.Type())
+		assert.Equal(t, "traQ", key.Issuer())
+		assert.Equal(t, "takashi_trap", key.AccountName())
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", key.Secret())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	secret, _, err := Generate("test")
+	require.NoError(t, err)
+	now := time.Unix(1600000000, 0)
+	code, err := totp.GenerateCodeCustom(secret, now, validateOpts)
+	require.NoError(t, err)
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		step, ok := Validate(code, secret, 0, now)
+		assert.True(t, ok)
+		assert.EqualValues(t, now.Unix()/period, step)
+	})
+
+	t.Run("skew", func(t *testing.T) {
+		t.Parallel()
+		_, ok := Validate(code, secret, 0, now.Add(period*time.Second))
+		assert.True(t, ok)
+		_, ok = Validate(code, secret, 0, now.Add(3*period*time.Second))
+		assert.False(t, ok)
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		t.Parallel()
+		_, ok := Validate(code, secret, now.Unix()/period, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		t.Parallel()
+		_, ok := Validate("abcdef", secret, 0, now)
+		assert.False(t, ok)
+		_, ok = Validate("", secret, 0, now)
+		assert.False(t, ok)
+	})
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	codes := GenerateRecoveryCodes()
+	assert.Len(t, codes, RecoveryCodeCount)
+	for _, c := range codes {
+		assert.Len(t, c, recoveryCodeLength+1)
+		assert.Equal(t, byte('-'), c[recoveryCodeLength/2])
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	h := HashRecoveryCode("abcde-12345")
+	assert.Len(t, h, 64)
+	assert.Equal(t, h, HashRecoveryCode("ABCDE12345"))
+	assert.Equal(t, h, HashRecoveryCode(" abcde 12345 "))
+	assert.NotEqual(t, h, HashRecoveryCode("abcde-12346"))
+}
@@ -92,6 +92,37 @@ var StampPaletteStampsRuleNotNil = append([]vd.Rule{
 	vd.NotNil,
 }, StampPaletteStampsRule...)
 
+// KeywordAlertRule キーワード通知のキーワードバリデーションルール
+var KeywordAlertRule = []vd.Rule{
+	vd.RuneLength(1, 100),
+}
+
+// KeywordAlertRuleRequired キーワード通知のキーワードバリデーションルール with Required
+var KeywordAlertRuleRequired = append([]vd.Rule{
+	vd.Required,
+}, KeywordAlertRule...)
+
+// PersonalAccessTokenNameRule パーソナルアクセストークン名バリデーションルール
+var PersonalAccessTokenNameRule = []vd.Rule{
+	vd.RuneLength(1, 32),
+}
+
+// PersonalAccessTokenNameRuleRequired パーソナルアクセストークン名バリデーションルール with Required
+var PersonalAccessTokenNameRuleRequired = append([]vd.Rule{
+	vd.Required,
+}, PersonalAccessTokenNameRule...)
+
+// RoleNameRule ロール名バリデーションルール
+var RoleNameRule = []vd.Rule{
+	vd.Match(regexp.MustCompile(`^[a-z0-9_-]+$`)).Error("must contain [a-z0-9_-] only"),
+	vd.RuneLength(1, 30),
+}
+
+// RoleNameRuleRequired ロール名バリデーションルール with Required
+var RoleNameRuleRequired = append([]vd.Rule{
+	vd.Required,
+}, RoleNameRule...)
+
 // TwitterIDRule TwitterIDバリデーションルール
 var TwitterIDRule = []vd.Rule{
 	vd.Match(regexp.MustCompile(`^[a-zA-Z0-9_]+$`)).Error("must contain [a-zA-Z0-9_] only"),
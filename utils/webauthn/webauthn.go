@@ -0,0 +1,288 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"io"
+	"math/big"
+)
+
+const (
+	// ChallengeLength チャレンジのバイト長
+	ChallengeLength = 32
+
+	// AlgES256 ECDSA P-256 with SHA-256
+	AlgES256 = int(webauthncose.AlgES256)
+	// AlgEdDSA EdDSA (Ed25519)
+	AlgEdDSA = int(webauthncose.AlgEdDSA)
+	// AlgRS256 RSASSA-PKCS1-v1_5 with SHA-256
+	AlgRS256 = int(webauthncose.AlgRS256)
+)
+
+// SupportedAlgorithms サポートしている公開鍵アルゴリズム(COSE Algorithm Identifier)
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+var (
+	// ErrInvalidClientData clientDataJSONが不正です
+	ErrInvalidClientData = errors.New("invalid client data")
+	// ErrInvalidAuthenticatorData authenticatorDataが不正です
+	ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+	// ErrInvalidAttestation attestationObjectが不正です
+	ErrInvalidAttestation = errors.New("invalid attestation object")
+	// ErrUnsupportedKey サポートしていない公開鍵です
+	ErrUnsupportedKey = errors.New("unsupported public key")
+	// ErrVerificationFailed 検証に失敗しました
+	ErrVerificationFailed = errors.New("verification failed")
+	// ErrSignCount signCountが巻き戻っています(認証器が複製された可能性があります)
+	ErrSignCount = errors.New("sign count did not increase")
+)
+
+// RelyingParty WebAuthnのRelying Party設定
+type RelyingParty struct {
+	// ID RP ID (通常はオリジンのホスト名)
+	ID string
+	// Name RPの表示名
+	Name string
+	// Origin 許可するオリジン
+	Origin string
+}
+
+// Credential 登録された公開鍵認証情報
+type Credential struct {
+	// ID Credential ID
+	ID []byte
+	// PublicKey COSE形式の公開鍵
+	PublicKey []byte
+	// SignCount 署名カウンタ
+	SignCount uint32
+	// AAGUID 認証器のAAGUID
+	AAGUID []byte
+}
+
+// NewChallenge 新しいチャレンジを生成します
+func NewChallenge() []byte {
+	b := make([]byte, ChallengeLength)
+	_, _ = io.ReadFull(rand.Reader, b)
+	return b
+}
+
+// EncodeBase64URL バイト列をパディングなしbase64urlでエンコードします
+func EncodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64URL パディングなし(あり)base64url文字列をデコードします
+func DecodeBase64URL(s string) ([]byte, error) {
+	for len(s) > 0 && s[len(s)-1] == '=' {
+		s = s[:len(s)-1]
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// VerifyRegistration 登録(navigator.credentials.create)のレスポンスを検証します
+//
+// attestation statementはgo-webauthnが対応している形式で検証します。トラストアンカーの検証は行いません。
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte, requireUserVerification bool) (*Credential, error) {
+	res := protocol.AuthenticatorAttestationResponse{
+		AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientDataJSON},
+		AttestationObject:     attestationObject,
+	}
+	parsed, err := res.Parse()
+	if err != nil {
+		if errors.Is(err, protocol.ErrParsingData) && json.Valid(clientDataJSON) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+
+	if err := rp.verifyClientData(&parsed.CollectedClientData, protocol.CreateCeremony, challenge); err != nil {
+		return nil, err
+	}
+
+	att := &parsed.AttestationObject
+	if err := rp.verifyAuthenticatorData(&att.AuthData, requireUserVerification); err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := att.Verify(rp.ID, clientDataHash[:], requireUserVerification); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+	}
+	if _, err := parsePublicKey(att.AuthData.AttData.CredentialPublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        att.AuthData.AttData.CredentialID,
+		PublicKey: att.AuthData.AttData.CredentialPublicKey,
+		SignCount: att.AuthData.Counter,
+		AAGUID:    att.AuthData.AttData.AAGUID,
+	}, nil
+}
+
+// VerifyAssertion 認証(navigator.credentials.get)のレスポンスを検証します
+//
+// 成功した場合、新しい署名カウンタを返します。
+func (rp *RelyingParty) VerifyAssertion(cred *Credential, challenge, clientDataJSON, authenticatorData, signature []byte, requireUserVerification bool) (uint32, error) {
+	var cd protocol.CollectedClientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return 0, ErrInvalidClientData
+	}
+	if err := rp.verifyClientData(&cd, protocol.AssertCeremony, challenge); err != nil {
+		return 0, err
+	}
+
+	var authData protocol.AuthenticatorData
+	if err := authData.Unmarshal(authenticatorData); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidAuthenticatorData, err)
+	}
+	if err := rp.verifyAuthenticatorData(&authData, requireUserVerification); err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authenticatorData)+len(clientDataHash))
+	signed = append(signed, authenticatorData...)
+	signed = append(signed, clientDataHash[:]...)
+	if ok, err := webauthncose.VerifySignature(key, signed, signature); err != nil || !ok {
+		return 0, ErrVerificationFailed
+	}
+
+	// 認証器がカウンタをサポートしている場合のみ検証
+	if (authData.Counter != 0 || cred.SignCount != 0) && authData.Counter <= cred.SignCount {
+		return 0, ErrSignCount
+	}
+	return authData.Counter, nil
+}
+
+func (rp *RelyingParty) verifyClientData(cd *protocol.CollectedClientData, ceremony protocol.CeremonyType, challenge []byte) error {
+	if len(challenge) == 0 {
+		return fmt.Errorf("%w: empty challenge", ErrInvalidClientData)
+	}
+	if err := cd.Verify(EncodeBase64URL(challenge), ceremony, []string{rp.Origin}); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *protocol.AuthenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if err := authData.Verify(rpIDHash[:], nil, requireUserVerification); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAuthenticatorData, err)
+	}
+	return nil
+}
+
+// Algorithm COSE形式の公開鍵のアルゴリズムを返します
+func Algorithm(publicKey []byte) (int, error) {
+	key, err := parsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	switch k := key.(type) {
+	case webauthncose.EC2PublicKeyData:
+		return int(k.Algorithm), nil
+	case webauthncose.OKPPublicKeyData:
+		return int(k.Algorithm), nil
+	case webauthncose.RSAPublicKeyData:
+		return int(k.Algorithm), nil
+	}
+	return 0, ErrUnsupportedKey
+}
+
+// parsePublicKey COSE形式の公開鍵をパースし、サポートしている鍵かどうかを検証します
+func parsePublicKey(b []byte) (interface{}, error) {
+	var raw map[int]interface{}
+	if err := webauthncbor.Unmarshal(b, &raw); err != nil {
+		return nil, ErrUnsupportedKey
+	}
+	key, err := webauthncose.ParsePublicKey(b)
+	if err != nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	switch k := key.(type) {
+	case webauthncose.EC2PublicKeyData:
+		if int(k.Algorithm) != AlgES256 || k.Curve != int64(webauthncose.P256) || len(k.XCoord) != 32 || len(k.YCoord) != 32 {
+			return nil, ErrUnsupportedKey
+		}
+		if !elliptic.P256().IsOnCurve(new(big.Int).SetBytes(k.XCoord), new(big.Int).SetBytes(k.YCoord)) {
+			return nil, ErrUnsupportedKey
+		}
+	case webauthncose.OKPPublicKeyData:
+		// OKPPublicKeyData.Curveはデコードされないため、元のマップから確認する
+		if crv, ok := raw[coseKeyCrv].(uint64); int(k.Algorithm) != AlgEdDSA || !ok || crv != uint64(webauthncose.Ed25519) || len(k.XCoord) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKey
+		}
+	case webauthncose.RSAPublicKeyData:
+		if int(k.Algorithm) != AlgRS256 || len(k.Exponent) == 0 || len(k.Exponent) > 4 || new(big.Int).SetBytes(k.Modulus).BitLen() < 2048 {
+			return nil, ErrUnsupportedKey
+		}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return key, nil
+}
+
+// coseKeyCrv COSE_Keyのcrvパラメータのラベル
+const coseKeyCrv = -1
+
+// okpPublicKey Ed25519公開鍵のCOSE_Key
+//
+// webauthncose.OKPPublicKeyDataはcrvのラベルを持たないため、エンコード用に定義しています。
+type okpPublicKey struct {
+	webauthncose.PublicKeyData
+	Curve  int64  `cbor:"-1,keyasint"`
+	XCoord []byte `cbor:"-2,keyasint"`
+}
+
+// MarshalES256PublicKey ECDSA P-256公開鍵をCOSE形式にエンコードします
+func MarshalES256PublicKey(pub *ecdsa.PublicKey) ([]byte, error) {
+	return webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: pub.X.FillBytes(make([]byte, 32)),
+		YCoord: pub.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+// MarshalEd25519PublicKey Ed25519公開鍵をCOSE形式にエンコードします
+func MarshalEd25519PublicKey(pub ed25519.PublicKey) ([]byte, error) {
+	return webauthncbor.Marshal(okpPublicKey{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.OctetKey),
+			Algorithm: int64(webauthncose.AlgEdDSA),
+		},
+		Curve:  int64(webauthncose.Ed25519),
+		XCoord: []byte(pub),
+	})
+}
+
+// MarshalRS256PublicKey RSA公開鍵をCOSE形式にエンコードします
+func MarshalRS256PublicKey(pub *rsa.PublicKey) ([]byte, error) {
+	return webauthncbor.Marshal(webauthncose.RSAPublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.RSAKey),
+			Algorithm: int64(webauthncose.AlgRS256),
+		},
+		Modulus:  pub.N.Bytes(),
+		Exponent: big.NewInt(int64(pub.E)).Bytes(),
+	})
+}
@@ -0,0 +1,220 @@
+package webauthn_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/utils/webauthn"
+	"github.com/traPtitech/traQ/utils/webauthn/webauthntest"
+	"testing"
+)
+
+var testRP = &webauthn.RelyingParty{
+	ID:     "example.com",
+	Name:   "traQ",
+	Origin: "https://example.com",
+}
+
+func register(t *testing.T, a *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	challenge := webauthn.NewChallenge()
+	res, err := a.Register(challenge, []byte("user"))
+	require.NoError(t, err)
+	cred, err := testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+	require.NoError(t, err)
+	return cred
+}
+
+func TestRelyingParty_VerifyRegistration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+
+		cred, err := testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+		if assert.NoError(t, err) {
+			assert.Equal(t, res.CredentialID, cred.ID)
+			assert.EqualValues(t, 0, cred.SignCount)
+			alg, err := webauthn.Algorithm(cred.PublicKey)
+			assert.NoError(t, err)
+			assert.Equal(t, webauthn.AlgES256, alg)
+		}
+	})
+
+	t.Run("challenge mismatch", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		res, err := a.Register(webauthn.NewChallenge(), []byte("user"))
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyRegistration(webauthn.NewChallenge(), res.ClientDataJSON, res.AttestationObject, true)
+		assert.True(t, errors.Is(err, webauthn.ErrInvalidClientData))
+	})
+
+	t.Run("origin mismatch", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, "https://evil.example.com")
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+		assert.True(t, errors.Is(err, webauthn.ErrInvalidClientData))
+	})
+
+	t.Run("rp id mismatch", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New("evil.example.com", testRP.Origin)
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+		assert.True(t, errors.Is(err, webauthn.ErrInvalidAuthenticatorData))
+	})
+
+	t.Run("user not verified", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		a.UserVerification = false
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+		assert.True(t, errors.Is(err, webauthn.ErrInvalidAuthenticatorData))
+		_, err = testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid attestation object", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyRegistration(challenge, res.ClientDataJSON, []byte{0xff, 0x00}, true)
+		assert.Error(t, err)
+	})
+}
+
+func TestRelyingParty_VerifyAssertion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		cred := register(t, a)
+
+		for i := 1; i <= 2; i++ {
+			challenge := webauthn.NewChallenge()
+			res, err := a.Assert(cred.ID, challenge)
+			require.NoError(t, err)
+			count, err := testRP.VerifyAssertion(cred, challenge, res.ClientDataJSON, res.AuthenticatorData, res.Signature, true)
+			if assert.NoError(t, err) {
+				assert.EqualValues(t, i, count)
+			}
+			cred.SignCount = count
+		}
+	})
+
+	t.Run("counter not supported", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		a.CounterDisabled = true
+		cred := register(t, a)
+
+		challenge := webauthn.NewChallenge()
+		res, err := a.Assert(cred.ID, challenge)
+		require.NoError(t, err)
+		count, err := testRP.VerifyAssertion(cred, challenge, res.ClientDataJSON, res.AuthenticatorData, res.Signature, true)
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, 0, count)
+		}
+	})
+
+	t.Run("sign count rollback", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		cred := register(t, a)
+		cred.SignCount = 10
+
+		challenge := webauthn.NewChallenge()
+		res, err := a.Assert(cred.ID, challenge)
+		require.NoError(t, err)
+		_, err = testRP.VerifyAssertion(cred, challenge, res.ClientDataJSON, res.AuthenticatorData, res.Signature, true)
+		assert.True(t, errors.Is(err, webauthn.ErrSignCount))
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		challenge := webauthn.NewChallenge()
+		res, err := a.Register(challenge, []byte("user"))
+		require.NoError(t, err)
+		cred, err := testRP.VerifyRegistration(challenge, res.ClientDataJSON, res.AttestationObject, true)
+		require.NoError(t, err)
+
+		_, err = testRP.VerifyAssertion(cred, challenge, res.ClientDataJSON, res.AttestationObject, nil, true)
+		assert.True(t, errors.Is(err, webauthn.ErrInvalidClientData))
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		cred := register(t, a)
+
+		challenge := webauthn.NewChallenge()
+		res, err := a.Assert(cred.ID, challenge)
+		require.NoError(t, err)
+		res.Signature[len(res.Signature)-1] ^= 0xff
+		_, err = testRP.VerifyAssertion(cred, challenge, res.ClientDataJSON, res.AuthenticatorData, res.Signature, true)
+		assert.True(t, errors.Is(err, webauthn.ErrVerificationFailed))
+	})
+
+	t.Run("other credential", func(t *testing.T) {
+		t.Parallel()
+		a := webauthntest.New(testRP.ID, testRP.Origin)
+		cred1 := register(t, a)
+		cred2 := register(t, a)
+
+		challenge := webauthn.NewChallenge()
+		res, err := a.Assert(cred2.ID, challenge)
+		require.NoError(t, err)
+		_, err = testRP.VerifyAssertion(cred1, challenge, res.ClientDataJSON, res.AuthenticatorData, res.Signature, true)
+		assert.True(t, errors.Is(err, webauthn.ErrVerificationFailed))
+	})
+}
+
+func TestAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ed, err := webauthn.MarshalEd25519PublicKey(edPub)
+	require.NoError(t, err)
+	alg, err := webauthn.Algorithm(ed)
+	if assert.NoError(t, err) {
+		assert.Equal(t, webauthn.AlgEdDSA, alg)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rs, err := webauthn.MarshalRS256PublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	alg, err = webauthn.Algorithm(rs)
+	if assert.NoError(t, err) {
+		assert.Equal(t, webauthn.AlgRS256, alg)
+	}
+
+	_, err = webauthn.Algorithm([]byte{0xa0})
+	assert.True(t, errors.Is(err, webauthn.ErrUnsupportedKey))
+}
@@ -0,0 +1,165 @@
+// Package webauthntest テスト用のソフトウェアWebAuthn認証器を提供します
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/traPtitech/traQ/utils/webauthn"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// Authenticator ES256の鍵をメモリ上に保持するソフトウェア認証器
+type Authenticator struct {
+	// Origin clientDataJSONに埋め込むオリジン
+	Origin string
+	// RPID 署名対象のRP ID
+	RPID string
+	// UserVerification UVフラグを立てるかどうか
+	UserVerification bool
+	// CounterDisabled trueの場合、署名カウンタを常に0にします
+	CounterDisabled bool
+
+	mu          sync.Mutex
+	credentials map[string]*credential
+}
+
+type credential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	counter    uint32
+}
+
+// AttestationResponse navigator.credentials.createのレスポンス
+type AttestationResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// AssertionResponse navigator.credentials.getのレスポンス
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// New 新しいソフトウェア認証器を生成します
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{
+		Origin:           origin,
+		RPID:             rpID,
+		UserVerification: true,
+		credentials:      map[string]*credential{},
+	}
+}
+
+// Register 新しい鍵ペアを生成し、登録レスポンスを返します
+func (a *Authenticator) Register(challenge, userHandle []byte) (*AttestationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, key: key, userHandle: userHandle}
+
+	pub, err := webauthn.MarshalES256PublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authenticatorData(cred, protocol.FlagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = append(authData, byte(len(id)>>8), byte(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, pub...)
+
+	attObj, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.credentials[string(id)] = cred
+	a.mu.Unlock()
+
+	return &AttestationResponse{
+		CredentialID:      id,
+		ClientDataJSON:    a.clientData(protocol.CreateCeremony, challenge),
+		AttestationObject: attObj,
+	}, nil
+}
+
+// Assert 指定したCredentialで署名し、認証レスポンスを返します
+func (a *Authenticator) Assert(credentialID, challenge []byte) (*AssertionResponse, error) {
+	a.mu.Lock()
+	cred, ok := a.credentials[string(credentialID)]
+	if ok && !a.CounterDisabled {
+		cred.counter++
+	}
+	a.mu.Unlock()
+	if !ok {
+		return nil, errors.New("unknown credential")
+	}
+
+	authData := a.authenticatorData(cred, 0)
+	clientData := a.clientData(protocol.AssertCeremony, challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	h := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	r, ss, err := ecdsa.Sign(rand.Reader, cred.key, h[:])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, ss})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AssertionResponse{
+		CredentialID:      cred.id,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         sig,
+		UserHandle:        cred.userHandle,
+	}, nil
+}
+
+func (a *Authenticator) authenticatorData(cred *credential, flags protocol.AuthenticatorFlags) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags |= protocol.FlagUserPresent
+	if a.UserVerification {
+		flags |= protocol.FlagUserVerified
+	}
+	b := make([]byte, 37)
+	copy(b, rpIDHash[:])
+	b[32] = byte(flags)
+	binary.BigEndian.PutUint32(b[33:], cred.counter)
+	return b
+}
+
+func (a *Authenticator) clientData(typ protocol.CeremonyType, challenge []byte) []byte {
+	b, _ := json.Marshal(protocol.CollectedClientData{
+		Type:      typ,
+		Challenge: webauthn.EncodeBase64URL(challenge),
+		Origin:    a.Origin,
+	})
+	return b
+}